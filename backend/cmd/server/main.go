@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log/slog"
 	"net/http"
 	"os"
@@ -9,33 +10,77 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/archive"
 	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/consolidation"
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/egress"
 	"github.com/ThatHunky/gryag/backend/internal/handler"
 	"github.com/ThatHunky/gryag/backend/internal/i18n"
 	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/ThatHunky/gryag/backend/internal/mcp"
+	"github.com/ThatHunky/gryag/backend/internal/metrics"
 	"github.com/ThatHunky/gryag/backend/internal/middleware"
 	"github.com/ThatHunky/gryag/backend/internal/proactive"
+	"github.com/ThatHunky/gryag/backend/internal/reporting"
+	"github.com/ThatHunky/gryag/backend/internal/retention"
+	"github.com/ThatHunky/gryag/backend/internal/scrub"
 	"github.com/ThatHunky/gryag/backend/internal/summarizer"
 	"github.com/ThatHunky/gryag/backend/internal/tools"
+	"github.com/ThatHunky/gryag/backend/internal/topicindex"
 )
 
 func main() {
 	// ── Structured JSON Logger ──────────────────────────────────────────
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+	// Wrapped so request_id/chat_id/user_id/tool scoped on a context appear on every line logged with it.
+	jsonHandler := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
-	}))
-	slog.SetDefault(logger)
+	})
+	slog.SetDefault(slog.New(logging.NewHandler(jsonHandler)))
 
 	// ── Load Configuration ──────────────────────────────────────────────
-	cfg, err := config.Load()
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "config file (TOML) layered under the environment; defaults to $CONFIG_FILE")
+	printCfg := flag.Bool("print-config", false, "print the effective configuration (secrets redacted) and exit")
+	flag.Parse()
+	cfg, err := config.LoadFile(*configFile)
 	if err != nil {
 		slog.Error("failed to load configuration", "error", err)
 		os.Exit(1)
 	}
+	if *printCfg {
+		if err := printConfig(os.Stdout, cfg); err != nil {
+			slog.Error("print-config failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if args := flag.Args(); len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(cfg, args[1:]); err != nil {
+			slog.Error("migrate failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// ── PII Scrubbing (optional) ────────────────────────────────────────
+	// Patterns were validated by config.Load, so New only fails on a bug.
+	scrubber, err := scrub.New(cfg.ScrubPatterns)
+	if err != nil {
+		slog.Error("failed to build the PII scrubber", "error", err)
+		os.Exit(1)
+	}
+	if cfg.ScrubLogs {
+		slog.SetDefault(slog.New(logging.NewHandler(scrub.NewHandler(jsonHandler, scrubber))))
+	}
+	for _, issue := range cfg.Issues {
+		slog.Warn("configuration value ignored", "key", issue.Key, "value", issue.Value, "problem", issue.Problem, "using", issue.Fallback)
+	}
 	slog.Info("configuration loaded",
 		"model", cfg.GeminiModel,
+		"config_file", cfg.ConfigFile,
 		"backend_addr", cfg.ListenAddr(),
 		"postgres", cfg.PostgresHost,
 		"redis", cfg.RedisAddr(),
@@ -50,9 +95,13 @@ func main() {
 		os.Exit(1)
 	}
 	slog.Info("i18n loaded", "languages", bundle.Languages())
+	if cfg.LocaleReloadIntervalSeconds > 0 {
+		go bundle.Watch(context.Background(), time.Duration(cfg.LocaleReloadIntervalSeconds)*time.Second)
+		slog.Info("locale auto-reload enabled", "interval_seconds", cfg.LocaleReloadIntervalSeconds)
+	}
 
 	// ── PostgreSQL ──────────────────────────────────────────────────────
-	database, err := db.New(cfg.PostgresDSN())
+	database, err := db.New(cfg.PostgresDSN(), poolOptions(cfg))
 	if err != nil {
 		slog.Error("failed to connect to postgres", "error", err)
 		os.Exit(1)
@@ -60,16 +109,11 @@ func main() {
 	defer database.Close()
 
 	// ── Run Migrations ─────────────────────────────────────────────────
-	if err := db.RunMigrations(database.Pool(), "migrations"); err != nil {
+	if err := db.RunMigrations(database.Pool(), migrationsDir); err != nil {
 		slog.Error("failed to run migrations", "error", err)
 		os.Exit(1)
 	}
 
-	// ── Message Retention Cleanup ───────────────────────────────────────
-	if _, err := database.PruneOldMessages(context.Background(), cfg.MessageRetentionDays); err != nil {
-		slog.Warn("message retention cleanup failed", "error", err)
-	}
-
 	// ── Redis ───────────────────────────────────────────────────────────
 	redisCache, err := cache.New(cfg.RedisAddr(), cfg.RedisPassword)
 	if err != nil {
@@ -77,6 +121,31 @@ func main() {
 		os.Exit(1)
 	}
 	defer redisCache.Close()
+	if n, err := redisCache.MigrateProactiveQueue(context.Background()); err != nil {
+		slog.Warn("proactive queue migration failed", "error", err)
+	} else if n > 0 {
+		slog.Info("proactive queue migrated to stream", "items", n)
+	}
+
+	if cfg.ScrubMessages {
+		database.SetTextScrubber(scrubber.ScrubPtr)
+	}
+	// Keys were validated by config.Load; a nil cipher (no key) stores text as given.
+	cipher, err := cfg.Cipher()
+	if err != nil {
+		slog.Error("failed to set up encryption at rest", "error", err)
+		os.Exit(1)
+	}
+	if cipher != nil {
+		database.SetCipher(cipher)
+		slog.Info("encryption at rest enabled", "previous_keys", len(cfg.EncryptionPreviousKeys))
+	}
+	if cfg.ContextCacheTTLSeconds > 0 {
+		database.SetContextCache(redisCache, time.Duration(cfg.ContextCacheTTLSeconds)*time.Second, cfg.ImmediateContextPool())
+	}
+
+	// ── Per-chat Settings (env defaults + chat_settings overrides) ────────
+	settingsStore := chatsettings.NewStore(database, redisCache, cfg)
 
 	// ── Gemini LLM Client ───────────────────────────────────────────────
 	llmClient, err := llm.NewClient(cfg)
@@ -84,50 +153,223 @@ func main() {
 		slog.Error("failed to initialize gemini client", "error", err)
 		os.Exit(1)
 	}
+	llmClient.SetUsageStore(database)
+	geminiHealth := metrics.NewWindow(time.Duration(cfg.ProactiveHealthWindowMinutes) * time.Minute)
+	llmClient.SetMetrics(geminiHealth)
+	// Deletes Files API uploads (media over MEDIA_INLINE_MAX_BYTES) that their request left behind
+	go llmClient.UploadSweeper(context.Background())
 
 	// ── Tool Registry & Executor ────────────────────────────────────────
 	registry := tools.NewRegistry(cfg)
-	executor := tools.NewExecutor(cfg, database, bundle, llmClient)
+	// One outbound policy (and connection pool) for every tool that makes its own HTTP calls
+	egressPolicy := egress.NewPolicy(cfg)
+	var webhookTools *tools.WebhookTools
+	if cfg.WebhookToolsFile != "" {
+		webhookTools, err = tools.LoadWebhookTools(cfg.WebhookToolsFile, egressPolicy)
+		if err != nil {
+			slog.Error("failed to load webhook tools", "file", cfg.WebhookToolsFile, "error", err)
+			os.Exit(1)
+		}
+		if n := registry.RegisterExternal(webhookTools.Declarations()); n < len(webhookTools.Declarations()) {
+			slog.Warn("webhook tools skipped: name already taken by a built-in tool", "skipped", len(webhookTools.Declarations())-n)
+		}
+	}
+	var mcpTools *mcp.Manager
+	if cfg.MCPConfigFile != "" {
+		servers, err := mcp.LoadConfig(cfg.MCPConfigFile)
+		if err != nil {
+			slog.Error("failed to load mcp config", "file", cfg.MCPConfigFile, "error", err)
+			os.Exit(1)
+		}
+		mcpTools = mcp.Connect(context.Background(), servers, registry.HasTool, egressPolicy)
+		defer mcpTools.Close()
+		registry.RegisterExternal(mcpTools.Declarations())
+	}
+	if cfg.ToolDeclarationsDir != "" {
+		n, err := registry.LoadDeclarationFiles(cfg.ToolDeclarationsDir)
+		if err != nil {
+			slog.Error("failed to load tool declarations", "dir", cfg.ToolDeclarationsDir, "error", err)
+			os.Exit(1)
+		}
+		slog.Info("tool declarations loaded", "dir", cfg.ToolDeclarationsDir, "overrides", n)
+	}
+	executor := tools.NewExecutor(cfg, database, bundle, llmClient, settingsStore)
+	executor.SetCache(redisCache)
+	executor.SetAuditStore(database)
+	if webhookTools != nil {
+		executor.AddExternalTools(webhookTools)
+	}
+	if mcpTools != nil {
+		executor.AddExternalTools(mcpTools)
+	}
 	slog.Info("tools loaded", "count", registry.Count(), "names", registry.GetToolNames())
 
 	// ── Request Handler ─────────────────────────────────────────────────
-	h := handler.New(cfg, database, redisCache, llmClient, registry, executor, bundle)
+	h := handler.New(cfg, database, redisCache, llmClient, registry, executor, bundle, settingsStore)
 
 	// ── Rate Limiter Middleware ──────────────────────────────────────────
-	rateLimiter := middleware.NewRateLimiter(redisCache, database, cfg)
+	rateLimiter := middleware.NewRateLimiter(redisCache, database, cfg, settingsStore)
+	rateLimiter.SetTriggerCheck(h.Triggered)
+
+	// ── Message archive (optional; retention copies expiring messages here) ──
+	var archiveStore *archive.Store
+	if cfg.RetentionArchiveDir != "" {
+		archiveStore = archive.NewStore(cfg.RetentionArchiveDir)
+		archiveStore.SetCipher(cipher)
+	}
 
 	// ── Admin Handler ───────────────────────────────────────────────────
-	adminH := handler.NewAdminHandler(cfg, database)
+	adminH := handler.NewAdminHandler(cfg, database, settingsStore, bundle)
+	adminH.SetCache(redisCache)
+	if archiveStore != nil {
+		adminH.SetArchive(archiveStore)
+	}
+	if cfg.DebugTrace {
+		adminH.SetTracer(h)
+		slog.Warn("DEBUG_TRACE is on: full prompts and replies are kept in Redis", "ttl_hours", cfg.DebugTraceTTLHours)
+	}
+	if cfg.CanaryEnabled() {
+		slog.Info("canary replies enabled", "model", cfg.CanaryModel, "temperature", cfg.CanaryTemperature, "percent", cfg.CanaryPercent, "chats", len(cfg.CanaryChatIDs))
+	}
 
 	// ── Proactive messaging (optional) ───────────────────────────────────
 	if cfg.EnableProactiveMessaging {
-		proactiveRunner := proactive.NewRunner(cfg, database, llmClient, registry, executor, redisCache)
+		proactiveRunner := proactive.NewRunner(cfg, database, llmClient, registry, executor, redisCache, settingsStore, geminiHealth)
 		go proactive.Scheduler(context.Background(), proactiveRunner, cfg.ProactiveActiveStartHour, cfg.ProactiveActiveEndHour)
 		slog.Info("proactive messaging started", "active_hours_start", cfg.ProactiveActiveStartHour, "active_hours_end", cfg.ProactiveActiveEndHour)
 	}
 
 	// ── Summarization (optional; 3 AM Kyiv, 7-day every 3 days, 30-day every 12 days) ──
 	if cfg.EnableSummarization {
-		summarizerRunner := summarizer.NewRunner(database, redisCache, llmClient, cfg)
+		summarizerRunner := summarizer.NewRunner(database, redisCache, llmClient, settingsStore, cfg)
 		go summarizer.Scheduler(context.Background(), summarizerRunner, cfg)
 		slog.Info("summarization started", "run_hour_kyiv", cfg.SummaryRunHour, "7day_interval_days", cfg.Summary7DayIntervalDays, "30day_interval_days", cfg.Summary30DayIntervalDays)
 	}
 
+	// ── Morning and weekly personal digests (optional; opted-in chats/users, Kyiv time) ──
+	if cfg.EnableDailyDigest || cfg.EnablePersonalDigest {
+		digestRunner := summarizer.NewDigestRunner(summarizer.NewRunner(database, redisCache, llmClient, settingsStore, cfg), bundle)
+		go summarizer.DigestScheduler(context.Background(), digestRunner, cfg)
+		slog.Info("digests started",
+			"daily", cfg.EnableDailyDigest, "default_hour_kyiv", cfg.DailyDigestHour, "min_messages", cfg.DailyDigestMinMessages,
+			"personal", cfg.EnablePersonalDigest, "personal_weekday", cfg.PersonalDigestWeekday, "personal_hour_kyiv", cfg.PersonalDigestHour)
+	}
+
+	// ── Memory consolidation (optional; nightly, Kyiv time) ─────────────
+	if cfg.EnableMemoryConsolidation {
+		consolidationRunner := consolidation.NewRunner(database, redisCache, llmClient, cfg)
+		go consolidation.Scheduler(context.Background(), consolidationRunner, cfg)
+		slog.Info("memory consolidation started", "run_hour_kyiv", cfg.MemoryConsolidationRunHour, "decay_months", cfg.FactDecayMonths, "max_facts_per_user", cfg.MaxFactsPerUser)
+	}
+
+	// ── Topic index (optional; hourly) ────────────────────────────────
+	if cfg.EnableTopicIndex {
+		topicIndexRunner := topicindex.NewRunner(database, redisCache, llmClient, cfg)
+		go topicindex.Scheduler(context.Background(), topicIndexRunner)
+		slog.Info("topic index started", "min_messages", cfg.TopicIndexMinMessages)
+	}
+
+	// ── Data retention (daily, Kyiv time; right away when overdue) ──────
+	retentionRunner := retention.NewRunner(database, redisCache, cfg)
+	if archiveStore != nil {
+		retentionRunner.SetArchive(archiveStore)
+	}
+	go retention.Scheduler(context.Background(), retentionRunner, cfg)
+	slog.Info("retention started", "run_hour_kyiv", cfg.RetentionRunHour, "message_days", cfg.MessageRetentionDays, "summary_days", cfg.SummaryRetentionDays, "archive_dir", cfg.RetentionArchiveDir)
+
+	// ── Weekly activity report to admins (optional; Kyiv time) ──────────
+	if cfg.EnableActivityReport {
+		reportRunner := reporting.NewRunner(database, redisCache, bundle, cfg)
+		go reporting.Scheduler(context.Background(), reportRunner, cfg)
+		slog.Info("activity report started", "weekday", cfg.ActivityReportWeekday, "hour_kyiv", cfg.ActivityReportHour, "admins", len(cfg.AdminIDs))
+	}
+
 	// ── HTTP Mux ────────────────────────────────────────────────────────
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", handler.HealthCheck)
-	mux.Handle("POST /api/v1/process", rateLimiter.Middleware(http.HandlerFunc(h.Process)))
+	var geminiPinger handler.Pinger
+	if cfg.ReadyCheckGemini {
+		geminiPinger = llmClient
+	}
+	readyH := handler.NewReadyHandler(database, redisCache, geminiPinger, time.Duration(cfg.ReadyGeminiCacheSeconds)*time.Second)
+	if cfg.RedisFallback == config.RedisFallbackMemory {
+		readyH.AllowDegraded("redis")
+	}
+	mux.HandleFunc("GET /ready", readyH.Ready)
+	mux.HandleFunc("GET /api/v1/openapi.json", handler.OpenAPI)
+	mux.Handle("POST /api/v1/process", h.Albums(rateLimiter.Middleware(http.HandlerFunc(h.Process))))
+	mux.Handle("POST /api/v2/process", h.Albums(rateLimiter.Middleware(http.HandlerFunc(h.ProcessV2))))
+	mux.HandleFunc("POST /api/v1/ack_reply", h.AckReply)
+	mux.HandleFunc("POST /api/v1/ack", h.AckReply) // short alias, same body
+	mux.HandleFunc("POST /api/v1/event", h.Event)
 	mux.HandleFunc("POST /api/v1/admin/stats", adminH.Stats)
 	mux.HandleFunc("POST /api/v1/admin/reload_persona", adminH.ReloadPersona)
-	if cfg.EnableProactiveMessaging {
+	mux.HandleFunc("POST /api/v1/admin/reload_locales", adminH.ReloadLocales)
+	mux.HandleFunc("POST /api/v1/admin/chat_settings", adminH.GetChatSettings)
+	mux.HandleFunc("PUT /api/v1/admin/chat_settings", adminH.PutChatSettings)
+	mux.HandleFunc("DELETE /api/v1/admin/chat_settings", adminH.DeleteChatSettings)
+	mux.HandleFunc("POST /api/v1/admin/personas", adminH.ListPersonas)
+	mux.HandleFunc("PUT /api/v1/admin/personas", adminH.PutPersona)
+	mux.HandleFunc("DELETE /api/v1/admin/personas", adminH.DeletePersona)
+	mux.HandleFunc("POST /api/v1/admin/off_record", adminH.ListOffRecordWindows)
+	mux.HandleFunc("PUT /api/v1/admin/off_record", adminH.PutOffRecordWindow)
+	mux.HandleFunc("DELETE /api/v1/admin/off_record", adminH.DeleteOffRecordWindow)
+	mux.HandleFunc("POST /api/v1/admin/chat_topics", adminH.ListChatTopics)
+	mux.HandleFunc("PUT /api/v1/admin/chat_topics", adminH.PutChatTopic)
+	mux.HandleFunc("DELETE /api/v1/admin/chat_topics", adminH.DeleteChatTopic)
+	mux.HandleFunc("POST /api/v1/admin/proactive_history", adminH.ProactiveHistory)
+	mux.HandleFunc("POST /api/v1/admin/archives", adminH.ListArchives)
+	mux.HandleFunc("POST /api/v1/admin/archives/query", adminH.QueryArchives)
+	mux.HandleFunc("POST /api/v1/admin/archives/restore", adminH.RestoreArchives)
+	mux.HandleFunc("POST /api/v1/admin/block", adminH.ListBlockedUsers)
+	mux.HandleFunc("PUT /api/v1/admin/block", adminH.PutBlockedUser)
+	mux.HandleFunc("DELETE /api/v1/admin/block", adminH.DeleteBlockedUser)
+	mux.HandleFunc("POST /api/v1/admin/rate_limit_boost", adminH.GetRateBoost)
+	mux.HandleFunc("PUT /api/v1/admin/rate_limit_boost", adminH.PutRateBoost)
+	mux.HandleFunc("DELETE /api/v1/admin/rate_limit_boost", adminH.DeleteRateBoost)
+	mux.HandleFunc("POST /api/v1/admin/trace/{request_id}", adminH.Trace)
+	mux.HandleFunc("POST /api/v1/admin/replay/{request_id}", adminH.Replay)
+	mux.HandleFunc("POST /api/v1/admin/report", adminH.Report)
+	mux.HandleFunc("POST /api/v1/admin/canary", adminH.Canary)
+	mux.HandleFunc("POST /api/v1/admin/tool_calls", adminH.ToolCalls)
+	mux.HandleFunc("POST /api/v1/admin/query", adminH.Query)
+	mux.HandleFunc("POST /api/v1/admin/analytics", adminH.Analytics)
+	mux.HandleFunc("POST /api/v1/admin/user/{id}", adminH.UserProfile)
+	mux.HandleFunc("POST /api/v1/admin/config", adminH.Config)
+	mux.HandleFunc("POST /api/v1/admin/debug", adminH.Debug)
+	if cfg.EnablePprof {
+		if cfg.BackendAPISecret != "" {
+			mux.HandleFunc("GET /debug/pprof/", adminH.Pprof)
+			mux.HandleFunc("POST /debug/pprof/symbol", adminH.Pprof)
+			slog.Info("pprof enabled", "path", "/debug/pprof/")
+		} else {
+			slog.Warn("ENABLE_PPROF ignored: /debug/pprof/ needs BACKEND_API_SECRET")
+		}
+	}
+	if cfg.ProactiveQueueEnabled() {
 		mux.HandleFunc("GET /api/v1/proactive", h.Proactive)
+		mux.HandleFunc("GET /api/v1/proactive/stream", h.ProactiveStream)
+		mux.HandleFunc("POST /api/v1/proactive/ack", h.AckProactive)
 	}
+	if cfg.EnableProactiveMessaging {
+		mux.HandleFunc("POST /api/v1/proactive/settings", adminH.ProactiveCommand)
+	}
+
+	// Bodies are checked against the OpenAPI schemas (after auth, so only clients see the details)
+	root := middleware.Validate(mux)
+	if cfg.BackendAPISecret != "" {
+		root = middleware.NewAPIAuth(cfg.BackendAPISecret, time.Duration(cfg.BackendAPIMaxSkewSeconds)*time.Second).Middleware(root)
+	} else {
+		slog.Warn("BACKEND_API_SECRET is not set: /api/v1 endpoints are unauthenticated, keep the backend port private")
+	}
+	// Outermost, so even rejected requests are logged and answered with their X-Request-ID
+	root = middleware.RequestID(root)
 
 	// ── Server with Graceful Shutdown ────────────────────────────────────
 	addr := cfg.ListenAddr()
 	server := &http.Server{
 		Addr:         addr,
-		Handler:      mux,
+		Handler:      root,
 		ReadTimeout:  30 * time.Second,
 		WriteTimeout: 120 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -157,3 +399,13 @@ func main() {
 
 	slog.Info("server stopped")
 }
+
+// poolOptions returns the Postgres pool sizing from the config.
+func poolOptions(cfg *config.Config) db.PoolOptions {
+	return db.PoolOptions{
+		MaxConns:        cfg.PostgresMaxConns,
+		MinConns:        cfg.PostgresMinConns,
+		MaxConnLifetime: time.Duration(cfg.PostgresMaxConnLifetimeSeconds) * time.Second,
+		MaxConnIdleTime: time.Duration(cfg.PostgresMaxConnIdleSeconds) * time.Second,
+	}
+}
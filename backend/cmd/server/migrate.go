@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// migrationsDir is where the migration files live, relative to the working directory.
+const migrationsDir = "migrations"
+
+const migrateUsage = `usage: gryag-backend migrate <command>
+
+  up             apply pending migrations
+  down [n]       roll back the last n applied migrations (default 1)
+  status         list migrations and whether they are applied
+  force VERSION  record the schema as at VERSION without running SQL`
+
+// runMigrate implements the `migrate` subcommand.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return fmt.Errorf("missing migrate command")
+	}
+	database, err := db.New(cfg.PostgresDSN(), poolOptions(cfg))
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+	m := db.NewMigrator(database.Pool(), migrationsDir)
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("down: n must be a positive number")
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		for _, v := range done {
+			fmt.Println("rolled back", v)
+		}
+		return err
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tAPPLIED\tNOTE")
+		for _, s := range status {
+			applied, note := "no", ""
+			if s.Applied {
+				applied = s.AppliedAt.Local().Format(time.DateTime)
+			}
+			switch {
+			case s.Missing:
+				note = "file missing"
+			case s.Modified:
+				note = "modified since applied"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Version, applied, note)
+		}
+		return tw.Flush()
+	case "force":
+		if len(args) < 2 {
+			return fmt.Errorf("force: VERSION is required")
+		}
+		if err := m.Force(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Println("schema forced to", args[1])
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+// printConfig implements --print-config: the effective configuration, after the config file and
+// the environment are merged, with secrets redacted, plus every value that was rejected.
+func printConfig(w io.Writer, cfg *config.Config) error {
+	issues := cfg.Issues
+	if issues == nil {
+		issues = []config.Issue{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Config config.Config  `json:"config"`
+		Issues []config.Issue `json:"issues"`
+	}{cfg.Redacted(), issues})
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+func TestPrintConfig(t *testing.T) {
+	cfg := &config.Config{
+		GeminiAPIKey: "secret",
+		GeminiModel:  "gemini-2.5-flash",
+		ConfigFile:   "/etc/gryag.toml",
+		Issues:       []config.Issue{{Key: "SANDBOX_MEMROY_MB", Value: "64", Problem: "unknown setting"}},
+	}
+	var buf bytes.Buffer
+	if err := printConfig(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("expected secrets redacted, got %s", buf.String())
+	}
+	var out struct {
+		Config config.Config  `json:"config"`
+		Issues []config.Issue `json:"issues"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Config.ConfigFile != "/etc/gryag.toml" || out.Config.GeminiModel != "gemini-2.5-flash" || len(out.Issues) != 1 {
+		t.Errorf("unexpected output: %+v", out)
+	}
+}
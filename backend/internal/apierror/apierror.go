@@ -0,0 +1,93 @@
+// Package apierror writes the JSON body of every error response, so clients can branch on a
+// stable code instead of parsing messages:
+//
+//	{"error": "poll not found", "code": "not_found", "request_id": "…", "retryable": false}
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// Code classifies an error. Each code has one HTTP status.
+type Code string
+
+// Error codes.
+const (
+	InvalidPayload  Code = "invalid_payload"  // 400: the body is not valid JSON or fails its schema
+	InvalidArgument Code = "invalid_argument" // 400: a field value is out of range or inconsistent
+	Unauthorized    Code = "unauthorized"     // 401: missing or bad API secret or signature
+	Forbidden       Code = "forbidden"        // 403: user_id is not an admin
+	NotFound        Code = "not_found"        // 404
+	Disabled        Code = "disabled"         // 404: the feature is turned off in the configuration
+	Unprocessable   Code = "unprocessable"    // 422: well-formed, but cannot be applied
+	Overloaded      Code = "overloaded"       // 429: no processing slot freed up in time
+	Internal        Code = "internal"         // 500
+	Upstream        Code = "upstream_failed"  // 502: Gemini or another upstream call failed
+	Unavailable     Code = "unavailable"      // 503: a dependency is down
+)
+
+// Codes lists every code, for the API description.
+var Codes = []Code{InvalidPayload, InvalidArgument, Unauthorized, Forbidden, NotFound, Disabled,
+	Unprocessable, Overloaded, Internal, Upstream, Unavailable}
+
+var statuses = map[Code]int{
+	InvalidPayload:  http.StatusBadRequest,
+	InvalidArgument: http.StatusBadRequest,
+	Unauthorized:    http.StatusUnauthorized,
+	Forbidden:       http.StatusForbidden,
+	NotFound:        http.StatusNotFound,
+	Disabled:        http.StatusNotFound,
+	Unprocessable:   http.StatusUnprocessableEntity,
+	Overloaded:      http.StatusTooManyRequests,
+	Internal:        http.StatusInternalServerError,
+	Upstream:        http.StatusBadGateway,
+	Unavailable:     http.StatusServiceUnavailable,
+}
+
+// Status returns the HTTP status of c (500 for an unknown code).
+func (c Code) Status() int {
+	if s, ok := statuses[c]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// Retryable reports whether the same request may succeed later.
+func (c Code) Retryable() bool {
+	switch c {
+	case Overloaded, Internal, Upstream, Unavailable:
+		return true
+	}
+	return false
+}
+
+// Error is the body of an error response. Message keeps the "error" key the API always used.
+type Error struct {
+	Message   string `json:"error"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	Retryable bool   `json:"retryable"`
+	Details   any    `json:"details,omitempty"` // e.g. the schema problems of an invalid payload
+}
+
+// Write answers r with code's status and an Error carrying message and the request's ID.
+func Write(w http.ResponseWriter, r *http.Request, code Code, message string) {
+	WriteDetails(w, r, code, message, nil)
+}
+
+// WriteDetails is Write with details attached.
+func WriteDetails(w http.ResponseWriter, r *http.Request, code Code, message string, details any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(code.Status())
+	json.NewEncoder(w).Encode(Error{
+		Message:   message,
+		Code:      code,
+		RequestID: r.Header.Get(logging.HeaderRequestID),
+		Retryable: code.Retryable(),
+		Details:   details,
+	})
+}
@@ -0,0 +1,51 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+func TestWrite(t *testing.T) {
+	r := httptest.NewRequest("POST", "/api/v1/admin/stats", nil)
+	r.Header.Set(logging.HeaderRequestID, "req-1")
+	w := httptest.NewRecorder()
+	Write(w, r, NotFound, "poll not found")
+
+	if w.Code != http.StatusNotFound || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"error": "poll not found", "code": "not_found", "request_id": "req-1", "retryable": false}
+	if len(got) != len(want) {
+		t.Errorf("body = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestCodes(t *testing.T) {
+	for _, c := range Codes {
+		if _, ok := statuses[c]; !ok {
+			t.Errorf("%s has no status", c)
+		}
+	}
+	if len(statuses) != len(Codes) {
+		t.Error("Codes and statuses list different codes")
+	}
+	if !Unavailable.Retryable() || !Internal.Retryable() || InvalidArgument.Retryable() || Forbidden.Retryable() {
+		t.Error("unexpected retryable flags")
+	}
+	if Disabled.Status() != http.StatusNotFound || Code("bogus").Status() != http.StatusInternalServerError {
+		t.Error("unexpected statuses")
+	}
+}
@@ -0,0 +1,123 @@
+package apispec
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func decode(t *testing.T, body string) any {
+	t.Helper()
+	dec := json.NewDecoder(strings.NewReader(body))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("decode %s: %v", body, err)
+	}
+	return v
+}
+
+func TestValidate(t *testing.T) {
+	s := Obj(map[string]*Schema{
+		"chat_id": Int(""),
+		"temp":    Num("").Range(0, 2),
+		"kind":    Str("").OneOf("a", "b"),
+		"at":      Time(""),
+		"on":      Bool(""),
+		"tags":    Arr(Int(""), ""),
+		"inner":   Obj(map[string]*Schema{"id": Str("")}, "id"),
+	}, "chat_id")
+	tests := []struct {
+		body string
+		want []string // "field: problem"
+	}{
+		{`{"chat_id": 1}`, nil},
+		{`{"chat_id": 1, "temp": null, "kind": null, "inner": null}`, nil},
+		{`{"chat_id": 1, "extra": "ignored"}`, nil},
+		{`{"chat_id": 1, "temp": 1.5, "kind": "b", "at": "2026-03-01T10:00:00Z", "on": true, "tags": [1, 2], "inner": {"id": "x"}}`, nil},
+		{`{}`, []string{"chat_id: is required"}},
+		{`{"chat_id": null}`, []string{"chat_id: is required"}},
+		{`{"chat_id": "1"}`, []string{"chat_id: must be a number"}},
+		{`{"chat_id": 1.5}`, []string{"chat_id: must be an integer"}},
+		{`{"chat_id": 1, "temp": 3}`, []string{"temp: must be at most 2"}},
+		{`{"chat_id": 1, "kind": "c"}`, []string{"kind: must be one of a, b"}},
+		{`{"chat_id": 1, "at": "tomorrow"}`, []string{"at: must be an RFC 3339 time"}},
+		{`{"chat_id": 1, "on": "yes"}`, []string{"on: must be true or false"}},
+		{`{"chat_id": 1, "tags": [1, "x"]}`, []string{"tags[1]: must be a number"}},
+		{`{"chat_id": 1, "inner": {}}`, []string{"inner.id: is required"}},
+		{`[1]`, []string{": must be an object"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range s.Validate(decode(t, tt.body)) {
+			got = append(got, e.Field+": "+e.Problem)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: got %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestObj_OptionalFieldsNullable(t *testing.T) {
+	s := Obj(map[string]*Schema{"a": Int(""), "b": Int("")}, "a")
+	if s.Properties["a"].Nullable || !s.Properties["b"].Nullable {
+		t.Error("only optional properties should be nullable")
+	}
+}
+
+func TestFind(t *testing.T) {
+	if op := Find(http.MethodPost, "/api/v1/admin/replay/abc-123"); op == nil || op.Path != "/api/v1/admin/replay/{request_id}" {
+		t.Errorf("expected the replay operation, got %+v", op)
+	}
+	if op := Find(http.MethodPut, "/api/v1/admin/chat_settings"); op == nil || op.Request == nil {
+		t.Error("expected PUT chat_settings with a body")
+	}
+	for _, c := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/process"},
+		{http.MethodPost, "/api/v1/admin/replay/"},
+		{http.MethodPost, "/api/v1/admin/replay/a/b"},
+		{http.MethodPost, "/api/v1/nope"},
+	} {
+		if op := Find(c.method, c.path); op != nil {
+			t.Errorf("%s %s should not match, got %s", c.method, c.path, op.Path)
+		}
+	}
+}
+
+func TestDocument(t *testing.T) {
+	doc := Document()
+	data, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var parsed struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(parsed.OpenAPI, "3.") {
+		t.Errorf("openapi version %q", parsed.OpenAPI)
+	}
+	ids := map[string]bool{}
+	for _, op := range Operations() {
+		o, ok := parsed.Paths[op.Path][strings.ToLower(op.Method)]
+		if !ok {
+			t.Errorf("%s %s missing from the document", op.Method, op.Path)
+			continue
+		}
+		id, _ := o["operationId"].(string)
+		if ids[id] {
+			t.Errorf("duplicate operationId %s", id)
+		}
+		ids[id] = true
+		if op.Admin && (op.Request == nil || op.Request.Properties["user_id"] == nil) {
+			t.Errorf("%s %s: admin operations take user_id", op.Method, op.Path)
+		}
+	}
+	if !strings.Contains(string(data), `"$ref":"#/components/schemas/ValidationError"`) {
+		t.Error("expected 400 responses to reference ValidationError")
+	}
+}
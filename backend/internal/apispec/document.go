@@ -0,0 +1,124 @@
+package apispec
+
+import (
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+)
+
+// errorBody is the body of every error response (see apierror).
+func errorBody(details *Schema) *Schema {
+	codes := make([]string, len(apierror.Codes))
+	for i, c := range apierror.Codes {
+		codes[i] = string(c)
+	}
+	props := map[string]*Schema{
+		"error":      Str("Human-readable message"),
+		"code":       Str("Stable error code to branch on").OneOf(codes...),
+		"request_id": Str("The request's X-Request-ID"),
+		"retryable":  Bool("Whether the same request may succeed later"),
+	}
+	required := []string{"error", "code", "retryable"}
+	if details != nil {
+		props["details"] = details
+		required = append(required, "details")
+	}
+	return Obj(props, required...)
+}
+
+// validationError is the body of a 400 from the validation middleware.
+var validationError = errorBody(Arr(Obj(map[string]*Schema{
+	"field":   Str("Dotted path of the field; empty for the body itself"),
+	"problem": Str(""),
+}, "field", "problem"), ""))
+
+// Document builds the OpenAPI 3 document for every operation.
+func Document() map[string]any {
+	paths := map[string]map[string]any{}
+	for _, op := range operations {
+		item := paths[op.Path]
+		if item == nil {
+			item = map[string]any{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operationObject(op)
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "gryag backend API",
+			"version":     Version,
+			"description": "Called by the Telegram frontend and by admins. With BACKEND_API_SECRET set, every /api/ request needs X-Gryag-Secret or an X-Gryag-Signature (see docs/configuration.md). Request bodies are validated against these schemas; optional fields may be null.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{"Error": errorBody(nil), "ValidationError": validationError},
+			"securitySchemes": map[string]any{
+				"secret": map[string]any{"type": "apiKey", "in": "header", "name": "X-Gryag-Secret"},
+			},
+		},
+	}
+}
+
+func operationObject(op Operation) map[string]any {
+	response := op.Response
+	if response == nil {
+		response = &Schema{Type: "object"}
+	}
+	responses := map[string]any{
+		"200": map[string]any{"description": "OK", "content": jsonContent(response)},
+	}
+	if op.Silent {
+		responses["204"] = map[string]any{"description": "Nothing to send"}
+	}
+	if op.Request != nil {
+		responses["400"] = map[string]any{
+			"description": "Malformed or invalid body",
+			"content":     jsonContent(&Schema{Ref: "#/components/schemas/ValidationError"}),
+		}
+	}
+	if op.Admin {
+		responses["403"] = map[string]any{
+			"description": "user_id is not in ADMIN_IDS",
+			"content":     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+		}
+	}
+	responses["default"] = map[string]any{
+		"description": "Error",
+		"content":     jsonContent(&Schema{Ref: "#/components/schemas/Error"}),
+	}
+	obj := map[string]any{
+		"summary":     op.Summary,
+		"tags":        []string{op.Tag},
+		"operationId": operationID(op),
+		"responses":   responses,
+	}
+	if strings.HasPrefix(op.Path, "/api/") {
+		obj["security"] = []map[string][]string{{"secret": {}}}
+	}
+	if len(op.Parameters) > 0 {
+		obj["parameters"] = op.Parameters
+	}
+	if op.Request != nil {
+		obj["requestBody"] = map[string]any{"required": true, "content": jsonContent(op.Request)}
+	}
+	return obj
+}
+
+func jsonContent(s *Schema) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": s}}
+}
+
+// operationID is e.g. put_admin_chat_settings for PUT /api/v1/admin/chat_settings.
+func operationID(op Operation) string {
+	path := strings.TrimPrefix(op.Path, "/api/v1")
+	var parts []string
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, "{}")
+		seg = strings.NewReplacer(".", "_", "-", "_").Replace(seg)
+		if seg != "" {
+			parts = append(parts, seg)
+		}
+	}
+	return strings.ToLower(op.Method) + "_" + strings.Join(parts, "_")
+}
@@ -0,0 +1,171 @@
+// Package apispec describes the backend's HTTP API: the OpenAPI 3 document served at
+// /api/v1/openapi.json and the request schemas the validation middleware checks bodies against.
+package apispec
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of an OpenAPI 3.0 schema object the API needs.
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+}
+
+// Int, Num, Str, Bool, Time, Arr and Obj build schemas for the operation table.
+func Int(desc string) *Schema  { return &Schema{Type: "integer", Format: "int64", Description: desc} }
+func Num(desc string) *Schema  { return &Schema{Type: "number", Description: desc} }
+func Str(desc string) *Schema  { return &Schema{Type: "string", Description: desc} }
+func Bool(desc string) *Schema { return &Schema{Type: "boolean", Description: desc} }
+
+// Time is an RFC 3339 timestamp string.
+func Time(desc string) *Schema {
+	return &Schema{Type: "string", Format: "date-time", Description: desc}
+}
+
+// Arr is an array of items.
+func Arr(items *Schema, desc string) *Schema {
+	return &Schema{Type: "array", Items: items, Description: desc}
+}
+
+// Obj is an object with the given properties. Properties not listed in required may also be
+// null, which counts as omitted (the frontend sends None for unknown values).
+func Obj(props map[string]*Schema, required ...string) *Schema {
+	for name, p := range props {
+		p.Nullable = !slices.Contains(required, name)
+	}
+	return &Schema{Type: "object", Properties: props, Required: required}
+}
+
+// Range limits a number to [lo, hi].
+func (s *Schema) Range(lo, hi float64) *Schema {
+	s.Minimum, s.Maximum = &lo, &hi
+	return s
+}
+
+// Min limits a number to lo or more.
+func (s *Schema) Min(lo float64) *Schema {
+	s.Minimum = &lo
+	return s
+}
+
+// OneOf limits a string to the given values.
+func (s *Schema) OneOf(values ...string) *Schema {
+	s.Enum = values
+	return s
+}
+
+// FieldError is one problem with a request body; Field is a dotted path ("poll.options[2]"),
+// empty for the body itself.
+type FieldError struct {
+	Field   string `json:"field"`
+	Problem string `json:"problem"`
+}
+
+// Validate checks a value decoded with json.Decoder.UseNumber against the schema.
+func (s *Schema) Validate(v any) []FieldError {
+	var errs []FieldError
+	s.validate("", v, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path string, v any, errs *[]FieldError) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, FieldError{Field: path, Problem: fmt.Sprintf(format, args...)})
+	}
+	switch s.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			fail("must be an object")
+			return
+		}
+		for _, name := range s.Required {
+			if obj[name] == nil {
+				*errs = append(*errs, FieldError{Field: join(path, name), Problem: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		slices.Sort(names) // stable error order
+		for _, name := range names {
+			if val, ok := obj[name]; ok && val != nil {
+				s.Properties[name].validate(join(path, name), val, errs)
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			fail("must be an array")
+			return
+		}
+		if s.Items != nil {
+			for i, item := range arr {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item, errs)
+			}
+		}
+	case "string":
+		str, ok := v.(string)
+		if !ok {
+			fail("must be a string")
+			return
+		}
+		if len(s.Enum) > 0 && !slices.Contains(s.Enum, str) {
+			fail("must be one of %s", strings.Join(s.Enum, ", "))
+		}
+		if s.Format == "date-time" {
+			if _, err := time.Parse(time.RFC3339, str); err != nil {
+				fail("must be an RFC 3339 time")
+			}
+		}
+	case "integer", "number":
+		n, ok := v.(json.Number)
+		if !ok {
+			fail("must be a number")
+			return
+		}
+		f, err := n.Float64()
+		if err != nil {
+			fail("must be a number")
+			return
+		}
+		if s.Type == "integer" {
+			if _, err := n.Int64(); err != nil {
+				fail("must be an integer")
+				return
+			}
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			fail("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			fail("must be at most %v", *s.Maximum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			fail("must be true or false")
+		}
+	}
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,340 @@
+package apispec
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Version is the API version in the OpenAPI document.
+const Version = "1.0.0"
+
+// Parameter is a query or path parameter.
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"` // "query" or "path"
+	Required    bool    `json:"required,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// Operation is one method and path of the API.
+type Operation struct {
+	Method     string
+	Path       string // ServeMux pattern path, e.g. /api/v1/admin/trace/{request_id}
+	Tag        string
+	Summary    string
+	Parameters []Parameter
+	Request    *Schema // JSON body; nil = none
+	Response   *Schema // 200 body; nil = a generic object
+	Silent     bool    // may answer 204 with no body
+	Admin      bool    // user_id must be in ADMIN_IDS (403 otherwise)
+}
+
+// Find returns the operation for a method and request path, or nil.
+func Find(method, path string) *Operation {
+	for i := range operations {
+		op := &operations[i]
+		if op.Method == method && matchPath(op.Path, path) {
+			return op
+		}
+	}
+	return nil
+}
+
+// Operations returns every documented operation.
+func Operations() []Operation {
+	return operations
+}
+
+// matchPath matches a request path against a pattern whose {name} segments match any one segment.
+func matchPath(pattern, path string) bool {
+	ps, rs := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(ps) != len(rs) {
+		return false
+	}
+	for i, p := range ps {
+		if strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}") {
+			if rs[i] == "" {
+				return false
+			}
+			continue
+		}
+		if p != rs[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// admin is an admin request body: user_id (the admin's Telegram ID) plus props.
+func admin(props map[string]*Schema, required ...string) *Schema {
+	if props == nil {
+		props = map[string]*Schema{}
+	}
+	props["user_id"] = Int("Telegram ID of the admin making the request")
+	return Obj(props, append([]string{"user_id"}, required...)...)
+}
+
+// days is the report period of the admin report endpoints.
+func days() *Schema { return Int("Period in days; 0 or omitted = 7").Range(0, 90) }
+
+func chatSettingsBody() *Schema {
+	hour := func(desc string) *Schema { return Int(desc).Range(0, 23) }
+	return admin(map[string]*Schema{
+		"chat_id":                        Int("Telegram chat ID"),
+		"language":                       Str("Reply language code"),
+		"persona":                        Str("Inline persona text"),
+		"active_persona":                 Str("Name of a stored persona; overrides persona"),
+		"proactive_enabled":              Bool(""),
+		"disabled_tools":                 Arr(Str(""), "Tool names hidden from the model in this chat"),
+		"temperature":                    Num("").Range(0, 2),
+		"mention_reply_probability":      Num("").Range(0, 1),
+		"mention_daily_cap":              Int("0 = unlimited").Min(0),
+		"summary_language":               Str("Language code for summaries; empty = detect"),
+		"summary_enabled":                Bool(""),
+		"summary_run_hour":               hour("Kyiv time"),
+		"summary_interval_days":          Int("").Range(1, 30),
+		"summary_anonymize":              Bool(""),
+		"digest_enabled":                 Bool(""),
+		"digest_hour":                    hour("Kyiv time"),
+		"watermark_enabled":              Bool(""),
+		"watermark_label":                Str(""),
+		"proactive_min_interval_minutes": Int("").Range(0, 7*24*60),
+		"proactive_max_interval_minutes": Int("").Range(0, 7*24*60),
+		"proactive_quiet_start":          hour("In the chat's timezone"),
+		"proactive_quiet_end":            hour("In the chat's timezone, exclusive"),
+		"timezone":                       Str("IANA name, e.g. Europe/Kyiv"),
+		"message_retention_days":         Int("0 = keep forever").Range(0, 3650),
+		"shadow_mode":                    Bool("Log replies instead of sending them"),
+		"rate_limit_exempt_users":        Arr(Int(""), "Users who skip the chat and user rate limits in this chat"),
+		"proactive_news_probability":     Num("Chance that a proactive turn searches for news; 0 = never").Range(0, 1),
+		"proactive_news_queries":         Arr(Str(""), "Query hints for news turns; empty = PROACTIVE_NEWS_QUERIES"),
+		"privacy_mode":                   Bool("Pseudonyms instead of names and user IDs in prompts"),
+	}, "chat_id")
+}
+
+func archiveBody() *Schema {
+	return admin(map[string]*Schema{
+		"chat_id": Int("Telegram chat ID"),
+		"file":    Str("One archive file"),
+		"from":    Time(""),
+		"to":      Time("Exclusive"),
+		"query":   Str("Case-insensitive text match"),
+		"limit":   Int("").Min(0),
+	}, "chat_id")
+}
+
+func processBody() *Schema {
+	return Obj(map[string]*Schema{
+		"chat_id":             Int("Telegram chat ID"),
+		"user_id":             Int("Sender; null for channel posts"),
+		"username":            Str(""),
+		"first_name":          Str(""),
+		"text":                Str("Text or caption"),
+		"message_id":          Int("Telegram message ID"),
+		"date":                Str("ISO 8601 send time"),
+		"file_id":             Str(""),
+		"media_type":          Str("photo, video, document, voice, video_note, sticker, animation"),
+		"media_base64":        Str("Downloaded media"),
+		"mime_type":           Str(""),
+		"reply_to_message_id": Int(""),
+		"reply_to_text":       Str(""),
+		"language_code":       Str("Telegram client language"),
+		"sticker_emoji":       Str(""),
+		"sticker_set":         Str(""),
+		"forwarded_from":      Str("Forwarded messages: original author or post signature"),
+		"forwarded_from_chat": Str("Forwarded messages: chat or channel it was posted in"),
+		"forward_date":        Str("Forwarded messages: RFC 3339 time it was first sent"),
+		"media_group_id":      Str("Album items: Telegram's media group ID, shared by every item"),
+		"message_thread_id":   Int("Forum topic"),
+		"chat_type":           Str("").OneOf("private", "group", "supergroup", "channel"),
+		"is_command":          Bool(""),
+		"mentions_bot":        Bool("@username or text mention of the bot"),
+		"reply_to_bot":        Bool(""),
+		"addressed":           Bool("Older frontends only; read when chat_type is empty"),
+	}, "chat_id")
+}
+
+func ackBody() *Schema {
+	return Obj(map[string]*Schema{
+		"request_id": Str(""),
+		"chat_id":    Int(""),
+		"message_id": Int("").Min(1),
+		"file_id":    Str(""),
+	}, "request_id", "chat_id", "message_id")
+}
+
+func eventBody() *Schema {
+	return Obj(map[string]*Schema{
+		"type":       Str("").OneOf("poll", "poll_answer", "karma"),
+		"chat_id":    Int(""),
+		"message_id": Int(""),
+		"user_id":    Int(""),
+		"username":   Str(""),
+		"first_name": Str(""),
+		"poll": Obj(map[string]*Schema{
+			"id":                Str(""),
+			"question":          Str(""),
+			"options":           Arr(Str(""), ""),
+			"option_votes":      Arr(Int(""), ""),
+			"total_voter_count": Int(""),
+			"is_anonymous":      Bool(""),
+			"is_closed":         Bool(""),
+		}, "id"),
+		"poll_answer": Obj(map[string]*Schema{
+			"poll_id":    Str(""),
+			"option_ids": Arr(Int(""), "Empty = vote retracted"),
+		}, "poll_id"),
+		"karma": Obj(map[string]*Schema{
+			"source":         Str("").OneOf("reaction", "reply"),
+			"delta":          Int("0 = reaction removed").Range(-1, 1),
+			"target_user_id": Int("Author of the voted message, when known"),
+		}, "source"),
+	}, "type")
+}
+
+var statusOK = Obj(map[string]*Schema{"status": Str("ok")}, "status")
+
+var operations = []Operation{
+	{Method: http.MethodGet, Path: "/health", Tag: "ops", Summary: "Liveness: the process is up", Response: statusOK},
+	{Method: http.MethodGet, Path: "/ready", Tag: "ops", Summary: "Readiness: Postgres, Redis and optionally Gemini respond (503 otherwise)"},
+	{Method: http.MethodGet, Path: "/api/v1/openapi.json", Tag: "ops", Summary: "This document"},
+
+	{Method: http.MethodPost, Path: "/api/v1/process", Tag: "frontend", Summary: "Store a message and generate the reply, if the trigger policy calls for one",
+		Request: processBody(), Silent: true, Response: Obj(map[string]*Schema{
+			"reply":             Str(""),
+			"request_id":        Str(""),
+			"media_url":         Str(""),
+			"media_type":        Str(""),
+			"media_base64":      Str(""),
+			"message_thread_id": Int(""),
+			"delete":            Arr(Obj(map[string]*Schema{"chat_id": Int(""), "message_id": Int("")}, "chat_id", "message_id"), "Messages to delete after sending"),
+		}, "reply", "request_id")},
+	{Method: http.MethodPost, Path: "/api/v2/process", Tag: "frontend", Summary: "As /api/v1/process, with the reply as a segmented envelope",
+		Request: processBody(), Silent: true, Response: Obj(map[string]*Schema{
+			"request_id": Str(""),
+			"segments": Arr(Obj(map[string]*Schema{
+				"type":         Str("").OneOf("text", "photo", "document", "voice"),
+				"text":         Str("Message text, or the media caption"),
+				"media_base64": Str(""),
+				"media_url":    Str(""),
+				"mime_type":    Str(""),
+			}, "type"), "Messages to send, in order"),
+			"parse_mode":        Str("markdown: convert the text from Markdown to Telegram HTML").OneOf("markdown"),
+			"reactions":         Arr(Str(""), "Suggested reactions to the user's message"),
+			"message_thread_id": Int(""),
+			"delete":            Arr(Obj(map[string]*Schema{"chat_id": Int(""), "message_id": Int("")}, "chat_id", "message_id"), "Messages to delete after sending"),
+			"meta": Obj(map[string]*Schema{
+				"tools": Arr(Obj(map[string]*Schema{
+					"name":        Str(""),
+					"ok":          Bool(""),
+					"error":       Str(""),
+					"duration_ms": Int(""),
+				}, "name", "ok", "duration_ms"), "Tool calls made for the reply"),
+				"model":      Str(""),
+				"variant":    Str("control or canary, while a canary is configured"),
+				"latency_ms": Int("Generate/tool loop"),
+			}, "tools", "model", "latency_ms"),
+		}, "request_id", "segments", "parse_mode")},
+	{Method: http.MethodPost, Path: "/api/v1/ack_reply", Tag: "frontend", Summary: "Record the Telegram message ID of a delivered reply",
+		Request: ackBody(), Response: statusOK},
+	{Method: http.MethodPost, Path: "/api/v1/ack", Tag: "frontend", Summary: "Alias of /api/v1/ack_reply",
+		Request: ackBody(), Response: statusOK},
+	{Method: http.MethodPost, Path: "/api/v1/event", Tag: "frontend", Summary: "Ingest a poll, poll answer or karma vote", Request: eventBody(), Response: statusOK},
+	{Method: http.MethodGet, Path: "/api/v1/proactive", Tag: "frontend", Summary: "Long-poll the next proactive message", Silent: true,
+		Parameters: []Parameter{
+			{Name: "wait", In: "query", Description: "Seconds or a duration such as 30s", Schema: Str("")},
+			{Name: "ack", In: "query", Description: "Remove the item on delivery instead of waiting for /proactive/ack", Schema: Bool("")},
+		}},
+	{Method: http.MethodGet, Path: "/api/v1/proactive/stream", Tag: "frontend", Summary: "Server-sent events stream of proactive messages"},
+	{Method: http.MethodPost, Path: "/api/v1/proactive/ack", Tag: "frontend", Summary: "Acknowledge a delivered proactive message",
+		Request: Obj(map[string]*Schema{"id": Str("")}, "id"), Response: statusOK},
+	{Method: http.MethodPost, Path: "/api/v1/proactive/settings", Tag: "frontend", Summary: "/proactive command from a chat",
+		Request: Obj(map[string]*Schema{
+			"chat_id":       Int(""),
+			"user_id":       Int("Who sent the command"),
+			"is_chat_admin": Bool(""),
+			"args":          Str("Command arguments"),
+		}, "chat_id")},
+
+	{Method: http.MethodPost, Path: "/api/v1/admin/stats", Tag: "admin", Admin: true, Summary: "Server statistics", Request: admin(nil)},
+	{Method: http.MethodPost, Path: "/api/v1/admin/config", Tag: "admin", Admin: true, Summary: "Effective configuration with secrets masked", Request: admin(nil)},
+	{Method: http.MethodPost, Path: "/api/v1/admin/debug", Tag: "admin", Admin: true, Summary: "Goroutine dump, GC and heap statistics, and the configuration with secrets masked",
+		Request: admin(map[string]*Schema{"stacks": Bool("Include the goroutine dump (default true)")})},
+	{Method: http.MethodPost, Path: "/api/v1/admin/reload_persona", Tag: "admin", Admin: true, Summary: "Re-read the persona file", Request: admin(nil)},
+	{Method: http.MethodPost, Path: "/api/v1/admin/reload_locales", Tag: "admin", Admin: true, Summary: "Re-read the locale files", Request: admin(nil)},
+	{Method: http.MethodPost, Path: "/api/v1/admin/chat_settings", Tag: "admin", Admin: true, Summary: "A chat's overrides and effective settings; all chats without chat_id",
+		Request: admin(map[string]*Schema{"chat_id": Int("")})},
+	{Method: http.MethodPut, Path: "/api/v1/admin/chat_settings", Tag: "admin", Admin: true, Summary: "Replace a chat's overrides", Request: chatSettingsBody()},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/chat_settings", Tag: "admin", Admin: true, Summary: "Reset a chat to the defaults",
+		Request: admin(map[string]*Schema{"chat_id": Int("")}, "chat_id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/personas", Tag: "admin", Admin: true, Summary: "List stored personas", Request: admin(nil)},
+	{Method: http.MethodPut, Path: "/api/v1/admin/personas", Tag: "admin", Admin: true, Summary: "Create or replace a persona",
+		Request: admin(map[string]*Schema{"name": Str("a-z, 0-9, - or _"), "description": Str(""), "prompt": Str("")}, "name")},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/personas", Tag: "admin", Admin: true, Summary: "Delete a persona",
+		Request: admin(map[string]*Schema{"name": Str("")}, "name")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/off_record", Tag: "admin", Admin: true, Summary: "List a chat's off-the-record windows",
+		Request: admin(map[string]*Schema{"chat_id": Int("")}, "chat_id")},
+	{Method: http.MethodPut, Path: "/api/v1/admin/off_record", Tag: "admin", Admin: true, Summary: "Open a window, or close window id",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "id": Int(""), "starts_at": Time(""), "ends_at": Time(""), "reason": Str("")}, "chat_id")},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/off_record", Tag: "admin", Admin: true, Summary: "Remove a window",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "id": Int("")}, "chat_id", "id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/chat_topics", Tag: "admin", Admin: true, Summary: "List a chat's proactive topics",
+		Request: admin(map[string]*Schema{"chat_id": Int("")}, "chat_id")},
+	{Method: http.MethodPut, Path: "/api/v1/admin/chat_topics", Tag: "admin", Admin: true, Summary: "Add a proactive topic",
+		Request: admin(map[string]*Schema{
+			"chat_id": Int(""),
+			"kind":    Str("").OneOf("event", "joke", "follow_up"),
+			"text":    Str(""),
+			"due_at":  Str("RFC 3339 or a local date/time in the chat's timezone"),
+		}, "chat_id", "text")},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/chat_topics", Tag: "admin", Admin: true, Summary: "Remove a topic",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "id": Int("")}, "chat_id", "id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/proactive_history", Tag: "admin", Admin: true, Summary: "Delivered proactive messages of a chat",
+		Request: admin(map[string]*Schema{
+			"chat_id": Int(""),
+			"source":  Str("").OneOf("proactive", "digest", "personal_digest", "activity_report", "research"),
+			"limit":   Int("0 or omitted = 20").Range(0, 200),
+		}, "chat_id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/archives", Tag: "admin", Admin: true, Summary: "List a chat's archive files", Request: archiveBody()},
+	{Method: http.MethodPost, Path: "/api/v1/admin/archives/query", Tag: "admin", Admin: true, Summary: "Search archived messages", Request: archiveBody()},
+	{Method: http.MethodPost, Path: "/api/v1/admin/archives/restore", Tag: "admin", Admin: true, Summary: "Restore archived messages", Request: archiveBody()},
+	{Method: http.MethodPost, Path: "/api/v1/admin/block", Tag: "admin", Admin: true, Summary: "List active blocks",
+		Request: admin(map[string]*Schema{"chat_id": Int("0 or omitted = all")})},
+	{Method: http.MethodPut, Path: "/api/v1/admin/block", Tag: "admin", Admin: true, Summary: "Block a user",
+		Request: admin(map[string]*Schema{
+			"blocked_user_id": Int(""),
+			"chat_id":         Int("0 or omitted = every chat"),
+			"reason":          Str(""),
+			"expires_at":      Time("Omitted = until unblocked"),
+		}, "blocked_user_id")},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/block", Tag: "admin", Admin: true, Summary: "Lift a block",
+		Request: admin(map[string]*Schema{"blocked_user_id": Int(""), "chat_id": Int("")}, "blocked_user_id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/rate_limit_boost", Tag: "admin", Admin: true, Summary: "Active rate limit boosts of a chat",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "boost_user_id": Int("Also show this user's boost")}, "chat_id")},
+	{Method: http.MethodPut, Path: "/api/v1/admin/rate_limit_boost", Tag: "admin", Admin: true, Summary: "Raise or lift rate limits for a while",
+		Request: admin(map[string]*Schema{
+			"chat_id":       Int(""),
+			"boost_user_id": Int("0 or omitted = the whole chat"),
+			"minutes":       Int("0 or omitted = 60").Range(0, 7*24*60),
+			"factor":        Int("Limits are multiplied by this; 0 or omitted = no limit").Range(0, 100),
+		}, "chat_id")},
+	{Method: http.MethodDelete, Path: "/api/v1/admin/rate_limit_boost", Tag: "admin", Admin: true, Summary: "End a rate limit boost",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "boost_user_id": Int("")}, "chat_id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/trace/{request_id}", Tag: "admin", Admin: true, Summary: "Stored debug trace of a request (DEBUG_TRACE)",
+		Parameters: []Parameter{{Name: "request_id", In: "path", Required: true, Schema: Str("")}}, Request: admin(nil)},
+	{Method: http.MethodPost, Path: "/api/v1/admin/replay/{request_id}", Tag: "admin", Admin: true, Summary: "Regenerate one model turn of a traced request",
+		Parameters: []Parameter{{Name: "request_id", In: "path", Required: true, Schema: Str("")}},
+		Request:    admin(map[string]*Schema{"turn": Int("0 = the first answer").Min(0)})},
+	{Method: http.MethodPost, Path: "/api/v1/admin/report", Tag: "admin", Admin: true, Summary: "Activity report", Request: admin(map[string]*Schema{"days": days()})},
+	{Method: http.MethodPost, Path: "/api/v1/admin/canary", Tag: "admin", Admin: true, Summary: "Compare canary and control replies", Request: admin(map[string]*Schema{"days": days()})},
+	{Method: http.MethodPost, Path: "/api/v1/admin/tool_calls", Tag: "admin", Admin: true, Summary: "Tool call statistics per tool and chat",
+		Request: admin(map[string]*Schema{"days": days(), "chat_id": Int("0 or omitted = all chats"), "tool": Str("Omitted = all tools")})},
+	{Method: http.MethodPost, Path: "/api/v1/admin/query", Tag: "admin", Admin: true, Summary: "Run one read-only SQL statement (ENABLE_ADMIN_QUERY and ADMIN_QUERY_ROLE)",
+		Request: admin(map[string]*Schema{"sql": Str("SELECT, WITH, TABLE, VALUES, EXPLAIN or SHOW; one statement")}, "sql")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/analytics", Tag: "admin", Admin: true, Summary: "get_chat_stats for any chat",
+		Request: admin(map[string]*Schema{"chat_id": Int(""), "days": days()}, "chat_id")},
+	{Method: http.MethodPost, Path: "/api/v1/admin/user/{id}", Tag: "admin", Admin: true, Summary: "Everything stored about a user: message stats, settings, karma and facts",
+		Parameters: []Parameter{{Name: "id", In: "path", Required: true, Schema: Int("Telegram user ID")}},
+		Request:    admin(map[string]*Schema{"chat_id": Int("0 or omitted = all chats")})},
+}
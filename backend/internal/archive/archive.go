@@ -0,0 +1,314 @@
+// Package archive keeps messages removed by retention as gzip-compressed JSONL files, one
+// directory per chat, and reads them back for queries and restores.
+package archive
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	fileExt    = ".jsonl.gz"
+	timeLayout = "20060102T150405Z"
+)
+
+// ErrInvalidName is returned for archive file names that were not written by a Store.
+var ErrInvalidName = errors.New("invalid archive name")
+
+// Record is one archived message, one JSON object per line.
+type Record struct {
+	ID               int64     `json:"id"`
+	ChatID           int64     `json:"chat_id"`
+	ThreadID         int64     `json:"thread_id,omitempty"`
+	UserID           *int64    `json:"user_id,omitempty"`
+	Username         *string   `json:"username,omitempty"`
+	FirstName        *string   `json:"first_name,omitempty"`
+	Text             *string   `json:"text,omitempty"`
+	MessageID        *int64    `json:"message_id,omitempty"`
+	MediaType        *string   `json:"media_type,omitempty"`
+	FileID           *string   `json:"file_id,omitempty"`
+	IsBotReply       bool      `json:"is_bot_reply,omitempty"`
+	RequestID        *string   `json:"request_id,omitempty"`
+	WasThrottled     bool      `json:"was_throttled,omitempty"`
+	ReplyToMessageID *int64    `json:"reply_to_message_id,omitempty"`
+	StickerEmoji     *string   `json:"sticker_emoji,omitempty"`
+	StickerSet       *string   `json:"sticker_set,omitempty"`
+	Transcript       *string   `json:"transcript,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func recordOf(m db.Message) Record {
+	return Record{
+		ID: m.ID, ChatID: m.ChatID, ThreadID: m.ThreadID, UserID: m.UserID, Username: m.Username,
+		FirstName: m.FirstName, Text: m.Text, MessageID: m.MessageID, MediaType: m.MediaType,
+		FileID: m.FileID, IsBotReply: m.IsBotReply, RequestID: m.RequestID, WasThrottled: m.WasThrottled,
+		ReplyToMessageID: m.ReplyToMessageID, StickerEmoji: m.StickerEmoji, StickerSet: m.StickerSet,
+		Transcript: m.Transcript, CreatedAt: m.CreatedAt,
+	}
+}
+
+// Message converts the record back for db.RestoreMessages.
+func (r Record) Message() db.Message {
+	return db.Message{
+		ID: r.ID, ChatID: r.ChatID, ThreadID: r.ThreadID, UserID: r.UserID, Username: r.Username,
+		FirstName: r.FirstName, Text: r.Text, MessageID: r.MessageID, MediaType: r.MediaType,
+		FileID: r.FileID, IsBotReply: r.IsBotReply, RequestID: r.RequestID, WasThrottled: r.WasThrottled,
+		ReplyToMessageID: r.ReplyToMessageID, StickerEmoji: r.StickerEmoji, StickerSet: r.StickerSet,
+		Transcript: r.Transcript, CreatedAt: r.CreatedAt,
+	}
+}
+
+// File describes one archive file of a chat.
+type File struct {
+	Name string    `json:"name"`
+	Size int64     `json:"size"`
+	From time.Time `json:"from"` // oldest message in the file
+	To   time.Time `json:"to"`   // newest message in the file
+}
+
+// Query selects archived messages of a chat. Zero fields don't filter.
+type Query struct {
+	File  string    // one archive file (a File.Name)
+	From  time.Time // created at or after
+	To    time.Time // created before
+	Text  string    // case-insensitive substring of the text or transcript
+	Limit int       // at most this many, oldest first
+}
+
+// Store writes and reads archives under a directory.
+type Store struct {
+	dir    string
+	cipher *atrest.Cipher // optional; opens encrypted text (see SetCipher)
+}
+
+// NewStore creates a store rooted at dir (created on first write).
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// SetCipher decrypts text archived encrypted (as it was stored, see db.DB.SetCipher) when it is
+// queried; files keep the encrypted text. Text the cipher cannot open is returned as it is.
+func (s *Store) SetCipher(c *atrest.Cipher) {
+	s.cipher = c
+}
+
+// ArchiveMessages writes msgs to one new file per chat. Files are written under a temporary
+// name and renamed, so a crash never leaves a partial archive behind.
+func (s *Store) ArchiveMessages(ctx context.Context, msgs []db.Message) error {
+	byChat := make(map[int64][]db.Message)
+	for _, m := range msgs {
+		byChat[m.ChatID] = append(byChat[m.ChatID], m)
+	}
+	for chatID, chatMsgs := range byChat {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := s.writeFile(chatID, chatMsgs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) writeFile(chatID int64, msgs []db.Message) error {
+	from, to := msgs[0].CreatedAt, msgs[0].CreatedAt
+	for _, m := range msgs {
+		if m.CreatedAt.Before(from) {
+			from = m.CreatedAt
+		}
+		if m.CreatedAt.After(to) {
+			to = m.CreatedAt
+		}
+	}
+	dir := s.chatDir(chatID)
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return fmt.Errorf("archive mkdir: %w", err)
+	}
+	name := fmt.Sprintf("%s_%s_%d%s", from.UTC().Format(timeLayout), to.UTC().Format(timeLayout), msgs[0].ID, fileExt)
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("archive create: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after the rename
+
+	zw := gzip.NewWriter(tmp)
+	enc := json.NewEncoder(zw)
+	for _, m := range msgs {
+		if err := enc.Encode(recordOf(m)); err != nil {
+			tmp.Close()
+			return fmt.Errorf("archive write: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("archive write: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("archive sync: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("archive close: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, name)); err != nil {
+		return fmt.Errorf("archive rename: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) chatDir(chatID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(chatID, 10))
+}
+
+// parseName reads the time range from an archive file name.
+func parseName(name string) (from, to time.Time, err error) {
+	if filepath.Base(name) != name || !strings.HasSuffix(name, fileExt) {
+		return from, to, ErrInvalidName
+	}
+	parts := strings.Split(strings.TrimSuffix(name, fileExt), "_")
+	if len(parts) != 3 {
+		return from, to, ErrInvalidName
+	}
+	if from, err = time.Parse(timeLayout, parts[0]); err != nil {
+		return from, to, ErrInvalidName
+	}
+	if to, err = time.Parse(timeLayout, parts[1]); err != nil {
+		return from, to, ErrInvalidName
+	}
+	return from, to, nil
+}
+
+// List returns the archive files of a chat, oldest first.
+func (s *Store) List(chatID int64) ([]File, error) {
+	entries, err := os.ReadDir(s.chatDir(chatID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []File{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("list archives: %w", err)
+	}
+	files := []File{}
+	for _, e := range entries {
+		from, to, err := parseName(e.Name())
+		if err != nil || e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, File{Name: e.Name(), Size: info.Size(), From: from, To: to})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].From.Before(files[j].From) })
+	return files, nil
+}
+
+// Query returns the archived messages of a chat matching q, oldest first.
+func (s *Store) Query(ctx context.Context, chatID int64, q Query) ([]Record, error) {
+	files, err := s.List(chatID)
+	if err != nil {
+		return nil, err
+	}
+	if q.File != "" {
+		if _, _, err := parseName(q.File); err != nil {
+			return nil, err
+		}
+	}
+	text := strings.ToLower(q.Text)
+	out := []Record{}
+	seen := make(map[int64]bool) // restored messages that expired again are in two files
+	for _, f := range files {
+		if q.File != "" && f.Name != q.File {
+			continue
+		}
+		// Skip files entirely outside the window (names are in whole seconds).
+		if (!q.From.IsZero() && f.To.Add(time.Second).Before(q.From)) || (!q.To.IsZero() && !f.From.Before(q.To)) {
+			continue
+		}
+		err := s.readFile(filepath.Join(s.chatDir(chatID), f.Name), func(r Record) bool {
+			if (!q.From.IsZero() && r.CreatedAt.Before(q.From)) || (!q.To.IsZero() && !r.CreatedAt.Before(q.To)) {
+				return true
+			}
+			r.Text, r.Transcript = s.open(r.Text), s.open(r.Transcript)
+			if text != "" && !contains(r.Text, text) && !contains(r.Transcript, text) {
+				return true
+			}
+			if seen[r.ID] {
+				return true
+			}
+			seen[r.ID] = true
+			out = append(out, r)
+			return q.Limit <= 0 || len(out) < q.Limit
+		})
+		if err != nil {
+			return nil, err
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if q.Limit > 0 && len(out) >= q.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// open decrypts an archived text field; text the cipher cannot open is returned as it is.
+func (s *Store) open(text *string) *string {
+	if text == nil || !atrest.IsSealed(*text) {
+		return text
+	}
+	plain, err := s.cipher.Open(*text)
+	if err != nil {
+		return text
+	}
+	return &plain
+}
+
+// contains reports whether text holds the lower-cased substring sub, ignoring case.
+func contains(text *string, sub string) bool {
+	return text != nil && strings.Contains(strings.ToLower(*text), sub)
+}
+
+// readFile calls fn for each record until it returns false.
+func (s *Store) readFile(path string, fn func(Record) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open archive: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("read archive %s: %w", filepath.Base(path), err)
+	}
+	defer zr.Close()
+
+	sc := bufio.NewScanner(zr)
+	sc.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			return fmt.Errorf("read archive %s: %w", filepath.Base(path), err)
+		}
+		if !fn(r) {
+			return nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("read archive %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
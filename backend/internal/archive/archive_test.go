@@ -0,0 +1,172 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func msg(id, chatID int64, text string, at time.Time) db.Message {
+	return db.Message{ID: id, ChatID: chatID, Text: &text, CreatedAt: at}
+}
+
+func TestStore_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	s := NewStore(t.TempDir())
+	day := func(d int) time.Time { return time.Date(2026, 1, d, 12, 0, 0, 0, time.UTC) }
+
+	if err := s.ArchiveMessages(ctx, []db.Message{
+		msg(1, 10, "hello there", day(1)),
+		msg(2, 10, "second", day(2)),
+		msg(3, 20, "other chat", day(1)),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ArchiveMessages(ctx, []db.Message{msg(4, 10, "Hello again", day(5))}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := s.List(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || !files[0].From.Equal(day(1)) || !files[0].To.Equal(day(2)) || files[0].Size == 0 {
+		t.Fatalf("unexpected files: %+v", files)
+	}
+	if files, _ := s.List(99); len(files) != 0 {
+		t.Errorf("expected no files for an unknown chat, got %+v", files)
+	}
+
+	all, err := s.Query(ctx, 10, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 || all[0].ID != 1 || all[2].ID != 4 || *all[0].Text != "hello there" {
+		t.Fatalf("unexpected records: %+v", all)
+	}
+	if m := all[0].Message(); m.ID != 1 || m.ChatID != 10 || !m.CreatedAt.Equal(day(1)) {
+		t.Errorf("record does not convert back: %+v", m)
+	}
+
+	hits, _ := s.Query(ctx, 10, Query{Text: "HELLO"})
+	if len(hits) != 2 {
+		t.Errorf("expected 2 case-insensitive matches, got %d", len(hits))
+	}
+	window, _ := s.Query(ctx, 10, Query{From: day(2), To: day(6)})
+	if len(window) != 2 || window[0].ID != 2 {
+		t.Errorf("expected messages 2 and 4 in the window, got %+v", window)
+	}
+	one, _ := s.Query(ctx, 10, Query{File: files[1].Name})
+	if len(one) != 1 || one[0].ID != 4 {
+		t.Errorf("expected only message 4 from the second file, got %+v", one)
+	}
+	limited, _ := s.Query(ctx, 10, Query{Limit: 1})
+	if len(limited) != 1 {
+		t.Errorf("expected the limit to apply, got %d", len(limited))
+	}
+}
+
+func TestStore_EncryptedText(t *testing.T) {
+	ctx := context.Background()
+	c, err := atrest.New(bytes.Repeat([]byte{3}, atrest.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(t.TempDir())
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := s.ArchiveMessages(ctx, []db.Message{msg(1, 10, c.Seal("secret plans"), at)}); err != nil {
+		t.Fatal(err)
+	}
+
+	if hits, _ := s.Query(ctx, 10, Query{Text: "plans"}); len(hits) != 0 {
+		t.Errorf("encrypted text should not match without the key, got %+v", hits)
+	}
+	s.SetCipher(c)
+	hits, err := s.Query(ctx, 10, Query{Text: "plans"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 1 || *hits[0].Text != "secret plans" {
+		t.Errorf("expected the decrypted message, got %+v", hits)
+	}
+	files, _ := s.List(10)
+	f, err := os.Open(filepath.Join(s.chatDir(10), files[0].Name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(zr)
+	if !bytes.Contains(raw, []byte(atrest.Prefix)) || bytes.Contains(raw, []byte("secret")) {
+		t.Error("the archive file should keep the encrypted text")
+	}
+}
+
+func TestStore_Transcript(t *testing.T) {
+	ctx := context.Background()
+	c, err := atrest.New(bytes.Repeat([]byte{4}, atrest.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(t.TempDir())
+	at := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	voice := msg(1, 10, "", at)
+	voice.Text = nil
+	plain := "зустрічаємось о сьомій"
+	voice.Transcript = &plain
+	sealed := msg(2, 10, "", at.Add(time.Minute))
+	sealed.Text = nil
+	secret := c.Seal("secret voice plans")
+	sealed.Transcript = &secret
+	if err := s.ArchiveMessages(ctx, []db.Message{voice, sealed}); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetCipher(c)
+	all, err := s.Query(ctx, 10, Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || all[0].Transcript == nil || *all[0].Transcript != plain || *all[1].Transcript != "secret voice plans" {
+		t.Fatalf("expected both transcripts back, got %+v", all)
+	}
+	if m := all[0].Message(); m.Transcript == nil || *m.Transcript != plain {
+		t.Errorf("transcript lost converting back for a restore: %+v", m)
+	}
+	if hits, _ := s.Query(ctx, 10, Query{Text: "VOICE"}); len(hits) != 1 || hits[0].ID != 2 {
+		t.Errorf("expected the text filter to search transcripts, got %+v", hits)
+	}
+}
+
+func TestStore_InvalidName(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, name := range []string{"../10/x.jsonl.gz", "notes.txt", "a_b_c.jsonl.gz"} {
+		if _, err := s.Query(context.Background(), 10, Query{File: name}); !errors.Is(err, ErrInvalidName) {
+			t.Errorf("%q: expected ErrInvalidName, got %v", name, err)
+		}
+	}
+}
+
+func TestStore_NoPartialFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	if err := s.ArchiveMessages(context.Background(), []db.Message{msg(1, 10, "x", time.Now())}); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(filepath.Join(dir, "10"))
+	if len(entries) != 1 || filepath.Ext(entries[0].Name()) != ".gz" {
+		t.Errorf("expected exactly one archive and no temp files, got %v", entries)
+	}
+}
@@ -0,0 +1,162 @@
+// Package atrest encrypts text stored in the database (message text, transcripts and user facts)
+// with AES-256-GCM, so a leaked dump or backup does not expose the conversation.
+package atrest
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Prefix marks an encrypted value: Prefix + base64(key ID || nonce || ciphertext). Values without
+// it are plaintext (rows stored before encryption was enabled) and are read as they are.
+const Prefix = "enc1:"
+
+// KeySize is the length of an AES-256 key.
+const KeySize = 32
+
+const keyIDSize = 4
+
+// ErrUnknownKey is returned for a value sealed with a key the Cipher was not given.
+var ErrUnknownKey = errors.New("encrypted with an unknown key")
+
+// Cipher seals text with its current key and opens text sealed with it or any previous key. A nil
+// *Cipher seals nothing and opens only plaintext.
+type Cipher struct {
+	current  [keyIDSize]byte
+	aeads    map[[keyIDSize]byte]cipher.AEAD
+	nonceKey []byte // derives the nonces of SealDeterministic
+}
+
+// New returns a Cipher that seals with key and can still open values sealed with previous keys
+// (for key rotation). Keys are KeySize bytes.
+func New(key []byte, previous ...[]byte) (*Cipher, error) {
+	c := &Cipher{aeads: make(map[[keyIDSize]byte]cipher.AEAD)}
+	for i, k := range append([][]byte{key}, previous...) {
+		if len(k) != KeySize {
+			return nil, fmt.Errorf("encryption key %d: %d bytes, want %d", i, len(k), KeySize)
+		}
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", i, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %d: %w", i, err)
+		}
+		id := keyID(k)
+		if i == 0 {
+			c.current = id
+			mac := hmac.New(sha256.New, k)
+			mac.Write([]byte("gryag at-rest nonce"))
+			c.nonceKey = mac.Sum(nil)
+		}
+		if _, dup := c.aeads[id]; !dup {
+			c.aeads[id] = aead
+		}
+	}
+	return c, nil
+}
+
+// ParseKey decodes a key given as base64 (standard or URL alphabet, padded or not) or hex.
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if len(s) == 2*KeySize {
+		if k, err := hex.DecodeString(s); err == nil {
+			return k, nil
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if k, err := enc.DecodeString(s); err == nil {
+			if len(k) != KeySize {
+				return nil, fmt.Errorf("key is %d bytes, want %d", len(k), KeySize)
+			}
+			return k, nil
+		}
+	}
+	return nil, errors.New("key is neither base64 nor hex")
+}
+
+func keyID(key []byte) [keyIDSize]byte {
+	sum := sha256.Sum256(key)
+	var id [keyIDSize]byte
+	copy(id[:], sum[:])
+	return id
+}
+
+// IsSealed reports whether s is an encrypted value.
+func IsSealed(s string) bool {
+	return strings.HasPrefix(s, Prefix)
+}
+
+// Seal encrypts text under a random nonce. Empty text stays empty.
+func (c *Cipher) Seal(text string) string {
+	if c == nil || text == "" {
+		return text
+	}
+	nonce := make([]byte, c.aeads[c.current].NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		panic(fmt.Sprintf("atrest: read nonce: %v", err)) // crypto/rand does not fail on supported platforms
+	}
+	return c.seal(nonce, text)
+}
+
+// SealDeterministic encrypts text under a nonce derived from it, so equal texts give equal values.
+// It is for columns that are compared or indexed for uniqueness (user_facts.fact_text); it reveals
+// which rows hold the same text, and nothing else.
+func (c *Cipher) SealDeterministic(text string) string {
+	if c == nil || text == "" {
+		return text
+	}
+	mac := hmac.New(sha256.New, c.nonceKey)
+	mac.Write([]byte(text))
+	return c.seal(mac.Sum(nil)[:c.aeads[c.current].NonceSize()], text)
+}
+
+func (c *Cipher) seal(nonce []byte, text string) string {
+	aead := c.aeads[c.current]
+	buf := make([]byte, 0, keyIDSize+len(nonce)+len(text)+aead.Overhead())
+	buf = append(buf, c.current[:]...)
+	buf = append(buf, nonce...)
+	buf = aead.Seal(buf, nonce, []byte(text), c.current[:])
+	return Prefix + base64.RawStdEncoding.EncodeToString(buf)
+}
+
+// Open decrypts a sealed value; plaintext is returned as it is.
+func (c *Cipher) Open(stored string) (string, error) {
+	if !IsSealed(stored) {
+		return stored, nil
+	}
+	raw, err := base64.RawStdEncoding.DecodeString(stored[len(Prefix):])
+	if err != nil {
+		return "", fmt.Errorf("decode encrypted value: %w", err)
+	}
+	if len(raw) < keyIDSize {
+		return "", errors.New("encrypted value is truncated")
+	}
+	var id [keyIDSize]byte
+	copy(id[:], raw)
+	var aead cipher.AEAD
+	if c != nil {
+		aead = c.aeads[id]
+	}
+	if aead == nil {
+		return "", ErrUnknownKey
+	}
+	raw = raw[keyIDSize:]
+	if len(raw) < aead.NonceSize()+aead.Overhead() {
+		return "", errors.New("encrypted value is truncated")
+	}
+	text, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], id[:])
+	if err != nil {
+		return "", fmt.Errorf("decrypt: %w", err)
+	}
+	return string(text), nil
+}
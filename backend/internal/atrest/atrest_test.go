@@ -0,0 +1,98 @@
+package atrest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) []byte { return bytes.Repeat([]byte{b}, KeySize) }
+
+func TestSealOpen(t *testing.T) {
+	c, err := New(testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "Олег, завтра о 19:00 біля фонтану"
+	sealed := c.Seal(text)
+	if !IsSealed(sealed) || strings.Contains(sealed, "фонтану") {
+		t.Fatalf("not sealed: %q", sealed)
+	}
+	if sealed == c.Seal(text) {
+		t.Error("Seal should use a fresh nonce each time")
+	}
+	if got, err := c.Open(sealed); err != nil || got != text {
+		t.Errorf("Open = %q, %v", got, err)
+	}
+	if got, err := c.Open("plain old row"); err != nil || got != "plain old row" {
+		t.Errorf("plaintext should pass through: %q, %v", got, err)
+	}
+	if c.Seal("") != "" {
+		t.Error("empty text should stay empty")
+	}
+	tampered := sealed[:len(sealed)-2] + "AA"
+	if _, err := c.Open(tampered); err == nil {
+		t.Error("expected an error for a tampered value")
+	}
+}
+
+func TestSealDeterministic(t *testing.T) {
+	c, _ := New(testKey(1))
+	a, b := c.SealDeterministic("likes borscht"), c.SealDeterministic("likes borscht")
+	if a != b {
+		t.Error("equal texts should seal to equal values")
+	}
+	if a == c.SealDeterministic("likes varenyky") {
+		t.Error("different texts sealed to the same value")
+	}
+	if got, _ := c.Open(a); got != "likes borscht" {
+		t.Errorf("Open = %q", got)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	old, _ := New(testKey(1))
+	sealed := old.Seal("secret")
+	rotated, err := New(testKey(2), testKey(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := rotated.Open(sealed); err != nil || got != "secret" {
+		t.Errorf("Open with a previous key = %q, %v", got, err)
+	}
+	fresh, _ := New(testKey(2))
+	if _, err := fresh.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("err = %v, want ErrUnknownKey", err)
+	}
+	var none *Cipher
+	if none.Seal("x") != "x" {
+		t.Error("nil Cipher should not seal")
+	}
+	if _, err := none.Open(sealed); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("nil Cipher: err = %v, want ErrUnknownKey", err)
+	}
+	if _, err := New(make([]byte, 16)); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	key := testKey(7)
+	for _, s := range []string{
+		base64.StdEncoding.EncodeToString(key),
+		base64.RawURLEncoding.EncodeToString(key),
+		hex.EncodeToString(key) + "\n",
+	} {
+		if got, err := ParseKey(s); err != nil || !bytes.Equal(got, key) {
+			t.Errorf("ParseKey(%q) = %x, %v", s, got, err)
+		}
+	}
+	for _, s := range []string{"", "not a key!", base64.StdEncoding.EncodeToString(key[:16])} {
+		if _, err := ParseKey(s); err == nil {
+			t.Errorf("ParseKey(%q): expected an error", s)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Degraded reports whether the last Redis command failed to reach Redis. It clears on the next
+// command that gets an answer (including /ready's ping). A nil *Cache is never degraded.
+func (c *Cache) Degraded() bool {
+	return c != nil && c.downSince.Load() != 0
+}
+
+// DownSince returns when Redis became unreachable, or the zero time when it is up.
+func (c *Cache) DownSince() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	if ns := c.downSince.Load(); ns != 0 {
+		return time.Unix(0, ns)
+	}
+	return time.Time{}
+}
+
+// record updates the health flag after a command: replies (including errors Redis itself
+// returns, and misses) mean it is up; connection errors and timeouts mean it is down. A caller
+// giving up (context canceled) says nothing about Redis.
+func (c *Cache) record(err error) {
+	var replyErr redis.Error
+	switch {
+	case err == nil, errors.Is(err, redis.Nil), errors.As(err, &replyErr):
+		if since := c.downSince.Swap(0); since != 0 {
+			slog.Info("redis reachable again, leaving degraded mode", "down_for", time.Since(time.Unix(0, since)).Round(time.Second))
+		}
+	case errors.Is(err, context.Canceled):
+	default:
+		if c.downSince.CompareAndSwap(0, time.Now().UnixNano()) {
+			slog.Error("redis unreachable, entering degraded mode", "error", err)
+		}
+	}
+}
+
+// healthHook feeds every command and pipeline result into Cache.record.
+type healthHook struct{ c *Cache }
+
+func (h healthHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			h.c.record(err)
+		}
+		return conn, err
+	}
+}
+
+func (h healthHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		err := next(ctx, cmd)
+		h.c.record(err)
+		return err
+	}
+}
+
+func (h healthHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		err := next(ctx, cmds)
+		h.c.record(err)
+		return err
+	}
+}
@@ -0,0 +1,47 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRecord(t *testing.T) {
+	c := &Cache{}
+	c.record(redis.Nil)
+	if c.Degraded() {
+		t.Fatal("a cache miss must not mark Redis down")
+	}
+	c.record(errors.New("dial tcp 10.0.0.5:6379: connect: connection refused"))
+	since := c.DownSince()
+	if !c.Degraded() || since.IsZero() {
+		t.Fatal("expected a connection error to mark Redis down")
+	}
+	c.record(errors.New("i/o timeout"))
+	if !c.DownSince().Equal(since) {
+		t.Error("expected the first failure time to be kept")
+	}
+	c.record(context.Canceled)
+	c.record(redis.Nil)
+	if c.Degraded() {
+		t.Error("expected a reply to clear the flag")
+	}
+	if (*Cache)(nil).Degraded() {
+		t.Error("a nil cache is never degraded")
+	}
+}
+
+func TestHealthHook_Unreachable(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	c := Wrap(client)
+
+	if err := c.Ping(context.Background()); err == nil {
+		t.Skip("something is listening on port 1")
+	}
+	if !c.Degraded() {
+		t.Error("expected a failed ping to mark Redis down")
+	}
+}
@@ -6,16 +6,18 @@ import (
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
-const proactiveQueueKey = "proactive:queue"
-
 // Cache wraps the Redis client for rate-limiting and state management.
 type Cache struct {
-	client *redis.Client
+	client    *redis.Client
+	downSince atomic.Int64 // unix nanoseconds since Redis became unreachable; 0 = up (see Degraded)
 }
 
 // New creates a new Redis cache connection.
@@ -34,7 +36,15 @@ func New(addr, password string) (*Cache, error) {
 	}
 
 	slog.Info("redis connected", "addr", addr)
-	return &Cache{client: client}, nil
+	return Wrap(client), nil
+}
+
+// Wrap returns a Cache over an existing client without checking the connection. The client's
+// commands then feed Degraded.
+func Wrap(client *redis.Client) *Cache {
+	c := &Cache{client: client}
+	client.AddHook(healthHook{c})
+	return c
 }
 
 // Close shuts down the Redis connection.
@@ -42,88 +52,374 @@ func (c *Cache) Close() error {
 	return c.client.Close()
 }
 
+// Ping checks that Redis is reachable.
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx).Err()
+}
+
 // Client returns the underlying redis client for advanced use.
 func (c *Cache) Client() *redis.Client {
 	return c.client
 }
 
-// ── Sliding Window Rate Limiter (Section 10) ────────────────────────────
+// ── JSON value cache ────────────────────────────────────────────────────
 
-// RateLimitResult holds the outcome of a rate limit check.
-type RateLimitResult struct {
-	Allowed   bool
-	Remaining int
-	RetryIn   time.Duration
+// GetJSON loads key and unmarshals it into v. Returns false (and no error) on a cache miss.
+func (c *Cache) GetJSON(ctx context.Context, key string, v any) (bool, error) {
+	val, err := c.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("cache get %s: %w", key, err)
+	}
+	if err := json.Unmarshal(val, v); err != nil {
+		return false, fmt.Errorf("cache decode %s: %w", key, err)
+	}
+	return true, nil
 }
 
-// CheckRateLimit implements a sliding window rate limiter using Redis sorted sets.
-// key: the rate limit bucket (e.g., "rl:chat:12345" or "rl:user:67890")
-// limit: max allowed requests in the window
-// window: the sliding window duration
-func (c *Cache) CheckRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error) {
-	now := time.Now()
-	nowMs := now.UnixMilli()
-	windowStartMs := now.Add(-window).UnixMilli()
+// SetJSON marshals v and stores it under key with the given TTL (0 = no expiry).
+func (c *Cache) SetJSON(ctx context.Context, key string, v any, ttl time.Duration) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("cache encode %s: %w", key, err)
+	}
+	return c.client.Set(ctx, key, b, ttl).Err()
+}
 
-	// Use a pipeline for atomicity
-	pipe := c.client.Pipeline()
+// Delete removes the given keys (write-through invalidation).
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.client.Del(ctx, keys...).Err()
+}
 
-	// Remove expired entries outside the window
-	pipe.ZRemRangeByScore(ctx, key, "-inf", strconv.FormatInt(windowStartMs, 10))
+// ── Counters ────────────────────────────────────────────────────────────
 
-	// Count current entries in the window
-	countCmd := pipe.ZCard(ctx, key)
+// GetCount returns the integer stored at key, or 0 if it does not exist.
+func (c *Cache) GetCount(ctx context.Context, key string) (int64, error) {
+	n, err := c.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return n, err
+}
 
-	// Add the current request
-	pipe.ZAdd(ctx, key, redis.Z{
-		Score:  float64(nowMs),
-		Member: strconv.FormatInt(nowMs, 10),
-	})
+// GetCounts returns the integers stored at keys in one round trip (0 for missing keys).
+func (c *Cache) GetCounts(ctx context.Context, keys ...string) ([]int64, error) {
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+	counts := make([]int64, len(keys))
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		if counts[i], err = strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("counter %s: %w", keys[i], err)
+		}
+	}
+	return counts, nil
+}
 
-	// Set TTL on the key to auto-cleanup
-	pipe.Expire(ctx, key, window+time.Second)
+// IncrCount increments the counter at key and sets ttl when the key is new.
+func (c *Cache) IncrCount(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := c.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
 
-	_, err := pipe.Exec(ctx)
+// ── Capped lists (write-through cache of recent items) ──────────────────
+//
+// A capped list holds the newest items of something stored elsewhere, oldest first. Writers
+// append to it (and bump its version counter); readers fill it on a miss, but only if the
+// version is still the one they read before loading, so a fill computed before a concurrent
+// append cannot drop that append.
+
+// fillListScript: KEYS[1] list, KEYS[2] version; ARGV[1] expected version, ARGV[2] TTL in ms,
+// ARGV[3..] items.
+var fillListScript = redis.NewScript(`
+if tonumber(redis.call("GET", KEYS[2]) or "0") ~= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call("DEL", KEYS[1])
+redis.call("RPUSH", KEYS[1], unpack(ARGV, 3))
+redis.call("PEXPIRE", KEYS[1], ARGV[2])
+return 1`)
+
+// ListTail returns the last n items of the list at key, oldest first (none if it does not exist).
+func (c *Cache) ListTail(ctx context.Context, key string, n int) ([]string, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	items, err := c.client.LRange(ctx, key, int64(-n), -1).Result()
 	if err != nil {
-		return nil, fmt.Errorf("rate limit check: %w", err)
+		return nil, fmt.Errorf("cache list %s: %w", key, err)
 	}
+	return items, nil
+}
 
-	count := int(countCmd.Val())
+// FillList replaces the list at key with items for ttl, unless the counter at versionKey has
+// moved past version in the meantime. Returns whether the list was written.
+func (c *Cache) FillList(ctx context.Context, key, versionKey string, version int64, items []string, ttl time.Duration) (bool, error) {
+	if len(items) == 0 {
+		return false, nil
+	}
+	args := make([]any, 0, len(items)+2)
+	args = append(args, version, ttl.Milliseconds())
+	for _, it := range items {
+		args = append(args, it)
+	}
+	n, err := fillListScript.Run(ctx, c.client, []string{key, versionKey}, args...).Int()
+	if err != nil {
+		return false, fmt.Errorf("cache fill %s: %w", key, err)
+	}
+	return n == 1, nil
+}
 
-	if count >= limit {
-		// Find the oldest entry to calculate retry time
-		oldest, err := c.client.ZRangeWithScores(ctx, key, 0, 0).Result()
-		if err != nil || len(oldest) == 0 {
-			return &RateLimitResult{Allowed: false, Remaining: 0, RetryIn: window}, nil
-		}
-		oldestMs := int64(oldest[0].Score)
-		retryIn := time.Duration(oldestMs+window.Milliseconds()-nowMs) * time.Millisecond
-		if retryIn < 0 {
-			retryIn = time.Second
-		}
+// AppendList bumps versionKey (kept for versionTTL) and, if the list at key exists, appends item
+// and trims the list to its newest max items.
+func (c *Cache) AppendList(ctx context.Context, key, versionKey, item string, max int, versionTTL time.Duration) error {
+	pipe := c.client.TxPipeline()
+	pipe.Incr(ctx, versionKey)
+	pipe.Expire(ctx, versionKey, versionTTL)
+	pipe.RPushX(ctx, key, item)
+	pipe.LTrim(ctx, key, int64(-max), -1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("cache append %s: %w", key, err)
+	}
+	return nil
+}
+
+// DropList bumps versionKey (kept for versionTTL) and deletes the list at key, for writes that
+// change items already in it.
+func (c *Cache) DropList(ctx context.Context, key, versionKey string, versionTTL time.Duration) error {
+	pipe := c.client.TxPipeline()
+	pipe.Incr(ctx, versionKey)
+	pipe.Expire(ctx, versionKey, versionTTL)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("cache drop %s: %w", key, err)
+	}
+	return nil
+}
+
+// ── Album buffer (media groups) ─────────────────────────────────────────
+//
+// Telegram delivers an album as one message per item sharing a media_group_id. Each item is
+// appended to a list; the first one in collects the rest and answers for the whole album.
+
+func albumKey(chatID int64, groupID string) string {
+	return fmt.Sprintf("album:%d:%s", chatID, groupID)
+}
+
+// AddAlbumPart appends an item to the album buffer of a media group, kept for ttl, and returns
+// how many items it holds now. 1 means the caller is the first and collects the album.
+func (c *Cache) AddAlbumPart(ctx context.Context, chatID int64, groupID, part string, ttl time.Duration) (int64, error) {
+	key := albumKey(chatID, groupID)
+	pipe := c.client.TxPipeline()
+	n := pipe.RPush(ctx, key, part)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("album add %s: %w", key, err)
+	}
+	return n.Val(), nil
+}
 
-		// Remove the entry we just added since we're denying
-		c.client.ZRem(ctx, key, strconv.FormatInt(nowMs, 10))
+// AlbumSize returns how many items the album buffer of a media group holds.
+func (c *Cache) AlbumSize(ctx context.Context, chatID int64, groupID string) (int64, error) {
+	n, err := c.client.LLen(ctx, albumKey(chatID, groupID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("album size: %w", err)
+	}
+	return n, nil
+}
 
-		return &RateLimitResult{
-			Allowed:   false,
-			Remaining: 0,
-			RetryIn:   retryIn,
-		}, nil
+// TakeAlbum returns the items of a media group's album buffer in arrival order and deletes it,
+// so an item arriving later starts a new album.
+func (c *Cache) TakeAlbum(ctx context.Context, chatID int64, groupID string) ([]string, error) {
+	key := albumKey(chatID, groupID)
+	pipe := c.client.TxPipeline()
+	items := pipe.LRange(ctx, key, 0, -1)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("album take %s: %w", key, err)
 	}
+	return items.Val(), nil
+}
+
+// ── Token Bucket Rate Limiter (Section 10) ─────────────────────────────
+
+// RateLimitResult holds the outcome of a rate limit check.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int // bucket size (burst)
+	Remaining int // whole tokens left
+	RetryIn   time.Duration
+}
+
+// Bucket is a token bucket: it holds up to Burst tokens and refills Rate tokens every Per.
+// A full bucket allows a burst of Burst requests, then Rate per Per.
+type Bucket struct {
+	Rate  int
+	Per   time.Duration
+	Burst int
+}
 
+// tokenBucketScript: KEYS[1] bucket hash (tokens, ts); ARGV[1] refill in tokens per ms, ARGV[2]
+// burst. Uses the Redis clock so backends with skewed clocks share one bucket fairly. Returns
+// {allowed, tokens left, ms until the next token}.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local t = redis.call("TIME")
+local now = t[1] * 1000 + math.floor(t[2] / 1000)
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+if now > ts then
+	tokens = math.min(burst, tokens + (now - ts) * rate)
+end
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate)
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], math.ceil(burst / rate) + 1000)
+return {allowed, tostring(tokens), wait}`)
+
+// TakeToken takes one token from the bucket at key, in a single EVALSHA. A bucket with no
+// refill rate allows nothing.
+func (c *Cache) TakeToken(ctx context.Context, key string, b Bucket) (*RateLimitResult, error) {
+	if b.Rate <= 0 || b.Per <= 0 {
+		return &RateLimitResult{Allowed: false, RetryIn: b.Per}, nil
+	}
+	if b.Burst < 1 {
+		b.Burst = 1
+	}
+	perMs := float64(b.Rate) / float64(b.Per.Milliseconds())
+	res, err := tokenBucketScript.Run(ctx, c.client, []string{key}, perMs, b.Burst).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check: %w", err)
+	}
+	if len(res) != 3 {
+		return nil, fmt.Errorf("rate limit check: unexpected reply %v", res)
+	}
+	allowed, _ := res[0].(int64)
+	tokensStr, _ := res[1].(string)
+	wait, _ := res[2].(int64)
+	tokens, err := strconv.ParseFloat(tokensStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check: tokens %q: %w", tokensStr, err)
+	}
 	return &RateLimitResult{
-		Allowed:   true,
-		Remaining: limit - count - 1,
+		Allowed:   allowed == 1,
+		Limit:     b.Burst,
+		Remaining: int(tokens),
+		RetryIn:   time.Duration(wait) * time.Millisecond,
 	}, nil
 }
 
+// CheckRateLimit allows limit requests per window, and bursts of up to limit: a token bucket of
+// size limit refilled over window.
+func (c *Cache) CheckRateLimit(ctx context.Context, key string, limit int, window time.Duration) (*RateLimitResult, error) {
+	return c.TakeToken(ctx, key, Bucket{Rate: limit, Per: window, Burst: limit})
+}
+
+// RateBoost temporarily raises (Factor > 1) or lifts (Factor 0) the rate limits of a chat, or of
+// one user in it (UserID != 0).
+type RateBoost struct {
+	ChatID int64     `json:"chat_id"`
+	UserID int64     `json:"user_id,omitempty"`
+	Factor int       `json:"factor"` // limits are multiplied by this; 0 = no limit
+	Until  time.Time `json:"until"`
+	SetBy  int64     `json:"set_by"` // admin who set it
+}
+
+// Limit applies the boost to a base limit; ok is false when the limit is lifted.
+func (b *RateBoost) Limit(base int) (limit int, ok bool) {
+	if b == nil {
+		return base, true
+	}
+	if b.Factor == 0 {
+		return 0, false
+	}
+	return base * b.Factor, true
+}
+
+func boostKey(chatID, userID int64) string {
+	if userID == 0 {
+		return fmt.Sprintf("rl:boost:%d", chatID)
+	}
+	return fmt.Sprintf("rl:boost:%d:%d", chatID, userID)
+}
+
+// SetRateBoost stores b until b.Until, replacing any boost of the same chat (user).
+func (c *Cache) SetRateBoost(ctx context.Context, b *RateBoost) error {
+	ttl := time.Until(b.Until)
+	if ttl <= 0 {
+		return fmt.Errorf("rate boost already expired")
+	}
+	return c.SetJSON(ctx, boostKey(b.ChatID, b.UserID), b, ttl)
+}
+
+// ClearRateBoost removes the boost of a chat (userID 0) or of one user in it.
+func (c *Cache) ClearRateBoost(ctx context.Context, chatID, userID int64) error {
+	return c.Delete(ctx, boostKey(chatID, userID))
+}
+
+// RateBoosts returns the active boosts of a chat and of one user in it (nil when none) in one
+// round trip. userID 0 looks up the chat only.
+func (c *Cache) RateBoosts(ctx context.Context, chatID, userID int64) (chat, user *RateBoost, err error) {
+	keys := []string{boostKey(chatID, 0)}
+	if userID != 0 {
+		keys = append(keys, boostKey(chatID, userID))
+	}
+	vals, err := c.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, nil, fmt.Errorf("rate boosts: %w", err)
+	}
+	boosts := make([]*RateBoost, 2)
+	for i, v := range vals {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var b RateBoost
+		if err := json.Unmarshal([]byte(s), &b); err != nil {
+			return nil, nil, fmt.Errorf("rate boost %s: %w", keys[i], err)
+		}
+		boosts[i] = &b
+	}
+	return boosts[0], boosts[1], nil
+}
+
 // ── Queue Lock (Exclusive Processing per chat, Section 10) ──────────────
 
-// AcquireLock attempts to acquire an exclusive processing lock for a chat.
+// lockKey is the queue lock key for a chat, or for one forum topic of it (threadID != 0),
+// so topics of a forum group are processed independently.
+func lockKey(chatID, threadID int64) string {
+	if threadID == 0 {
+		return fmt.Sprintf("lock:chat:%d", chatID)
+	}
+	return fmt.Sprintf("lock:chat:%d:%d", chatID, threadID)
+}
+
+// AcquireLock attempts to acquire an exclusive processing lock for a chat (forum topic).
 // Returns true if the lock was acquired, false if another request is already being processed.
-func (c *Cache) AcquireLock(ctx context.Context, chatID int64, ttl time.Duration) (bool, error) {
-	key := fmt.Sprintf("lock:chat:%d", chatID)
+func (c *Cache) AcquireLock(ctx context.Context, chatID, threadID int64, ttl time.Duration) (bool, error) {
+	key := lockKey(chatID, threadID)
 	ok, err := c.client.SetNX(ctx, key, "locked", ttl).Result()
 	if err != nil {
 		return false, fmt.Errorf("acquire lock: %w", err)
@@ -131,39 +427,260 @@ func (c *Cache) AcquireLock(ctx context.Context, chatID int64, ttl time.Duration
 	return ok, nil
 }
 
-// ReleaseLock releases the exclusive processing lock for a chat.
-func (c *Cache) ReleaseLock(ctx context.Context, chatID int64) error {
-	key := fmt.Sprintf("lock:chat:%d", chatID)
+// ReleaseLock releases the exclusive processing lock for a chat (forum topic).
+func (c *Cache) ReleaseLock(ctx context.Context, chatID, threadID int64) error {
+	key := lockKey(chatID, threadID)
 	return c.client.Del(ctx, key).Err()
 
 }
 
+// ── Scheduler Job Locks (one replica per scheduled run) ─────────────────
+
+// JobLock is a held scheduler job lock (see AcquireJobLock).
+type JobLock struct {
+	key   string
+	token string
+}
+
+// releaseJobLockScript deletes a job lock only if it still holds our token, so a run that
+// outlived its TTL cannot release a lock another replica has since taken.
+var releaseJobLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireJobLock takes the cluster-wide lock for a scheduled job run, so that with several
+// replicas exactly one executes it. Returns nil (and no error) when another replica holds it.
+// For once-per-slot jobs put the slot in the name (e.g. "summarizer:2026-03-09T03") and let the
+// lock expire instead of releasing it.
+func (c *Cache) AcquireJobLock(ctx context.Context, name string, ttl time.Duration) (*JobLock, error) {
+	l := &JobLock{key: "job:lock:" + name, token: uuid.NewString()}
+	ok, err := c.client.SetNX(ctx, l.key, l.token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("acquire job lock: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return l, nil
+}
+
+// ReleaseJobLock releases a lock taken with AcquireJobLock, if it is still ours.
+func (c *Cache) ReleaseJobLock(ctx context.Context, l *JobLock) error {
+	if l == nil {
+		return nil
+	}
+	if err := releaseJobLockScript.Run(ctx, c.client, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("release job lock: %w", err)
+	}
+	return nil
+}
+
 // ── Proactive message queue ─────────────────────────────────────────────
 
+// The queue is a Redis stream read through a consumer group: a popped item stays pending until
+// it is acknowledged, and pending items idle for longer than the ack timeout are handed out
+// again, so nothing is lost when the frontend dies between popping and sending.
+const (
+	proactiveStreamKey = "proactive:stream"
+	proactiveGroup     = "frontend"
+	proactiveConsumer  = "backend"
+	proactiveMaxLen    = 10000
+)
+
+// Proactive item kinds.
+const (
+	ProactiveMessage = "message" // text to send to ChatID
+)
+
+// Where a proactive item came from (ProactiveItem.Source), as kept in the delivery history.
+const (
+	SourceProactive      = "proactive"       // the proactive runner
+	SourceDigest         = "digest"          // morning chat digest
+	SourcePersonalDigest = "personal_digest" // per-user digest in a private chat
+	SourceActivityReport = "activity_report" // admin activity report
+	SourceResearch       = "research"        // deep research result
+)
+
 // ProactiveItem is one queued proactive message for the frontend to send.
 type ProactiveItem struct {
+	ID     string `json:"id,omitempty"`     // stream entry ID, set when popped; pass it to AckProactive
+	Kind   string `json:"kind,omitempty"`   // ProactiveMessage when empty
+	Source string `json:"source,omitempty"` // Source* constant; SourceProactive when empty
 	ChatID int64  `json:"chat_id"`
-	Reply  string `json:"reply"`
+	Reply  string `json:"reply"` // the caption when MediaBase64 is set; may then be empty
+
+	// A generated image to send with the reply, as in /api/v1/process responses
+	MediaBase64 string `json:"media_base64,omitempty"`
+	MediaType   string `json:"media_type,omitempty"` // "photo" or "document"
 }
 
 // PushProactive pushes a proactive message onto the queue (frontend will pop and send to Telegram).
 func (c *Cache) PushProactive(ctx context.Context, item ProactiveItem) error {
+	item.ID = ""
+	if item.Kind == "" {
+		item.Kind = ProactiveMessage
+	}
+	if item.Source == "" {
+		item.Source = SourceProactive
+	}
 	b, err := json.Marshal(item)
 	if err != nil {
 		return err
 	}
-	return c.client.LPush(ctx, proactiveQueueKey, string(b)).Err()
+	return c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: proactiveStreamKey,
+		MaxLen: proactiveMaxLen,
+		Approx: true,
+		Values: map[string]any{"item": string(b)},
+	}).Err()
 }
 
-// PopProactive blocks up to timeout for an item; returns (chatID, reply, true) or (0, "", false).
-func (c *Cache) PopProactive(ctx context.Context, timeout time.Duration) (chatID int64, reply string, ok bool) {
-	result, err := c.client.BRPop(ctx, timeout, proactiveQueueKey).Result()
-	if err != nil || len(result) != 2 {
-		return 0, "", false
+// ensureProactiveGroup creates the consumer group (and the stream) on first use.
+func (c *Cache) ensureProactiveGroup(ctx context.Context) error {
+	err := c.client.XGroupCreateMkStream(ctx, proactiveStreamKey, proactiveGroup, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return fmt.Errorf("create proactive group: %w", err)
 	}
+	return nil
+}
+
+// PopProactive returns the next queued item, waiting up to wait for one (0 = don't wait).
+// With ackTimeout > 0 the item stays pending until AckProactive and is returned again by a
+// later pop once it has been unacknowledged for ackTimeout; with ackTimeout 0 it is removed
+// right away. ok is false when nothing arrived in time.
+func (c *Cache) PopProactive(ctx context.Context, wait, ackTimeout time.Duration) (item ProactiveItem, ok bool, err error) {
+	if err := c.ensureProactiveGroup(ctx); err != nil {
+		return item, false, err
+	}
+	if ackTimeout > 0 {
+		msgs, _, err := c.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   proactiveStreamKey,
+			Group:    proactiveGroup,
+			Consumer: proactiveConsumer,
+			MinIdle:  ackTimeout,
+			Start:    "0-0",
+			Count:    1,
+		}).Result()
+		if err != nil {
+			return item, false, fmt.Errorf("reclaim proactive item: %w", err)
+		}
+		if len(msgs) > 0 {
+			slog.WarnContext(ctx, "redelivering unacknowledged proactive item", "id", msgs[0].ID)
+			return c.proactiveItem(ctx, msgs[0], ackTimeout)
+		}
+	}
+
+	block := wait
+	if block <= 0 {
+		block = -1 // no BLOCK argument: return immediately
+	}
+	streams, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    proactiveGroup,
+		Consumer: proactiveConsumer,
+		Streams:  []string{proactiveStreamKey, ">"},
+		Count:    1,
+		Block:    block,
+	}).Result()
+	if err == redis.Nil {
+		return item, false, nil
+	}
+	if err != nil {
+		return item, false, fmt.Errorf("read proactive item: %w", err)
+	}
+	if len(streams) == 0 || len(streams[0].Messages) == 0 {
+		return item, false, nil
+	}
+	return c.proactiveItem(ctx, streams[0].Messages[0], ackTimeout)
+}
+
+// proactiveItem decodes a stream entry, acknowledging it right away when acks are off or the
+// entry is unreadable (so it is not redelivered forever).
+func (c *Cache) proactiveItem(ctx context.Context, msg redis.XMessage, ackTimeout time.Duration) (ProactiveItem, bool, error) {
+	item, err := decodeProactiveItem(msg)
+	if err != nil {
+		slog.WarnContext(ctx, "dropping malformed proactive item", "id", msg.ID, "error", err)
+		return item, false, c.AckProactive(ctx, msg.ID)
+	}
+	if ackTimeout <= 0 {
+		if err := c.AckProactive(ctx, msg.ID); err != nil {
+			return item, false, err
+		}
+	}
+	return item, true, nil
+}
+
+// PendingProactive returns a queued item by ID without removing it; ok is false when the ID is
+// unknown or already acknowledged.
+func (c *Cache) PendingProactive(ctx context.Context, id string) (item ProactiveItem, ok bool, err error) {
+	msgs, err := c.client.XRange(ctx, proactiveStreamKey, id, id).Result()
+	if err != nil {
+		return item, false, fmt.Errorf("read proactive item: %w", err)
+	}
+	if len(msgs) == 0 {
+		return item, false, nil
+	}
+	if item, err = decodeProactiveItem(msgs[0]); err != nil {
+		return item, false, nil
+	}
+	return item, true, nil
+}
+
+// decodeProactiveItem decodes a stream entry, filling in the ID and the defaults of items queued
+// before Kind and Source existed.
+func decodeProactiveItem(msg redis.XMessage) (ProactiveItem, error) {
 	var item ProactiveItem
-	if json.Unmarshal([]byte(result[1]), &item) != nil {
-		return 0, "", false
+	raw, _ := msg.Values["item"].(string)
+	if err := json.Unmarshal([]byte(raw), &item); err != nil {
+		return item, err
+	}
+	item.ID = msg.ID
+	if item.Kind == "" {
+		item.Kind = ProactiveMessage
+	}
+	if item.Source == "" {
+		item.Source = SourceProactive
+	}
+	return item, nil
+}
+
+// AckProactive marks an item as delivered and removes it from the queue. Acknowledging an
+// unknown or already acknowledged ID is a no-op.
+func (c *Cache) AckProactive(ctx context.Context, id string) error {
+	_, err := c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.XAck(ctx, proactiveStreamKey, proactiveGroup, id)
+		p.XDel(ctx, proactiveStreamKey, id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("ack proactive item: %w", err)
+	}
+	return nil
+}
+
+// legacyProactiveQueueKey is the list the queue lived in before it became a stream.
+const legacyProactiveQueueKey = "proactive:queue"
+
+// MigrateProactiveQueue moves items left in the old list-based queue onto the stream, oldest
+// first. It returns how many were moved.
+func (c *Cache) MigrateProactiveQueue(ctx context.Context) (int, error) {
+	moved := 0
+	for {
+		raw, err := c.client.RPop(ctx, legacyProactiveQueueKey).Result()
+		if err == redis.Nil {
+			return moved, nil
+		}
+		if err != nil {
+			return moved, fmt.Errorf("migrate proactive queue: %w", err)
+		}
+		var item ProactiveItem
+		if json.Unmarshal([]byte(raw), &item) != nil {
+			continue
+		}
+		if err := c.PushProactive(ctx, item); err != nil {
+			return moved, err
+		}
+		moved++
 	}
-	return item.ChatID, item.Reply, true
 }
@@ -3,8 +3,11 @@ package cache
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/redis/go-redis/v9"
 )
 
 // These tests require a running Redis instance.
@@ -74,6 +77,30 @@ func TestCheckRateLimit_BlocksOverLimit(t *testing.T) {
 	}
 }
 
+func TestTakeToken_BurstThenRefill(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	key := "test:rl:bucket:" + t.Name()
+	defer c.Client().Del(ctx, key)
+
+	// 60/s refill with a burst of 5: five pass at once, the sixth waits about one refill
+	b := Bucket{Rate: 60, Per: time.Second, Burst: 5}
+	for i := 0; i < 5; i++ {
+		result, err := c.TakeToken(ctx, key, b)
+		if err != nil || !result.Allowed {
+			t.Fatalf("request %d: expected to be allowed, got %+v, %v", i, result, err)
+		}
+	}
+	result, err := c.TakeToken(ctx, key, b)
+	if err != nil || result.Allowed || result.RetryIn <= 0 || result.RetryIn > 50*time.Millisecond {
+		t.Fatalf("expected a short wait after the burst, got %+v, %v", result, err)
+	}
+	time.Sleep(result.RetryIn + 10*time.Millisecond)
+	if result, err := c.TakeToken(ctx, key, b); err != nil || !result.Allowed {
+		t.Errorf("expected a refilled token, got %+v, %v", result, err)
+	}
+}
+
 func TestAcquireLock_ExclusiveProcessing(t *testing.T) {
 	c := getTestCache(t)
 	ctx := context.Background()
@@ -81,7 +108,7 @@ func TestAcquireLock_ExclusiveProcessing(t *testing.T) {
 	defer c.Client().Del(ctx, "lock:chat:99999")
 
 	// First lock should succeed
-	ok, err := c.AcquireLock(ctx, chatID, 30*time.Second)
+	ok, err := c.AcquireLock(ctx, chatID, 0, 30*time.Second)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -90,7 +117,7 @@ func TestAcquireLock_ExclusiveProcessing(t *testing.T) {
 	}
 
 	// Second lock should fail (already locked)
-	ok2, err := c.AcquireLock(ctx, chatID, 30*time.Second)
+	ok2, err := c.AcquireLock(ctx, chatID, 0, 30*time.Second)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -99,10 +126,10 @@ func TestAcquireLock_ExclusiveProcessing(t *testing.T) {
 	}
 
 	// Release and re-acquire
-	if err := c.ReleaseLock(ctx, chatID); err != nil {
+	if err := c.ReleaseLock(ctx, chatID, 0); err != nil {
 		t.Fatalf("release error: %v", err)
 	}
-	ok3, err := c.AcquireLock(ctx, chatID, 30*time.Second)
+	ok3, err := c.AcquireLock(ctx, chatID, 0, 30*time.Second)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -110,3 +137,182 @@ func TestAcquireLock_ExclusiveProcessing(t *testing.T) {
 		t.Error("expected lock to be acquired after release")
 	}
 }
+
+func TestAcquireLock_PerForumTopic(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	chatID := int64(99998)
+	defer c.Client().Del(ctx, "lock:chat:99998", "lock:chat:99998:7")
+
+	if ok, err := c.AcquireLock(ctx, chatID, 0, 30*time.Second); err != nil || !ok {
+		t.Fatalf("expected General lock, got ok=%v err=%v", ok, err)
+	}
+	// A busy General topic must not block another topic of the same forum.
+	if ok, err := c.AcquireLock(ctx, chatID, 7, 30*time.Second); err != nil || !ok {
+		t.Errorf("expected topic lock, got ok=%v err=%v", ok, err)
+	}
+	if ok, _ := c.AcquireLock(ctx, chatID, 7, 30*time.Second); ok {
+		t.Error("expected topic lock to be denied (already locked)")
+	}
+}
+
+func TestCounters(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	key := "test:counter:" + t.Name()
+	defer c.Client().Del(ctx, key)
+
+	if n, err := c.GetCount(ctx, key); err != nil || n != 0 {
+		t.Fatalf("expected 0 for missing key, got %d (%v)", n, err)
+	}
+	for i := int64(1); i <= 3; i++ {
+		n, err := c.IncrCount(ctx, key, time.Minute)
+		if err != nil || n != i {
+			t.Fatalf("expected %d, got %d (%v)", i, n, err)
+		}
+	}
+	if ttl := c.Client().TTL(ctx, key).Val(); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("expected TTL set on first increment, got %v", ttl)
+	}
+}
+
+func TestJobLock_ExclusiveUntilReleased(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	name := "test:" + t.Name()
+	defer c.Client().Del(ctx, "job:lock:"+name)
+
+	first, err := c.AcquireJobLock(ctx, name, time.Minute)
+	if err != nil || first == nil {
+		t.Fatalf("expected to acquire the lock, got %v (%v)", first, err)
+	}
+	if second, err := c.AcquireJobLock(ctx, name, time.Minute); err != nil || second != nil {
+		t.Fatalf("expected the lock to be held, got %v (%v)", second, err)
+	}
+	// A stale holder must not release someone else's lock.
+	if err := c.ReleaseJobLock(ctx, &JobLock{key: first.key, token: "stale"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, _ := c.AcquireJobLock(ctx, name, time.Minute); again != nil {
+		t.Fatal("stale release freed the lock")
+	}
+	if err := c.ReleaseJobLock(ctx, first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if again, err := c.AcquireJobLock(ctx, name, time.Minute); err != nil || again == nil {
+		t.Fatalf("expected to reacquire after release, got %v (%v)", again, err)
+	}
+}
+
+func TestCappedList_FillAppend(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	key, ver := "test:list:"+t.Name(), "test:list:ver:"+t.Name()
+	defer c.Client().Del(ctx, key, ver)
+
+	// Appending to a list that was never filled caches nothing.
+	if err := c.AppendList(ctx, key, ver, "a", 3, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if items, _ := c.ListTail(ctx, key, 3); len(items) != 0 {
+		t.Fatalf("expected no list, got %v", items)
+	}
+
+	// A fill read at version 0 is stale after the append above.
+	if ok, err := c.FillList(ctx, key, ver, 0, []string{"a"}, time.Minute); err != nil || ok {
+		t.Fatalf("expected stale fill to be rejected, ok=%v err=%v", ok, err)
+	}
+	counts, err := c.GetCounts(ctx, ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := c.FillList(ctx, key, ver, counts[0], []string{"a", "b"}, time.Minute); err != nil || !ok {
+		t.Fatalf("expected fill, ok=%v err=%v", ok, err)
+	}
+	for _, it := range []string{"c", "d"} {
+		if err := c.AppendList(ctx, key, ver, it, 3, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	items, err := c.ListTail(ctx, key, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(items, "") != "bcd" {
+		t.Errorf("expected the newest 3 items, got %v", items)
+	}
+}
+
+func TestRateBoost_Limit(t *testing.T) {
+	var none *RateBoost
+	if n, ok := none.Limit(3); !ok || n != 3 {
+		t.Errorf("expected the base limit without a boost, got %d/%v", n, ok)
+	}
+	if n, ok := (&RateBoost{Factor: 5}).Limit(3); !ok || n != 15 {
+		t.Errorf("expected 15, got %d/%v", n, ok)
+	}
+	if _, ok := (&RateBoost{Factor: 0}).Limit(3); ok {
+		t.Error("expected factor 0 to lift the limit")
+	}
+}
+
+func TestRateBoosts(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	const chatID = -991
+	defer c.ClearRateBoost(ctx, chatID, 0)
+	defer c.ClearRateBoost(ctx, chatID, 7)
+
+	if err := c.SetRateBoost(ctx, &RateBoost{ChatID: chatID, UserID: 7, Factor: 3, Until: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	chat, user, err := c.RateBoosts(ctx, chatID, 7)
+	if err != nil || chat != nil || user == nil || user.Factor != 3 {
+		t.Fatalf("expected only the user boost, got %+v %+v %v", chat, user, err)
+	}
+	if err := c.SetRateBoost(ctx, &RateBoost{ChatID: chatID, Until: time.Now().Add(-time.Second)}); err == nil {
+		t.Error("expected an expired boost to be rejected")
+	}
+	c.ClearRateBoost(ctx, chatID, 7)
+	if _, user, _ := c.RateBoosts(ctx, chatID, 7); user != nil {
+		t.Error("expected the boost to be cleared")
+	}
+}
+
+func TestAlbumBuffer(t *testing.T) {
+	c := getTestCache(t)
+	ctx := context.Background()
+	group := "test:" + t.Name()
+	defer c.TakeAlbum(ctx, -100, group)
+
+	for i, part := range []string{"a", "b", "c"} {
+		n, err := c.AddAlbumPart(ctx, -100, group, part, time.Minute)
+		if err != nil || n != int64(i+1) {
+			t.Fatalf("part %d: got %d, %v", i, n, err)
+		}
+	}
+	if n, err := c.AlbumSize(ctx, -100, group); err != nil || n != 3 {
+		t.Errorf("expected 3 parts, got %d, %v", n, err)
+	}
+	parts, err := c.TakeAlbum(ctx, -100, group)
+	if err != nil || strings.Join(parts, "") != "abc" {
+		t.Errorf("expected the parts in order, got %v, %v", parts, err)
+	}
+	if n, _ := c.AddAlbumPart(ctx, -100, group, "d", time.Minute); n != 1 {
+		t.Errorf("expected a part after TakeAlbum to start a new album, got %d", n)
+	}
+}
+
+func TestDecodeProactiveItem(t *testing.T) {
+	item, err := decodeProactiveItem(redis.XMessage{ID: "1-0", Values: map[string]any{"item": `{"chat_id": -100, "reply": "hi"}`}})
+	if err != nil || item.ID != "1-0" || item.Kind != ProactiveMessage || item.Source != SourceProactive || item.ChatID != -100 {
+		t.Errorf("expected the defaults filled in, got %+v, %v", item, err)
+	}
+	item, _ = decodeProactiveItem(redis.XMessage{ID: "2-0", Values: map[string]any{"item": `{"source": "digest", "chat_id": 1, "reply": "x"}`}})
+	if item.Source != SourceDigest {
+		t.Errorf("expected the stored source kept, got %q", item.Source)
+	}
+	if _, err := decodeProactiveItem(redis.XMessage{ID: "3-0", Values: map[string]any{"item": "{"}}); err == nil {
+		t.Error("expected a malformed entry to fail")
+	}
+}
@@ -0,0 +1,420 @@
+package chatsettings
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"github.com/ThatHunky/gryag/backend/internal/watermark"
+)
+
+// cacheTTL bounds how long a chat's stored overrides live in Redis before being re-read.
+const cacheTTL = 10 * time.Minute
+
+// Settings is the effective configuration for one chat: env defaults with the chat's overrides applied.
+type Settings struct {
+	ChatID           int64    `json:"chat_id"`
+	Language         string   `json:"language"`
+	Persona          string   `json:"persona,omitempty"`        // empty = global persona file
+	ActivePersona    string   `json:"active_persona,omitempty"` // stored persona name; empty = default
+	ProactiveEnabled bool     `json:"proactive_enabled"`
+	DisabledTools    []string `json:"disabled_tools"`
+	Temperature      float64  `json:"temperature"`
+
+	// Replies to indirect name mentions (messages not formally addressed to the bot)
+	MentionReplyProbability float64 `json:"mention_reply_probability"`
+	MentionDailyCap         int     `json:"mention_daily_cap"` // 0 = unlimited
+
+	// Summaries
+	SummaryLanguage     string `json:"summary_language,omitempty"` // empty = detect from the chat's messages
+	SummaryEnabled      bool   `json:"summary_enabled"`
+	SummaryRunHour      int    `json:"summary_run_hour"`      // 0-23, Kyiv time
+	SummaryIntervalDays int    `json:"summary_interval_days"` // days between 7-day summaries
+	SummaryAnonymize    bool   `json:"summary_anonymize"`
+
+	// Morning digest (opt-in; needs ENABLE_DAILY_DIGEST)
+	DigestEnabled bool `json:"digest_enabled"`
+	DigestHour    int  `json:"digest_hour"` // 0-23, Kyiv time
+
+	// AI-content label stamped on generated images
+	WatermarkEnabled bool   `json:"watermark_enabled"`
+	WatermarkLabel   string `json:"watermark_label"`
+
+	// Proactive pacing. Intervals in minutes, 0 = no limit. Quiet hours are [start, end) in
+	// Timezone; start == end means none.
+	ProactiveMinIntervalMinutes int    `json:"proactive_min_interval_minutes"`
+	ProactiveMaxIntervalMinutes int    `json:"proactive_max_interval_minutes"`
+	ProactiveQuietStart         int    `json:"proactive_quiet_start"`
+	ProactiveQuietEnd           int    `json:"proactive_quiet_end"`
+	Timezone                    string `json:"timezone"`
+
+	// Messages older than this many days are deleted by the retention job; 0 = keep forever.
+	MessageRetentionDays int `json:"message_retention_days"`
+
+	// Shadow mode: replies are generated and logged but not sent, and tools that change state are
+	// not run. For trying a persona or model change on live traffic.
+	ShadowMode bool `json:"shadow_mode"`
+
+	// Users who skip the chat and per-user rate limits in this chat
+	RateLimitExemptUsers []int64 `json:"rate_limit_exempt_users"`
+
+	// News turns: chance (0-1) that a proactive turn without a topic hint searches for news, and
+	// the query hints suggested for it. 0 = no news turns in this chat.
+	ProactiveNewsProbability float64  `json:"proactive_news_probability"`
+	ProactiveNewsQueries     []string `json:"proactive_news_queries"`
+
+	// Privacy mode: names, @usernames and user IDs reach the model as stable pseudonyms ("Member 3",
+	// user ID 3) and are mapped back in tool calls and replies. Summaries are anonymized too.
+	PrivacyMode bool `json:"privacy_mode"`
+}
+
+// DefaultTimezone is the chat timezone when none is stored.
+const DefaultTimezone = "Europe/Kyiv"
+
+// maxMessageRetentionDays caps the per-chat message retention at ten years.
+const maxMessageRetentionDays = 3650
+
+// maxRateLimitExemptUsers caps the per-chat rate limit exemptions.
+const maxRateLimitExemptUsers = 100
+
+// maxProactiveNewsQueries caps the per-chat news query hints, and maxNewsQueryRunes each hint.
+const (
+	maxProactiveNewsQueries = 20
+	maxNewsQueryRunes       = 200
+)
+
+// maxProactiveIntervalMinutes caps the per-chat proactive intervals at one week.
+const maxProactiveIntervalMinutes = 7 * 24 * 60
+
+// Location returns the chat's timezone, falling back to UTC if it cannot be loaded.
+func (s *Settings) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// InQuietHours reports whether t falls inside the chat's proactive quiet hours.
+func (s *Settings) InQuietHours(t time.Time) bool {
+	start, end := s.ProactiveQuietStart, s.ProactiveQuietEnd
+	if start == end {
+		return false
+	}
+	hour := t.In(s.Location()).Hour()
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// ToolEnabled reports whether the named tool is allowed in this chat.
+func (s *Settings) ToolEnabled(name string) bool {
+	for _, t := range s.DisabledTools {
+		if t == name {
+			return false
+		}
+	}
+	return true
+}
+
+// Resolve applies stored overrides (may be nil) on top of the config defaults.
+func Resolve(cfg *config.Config, chatID int64, o *db.ChatSettings) *Settings {
+	s := &Settings{
+		ChatID:           chatID,
+		Language:         cfg.DefaultLang,
+		ProactiveEnabled: true,
+		DisabledTools:    []string{},
+		Temperature:      cfg.GeminiTemperature,
+
+		MentionReplyProbability: cfg.MentionReplyProbability,
+		MentionDailyCap:         cfg.MentionDailyCap,
+		SummaryEnabled:          true,
+		SummaryRunHour:          cfg.SummaryRunHour,
+		SummaryIntervalDays:     cfg.Summary7DayIntervalDays,
+		DigestHour:              cfg.DailyDigestHour,
+		WatermarkEnabled:        cfg.WatermarkImages,
+		WatermarkLabel:          cfg.WatermarkLabel,
+		Timezone:                DefaultTimezone,
+		MessageRetentionDays:    cfg.MessageRetentionDays,
+		RateLimitExemptUsers:    []int64{},
+
+		ProactiveNewsProbability: cfg.ProactiveNewsProbability,
+		ProactiveNewsQueries:     append([]string{}, cfg.ProactiveNewsQueries...),
+	}
+	if o == nil {
+		return s
+	}
+	if o.Language != nil && *o.Language != "" {
+		s.Language = *o.Language
+	}
+	if o.Persona != nil {
+		s.Persona = *o.Persona
+	}
+	if o.ActivePersona != nil && *o.ActivePersona != DefaultPersona {
+		s.ActivePersona = *o.ActivePersona
+	}
+	if o.ProactiveEnabled != nil {
+		s.ProactiveEnabled = *o.ProactiveEnabled
+	}
+	if len(o.DisabledTools) > 0 {
+		s.DisabledTools = o.DisabledTools
+	}
+	if o.Temperature != nil {
+		s.Temperature = *o.Temperature
+	}
+	if o.MentionReplyProbability != nil {
+		s.MentionReplyProbability = *o.MentionReplyProbability
+	}
+	if o.MentionDailyCap != nil {
+		s.MentionDailyCap = *o.MentionDailyCap
+	}
+	if o.SummaryLanguage != nil {
+		s.SummaryLanguage = i18n.Normalize(*o.SummaryLanguage)
+	}
+	if o.SummaryEnabled != nil {
+		s.SummaryEnabled = *o.SummaryEnabled
+	}
+	if o.SummaryRunHour != nil {
+		s.SummaryRunHour = *o.SummaryRunHour
+	}
+	if o.SummaryIntervalDays != nil {
+		s.SummaryIntervalDays = *o.SummaryIntervalDays
+	}
+	if o.SummaryAnonymize != nil {
+		s.SummaryAnonymize = *o.SummaryAnonymize
+	}
+	if o.DigestEnabled != nil {
+		s.DigestEnabled = *o.DigestEnabled
+	}
+	if o.DigestHour != nil {
+		s.DigestHour = *o.DigestHour
+	}
+	if o.WatermarkEnabled != nil {
+		s.WatermarkEnabled = *o.WatermarkEnabled
+	}
+	if o.WatermarkLabel != nil && strings.TrimSpace(*o.WatermarkLabel) != "" {
+		s.WatermarkLabel = strings.TrimSpace(*o.WatermarkLabel)
+	}
+	if o.ProactiveMinIntervalMinutes != nil {
+		s.ProactiveMinIntervalMinutes = *o.ProactiveMinIntervalMinutes
+	}
+	if o.ProactiveMaxIntervalMinutes != nil {
+		s.ProactiveMaxIntervalMinutes = *o.ProactiveMaxIntervalMinutes
+	}
+	if o.ProactiveQuietStart != nil && o.ProactiveQuietEnd != nil {
+		s.ProactiveQuietStart, s.ProactiveQuietEnd = *o.ProactiveQuietStart, *o.ProactiveQuietEnd
+	}
+	if o.Timezone != nil && *o.Timezone != "" {
+		s.Timezone = *o.Timezone
+	}
+	if o.MessageRetentionDays != nil {
+		s.MessageRetentionDays = *o.MessageRetentionDays
+	}
+	if o.ShadowMode != nil {
+		s.ShadowMode = *o.ShadowMode
+	}
+	if len(o.RateLimitExemptUsers) > 0 {
+		s.RateLimitExemptUsers = o.RateLimitExemptUsers
+	}
+	if o.ProactiveNewsProbability != nil {
+		s.ProactiveNewsProbability = *o.ProactiveNewsProbability
+	}
+	if len(o.ProactiveNewsQueries) > 0 {
+		s.ProactiveNewsQueries = o.ProactiveNewsQueries
+	}
+	if o.PrivacyMode != nil && *o.PrivacyMode {
+		s.PrivacyMode, s.SummaryAnonymize = true, true
+	}
+	return s
+}
+
+// Validate rejects overrides that cannot be applied.
+func Validate(o *db.ChatSettings) error {
+	if o.ChatID == 0 {
+		return fmt.Errorf("chat_id is required")
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > 2) {
+		return fmt.Errorf("temperature must be between 0 and 2")
+	}
+	if o.MentionReplyProbability != nil && (*o.MentionReplyProbability < 0 || *o.MentionReplyProbability > 1) {
+		return fmt.Errorf("mention_reply_probability must be between 0 and 1")
+	}
+	if o.ActivePersona != nil && *o.ActivePersona != "" && !ValidPersonaName(*o.ActivePersona) {
+		return fmt.Errorf("active_persona must be 1-40 characters of a-z, 0-9, - or _")
+	}
+	if o.MentionDailyCap != nil && *o.MentionDailyCap < 0 {
+		return fmt.Errorf("mention_daily_cap must not be negative")
+	}
+	if o.SummaryLanguage != nil && *o.SummaryLanguage != "" && !validLanguageCode(i18n.Normalize(*o.SummaryLanguage)) {
+		return fmt.Errorf("summary_language must be a language code such as uk or en")
+	}
+	if o.SummaryRunHour != nil && (*o.SummaryRunHour < 0 || *o.SummaryRunHour > 23) {
+		return fmt.Errorf("summary_run_hour must be between 0 and 23")
+	}
+	if o.SummaryIntervalDays != nil && (*o.SummaryIntervalDays < 1 || *o.SummaryIntervalDays > 30) {
+		return fmt.Errorf("summary_interval_days must be between 1 and 30")
+	}
+	if o.DigestHour != nil && (*o.DigestHour < 0 || *o.DigestHour > 23) {
+		return fmt.Errorf("digest_hour must be between 0 and 23")
+	}
+	if o.WatermarkLabel != nil && strings.TrimSpace(*o.WatermarkLabel) != "" {
+		if err := watermark.ValidLabel(*o.WatermarkLabel); err != nil {
+			return fmt.Errorf("watermark_label: %w", err)
+		}
+	}
+	if v := o.ProactiveMinIntervalMinutes; v != nil && (*v < 0 || *v > maxProactiveIntervalMinutes) {
+		return fmt.Errorf("proactive_min_interval_minutes must be between 0 and %d", maxProactiveIntervalMinutes)
+	}
+	if v := o.ProactiveMaxIntervalMinutes; v != nil && (*v < 0 || *v > maxProactiveIntervalMinutes) {
+		return fmt.Errorf("proactive_max_interval_minutes must be between 0 and %d", maxProactiveIntervalMinutes)
+	}
+	if o.ProactiveMinIntervalMinutes != nil && o.ProactiveMaxIntervalMinutes != nil &&
+		*o.ProactiveMaxIntervalMinutes > 0 && *o.ProactiveMaxIntervalMinutes < *o.ProactiveMinIntervalMinutes {
+		return fmt.Errorf("proactive_max_interval_minutes must not be below proactive_min_interval_minutes")
+	}
+	if (o.ProactiveQuietStart == nil) != (o.ProactiveQuietEnd == nil) {
+		return fmt.Errorf("proactive_quiet_start and proactive_quiet_end must be set together")
+	}
+	if o.ProactiveQuietStart != nil && (*o.ProactiveQuietStart < 0 || *o.ProactiveQuietStart > 23 ||
+		*o.ProactiveQuietEnd < 0 || *o.ProactiveQuietEnd > 23) {
+		return fmt.Errorf("proactive quiet hours must be between 0 and 23")
+	}
+	if o.Timezone != nil && *o.Timezone != "" {
+		if _, err := time.LoadLocation(*o.Timezone); err != nil || *o.Timezone == "Local" {
+			return fmt.Errorf("timezone must be an IANA name such as Europe/Kyiv")
+		}
+	}
+	if v := o.MessageRetentionDays; v != nil && (*v < 0 || *v > maxMessageRetentionDays) {
+		return fmt.Errorf("message_retention_days must be between 0 and %d", maxMessageRetentionDays)
+	}
+	if len(o.RateLimitExemptUsers) > maxRateLimitExemptUsers {
+		return fmt.Errorf("rate_limit_exempt_users must not list more than %d users", maxRateLimitExemptUsers)
+	}
+	for _, id := range o.RateLimitExemptUsers {
+		if id <= 0 {
+			return fmt.Errorf("rate_limit_exempt_users must be Telegram user IDs")
+		}
+	}
+	if v := o.ProactiveNewsProbability; v != nil && (*v < 0 || *v > 1) {
+		return fmt.Errorf("proactive_news_probability must be between 0 and 1")
+	}
+	if len(o.ProactiveNewsQueries) > maxProactiveNewsQueries {
+		return fmt.Errorf("proactive_news_queries must not list more than %d queries", maxProactiveNewsQueries)
+	}
+	for _, q := range o.ProactiveNewsQueries {
+		if strings.TrimSpace(q) == "" || utf8.RuneCountInString(q) > maxNewsQueryRunes {
+			return fmt.Errorf("proactive_news_queries must be 1-%d characters each", maxNewsQueryRunes)
+		}
+	}
+	return nil
+}
+
+// validLanguageCode accepts bare ISO 639 codes ("uk", "en", "fil").
+func validLanguageCode(code string) bool {
+	if len(code) < 2 || len(code) > 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Store reads and writes per-chat settings with a Redis read-through cache.
+type Store struct {
+	db     *db.DB
+	cache  *cache.Cache
+	config *config.Config
+}
+
+// NewStore creates a settings store. The cache may be nil (reads go straight to Postgres).
+func NewStore(database *db.DB, c *cache.Cache, cfg *config.Config) *Store {
+	return &Store{db: database, cache: c, config: cfg}
+}
+
+func cacheKey(chatID int64) string {
+	return fmt.Sprintf("chat_settings:%d", chatID)
+}
+
+// Overrides returns the stored overrides for a chat (nil when none), consulting Redis first.
+func (s *Store) Overrides(ctx context.Context, chatID int64) (*db.ChatSettings, error) {
+	if s.cache != nil {
+		var cached db.ChatSettings
+		hit, err := s.cache.GetJSON(ctx, cacheKey(chatID), &cached)
+		if err != nil {
+			slog.WarnContext(ctx, "chat settings cache read failed", "chat_id", chatID, "error", err)
+		} else if hit {
+			if cached.ChatID == 0 {
+				return nil, nil // cached "no overrides"
+			}
+			return &cached, nil
+		}
+	}
+	if s.db == nil {
+		return nil, nil
+	}
+	o, err := s.db.GetChatSettings(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if s.cache != nil {
+		var toCache any = o
+		if o == nil {
+			toCache = &db.ChatSettings{}
+		}
+		if err := s.cache.SetJSON(ctx, cacheKey(chatID), toCache, cacheTTL); err != nil {
+			slog.WarnContext(ctx, "chat settings cache write failed", "chat_id", chatID, "error", err)
+		}
+	}
+	return o, nil
+}
+
+// Get returns the effective settings for a chat. On storage errors it logs and returns the defaults,
+// so a settings outage never blocks replies.
+func (s *Store) Get(ctx context.Context, chatID int64) *Settings {
+	o, err := s.Overrides(ctx, chatID)
+	if err != nil {
+		slog.ErrorContext(ctx, "load chat settings failed", "chat_id", chatID, "error", err)
+	}
+	return Resolve(s.config, chatID, o)
+}
+
+// Save validates and persists overrides, then invalidates the cached copy.
+func (s *Store) Save(ctx context.Context, o *db.ChatSettings) error {
+	if err := Validate(o); err != nil {
+		return err
+	}
+	if err := s.db.UpsertChatSettings(ctx, o); err != nil {
+		return err
+	}
+	s.invalidate(ctx, o.ChatID)
+	return nil
+}
+
+// Reset deletes all overrides for a chat and invalidates the cached copy.
+func (s *Store) Reset(ctx context.Context, chatID int64) error {
+	if err := s.db.DeleteChatSettings(ctx, chatID); err != nil {
+		return err
+	}
+	s.invalidate(ctx, chatID)
+	return nil
+}
+
+func (s *Store) invalidate(ctx context.Context, chatID int64) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.Delete(ctx, cacheKey(chatID)); err != nil {
+		slog.WarnContext(ctx, "chat settings cache invalidation failed", "chat_id", chatID, "error", err)
+	}
+}
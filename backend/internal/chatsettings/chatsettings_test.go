@@ -0,0 +1,317 @@
+package chatsettings
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func testConfig() *config.Config {
+	return &config.Config{DefaultLang: "uk", GeminiTemperature: 0.9}
+}
+
+func TestResolve_Defaults(t *testing.T) {
+	s := Resolve(testConfig(), 42, nil)
+	if s.ChatID != 42 {
+		t.Errorf("expected chat_id 42, got %d", s.ChatID)
+	}
+	if s.Language != "uk" {
+		t.Errorf("expected default language uk, got %q", s.Language)
+	}
+	if !s.ProactiveEnabled {
+		t.Error("expected proactive enabled by default")
+	}
+	if s.Temperature != 0.9 {
+		t.Errorf("expected default temperature 0.9, got %v", s.Temperature)
+	}
+	if s.Persona != "" {
+		t.Errorf("expected no persona override, got %q", s.Persona)
+	}
+}
+
+func TestResolve_Overrides(t *testing.T) {
+	lang := "en"
+	persona := "Be polite."
+	off := false
+	temp := 0.3
+	s := Resolve(testConfig(), 42, &db.ChatSettings{
+		ChatID:           42,
+		Language:         &lang,
+		Persona:          &persona,
+		ProactiveEnabled: &off,
+		DisabledTools:    []string{"generate_image"},
+		Temperature:      &temp,
+	})
+	if s.Language != "en" || s.Persona != "Be polite." || s.ProactiveEnabled || s.Temperature != 0.3 {
+		t.Errorf("overrides not applied: %+v", s)
+	}
+	if s.ToolEnabled("generate_image") {
+		t.Error("generate_image should be disabled")
+	}
+	if !s.ToolEnabled("search_web") {
+		t.Error("search_web should stay enabled")
+	}
+}
+
+func TestResolve_EmptyLanguageKeepsDefault(t *testing.T) {
+	empty := ""
+	s := Resolve(testConfig(), 1, &db.ChatSettings{ChatID: 1, Language: &empty})
+	if s.Language != "uk" {
+		t.Errorf("expected default language for empty override, got %q", s.Language)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(&db.ChatSettings{}); err == nil {
+		t.Error("expected error for missing chat_id")
+	}
+	bad := 3.5
+	if err := Validate(&db.ChatSettings{ChatID: 1, Temperature: &bad}); err == nil {
+		t.Error("expected error for out-of-range temperature")
+	}
+	ok := 1.0
+	if err := Validate(&db.ChatSettings{ChatID: 1, Temperature: &ok}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestResolve_MentionOverrides(t *testing.T) {
+	cfg := testConfig()
+	cfg.MentionReplyProbability = 0.3
+	cfg.MentionDailyCap = 20
+
+	s := Resolve(cfg, 1, nil)
+	if s.MentionReplyProbability != 0.3 || s.MentionDailyCap != 20 {
+		t.Errorf("expected env defaults, got %v/%d", s.MentionReplyProbability, s.MentionDailyCap)
+	}
+
+	prob, limit := 0.0, 5
+	s = Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, MentionReplyProbability: &prob, MentionDailyCap: &limit})
+	if s.MentionReplyProbability != 0 || s.MentionDailyCap != 5 {
+		t.Errorf("expected overrides, got %v/%d", s.MentionReplyProbability, s.MentionDailyCap)
+	}
+
+	tooHigh := 1.5
+	if err := Validate(&db.ChatSettings{ChatID: 1, MentionReplyProbability: &tooHigh}); err == nil {
+		t.Error("expected error for probability above 1")
+	}
+	negative := -1
+	if err := Validate(&db.ChatSettings{ChatID: 1, MentionDailyCap: &negative}); err == nil {
+		t.Error("expected error for negative daily cap")
+	}
+}
+
+func TestPersonaValidation(t *testing.T) {
+	for _, name := range []string{"gryag-classic", "polite_assistant", "p1"} {
+		if !ValidPersonaName(name) {
+			t.Errorf("expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"", "Upper", "with space", strings.Repeat("x", 41)} {
+		if ValidPersonaName(name) {
+			t.Errorf("expected %q to be invalid", name)
+		}
+	}
+	if err := ValidatePersona(&db.Persona{Name: "default", Prompt: "x"}); err == nil {
+		t.Error("expected the built-in default name to be rejected")
+	}
+	if err := ValidatePersona(&db.Persona{Name: "polite"}); err == nil {
+		t.Error("expected missing prompt to be rejected")
+	}
+
+	active := "polite"
+	if s := Resolve(testConfig(), 1, &db.ChatSettings{ChatID: 1, ActivePersona: &active}); s.ActivePersona != "polite" {
+		t.Errorf("expected active persona override, got %q", s.ActivePersona)
+	}
+	def := DefaultPersona
+	if s := Resolve(testConfig(), 1, &db.ChatSettings{ChatID: 1, ActivePersona: &def}); s.ActivePersona != "" {
+		t.Errorf("expected default persona to resolve to empty, got %q", s.ActivePersona)
+	}
+}
+
+func TestSummaryLanguage(t *testing.T) {
+	if s := Resolve(testConfig(), 1, nil); s.SummaryLanguage != "" {
+		t.Errorf("expected auto-detected summary language by default, got %q", s.SummaryLanguage)
+	}
+	tag := "EN-us"
+	if s := Resolve(testConfig(), 1, &db.ChatSettings{ChatID: 1, SummaryLanguage: &tag}); s.SummaryLanguage != "en" {
+		t.Errorf("expected normalized en, got %q", s.SummaryLanguage)
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, SummaryLanguage: &tag}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	bad := "ukrainian"
+	if err := Validate(&db.ChatSettings{ChatID: 1, SummaryLanguage: &bad}); err == nil {
+		t.Error("expected error for a language name instead of a code")
+	}
+}
+
+func TestSummarySchedule(t *testing.T) {
+	cfg := testConfig()
+	cfg.SummaryRunHour, cfg.Summary7DayIntervalDays = 3, 3
+	s := Resolve(cfg, 1, nil)
+	if !s.SummaryEnabled || s.SummaryRunHour != 3 || s.SummaryIntervalDays != 3 || s.SummaryAnonymize {
+		t.Errorf("expected env summary defaults, got %+v", s)
+	}
+	off, hour, days, anon := false, 22, 7, true
+	s = Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, SummaryEnabled: &off, SummaryRunHour: &hour, SummaryIntervalDays: &days, SummaryAnonymize: &anon})
+	if s.SummaryEnabled || s.SummaryRunHour != 22 || s.SummaryIntervalDays != 7 || !s.SummaryAnonymize {
+		t.Errorf("expected summary overrides, got %+v", s)
+	}
+	badHour, badDays := 24, 0
+	if err := Validate(&db.ChatSettings{ChatID: 1, SummaryRunHour: &badHour}); err == nil {
+		t.Error("expected error for run hour 24")
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, SummaryIntervalDays: &badDays}); err == nil {
+		t.Error("expected error for zero interval")
+	}
+}
+
+func TestWatermarkSettings(t *testing.T) {
+	cfg := testConfig()
+	cfg.WatermarkLabel = "AI"
+	s := Resolve(cfg, 1, nil)
+	if s.WatermarkEnabled || s.WatermarkLabel != "AI" {
+		t.Errorf("expected env watermark defaults, got enabled=%v label=%q", s.WatermarkEnabled, s.WatermarkLabel)
+	}
+	on, label, blank := true, " ШІ ", ""
+	s = Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, WatermarkEnabled: &on, WatermarkLabel: &label})
+	if !s.WatermarkEnabled || s.WatermarkLabel != "ШІ" {
+		t.Errorf("expected watermark overrides, got enabled=%v label=%q", s.WatermarkEnabled, s.WatermarkLabel)
+	}
+	if s = Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, WatermarkLabel: &blank}); s.WatermarkLabel != "AI" {
+		t.Errorf("blank label should keep the default, got %q", s.WatermarkLabel)
+	}
+	bad := "🤖"
+	if err := Validate(&db.ChatSettings{ChatID: 1, WatermarkLabel: &bad}); err == nil {
+		t.Error("expected error for an undrawable label")
+	}
+}
+
+func TestDigestSettings(t *testing.T) {
+	cfg := testConfig()
+	cfg.DailyDigestHour = 9
+	s := Resolve(cfg, 1, nil)
+	if s.DigestEnabled || s.DigestHour != 9 {
+		t.Errorf("expected digest off at env hour by default, got enabled=%v hour=%d", s.DigestEnabled, s.DigestHour)
+	}
+	on, hour := true, 8
+	s = Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, DigestEnabled: &on, DigestHour: &hour})
+	if !s.DigestEnabled || s.DigestHour != 8 {
+		t.Errorf("expected digest overrides, got enabled=%v hour=%d", s.DigestEnabled, s.DigestHour)
+	}
+	bad := -1
+	if err := Validate(&db.ChatSettings{ChatID: 1, DigestHour: &bad}); err == nil {
+		t.Error("expected error for digest hour -1")
+	}
+}
+
+func TestProactivePacingSettings(t *testing.T) {
+	start, end := 23, 8
+	tz := "America/New_York"
+	s := Resolve(testConfig(), 1, &db.ChatSettings{ChatID: 1, ProactiveQuietStart: &start, ProactiveQuietEnd: &end, Timezone: &tz})
+	// 03:00 UTC is 23:00 (EDT) in New York: quiet. 16:00 UTC is noon there: not quiet.
+	if !s.InQuietHours(time.Date(2026, 7, 1, 3, 0, 0, 0, time.UTC)) {
+		t.Error("expected 23:00 New York time to be quiet")
+	}
+	if s.InQuietHours(time.Date(2026, 7, 1, 16, 0, 0, 0, time.UTC)) {
+		t.Error("expected noon New York time not to be quiet")
+	}
+	if Resolve(testConfig(), 1, nil).InQuietHours(time.Now()) {
+		t.Error("no quiet hours by default")
+	}
+
+	minGap, maxGap := 120, 60
+	if err := Validate(&db.ChatSettings{ChatID: 1, ProactiveMinIntervalMinutes: &minGap, ProactiveMaxIntervalMinutes: &maxGap}); err == nil {
+		t.Error("expected error for max interval below min")
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, ProactiveQuietStart: &start}); err == nil {
+		t.Error("expected error for quiet start without end")
+	}
+	badTZ := "Mars/Olympus"
+	if err := Validate(&db.ChatSettings{ChatID: 1, Timezone: &badTZ}); err == nil {
+		t.Error("expected error for unknown timezone")
+	}
+}
+
+func TestMessageRetentionSettings(t *testing.T) {
+	cfg := testConfig()
+	cfg.MessageRetentionDays = 90
+	if s := Resolve(cfg, 1, nil); s.MessageRetentionDays != 90 {
+		t.Errorf("expected env retention by default, got %d", s.MessageRetentionDays)
+	}
+	forever := 0
+	if s := Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, MessageRetentionDays: &forever}); s.MessageRetentionDays != 0 {
+		t.Errorf("expected 0 (keep forever) override, got %d", s.MessageRetentionDays)
+	}
+	for _, bad := range []int{-1, maxMessageRetentionDays + 1} {
+		if err := Validate(&db.ChatSettings{ChatID: 1, MessageRetentionDays: &bad}); err == nil {
+			t.Errorf("expected error for message_retention_days %d", bad)
+		}
+	}
+}
+
+func TestShadowModeSetting(t *testing.T) {
+	cfg := testConfig()
+	if Resolve(cfg, 1, nil).ShadowMode {
+		t.Error("shadow mode should be off by default")
+	}
+	on := true
+	if !Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, ShadowMode: &on}).ShadowMode {
+		t.Error("expected the shadow_mode override")
+	}
+}
+
+func TestPrivacyModeSetting(t *testing.T) {
+	cfg := testConfig()
+	if s := Resolve(cfg, 1, nil); s.PrivacyMode || s.SummaryAnonymize {
+		t.Error("privacy mode and anonymized summaries should be off by default")
+	}
+	on, off := true, false
+	if s := Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, PrivacyMode: &on, SummaryAnonymize: &off}); !s.PrivacyMode || !s.SummaryAnonymize {
+		t.Error("privacy mode should anonymize summaries whatever summary_anonymize says")
+	}
+}
+
+func TestRateLimitExemptUsers(t *testing.T) {
+	cfg := testConfig()
+	if s := Resolve(cfg, 1, nil); s.RateLimitExemptUsers == nil || len(s.RateLimitExemptUsers) != 0 {
+		t.Errorf("expected no exemptions by default, got %v", s.RateLimitExemptUsers)
+	}
+	if s := Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, RateLimitExemptUsers: []int64{42}}); len(s.RateLimitExemptUsers) != 1 {
+		t.Errorf("expected the exemption override, got %v", s.RateLimitExemptUsers)
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, RateLimitExemptUsers: []int64{-100}}); err == nil {
+		t.Error("expected a chat ID to be rejected as an exempt user")
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, RateLimitExemptUsers: make([]int64, maxRateLimitExemptUsers+1)}); err == nil {
+		t.Error("expected too many exempt users to be rejected")
+	}
+}
+
+func TestProactiveNewsSettings(t *testing.T) {
+	cfg := testConfig()
+	cfg.ProactiveNewsProbability = 0.3
+	cfg.ProactiveNewsQueries = []string{"Kyiv news"}
+	if s := Resolve(cfg, 1, nil); s.ProactiveNewsProbability != 0.3 || len(s.ProactiveNewsQueries) != 1 {
+		t.Errorf("expected the env defaults, got %v %v", s.ProactiveNewsProbability, s.ProactiveNewsQueries)
+	}
+	off := 0.0
+	s := Resolve(cfg, 1, &db.ChatSettings{ChatID: 1, ProactiveNewsProbability: &off, ProactiveNewsQueries: []string{"football", "weather"}})
+	if s.ProactiveNewsProbability != 0 || len(s.ProactiveNewsQueries) != 2 {
+		t.Errorf("expected the overrides, got %v %v", s.ProactiveNewsProbability, s.ProactiveNewsQueries)
+	}
+	tooHigh := 1.5
+	if err := Validate(&db.ChatSettings{ChatID: 1, ProactiveNewsProbability: &tooHigh}); err == nil {
+		t.Error("expected a probability over 1 to be rejected")
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, ProactiveNewsQueries: []string{" "}}); err == nil {
+		t.Error("expected a blank query to be rejected")
+	}
+	if err := Validate(&db.ChatSettings{ChatID: 1, ProactiveNewsQueries: make([]string, maxProactiveNewsQueries+1)}); err == nil {
+		t.Error("expected too many queries to be rejected")
+	}
+}
@@ -0,0 +1,106 @@
+package chatsettings
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// DefaultPersona is the built-in persona backed by PERSONA_FILE. It is never stored in the personas table.
+const DefaultPersona = "default"
+
+// ErrUnknownPersona is returned when switching a chat to a persona that does not exist.
+var ErrUnknownPersona = errors.New("unknown persona")
+
+var personaNameRe = regexp.MustCompile(`^[a-z0-9_-]{1,40}$`)
+
+// ValidPersonaName reports whether name is usable as a persona identifier.
+func ValidPersonaName(name string) bool {
+	return personaNameRe.MatchString(name)
+}
+
+// SystemPrompt returns the persona prompt for a chat's effective settings: the active named persona,
+// else the chat's inline persona override, else "" (meaning the global persona file).
+// A missing named persona (e.g. deleted meanwhile) falls back instead of failing the request.
+func (s *Store) SystemPrompt(ctx context.Context, st *Settings) string {
+	if st.ActivePersona == "" || s.db == nil {
+		return st.Persona
+	}
+	p, err := s.db.GetPersona(ctx, st.ActivePersona)
+	if err != nil {
+		slog.ErrorContext(ctx, "load persona failed", "chat_id", st.ChatID, "persona", st.ActivePersona, "error", err)
+		return st.Persona
+	}
+	if p == nil {
+		slog.WarnContext(ctx, "active persona not found, using default", "chat_id", st.ChatID, "persona", st.ActivePersona)
+		return st.Persona
+	}
+	return p.Prompt
+}
+
+// SetActivePersona switches a chat to a stored persona; "" or DefaultPersona switches back to the default.
+func (s *Store) SetActivePersona(ctx context.Context, chatID int64, name string) error {
+	var active *string
+	if name != "" && name != DefaultPersona {
+		p, err := s.db.GetPersona(ctx, name)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return ErrUnknownPersona
+		}
+		active = &name
+	}
+	if err := s.db.SetChatActivePersona(ctx, chatID, active); err != nil {
+		return err
+	}
+	s.invalidate(ctx, chatID)
+	return nil
+}
+
+// PersonaNames lists the personas a chat can switch to, starting with the default.
+func (s *Store) PersonaNames(ctx context.Context) ([]string, error) {
+	names := []string{DefaultPersona}
+	personas, err := s.db.ListPersonas(ctx)
+	if err != nil {
+		return names, err
+	}
+	for _, p := range personas {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// DeletePersona removes a stored persona; chats that had it active revert to the default.
+func (s *Store) DeletePersona(ctx context.Context, name string) error {
+	chats, err := s.db.DeletePersona(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, chatID := range chats {
+		s.invalidate(ctx, chatID)
+	}
+	return nil
+}
+
+// ValidatePersona rejects personas that cannot be stored.
+func ValidatePersona(p *db.Persona) error {
+	if !ValidPersonaName(p.Name) || p.Name == DefaultPersona {
+		return errors.New("name must be 1-40 characters of a-z, 0-9, - or _ and not \"default\"")
+	}
+	if p.Prompt == "" {
+		return errors.New("prompt is required")
+	}
+	return nil
+}
+
+// SavePersona validates and stores a persona.
+func (s *Store) SavePersona(ctx context.Context, p *db.Persona) error {
+	if err := ValidatePersona(p); err != nil {
+		return err
+	}
+	return s.db.UpsertPersona(ctx, p)
+}
@@ -2,12 +2,19 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
 )
 
-// Config holds all application configuration parsed from environment variables.
+// Config holds all application configuration parsed from environment variables and the optional
+// config file.
 type Config struct {
 	// Telegram
 	TelegramBotToken  string
@@ -20,6 +27,21 @@ type Config struct {
 	GeminiTemperature        float64
 	GeminiRoutingTemperature float64
 	GeminiThinkingBudget     int
+	// Canary: CanaryPercent of replies, and every reply in CanaryChatIDs, use CanaryModel (empty =
+	// GeminiModel) and CanaryTemperature (< 0 = the chat's temperature)
+	CanaryModel       string
+	CanaryTemperature float64
+	CanaryPercent     int
+	CanaryChatIDs     []int64
+	// Per-stage deadlines for a reply (0 = no limit of its own): one Gemini call, one tool call,
+	// and the whole generate/tool loop
+	LLMCallTimeoutSeconds  int
+	ToolTimeoutSeconds     int
+	ToolLoopTimeoutSeconds int
+	// Tool loop: model turns per reply before the model must answer, and how often the same call
+	// (tool and arguments) may repeat before the loop is broken
+	MaxToolIterations  int
+	MaxRepeatedToolCalls int
 
 	// OpenAI (Optional)
 	OpenAIAPIKey string
@@ -31,15 +53,47 @@ type Config struct {
 	PostgresUser     string
 	PostgresPassword string
 	PostgresDB       string
+	// TLS (libpq sslmode and certificate paths) and a full DSN that replaces all of the above
+	PostgresSSLMode     string
+	PostgresSSLRootCert string
+	PostgresSSLCert     string
+	PostgresSSLKey      string
+	DatabaseURL         string
+	// Connection pool
+	PostgresMaxConns               int
+	PostgresMinConns               int
+	PostgresMaxConnLifetimeSeconds int
+	PostgresMaxConnIdleSeconds     int
 
 	// Redis
 	RedisHost     string
 	RedisPort     int
 	RedisPassword string
+	// While Redis is unreachable: RedisFallbackMemory (per-instance limits and locks) or
+	// RedisFallbackOpen (no limits or locks); tools in RedisFailClosedTools are refused either way
+	RedisFallback        string
+	RedisFailClosedTools []string
 
 	// Backend Server
 	BackendHost string
 	BackendPort int
+	// Shared secret required on /api/v1/* (header or HMAC signature); empty = no auth
+	BackendAPISecret         string
+	BackendAPIMaxSkewSeconds int // how old a signed request's timestamp may be
+	// net/http/pprof under /debug/pprof/ (needs BackendAPISecret and an admin user_id)
+	EnablePprof bool
+	// Readiness probe (/ready): also check Gemini, caching the result between probes
+	ReadyCheckGemini        bool
+	ReadyGeminiCacheSeconds int
+	// Debug traces: prompt, tool calls and reply of every request, kept in Redis for admin replay
+	DebugTrace         bool
+	DebugTraceTTLHours int
+	// Admin read-only SQL endpoint (/api/v1/admin/query)
+	EnableAdminQuery         bool
+	AdminQueryRole           string // read-only Postgres role queries run as; EnableAdminQuery needs it
+	AdminQueryMaxRows        int
+	AdminQueryMaxBytes       int
+	AdminQueryTimeoutSeconds int
 
 	// Feature Toggles
 	EnableSandbox           bool
@@ -47,20 +101,76 @@ type Config struct {
 	EnableProactiveMessaging bool
 	EnableWebSearch         bool
 	EnableVoiceSTT          bool
+	EnablePersonaSwitch     bool
+	EnableKarma             bool
+	EnableGames             bool
+	EnableMoodTracking      bool
 
 	// Rate Limiting
 	RateLimitGlobalPerMinute int
 	RateLimitUserPerMinute   int
 	RateLimitImagePerDay     int
 	RateLimitSandboxPerDay   int
+	RateLimitGlobalBurst     int // token bucket size per chat (0 = RateLimitGlobalPerMinute)
+	RateLimitUserBurst       int // token bucket size per user (0 = RateLimitUserPerMinute)
+	RateLimitExemptUserIDs   []int64 // skip the chat and user limits everywhere
+	AdminBypassRateLimits    bool    // ADMIN_IDS skip the chat and user limits
+	// Backpressure: /process requests handled at once (0 = unlimited) and how long a request
+	// waits for a free slot before it is turned away with 429
+	MaxConcurrentRequests int
+	ConcurrencyWaitMS     int
 
 	// Sandbox
 	SandboxTimeoutSeconds int
 	SandboxMaxMemoryMB    int
+	SandboxMaxConcurrent  int // sandbox containers running at once (0 = unlimited)
+
+	// Outbound network policy for tools that make HTTP calls
+	EgressAllowedDomains       []string // empty = any public host
+	EgressInternalHosts        []string // webhook/MCP hosts (or CIDRs) allowed to be private
+	EgressMaxResponseBytes     int
+	EgressTimeoutSeconds       int
+	EgressAllowPrivateNetworks bool
+
+	// Indirect name mentions (messages mentioning the bot without @mention or reply)
+	BotNames                []string // lowercase name stems, including inflected ones ("гряг", "гряж")
+	MentionReplyProbability float64  // chance of replying to an indirect mention (0-1)
+	MentionDailyCap         int      // max indirect-mention replies per chat per day (0 = unlimited)
+
+	// Reply triggers besides private chats, commands and @mentions (see handler.evaluateTrigger)
+	TriggerReplyToBot       bool     // replies to the bot's messages trigger a reply
+	TriggerKeywords         []string // words or phrases that always trigger a reply
+	InterjectionProbability float64  // chance (0-1) of answering any other group message; shares MentionDailyCap
 
 	// Proactive Messaging (Kyiv time)
 	ProactiveActiveStartHour int // 0-23, inclusive
 	ProactiveActiveEndHour   int // 0-23, exclusive (e.g. 9-22 means 09:00–21:59)
+	// Health gate: skip proactive runs while Gemini is failing or slow (over the last window)
+	ProactiveHealthWindowMinutes int
+	ProactiveMaxErrorRate        float64 // 0-1; 0 = no error-rate check
+	ProactiveMaxP95LatencyMS     int     // 0 = no latency check
+	ProactiveHealthMinCalls      int     // fewer calls in the window = not enough data, run anyway
+	// Don't interrupt: skip chats where a person wrote within this many minutes (0 = off)
+	ProactiveMinSilenceMinutes int
+	// Quality gate: a cheap LLM pass scores each candidate 1-10 against recent bot messages and
+	// earlier proactive posts; lower scores are dropped (0 = only exact repeats are dropped)
+	ProactiveJudgeMinScore int
+	ProactiveHistorySize   int // earlier proactive posts kept per chat for the judge
+	// News turns: with this chance (0-1; 0 = never) a proactive turn without a topic hint must
+	// search the web for news. Query hints are suggested to the model; the instruction is the
+	// prompt line itself. Probability and hints can be overridden per chat.
+	ProactiveNewsProbability float64
+	ProactiveNewsQueries     []string
+	ProactiveNewsInstruction string
+	// Media turns: with this chance (0-1; 0 = never) a turn that is neither about a topic nor news
+	// is invited to attach a generated picture (needs generate_image in the chat)
+	ProactiveMediaProbability float64
+	// Follow-ups: promises in the bot's replies ("нагадаю завтра") are stored and brought up by a
+	// proactive turn once due (needs ENABLE_PROACTIVE_MESSAGING)
+	EnableFollowUps bool
+	// Queue delivery to the frontend (GET /api/v1/proactive and its SSE stream)
+	ProactiveAckTimeoutSeconds  int // unacknowledged items are redelivered after this long
+	ProactiveLongPollMaxSeconds int // cap on ?wait= for the long poll
 
 	// Summarization (3 AM Kyiv; 7-day every 3 days, 30-day every 12 days)
 	EnableSummarization       bool
@@ -68,13 +178,75 @@ type Config struct {
 	Summary7DayIntervalDays   int
 	Summary30DayIntervalDays  int
 	SummaryMaxMessagesPerWindow int
+	SummaryMessageThreshold     int // extra 7-day summary once a chat has this many unsummarized messages (0 = off)
+
+	// Deep research (background multi-search tool; needs ENABLE_WEB_SEARCH)
+	EnableDeepResearch     bool
+	DeepResearchMaxQueries int
+
+	// watch_video: YouTube links summarized by Gemini's video understanding
+	EnableWatchVideo bool
+
+	// AI-content label on generated images (chats can override)
+	WatermarkImages bool
+	WatermarkLabel  string
+
+	// Morning digest (per-chat opt-in; 24h summary pushed through the proactive queue)
+	EnableDailyDigest      bool
+	DailyDigestHour        int // 0-23, Kyiv time (default 9); chats can override
+	DailyDigestMinMessages int // skip chats quieter than this over the last 24h
+
+	// Weekly personal digest DM (per-user opt-in via set_personal_digest)
+	EnablePersonalDigest  bool
+	PersonalDigestWeekday int // 0 = Sunday … 6 = Saturday, Kyiv time
+	PersonalDigestHour    int // 0-23, Kyiv time
+
+	// Memory consolidation (nightly: merge duplicate facts, forget stale ones, cap per user)
+	EnableMemoryConsolidation  bool
+	MemoryConsolidationRunHour int // 0-23, Kyiv time (default 4)
+	FactDecayMonths            int // forget facts not referenced for this many months (0 = never)
+	MaxFactsPerUser            int // per user and chat (0 = unlimited)
+
+	// Topic index (hourly: segment new chat history into topics for recall_topic)
+	EnableTopicIndex      bool
+	TopicIndexMinMessages int // new messages a chat topic needs before it is segmented again
+
+	// Weekly activity report to admins' DMs (Kyiv time)
+	EnableActivityReport   bool
+	ActivityReportWeekday  int     // 0 = Sunday … 6 = Saturday (default 1, Monday)
+	ActivityReportHour     int     // 0-23, Kyiv time (default 10)
+	ActivityReportTopChats int     // busiest chats listed
+	LLMPriceInputPerMTok   float64 // USD per million prompt tokens, for spend estimates
+	LLMPriceOutputPerMTok  float64 // USD per million output (incl. thinking) tokens
 
 	// Context Window
-	ImmediateContextSize int
-	MediaBufferMax       int
+	ImmediateContextSize   int
+	MediaBufferMax         int
+	AlbumWaitMS            int // how long an album waits for its next item before it is answered; 0 = items answered one by one
+	MediaInlineMaxBytes    int // media up to this size is sent inline; bigger media goes through the Gemini Files API
+	ContextCacheTTLSeconds int // Redis cache of recent messages, summaries and facts; 0 = off
+	NotesInContext         int // chat notes shown in every prompt; 0 = none
+	UserProfileInContext   bool // a User Profile block (message stats, language, timezone) in every prompt
+
+	// Recent messages scored for the immediate context; the newest and the most informative
+	// ImmediateContextSize of them are kept. Up to ImmediateContextSize = a plain tail
+	ImmediateContextCandidates int
 
 	// Data Retention
-	MessageRetentionDays int
+	MessageRetentionDays int // default for chats without message_retention_days; 0 = keep forever
+	SummaryRetentionDays int // 0 = keep forever; the latest summary of each kind is always kept
+	RetentionRunHour     int // 0-23, Kyiv time (default 5)
+	RetentionArchiveDir  string // when set, expiring messages are archived here before deletion
+	ToolCallRetentionDays int   // tool_calls audit rows; 0 = keep forever
+
+	// PII scrubbing: emails, phone and card numbers (plus ScrubPatterns) are masked
+	ScrubLogs     bool     // in log output
+	ScrubMessages bool     // in message text stored in the database
+	ScrubPatterns []string // extra regular expressions to mask
+
+	// Encryption at rest of message text, transcripts and user facts (see Cipher)
+	EncryptionKey          string   // base64 or hex, 32 bytes; from ENCRYPTION_KEY or ENCRYPTION_KEY_FILE
+	EncryptionPreviousKeys []string // retired keys, still used to decrypt
 
 	// Media cache (generated images for edit by media_id)
 	MediaCacheDir      string
@@ -83,207 +255,477 @@ type Config struct {
 	// Persona
 	PersonaFile string
 
+	// Tool declarations (optional JSON overrides of tool descriptions/schemas)
+	ToolDeclarationsDir string
+
+	// Custom tools that POST their arguments to a webhook (empty = none)
+	WebhookToolsFile string
+
+	// MCP servers whose tools are offered next to the built-in ones (empty = none)
+	MCPConfigFile string
+
+	// Tool output budget: characters of tool output the model sees; 0 = no cap
+	ToolOutputMaxChars int
+	ToolOutputLimits   map[string]int // per-tool caps that replace ToolOutputMaxChars
+
 	// Telegram Mode
 	TelegramMode  string
 	WebhookURL    string
 	WebhookSecret string
 
 	// Localization
-	LocaleDir   string
-	DefaultLang string
+	LocaleDir                   string
+	DefaultLang                 string
+	LocaleReloadIntervalSeconds int // 0 = no automatic reload (use the admin endpoint)
+
+	// Validation: values that could not be applied, and whether they stop startup
+	ValidationMode string  // ValidationWarn or ValidationDeny
+	Issues         []Issue `json:"-"` // rejected values (their defaults were used)
+
+	ConfigFile string // CONFIG_FILE or --config; "" = environment only
 }
 
-// Load reads all configuration from environment variables.
+// Load reads all configuration from environment variables, layered over the config file named
+// by CONFIG_FILE when that is set.
 func Load() (*Config, error) {
+	return LoadFile(os.Getenv("CONFIG_FILE"))
+}
+
+// LoadFile is Load with the config file at path; "" reads the environment only. Environment
+// variables override the file's values.
+func LoadFile(path string) (*Config, error) {
+	l := &loader{}
+	if path != "" {
+		file, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		l.file = file
+	}
 	cfg := &Config{
+		ConfigFile: path,
+
 		// Telegram
-		TelegramBotToken: getEnv("TELEGRAM_BOT_TOKEN", ""),
-		AdminIDs:         parseAdminIDs(getEnv("ADMIN_IDS", "")),
-		AllowedChatIDs:   parseAdminIDs(getEnv("ALLOWED_CHAT_IDS", "")),
+		TelegramBotToken: l.getEnv("TELEGRAM_BOT_TOKEN", ""),
+		AdminIDs:         l.getEnvIDs("ADMIN_IDS"),
+		AllowedChatIDs:   l.getEnvIDs("ALLOWED_CHAT_IDS"),
 
 		// Gemini
-		GeminiAPIKey:             getEnv("GEMINI_API_KEY", ""),
-		GeminiModel:              getEnv("GEMINI_MODEL", "gemini-2.5-flash"),
-		GeminiTemperature:        getEnvFloat("GEMINI_TEMPERATURE", 0.9),
-		GeminiRoutingTemperature: getEnvFloat("GEMINI_ROUTING_TEMPERATURE", 0.0),
-		GeminiThinkingBudget:     getEnvInt("GEMINI_THINKING_BUDGET", 0),
+		GeminiAPIKey:             l.getEnv("GEMINI_API_KEY", ""),
+		GeminiModel:              l.getEnv("GEMINI_MODEL", "gemini-2.5-flash"),
+		GeminiTemperature:        l.getEnvFloat("GEMINI_TEMPERATURE", 0.9),
+		LLMCallTimeoutSeconds:    l.getEnvDuration("LLM_CALL_TIMEOUT_SECONDS", 60, time.Second),
+		ToolTimeoutSeconds:       l.getEnvDuration("TOOL_TIMEOUT_SECONDS", 60, time.Second),
+		ToolLoopTimeoutSeconds:   l.getEnvDuration("TOOL_LOOP_TIMEOUT_SECONDS", 110, time.Second),
+		MaxToolIterations:        l.getEnvIntRange("MAX_TOOL_ITERATIONS", 5, 1, 20),
+		MaxRepeatedToolCalls:     l.getEnvIntRange("MAX_REPEATED_TOOL_CALLS", 2, 1, 10),
+		GeminiRoutingTemperature: l.getEnvFloat("GEMINI_ROUTING_TEMPERATURE", 0.0),
+		GeminiThinkingBudget:     l.getEnvInt("GEMINI_THINKING_BUDGET", 0),
+		CanaryModel:              l.getEnv("CANARY_MODEL", ""),
+		CanaryTemperature:        l.getEnvFloat("CANARY_TEMPERATURE", -1),
+		CanaryPercent:            l.getEnvIntRange("CANARY_PERCENT", 0, 0, 100),
+		CanaryChatIDs:            l.getEnvIDs("CANARY_CHAT_IDS"),
 
 		// OpenAI
-		OpenAIAPIKey: getEnv("OPENAI_API_KEY", ""),
-		OpenAIModel:  getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		OpenAIAPIKey: l.getEnv("OPENAI_API_KEY", ""),
+		OpenAIModel:  l.getEnv("OPENAI_MODEL", "gpt-4o-mini"),
 
 		// PostgreSQL
-		PostgresHost:     getEnv("POSTGRES_HOST", "gryag-postgres"),
-		PostgresPort:     getEnvInt("POSTGRES_PORT", 5432),
-		PostgresUser:     getEnv("POSTGRES_USER", "gryag"),
-		PostgresPassword: getEnv("POSTGRES_PASSWORD", "changeme_in_production"),
-		PostgresDB:       getEnv("POSTGRES_DB", "gryag"),
+		PostgresHost:     l.getEnv("POSTGRES_HOST", "gryag-postgres"),
+		PostgresPort:     l.getEnvInt("POSTGRES_PORT", 5432),
+		PostgresUser:     l.getEnv("POSTGRES_USER", "gryag"),
+		PostgresPassword: l.getEnv("POSTGRES_PASSWORD", "changeme_in_production"),
+		PostgresDB:       l.getEnv("POSTGRES_DB", "gryag"),
+		PostgresSSLRootCert: l.getEnv("POSTGRES_SSLROOTCERT", ""),
+		PostgresSSLCert:     l.getEnv("POSTGRES_SSLCERT", ""),
+		PostgresSSLKey:      l.getEnv("POSTGRES_SSLKEY", ""),
+		DatabaseURL:         l.getEnv("DATABASE_URL", ""),
+		PostgresMaxConns:               l.getEnvIntRange("POSTGRES_MAX_CONNS", 25, 1, 1000),
+		PostgresMinConns:               l.getEnvIntRange("POSTGRES_MIN_CONNS", 2, 0, 1000),
+		PostgresMaxConnLifetimeSeconds: l.getEnvDuration("POSTGRES_MAX_CONN_LIFETIME_SECONDS", 300, time.Second),
+		PostgresMaxConnIdleSeconds:     l.getEnvDuration("POSTGRES_MAX_CONN_IDLE_SECONDS", 60, time.Second),
 
 		// Redis
-		RedisHost:     getEnv("REDIS_HOST", "gryag-redis"),
-		RedisPort:     getEnvInt("REDIS_PORT", 6379),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisHost:     l.getEnv("REDIS_HOST", "gryag-redis"),
+		RedisPort:     l.getEnvInt("REDIS_PORT", 6379),
+		RedisPassword: l.getEnv("REDIS_PASSWORD", ""),
+		RedisFailClosedTools: parseList(l.getEnv("REDIS_FAIL_CLOSED_TOOLS", "")),
 
 		// Backend Server
-		BackendHost: getEnv("BACKEND_HOST", "0.0.0.0"),
-		BackendPort: getEnvInt("BACKEND_PORT", 27710),
+		BackendHost: l.getEnv("BACKEND_HOST", "0.0.0.0"),
+		BackendPort: l.getEnvInt("BACKEND_PORT", 27710),
+		BackendAPISecret:         l.getEnv("BACKEND_API_SECRET", ""),
+		BackendAPIMaxSkewSeconds: l.getEnvDuration("BACKEND_API_MAX_SKEW_SECONDS", 300, time.Second),
+		EnablePprof:              l.getEnvBool("ENABLE_PPROF", false),
+		ReadyCheckGemini:         l.getEnvBool("READY_CHECK_GEMINI", false),
+		ReadyGeminiCacheSeconds:  l.getEnvDuration("READY_GEMINI_CACHE_SECONDS", 60, time.Second),
+		DebugTrace:               l.getEnvBool("DEBUG_TRACE", false),
+		DebugTraceTTLHours:       l.getEnvDuration("DEBUG_TRACE_TTL_HOURS", 24, time.Hour),
+		EnableAdminQuery:         l.getEnvBool("ENABLE_ADMIN_QUERY", false),
+		AdminQueryRole:           l.getEnv("ADMIN_QUERY_ROLE", ""),
+		AdminQueryMaxRows:        l.getEnvIntRange("ADMIN_QUERY_MAX_ROWS", 500, 1, 10000),
+		AdminQueryMaxBytes:       l.getEnvSize("ADMIN_QUERY_MAX_BYTES", 1<<20, 1),
+		AdminQueryTimeoutSeconds: l.getEnvDuration("ADMIN_QUERY_TIMEOUT_SECONDS", 10, time.Second),
 
 		// Feature Toggles
-		EnableSandbox:           getEnvBool("ENABLE_SANDBOX", true),
-		EnableImageGeneration:   getEnvBool("ENABLE_IMAGE_GENERATION", true),
-		EnableProactiveMessaging: getEnvBool("ENABLE_PROACTIVE_MESSAGING", false),
-		EnableWebSearch:         getEnvBool("ENABLE_WEB_SEARCH", true),
-		EnableVoiceSTT:          getEnvBool("ENABLE_VOICE_STT", false),
+		EnableSandbox:           l.getEnvBool("ENABLE_SANDBOX", true),
+		EnableImageGeneration:   l.getEnvBool("ENABLE_IMAGE_GENERATION", true),
+		EnableProactiveMessaging: l.getEnvBool("ENABLE_PROACTIVE_MESSAGING", false),
+		EnableWebSearch:         l.getEnvBool("ENABLE_WEB_SEARCH", true),
+		EnableVoiceSTT:          l.getEnvBool("ENABLE_VOICE_STT", false),
+		EnablePersonaSwitch:     l.getEnvBool("ENABLE_PERSONA_SWITCH", false),
+		EnableKarma:             l.getEnvBool("ENABLE_KARMA", false),
+		EnableGames:             l.getEnvBool("ENABLE_GAMES", false),
+		EnableMoodTracking:      l.getEnvBool("ENABLE_MOOD_TRACKING", true),
 
 		// Rate Limiting
-		RateLimitGlobalPerMinute: getEnvInt("RATE_LIMIT_GLOBAL_PER_MINUTE", 10),
-		RateLimitUserPerMinute:   getEnvInt("RATE_LIMIT_USER_PER_MINUTE", 3),
-		RateLimitImagePerDay:     getEnvInt("RATE_LIMIT_IMAGE_PER_DAY", 5),
-		RateLimitSandboxPerDay:   getEnvInt("RATE_LIMIT_SANDBOX_PER_DAY", 20),
+		RateLimitGlobalPerMinute: l.getEnvInt("RATE_LIMIT_GLOBAL_PER_MINUTE", 10),
+		RateLimitUserPerMinute:   l.getEnvInt("RATE_LIMIT_USER_PER_MINUTE", 3),
+		RateLimitImagePerDay:     l.getEnvInt("RATE_LIMIT_IMAGE_PER_DAY", 5),
+		RateLimitSandboxPerDay:   l.getEnvInt("RATE_LIMIT_SANDBOX_PER_DAY", 20),
+		RateLimitGlobalBurst:     l.getEnvIntRange("RATE_LIMIT_GLOBAL_BURST", 0, 0, 1000),
+		RateLimitUserBurst:       l.getEnvIntRange("RATE_LIMIT_USER_BURST", 0, 0, 1000),
+		RateLimitExemptUserIDs:   l.getEnvIDs("RATE_LIMIT_EXEMPT_USER_IDS"),
+		AdminBypassRateLimits:    l.getEnvBool("ADMIN_BYPASS_RATE_LIMITS", false),
+		MaxConcurrentRequests:    l.getEnvIntRange("MAX_CONCURRENT_REQUESTS", 32, 0, 10000),
+		ConcurrencyWaitMS:        l.getEnvDuration("CONCURRENCY_WAIT_MS", 5000, time.Millisecond),
 
 		// Sandbox
-		SandboxTimeoutSeconds: getEnvInt("SANDBOX_TIMEOUT_SECONDS", 5),
-		SandboxMaxMemoryMB:    getEnvInt("SANDBOX_MAX_MEMORY_MB", 128),
+		SandboxTimeoutSeconds: l.getEnvDuration("SANDBOX_TIMEOUT_SECONDS", 5, time.Second),
+		SandboxMaxMemoryMB:    l.getEnvSize("SANDBOX_MAX_MEMORY_MB", 128, 1 << 20),
+		SandboxMaxConcurrent:  l.getEnvIntRange("SANDBOX_MAX_CONCURRENT", 2, 0, 100),
+
+		// Outbound network policy
+		EgressAllowedDomains:       parseList(l.getEnv("EGRESS_ALLOWED_DOMAINS", "")),
+		EgressInternalHosts:        parseList(l.getEnv("EGRESS_INTERNAL_HOSTS", "")),
+		EgressMaxResponseBytes:     l.getEnvSize("EGRESS_MAX_RESPONSE_BYTES", 2*1024*1024, 1),
+		EgressTimeoutSeconds:       l.getEnvDuration("EGRESS_TIMEOUT_SECONDS", 10, time.Second),
+		EgressAllowPrivateNetworks: l.getEnvBool("EGRESS_ALLOW_PRIVATE_NETWORKS", false),
+
+		// Indirect name mentions
+		BotNames:                parseList(l.getEnv("BOT_NAMES", "гряг,гряж,gryag")),
+		MentionReplyProbability: l.getEnvFraction("MENTION_REPLY_PROBABILITY", 0.3),
+		MentionDailyCap:         l.getEnvInt("MENTION_DAILY_CAP", 20),
+
+		// Reply triggers
+		TriggerReplyToBot:       l.getEnvBool("TRIGGER_REPLY_TO_BOT", true),
+		TriggerKeywords:         parseList(l.getEnv("TRIGGER_KEYWORDS", "")),
+		InterjectionProbability: l.getEnvFraction("INTERJECTION_PROBABILITY", 0),
 
 		// Proactive Messaging (active hours in Kyiv time; parsed below)
 		ProactiveActiveStartHour: 9,
 		ProactiveActiveEndHour:   22,
+		ProactiveHealthWindowMinutes: l.getEnvDuration("PROACTIVE_HEALTH_WINDOW_MINUTES", 15, time.Minute),
+		ProactiveMaxErrorRate:        l.getEnvFraction("PROACTIVE_MAX_ERROR_RATE", 0.2),
+		ProactiveMaxP95LatencyMS:     l.getEnvDuration("PROACTIVE_MAX_P95_LATENCY_MS", 20000, time.Millisecond),
+		ProactiveHealthMinCalls:      l.getEnvInt("PROACTIVE_HEALTH_MIN_CALLS", 5),
+		ProactiveMinSilenceMinutes:   l.getEnvDuration("PROACTIVE_MIN_SILENCE_MINUTES", 10, time.Minute),
+		ProactiveJudgeMinScore:       l.getEnvIntRange("PROACTIVE_JUDGE_MIN_SCORE", 6, 0, 10),
+		ProactiveHistorySize:         l.getEnvIntRange("PROACTIVE_HISTORY_SIZE", 10, 1, 100),
+		ProactiveNewsProbability:     l.getEnvFraction("PROACTIVE_NEWS_PROBABILITY", 0.3),
+		ProactiveNewsQueries:         parseList(l.getEnv("PROACTIVE_NEWS_QUERIES", "")),
+		ProactiveNewsInstruction:     l.getEnv("PROACTIVE_NEWS_INSTRUCTION", DefaultProactiveNewsInstruction),
+		ProactiveMediaProbability:    l.getEnvFraction("PROACTIVE_MEDIA_PROBABILITY", 0.1),
+		EnableFollowUps:              l.getEnvBool("ENABLE_FOLLOW_UPS", true),
+		ProactiveAckTimeoutSeconds:   l.getEnvDuration("PROACTIVE_ACK_TIMEOUT_SECONDS", 60, time.Second),
+		ProactiveLongPollMaxSeconds:  l.getEnvDuration("PROACTIVE_LONG_POLL_MAX_SECONDS", 30, time.Second),
 
 		// Summarization (3 AM Kyiv; 7-day every 3 days, 30-day every 12 days)
-		EnableSummarization:         getEnvBool("ENABLE_SUMMARIZATION", false),
-		SummaryRunHour:              getEnvInt("SUMMARY_RUN_HOUR", 3),
-		Summary7DayIntervalDays:     getEnvInt("SUMMARY_7DAY_INTERVAL_DAYS", 3),
-		Summary30DayIntervalDays:    getEnvInt("SUMMARY_30DAY_INTERVAL_DAYS", 12),
-		SummaryMaxMessagesPerWindow: getEnvInt("SUMMARY_MAX_MESSAGES_PER_WINDOW", 2000),
+		EnableSummarization:         l.getEnvBool("ENABLE_SUMMARIZATION", false),
+		SummaryRunHour:              l.getEnvIntRange("SUMMARY_RUN_HOUR", 3, 0, 23),
+		Summary7DayIntervalDays:     l.getEnvInt("SUMMARY_7DAY_INTERVAL_DAYS", 3),
+		Summary30DayIntervalDays:    l.getEnvInt("SUMMARY_30DAY_INTERVAL_DAYS", 12),
+		SummaryMaxMessagesPerWindow: l.getEnvInt("SUMMARY_MAX_MESSAGES_PER_WINDOW", 2000),
+		SummaryMessageThreshold:     l.getEnvInt("SUMMARY_MESSAGE_THRESHOLD", 500),
+
+		// Deep research
+		EnableDeepResearch:     l.getEnvBool("ENABLE_DEEP_RESEARCH", false),
+		DeepResearchMaxQueries: l.getEnvInt("DEEP_RESEARCH_MAX_QUERIES", 4),
+
+		// YouTube video understanding
+		EnableWatchVideo: l.getEnvBool("ENABLE_WATCH_VIDEO", false),
+
+		// Image watermark
+		WatermarkImages: l.getEnvBool("WATERMARK_IMAGES", false),
+		WatermarkLabel:  l.getEnv("WATERMARK_LABEL", "AI"),
+
+		// Morning digest
+		EnableDailyDigest:      l.getEnvBool("ENABLE_DAILY_DIGEST", false),
+		DailyDigestHour:        l.getEnvIntRange("DAILY_DIGEST_HOUR", 9, 0, 23),
+		DailyDigestMinMessages: l.getEnvInt("DAILY_DIGEST_MIN_MESSAGES", 20),
+
+		// Weekly personal digest
+		EnablePersonalDigest:  l.getEnvBool("ENABLE_PERSONAL_DIGEST", false),
+		PersonalDigestWeekday: l.getEnvIntRange("PERSONAL_DIGEST_WEEKDAY", 0, 0, 6),
+		PersonalDigestHour:    l.getEnvIntRange("PERSONAL_DIGEST_HOUR", 18, 0, 23),
+
+		// Memory consolidation
+		EnableMemoryConsolidation:  l.getEnvBool("ENABLE_MEMORY_CONSOLIDATION", false),
+		MemoryConsolidationRunHour: l.getEnvIntRange("MEMORY_CONSOLIDATION_RUN_HOUR", 4, 0, 23),
+		FactDecayMonths:            l.getEnvInt("FACT_DECAY_MONTHS", 6),
+		MaxFactsPerUser:            l.getEnvInt("MAX_FACTS_PER_USER", 50),
+
+		// Topic index
+		EnableTopicIndex:      l.getEnvBool("ENABLE_TOPIC_INDEX", false),
+		TopicIndexMinMessages: l.getEnvIntRange("TOPIC_INDEX_MIN_MESSAGES", 30, 5, 300),
+
+		// Weekly activity report
+		EnableActivityReport:   l.getEnvBool("ENABLE_ACTIVITY_REPORT", false),
+		ActivityReportWeekday:  l.getEnvIntRange("ACTIVITY_REPORT_WEEKDAY", 1, 0, 6),
+		ActivityReportHour:     l.getEnvIntRange("ACTIVITY_REPORT_HOUR", 10, 0, 23),
+		ActivityReportTopChats: l.getEnvInt("ACTIVITY_REPORT_TOP_CHATS", 5),
+		LLMPriceInputPerMTok:   l.getEnvFloat("LLM_PRICE_INPUT_PER_MTOK", 0.30),
+		LLMPriceOutputPerMTok:  l.getEnvFloat("LLM_PRICE_OUTPUT_PER_MTOK", 2.50),
 
 		// Context Window
-		ImmediateContextSize: getEnvInt("IMMEDIATE_CONTEXT_SIZE", 50),
-		MediaBufferMax:       getEnvInt("MEDIA_BUFFER_MAX", 10),
+		ImmediateContextSize:   l.getEnvInt("IMMEDIATE_CONTEXT_SIZE", 50),
+		MediaBufferMax:         l.getEnvInt("MEDIA_BUFFER_MAX", 10),
+		AlbumWaitMS:            l.getEnvDuration("ALBUM_WAIT_MS", 1500, time.Millisecond),
+		MediaInlineMaxBytes:    l.getEnvSize("MEDIA_INLINE_MAX_BYTES", 4<<20, 1),
+		ContextCacheTTLSeconds: l.getEnvIntRange("CONTEXT_CACHE_TTL_SECONDS", 300, 0, 3600),
+		NotesInContext:         l.getEnvIntRange("NOTES_IN_CONTEXT", 10, 0, 50),
+		UserProfileInContext:   l.getEnvBool("USER_PROFILE_IN_CONTEXT", true),
+
+		ImmediateContextCandidates: l.getEnvIntRange("IMMEDIATE_CONTEXT_CANDIDATES", 100, 0, 500),
 
 		// Data Retention
-		MessageRetentionDays: getEnvInt("MESSAGE_RETENTION_DAYS", 90),
+		MessageRetentionDays: l.getEnvInt("MESSAGE_RETENTION_DAYS", 90),
+		SummaryRetentionDays: l.getEnvInt("SUMMARY_RETENTION_DAYS", 365),
+		RetentionRunHour:     l.getEnvIntRange("RETENTION_RUN_HOUR", 5, 0, 23),
+		RetentionArchiveDir:  l.getEnv("RETENTION_ARCHIVE_DIR", ""),
+		ToolCallRetentionDays: l.getEnvInt("TOOL_CALL_RETENTION_DAYS", 30),
+
+		// PII scrubbing
+		ScrubLogs:     l.getEnvBool("SCRUB_LOGS", false),
+		ScrubMessages: l.getEnvBool("SCRUB_STORED_MESSAGES", false),
+		ScrubPatterns: l.getEnvPatterns("SCRUB_PATTERNS"),
 
 		// Media cache (generated images, TTL for edit by media_id)
-		MediaCacheDir:      getEnv("MEDIA_CACHE_DIR", "/tmp/gryag_media_cache"),
-		MediaCacheTTLHours: getEnvInt("MEDIA_CACHE_TTL_HOURS", 48),
+		MediaCacheDir:      l.getEnv("MEDIA_CACHE_DIR", "/tmp/gryag_media_cache"),
+		MediaCacheTTLHours: l.getEnvDuration("MEDIA_CACHE_TTL_HOURS", 48, time.Hour),
 
 		// Persona
-		PersonaFile: getEnv("PERSONA_FILE", "config/persona.txt"),
+		PersonaFile: l.getEnv("PERSONA_FILE", "config/persona.txt"),
+
+		// Tool declarations
+		ToolDeclarationsDir: l.getEnv("TOOL_DECLARATIONS_DIR", ""),
+
+		// Webhook tools
+		WebhookToolsFile: l.getEnv("WEBHOOK_TOOLS_FILE", ""),
+
+		// MCP servers
+		MCPConfigFile: l.getEnv("MCP_CONFIG_FILE", ""),
+
+		// Tool output budget
+		ToolOutputMaxChars: l.getEnvInt("TOOL_OUTPUT_MAX_CHARS", 8000),
+		ToolOutputLimits:   l.getEnvLimits("TOOL_OUTPUT_LIMITS"),
 
 		// Telegram Mode
-		TelegramMode:  getEnv("TELEGRAM_MODE", "polling"),
-		WebhookURL:    getEnv("WEBHOOK_URL", ""),
-		WebhookSecret: getEnv("WEBHOOK_SECRET", ""),
+		TelegramMode:  l.getEnv("TELEGRAM_MODE", "polling"),
+		WebhookURL:    l.getEnv("WEBHOOK_URL", ""),
+		WebhookSecret: l.getEnv("WEBHOOK_SECRET", ""),
 
 		// Localization
-		LocaleDir:   getEnv("LOCALE_DIR", "config/locales"),
-		DefaultLang: getEnv("DEFAULT_LANG", "uk"),
+		LocaleDir:                   l.getEnv("LOCALE_DIR", "config/locales"),
+		DefaultLang:                 l.getEnv("DEFAULT_LANG", "uk"),
+		LocaleReloadIntervalSeconds: l.getEnvDuration("LOCALE_RELOAD_INTERVAL_SECONDS", 0, time.Second),
+	}
+	l.parseProactiveActiveHours("PROACTIVE_ACTIVE_HOURS_KYIV", cfg)
+	if cfg.CanaryTemperature > 2 {
+		l.report("CANARY_TEMPERATURE", strconv.FormatFloat(cfg.CanaryTemperature, 'g', -1, 64), "must be between 0 and 2", -1)
+		cfg.CanaryTemperature = -1
+	}
+
+	cfg.PostgresSSLMode = strings.ToLower(l.getEnv("POSTGRES_SSLMODE", "disable"))
+	if !slices.Contains(postgresSSLModes, cfg.PostgresSSLMode) {
+		l.report("POSTGRES_SSLMODE", cfg.PostgresSSLMode, "must be one of "+strings.Join(postgresSSLModes, ", "), "disable")
+		cfg.PostgresSSLMode = "disable"
+	}
+	if cfg.PostgresMinConns > cfg.PostgresMaxConns {
+		l.report("POSTGRES_MIN_CONNS", strconv.Itoa(cfg.PostgresMinConns), "must not exceed POSTGRES_MAX_CONNS", cfg.PostgresMaxConns)
+		cfg.PostgresMinConns = cfg.PostgresMaxConns
+	}
+
+	if cfg.MediaInlineMaxBytes > maxInlineRequestBytes {
+		l.report("MEDIA_INLINE_MAX_BYTES", strconv.Itoa(cfg.MediaInlineMaxBytes), "must be at most 14MB (Gemini caps inline data at 20MB, base64 included)", maxInlineRequestBytes)
+		cfg.MediaInlineMaxBytes = maxInlineRequestBytes
+	}
+
+	// The read-only role is the guarantee; the statement check alone can't list every function
+	// with side effects
+	if cfg.EnableAdminQuery && cfg.AdminQueryRole == "" {
+		l.report("ENABLE_ADMIN_QUERY", "true", "needs ADMIN_QUERY_ROLE, a Postgres role that can only SELECT", false)
+		cfg.EnableAdminQuery = false
+	}
+
+	cfg.RedisFallback = strings.ToLower(l.getEnv("REDIS_FALLBACK", RedisFallbackMemory))
+	if cfg.RedisFallback != RedisFallbackMemory && cfg.RedisFallback != RedisFallbackOpen {
+		l.report("REDIS_FALLBACK", cfg.RedisFallback, "must be memory or open", RedisFallbackMemory)
+		cfg.RedisFallback = RedisFallbackMemory
+	}
+
+	cfg.ValidationMode = strings.ToLower(l.getEnv("CONFIG_VALIDATION", ValidationWarn))
+	if cfg.ValidationMode != ValidationWarn && cfg.ValidationMode != ValidationDeny {
+		l.report("CONFIG_VALIDATION", cfg.ValidationMode, "must be warn or deny", ValidationWarn)
+		cfg.ValidationMode = ValidationWarn
 	}
-	parseProactiveActiveHours(getEnv("PROACTIVE_ACTIVE_HOURS_KYIV", "9-22"), cfg)
 
 	// Validate required fields
 	if cfg.GeminiAPIKey == "" {
 		return nil, fmt.Errorf("GEMINI_API_KEY is required")
 	}
+	if err := loadEncryptionKeys(l, cfg); err != nil {
+		return nil, err
+	}
+	l.reportUnknown()
+	cfg.Issues = l.issues
+	if cfg.ValidationMode == ValidationDeny && len(cfg.Issues) > 0 {
+		problems := make([]string, len(cfg.Issues))
+		for i, is := range cfg.Issues {
+			problems[i] = is.String()
+		}
+		return nil, fmt.Errorf("invalid configuration (CONFIG_VALIDATION=deny): %s", strings.Join(problems, "; "))
+	}
 
 	return cfg, nil
 }
 
-// PostgresDSN returns the PostgreSQL connection string.
-func (c *Config) PostgresDSN() string {
-	return fmt.Sprintf(
-		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
-		c.PostgresUser, c.PostgresPassword,
-		c.PostgresHost, c.PostgresPort,
-		c.PostgresDB,
-	)
+// loadEncryptionKeys reads ENCRYPTION_KEY (or the file named by ENCRYPTION_KEY_FILE, as mounted by
+// a secret manager or KMS agent) and ENCRYPTION_PREVIOUS_KEYS. A bad key is an error, not an
+// issue: falling back would store plaintext, or leave stored text unreadable.
+func loadEncryptionKeys(l *loader, cfg *Config) error {
+	key, file := strings.TrimSpace(l.lookup("ENCRYPTION_KEY")), l.lookup("ENCRYPTION_KEY_FILE")
+	if key != "" && file != "" {
+		return fmt.Errorf("set ENCRYPTION_KEY or ENCRYPTION_KEY_FILE, not both")
+	}
+	if file != "" {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("ENCRYPTION_KEY_FILE: %w", err)
+		}
+		key = strings.TrimSpace(string(b))
+	}
+	cfg.EncryptionKey = key
+	cfg.EncryptionPreviousKeys = parseList(l.lookup("ENCRYPTION_PREVIOUS_KEYS"))
+	if key == "" && len(cfg.EncryptionPreviousKeys) > 0 {
+		return fmt.Errorf("ENCRYPTION_PREVIOUS_KEYS needs ENCRYPTION_KEY")
+	}
+	_, err := cfg.Cipher()
+	return err
 }
 
-// RedisAddr returns the Redis connection address.
-func (c *Config) RedisAddr() string {
-	return fmt.Sprintf("%s:%d", c.RedisHost, c.RedisPort)
+// Cipher returns the cipher for encryption at rest, or nil when no key is set.
+func (c *Config) Cipher() (*atrest.Cipher, error) {
+	if c.EncryptionKey == "" {
+		return nil, nil
+	}
+	key, err := atrest.ParseKey(c.EncryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("ENCRYPTION_KEY: %w", err)
+	}
+	var previous [][]byte
+	for i, s := range c.EncryptionPreviousKeys {
+		k, err := atrest.ParseKey(s)
+		if err != nil {
+			return nil, fmt.Errorf("ENCRYPTION_PREVIOUS_KEYS[%d]: %w", i, err)
+		}
+		previous = append(previous, k)
+	}
+	return atrest.New(key, previous...)
 }
 
-// ListenAddr returns the backend server listen address.
-func (c *Config) ListenAddr() string {
-	return fmt.Sprintf("%s:%d", c.BackendHost, c.BackendPort)
+// CanaryEnabled reports whether some replies go to a canary model or temperature.
+func (c *Config) CanaryEnabled() bool {
+	return (c.CanaryPercent > 0 || len(c.CanaryChatIDs) > 0) && (c.CanaryModel != "" || c.CanaryTemperature >= 0)
 }
 
-// --- helpers ---
-
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// ToolOutputLimit returns the output cap in characters for a tool (0 = no cap).
+func (c *Config) ToolOutputLimit(tool string) int {
+	if n, ok := c.ToolOutputLimits[tool]; ok {
+		return n
 	}
-	return fallback
+	return c.ToolOutputMaxChars
 }
 
-func getEnvInt(key string, fallback int) int {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
+// postgresSSLModes are the libpq sslmode values POSTGRES_SSLMODE accepts.
+var postgresSSLModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// PostgresDSN returns the PostgreSQL connection string: DATABASE_URL as is when set, otherwise
+// one built from the POSTGRES_* settings.
+func (c *Config) PostgresDSN() string {
+	if c.DatabaseURL != "" {
+		return c.DatabaseURL
 	}
-	i, err := strconv.Atoi(v)
-	if err != nil {
-		return fallback
+	q := url.Values{"sslmode": {c.PostgresSSLMode}}
+	for key, path := range map[string]string{"sslrootcert": c.PostgresSSLRootCert, "sslcert": c.PostgresSSLCert, "sslkey": c.PostgresSSLKey} {
+		if path != "" {
+			q.Set(key, path)
+		}
 	}
-	return i
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.PostgresUser, c.PostgresPassword),
+		Host:     net.JoinHostPort(c.PostgresHost, strconv.Itoa(c.PostgresPort)),
+		Path:     "/" + c.PostgresDB,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
 }
 
-func getEnvFloat(key string, fallback float64) float64 {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
-	}
-	f, err := strconv.ParseFloat(v, 64)
-	if err != nil {
-		return fallback
-	}
-	return f
+// maxInlineRequestBytes is the largest MEDIA_INLINE_MAX_BYTES: Gemini caps a request's inline
+// data at 20 MB, and base64 adds a third.
+const maxInlineRequestBytes = 14 << 20
+
+// DefaultProactiveNewsInstruction is the prompt line of a proactive news turn
+// (PROACTIVE_NEWS_INSTRUCTION).
+const DefaultProactiveNewsInstruction = "This turn you MUST conduct a news search: call the search_web tool with a relevant query (e.g. trending or topical), then share something from the results in your reply."
+
+// What the rate limiter and queue lock do while Redis is unreachable (REDIS_FALLBACK).
+const (
+	RedisFallbackMemory = "memory" // per-instance in-memory limits and locks (the default)
+	RedisFallbackOpen   = "open"   // let everything through
+)
+
+// RedisAddr returns the Redis connection address.
+func (c *Config) RedisAddr() string {
+	return fmt.Sprintf("%s:%d", c.RedisHost, c.RedisPort)
 }
 
-func getEnvBool(key string, fallback bool) bool {
-	v := os.Getenv(key)
-	if v == "" {
-		return fallback
-	}
-	b, err := strconv.ParseBool(v)
-	if err != nil {
-		return fallback
-	}
-	return b
+// ImmediateContextPool returns how many recent messages to load for the immediate context:
+// IMMEDIATE_CONTEXT_CANDIDATES, or IMMEDIATE_CONTEXT_SIZE when that is more.
+func (c *Config) ImmediateContextPool() int {
+	return max(c.ImmediateContextSize, c.ImmediateContextCandidates)
 }
 
-func parseAdminIDs(raw string) []int64 {
-	if raw == "" {
-		return nil
-	}
-	parts := strings.Split(raw, ",")
-	ids := make([]int64, 0, len(parts))
-	for _, p := range parts {
-		p = strings.TrimSpace(p)
-		if id, err := strconv.ParseInt(p, 10, 64); err == nil {
-			ids = append(ids, id)
-		}
-	}
-	return ids
+// FollowUpsEnabled reports whether promises in replies are tracked: ENABLE_FOLLOW_UPS with
+// proactive messaging on, since only a proactive turn brings them up.
+func (c *Config) FollowUpsEnabled() bool {
+	return c.EnableFollowUps && c.EnableProactiveMessaging
 }
 
-// parseProactiveActiveHours sets cfg.ProactiveActiveStartHour and ProactiveActiveEndHour from
-// a string like "9-22" (09:00–22:00 Kyiv) or "22-6" (22:00–06:00 overnight). End is exclusive.
-func parseProactiveActiveHours(raw string, cfg *Config) {
-	raw = strings.TrimSpace(raw)
-	parts := strings.Split(raw, "-")
-	if len(parts) != 2 {
-		return
-	}
-	start, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
-	end, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
-	if err1 != nil || err2 != nil {
-		return
+// ProactiveQueueEnabled reports whether anything pushes to the proactive queue, i.e. whether the
+// frontend needs GET /api/v1/proactive.
+func (c *Config) ProactiveQueueEnabled() bool {
+	return c.EnableProactiveMessaging || c.EnableActivityReport || c.EnableDailyDigest || c.EnablePersonalDigest ||
+		(c.EnableDeepResearch && c.EnableWebSearch)
+}
+
+// Redacted returns a copy of the config with secrets masked, safe to log or return from the
+// admin API.
+func (c *Config) Redacted() Config {
+	r := *c
+	for _, secret := range []*string{&r.TelegramBotToken, &r.GeminiAPIKey, &r.OpenAIAPIKey, &r.PostgresPassword, &r.DatabaseURL, &r.RedisPassword, &r.WebhookSecret, &r.BackendAPISecret, &r.EncryptionKey} {
+		if *secret != "" {
+			*secret = "[redacted]"
+		}
 	}
-	if start >= 0 && start <= 23 && end >= 0 && end <= 23 {
-		cfg.ProactiveActiveStartHour = start
-		cfg.ProactiveActiveEndHour = end
+	if len(r.EncryptionPreviousKeys) > 0 {
+		r.EncryptionPreviousKeys = []string{"[redacted]"}
 	}
+	return r
+}
+
+// ListenAddr returns the backend server listen address.
+func (c *Config) ListenAddr() string {
+	return fmt.Sprintf("%s:%d", c.BackendHost, c.BackendPort)
 }
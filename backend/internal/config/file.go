@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// fileValue is one setting from CONFIG_FILE: a scalar, or an array kept as its elements so each
+// variable can join them with its own separator (commas, or semicolons for SCRUB_PATTERNS).
+type fileValue struct {
+	text  string
+	list  []string
+	array bool
+	key   string // dotted path in the file, for messages
+}
+
+func (v fileValue) join(sep string) string {
+	if v.array {
+		return strings.Join(v.list, sep)
+	}
+	return v.text
+}
+
+// readConfigFile parses the file named by CONFIG_FILE (or --config): YAML for .yaml and .yml,
+// TOML otherwise.
+func readConfigFile(path string) (map[string]fileValue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	format := "toml"
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+		format = "yaml"
+	}
+	values, err := parseConfigFile(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// parseConfigFile decodes a TOML or YAML document and flattens each key into the environment
+// variable it stands for: the table path and the key joined with underscores and upper-cased,
+// so rate_limit.user_per_minute is RATE_LIMIT_USER_PER_MINUTE. Values must be strings, numbers,
+// booleans, or arrays of those.
+func parseConfigFile(data []byte, format string) (map[string]fileValue, error) {
+	doc := make(map[string]any)
+	var err error
+	switch format {
+	case "yaml":
+		err = yaml.Unmarshal(data, &doc)
+	default:
+		_, err = toml.Decode(string(data), &doc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]fileValue)
+	if err := flattenConfig("", doc, values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// flattenConfig adds the settings of one table to values, walking keys in order so a clash is
+// reported the same way every time.
+func flattenConfig(prefix string, table map[string]any, values map[string]fileValue) error {
+	keys := make([]string, 0, len(table))
+	for k := range table {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		var v fileValue
+		switch raw := table[k].(type) {
+		case map[string]any:
+			if err := flattenConfig(key, raw, values); err != nil {
+				return err
+			}
+			continue
+		case []any:
+			v.array = true
+			v.list = make([]string, 0, len(raw))
+			for _, item := range raw {
+				s, err := configScalar(key, item)
+				if err != nil {
+					return err
+				}
+				v.list = append(v.list, s)
+			}
+		case []map[string]any:
+			return fmt.Errorf("%s: arrays of tables are not supported", key)
+		default:
+			s, err := configScalar(key, raw)
+			if err != nil {
+				return err
+			}
+			v.text = s
+		}
+		v.key = key
+		name := envName(key)
+		if prev, dup := values[name]; dup {
+			return fmt.Errorf("%s and %s both set %s", prev.key, key, name)
+		}
+		values[name] = v
+	}
+	return nil
+}
+
+// configScalar returns a string, number or boolean as the text an environment variable would hold.
+func configScalar(key string, v any) (string, error) {
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case bool:
+		return strconv.FormatBool(s), nil
+	case int:
+		return strconv.Itoa(s), nil
+	case int64:
+		return strconv.FormatInt(s, 10), nil
+	case uint64:
+		return strconv.FormatUint(s, 10), nil
+	case float64:
+		return strconv.FormatFloat(s, 'f', -1, 64), nil
+	case nil:
+		return "", fmt.Errorf("%s: missing value", key)
+	case []any, map[string]any, []map[string]any:
+		return "", fmt.Errorf("%s: nested arrays and tables inside arrays are not supported", key)
+	}
+	return "", fmt.Errorf("%s: unsupported value %v (quote durations, dates and sizes)", key, v)
+}
+
+// envName turns a dotted config file key into its environment variable name.
+func envName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	values, err := parseConfigFile([]byte(`# gryag settings
+gemini_model = "gemini-2.5-pro" # trailing comment
+admin_ids = [111, 222]
+
+[rate_limit]
+user_per_minute = 1_000
+global_burst = 5
+
+[proactive]
+news_instruction = 'Prefer "local" news'
+news_queries = [
+  "Kyiv",       # comment inside an array
+  "weather\tUA",
+]
+
+[enable]
+sandbox = true
+`), "toml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"GEMINI_MODEL":               "gemini-2.5-pro",
+		"ADMIN_IDS":                  "111,222",
+		"RATE_LIMIT_USER_PER_MINUTE": "1000",
+		"RATE_LIMIT_GLOBAL_BURST":    "5",
+		"PROACTIVE_NEWS_INSTRUCTION": `Prefer "local" news`,
+		"PROACTIVE_NEWS_QUERIES":     "Kyiv,weather\tUA",
+		"ENABLE_SANDBOX":             "true",
+	}
+	if len(values) != len(want) {
+		t.Errorf("expected %d values, got %v", len(want), values)
+	}
+	for key, v := range want {
+		if got := values[key].join(","); got != v {
+			t.Errorf("%s: expected %q, got %q", key, v, got)
+		}
+	}
+	if values["RATE_LIMIT_GLOBAL_BURST"].key != "rate_limit.global_burst" {
+		t.Errorf("expected key rate_limit.global_burst, got %q", values["RATE_LIMIT_GLOBAL_BURST"].key)
+	}
+}
+
+func TestParseConfigFile_YAML(t *testing.T) {
+	values, err := parseConfigFile([]byte(`# gryag settings
+gemini_model: gemini-2.5-pro
+admin_ids: [111, 222]
+rate_limit:
+  user_per_minute: 1000
+  global:
+    burst: 5
+proactive:
+  news_queries:
+    - Kyiv
+    - "weather\tUA"
+  probability: 0.25
+enable:
+  sandbox: true
+`), "yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]string{
+		"GEMINI_MODEL":               "gemini-2.5-pro",
+		"ADMIN_IDS":                  "111,222",
+		"RATE_LIMIT_USER_PER_MINUTE": "1000",
+		"RATE_LIMIT_GLOBAL_BURST":    "5",
+		"PROACTIVE_NEWS_QUERIES":     "Kyiv,weather\tUA",
+		"PROACTIVE_PROBABILITY":      "0.25",
+		"ENABLE_SANDBOX":             "true",
+	}
+	if len(values) != len(want) {
+		t.Errorf("expected %d values, got %v", len(want), values)
+	}
+	for key, v := range want {
+		if got := values[key].join(","); got != v {
+			t.Errorf("%s: expected %q, got %q", key, v, got)
+		}
+	}
+}
+
+func TestParseConfigFile_Errors(t *testing.T) {
+	for _, tc := range []struct{ name, format, data, want string }{
+		{"unquoted string", "toml", "gemini_model = gemini", "line 1"},
+		{"duplicate", "toml", "[rate_limit]\nuser_per_minute = 1\n[rate_limit]\nuser_per_minute = 2", "line 3"},
+		{"duplicate across tables", "toml", "rate_limit_user_burst = 1\n[rate_limit]\nuser_burst = 2", "rate_limit.user_burst and rate_limit_user_burst both set RATE_LIMIT_USER_BURST"},
+		{"unterminated string", "toml", `webhook_url = "https://x`, "unexpected EOF"},
+		{"nested array", "toml", "admin_ids = [[1]]", "admin_ids: nested arrays"},
+		{"table array", "toml", "[[tools]]\nname = 'x'", "tools: arrays of tables"},
+		{"datetime", "toml", "start = 2026-01-01T00:00:00Z", "start: unsupported value"},
+		{"yaml syntax", "yaml", "rate_limit:\n  user_burst: [1, 2\n", "line"},
+		{"yaml null", "yaml", "webhook_url:\n", "webhook_url: missing value"},
+		{"yaml duplicate", "yaml", "rate_limit_user_burst: 1\nrate_limit:\n  user_burst: 2\n", "both set RATE_LIMIT_USER_BURST"},
+		{"yaml table in array", "yaml", "tools:\n  - name: x\n", "tools: nested arrays"},
+	} {
+		if _, err := parseConfigFile([]byte(tc.data), tc.format); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "gryag.toml")
+	err := os.WriteFile(path, []byte(`gemini_api_key = "file-key"
+gemini_model = "file-model"
+scrub_patterns = ['\bTX-\d{4,8}\b', 'IBAN\s*UA\d{27}']
+
+[rate_limit]
+user_per_minute = 7
+global_per_minute = 70
+
+[proactive]
+active_hours_kyiv = "22-6"
+
+[sandbox]
+timeout_seconds = "90s"
+memroy_mb = 64
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GEMINI_MODEL", "env-model")
+	t.Setenv("RATE_LIMIT_GLOBAL_PER_MINUTE", "")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.GeminiAPIKey != "file-key" || cfg.GeminiModel != "env-model" {
+		t.Errorf("expected the file's key under the environment's model, got %q %q", cfg.GeminiAPIKey, cfg.GeminiModel)
+	}
+	if cfg.RateLimitUserPerMinute != 7 || cfg.RateLimitGlobalPerMinute != 70 {
+		t.Errorf("expected rate limits from the file (an empty variable counts as unset), got %d %d", cfg.RateLimitUserPerMinute, cfg.RateLimitGlobalPerMinute)
+	}
+	if cfg.ProactiveActiveStartHour != 22 || cfg.ProactiveActiveEndHour != 6 || cfg.SandboxTimeoutSeconds != 90 {
+		t.Errorf("expected proactive hours 22-6 and a 90s sandbox timeout, got %d-%d %d", cfg.ProactiveActiveStartHour, cfg.ProactiveActiveEndHour, cfg.SandboxTimeoutSeconds)
+	}
+	if len(cfg.ScrubPatterns) != 2 || cfg.ScrubPatterns[0] != `\bTX-\d{4,8}\b` {
+		t.Errorf("expected both patterns (they contain commas), got %q", cfg.ScrubPatterns)
+	}
+	if len(cfg.Issues) != 1 || cfg.Issues[0].Key != "SANDBOX_MEMROY_MB" || !strings.Contains(cfg.Issues[0].Problem, "sandbox.memroy_mb") {
+		t.Errorf("expected the misspelt key reported, got %v", cfg.Issues)
+	}
+	if cfg.ConfigFile != path {
+		t.Errorf("expected ConfigFile %q, got %q", path, cfg.ConfigFile)
+	}
+
+	t.Setenv("CONFIG_VALIDATION", "deny")
+	if _, err := LoadFile(path); err == nil || !strings.Contains(err.Error(), "SANDBOX_MEMROY_MB") {
+		t.Errorf("expected deny mode to fail on the unknown key, got %v", err)
+	}
+	if _, err := LoadFile(filepath.Join(t.TempDir(), "missing.toml")); err == nil {
+		t.Error("expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Validation modes for values that fail to parse or are out of range (CONFIG_VALIDATION).
+const (
+	ValidationWarn = "warn" // report the issue and use the default (the default mode)
+	ValidationDeny = "deny" // refuse to start
+)
+
+// Issue is one setting (an environment variable, or its config file key) that could not be
+// applied as given.
+type Issue struct {
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+	Problem  string `json:"problem"`
+	Fallback string `json:"fallback,omitempty"` // value used instead (warn mode)
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s=%q: %s", i.Key, i.Value, i.Problem)
+}
+
+// loader reads environment variables, falling back to the config file for ones that are unset,
+// and records every value it had to reject.
+type loader struct {
+	issues []Issue
+	file   map[string]fileValue // from CONFIG_FILE; nil without one
+	read   map[string]bool      // every key looked up, to spot unknown ones in the file
+}
+
+// lookup returns the value of key: the environment variable when set and non-empty, else the
+// config file's value, with arrays joined by commas.
+func (l *loader) lookup(key string) string {
+	return l.lookupSep(key, ",")
+}
+
+// lookupSep is lookup with arrays from the config file joined by sep.
+func (l *loader) lookupSep(key, sep string) string {
+	if l.read == nil {
+		l.read = make(map[string]bool)
+	}
+	l.read[key] = true
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return l.file[key].join(sep)
+}
+
+// reportUnknown records config file keys that no setting read, usually typos or a table name
+// that doesn't match the variable's prefix.
+func (l *loader) reportUnknown() {
+	keys := make([]string, 0, len(l.file))
+	for key := range l.file {
+		if !l.read[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		l.report(key, l.file[key].join(","), fmt.Sprintf("unknown setting (config file key %s); ignored", l.file[key].key), nil)
+	}
+}
+
+func (l *loader) report(key, value, problem string, fallback any) {
+	issue := Issue{Key: key, Value: value, Problem: problem}
+	if fallback != nil {
+		issue.Fallback = fmt.Sprint(fallback)
+	}
+	l.issues = append(l.issues, issue)
+}
+
+func (l *loader) getEnv(key, fallback string) string {
+	if v := l.lookup(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func (l *loader) getEnvInt(key string, fallback int) int {
+	v := strings.TrimSpace(l.lookup(key))
+	if v == "" {
+		return fallback
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		l.report(key, v, "not an integer", fallback)
+		return fallback
+	}
+	return i
+}
+
+// getEnvIntRange is getEnvInt limited to [lo, hi].
+func (l *loader) getEnvIntRange(key string, fallback, lo, hi int) int {
+	i := l.getEnvInt(key, fallback)
+	if i < lo || i > hi {
+		l.report(key, strconv.Itoa(i), fmt.Sprintf("must be between %d and %d", lo, hi), fallback)
+		return fallback
+	}
+	return i
+}
+
+func (l *loader) getEnvFloat(key string, fallback float64) float64 {
+	v := strings.TrimSpace(l.lookup(key))
+	if v == "" {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		l.report(key, v, "not a number", fallback)
+		return fallback
+	}
+	return f
+}
+
+// getEnvFraction is getEnvFloat limited to [0, 1].
+func (l *loader) getEnvFraction(key string, fallback float64) float64 {
+	f := l.getEnvFloat(key, fallback)
+	if f < 0 || f > 1 {
+		l.report(key, strconv.FormatFloat(f, 'g', -1, 64), "must be between 0 and 1", fallback)
+		return fallback
+	}
+	return f
+}
+
+func (l *loader) getEnvBool(key string, fallback bool) bool {
+	v := strings.TrimSpace(l.lookup(key))
+	if v == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.report(key, v, "not a boolean (use true or false)", fallback)
+		return fallback
+	}
+	return b
+}
+
+// getEnvDuration reads a count of unit, e.g. SANDBOX_TIMEOUT_SECONDS. Besides a plain number in
+// that unit it accepts a Go duration string ("90s", "2h", "1h30m"), which must be a whole
+// number of units. Negative values are rejected.
+func (l *loader) getEnvDuration(key string, fallback int, unit time.Duration) int {
+	v := strings.TrimSpace(l.lookup(key))
+	if v == "" {
+		return fallback
+	}
+	n, err := parseDuration(v, unit)
+	if err != nil {
+		l.report(key, v, err.Error(), fallback)
+		return fallback
+	}
+	return n
+}
+
+func parseDuration(v string, unit time.Duration) (int, error) {
+	if n, err := strconv.Atoi(v); err == nil {
+		if n < 0 {
+			return 0, fmt.Errorf("must not be negative")
+		}
+		return n, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("not a number or duration (e.g. 90s, 2h)")
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	if d%unit != 0 {
+		return 0, fmt.Errorf("must be a whole number of %s", unitName(unit))
+	}
+	return int(d / unit), nil
+}
+
+func unitName(unit time.Duration) string {
+	switch unit {
+	case time.Millisecond:
+		return "milliseconds"
+	case time.Second:
+		return "seconds"
+	case time.Minute:
+		return "minutes"
+	case time.Hour:
+		return "hours"
+	}
+	return unit.String()
+}
+
+// Size units; KB, MB and GB are binary (1024-based), like the *_MB variables have always been.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30},
+	{"B", 1},
+}
+
+// getEnvSize reads a size counted in unit bytes, e.g. SANDBOX_MAX_MEMORY_MB (unit 1 MiB). Besides
+// a plain number in that unit it accepts a size string ("64MB", "512KiB", "2G"), which must be a
+// whole number of units.
+func (l *loader) getEnvSize(key string, fallback int, unit int64) int {
+	v := strings.TrimSpace(l.lookup(key))
+	if v == "" {
+		return fallback
+	}
+	n, err := parseSize(v, unit)
+	if err != nil {
+		l.report(key, v, err.Error(), fallback)
+		return fallback
+	}
+	return n
+}
+
+func parseSize(v string, unit int64) (int, error) {
+	num, mult := strings.ToUpper(strings.ReplaceAll(v, " ", "")), unit
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(num, u.suffix) {
+			num, mult = strings.TrimSuffix(num, u.suffix), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a number or size (e.g. 64MB)")
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	bytes := n * mult
+	if bytes%unit != 0 {
+		return 0, fmt.Errorf("must be a whole number of %d-byte units", unit)
+	}
+	return int(bytes / unit), nil
+}
+
+// getEnvIDs reads a comma-separated list of Telegram IDs.
+func (l *loader) getEnvIDs(key string) []int64 {
+	raw := l.lookup(key)
+	if raw == "" {
+		return nil
+	}
+	parts := strings.Split(raw, ",")
+	ids := make([]int64, 0, len(parts))
+	for _, p := range parts {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		id, err := strconv.ParseInt(p, 10, 64)
+		if err != nil {
+			l.report(key, p, "not a numeric ID; entry skipped", nil)
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// getEnvLimits reads comma-separated name=number pairs, e.g. TOOL_OUTPUT_LIMITS
+// "search_messages=4000,fetch_url=12000". Malformed or negative entries are skipped.
+func (l *loader) getEnvLimits(key string) map[string]int {
+	entries := parseList(l.lookup(key))
+	if len(entries) == 0 {
+		return nil
+	}
+	limits := make(map[string]int, len(entries))
+	for _, e := range entries {
+		name, value, ok := strings.Cut(e, "=")
+		name = strings.TrimSpace(name)
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if !ok || name == "" || err != nil || n < 0 {
+			l.report(key, e, "not name=number with a number of 0 or more; entry skipped", nil)
+			continue
+		}
+		limits[name] = n
+	}
+	return limits
+}
+
+// getEnvPatterns reads semicolon-separated regular expressions (commas are common inside them,
+// e.g. \d{2,4}). Ones that don't compile are skipped.
+func (l *loader) getEnvPatterns(key string) []string {
+	var patterns []string
+	for _, p := range strings.Split(l.lookupSep(key, ";"), ";") {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		if _, err := regexp.Compile(p); err != nil {
+			l.report(key, p, "not a valid regular expression; pattern skipped", nil)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns
+}
+
+// parseList splits a comma-separated string into trimmed, non-empty entries.
+func parseList(raw string) []string {
+	if raw == "" {
+		return nil
+	}
+	var out []string
+	for _, p := range strings.Split(raw, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// parseProactiveActiveHours sets cfg.ProactiveActiveStartHour and ProactiveActiveEndHour from
+// a string like "9-22" (09:00–22:00 Kyiv) or "22-6" (22:00–06:00 overnight). End is exclusive.
+func (l *loader) parseProactiveActiveHours(key string, cfg *Config) {
+	raw := strings.TrimSpace(l.lookup(key))
+	if raw == "" {
+		return
+	}
+	fallback := fmt.Sprintf("%d-%d", cfg.ProactiveActiveStartHour, cfg.ProactiveActiveEndHour)
+	parts := strings.Split(raw, "-")
+	if len(parts) != 2 {
+		l.report(key, raw, "expected START-END hours, e.g. 9-22", fallback)
+		return
+	}
+	start, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	end, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		l.report(key, raw, "expected START-END hours, e.g. 9-22", fallback)
+		return
+	}
+	if start < 0 || start > 23 || end < 0 || end > 23 {
+		l.report(key, raw, "hours must be between 0 and 23", fallback)
+		return
+	}
+	cfg.ProactiveActiveStartHour = start
+	cfg.ProactiveActiveEndHour = end
+}
@@ -0,0 +1,130 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in   string
+		unit time.Duration
+		want int
+		ok   bool
+	}{
+		{"30", time.Second, 30, true},
+		{"90s", time.Second, 90, true},
+		{"2h", time.Minute, 120, true},
+		{"1h30m", time.Minute, 90, true},
+		{"1500ms", time.Millisecond, 1500, true},
+		{"90s", time.Minute, 0, false},
+		{"-5", time.Second, 0, false},
+		{"soon", time.Second, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.in, tt.unit)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseDuration(%q, %s) = %d, %v; want %d, ok=%v", tt.in, tt.unit, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		unit int64
+		want int
+		ok   bool
+	}{
+		{"128", 1 << 20, 128, true},
+		{"64MB", 1 << 20, 64, true},
+		{"1GiB", 1 << 20, 1024, true},
+		{"2mb", 1, 2 << 20, true},
+		{"512 KB", 1, 512 << 10, true},
+		{"100B", 1, 100, true},
+		{"512KB", 1 << 20, 0, false},
+		{"lots", 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseSize(tt.in, tt.unit)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseSize(%q, %d) = %d, %v; want %d, ok=%v", tt.in, tt.unit, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func setEnv(t *testing.T, kv map[string]string) {
+	t.Helper()
+	for k, v := range kv {
+		os.Setenv(k, v)
+	}
+	t.Cleanup(func() {
+		for k := range kv {
+			os.Unsetenv(k)
+		}
+	})
+}
+
+func TestLoad_ValidationReport(t *testing.T) {
+	setEnv(t, map[string]string{
+		"GEMINI_API_KEY":          "test-key",
+		"BACKEND_PORT":            "eighty",
+		"SANDBOX_TIMEOUT_SECONDS": "90s",
+		"SANDBOX_MAX_MEMORY_MB":   "1GB",
+		"SUMMARY_RUN_HOUR":        "25",
+		"ADMIN_IDS":               "111,bob",
+	})
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("warn mode should not fail: %v", err)
+	}
+	if cfg.BackendPort != 27710 || cfg.SummaryRunHour != 3 {
+		t.Errorf("rejected values should fall back to defaults, got port=%d hour=%d", cfg.BackendPort, cfg.SummaryRunHour)
+	}
+	if cfg.SandboxTimeoutSeconds != 90 || cfg.SandboxMaxMemoryMB != 1024 {
+		t.Errorf("expected parsed duration and size, got timeout=%d memory=%d", cfg.SandboxTimeoutSeconds, cfg.SandboxMaxMemoryMB)
+	}
+	if len(cfg.AdminIDs) != 1 || cfg.AdminIDs[0] != 111 {
+		t.Errorf("expected admin IDs [111], got %v", cfg.AdminIDs)
+	}
+	keys := map[string]bool{}
+	for _, is := range cfg.Issues {
+		keys[is.Key] = true
+	}
+	for _, k := range []string{"BACKEND_PORT", "SUMMARY_RUN_HOUR", "ADMIN_IDS"} {
+		if !keys[k] {
+			t.Errorf("expected an issue for %s, got %v", k, cfg.Issues)
+		}
+	}
+	if len(cfg.Issues) != 3 {
+		t.Errorf("expected 3 issues, got %v", cfg.Issues)
+	}
+}
+
+func TestLoad_ValidationDeny(t *testing.T) {
+	setEnv(t, map[string]string{
+		"GEMINI_API_KEY":    "test-key",
+		"CONFIG_VALIDATION": "deny",
+		"ENABLE_SANDBOX":    "maybe",
+	})
+	_, err := Load()
+	if err == nil || !strings.Contains(err.Error(), "ENABLE_SANDBOX") {
+		t.Fatalf("expected deny mode to fail on ENABLE_SANDBOX, got %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{GeminiAPIKey: "secret", PostgresPassword: "pw", GeminiModel: "m"}
+	r := cfg.Redacted()
+	if r.GeminiAPIKey == "secret" || r.PostgresPassword == "pw" || r.GeminiModel != "m" {
+		t.Errorf("unexpected redaction: %+v", r)
+	}
+	if r.OpenAIAPIKey != "" {
+		t.Error("empty secrets should stay empty")
+	}
+	if cfg.GeminiAPIKey != "secret" {
+		t.Error("Redacted must not modify the original")
+	}
+}
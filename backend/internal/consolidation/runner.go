@@ -0,0 +1,162 @@
+// Package consolidation runs the nightly memory maintenance job: merge duplicate user facts
+// via the LLM, forget facts nobody has referenced for months, and cap facts per user.
+package consolidation
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lastRunKey = "memory_consolidation:last_run"
+
+	// minFactsToConsolidate skips users with too few facts to be worth an LLM call.
+	minFactsToConsolidate = 5
+	// firstRunLookback bounds which users are consolidated when the job has never run.
+	firstRunLookback = 30 * 24 * time.Hour
+)
+
+// Runner performs one consolidation pass over all users' facts.
+type Runner struct {
+	db     *db.DB
+	cache  *cache.Cache
+	llm    *llm.Client
+	config *config.Config
+}
+
+// NewRunner creates a memory consolidation runner.
+func NewRunner(database *db.DB, c *cache.Cache, llmClient *llm.Client, cfg *config.Config) *Runner {
+	return &Runner{db: database, cache: c, llm: llmClient, config: cfg}
+}
+
+// RunOnce merges duplicates for users whose facts changed since the last run, then applies
+// decay (FactDecayMonths) and the per-user cap (MaxFactsPerUser). Each step is best effort.
+func (r *Runner) RunOnce(ctx context.Context) {
+	ctx = logging.With(ctx, "component", "memory_consolidation")
+
+	since := time.Now().Add(-firstRunLookback)
+	if last, err := r.GetLastRun(ctx); err != nil {
+		slog.WarnContext(ctx, "get last run failed", "error", err)
+	} else if last > 0 {
+		since = time.Unix(last, 0)
+	}
+
+	merged := 0
+	owners, err := r.db.GetFactOwnersForConsolidation(ctx, since, minFactsToConsolidate)
+	if err != nil {
+		slog.ErrorContext(ctx, "get fact owners failed", "error", err)
+	}
+	for _, o := range owners {
+		merged += r.consolidateOwner(ctx, o)
+	}
+
+	var decayed, trimmed int64
+	if r.config.FactDecayMonths > 0 {
+		decayed, err = r.db.DeleteStaleUserFacts(ctx, time.Now().AddDate(0, -r.config.FactDecayMonths, 0))
+		if err != nil {
+			slog.ErrorContext(ctx, "fact decay failed", "error", err)
+		}
+	}
+	if r.config.MaxFactsPerUser > 0 {
+		trimmed, err = r.db.TrimUserFacts(ctx, r.config.MaxFactsPerUser)
+		if err != nil {
+			slog.ErrorContext(ctx, "fact cap failed", "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "memory consolidation finished", "users", len(owners), "merged_groups", merged, "decayed", decayed, "trimmed", trimmed)
+}
+
+// consolidateOwner asks the LLM to merge one user's facts and applies the valid merges.
+func (r *Runner) consolidateOwner(ctx context.Context, o db.FactOwner) int {
+	ctx = logging.WithChat(ctx, o.ChatID, o.UserID)
+	facts, err := r.db.GetUserFacts(ctx, o.ChatID, o.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "get user facts failed", "error", err)
+		return 0
+	}
+	merges, err := r.llm.ConsolidateFacts(ctx, facts)
+	if err != nil {
+		slog.ErrorContext(ctx, "consolidate facts failed", "error", err)
+		return 0
+	}
+
+	applied := 0
+	for _, m := range validMerges(facts, merges) {
+		err := r.db.MergeUserFacts(ctx, o, m.KeepID, m.RemoveIDs, m.Text, db.NormalizeFactCategory(m.Category), db.ClampImportance(m.Importance))
+		if err != nil {
+			slog.WarnContext(ctx, "merge facts failed", "keep_id", m.KeepID, "error", err)
+			continue
+		}
+		applied++
+	}
+	if applied > 0 {
+		slog.InfoContext(ctx, "merged user facts", "groups", applied)
+	}
+	return applied
+}
+
+// validMerges drops merges that reference facts not in facts, reuse a fact across groups,
+// remove nothing, or have empty text — the model's output is never trusted blindly.
+func validMerges(facts []db.UserFact, merges []llm.FactMerge) []llm.FactMerge {
+	known := make(map[int64]bool, len(facts))
+	for _, f := range facts {
+		known[f.ID] = true
+	}
+	used := make(map[int64]bool)
+	var out []llm.FactMerge
+	for _, m := range merges {
+		if m.Text == "" || !known[m.KeepID] || used[m.KeepID] {
+			continue
+		}
+		var remove []int64
+		ok := true
+		for _, id := range m.RemoveIDs {
+			if id == m.KeepID {
+				continue
+			}
+			if !known[id] || used[id] || slices.Contains(remove, id) {
+				ok = false
+				break
+			}
+			remove = append(remove, id)
+		}
+		if !ok || len(remove) == 0 {
+			continue
+		}
+		used[m.KeepID] = true
+		for _, id := range remove {
+			used[id] = true
+		}
+		m.RemoveIDs = remove
+		out = append(out, m)
+	}
+	return out
+}
+
+// SetLastRun records the current time as the last completed consolidation run.
+func (r *Runner) SetLastRun(ctx context.Context) error {
+	return r.cache.Client().Set(ctx, lastRunKey, time.Now().Unix(), 0).Err()
+}
+
+// GetLastRun returns the Unix time of the last run (0 if never run).
+func (r *Runner) GetLastRun(ctx context.Context) (int64, error) {
+	val, err := r.cache.Client().Get(ctx, lastRunKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
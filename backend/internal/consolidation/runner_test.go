@@ -0,0 +1,33 @@
+package consolidation
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+func TestValidMerges(t *testing.T) {
+	facts := []db.UserFact{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	merges := []llm.FactMerge{
+		{KeepID: 1, RemoveIDs: []int64{2, 1}, Text: "ok, keep id in remove list is ignored"},
+		{KeepID: 3, RemoveIDs: []int64{99}, Text: "unknown id"},
+		{KeepID: 3, RemoveIDs: []int64{2}, Text: "fact 2 already merged"},
+		{KeepID: 4, RemoveIDs: nil, Text: "removes nothing"},
+		{KeepID: 4, RemoveIDs: []int64{5}, Text: ""},
+		{KeepID: 42, RemoveIDs: []int64{4}, Text: "unknown keep"},
+		{KeepID: 4, RemoveIDs: []int64{5, 5}, Text: "duplicate id"},
+		{KeepID: 5, RemoveIDs: []int64{3, 4}, Text: "ok"},
+	}
+	got := validMerges(facts, merges)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 valid merges, got %d: %+v", len(got), got)
+	}
+	if got[0].KeepID != 1 || !reflect.DeepEqual(got[0].RemoveIDs, []int64{2}) {
+		t.Errorf("first merge = %+v", got[0])
+	}
+	if got[1].KeepID != 5 || !reflect.DeepEqual(got[1].RemoveIDs, []int64{3, 4}) {
+		t.Errorf("second merge = %+v", got[1])
+	}
+}
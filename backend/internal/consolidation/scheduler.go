@@ -0,0 +1,64 @@
+package consolidation
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+const pollInterval = 1 * time.Minute
+
+// minRunGap keeps the job to one run per night even if the run hour is seen twice (restart, DST).
+const minRunGap = 20 * time.Hour
+
+// jobLockTTL bounds how long one replica holds the run (longer than any run should take).
+const jobLockTTL = 2 * time.Hour
+
+// Scheduler runs memory consolidation once a day at MemoryConsolidationRunHour (Kyiv).
+func Scheduler(ctx context.Context, r *Runner, cfg *config.Config) {
+	logger := slog.With("component", "memory_consolidation_scheduler")
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		kyiv, err = time.LoadLocation("Europe/Kiev")
+		if err != nil {
+			logger.Error("could not load Kyiv timezone", "error", err)
+			return
+		}
+	}
+	runHour := cfg.MemoryConsolidationRunHour
+	if runHour < 0 || runHour > 23 {
+		runHour = 4
+	}
+
+	for {
+		now := time.Now().In(kyiv)
+		if now.Hour() == runHour {
+			// With several replicas only the lock holder checks and runs; the others see the new last run.
+			lock, err := r.cache.AcquireJobLock(ctx, "memory_consolidation", jobLockTTL)
+			if err != nil {
+				logger.Warn("acquire job lock failed", "error", err)
+			} else if lock != nil {
+				last, err := r.GetLastRun(ctx)
+				if err != nil {
+					logger.Warn("get last run failed", "error", err)
+				} else if last == 0 || now.Sub(time.Unix(last, 0)) >= minRunGap {
+					logger.Info("running memory consolidation")
+					r.RunOnce(ctx)
+					_ = r.SetLastRun(ctx)
+				}
+				if err := r.cache.ReleaseJobLock(ctx, lock); err != nil {
+					logger.Warn("release job lock failed", "error", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+			continue
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+)
+
+// UserActivity is how many messages one user sent.
+type UserActivity struct {
+	UserID    int64  `json:"user_id"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Messages  int    `json:"messages"`
+}
+
+// HourActivity is how many messages were sent in one hour of the day (chat's timezone).
+type HourActivity struct {
+	Hour     int `json:"hour"`
+	Messages int `json:"messages"`
+}
+
+// TermCount is an emoji or word and how often it was used.
+type TermCount struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// ChatStats is the activity of one chat's users over [Since, Until). Bot replies and
+// off-the-record windows are not counted.
+type ChatStats struct {
+	ChatID      int64          `json:"chat_id"`
+	Since       time.Time      `json:"since"`
+	Until       time.Time      `json:"until"`
+	Messages    int            `json:"messages"`
+	ActiveUsers int            `json:"active_users"`
+	TopUsers    []UserActivity `json:"top_users"`
+	Hours       []HourActivity `json:"hours"` // only hours with messages, in order
+	TopEmoji    []TermCount    `json:"top_emoji"`
+	TopWords    []TermCount    `json:"top_words"` // by the number of messages using them
+}
+
+// statsMessages selects the counted messages of a chat; takes chat ID, since and until.
+const statsMessages = `
+	SELECT user_id, first_name, username, text, sticker_emoji, created_at FROM messages
+	WHERE chat_id = $1 AND created_at >= $2 AND created_at < $3
+	  AND NOT is_bot_reply AND user_id IS NOT NULL
+	  AND NOT is_off_record(chat_id, created_at)`
+
+// statsText is the text of a counted message for the emoji and word counts; encrypted text
+// (SetCipher) is left out, as SQL cannot read it.
+const statsText = `CASE WHEN text LIKE '` + atrest.Prefix + `%' THEN '' ELSE COALESCE(text, '') END`
+
+// minStatsWordLen drops short words, which are mostly particles and pronouns in any language.
+const minStatsWordLen = 4
+
+// GetChatStats aggregates a chat's activity over [since, until): messages per user, per hour of
+// day in the IANA timezone tz, and the top emoji and words, each list cut to top entries.
+func (d *DB) GetChatStats(ctx context.Context, chatID int64, since, until time.Time, tz string, top int) (*ChatStats, error) {
+	s := &ChatStats{ChatID: chatID, Since: since, Until: until,
+		TopUsers: []UserActivity{}, Hours: []HourActivity{}, TopEmoji: []TermCount{}, TopWords: []TermCount{}}
+
+	if err := d.pool.QueryRowContext(ctx, `
+		SELECT COUNT(*), COUNT(DISTINCT user_id) FROM (`+statsMessages+`) m`,
+		chatID, since, until).Scan(&s.Messages, &s.ActiveUsers); err != nil {
+		return nil, fmt.Errorf("chat stats totals: %w", err)
+	}
+	if s.Messages == 0 {
+		return s, nil
+	}
+
+	rows, err := d.pool.QueryContext(ctx, `
+		SELECT user_id,
+		       COALESCE((array_agg(first_name ORDER BY created_at DESC))[1], ''),
+		       COALESCE((array_agg(username ORDER BY created_at DESC))[1], ''),
+		       COUNT(*)
+		FROM (`+statsMessages+`) m
+		GROUP BY user_id
+		ORDER BY 4 DESC, 1
+		LIMIT $4`, chatID, since, until, top)
+	if err != nil {
+		return nil, fmt.Errorf("chat stats users: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u UserActivity
+		if err := rows.Scan(&u.UserID, &u.FirstName, &u.Username, &u.Messages); err != nil {
+			return nil, fmt.Errorf("scan user activity: %w", err)
+		}
+		s.TopUsers = append(s.TopUsers, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("chat stats users: %w", err)
+	}
+
+	hours, err := d.pool.QueryContext(ctx, `
+		SELECT EXTRACT(HOUR FROM created_at AT TIME ZONE $4)::int AS hour, COUNT(*)
+		FROM (`+statsMessages+`) m
+		GROUP BY 1
+		ORDER BY 1`, chatID, since, until, tz)
+	if err != nil {
+		return nil, fmt.Errorf("chat stats hours: %w", err)
+	}
+	defer hours.Close()
+	for hours.Next() {
+		var h HourActivity
+		if err := hours.Scan(&h.Hour, &h.Messages); err != nil {
+			return nil, fmt.Errorf("scan hour activity: %w", err)
+		}
+		s.Hours = append(s.Hours, h)
+	}
+	if err := hours.Err(); err != nil {
+		return nil, fmt.Errorf("chat stats hours: %w", err)
+	}
+
+	// Emoji in the main pictographic blocks, plus sticker emoji.
+	if s.TopEmoji, err = d.termCounts(ctx, `
+		SELECT e.term, COUNT(*) FROM (
+			SELECT (regexp_matches(`+statsText+` || COALESCE(sticker_emoji, ''),
+			        '[\U0001F300-\U0001FAFF\u2600-\u27BF]', 'g'))[1] AS term
+			FROM (`+statsMessages+`) m
+		) e
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT $4`, chatID, since, until, top); err != nil {
+		return nil, fmt.Errorf("chat stats emoji: %w", err)
+	}
+
+	// Words as the search index splits them ('simple' config: lowercased, no stemming), counted
+	// once per message.
+	if s.TopWords, err = d.termCounts(ctx, `
+		SELECT w.term, COUNT(*) FROM (
+			SELECT unnest(tsvector_to_array(to_tsvector('simple', `+statsText+`))) AS term
+			FROM (`+statsMessages+`) m
+		) w
+		WHERE char_length(w.term) >= $5 AND w.term !~ '^[0-9.,:/-]+$'
+		GROUP BY 1
+		ORDER BY 2 DESC, 1
+		LIMIT $4`, chatID, since, until, top, minStatsWordLen); err != nil {
+		return nil, fmt.Errorf("chat stats words: %w", err)
+	}
+	return s, nil
+}
+
+func (d *DB) termCounts(ctx context.Context, query string, args ...any) ([]TermCount, error) {
+	rows, err := d.pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	terms := []TermCount{}
+	for rows.Next() {
+		var t TermCount
+		if err := rows.Scan(&t.Term, &t.Count); err != nil {
+			return nil, err
+		}
+		terms = append(terms, t)
+	}
+	return terms, rows.Err()
+}
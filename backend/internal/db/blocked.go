@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// BlockedUser is a user the bot ignores in one chat, or everywhere when ChatID is 0.
+type BlockedUser struct {
+	UserID    int64      `json:"user_id"`
+	ChatID    int64      `json:"chat_id"`
+	Reason    string     `json:"reason,omitempty"`
+	BlockedBy *int64     `json:"blocked_by,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // nil = until unblocked
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BlockUser stores a block, replacing the reason, author and expiry of an existing one for the same
+// user and chat.
+func (d *DB) BlockUser(ctx context.Context, b *BlockedUser) error {
+	const query = `
+		INSERT INTO blocked_users (user_id, chat_id, reason, blocked_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, chat_id) DO UPDATE
+		SET reason = EXCLUDED.reason, blocked_by = EXCLUDED.blocked_by, expires_at = EXCLUDED.expires_at, created_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, b.UserID, b.ChatID, b.Reason, b.BlockedBy, b.ExpiresAt); err != nil {
+		return fmt.Errorf("block user: %w", err)
+	}
+	return nil
+}
+
+// UnblockUser removes a block; false when there was none.
+func (d *DB) UnblockUser(ctx context.Context, userID, chatID int64) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, "DELETE FROM blocked_users WHERE user_id = $1 AND chat_id = $2", userID, chatID)
+	if err != nil {
+		return false, fmt.Errorf("unblock user: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// IsUserBlocked reports whether a block that has not expired applies to the user in the chat
+// (a block for that chat or a global one).
+func (d *DB) IsUserBlocked(ctx context.Context, chatID, userID int64) (bool, error) {
+	const query = `
+		SELECT EXISTS (
+			SELECT 1 FROM blocked_users
+			WHERE user_id = $1 AND chat_id IN (0, $2) AND (expires_at IS NULL OR expires_at > NOW())
+		)`
+	var blocked bool
+	if err := d.pool.QueryRowContext(ctx, query, userID, chatID).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("check blocked user: %w", err)
+	}
+	return blocked, nil
+}
+
+// ListBlockedUsers returns the blocks that have not expired, newest first: those applying in chatID
+// (its own and the global ones), or all of them when chatID is 0.
+func (d *DB) ListBlockedUsers(ctx context.Context, chatID int64) ([]BlockedUser, error) {
+	rows, err := d.pool.QueryContext(ctx, `
+		SELECT user_id, chat_id, reason, blocked_by, expires_at, created_at
+		FROM blocked_users
+		WHERE ($1 = 0 OR chat_id IN (0, $1)) AND (expires_at IS NULL OR expires_at > NOW())
+		ORDER BY created_at DESC`, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("list blocked users: %w", err)
+	}
+	defer rows.Close()
+	out := []BlockedUser{}
+	for rows.Next() {
+		var b BlockedUser
+		if err := rows.Scan(&b.UserID, &b.ChatID, &b.Reason, &b.BlockedBy, &b.ExpiresAt, &b.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan blocked user: %w", err)
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
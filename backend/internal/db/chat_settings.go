@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// ChatSettings holds the per-chat overrides stored in chat_settings.
+// Nil pointer fields mean "inherit the global default from config".
+type ChatSettings struct {
+	ChatID           int64    `json:"chat_id"`
+	Language         *string  `json:"language,omitempty"`
+	Persona          *string  `json:"persona,omitempty"`
+	ActivePersona    *string  `json:"active_persona,omitempty"` // name of a stored persona; overrides Persona
+	ProactiveEnabled *bool    `json:"proactive_enabled,omitempty"`
+	DisabledTools    []string `json:"disabled_tools,omitempty"`
+	Temperature      *float64 `json:"temperature,omitempty"`
+
+	MentionReplyProbability *float64 `json:"mention_reply_probability,omitempty"`
+	MentionDailyCap         *int     `json:"mention_daily_cap,omitempty"`
+
+	SummaryLanguage     *string `json:"summary_language,omitempty"` // language code for chat summaries; nil = detect
+	SummaryEnabled      *bool   `json:"summary_enabled,omitempty"`
+	SummaryRunHour      *int    `json:"summary_run_hour,omitempty"`      // 0-23, Kyiv time
+	SummaryIntervalDays *int    `json:"summary_interval_days,omitempty"` // days between 7-day summaries
+	SummaryAnonymize    *bool   `json:"summary_anonymize,omitempty"`     // pseudonyms instead of names in summaries
+
+	DigestEnabled *bool `json:"digest_enabled,omitempty"` // morning digest (opt-in)
+	DigestHour    *int  `json:"digest_hour,omitempty"`    // 0-23, Kyiv time
+
+	WatermarkEnabled *bool   `json:"watermark_enabled,omitempty"` // label generated images
+	WatermarkLabel   *string `json:"watermark_label,omitempty"`   // badge text, e.g. "AI"
+
+	ProactiveMinIntervalMinutes *int    `json:"proactive_min_interval_minutes,omitempty"` // min gap between proactive messages
+	ProactiveMaxIntervalMinutes *int    `json:"proactive_max_interval_minutes,omitempty"` // chat is preferred once this long has passed
+	ProactiveQuietStart         *int    `json:"proactive_quiet_start,omitempty"`          // 0-23 in Timezone
+	ProactiveQuietEnd           *int    `json:"proactive_quiet_end,omitempty"`            // 0-23 in Timezone, exclusive
+	Timezone                    *string `json:"timezone,omitempty"`                       // IANA name, e.g. "Europe/Warsaw"
+
+	MessageRetentionDays *int `json:"message_retention_days,omitempty"` // 0 = keep forever
+
+	ShadowMode *bool `json:"shadow_mode,omitempty"` // generate and log replies without sending them
+
+	RateLimitExemptUsers []int64 `json:"rate_limit_exempt_users,omitempty"` // skip the chat and user rate limits
+
+	ProactiveNewsProbability *float64 `json:"proactive_news_probability,omitempty"` // 0 = no news turns
+	ProactiveNewsQueries     []string `json:"proactive_news_queries,omitempty"`     // query hints for news turns
+
+	PrivacyMode *bool `json:"privacy_mode,omitempty"` // pseudonyms instead of names and user IDs in prompts
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+const chatSettingsColumns = `chat_id, language, persona, proactive_enabled, disabled_tools, temperature,
+	mention_reply_probability, mention_daily_cap, active_persona, summary_language,
+	summary_enabled, summary_run_hour, summary_interval_days, summary_anonymize,
+	digest_enabled, digest_hour, watermark_enabled, watermark_label,
+	proactive_min_interval_minutes, proactive_max_interval_minutes, proactive_quiet_start, proactive_quiet_end,
+	timezone, message_retention_days, shadow_mode, rate_limit_exempt_users,
+	proactive_news_probability, proactive_news_queries, privacy_mode, updated_at`
+
+// scanChatSettings scans one chat_settings row in chatSettingsColumns order.
+func scanChatSettings(row interface{ Scan(...any) error }) (*ChatSettings, error) {
+	var s ChatSettings
+	if err := row.Scan(
+		&s.ChatID, &s.Language, &s.Persona, &s.ProactiveEnabled,
+		array(&s.DisabledTools), &s.Temperature,
+		&s.MentionReplyProbability, &s.MentionDailyCap, &s.ActivePersona, &s.SummaryLanguage,
+		&s.SummaryEnabled, &s.SummaryRunHour, &s.SummaryIntervalDays, &s.SummaryAnonymize,
+		&s.DigestEnabled, &s.DigestHour, &s.WatermarkEnabled, &s.WatermarkLabel,
+		&s.ProactiveMinIntervalMinutes, &s.ProactiveMaxIntervalMinutes, &s.ProactiveQuietStart, &s.ProactiveQuietEnd,
+		&s.Timezone, &s.MessageRetentionDays, &s.ShadowMode, array(&s.RateLimitExemptUsers),
+		&s.ProactiveNewsProbability, array(&s.ProactiveNewsQueries), &s.PrivacyMode, &s.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// GetChatSettings returns the stored overrides for a chat, or nil if the chat has none.
+func (d *DB) GetChatSettings(ctx context.Context, chatID int64) (*ChatSettings, error) {
+	query := `SELECT ` + chatSettingsColumns + ` FROM chat_settings WHERE chat_id = $1`
+	s, err := scanChatSettings(d.pool.QueryRowContext(ctx, query, chatID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get chat settings: %w", err)
+	}
+	return s, nil
+}
+
+// ListChatSettings returns all chats that have stored overrides, ordered by chat_id.
+func (d *DB) ListChatSettings(ctx context.Context) ([]ChatSettings, error) {
+	query := `SELECT ` + chatSettingsColumns + ` FROM chat_settings ORDER BY chat_id`
+	rows, err := d.pool.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list chat settings: %w", err)
+	}
+	defer rows.Close()
+
+	var out []ChatSettings
+	for rows.Next() {
+		s, err := scanChatSettings(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan chat settings: %w", err)
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+// UpsertChatSettings inserts or fully replaces the overrides for a chat.
+func (d *DB) UpsertChatSettings(ctx context.Context, s *ChatSettings) error {
+	const query = `
+		INSERT INTO chat_settings (chat_id, language, persona, proactive_enabled, disabled_tools, temperature,
+			mention_reply_probability, mention_daily_cap, active_persona, summary_language,
+			summary_enabled, summary_run_hour, summary_interval_days, summary_anonymize,
+			digest_enabled, digest_hour, watermark_enabled, watermark_label,
+			proactive_min_interval_minutes, proactive_max_interval_minutes, proactive_quiet_start, proactive_quiet_end,
+			timezone, message_retention_days, shadow_mode, rate_limit_exempt_users,
+			proactive_news_probability, proactive_news_queries, privacy_mode)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29)
+		ON CONFLICT (chat_id) DO UPDATE SET
+			language = EXCLUDED.language,
+			persona = EXCLUDED.persona,
+			proactive_enabled = EXCLUDED.proactive_enabled,
+			disabled_tools = EXCLUDED.disabled_tools,
+			temperature = EXCLUDED.temperature,
+			mention_reply_probability = EXCLUDED.mention_reply_probability,
+			mention_daily_cap = EXCLUDED.mention_daily_cap,
+			active_persona = EXCLUDED.active_persona,
+			summary_language = EXCLUDED.summary_language,
+			summary_enabled = EXCLUDED.summary_enabled,
+			summary_run_hour = EXCLUDED.summary_run_hour,
+			summary_interval_days = EXCLUDED.summary_interval_days,
+			summary_anonymize = EXCLUDED.summary_anonymize,
+			digest_enabled = EXCLUDED.digest_enabled,
+			digest_hour = EXCLUDED.digest_hour,
+			watermark_enabled = EXCLUDED.watermark_enabled,
+			watermark_label = EXCLUDED.watermark_label,
+			proactive_min_interval_minutes = EXCLUDED.proactive_min_interval_minutes,
+			proactive_max_interval_minutes = EXCLUDED.proactive_max_interval_minutes,
+			proactive_quiet_start = EXCLUDED.proactive_quiet_start,
+			proactive_quiet_end = EXCLUDED.proactive_quiet_end,
+			timezone = EXCLUDED.timezone,
+			message_retention_days = EXCLUDED.message_retention_days,
+			shadow_mode = EXCLUDED.shadow_mode,
+			rate_limit_exempt_users = EXCLUDED.rate_limit_exempt_users,
+			proactive_news_probability = EXCLUDED.proactive_news_probability,
+			proactive_news_queries = EXCLUDED.proactive_news_queries,
+			privacy_mode = EXCLUDED.privacy_mode,
+			updated_at = NOW()`
+	disabled := s.DisabledTools
+	if disabled == nil {
+		disabled = []string{}
+	}
+	exempt := s.RateLimitExemptUsers
+	if exempt == nil {
+		exempt = []int64{}
+	}
+	newsQueries := s.ProactiveNewsQueries
+	if newsQueries == nil {
+		newsQueries = []string{}
+	}
+	_, err := d.pool.ExecContext(ctx, query,
+		s.ChatID, s.Language, s.Persona, s.ProactiveEnabled,
+		disabled, s.Temperature,
+		s.MentionReplyProbability, s.MentionDailyCap, s.ActivePersona, s.SummaryLanguage,
+		s.SummaryEnabled, s.SummaryRunHour, s.SummaryIntervalDays, s.SummaryAnonymize,
+		s.DigestEnabled, s.DigestHour, s.WatermarkEnabled, s.WatermarkLabel,
+		s.ProactiveMinIntervalMinutes, s.ProactiveMaxIntervalMinutes, s.ProactiveQuietStart, s.ProactiveQuietEnd,
+		s.Timezone, s.MessageRetentionDays, s.ShadowMode, exempt,
+		s.ProactiveNewsProbability, newsQueries, s.PrivacyMode,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert chat settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteChatSettings removes all overrides for a chat so it falls back to the defaults.
+func (d *DB) DeleteChatSettings(ctx context.Context, chatID int64) error {
+	_, err := d.pool.ExecContext(ctx, "DELETE FROM chat_settings WHERE chat_id = $1", chatID)
+	if err != nil {
+		return fmt.Errorf("delete chat settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ContextCache is the Redis side (*cache.Cache) of the reply-context cache.
+type ContextCache interface {
+	GetJSON(ctx context.Context, key string, v any) (bool, error)
+	SetJSON(ctx context.Context, key string, v any, ttl time.Duration) error
+	GetCounts(ctx context.Context, keys ...string) ([]int64, error)
+	IncrCount(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	ListTail(ctx context.Context, key string, n int) ([]string, error)
+	FillList(ctx context.Context, key, versionKey string, version int64, items []string, ttl time.Duration) (bool, error)
+	AppendList(ctx context.Context, key, versionKey, item string, max int, versionTTL time.Duration) error
+	DropList(ctx context.Context, key, versionKey string, versionTTL time.Duration) error
+}
+
+// Recent messages are cached write-through: a capped list per topic that InsertMessage appends
+// to. Summaries and facts change rarely and are cached as JSON under keys carrying a version
+// counter, which writes bump; superseded entries just expire.
+const (
+	messagesKey        = "ctx:msgs:%d:%d"     // chat, thread
+	messagesVersionKey = "ctx:ver:msgs:%d:%d" // chat, thread
+	summaryVersionKey  = "ctx:ver:sum:%d:%d"  // chat, thread
+	userVersionKey     = "ctx:ver:user:%d"    // bumped by fact writes of a user
+	factsVersionKey    = "ctx:ver:facts"      // bumped by fact writes whose owner is unknown (bulk)
+
+	// contextVersionTTL outlives every entry, so a version counter never resets while an
+	// entry written under an earlier value is still readable.
+	contextVersionTTL = 24 * time.Hour
+)
+
+// replySummaries is the cached summary half of a ReplyContext.
+type replySummaries struct {
+	Summary30Day string
+	Summary7Day  string
+}
+
+// replyFacts is the cached per-user half of a ReplyContext.
+type replyFacts struct {
+	Facts      []UserFact
+	FactsTotal int
+}
+
+// SetContextCache enables the reply-context cache: summaries and facts are kept for ttl, and
+// the newest maxMessages messages of each topic for ttl after they were loaded. A nil cache or
+// a non-positive ttl disables it.
+func (d *DB) SetContextCache(c ContextCache, ttl time.Duration, maxMessages int) {
+	if c == nil || ttl <= 0 || maxMessages <= 0 {
+		d.ctxCache, d.ctxCacheTTL, d.ctxCacheMessages = nil, 0, 0
+		return
+	}
+	d.ctxCache, d.ctxCacheTTL, d.ctxCacheMessages = c, ttl, maxMessages
+}
+
+func summariesKey(chatID, threadID int64, ver int64) string {
+	return fmt.Sprintf("ctx:sum:%d:%d:v%d", chatID, threadID, ver)
+}
+
+func factsKey(chatID, userID int64, limit int, userVer, factsVer int64) string {
+	return fmt.Sprintf("ctx:facts:%d:%d:%d:v%d.%d", chatID, userID, limit, userVer, factsVer)
+}
+
+// messageCache is where the recent messages of one topic live in the context cache.
+type messageCache struct {
+	key, versionKey string
+	version         int64
+}
+
+// messagesCacheable reports whether a recent-messages read can be served from the cache.
+func (d *DB) messagesCacheable(threadID int64, limit int) bool {
+	return d.ctxCache != nil && threadID >= 0 && limit > 0 && limit <= d.ctxCacheMessages
+}
+
+// cachedMessages returns the last limit messages of a topic from the cache (version is the
+// topic's messagesVersionKey as read before). On a miss, load d.ctxCacheMessages of them and
+// hand them to fillMessages with mc.
+func (d *DB) cachedMessages(ctx context.Context, chatID, threadID int64, limit int, version int64) (msgs []Message, hit bool, mc messageCache) {
+	mc = messageCache{
+		key:        fmt.Sprintf(messagesKey, chatID, threadID),
+		versionKey: fmt.Sprintf(messagesVersionKey, chatID, threadID),
+		version:    version,
+	}
+	items, err := d.ctxCache.ListTail(ctx, mc.key, limit)
+	if err != nil {
+		slog.WarnContext(ctx, "context cache: read messages failed", "chat_id", chatID, "error", err)
+		return nil, false, mc
+	}
+	// The list is filled with the newest d.ctxCacheMessages rows and only ever appended to, so
+	// when it exists and is shorter than limit it holds the whole topic.
+	if len(items) == 0 {
+		return nil, false, mc
+	}
+	msgs = make([]Message, len(items))
+	for i, it := range items {
+		if err := json.Unmarshal([]byte(it), &msgs[i]); err != nil {
+			slog.WarnContext(ctx, "context cache: decode message failed", "chat_id", chatID, "error", err)
+			return nil, false, mc
+		}
+	}
+	return msgs, true, mc
+}
+
+// fillMessages stores freshly loaded messages (oldest first) in the cache.
+func (d *DB) fillMessages(ctx context.Context, mc messageCache, msgs []Message) {
+	items := make([]string, len(msgs))
+	for i, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return
+		}
+		items[i] = string(b)
+	}
+	if _, err := d.ctxCache.FillList(ctx, mc.key, mc.versionKey, mc.version, items, d.ctxCacheTTL); err != nil {
+		slog.WarnContext(ctx, "context cache: fill messages failed", "key", mc.key, "error", err)
+	}
+}
+
+// appendMessage writes a newly stored message through to the cached list of its topic.
+// m must look like a recentMessagesQuery row.
+func (d *DB) appendMessage(ctx context.Context, m Message) {
+	if d.ctxCache == nil {
+		return
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	key := fmt.Sprintf(messagesKey, m.ChatID, m.ThreadID)
+	if err := d.ctxCache.AppendList(ctx, key, fmt.Sprintf(messagesVersionKey, m.ChatID, m.ThreadID),
+		string(b), d.ctxCacheMessages, contextVersionTTL); err != nil {
+		slog.WarnContext(ctx, "context cache: append message failed", "key", key, "error", err)
+	}
+}
+
+// dropMessages forgets the cached list of a topic after messages in it changed.
+func (d *DB) dropMessages(ctx context.Context, chatID, threadID int64) {
+	if d.ctxCache == nil {
+		return
+	}
+	key := fmt.Sprintf(messagesKey, chatID, threadID)
+	if err := d.ctxCache.DropList(ctx, key, fmt.Sprintf(messagesVersionKey, chatID, threadID), contextVersionTTL); err != nil {
+		slog.WarnContext(ctx, "context cache: drop messages failed", "key", key, "error", err)
+	}
+}
+
+// getCached reads key into v; a failing cache counts as a miss.
+func (d *DB) getCached(ctx context.Context, key string, v any) bool {
+	found, err := d.ctxCache.GetJSON(ctx, key, v)
+	if err != nil {
+		slog.WarnContext(ctx, "context cache: get failed", "key", key, "error", err)
+		return false
+	}
+	return found
+}
+
+func (d *DB) setCached(ctx context.Context, key string, v any) {
+	if err := d.ctxCache.SetJSON(ctx, key, v, d.ctxCacheTTL); err != nil {
+		slog.WarnContext(ctx, "context cache: set failed", "key", key, "error", err)
+	}
+}
+
+// invalidateContext bumps a version counter (one of the *VersionKey formats and its IDs) so
+// cached entries depending on it are no longer read.
+func (d *DB) invalidateContext(ctx context.Context, format string, ids ...any) {
+	if d.ctxCache == nil {
+		return
+	}
+	key := format
+	if len(ids) > 0 {
+		key = fmt.Sprintf(format, ids...)
+	}
+	if _, err := d.ctxCache.IncrCount(ctx, key, contextVersionTTL); err != nil {
+		slog.WarnContext(ctx, "context cache: invalidate failed", "key", key, "error", err)
+	}
+}
@@ -0,0 +1,193 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memContextCache is an in-memory ContextCache with the same list and counter semantics as Redis.
+type memContextCache struct {
+	values map[string][]byte
+	counts map[string]int64
+	lists  map[string][]string
+}
+
+func newMemContextCache() *memContextCache {
+	return &memContextCache{values: map[string][]byte{}, counts: map[string]int64{}, lists: map[string][]string{}}
+}
+
+func (m *memContextCache) GetJSON(_ context.Context, key string, v any) (bool, error) {
+	b, ok := m.values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(b, v)
+}
+
+func (m *memContextCache) SetJSON(_ context.Context, key string, v any, _ time.Duration) error {
+	b, err := json.Marshal(v)
+	m.values[key] = b
+	return err
+}
+
+func (m *memContextCache) GetCounts(_ context.Context, keys ...string) ([]int64, error) {
+	out := make([]int64, len(keys))
+	for i, k := range keys {
+		out[i] = m.counts[k]
+	}
+	return out, nil
+}
+
+func (m *memContextCache) IncrCount(_ context.Context, key string, _ time.Duration) (int64, error) {
+	m.counts[key]++
+	return m.counts[key], nil
+}
+
+func (m *memContextCache) ListTail(_ context.Context, key string, n int) ([]string, error) {
+	l := m.lists[key]
+	if len(l) > n {
+		l = l[len(l)-n:]
+	}
+	return l, nil
+}
+
+func (m *memContextCache) FillList(_ context.Context, key, versionKey string, version int64, items []string, _ time.Duration) (bool, error) {
+	if len(items) == 0 || m.counts[versionKey] != version {
+		return false, nil
+	}
+	m.lists[key] = append([]string(nil), items...)
+	return true, nil
+}
+
+func (m *memContextCache) AppendList(_ context.Context, key, versionKey, item string, max int, _ time.Duration) error {
+	m.counts[versionKey]++
+	if l, ok := m.lists[key]; ok {
+		l = append(l, item)
+		if len(l) > max {
+			l = l[len(l)-max:]
+		}
+		m.lists[key] = l
+	}
+	return nil
+}
+
+func (m *memContextCache) DropList(_ context.Context, key, versionKey string, _ time.Duration) error {
+	m.counts[versionKey]++
+	delete(m.lists, key)
+	return nil
+}
+
+func testMessages(chatID int64, from, to int) []Message {
+	var msgs []Message
+	for i := from; i <= to; i++ {
+		text := "m" + strconv.Itoa(i)
+		msgs = append(msgs, Message{ID: int64(i), ChatID: chatID, Text: &text})
+	}
+	return msgs
+}
+
+func messageIDs(msgs []Message) []int64 {
+	ids := make([]int64, len(msgs))
+	for i, m := range msgs {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func TestContextCache_MessagesWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemContextCache()
+	d := &DB{} // no pool: every read below must be served from the cache
+	d.SetContextCache(mem, time.Minute, 3)
+
+	// Appends before the list is loaded are not cached (the list would be incomplete).
+	d.appendMessage(ctx, testMessages(1, 1, 1)[0])
+	if _, hit, _ := d.cachedMessages(ctx, 1, 0, 3, 0); hit {
+		t.Fatal("expected a miss before the list is filled")
+	}
+
+	vers, _ := mem.GetCounts(ctx, "ctx:ver:msgs:1:0")
+	_, _, mc := d.cachedMessages(ctx, 1, 0, 3, vers[0])
+	d.fillMessages(ctx, mc, testMessages(1, 1, 3))
+	for _, m := range testMessages(1, 4, 5) {
+		d.appendMessage(ctx, m)
+	}
+
+	got, err := d.GetRecentMessages(ctx, 1, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := messageIDs(got); len(ids) != 3 || ids[0] != 3 || ids[2] != 5 {
+		t.Errorf("expected messages 3..5 oldest first, got %v", ids)
+	}
+	got, _ = d.GetRecentMessages(ctx, 1, 0, 2)
+	if ids := messageIDs(got); len(ids) != 2 || ids[0] != 4 {
+		t.Errorf("expected messages 4..5, got %v", ids)
+	}
+	if got[1].Text == nil || *got[1].Text != "m5" {
+		t.Errorf("message not round-tripped: %+v", got[1])
+	}
+}
+
+func TestContextCache_StaleFillRejected(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemContextCache()
+	d := &DB{}
+	d.SetContextCache(mem, time.Minute, 10)
+
+	_, _, mc := d.cachedMessages(ctx, 1, 0, 10, 0)
+	// A message stored while the fill was being loaded makes the fill stale.
+	d.appendMessage(ctx, testMessages(1, 2, 2)[0])
+	d.fillMessages(ctx, mc, testMessages(1, 1, 1))
+	if _, hit, _ := d.cachedMessages(ctx, 1, 0, 10, 0); hit {
+		t.Error("stale fill must not be cached")
+	}
+
+	// Changing a cached message drops the list.
+	vers, _ := mem.GetCounts(ctx, "ctx:ver:msgs:1:0")
+	_, _, mc = d.cachedMessages(ctx, 1, 0, 10, vers[0])
+	d.fillMessages(ctx, mc, testMessages(1, 1, 2))
+	d.dropMessages(ctx, 1, 0)
+	if _, hit, _ := d.cachedMessages(ctx, 1, 0, 10, 0); hit {
+		t.Error("expected the list to be dropped")
+	}
+}
+
+func TestContextCache_Cacheable(t *testing.T) {
+	d := &DB{}
+	if d.messagesCacheable(0, 5) {
+		t.Error("no cache set: nothing is cacheable")
+	}
+	d.SetContextCache(newMemContextCache(), time.Minute, 50)
+	if !d.messagesCacheable(0, 50) {
+		t.Error("expected a topic read within the cap to be cacheable")
+	}
+	if d.messagesCacheable(AllThreads, 10) || d.messagesCacheable(0, 51) {
+		t.Error("all-topic reads and reads above the cap go to Postgres")
+	}
+	d.SetContextCache(newMemContextCache(), 0, 50)
+	if d.ctxCache != nil {
+		t.Error("ttl 0 disables the cache")
+	}
+}
+
+func TestContextCache_InvalidateBumpsKeys(t *testing.T) {
+	ctx := context.Background()
+	mem := newMemContextCache()
+	d := &DB{}
+	d.SetContextCache(mem, time.Minute, 10)
+
+	before := factsKey(1, 7, 20, mem.counts["ctx:ver:user:7"], mem.counts[factsVersionKey])
+	d.invalidateContext(ctx, userVersionKey, int64(7))
+	after := factsKey(1, 7, 20, mem.counts["ctx:ver:user:7"], mem.counts[factsVersionKey])
+	if before == after {
+		t.Errorf("fact write must change the facts key, still %s", after)
+	}
+	d.invalidateContext(ctx, factsVersionKey)
+	if k := factsKey(1, 7, 20, mem.counts["ctx:ver:user:7"], mem.counts[factsVersionKey]); k == after {
+		t.Errorf("bulk fact write must change the facts key, still %s", k)
+	}
+}
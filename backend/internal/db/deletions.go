@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MessageDeletion is one audited request_delete call.
+type MessageDeletion struct {
+	ChatID       int64
+	MessageID    int64
+	RequestedBy  int64
+	TargetUserID *int64 // nil for bot replies and messages that were never stored
+	TargetIsBot  bool
+	Allowed      bool
+	Reason       *string
+	RequestID    *string
+}
+
+// GetMessageByTelegramID returns the stored message with this Telegram message_id in a chat
+// (incoming or an acked bot reply), or nil if there is none.
+func (d *DB) GetMessageByTelegramID(ctx context.Context, chatID, messageID int64) (*Message, error) {
+	const query = `
+		SELECT id, chat_id, thread_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, sticker_emoji, sticker_set, created_at
+		FROM messages
+		WHERE chat_id = $1 AND message_id = $2
+		ORDER BY id DESC
+		LIMIT 1`
+	var m Message
+	err := d.pool.QueryRowContext(ctx, query, chatID, messageID).Scan(
+		&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName,
+		&m.Text, &m.MessageID, &m.MediaType, &m.IsBotReply,
+		&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.StickerEmoji, &m.StickerSet, &m.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get message by telegram id: %w", err)
+	}
+	d.open(ctx, m.Text)
+	return &m, nil
+}
+
+// InsertMessageDeletion writes one entry to the deletion audit log.
+func (d *DB) InsertMessageDeletion(ctx context.Context, del *MessageDeletion) error {
+	const query = `
+		INSERT INTO message_deletions (chat_id, message_id, requested_by, target_user_id, target_is_bot, allowed, reason, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := d.pool.ExecContext(ctx, query,
+		del.ChatID, del.MessageID, del.RequestedBy, del.TargetUserID, del.TargetIsBot, del.Allowed, del.Reason, del.RequestID)
+	if err != nil {
+		return fmt.Errorf("insert message deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+)
+
+// undecryptableText stands in for a stored value the cipher cannot open (its key is gone).
+const undecryptableText = "[encrypted]"
+
+// SetCipher encrypts message text, transcripts and user facts before they are stored and
+// decrypts them as they are read back (ENCRYPTION_KEY); nil stores new text as given. Rows
+// stored before it was set stay plaintext and are read as they are.
+func (d *DB) SetCipher(c *atrest.Cipher) {
+	d.cipher = c
+}
+
+// seal encrypts a stored message text field; nil stays nil. Text that merely looks encrypted
+// (a user typing "enc1:...") is encrypted like any other.
+func (d *DB) seal(text *string) *string {
+	if d.cipher == nil || text == nil {
+		return text
+	}
+	out := d.cipher.Seal(*text)
+	return &out
+}
+
+// sealArchived is seal for text read back from an archive: values that were stored encrypted are
+// written as they are, plaintext from before the cipher was set is encrypted.
+func (d *DB) sealArchived(text *string) *string {
+	if text != nil && atrest.IsSealed(*text) {
+		return text
+	}
+	return d.seal(text)
+}
+
+// sealFact encrypts a fact deterministically, so the (chat_id, user_id, md5(fact_text)) unique
+// index still catches duplicates.
+func (d *DB) sealFact(text string) string {
+	return d.cipher.SealDeterministic(text)
+}
+
+// open decrypts a stored text field in place.
+func (d *DB) open(ctx context.Context, text *string) {
+	if text == nil || !atrest.IsSealed(*text) {
+		return
+	}
+	plain, err := d.cipher.Open(*text)
+	if err != nil {
+		slog.WarnContext(ctx, "decrypt stored text failed", "error", err)
+		plain = undecryptableText
+	}
+	*text = plain
+}
+
+// openMessages decrypts the text of msgs in place.
+func (d *DB) openMessages(ctx context.Context, msgs []Message) {
+	for i := range msgs {
+		d.open(ctx, msgs[i].Text)
+	}
+}
+
+// openFacts decrypts the text of facts in place.
+func (d *DB) openFacts(ctx context.Context, facts []UserFact) {
+	for i := range facts {
+		d.open(ctx, &facts[i].FactText)
+	}
+}
@@ -0,0 +1,143 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+)
+
+func TestEncryption_CachedMessagesStayEncrypted(t *testing.T) {
+	ctx := context.Background()
+	c, err := atrest.New(bytes.Repeat([]byte{1}, atrest.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem := newMemContextCache()
+	d := &DB{}
+	d.SetContextCache(mem, time.Minute, 3)
+	d.SetCipher(c)
+
+	vers, _ := mem.GetCounts(ctx, "ctx:ver:msgs:1:0")
+	_, _, mc := d.cachedMessages(ctx, 1, 0, 3, vers[0])
+	stored := testMessages(1, 1, 2)
+	for i := range stored {
+		stored[i].Text = d.seal(stored[i].Text)
+	}
+	plain := "m1"
+	stored = append(stored, Message{ID: 3, ChatID: 1, Text: &plain}) // stored before encryption
+	d.fillMessages(ctx, mc, stored)
+
+	for _, raw := range mem.lists {
+		for _, m := range raw[:2] {
+			if strings.Contains(m, `"m1"`) || strings.Contains(m, `"m2"`) {
+				t.Errorf("cache holds plaintext: %s", m)
+			}
+		}
+	}
+	got, err := d.GetRecentMessages(ctx, 1, 0, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []string{"m1", "m2", "m1"} {
+		if got[i].Text == nil || *got[i].Text != want {
+			t.Errorf("message %d: text = %v, want %q", i, got[i].Text, want)
+		}
+	}
+
+	// Without the key the text is masked, not passed on as ciphertext.
+	d.SetCipher(nil)
+	got, _ = d.GetRecentMessages(ctx, 1, 0, 3)
+	if *got[0].Text != undecryptableText || *got[2].Text != "m1" {
+		t.Errorf("unexpected texts without the key: %q, %q", *got[0].Text, *got[2].Text)
+	}
+}
+
+func TestEncryption_Facts(t *testing.T) {
+	ctx := context.Background()
+	d := &DB{}
+	if d.sealFact("likes tea") != "likes tea" {
+		t.Error("facts should be stored as given without a cipher")
+	}
+	c, _ := atrest.New(bytes.Repeat([]byte{2}, atrest.KeySize))
+	d.SetCipher(c)
+	sealed := d.sealFact("likes tea")
+	if !atrest.IsSealed(sealed) || sealed != d.sealFact("likes tea") {
+		t.Errorf("facts should be sealed deterministically: %q", sealed)
+	}
+	facts := []UserFact{{FactText: sealed}, {FactText: "old plaintext fact"}}
+	d.openFacts(ctx, facts)
+	if facts[0].FactText != "likes tea" || facts[1].FactText != "old plaintext fact" {
+		t.Errorf("unexpected facts: %+v", facts)
+	}
+}
+
+// replayRow feeds scanMessageReplay the columns FindMessageReplay selects.
+type replayRow struct{ cols []any }
+
+func (r replayRow) Scan(dest ...any) error {
+	*dest[0].(*string) = r.cols[0].(string)
+	*dest[1].(**string) = r.cols[1].(*string)
+	*dest[2].(**string) = nil
+	*dest[3].(*bool) = r.cols[3].(bool)
+	return nil
+}
+
+func TestEncryption_ReplayedReplyIsDecrypted(t *testing.T) {
+	c, err := atrest.New(bytes.Repeat([]byte{1}, atrest.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &DB{}
+	d.SetCipher(c)
+
+	reply := "бот відповів"
+	sealed := d.seal(&reply)
+	if sealed == nil || !atrest.IsSealed(*sealed) {
+		t.Fatalf("reply not sealed: %v", sealed)
+	}
+
+	rep, err := d.scanMessageReplay(context.Background(), replayRow{cols: []any{"req-1", sealed, nil, false}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep == nil || rep.Reply == nil || *rep.Reply != reply {
+		t.Fatalf("replayed reply = %+v, want %q", rep, reply)
+	}
+	if rep.RequestID != "req-1" {
+		t.Errorf("request id = %q", rep.RequestID)
+	}
+}
+
+func TestEncryption_TextThatLooksSealed(t *testing.T) {
+	ctx := context.Background()
+	c, err := atrest.New(bytes.Repeat([]byte{3}, atrest.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &DB{}
+	d.SetCipher(c)
+
+	typed := atrest.Prefix + "hi"
+	stored := d.seal(&typed)
+	if *stored == typed {
+		t.Fatal("user text starting with the prefix was stored as plaintext")
+	}
+	got := *stored
+	d.open(ctx, &got)
+	if got != typed {
+		t.Errorf("expected %q back, got %q", typed, got)
+	}
+
+	// Restores keep archived ciphertext and encrypt archived plaintext
+	if again := d.sealArchived(stored); *again != *stored {
+		t.Error("expected archived ciphertext written as it is")
+	}
+	old := "archived before the key"
+	if restored := d.sealArchived(&old); !atrest.IsSealed(*restored) {
+		t.Errorf("expected archived plaintext encrypted, got %q", *restored)
+	}
+}
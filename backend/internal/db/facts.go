@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"time"
+)
+
+// Fact categories stored in user_facts.category.
+const (
+	FactCategoryPreference = "preference" // likes, dislikes, habits
+	FactCategoryBio        = "bio"        // who they are: job, city, family
+	FactCategoryEvent      = "event"      // something that happened or is planned
+	FactCategoryJoke       = "joke"       // running gags and nicknames
+)
+
+// Fact importance bounds; DefaultFactImportance is used when the model gives none.
+const (
+	MinFactImportance     = 1
+	MaxFactImportance     = 5
+	DefaultFactImportance = 3
+)
+
+// GlobalFactsChatID is the chat_id of facts that follow a user into every chat.
+// They are only stored and shown for users who opted in (see SetGlobalMemory).
+const GlobalFactsChatID int64 = 0
+
+// FactCategories lists the valid categories in schema order.
+var FactCategories = []string{FactCategoryPreference, FactCategoryBio, FactCategoryEvent, FactCategoryJoke}
+
+// NormalizeFactCategory lowercases category and falls back to bio for unknown values.
+func NormalizeFactCategory(category string) string {
+	category = strings.ToLower(strings.TrimSpace(category))
+	for _, c := range FactCategories {
+		if c == category {
+			return c
+		}
+	}
+	return FactCategoryBio
+}
+
+// ClampImportance maps importance into [MinFactImportance, MaxFactImportance]; 0 means unset.
+func ClampImportance(importance int) int {
+	switch {
+	case importance == 0:
+		return DefaultFactImportance
+	case importance < MinFactImportance:
+		return MinFactImportance
+	case importance > MaxFactImportance:
+		return MaxFactImportance
+	}
+	return importance
+}
+
+// ErrDuplicateFact is returned when an update would make a fact identical to another fact of the same user.
+var ErrDuplicateFact = errors.New("duplicate fact")
+
+// nearDuplicateThreshold is the word-overlap (Jaccard) ratio above which two facts count as the same fact.
+const nearDuplicateThreshold = 0.8
+
+// UpdateUserFact replaces a fact's text and bumps updated_at (and last_referenced_at). Returns false if the fact does not exist.
+// Returns ErrOffRecord while the fact's chat is in an off-the-record window.
+func (d *DB) UpdateUserFact(ctx context.Context, factID int64, factText string) (bool, error) {
+	var off bool
+	var userID int64
+	err := d.pool.QueryRowContext(ctx,
+		"SELECT is_off_record(chat_id, NOW()), user_id FROM user_facts WHERE id = $1", factID).Scan(&off, &userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("update user fact: %w", err)
+	}
+	if off {
+		return false, ErrOffRecord
+	}
+
+	res, err := d.pool.ExecContext(ctx,
+		"UPDATE user_facts SET fact_text = $2, updated_at = NOW(), last_referenced_at = NOW() WHERE id = $1",
+		factID, d.sealFact(factText))
+	if err != nil {
+		if isUniqueViolation(err) {
+			return false, ErrDuplicateFact
+		}
+		return false, fmt.Errorf("update user fact: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("update user fact: %w", err)
+	}
+	if n > 0 {
+		d.invalidateContext(ctx, userVersionKey, userID)
+	}
+	return n > 0, nil
+}
+
+// factWords lowercases text and splits it into words, dropping punctuation.
+func factWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// factSimilarity returns the Jaccard overlap of the two texts' word sets (1 = same words).
+func factSimilarity(a, b string) float64 {
+	wa, wb := factWords(a), factWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return 0
+	}
+	set := make(map[string]bool, len(wa))
+	for _, w := range wa {
+		set[w] = true
+	}
+	inter, union := 0, len(set)
+	seen := make(map[string]bool, len(wb))
+	for _, w := range wb {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		if set[w] {
+			inter++
+		} else {
+			union++
+		}
+	}
+	return float64(inter) / float64(union)
+}
+
+// FindNearDuplicateFact returns the stored fact most similar to text if it is similar enough
+// to be the same fact reworded ("Lives in Kyiv" vs "lives in Kyiv now").
+func FindNearDuplicateFact(facts []UserFact, text string) (UserFact, bool) {
+	var best UserFact
+	bestScore := 0.0
+	for _, f := range facts {
+		if s := factSimilarity(f.FactText, text); s > bestScore {
+			best, bestScore = f, s
+		}
+	}
+	return best, bestScore >= nearDuplicateThreshold
+}
+
+// SameFactText reports whether two facts differ only in case, spacing or punctuation.
+func SameFactText(a, b string) bool {
+	return strings.Join(factWords(a), " ") == strings.Join(factWords(b), " ")
+}
+
+// ── Fact maintenance (nightly consolidation job) ─────────────────────────
+
+// FactOwner identifies one user's fact set in one chat.
+type FactOwner struct {
+	ChatID int64
+	UserID int64
+}
+
+// TouchUserFacts marks facts as referenced now (shown in a prompt or recalled), so they don't decay.
+func (d *DB) TouchUserFacts(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := d.pool.ExecContext(ctx,
+		"UPDATE user_facts SET last_referenced_at = NOW() WHERE id = ANY($1)", ids)
+	if err != nil {
+		return fmt.Errorf("touch user facts: %w", err)
+	}
+	return nil
+}
+
+// DeleteStaleUserFacts removes facts not referenced since before. Facts at MaxFactImportance
+// (core identity) never decay.
+func (d *DB) DeleteStaleUserFacts(ctx context.Context, before time.Time) (int64, error) {
+	res, err := d.pool.ExecContext(ctx,
+		"DELETE FROM user_facts WHERE last_referenced_at < $1 AND importance < $2", before, MaxFactImportance)
+	if err != nil {
+		return 0, fmt.Errorf("delete stale user facts: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if n > 0 {
+		d.invalidateContext(ctx, factsVersionKey)
+	}
+	return n, err
+}
+
+// TrimUserFacts keeps at most maxPerUser facts per user and chat, dropping the least important
+// and least recently referenced ones first.
+func (d *DB) TrimUserFacts(ctx context.Context, maxPerUser int) (int64, error) {
+	const query = `
+		DELETE FROM user_facts WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY chat_id, user_id
+					ORDER BY importance DESC, last_referenced_at DESC, id DESC
+				) AS rn
+				FROM user_facts
+			) ranked
+			WHERE rn > $1
+		)`
+	res, err := d.pool.ExecContext(ctx, query, maxPerUser)
+	if err != nil {
+		return 0, fmt.Errorf("trim user facts: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if n > 0 {
+		d.invalidateContext(ctx, factsVersionKey)
+	}
+	return n, err
+}
+
+// GetFactOwnersForConsolidation returns users with at least minFacts facts in a chat,
+// of which at least one was added or changed since changedSince.
+func (d *DB) GetFactOwnersForConsolidation(ctx context.Context, changedSince time.Time, minFacts int) ([]FactOwner, error) {
+	const query = `
+		SELECT chat_id, user_id
+		FROM user_facts
+		GROUP BY chat_id, user_id
+		HAVING COUNT(*) >= $2 AND MAX(updated_at) >= $1`
+
+	rows, err := d.pool.QueryContext(ctx, query, changedSince, minFacts)
+	if err != nil {
+		return nil, fmt.Errorf("get fact owners: %w", err)
+	}
+	defer rows.Close()
+
+	var owners []FactOwner
+	for rows.Next() {
+		var o FactOwner
+		if err := rows.Scan(&o.ChatID, &o.UserID); err != nil {
+			return nil, fmt.Errorf("scan fact owner: %w", err)
+		}
+		owners = append(owners, o)
+	}
+	return owners, rows.Err()
+}
+
+// MergeUserFacts folds removeIDs into keepID: the kept fact gets the merged text, category and
+// importance, and the most recent last_referenced_at of the group. Only facts of owner are touched.
+func (d *DB) MergeUserFacts(ctx context.Context, owner FactOwner, keepID int64, removeIDs []int64, text, category string, importance int) error {
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	var lastRef sql.NullTime
+	if err := tx.QueryRowContext(ctx,
+		`SELECT MAX(last_referenced_at) FROM user_facts WHERE chat_id = $1 AND user_id = $2 AND (id = $3 OR id = ANY($4))`,
+		owner.ChatID, owner.UserID, keepID, removeIDs).Scan(&lastRef); err != nil {
+		return fmt.Errorf("merge user facts: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM user_facts WHERE chat_id = $1 AND user_id = $2 AND id = ANY($3) AND id <> $4`,
+		owner.ChatID, owner.UserID, removeIDs, keepID); err != nil {
+		return fmt.Errorf("delete merged facts: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_facts
+		SET fact_text = $4, category = $5, importance = $6, updated_at = NOW(),
+		    last_referenced_at = COALESCE($7, last_referenced_at)
+		WHERE id = $3 AND chat_id = $1 AND user_id = $2`,
+		owner.ChatID, owner.UserID, keepID, d.sealFact(text), category, importance, lastRef)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicateFact
+		}
+		return fmt.Errorf("update merged fact: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	d.invalidateContext(ctx, userVersionKey, owner.UserID)
+	return nil
+}
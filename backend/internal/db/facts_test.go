@@ -0,0 +1,57 @@
+package db
+
+import "testing"
+
+func TestFindNearDuplicateFact(t *testing.T) {
+	facts := []UserFact{
+		{ID: 1, FactText: "Likes cats"},
+		{ID: 2, FactText: "Works as a backend developer at Google"},
+	}
+
+	tests := []struct {
+		text   string
+		wantID int64
+		dup    bool
+	}{
+		{"likes cats!", 1, true},
+		{"Works as a backend developer at Google now", 2, true},
+		{"Likes cats and dogs a lot", 0, false},
+		{"Lives in Kyiv", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		f, ok := FindNearDuplicateFact(facts, tt.text)
+		if ok != tt.dup {
+			t.Errorf("%q: dup = %v, want %v", tt.text, ok, tt.dup)
+			continue
+		}
+		if ok && f.ID != tt.wantID {
+			t.Errorf("%q: matched fact %d, want %d", tt.text, f.ID, tt.wantID)
+		}
+	}
+}
+
+func TestSameFactText(t *testing.T) {
+	if !SameFactText("Lives in  Kyiv.", "lives in kyiv") {
+		t.Error("expected punctuation/case-only difference to be the same fact")
+	}
+	if SameFactText("Lives in Kyiv", "Lives in Kyiv now") {
+		t.Error("expected different wording to differ")
+	}
+}
+
+func TestNormalizeFactCategory(t *testing.T) {
+	for in, want := range map[string]string{"Preference": "preference", " joke ": "joke", "event": "event", "hobby": "bio", "": "bio"} {
+		if got := NormalizeFactCategory(in); got != want {
+			t.Errorf("NormalizeFactCategory(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestClampImportance(t *testing.T) {
+	for in, want := range map[int]int{0: DefaultFactImportance, -2: 1, 1: 1, 4: 4, 9: 5} {
+		if got := ClampImportance(in); got != want {
+			t.Errorf("ClampImportance(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
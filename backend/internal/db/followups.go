@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// followUpMaxAge is how long past its due time an open follow-up is still brought up.
+const followUpMaxAge = 72 * time.Hour
+
+// FollowUp is a promise the bot made in a reply, as stored in follow_ups.
+type FollowUp struct {
+	ID        int64      `json:"id"`
+	ChatID    int64      `json:"chat_id"`
+	UserID    *int64     `json:"user_id,omitempty"`
+	RequestID *string    `json:"request_id,omitempty"`
+	Text      string     `json:"text"`
+	DueAt     time.Time  `json:"due_at"`
+	Done      bool       `json:"done"`
+	DoneAt    *time.Time `json:"done_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InsertFollowUp stores a follow-up and returns its id.
+func (d *DB) InsertFollowUp(ctx context.Context, f *FollowUp) (int64, error) {
+	const query = `
+		INSERT INTO follow_ups (chat_id, user_id, request_id, text, due_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	var id int64
+	if err := d.pool.QueryRowContext(ctx, query, f.ChatID, f.UserID, f.RequestID, f.Text, f.DueAt).Scan(&id); err != nil {
+		return 0, fmt.Errorf("insert follow-up: %w", err)
+	}
+	return id, nil
+}
+
+// DueFollowUps returns the chat's open follow-ups due at now, earliest first. Ones more than
+// three days overdue are left out: by then the moment has passed.
+func (d *DB) DueFollowUps(ctx context.Context, chatID int64, now time.Time) ([]FollowUp, error) {
+	const query = `
+		SELECT id, chat_id, user_id, request_id, text, due_at, done, done_at, created_at
+		FROM follow_ups
+		WHERE chat_id = $1 AND NOT done AND due_at <= $2 AND due_at > $3
+		ORDER BY due_at, id
+		LIMIT 5`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, now, now.Add(-followUpMaxAge))
+	if err != nil {
+		return nil, fmt.Errorf("due follow-ups: %w", err)
+	}
+	defer rows.Close()
+	var out []FollowUp
+	for rows.Next() {
+		var f FollowUp
+		if err := rows.Scan(&f.ID, &f.ChatID, &f.UserID, &f.RequestID, &f.Text, &f.DueAt, &f.Done, &f.DoneAt, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan follow-up: %w", err)
+		}
+		out = append(out, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("due follow-ups: %w", err)
+	}
+	return out, nil
+}
+
+// MarkFollowUpDone closes a follow-up once the proactive runner brought it up.
+func (d *DB) MarkFollowUpDone(ctx context.Context, id int64) error {
+	if _, err := d.pool.ExecContext(ctx, `UPDATE follow_ups SET done = TRUE, done_at = NOW() WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("mark follow-up done: %w", err)
+	}
+	return nil
+}
+
+// PruneOldFollowUps deletes follow-ups created past their chat's message retention, like
+// PruneOldMessages.
+func (d *DB) PruneOldFollowUps(ctx context.Context, defaultDays int) (int64, error) {
+	const query = `
+		DELETE FROM follow_ups WHERE id IN (
+			SELECT f.id FROM follow_ups f
+			LEFT JOIN chat_settings cs ON cs.chat_id = f.chat_id
+			WHERE COALESCE(cs.message_retention_days, $1) > 0
+			  AND f.created_at < NOW() - INTERVAL '1 day' * COALESCE(cs.message_retention_days, $1)
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, defaultDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old follow-ups: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old follow-ups", "deleted", total, "default_retention_days", defaultDays)
+	}
+	return total, nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Games with scores.
+const (
+	GameTrivia   = "trivia"
+	GameRoulette = "roulette"
+)
+
+// GameScore is a user's score in one game of a chat, with the name they last wrote under.
+type GameScore struct {
+	UserID    int64  `json:"user_id"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Wins      int    `json:"wins"`
+	Losses    int    `json:"losses,omitempty"`
+}
+
+// AddGameScore adds wins and losses to a user's score in a game and returns the new totals.
+func (d *DB) AddGameScore(ctx context.Context, chatID, userID int64, game string, wins, losses int) (GameScore, error) {
+	const query = `
+		INSERT INTO game_scores (chat_id, game, user_id, wins, losses) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, game, user_id) DO UPDATE
+		SET wins = game_scores.wins + EXCLUDED.wins, losses = game_scores.losses + EXCLUDED.losses, updated_at = NOW()
+		RETURNING wins, losses`
+	s := GameScore{UserID: userID}
+	if err := d.pool.QueryRowContext(ctx, query, chatID, game, userID, wins, losses).Scan(&s.Wins, &s.Losses); err != nil {
+		return s, fmt.Errorf("add game score: %w", err)
+	}
+	return s, nil
+}
+
+// GetGameScores returns the limit best players of a game in a chat: most wins, then fewest losses.
+func (d *DB) GetGameScores(ctx context.Context, chatID int64, game string, limit int) ([]GameScore, error) {
+	const query = `
+		SELECT s.user_id, COALESCE(n.first_name, ''), COALESCE(n.username, ''), s.wins, s.losses
+		FROM (
+			SELECT user_id, wins, losses FROM game_scores
+			WHERE chat_id = $1 AND game = $2
+			ORDER BY wins DESC, losses, user_id
+			LIMIT $3
+		) s
+		LEFT JOIN LATERAL (
+			SELECT first_name, username FROM messages
+			WHERE chat_id = $1 AND user_id = s.user_id
+			ORDER BY id DESC LIMIT 1
+		) n ON TRUE
+		ORDER BY s.wins DESC, s.losses, s.user_id`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, game, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get game scores: %w", err)
+	}
+	defer rows.Close()
+	scores := []GameScore{}
+	for rows.Next() {
+		var s GameScore
+		if err := rows.Scan(&s.UserID, &s.FirstName, &s.Username, &s.Wins, &s.Losses); err != nil {
+			return nil, fmt.Errorf("scan game score: %w", err)
+		}
+		scores = append(scores, s)
+	}
+	return scores, rows.Err()
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GlossaryTerm is how translate renders a name or slang word in one chat.
+type GlossaryTerm struct {
+	ID          int64     `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	Term        string    `json:"term"`
+	Translation string    `json:"translation"`
+	Lang        string    `json:"lang,omitempty"` // target language; empty = every target
+	CreatedBy   *int64    `json:"created_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// SetGlossaryTerm stores a glossary entry, replacing the translation of the chat's entry with the
+// same term (case-insensitive) and target language. Returns whether it is new.
+func (d *DB) SetGlossaryTerm(ctx context.Context, g *GlossaryTerm) (bool, error) {
+	const query = `
+		INSERT INTO chat_glossary (chat_id, term, translation, lang, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, lower(term), lang) DO UPDATE
+		SET translation = EXCLUDED.translation, created_by = EXCLUDED.created_by, updated_at = NOW()
+		RETURNING xmax = 0`
+	var created bool
+	if err := d.pool.QueryRowContext(ctx, query, g.ChatID, g.Term, g.Translation, g.Lang, g.CreatedBy).Scan(&created); err != nil {
+		return false, fmt.Errorf("set glossary term: %w", err)
+	}
+	return created, nil
+}
+
+// DeleteGlossaryTerm removes a chat's entry for term and target language; false when there was none.
+func (d *DB) DeleteGlossaryTerm(ctx context.Context, chatID int64, term, lang string) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, "DELETE FROM chat_glossary WHERE chat_id = $1 AND lower(term) = lower($2) AND lang = $3", chatID, term, lang)
+	if err != nil {
+		return false, fmt.Errorf("delete glossary term: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// CountGlossaryTerms returns how many glossary entries a chat has.
+func (d *DB) CountGlossaryTerms(ctx context.Context, chatID int64) (int, error) {
+	var n int
+	if err := d.pool.QueryRowContext(ctx, "SELECT COUNT(*) FROM chat_glossary WHERE chat_id = $1", chatID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count glossary terms: %w", err)
+	}
+	return n, nil
+}
+
+// ListGlossary returns a chat's glossary entries that apply to the target language lang (its own
+// entries and the language-independent ones), or all entries when lang is empty. Ordered by term.
+func (d *DB) ListGlossary(ctx context.Context, chatID int64, lang string) ([]GlossaryTerm, error) {
+	rows, err := d.pool.QueryContext(ctx, `
+		SELECT id, chat_id, term, translation, lang, created_by, updated_at
+		FROM chat_glossary
+		WHERE chat_id = $1 AND ($2 = '' OR lang = '' OR lang = $2)
+		ORDER BY lower(term), lang`, chatID, lang)
+	if err != nil {
+		return nil, fmt.Errorf("list glossary: %w", err)
+	}
+	defer rows.Close()
+	terms := []GlossaryTerm{}
+	for rows.Next() {
+		var g GlossaryTerm
+		if err := rows.Scan(&g.ID, &g.ChatID, &g.Term, &g.Translation, &g.Lang, &g.CreatedBy, &g.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan glossary term: %w", err)
+		}
+		terms = append(terms, g)
+	}
+	return terms, rows.Err()
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Karma vote sources.
+const (
+	KarmaReaction = "reaction" // a reaction on the message
+	KarmaReply    = "reply"    // a "+"/"-" reply to the message
+)
+
+// ValidKarmaSource reports whether source is one of the karma vote sources.
+func ValidKarmaSource(source string) bool {
+	return source == KarmaReaction || source == KarmaReply
+}
+
+// KarmaVote is one user's vote on a message. Delta is -1, 0 (retracted) or 1.
+type KarmaVote struct {
+	ChatID       int64
+	MessageID    int64 // Telegram message_id of the voted message
+	VoterID      int64
+	Source       string
+	TargetUserID int64 // author of the message; 0 = look it up in the message log
+	Delta        int
+}
+
+// KarmaEntry is a user's karma in a chat, with the name they last wrote under.
+type KarmaEntry struct {
+	UserID    int64  `json:"user_id"`
+	FirstName string `json:"first_name,omitempty"`
+	Username  string `json:"username,omitempty"`
+	Score     int    `json:"score"`
+	Rank      int    `json:"rank"`
+}
+
+// ApplyKarmaVote records v, replacing the voter's earlier vote on the message from the same source,
+// and moves the author's karma by the difference. Returns false without changes when the author is
+// unknown, a bot reply, or the voter themselves.
+func (d *DB) ApplyKarmaVote(ctx context.Context, v KarmaVote) (bool, error) {
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("karma vote: %w", err)
+	}
+	defer tx.Rollback()
+
+	target := v.TargetUserID
+	if target == 0 {
+		err := tx.QueryRowContext(ctx, `
+			SELECT user_id FROM messages
+			WHERE chat_id = $1 AND message_id = $2 AND user_id IS NOT NULL AND NOT is_bot_reply
+			ORDER BY id DESC LIMIT 1`, v.ChatID, v.MessageID).Scan(&target)
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("karma vote author: %w", err)
+		}
+	}
+	if target == v.VoterID {
+		return false, nil
+	}
+
+	var old int
+	err = tx.QueryRowContext(ctx, `
+		SELECT delta FROM karma_votes
+		WHERE chat_id = $1 AND message_id = $2 AND voter_id = $3 AND source = $4
+		FOR UPDATE`, v.ChatID, v.MessageID, v.VoterID, v.Source).Scan(&old)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("karma vote: %w", err)
+	}
+	diff := v.Delta - old
+	if diff == 0 {
+		return true, nil
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO karma_votes (chat_id, message_id, voter_id, source, target_user_id, delta)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (chat_id, message_id, voter_id, source)
+		DO UPDATE SET delta = EXCLUDED.delta, updated_at = NOW()`,
+		v.ChatID, v.MessageID, v.VoterID, v.Source, target, v.Delta); err != nil {
+		return false, fmt.Errorf("karma vote: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO karma (chat_id, user_id, score) VALUES ($1, $2, $3)
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET score = karma.score + EXCLUDED.score, updated_at = NOW()`,
+		v.ChatID, target, diff); err != nil {
+		return false, fmt.Errorf("karma update: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("karma vote: %w", err)
+	}
+	return true, nil
+}
+
+// karmaQuery ranks a chat's karma (ties share a rank), keeps the rows selected by tail (a WHERE,
+// ORDER BY and/or LIMIT over user_id, score and rank) and adds each user's latest name. Takes
+// chat_id as $1.
+func karmaQuery(tail, order string) string {
+	return `
+		SELECT r.user_id, COALESCE(n.first_name, ''), COALESCE(n.username, ''), r.score, r.rank
+		FROM (
+			SELECT * FROM (
+				SELECT user_id, score, RANK() OVER (ORDER BY score DESC)::int AS rank
+				FROM karma WHERE chat_id = $1
+			) ranked ` + tail + `
+		) r
+		LEFT JOIN LATERAL (
+			SELECT first_name, username FROM messages
+			WHERE chat_id = $1 AND user_id = r.user_id
+			ORDER BY id DESC LIMIT 1
+		) n ON TRUE
+		ORDER BY ` + order
+}
+
+func scanKarmaEntries(rows *sql.Rows) ([]KarmaEntry, error) {
+	defer rows.Close()
+	entries := []KarmaEntry{}
+	for rows.Next() {
+		var e KarmaEntry
+		if err := rows.Scan(&e.UserID, &e.FirstName, &e.Username, &e.Score, &e.Rank); err != nil {
+			return nil, fmt.Errorf("scan karma: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// GetKarma returns a user's karma and rank in a chat; nil when they have none.
+func (d *DB) GetKarma(ctx context.Context, chatID, userID int64) (*KarmaEntry, error) {
+	rows, err := d.pool.QueryContext(ctx, karmaQuery("WHERE user_id = $2", "r.user_id"), chatID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get karma: %w", err)
+	}
+	entries, err := scanKarmaEntries(rows)
+	if err != nil {
+		return nil, fmt.Errorf("get karma: %w", err)
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	return &entries[0], nil
+}
+
+// GetKarmaLeaderboard returns the limit users with the most karma in a chat. With lowest set it
+// returns those with the least instead, lowest first.
+func (d *DB) GetKarmaLeaderboard(ctx context.Context, chatID int64, limit int, lowest bool) ([]KarmaEntry, error) {
+	order := "score DESC, user_id"
+	if lowest {
+		order = "score ASC, user_id"
+	}
+	rows, err := d.pool.QueryContext(ctx, karmaQuery("ORDER BY "+order+" LIMIT $2", "r."+order), chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get karma leaderboard: %w", err)
+	}
+	return scanKarmaEntries(rows)
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetUserHandle returns the most recent username and first name a user was seen with ("" if unknown).
+func (d *DB) GetUserHandle(ctx context.Context, userID int64) (username, firstName string, err error) {
+	const query = `
+		SELECT COALESCE(username, ''), COALESCE(first_name, '')
+		FROM messages
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 1`
+	err = d.pool.QueryRowContext(ctx, query, userID).Scan(&username, &firstName)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("get user handle: %w", err)
+	}
+	return username, firstName, nil
+}
+
+// GetUserMentions returns group messages in [since, until] that concern a user: ones mentioning
+// their @username (case-insensitive) and replies to their messages. The user's own messages,
+// private chats and off-the-record windows are excluded. Ordered by chat, then oldest first.
+func (d *DB) GetUserMentions(ctx context.Context, userID int64, username string, since, until time.Time, limit int) ([]Message, error) {
+	mention := ""
+	if username != "" {
+		mention = "@" + username
+	}
+	const query = `
+		SELECT m.id, m.chat_id, m.thread_id, m.user_id, m.username, m.first_name, m.text, m.message_id, m.media_type, m.is_bot_reply, m.request_id, m.was_throttled, m.reply_to_message_id, m.sticker_emoji, m.sticker_set, m.created_at
+		FROM messages m
+		WHERE m.chat_id < 0 AND m.created_at >= $2 AND m.created_at <= $3
+		  AND m.user_id IS DISTINCT FROM $1 AND NOT m.was_throttled
+		  AND NOT is_off_record(m.chat_id, m.created_at)
+		  AND (
+		    ($4 <> '' AND strpos(lower(m.text), lower($4)) > 0)
+		    OR EXISTS (
+		      SELECT 1 FROM messages p
+		      WHERE p.chat_id = m.chat_id AND p.message_id = m.reply_to_message_id AND p.user_id = $1
+		    )
+		  )
+		ORDER BY m.chat_id, m.created_at ASC
+		LIMIT $5`
+	rows, err := d.pool.QueryContext(ctx, query, userID, since, until, mention, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get user mentions: %w", err)
+	}
+	defer rows.Close()
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName,
+			&m.Text, &m.MessageID, &m.MediaType, &m.IsBotReply,
+			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.StickerEmoji, &m.StickerSet, &m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+	d.openMessages(ctx, messages)
+	return messages, nil
+}
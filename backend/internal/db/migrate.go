@@ -1,91 +1,337 @@
 package db
 
 import (
+	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
-// RunMigrations executes all .up.sql files in the given directory in order.
-// It tracks applied migrations in a schema_migrations table.
+// migrationLockKey is the Postgres advisory lock held while migrations run, so replicas
+// starting together apply them one at a time.
+const migrationLockKey int64 = 0x67727961676d6967 // "gryagmig"
+
+// ErrChecksumMismatch is returned when an applied migration's .up.sql has changed since.
+// Fix the file, or accept the change with `migrate force`.
+var ErrChecksumMismatch = errors.New("migration file changed after it was applied")
+
+// Migration is one schema change: NNN_name.up.sql and, usually, NNN_name.down.sql.
+type Migration struct {
+	Version  string // file name without .up.sql, e.g. "024_chat_topics"
+	UpPath   string
+	DownPath string // empty when there is no .down.sql
+	Checksum string // hex SHA-256 of the .up.sql file
+}
+
+// MigrationStatus is a migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   string
+	Applied   bool
+	AppliedAt time.Time
+	Modified  bool // .up.sql changed since it was applied
+	Missing   bool // applied, but no longer on disk
+}
+
+// LoadMigrations reads the migrations in dir, ordered by version. A .down.sql without its
+// .up.sql is an error.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir %s: %w", dir, err)
+	}
+	byVersion := make(map[string]*Migration)
+	var downs []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() {
+			continue
+		}
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			path := filepath.Join(dir, name)
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("read migration file %s: %w", path, err)
+			}
+			sum := sha256.Sum256(content)
+			v := strings.TrimSuffix(name, ".up.sql")
+			byVersion[v] = &Migration{Version: v, UpPath: path, Checksum: hex.EncodeToString(sum[:])}
+		case strings.HasSuffix(name, ".down.sql"):
+			downs = append(downs, name)
+		}
+	}
+	for _, name := range downs {
+		m, ok := byVersion[strings.TrimSuffix(name, ".down.sql")]
+		if !ok {
+			return nil, fmt.Errorf("migration %s has no .up.sql", name)
+		}
+		m.DownPath = filepath.Join(dir, name)
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies and rolls back the migrations in a directory, tracking them in the
+// schema_migrations table.
+type Migrator struct {
+	pool *sql.DB
+	dir  string
+}
+
+// NewMigrator creates a Migrator for the migrations in dir.
+func NewMigrator(pool *sql.DB, dir string) *Migrator {
+	return &Migrator{pool: pool, dir: dir}
+}
+
+// RunMigrations applies all pending migrations in dir (see Migrator.Up).
 func RunMigrations(pool *sql.DB, migrationsDir string) error {
-	// Create the tracking table if it doesn't exist
-	_, err := pool.Exec(`
+	_, err := NewMigrator(pool, migrationsDir).Up(context.Background())
+	return err
+}
+
+// appliedMigration is a schema_migrations row.
+type appliedMigration struct {
+	at       time.Time
+	checksum sql.NullString // NULL for migrations applied before checksums were recorded
+}
+
+// ensureTable creates schema_migrations, or adds the checksum column to an older one.
+func ensureTable(ctx context.Context, q interface {
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+}) error {
+	_, err := q.ExecContext(ctx, `
 		CREATE TABLE IF NOT EXISTS schema_migrations (
 			version TEXT PRIMARY KEY,
-			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
-		)
-	`)
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			checksum TEXT
+		);
+		ALTER TABLE schema_migrations ADD COLUMN IF NOT EXISTS checksum TEXT`)
 	if err != nil {
 		return fmt.Errorf("create schema_migrations table: %w", err)
 	}
+	return nil
+}
 
-	// Read migration files
-	entries, err := os.ReadDir(migrationsDir)
+// locked runs fn on one connection holding the migration advisory lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error) error {
+	migrations, err := LoadMigrations(m.dir)
 	if err != nil {
-		return fmt.Errorf("read migrations dir %s: %w", migrationsDir, err)
+		return err
 	}
+	conn, err := m.pool.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	defer conn.Close()
 
-	// Collect .up.sql files and sort by name
-	var upFiles []string
-	for _, e := range entries {
-		if !e.IsDir() && strings.HasSuffix(e.Name(), ".up.sql") {
-			upFiles = append(upFiles, e.Name())
-		}
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("take migration lock: %w", err)
 	}
-	sort.Strings(upFiles)
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			slog.Warn("release migration lock failed", "error", err)
+		}
+	}()
 
-	if len(upFiles) == 0 {
-		slog.Info("no migrations found", "dir", migrationsDir)
-		return nil
+	if err := ensureTable(ctx, conn); err != nil {
+		return err
 	}
+	applied, err := loadApplied(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, migrations, applied)
+}
 
-	for _, filename := range upFiles {
-		version := strings.TrimSuffix(filename, ".up.sql")
+func loadApplied(ctx context.Context, q interface {
+	QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+}) (map[string]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, "SELECT version, applied_at, checksum FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	applied := make(map[string]appliedMigration)
+	for rows.Next() {
+		var v string
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.at, &a.checksum); err != nil {
+			return nil, fmt.Errorf("scan schema_migrations: %w", err)
+		}
+		applied[v] = a
+	}
+	return applied, rows.Err()
+}
 
-		// Check if already applied
-		var exists bool
-		err := pool.QueryRow("SELECT EXISTS(SELECT 1 FROM schema_migrations WHERE version = $1)", version).Scan(&exists)
-		if err != nil {
-			return fmt.Errorf("check migration %s: %w", version, err)
+// Up verifies the checksums of applied migrations and applies the pending ones in order, each
+// in its own transaction. Returns how many were applied.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	n := 0
+	err := m.locked(ctx, func(conn *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error {
+		if len(migrations) == 0 {
+			slog.Info("no migrations found", "dir", m.dir)
+			return nil
 		}
-		if exists {
-			slog.Debug("migration already applied", "version", version)
-			continue
+		for _, mig := range migrations {
+			a, ok := applied[mig.Version]
+			if !ok {
+				continue
+			}
+			if !a.checksum.Valid {
+				// Applied before checksums were recorded: trust the file as it is now.
+				if _, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET checksum = $2 WHERE version = $1", mig.Version, mig.Checksum); err != nil {
+					return fmt.Errorf("record checksum of %s: %w", mig.Version, err)
+				}
+			} else if a.checksum.String != mig.Checksum {
+				return fmt.Errorf("%s: %w", mig.Version, ErrChecksumMismatch)
+			}
 		}
 
-		// Read and execute the migration
-		path := filepath.Join(migrationsDir, filename)
-		content, err := os.ReadFile(path)
-		if err != nil {
-			return fmt.Errorf("read migration file %s: %w", path, err)
+		for _, mig := range migrations {
+			if _, ok := applied[mig.Version]; ok {
+				slog.Debug("migration already applied", "version", mig.Version)
+				continue
+			}
+			if err := m.exec(ctx, conn, mig.UpPath, mig.Version,
+				"INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)", mig.Version, mig.Checksum); err != nil {
+				return err
+			}
+			slog.Info("migration applied", "version", mig.Version)
+			n++
 		}
+		return nil
+	})
+	return n, err
+}
 
-		tx, err := pool.Begin()
-		if err != nil {
-			return fmt.Errorf("begin transaction for %s: %w", version, err)
+// Down rolls back the last steps applied migrations, newest first, with their .down.sql.
+// Returns the versions rolled back.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]string, error) {
+	var done []string
+	err := m.locked(ctx, func(conn *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error {
+		files := make(map[string]Migration, len(migrations))
+		for _, mig := range migrations {
+			files[mig.Version] = mig
+		}
+		versions := make([]string, 0, len(applied))
+		for v := range applied {
+			versions = append(versions, v)
 		}
+		sort.Sort(sort.Reverse(sort.StringSlice(versions)))
 
-		if _, err := tx.Exec(string(content)); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("execute migration %s: %w", version, err)
+		for _, v := range versions {
+			if len(done) == steps {
+				break
+			}
+			mig, ok := files[v]
+			if !ok || mig.DownPath == "" {
+				return fmt.Errorf("migration %s has no .down.sql", v)
+			}
+			if err := m.exec(ctx, conn, mig.DownPath, v, "DELETE FROM schema_migrations WHERE version = $1", v); err != nil {
+				return err
+			}
+			slog.Info("migration rolled back", "version", v)
+			done = append(done, v)
 		}
+		return nil
+	})
+	return done, err
+}
 
-		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES ($1)", version); err != nil {
-			tx.Rollback()
-			return fmt.Errorf("record migration %s: %w", version, err)
+// Status lists the migrations on disk and the applied ones no longer on disk, by version.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+	err := m.locked(ctx, func(_ *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error {
+		for _, mig := range migrations {
+			s := MigrationStatus{Version: mig.Version}
+			if a, ok := applied[mig.Version]; ok {
+				s.Applied, s.AppliedAt = true, a.at
+				s.Modified = a.checksum.Valid && a.checksum.String != mig.Checksum
+				delete(applied, mig.Version)
+			}
+			status = append(status, s)
 		}
+		for v, a := range applied {
+			status = append(status, MigrationStatus{Version: v, Applied: true, AppliedAt: a.at, Missing: true})
+		}
+		sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+		return nil
+	})
+	return status, err
+}
 
+// Force records the schema as being exactly at version without running any SQL: migrations up
+// to it count as applied (with their current checksums) and later ones as not applied. Use it
+// after fixing the schema by hand or to accept an edited migration file.
+func (m *Migrator) Force(ctx context.Context, version string) error {
+	return m.locked(ctx, func(conn *sql.Conn, migrations []Migration, applied map[string]appliedMigration) error {
+		known := false
+		for _, mig := range migrations {
+			known = known || mig.Version == version
+		}
+		if !known {
+			return fmt.Errorf("unknown migration %s", version)
+		}
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("force %s: %w", version, err)
+		}
+		defer tx.Rollback()
+		if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version > $1", version); err != nil {
+			return fmt.Errorf("force %s: %w", version, err)
+		}
+		for _, mig := range migrations {
+			if mig.Version > version {
+				break
+			}
+			if _, err := tx.ExecContext(ctx, `
+				INSERT INTO schema_migrations (version, checksum) VALUES ($1, $2)
+				ON CONFLICT (version) DO UPDATE SET checksum = EXCLUDED.checksum`,
+				mig.Version, mig.Checksum); err != nil {
+				return fmt.Errorf("force %s: %w", mig.Version, err)
+			}
+		}
 		if err := tx.Commit(); err != nil {
-			return fmt.Errorf("commit migration %s: %w", version, err)
+			return fmt.Errorf("force %s: %w", version, err)
 		}
+		slog.Info("migration version forced", "version", version)
+		return nil
+	})
+}
 
-		slog.Info("migration applied", "version", version)
+// exec runs the SQL file at path and the bookkeeping statement in one transaction.
+func (m *Migrator) exec(ctx context.Context, conn *sql.Conn, path, version, record string, args ...any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read migration file %s: %w", path, err)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction for %s: %w", version, err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, string(content)); err != nil {
+		return fmt.Errorf("execute migration %s: %w", filepath.Base(path), err)
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return fmt.Errorf("record migration %s: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %s: %w", version, err)
 	}
-
 	return nil
 }
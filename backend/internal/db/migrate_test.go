@@ -0,0 +1,70 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeMigrationFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadMigrations(t *testing.T) {
+	dir := writeMigrationFiles(t, map[string]string{
+		"002_b.up.sql":   "CREATE TABLE b (id INT);",
+		"001_a.up.sql":   "CREATE TABLE a (id INT);",
+		"001_a.down.sql": "DROP TABLE a;",
+		"README.md":      "not a migration",
+	})
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != "001_a" || migrations[1].Version != "002_b" {
+		t.Fatalf("expected 001_a, 002_b in order, got %+v", migrations)
+	}
+	if migrations[0].DownPath == "" || migrations[1].DownPath != "" {
+		t.Errorf("expected only 001_a to have a down file, got %q / %q", migrations[0].DownPath, migrations[1].DownPath)
+	}
+	if len(migrations[0].Checksum) != 64 || migrations[0].Checksum == migrations[1].Checksum {
+		t.Errorf("expected distinct SHA-256 checksums, got %q / %q", migrations[0].Checksum, migrations[1].Checksum)
+	}
+
+	// Editing a file changes its checksum.
+	before := migrations[1].Checksum
+	if err := os.WriteFile(filepath.Join(dir, "002_b.up.sql"), []byte("CREATE TABLE b (id BIGINT);"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	migrations, _ = LoadMigrations(dir)
+	if migrations[1].Checksum == before {
+		t.Error("expected the checksum to change with the file")
+	}
+}
+
+func TestLoadMigrations_OrphanDown(t *testing.T) {
+	dir := writeMigrationFiles(t, map[string]string{"003_c.down.sql": "DROP TABLE c;"})
+	if _, err := LoadMigrations(dir); err == nil || !strings.Contains(err.Error(), "003_c") {
+		t.Errorf("expected an error naming the orphan down file, got %v", err)
+	}
+}
+
+func TestLoadMigrations_RepoFiles(t *testing.T) {
+	migrations, err := LoadMigrations("../../../migrations")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range migrations {
+		if m.DownPath == "" {
+			t.Errorf("migration %s has no .down.sql", m.Version)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ChatNote is a note a chat asked the bot to keep.
+type ChatNote struct {
+	ID         int64     `json:"id"`
+	ChatID     int64     `json:"chat_id"`
+	Title      string    `json:"title"`
+	Body       string    `json:"body"`
+	AuthorID   *int64    `json:"author_id,omitempty"`
+	AuthorName *string   `json:"author_name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+const chatNoteColumns = `id, chat_id, title, body, author_id, author_name, created_at, updated_at`
+
+func scanChatNote(row interface{ Scan(...any) error }) (ChatNote, error) {
+	var n ChatNote
+	err := row.Scan(&n.ID, &n.ChatID, &n.Title, &n.Body, &n.AuthorID, &n.AuthorName, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
+
+// SaveChatNote stores a note, replacing the body and author of the chat's note with the same
+// title (case-insensitive). Returns the note's id and whether it is new.
+func (d *DB) SaveChatNote(ctx context.Context, n *ChatNote) (int64, bool, error) {
+	const query = `
+		INSERT INTO chat_notes (chat_id, title, body, author_id, author_name)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (chat_id, lower(title)) DO UPDATE
+		SET body = EXCLUDED.body, author_id = EXCLUDED.author_id, author_name = EXCLUDED.author_name, updated_at = NOW()
+		RETURNING id, xmax = 0`
+	var id int64
+	var created bool
+	if err := d.pool.QueryRowContext(ctx, query, n.ChatID, n.Title, n.Body, n.AuthorID, n.AuthorName).Scan(&id, &created); err != nil {
+		return 0, false, fmt.Errorf("save chat note: %w", err)
+	}
+	return id, created, nil
+}
+
+// CountChatNotes returns how many notes a chat has.
+func (d *DB) CountChatNotes(ctx context.Context, chatID int64) (int, error) {
+	var n int
+	if err := d.pool.QueryRowContext(ctx, "SELECT COUNT(*) FROM chat_notes WHERE chat_id = $1", chatID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count chat notes: %w", err)
+	}
+	return n, nil
+}
+
+// ListChatNotes returns up to limit notes of a chat, recently updated first. A non-empty query
+// keeps notes whose title or body contains it (case-insensitive).
+func (d *DB) ListChatNotes(ctx context.Context, chatID int64, query string, limit int) ([]ChatNote, error) {
+	rows, err := d.pool.QueryContext(ctx, `
+		SELECT `+chatNoteColumns+` FROM chat_notes
+		WHERE chat_id = $1 AND ($2 = '' OR strpos(lower(title || ' ' || body), lower($2)) > 0)
+		ORDER BY updated_at DESC, id DESC
+		LIMIT $3`, chatID, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list chat notes: %w", err)
+	}
+	defer rows.Close()
+	notes := []ChatNote{}
+	for rows.Next() {
+		n, err := scanChatNote(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan chat note: %w", err)
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+// GetChatNote finds a chat's note by id, or by title (case-insensitive) when id is 0. Returns nil
+// when there is none.
+func (d *DB) GetChatNote(ctx context.Context, chatID, id int64, title string) (*ChatNote, error) {
+	row := d.pool.QueryRowContext(ctx, `
+		SELECT `+chatNoteColumns+` FROM chat_notes
+		WHERE chat_id = $1 AND (id = $2 OR ($2 = 0 AND lower(title) = lower($3)))`, chatID, id, title)
+	n, err := scanChatNote(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get chat note: %w", err)
+	}
+	return &n, nil
+}
+
+// DeleteChatNote removes a chat's note; false when it did not exist.
+func (d *DB) DeleteChatNote(ctx context.Context, chatID, id int64) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, "DELETE FROM chat_notes WHERE chat_id = $1 AND id = $2", chatID, id)
+	if err != nil {
+		return false, fmt.Errorf("delete chat note: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrOffRecord is returned when something would be remembered from an off-the-record window.
+var ErrOffRecord = errors.New("chat is off the record")
+
+// OffRecordWindow is a time range whose messages are kept out of summaries, search and exports.
+// The rule itself lives in the SQL function is_off_record(chat_id, at); queries call it directly.
+type OffRecordWindow struct {
+	ID        int64      `json:"id"`
+	ChatID    int64      `json:"chat_id"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"` // nil = still open
+	Reason    string     `json:"reason,omitempty"`
+	CreatedBy *int64     `json:"created_by,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// InsertOffRecordWindow stores a window and returns its id.
+func (d *DB) InsertOffRecordWindow(ctx context.Context, w *OffRecordWindow) (int64, error) {
+	const query = `
+		INSERT INTO off_record_windows (chat_id, starts_at, ends_at, reason, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id`
+	var id int64
+	if err := d.pool.QueryRowContext(ctx, query, w.ChatID, w.StartsAt, w.EndsAt, w.Reason, w.CreatedBy).Scan(&id); err != nil {
+		return 0, fmt.Errorf("insert off-record window: %w", err)
+	}
+	return id, nil
+}
+
+// CloseOffRecordWindow sets the end of a window. Returns false if no such window exists in the chat
+// or endsAt is not after its start.
+func (d *DB) CloseOffRecordWindow(ctx context.Context, chatID, id int64, endsAt time.Time) (bool, error) {
+	res, err := d.pool.ExecContext(ctx,
+		`UPDATE off_record_windows SET ends_at = $3 WHERE id = $1 AND chat_id = $2 AND starts_at < $3`,
+		id, chatID, endsAt)
+	if err != nil {
+		return false, fmt.Errorf("close off-record window: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("close off-record window: %w", err)
+	}
+	return n > 0, nil
+}
+
+// DeleteOffRecordWindow removes a window (its messages become visible again). Returns false if not found.
+func (d *DB) DeleteOffRecordWindow(ctx context.Context, chatID, id int64) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, `DELETE FROM off_record_windows WHERE id = $1 AND chat_id = $2`, id, chatID)
+	if err != nil {
+		return false, fmt.Errorf("delete off-record window: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete off-record window: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ListOffRecordWindows returns a chat's windows, newest first.
+func (d *DB) ListOffRecordWindows(ctx context.Context, chatID int64) ([]OffRecordWindow, error) {
+	const query = `
+		SELECT id, chat_id, starts_at, ends_at, reason, created_by, created_at
+		FROM off_record_windows
+		WHERE chat_id = $1
+		ORDER BY starts_at DESC`
+	rows, err := d.pool.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("list off-record windows: %w", err)
+	}
+	defer rows.Close()
+
+	windows := []OffRecordWindow{}
+	for rows.Next() {
+		var w OffRecordWindow
+		if err := rows.Scan(&w.ID, &w.ChatID, &w.StartsAt, &w.EndsAt, &w.Reason, &w.CreatedBy, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan off-record window: %w", err)
+		}
+		windows = append(windows, w)
+	}
+	return windows, rows.Err()
+}
+
+// IsOffRecord reports whether the chat is inside an off-the-record window at the given time.
+func (d *DB) IsOffRecord(ctx context.Context, chatID int64, at time.Time) (bool, error) {
+	var off bool
+	if err := d.pool.QueryRowContext(ctx, `SELECT is_off_record($1, $2)`, chatID, at).Scan(&off); err != nil {
+		return false, fmt.Errorf("check off-record: %w", err)
+	}
+	return off, nil
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Persona is a named system prompt stored in the personas table.
+type Persona struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Prompt      string    `json:"prompt"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// GetPersona returns a persona by name, or nil if it does not exist.
+func (d *DB) GetPersona(ctx context.Context, name string) (*Persona, error) {
+	const query = `SELECT name, description, prompt, updated_at FROM personas WHERE name = $1`
+	var p Persona
+	err := d.pool.QueryRowContext(ctx, query, name).Scan(&p.Name, &p.Description, &p.Prompt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get persona: %w", err)
+	}
+	return &p, nil
+}
+
+// ListPersonas returns all stored personas ordered by name.
+func (d *DB) ListPersonas(ctx context.Context) ([]Persona, error) {
+	const query = `SELECT name, description, prompt, updated_at FROM personas ORDER BY name`
+	rows, err := d.pool.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list personas: %w", err)
+	}
+	defer rows.Close()
+
+	var out []Persona
+	for rows.Next() {
+		var p Persona
+		if err := rows.Scan(&p.Name, &p.Description, &p.Prompt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan persona: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// UpsertPersona creates or replaces a persona.
+func (d *DB) UpsertPersona(ctx context.Context, p *Persona) error {
+	const query = `
+		INSERT INTO personas (name, description, prompt)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET
+			description = EXCLUDED.description,
+			prompt = EXCLUDED.prompt,
+			updated_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, p.Name, p.Description, p.Prompt); err != nil {
+		return fmt.Errorf("upsert persona: %w", err)
+	}
+	return nil
+}
+
+// DeletePersona removes a persona and returns the chats that had it active (they fall back to the default).
+func (d *DB) DeletePersona(ctx context.Context, name string) ([]int64, error) {
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx,
+		`UPDATE chat_settings SET active_persona = NULL, updated_at = NOW() WHERE active_persona = $1 RETURNING chat_id`, name)
+	if err != nil {
+		return nil, fmt.Errorf("clear active persona: %w", err)
+	}
+	var chats []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scan chat_id: %w", err)
+		}
+		chats = append(chats, id)
+	}
+	rows.Close()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM personas WHERE name = $1`, name); err != nil {
+		return nil, fmt.Errorf("delete persona: %w", err)
+	}
+	return chats, tx.Commit()
+}
+
+// SetChatActivePersona sets (or with nil clears) the active persona of a chat without touching its other overrides.
+func (d *DB) SetChatActivePersona(ctx context.Context, chatID int64, name *string) error {
+	const query = `
+		INSERT INTO chat_settings (chat_id, active_persona)
+		VALUES ($1, $2)
+		ON CONFLICT (chat_id) DO UPDATE SET active_persona = EXCLUDED.active_persona, updated_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, chatID, name); err != nil {
+		return fmt.Errorf("set active persona: %w", err)
+	}
+	return nil
+}
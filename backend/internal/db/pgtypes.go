@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// typeMaps holds pgx type maps for decoding values that database/sql can't scan on its own
+// (arrays). A map caches scan plans and is not safe for concurrent use, hence the pool.
+var typeMaps = sync.Pool{New: func() any { return pgtype.NewMap() }}
+
+// arrayScanner scans a Postgres array column into a Go slice.
+type arrayScanner struct {
+	dst any
+}
+
+func (a arrayScanner) Scan(src any) error {
+	m := typeMaps.Get().(*pgtype.Map)
+	defer typeMaps.Put(m)
+	return m.SQLScanner(a.dst).Scan(src)
+}
+
+// array returns a scan target for an array column; dst is e.g. *[]string or *[]int64.
+// Slices are passed as query arguments directly.
+func array(dst any) sql.Scanner {
+	return arrayScanner{dst: dst}
+}
+
+// isUniqueViolation reports whether err is a Postgres unique_violation (23505).
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// nullTime passes a zero time as NULL, for optional time bounds.
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
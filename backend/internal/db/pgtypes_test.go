@@ -0,0 +1,44 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestArrayScanner(t *testing.T) {
+	var tools []string
+	if err := array(&tools).Scan(`{search_web,"run python"}`); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tools, []string{"search_web", "run python"}) {
+		t.Errorf("unexpected strings: %q", tools)
+	}
+
+	var ids []int64
+	if err := array(&ids).Scan(`{3,1,2}`); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []int64{3, 1, 2}) {
+		t.Errorf("unexpected ints: %v", ids)
+	}
+
+	ids = []int64{1}
+	if err := array(&ids).Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if ids != nil {
+		t.Errorf("expected NULL to scan as nil, got %v", ids)
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	if !isUniqueViolation(fmt.Errorf("update: %w", &pgconn.PgError{Code: "23505"})) {
+		t.Error("expected a wrapped 23505 to be a unique violation")
+	}
+	if isUniqueViolation(&pgconn.PgError{Code: "23503"}) || isUniqueViolation(fmt.Errorf("other")) {
+		t.Error("expected other errors not to be unique violations")
+	}
+}
@@ -0,0 +1,219 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Poll is a Telegram poll seen in a chat. OptionVotes/TotalVoters are the counts Telegram
+// reported in its last poll update (only delivered for polls the bot can observe).
+type Poll struct {
+	PollID      string
+	ChatID      int64
+	MessageID   *int64
+	Question    string
+	Options     []string
+	OptionVotes []int64
+	TotalVoters int
+	IsAnonymous bool
+	IsClosed    bool
+	CreatedBy   *int64
+	UpdatedAt   time.Time
+}
+
+// PollAnswer is one user's current vote in a non-anonymous poll.
+type PollAnswer struct {
+	PollID     string
+	UserID     int64
+	Username   *string
+	FirstName  *string
+	OptionIDs  []int64
+	AnsweredAt time.Time
+}
+
+// PollOption is a tallied poll option.
+type PollOption struct {
+	Text   string
+	Votes  int
+	Voters []string // display names of known voters (non-anonymous polls only)
+}
+
+// PollResult is a poll with its current tally.
+type PollResult struct {
+	Poll
+	Results []PollOption
+}
+
+// UpsertPoll stores a poll when it is first seen in a chat, or refreshes its options and counts.
+func (d *DB) UpsertPoll(ctx context.Context, p *Poll) error {
+	const query = `
+		INSERT INTO polls (poll_id, chat_id, message_id, question, options, option_votes, total_voters, is_anonymous, is_closed, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (poll_id) DO UPDATE SET
+			message_id = COALESCE(EXCLUDED.message_id, polls.message_id),
+			question = EXCLUDED.question,
+			options = EXCLUDED.options,
+			option_votes = EXCLUDED.option_votes,
+			total_voters = EXCLUDED.total_voters,
+			is_closed = EXCLUDED.is_closed,
+			updated_at = NOW()`
+	_, err := d.pool.ExecContext(ctx, query,
+		p.PollID, p.ChatID, p.MessageID, p.Question, p.Options,
+		p.OptionVotes, p.TotalVoters, p.IsAnonymous, p.IsClosed, p.CreatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("upsert poll: %w", err)
+	}
+	return nil
+}
+
+// UpdatePollState applies a poll state update (Telegram sends these without a chat_id).
+// Returns the number of rows updated; 0 means the poll was never seen in a chat.
+func (d *DB) UpdatePollState(ctx context.Context, pollID string, optionVotes []int64, totalVoters int, isClosed bool) (int64, error) {
+	const query = `
+		UPDATE polls SET option_votes = $2, total_voters = $3, is_closed = $4, updated_at = NOW()
+		WHERE poll_id = $1`
+	res, err := d.pool.ExecContext(ctx, query, pollID, optionVotes, totalVoters, isClosed)
+	if err != nil {
+		return 0, fmt.Errorf("update poll state: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// RecordPollAnswer stores a user's vote. An empty optionIDs means the vote was retracted.
+// Returns false if the poll is unknown.
+func (d *DB) RecordPollAnswer(ctx context.Context, a *PollAnswer) (bool, error) {
+	var exists bool
+	if err := d.pool.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM polls WHERE poll_id = $1)`, a.PollID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check poll: %w", err)
+	}
+	if !exists {
+		return false, nil
+	}
+
+	if len(a.OptionIDs) == 0 {
+		if _, err := d.pool.ExecContext(ctx, `DELETE FROM poll_answers WHERE poll_id = $1 AND user_id = $2`, a.PollID, a.UserID); err != nil {
+			return true, fmt.Errorf("retract poll answer: %w", err)
+		}
+	} else {
+		const query = `
+			INSERT INTO poll_answers (poll_id, user_id, username, first_name, option_ids)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (poll_id, user_id) DO UPDATE SET
+				username = EXCLUDED.username,
+				first_name = EXCLUDED.first_name,
+				option_ids = EXCLUDED.option_ids,
+				answered_at = NOW()`
+		if _, err := d.pool.ExecContext(ctx, query, a.PollID, a.UserID, a.Username, a.FirstName, a.OptionIDs); err != nil {
+			return true, fmt.Errorf("record poll answer: %w", err)
+		}
+	}
+
+	if _, err := d.pool.ExecContext(ctx, `UPDATE polls SET updated_at = NOW() WHERE poll_id = $1`, a.PollID); err != nil {
+		return true, fmt.Errorf("touch poll: %w", err)
+	}
+	return true, nil
+}
+
+// GetRecentPollResults returns up to limit polls in a chat active since the given time, newest first,
+// with their tallies.
+func (d *DB) GetRecentPollResults(ctx context.Context, chatID int64, since time.Time, limit int) ([]PollResult, error) {
+	const query = `
+		SELECT poll_id, chat_id, message_id, question, options, option_votes, total_voters,
+		       is_anonymous, is_closed, created_by, updated_at
+		FROM polls
+		WHERE chat_id = $1 AND updated_at >= $2
+		ORDER BY updated_at DESC
+		LIMIT $3`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get recent polls: %w", err)
+	}
+	defer rows.Close()
+
+	var polls []Poll
+	var ids []string
+	for rows.Next() {
+		var p Poll
+		if err := rows.Scan(
+			&p.PollID, &p.ChatID, &p.MessageID, &p.Question, array(&p.Options), array(&p.OptionVotes), &p.TotalVoters,
+			&p.IsAnonymous, &p.IsClosed, &p.CreatedBy, &p.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan poll: %w", err)
+		}
+		polls = append(polls, p)
+		ids = append(ids, p.PollID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(polls) == 0 {
+		return nil, nil
+	}
+
+	answers, err := d.getPollAnswers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]PollResult, 0, len(polls))
+	for i := range polls {
+		out = append(out, PollResult{Poll: polls[i], Results: tallyPoll(&polls[i], answers[polls[i].PollID])})
+	}
+	return out, nil
+}
+
+// getPollAnswers loads stored answers for the given polls, grouped by poll_id.
+func (d *DB) getPollAnswers(ctx context.Context, pollIDs []string) (map[string][]PollAnswer, error) {
+	const query = `
+		SELECT poll_id, user_id, username, first_name, option_ids, answered_at
+		FROM poll_answers
+		WHERE poll_id = ANY($1)
+		ORDER BY answered_at`
+	rows, err := d.pool.QueryContext(ctx, query, pollIDs)
+	if err != nil {
+		return nil, fmt.Errorf("get poll answers: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string][]PollAnswer)
+	for rows.Next() {
+		var a PollAnswer
+		if err := rows.Scan(&a.PollID, &a.UserID, &a.Username, &a.FirstName, array(&a.OptionIDs), &a.AnsweredAt); err != nil {
+			return nil, fmt.Errorf("scan poll answer: %w", err)
+		}
+		out[a.PollID] = append(out[a.PollID], a)
+	}
+	return out, rows.Err()
+}
+
+// tallyPoll counts votes per option. Stored answers give per-voter detail; Telegram's reported
+// counts may be higher (votes cast before the bot saw the poll), so the larger of the two wins.
+func tallyPoll(p *Poll, answers []PollAnswer) []PollOption {
+	results := make([]PollOption, len(p.Options))
+	for i, text := range p.Options {
+		results[i].Text = text
+	}
+	for _, a := range answers {
+		name := fmt.Sprintf("user %d", a.UserID)
+		if a.FirstName != nil && *a.FirstName != "" {
+			name = *a.FirstName
+		} else if a.Username != nil && *a.Username != "" {
+			name = "@" + *a.Username
+		}
+		for _, id := range a.OptionIDs {
+			if id < 0 || int(id) >= len(results) {
+				continue
+			}
+			results[id].Votes++
+			results[id].Voters = append(results[id].Voters, name)
+		}
+	}
+	for i, v := range p.OptionVotes {
+		if i < len(results) && int(v) > results[i].Votes {
+			results[i].Votes = int(v)
+		}
+	}
+	return results
+}
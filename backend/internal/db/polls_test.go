@@ -0,0 +1,31 @@
+package db
+
+import "testing"
+
+func TestTallyPoll(t *testing.T) {
+	alice, bob := "Alice", "bob"
+	p := &Poll{
+		Options:     []string{"Pizza", "Sushi", "Borscht"},
+		OptionVotes: []int64{0, 0, 4},
+	}
+	answers := []PollAnswer{
+		{UserID: 1, FirstName: &alice, OptionIDs: []int64{0}},
+		{UserID: 2, Username: &bob, OptionIDs: []int64{0, 1}},
+		{UserID: 3, OptionIDs: []int64{7}}, // out of range, ignored
+	}
+
+	got := tallyPoll(p, answers)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 options, got %d", len(got))
+	}
+	if got[0].Votes != 2 || len(got[0].Voters) != 2 || got[0].Voters[0] != "Alice" || got[0].Voters[1] != "@bob" {
+		t.Errorf("unexpected tally for option 0: %+v", got[0])
+	}
+	if got[1].Votes != 1 {
+		t.Errorf("expected 1 vote for option 1, got %d", got[1].Votes)
+	}
+	// Telegram-reported count wins when it exceeds the known answers
+	if got[2].Votes != 4 || len(got[2].Voters) != 0 {
+		t.Errorf("expected reported count 4 for option 2, got %+v", got[2])
+	}
+}
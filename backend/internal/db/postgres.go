@@ -7,67 +7,131 @@ import (
 	"log/slog"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/ThatHunky/gryag/backend/internal/atrest"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
 // Message represents a single stored message.
 type Message struct {
-	ID                 int64
-	ChatID             int64
-	UserID             *int64
-	Username           *string
-	FirstName          *string
-	Text               *string
-	MessageID          *int64
-	MediaType          *string
-	FileID             *string
-	IsBotReply         bool
-	RequestID          *string
-	WasThrottled       bool
-	ReplyToMessageID   *int64
-	CreatedAt          time.Time
+	ID               int64
+	ChatID           int64
+	UserID           *int64
+	Username         *string
+	FirstName        *string
+	Text             *string
+	MessageID        *int64
+	MediaType        *string
+	FileID           *string
+	IsBotReply       bool
+	RequestID        *string
+	WasThrottled     bool
+	ReplyToMessageID *int64
+	StickerEmoji     *string  // emoji associated with a sticker message
+	StickerSet       *string  // sticker set name (empty for loose stickers)
+	Transcript       *string  // transcript of a voice note or audio file (SetMessageTranscript)
+	ThreadID         int64    // forum topic (message_thread_id); 0 = not a topic message
+	SentimentValence *float64 // tone of a user message, -1..1 (sentiment.Analyze); nil = not scored
+	SentimentHeat    *float64 // how heated it is, 0..1; nil = not scored
+	CreatedAt        time.Time
+}
+
+// AllThreads passed as a thread ID reads a chat's messages across every forum topic
+// (e.g. search_messages with all_topics).
+const AllThreads int64 = -1
+
+// ChatThread identifies one forum topic of a chat (ThreadID 0 for regular chats).
+type ChatThread struct {
+	ChatID   int64
+	ThreadID int64
 }
 
 // UserFact represents a stored fact about a user.
 type UserFact struct {
-	ID        int64
-	ChatID    int64
-	UserID    int64
-	FactText  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID         int64
+	ChatID     int64
+	UserID     int64
+	FactText   string
+	Category   string // one of the FactCategory* constants
+	Importance int    // 1 (trivia) .. 5 (core identity)
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
 }
 
-// DB wraps the PostgreSQL connection pool.
+// DB wraps the PostgreSQL connection pool. Queries go through database/sql on top of a pgx
+// pool; pgx is used directly where it does more (batched round trips).
 type DB struct {
 	pool *sql.DB
+	pgx  *pgxpool.Pool
+
+	ctxCache         ContextCache // optional; caches reply context reads (see SetContextCache)
+	ctxCacheTTL      time.Duration
+	ctxCacheMessages int
+
+	scrubText func(*string) *string // optional; masks PII in stored message text (see SetTextScrubber)
+	cipher    *atrest.Cipher        // optional; encrypts stored text (see SetCipher)
 }
 
-// New creates a new DB connection pool.
-func New(dsn string) (*DB, error) {
-	pool, err := sql.Open("postgres", dsn)
-	if err != nil {
-		return nil, fmt.Errorf("db open: %w", err)
+// SetTextScrubber masks message text and transcripts with fn before they are stored
+// (SCRUB_STORED_MESSAGES); nil stores them as given.
+func (d *DB) SetTextScrubber(fn func(*string) *string) {
+	d.scrubText = fn
+}
+
+// scrubbed applies the text scrubber, if any.
+func (d *DB) scrubbed(text *string) *string {
+	if d.scrubText == nil {
+		return text
 	}
+	return d.scrubText(text)
+}
+
+// PoolOptions sizes the connection pool (POSTGRES_MAX_CONNS and friends).
+type PoolOptions struct {
+	MaxConns        int
+	MinConns        int
+	MaxConnLifetime time.Duration
+	MaxConnIdleTime time.Duration
+}
 
-	pool.SetMaxOpenConns(25)
-	pool.SetMaxIdleConns(5)
-	pool.SetConnMaxLifetime(5 * time.Minute)
+// New creates a new DB connection pool. TLS settings come from the DSN (sslmode, sslrootcert,
+// sslcert, sslkey).
+func New(dsn string, opts PoolOptions) (*DB, error) {
+	pcfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("db config: %w", err)
+	}
+	pcfg.MaxConns = int32(opts.MaxConns)
+	pcfg.MinConns = int32(opts.MinConns)
+	pcfg.MaxConnLifetime = opts.MaxConnLifetime
+	pcfg.MaxConnIdleTime = opts.MaxConnIdleTime
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	if err := pool.PingContext(ctx); err != nil {
+	pgxPool, err := pgxpool.NewWithConfig(ctx, pcfg)
+	if err != nil {
+		return nil, fmt.Errorf("db open: %w", err)
+	}
+	if err := pgxPool.Ping(ctx); err != nil {
+		pgxPool.Close()
 		return nil, fmt.Errorf("db ping: %w", err)
 	}
 
-	slog.Info("postgres connected")
-	return &DB{pool: pool}, nil
+	slog.Info("postgres connected", "host", pcfg.ConnConfig.Host, "tls", pcfg.ConnConfig.TLSConfig != nil, "max_conns", pcfg.MaxConns)
+	return &DB{pool: stdlib.OpenDBFromPool(pgxPool), pgx: pgxPool}, nil
 }
 
 // Close shuts down the connection pool.
 func (d *DB) Close() error {
-	return d.pool.Close()
+	err := d.pool.Close()
+	d.pgx.Close()
+	return err
+}
+
+// Ping checks that the database is reachable.
+func (d *DB) Ping(ctx context.Context) error {
+	return d.pool.PingContext(ctx)
 }
 
 // Pool returns the underlying *sql.DB for use in tests or migrations.
@@ -80,49 +144,137 @@ func (d *DB) Pool() *sql.DB {
 // InsertMessage stores a message in the log. Throttled messages use wasThrottled=true.
 func (d *DB) InsertMessage(ctx context.Context, msg *Message) (int64, error) {
 	const query = `
-		INSERT INTO messages (chat_id, user_id, username, first_name, text, message_id, media_type, file_id, is_bot_reply, request_id, was_throttled, reply_to_message_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		RETURNING id`
+		INSERT INTO messages (chat_id, user_id, username, first_name, text, message_id, media_type, file_id, is_bot_reply, request_id, was_throttled, reply_to_message_id, sticker_emoji, sticker_set, thread_id, sentiment_valence, sentiment_heat)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		RETURNING id, created_at`
 
 	var id int64
+	var createdAt time.Time
+	text := d.seal(d.scrubbed(msg.Text))
 	err := d.pool.QueryRowContext(ctx, query,
 		msg.ChatID, msg.UserID, msg.Username, msg.FirstName,
-		msg.Text, msg.MessageID, msg.MediaType, msg.FileID,
+		text, msg.MessageID, msg.MediaType, msg.FileID,
 		msg.IsBotReply, msg.RequestID, msg.WasThrottled, msg.ReplyToMessageID,
-	).Scan(&id)
+		msg.StickerEmoji, msg.StickerSet, msg.ThreadID, msg.SentimentValence, msg.SentimentHeat,
+	).Scan(&id, &createdAt)
 	if err != nil {
 		return 0, fmt.Errorf("insert message: %w", err)
 	}
+	row := *msg
+	row.ID, row.CreatedAt, row.FileID, row.Text = id, createdAt, nil, text // as recentMessagesQuery returns it
+	d.appendMessage(ctx, row)
 	return id, nil
 }
 
-// GetRecentMessages returns the last N messages for a chat, ordered oldest to newest.
-func (d *DB) GetRecentMessages(ctx context.Context, chatID int64, limit int) ([]Message, error) {
+// UpdateBotReplyDelivery backfills the Telegram message_id (and file_id for media replies) of a bot reply
+// stored under requestID, once the frontend has actually sent it. Returns the number of rows updated.
+func (d *DB) UpdateBotReplyDelivery(ctx context.Context, chatID int64, requestID string, messageID int64, fileID *string) (int64, error) {
 	const query = `
-		SELECT id, chat_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, created_at
-		FROM messages
-		WHERE chat_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2`
+		UPDATE messages
+		SET message_id = $3, file_id = COALESCE($4, file_id)
+		WHERE chat_id = $1 AND request_id = $2 AND is_bot_reply = TRUE
+		RETURNING thread_id`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, requestID, messageID, fileID)
+	if err != nil {
+		return 0, fmt.Errorf("update bot reply delivery: %w", err)
+	}
+	defer rows.Close()
+	var n int64
+	threads := make(map[int64]bool)
+	for rows.Next() {
+		var threadID int64
+		if err := rows.Scan(&threadID); err != nil {
+			return n, fmt.Errorf("update bot reply delivery: %w", err)
+		}
+		n++
+		threads[threadID] = true
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("update bot reply delivery: %w", err)
+	}
+	for threadID := range threads {
+		d.dropMessages(ctx, chatID, threadID)
+	}
+	return n, nil
+}
 
-	rows, err := d.pool.QueryContext(ctx, query, chatID, limit)
+// GetRecentMessages returns the last N messages of a chat's forum topic, ordered oldest to newest.
+// threadID 0 is a regular chat (or the General topic); AllThreads reads the whole chat.
+// Served from the context cache when one is set.
+func (d *DB) GetRecentMessages(ctx context.Context, chatID, threadID int64, limit int) ([]Message, error) {
+	if !d.messagesCacheable(threadID, limit) {
+		return d.queryRecentMessages(ctx, chatID, threadID, limit)
+	}
+	vers, err := d.ctxCache.GetCounts(ctx, fmt.Sprintf(messagesVersionKey, chatID, threadID))
+	if err != nil {
+		slog.WarnContext(ctx, "context cache: read versions failed", "chat_id", chatID, "error", err)
+		return d.queryRecentMessages(ctx, chatID, threadID, limit)
+	}
+	msgs, hit, mc := d.cachedMessages(ctx, chatID, threadID, limit, vers[0])
+	if hit {
+		d.openMessages(ctx, msgs)
+		return msgs, nil
+	}
+	msgs, err = d.queryStoredMessages(ctx, chatID, threadID, d.ctxCacheMessages)
+	if err != nil {
+		return nil, err
+	}
+	d.fillMessages(ctx, mc, msgs) // cached as stored, still encrypted
+	if n := len(msgs); n > limit {
+		msgs = msgs[n-limit:]
+	}
+	d.openMessages(ctx, msgs)
+	return msgs, nil
+}
+
+func (d *DB) queryRecentMessages(ctx context.Context, chatID, threadID int64, limit int) ([]Message, error) {
+	msgs, err := d.queryStoredMessages(ctx, chatID, threadID, limit)
+	if err != nil {
+		return nil, err
+	}
+	d.openMessages(ctx, msgs)
+	return msgs, nil
+}
+
+// queryStoredMessages runs recentMessagesQuery and returns the rows as stored.
+func (d *DB) queryStoredMessages(ctx context.Context, chatID, threadID int64, limit int) ([]Message, error) {
+	rows, err := d.pool.QueryContext(ctx, recentMessagesQuery, chatID, limit, threadID)
 	if err != nil {
 		return nil, fmt.Errorf("get recent messages: %w", err)
 	}
 	defer rows.Close()
+	return scanRecentMessages(rows)
+}
 
+// recentMessagesQuery takes chat_id, limit, thread_id and returns newest first.
+const recentMessagesQuery = `
+	SELECT id, chat_id, thread_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, sticker_emoji, sticker_set, created_at
+	FROM messages
+	WHERE chat_id = $1 AND ($3 < 0 OR thread_id = $3)
+	ORDER BY created_at DESC
+	LIMIT $2`
+
+// scanRecentMessages reads recentMessagesQuery rows (database/sql or pgx) and returns them oldest first.
+func scanRecentMessages(rows interface {
+	Next() bool
+	Scan(...any) error
+	Err() error
+}) ([]Message, error) {
 	var messages []Message
 	for rows.Next() {
 		var m Message
 		if err := rows.Scan(
-			&m.ID, &m.ChatID, &m.UserID, &m.Username, &m.FirstName,
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName,
 			&m.Text, &m.MessageID, &m.MediaType, &m.IsBotReply,
-			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.CreatedAt,
+			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.StickerEmoji, &m.StickerSet, &m.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
 		messages = append(messages, m)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get recent messages: %w", err)
+	}
 
 	// Reverse to oldest-first order
 	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
@@ -132,16 +284,18 @@ func (d *DB) GetRecentMessages(ctx context.Context, chatID int64, limit int) ([]
 	return messages, nil
 }
 
-// GetMessagesInRange returns messages for a chat within a time window, ordered oldest to newest.
-// Limit caps the number of messages to avoid unbounded result sets (e.g. 2000).
-func (d *DB) GetMessagesInRange(ctx context.Context, chatID int64, since, until time.Time, limit int) ([]Message, error) {
+// GetMessagesInRange returns messages for a chat's forum topic (AllThreads = whole chat) within a time
+// window, ordered oldest to newest. Limit caps the number of messages to avoid unbounded result sets (e.g. 2000).
+// Messages in off-the-record windows are excluded (this feeds summaries).
+func (d *DB) GetMessagesInRange(ctx context.Context, chatID, threadID int64, since, until time.Time, limit int) ([]Message, error) {
 	const query = `
-		SELECT id, chat_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, created_at
+		SELECT id, chat_id, thread_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, sticker_emoji, sticker_set, created_at
 		FROM messages
-		WHERE chat_id = $1 AND created_at >= $2 AND created_at <= $3
+		WHERE chat_id = $1 AND ($5 < 0 OR thread_id = $5) AND created_at >= $2 AND created_at <= $3
+		  AND NOT is_off_record(chat_id, created_at)
 		ORDER BY created_at ASC
 		LIMIT $4`
-	rows, err := d.pool.QueryContext(ctx, query, chatID, since, until, limit)
+	rows, err := d.pool.QueryContext(ctx, query, chatID, since, until, limit, threadID)
 	if err != nil {
 		return nil, fmt.Errorf("get messages in range: %w", err)
 	}
@@ -150,14 +304,15 @@ func (d *DB) GetMessagesInRange(ctx context.Context, chatID int64, since, until
 	for rows.Next() {
 		var m Message
 		if err := rows.Scan(
-			&m.ID, &m.ChatID, &m.UserID, &m.Username, &m.FirstName,
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName,
 			&m.Text, &m.MessageID, &m.MediaType, &m.IsBotReply,
-			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.CreatedAt,
+			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.StickerEmoji, &m.StickerSet, &m.CreatedAt,
 		); err != nil {
 			return nil, fmt.Errorf("scan message: %w", err)
 		}
 		messages = append(messages, m)
 	}
+	d.openMessages(ctx, messages)
 	return messages, nil
 }
 
@@ -186,30 +341,170 @@ func (d *DB) GetRecentChatIDs(ctx context.Context, since time.Duration) ([]int64
 	return ids, nil
 }
 
+// ChatLastHuman is a chat with recent messages and when a person (not the bot) last wrote in it.
+type ChatLastHuman struct {
+	ChatID      int64
+	LastHumanAt time.Time // zero if only the bot wrote in the period
+}
+
+// GetRecentChatLastHuman is GetRecentChatIDs with the time of each chat's last human message,
+// across all forum topics.
+func (d *DB) GetRecentChatLastHuman(ctx context.Context, since time.Duration) ([]ChatLastHuman, error) {
+	const query = `
+		SELECT chat_id, MAX(created_at) FILTER (WHERE NOT is_bot_reply)
+		FROM messages
+		WHERE created_at > $1
+		GROUP BY chat_id
+		ORDER BY MAX(created_at) DESC`
+	rows, err := d.pool.QueryContext(ctx, query, time.Now().Add(-since))
+	if err != nil {
+		return nil, fmt.Errorf("get recent chat activity: %w", err)
+	}
+	defer rows.Close()
+	var out []ChatLastHuman
+	for rows.Next() {
+		var a ChatLastHuman
+		var last sql.NullTime
+		if err := rows.Scan(&a.ChatID, &last); err != nil {
+			return nil, fmt.Errorf("scan chat activity: %w", err)
+		}
+		a.LastHumanAt = last.Time
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// GetRecentChatThreads is GetRecentChatIDs per forum topic: every (chat, thread) with messages
+// since the given duration, most recent activity first.
+func (d *DB) GetRecentChatThreads(ctx context.Context, since time.Duration) ([]ChatThread, error) {
+	const query = `
+		SELECT chat_id, thread_id
+		FROM messages
+		WHERE created_at > $1
+		GROUP BY chat_id, thread_id
+		ORDER BY MAX(created_at) DESC`
+	rows, err := d.pool.QueryContext(ctx, query, time.Now().Add(-since))
+	if err != nil {
+		return nil, fmt.Errorf("get recent chat threads: %w", err)
+	}
+	defer rows.Close()
+	var threads []ChatThread
+	for rows.Next() {
+		var t ChatThread
+		if err := rows.Scan(&t.ChatID, &t.ThreadID); err != nil {
+			return nil, fmt.Errorf("scan chat thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
 // ── Chat Summary Operations ─────────────────────────────────────────────
 
 // InsertChatSummary stores a new 7-day or 30-day summary for a chat.
-func (d *DB) InsertChatSummary(ctx context.Context, chatID int64, summaryType, summaryText string, periodStart, periodEnd time.Time) (int64, error) {
+func (d *DB) InsertChatSummary(ctx context.Context, chatID, threadID int64, summaryType, summaryText string, periodStart, periodEnd time.Time) (int64, error) {
 	const query = `
-		INSERT INTO chat_summaries (chat_id, summary_type, summary_text, period_start, period_end)
-		VALUES ($1, $2, $3, $4, $5)
+		INSERT INTO chat_summaries (chat_id, thread_id, summary_type, summary_text, period_start, period_end)
+		VALUES ($1, $2, $3, $4, $5, $6)
 		RETURNING id`
 	var id int64
-	err := d.pool.QueryRowContext(ctx, query, chatID, summaryType, summaryText, periodStart, periodEnd).Scan(&id)
+	err := d.pool.QueryRowContext(ctx, query, chatID, threadID, summaryType, summaryText, periodStart, periodEnd).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("insert chat summary: %w", err)
 	}
+	d.invalidateContext(ctx, summaryVersionKey, chatID, threadID)
 	return id, nil
 }
 
-// GetLatestSummary returns the most recent summary text for a chat and type (7day or 30day), or empty string if none.
-func (d *DB) GetLatestSummary(ctx context.Context, chatID int64, summaryType string) (string, error) {
+// SummaryState is a chat topic with recent messages and when each of its summaries last ran
+// (the latest period_end; zero if never).
+type SummaryState struct {
+	ChatThread
+	Last7Day  time.Time
+	Last30Day time.Time
+}
+
+// GetSummaryStates returns every chat topic with messages in the last since, with its latest
+// 7-day and 30-day summary times, most recently active first.
+func (d *DB) GetSummaryStates(ctx context.Context, since time.Duration) ([]SummaryState, error) {
+	const query = `
+		SELECT m.chat_id, m.thread_id,
+			MAX(s.period_end) FILTER (WHERE s.summary_type = '7day'),
+			MAX(s.period_end) FILTER (WHERE s.summary_type = '30day')
+		FROM (
+			SELECT chat_id, thread_id, MAX(created_at) AS last_message
+			FROM messages
+			WHERE created_at > $1
+			GROUP BY chat_id, thread_id
+		) m
+		LEFT JOIN chat_summaries s ON s.chat_id = m.chat_id AND s.thread_id = m.thread_id
+		GROUP BY m.chat_id, m.thread_id, m.last_message
+		ORDER BY m.last_message DESC`
+	rows, err := d.pool.QueryContext(ctx, query, time.Now().Add(-since))
+	if err != nil {
+		return nil, fmt.Errorf("get summary states: %w", err)
+	}
+	defer rows.Close()
+	var out []SummaryState
+	for rows.Next() {
+		var st SummaryState
+		var last7, last30 sql.NullTime
+		if err := rows.Scan(&st.ChatID, &st.ThreadID, &last7, &last30); err != nil {
+			return nil, fmt.Errorf("scan summary state: %w", err)
+		}
+		st.Last7Day, st.Last30Day = last7.Time, last30.Time
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+// ChatSummary is one stored 7-day or 30-day summary of a chat's forum topic.
+type ChatSummary struct {
+	ID          int64
+	ChatID      int64
+	ThreadID    int64
+	SummaryType string
+	SummaryText string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+}
+
+// GetSummariesInRange returns a chat topic's summaries of one type whose period ends in (since, until],
+// oldest first. Summaries written before an off-the-record window that overlaps their period are
+// skipped, since they may contain messages that are now off the record.
+func (d *DB) GetSummariesInRange(ctx context.Context, chatID, threadID int64, summaryType string, since, until time.Time) ([]ChatSummary, error) {
 	const query = `
-		SELECT summary_text FROM chat_summaries
-		WHERE chat_id = $1 AND summary_type = $2
-		ORDER BY period_end DESC LIMIT 1`
+		SELECT s.id, s.chat_id, s.thread_id, s.summary_type, s.summary_text, s.period_start, s.period_end
+		FROM chat_summaries s
+		WHERE s.chat_id = $1 AND s.thread_id = $2 AND s.summary_type = $3
+		  AND s.period_end > $4 AND s.period_end <= $5
+		  AND NOT EXISTS (
+			SELECT 1 FROM off_record_windows w
+			WHERE w.chat_id = s.chat_id AND w.created_at > s.created_at
+			  AND w.starts_at < s.period_end AND (w.ends_at IS NULL OR w.ends_at > s.period_start)
+		  )
+		ORDER BY s.period_end ASC`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, threadID, summaryType, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("get summaries in range: %w", err)
+	}
+	defer rows.Close()
+	var out []ChatSummary
+	for rows.Next() {
+		var cs ChatSummary
+		if err := rows.Scan(&cs.ID, &cs.ChatID, &cs.ThreadID, &cs.SummaryType, &cs.SummaryText, &cs.PeriodStart, &cs.PeriodEnd); err != nil {
+			return nil, fmt.Errorf("scan chat summary: %w", err)
+		}
+		out = append(out, cs)
+	}
+	return out, rows.Err()
+}
+
+// GetLatestSummary returns the most recent summary text for a chat's forum topic and type (7day or 30day),
+// or empty string if none.
+func (d *DB) GetLatestSummary(ctx context.Context, chatID, threadID int64, summaryType string) (string, error) {
 	var text string
-	err := d.pool.QueryRowContext(ctx, query, chatID, summaryType).Scan(&text)
+	err := d.pool.QueryRowContext(ctx, latestSummaryQuery, chatID, summaryType, threadID).Scan(&text)
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
@@ -219,31 +514,81 @@ func (d *DB) GetLatestSummary(ctx context.Context, chatID int64, summaryType str
 	return text, nil
 }
 
+// latestSummaryQuery takes chat_id, summary_type, thread_id.
+const latestSummaryQuery = `
+	SELECT summary_text FROM chat_summaries
+	WHERE chat_id = $1 AND thread_id = $3 AND summary_type = $2
+	ORDER BY period_end DESC LIMIT 1`
+
+// GetChatsWithUnsummarizedMessages returns chat topics that have at least threshold user messages in the
+// last 7 days newer than their latest 7-day summary (or no summary at all), busiest first.
+func (d *DB) GetChatsWithUnsummarizedMessages(ctx context.Context, threshold int) ([]ChatThread, error) {
+	const query = `
+		SELECT m.chat_id, m.thread_id
+		FROM messages m
+		LEFT JOIN (
+			SELECT chat_id, thread_id, MAX(period_end) AS last_end
+			FROM chat_summaries
+			WHERE summary_type = '7day'
+			GROUP BY chat_id, thread_id
+		) s ON s.chat_id = m.chat_id AND s.thread_id = m.thread_id
+		WHERE m.is_bot_reply = FALSE
+		  AND m.created_at > NOW() - INTERVAL '7 days'
+		  AND (s.last_end IS NULL OR m.created_at > s.last_end)
+		  AND NOT is_off_record(m.chat_id, m.created_at)
+		GROUP BY m.chat_id, m.thread_id
+		HAVING COUNT(*) >= $1
+		ORDER BY COUNT(*) DESC`
+	rows, err := d.pool.QueryContext(ctx, query, threshold)
+	if err != nil {
+		return nil, fmt.Errorf("get chats with unsummarized messages: %w", err)
+	}
+	defer rows.Close()
+	var threads []ChatThread
+	for rows.Next() {
+		var t ChatThread
+		if err := rows.Scan(&t.ChatID, &t.ThreadID); err != nil {
+			return nil, fmt.Errorf("scan chat thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
 // ── User Fact Operations ────────────────────────────────────────────────
 
 // InsertUserFact stores a new fact about a user. Duplicates are silently ignored.
-func (d *DB) InsertUserFact(ctx context.Context, chatID, userID int64, factText string) (int64, error) {
+// category and importance must already be normalized (see NormalizeFactCategory, ClampImportance).
+// Returns ErrOffRecord while the chat is in an off-the-record window.
+func (d *DB) InsertUserFact(ctx context.Context, chatID, userID int64, factText, category string, importance int) (int64, error) {
+	if off, err := d.IsOffRecord(ctx, chatID, time.Now()); err != nil {
+		return 0, err
+	} else if off {
+		return 0, ErrOffRecord
+	}
+
 	const query = `
-		INSERT INTO user_facts (chat_id, user_id, fact_text)
-		VALUES ($1, $2, $3)
+		INSERT INTO user_facts (chat_id, user_id, fact_text, category, importance)
+		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (chat_id, user_id, md5(fact_text)) DO NOTHING
 		RETURNING id`
 
 	var id int64
-	err := d.pool.QueryRowContext(ctx, query, chatID, userID, factText).Scan(&id)
+	err := d.pool.QueryRowContext(ctx, query, chatID, userID, d.sealFact(factText), category, importance).Scan(&id)
 	if err == sql.ErrNoRows {
 		return 0, nil // duplicate — silently ignored
 	}
 	if err != nil {
 		return 0, fmt.Errorf("insert user fact: %w", err)
 	}
+	d.invalidateContext(ctx, userVersionKey, userID)
 	return id, nil
 }
 
 // GetUserFacts returns all facts stored for a specific user in a chat.
 func (d *DB) GetUserFacts(ctx context.Context, chatID, userID int64) ([]UserFact, error) {
 	const query = `
-		SELECT id, chat_id, user_id, fact_text, created_at, updated_at
+		SELECT id, chat_id, user_id, fact_text, category, importance, created_at, updated_at
 		FROM user_facts
 		WHERE chat_id = $1 AND user_id = $2
 		ORDER BY created_at ASC`
@@ -257,19 +602,64 @@ func (d *DB) GetUserFacts(ctx context.Context, chatID, userID int64) ([]UserFact
 	var facts []UserFact
 	for rows.Next() {
 		var f UserFact
-		if err := rows.Scan(&f.ID, &f.ChatID, &f.UserID, &f.FactText, &f.CreatedAt, &f.UpdatedAt); err != nil {
+		if err := rows.Scan(&f.ID, &f.ChatID, &f.UserID, &f.FactText, &f.Category, &f.Importance, &f.CreatedAt, &f.UpdatedAt); err != nil {
 			return nil, fmt.Errorf("scan user fact: %w", err)
 		}
 		facts = append(facts, f)
 	}
+	d.openFacts(ctx, facts)
 	return facts, nil
 }
 
+// GetTopUserFacts returns the user's limit most important facts (most recently updated first on ties)
+// and the total number of facts stored, so callers can tell when some were left out.
+// With includeGlobal the user's global facts (GlobalFactsChatID) compete for the same slots.
+func (d *DB) GetTopUserFacts(ctx context.Context, chatID, userID int64, limit int, includeGlobal bool) ([]UserFact, int, error) {
+	const query = `
+		SELECT id, chat_id, user_id, fact_text, category, importance, created_at, updated_at, COUNT(*) OVER ()
+		FROM user_facts
+		WHERE (chat_id = $1 OR ($4 AND chat_id = $5)) AND user_id = $2
+		ORDER BY importance DESC, updated_at DESC
+		LIMIT $3`
+
+	rows, err := d.pool.QueryContext(ctx, query, chatID, userID, limit, includeGlobal, GlobalFactsChatID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("get top user facts: %w", err)
+	}
+	defer rows.Close()
+	facts, total, err := scanTopUserFacts(rows)
+	d.openFacts(ctx, facts)
+	return facts, total, err
+}
+
+// scanTopUserFacts reads fact rows that end with a COUNT(*) OVER () total.
+func scanTopUserFacts(rows interface {
+	Next() bool
+	Scan(...any) error
+	Err() error
+}) ([]UserFact, int, error) {
+	var facts []UserFact
+	total := 0
+	for rows.Next() {
+		var f UserFact
+		if err := rows.Scan(&f.ID, &f.ChatID, &f.UserID, &f.FactText, &f.Category, &f.Importance, &f.CreatedAt, &f.UpdatedAt, &total); err != nil {
+			return nil, 0, fmt.Errorf("scan user fact: %w", err)
+		}
+		facts = append(facts, f)
+	}
+	return facts, total, rows.Err()
+}
+
 // DeleteUserFact removes a specific fact by ID.
 func (d *DB) DeleteUserFact(ctx context.Context, factID int64) error {
-	_, err := d.pool.ExecContext(ctx, "DELETE FROM user_facts WHERE id = $1", factID)
+	var userID int64
+	err := d.pool.QueryRowContext(ctx, "DELETE FROM user_facts WHERE id = $1 RETURNING user_id", factID).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("delete user fact: %w", err)
 	}
+	d.invalidateContext(ctx, userVersionKey, userID)
 	return nil
 }
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ProactiveDelivery is one proactive queue item handed to the frontend, as stored in
+// proactive_deliveries.
+type ProactiveDelivery struct {
+	ID          int64     `json:"id"`
+	ChatID      int64     `json:"chat_id"`
+	Text        string    `json:"text"`
+	Source      string    `json:"source"`
+	DeliveredAt time.Time `json:"delivered_at"`
+}
+
+// RecordProactiveDelivery stores one delivered proactive item.
+func (d *DB) RecordProactiveDelivery(ctx context.Context, chatID int64, text, source string) error {
+	const query = `INSERT INTO proactive_deliveries (chat_id, text, source) VALUES ($1, $2, $3)`
+	if _, err := d.pool.ExecContext(ctx, query, chatID, text, source); err != nil {
+		return fmt.Errorf("record proactive delivery: %w", err)
+	}
+	return nil
+}
+
+// ProactiveDeliveries returns the chat's latest deliveries, newest first. source narrows them
+// when set.
+func (d *DB) ProactiveDeliveries(ctx context.Context, chatID int64, source string, limit int) ([]ProactiveDelivery, error) {
+	const query = `
+		SELECT id, chat_id, text, source, delivered_at
+		FROM proactive_deliveries
+		WHERE chat_id = $1 AND ($2 = '' OR source = $2)
+		ORDER BY delivered_at DESC, id DESC
+		LIMIT $3`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, source, limit)
+	if err != nil {
+		return nil, fmt.Errorf("proactive deliveries: %w", err)
+	}
+	defer rows.Close()
+	out := []ProactiveDelivery{}
+	for rows.Next() {
+		var p ProactiveDelivery
+		if err := rows.Scan(&p.ID, &p.ChatID, &p.Text, &p.Source, &p.DeliveredAt); err != nil {
+			return nil, fmt.Errorf("scan proactive delivery: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("proactive deliveries: %w", err)
+	}
+	return out, nil
+}
+
+// PruneOldProactiveDeliveries deletes deliveries past their chat's message retention, like
+// PruneOldMessages.
+func (d *DB) PruneOldProactiveDeliveries(ctx context.Context, defaultDays int) (int64, error) {
+	const query = `
+		DELETE FROM proactive_deliveries WHERE id IN (
+			SELECT p.id FROM proactive_deliveries p
+			LEFT JOIN chat_settings cs ON cs.chat_id = p.chat_id
+			WHERE COALESCE(cs.message_retention_days, $1) > 0
+			  AND p.delivered_at < NOW() - INTERVAL '1 day' * COALESCE(cs.message_retention_days, $1)
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, defaultDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old proactive deliveries: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old proactive deliveries", "deleted", total, "default_retention_days", defaultDays)
+	}
+	return total, nil
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserProfile is everything stored about a user in one chat, or across every chat (ChatID 0):
+// who they are, how much and since when they write, their preferences, karma and facts.
+type UserProfile struct {
+	UserID       int64         `json:"user_id"`
+	ChatID       int64         `json:"chat_id,omitempty"`
+	Username     *string       `json:"username,omitempty"`   // as of their latest message
+	FirstName    *string       `json:"first_name,omitempty"` // as of their latest message
+	Messages     int64         `json:"messages"`
+	Chats        int           `json:"chats"` // chats they wrote in (1 or 0 for a chat profile)
+	FirstSeen    *time.Time    `json:"first_seen,omitempty"`
+	LastSeen     *time.Time    `json:"last_seen,omitempty"`
+	Language     *string       `json:"language,omitempty"` // preferred reply language
+	Timezone     *string       `json:"timezone,omitempty"`
+	GlobalMemory bool          `json:"global_memory"`
+	Karma        *KarmaEntry   `json:"karma,omitempty"` // chat profiles only; nil = no votes
+	FactsTotal   int           `json:"facts_total"`     // the chat's facts plus global ones when opted in
+	Facts        []ProfileFact `json:"facts,omitempty"` // only when asked for, most important first
+}
+
+// ProfileFact is one fact in a UserProfile.
+type ProfileFact struct {
+	ID         int64     `json:"id"`
+	ChatID     int64     `json:"chat_id"` // GlobalFactsChatID = follows the user into every chat
+	Text       string    `json:"text"`
+	Category   string    `json:"category,omitempty"`
+	Importance int       `json:"importance"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GetUserProfile aggregates what is stored about userID in chatID (0 = every chat). Facts are
+// loaded only withFacts; FactsTotal is always set. Retention-pruned messages no longer count, so
+// FirstSeen is the oldest message still kept.
+func (d *DB) GetUserProfile(ctx context.Context, chatID, userID int64, withFacts bool) (*UserProfile, error) {
+	p := &UserProfile{UserID: userID, ChatID: chatID}
+
+	const statsQuery = `
+		SELECT COUNT(*), COUNT(DISTINCT chat_id), MIN(created_at), MAX(created_at)
+		FROM messages
+		WHERE user_id = $1 AND ($2 = 0 OR chat_id = $2) AND NOT is_bot_reply`
+	var first, last sql.NullTime
+	if err := d.pool.QueryRowContext(ctx, statsQuery, userID, chatID).Scan(&p.Messages, &p.Chats, &first, &last); err != nil {
+		return nil, fmt.Errorf("get user profile stats: %w", err)
+	}
+	if first.Valid {
+		p.FirstSeen, p.LastSeen = &first.Time, &last.Time
+	}
+
+	const nameQuery = `
+		SELECT username, first_name FROM messages
+		WHERE user_id = $1 AND ($2 = 0 OR chat_id = $2) AND NOT is_bot_reply
+		ORDER BY created_at DESC
+		LIMIT 1`
+	err := d.pool.QueryRowContext(ctx, nameQuery, userID, chatID).Scan(&p.Username, &p.FirstName)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("get user profile name: %w", err)
+	}
+
+	settings, err := d.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if settings != nil {
+		p.Language, p.Timezone, p.GlobalMemory = settings.Language, settings.Timezone, settings.GlobalMemory
+	}
+
+	if chatID != 0 {
+		if p.Karma, err = d.GetKarma(ctx, chatID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	// A chat profile covers the chat's facts and, for users who opted in, their global ones
+	const factsQuery = `
+		SELECT id, chat_id, fact_text, category, importance, updated_at, COUNT(*) OVER ()
+		FROM user_facts
+		WHERE user_id = $1 AND ($2 = 0 OR chat_id = $2 OR ($3 AND chat_id = $4))
+		ORDER BY importance DESC, updated_at DESC
+		LIMIT $5`
+	limit := 1 // without facts one row is enough for the total
+	if withFacts {
+		limit = 1000
+	}
+	rows, err := d.pool.QueryContext(ctx, factsQuery, userID, chatID, p.GlobalMemory, GlobalFactsChatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get user profile facts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var f ProfileFact
+		if err := rows.Scan(&f.ID, &f.ChatID, &f.Text, &f.Category, &f.Importance, &f.UpdatedAt, &p.FactsTotal); err != nil {
+			return nil, fmt.Errorf("scan user profile fact: %w", err)
+		}
+		if withFacts {
+			d.open(ctx, &f.Text)
+			p.Facts = append(p.Facts, f)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get user profile facts: %w", err)
+	}
+	return p, nil
+}
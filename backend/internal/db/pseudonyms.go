@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pseudonym is a user's stable alias in a privacy-mode chat: the model knows them as
+// "Member <Alias>" with user ID Alias. FirstName and Username are the names last seen for them.
+type Pseudonym struct {
+	UserID    int64
+	Alias     int
+	FirstName string
+	Username  string
+}
+
+// SyncPseudonyms records the users seen in a chat's prompt (newcomers get the next alias, known
+// users get their current names) and returns all of the chat's pseudonyms, by alias. Empty names
+// keep the stored ones.
+func (d *DB) SyncPseudonyms(ctx context.Context, chatID int64, seen []Pseudonym) ([]Pseudonym, error) {
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+	// Two prompts of the same chat must not hand out the same alias
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended('chat_pseudonyms:' || $1::text, 0))`, chatID); err != nil {
+		return nil, fmt.Errorf("lock chat pseudonyms: %w", err)
+	}
+	const upsert = `
+		INSERT INTO chat_pseudonyms (chat_id, user_id, alias, first_name, username)
+		VALUES ($1, $2, (SELECT COALESCE(MAX(alias), 0) + 1 FROM chat_pseudonyms WHERE chat_id = $1), NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET
+			first_name = COALESCE(EXCLUDED.first_name, chat_pseudonyms.first_name),
+			username = COALESCE(EXCLUDED.username, chat_pseudonyms.username),
+			updated_at = NOW()`
+	for _, p := range seen {
+		if _, err := tx.ExecContext(ctx, upsert, chatID, p.UserID, p.FirstName, p.Username); err != nil {
+			return nil, fmt.Errorf("upsert chat pseudonym: %w", err)
+		}
+	}
+
+	const query = `
+		SELECT user_id, alias, COALESCE(first_name, ''), COALESCE(username, '')
+		FROM chat_pseudonyms
+		WHERE chat_id = $1
+		ORDER BY alias`
+	rows, err := tx.QueryContext(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("list chat pseudonyms: %w", err)
+	}
+	defer rows.Close()
+	var out []Pseudonym
+	for rows.Next() {
+		var p Pseudonym
+		if err := rows.Scan(&p.UserID, &p.Alias, &p.FirstName, &p.Username); err != nil {
+			return nil, fmt.Errorf("scan chat pseudonym: %w", err)
+		}
+		out = append(out, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list chat pseudonyms: %w", err)
+	}
+	rows.Close()
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit chat pseudonyms: %w", err)
+	}
+	return out, nil
+}
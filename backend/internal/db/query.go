@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrQueryRejected is returned when an admin query is not a single read-only statement or
+// Postgres refuses it (syntax, permissions, read-only violation, timeout).
+var ErrQueryRejected = errors.New("query rejected")
+
+// QueryLimits bounds one admin query.
+type QueryLimits struct {
+	Role     string        // SET LOCAL ROLE; required, a role that can only SELECT
+	MaxRows  int           // rows returned; more set Truncated
+	MaxBytes int           // approximate JSON size of the rows; more set Truncated
+	Timeout  time.Duration // statement_timeout (0 = server default)
+}
+
+// QueryResult is the JSON view of an admin query.
+type QueryResult struct {
+	Columns    []string `json:"columns"`
+	Rows       [][]any  `json:"rows"`
+	Truncated  bool     `json:"truncated"`
+	DurationMS int64    `json:"duration_ms"`
+}
+
+// readOnlyStatements are the statements an admin query may start with.
+var readOnlyStatements = map[string]bool{
+	"select": true, "with": true, "table": true, "values": true, "explain": true, "show": true,
+}
+
+// deniedFunctions have side effects a read-only transaction does not stop (or, like set_config,
+// could undo the role the query runs as).
+var deniedFunctions = regexp.MustCompile(`(?i)\b(set_config|pg_terminate_backend|pg_cancel_backend|pg_reload_conf|` +
+	`pg_rotate_logfile|pg_read_file|pg_read_binary_file|pg_ls_dir|pg_stat_file|lo_import|lo_export|` +
+	`pg_notify|pg_sleep\w*|pg_advisory\w*|dblink\w*)"?\s*\(`)
+
+// checkReadOnlySQL allows a single statement from readOnlyStatements without denied functions,
+// and returns it without trailing semicolons. A ";" anywhere else is refused, even inside a string.
+func checkReadOnlySQL(query string) (string, error) {
+	q := strings.TrimRight(strings.TrimSpace(query), "; \t\r\n")
+	if q == "" {
+		return "", fmt.Errorf("empty query")
+	}
+	if strings.Contains(q, ";") {
+		return "", fmt.Errorf("only one statement is allowed")
+	}
+	end := strings.IndexFunc(q, func(r rune) bool { return !unicode.IsLetter(r) })
+	if end < 0 {
+		end = len(q)
+	}
+	if !readOnlyStatements[strings.ToLower(q[:end])] {
+		return "", fmt.Errorf("only SELECT, WITH, TABLE, VALUES, EXPLAIN and SHOW are allowed")
+	}
+	if m := deniedFunctions.FindStringSubmatch(q); m != nil {
+		return "", fmt.Errorf("function %s is not allowed", strings.ToLower(m[1]))
+	}
+	return q, nil
+}
+
+// ReadOnlyQuery runs one admin query in a read-only transaction as lim.Role and returns its rows
+// as JSON-ready values. The transaction is always rolled back. The role is what makes the query
+// read-only; the statement check in checkReadOnlySQL is a second layer.
+func (d *DB) ReadOnlyQuery(ctx context.Context, query string, lim QueryLimits) (*QueryResult, error) {
+	q, err := checkReadOnlySQL(query)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrQueryRejected, err)
+	}
+	if lim.Role == "" {
+		return nil, errors.New("admin query needs a read-only role (ADMIN_QUERY_ROLE)")
+	}
+	start := time.Now()
+	tx, err := d.pool.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	if lim.Timeout > 0 {
+		ms := strconv.FormatInt(lim.Timeout.Milliseconds(), 10)
+		if _, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`, ms); err != nil {
+			return nil, fmt.Errorf("set statement_timeout: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('role', $1, true)`, lim.Role); err != nil {
+		return nil, fmt.Errorf("set role %s: %w", lim.Role, err)
+	}
+
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return nil, queryError(err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &QueryResult{Columns: cols, Rows: [][]any{}}
+	size := 0
+	for rows.Next() {
+		if len(res.Rows) >= lim.MaxRows {
+			res.Truncated = true
+			break
+		}
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		for i, v := range vals {
+			vals[i] = jsonValue(v)
+		}
+		data, err := json.Marshal(vals)
+		if err != nil {
+			return nil, fmt.Errorf("encode row: %w", err)
+		}
+		if size += len(data); lim.MaxBytes > 0 && size > lim.MaxBytes {
+			res.Truncated = true
+			break
+		}
+		res.Rows = append(res.Rows, vals)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, queryError(err)
+	}
+	res.DurationMS = time.Since(start).Milliseconds()
+	return res, nil
+}
+
+// queryError passes Postgres' own message on as ErrQueryRejected; it describes the admin's query.
+func queryError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return fmt.Errorf("%w: %s", ErrQueryRejected, pgErr.Message)
+	}
+	return err
+}
+
+// jsonValue converts a scanned value into something encoding/json shows usefully: text and JSON
+// columns come back as bytes, UUIDs as a 16-byte array.
+func jsonValue(v any) any {
+	switch x := v.(type) {
+	case []byte:
+		if utf8.Valid(x) {
+			return string(x)
+		}
+		return `\x` + hex.EncodeToString(x)
+	case [16]byte:
+		h := hex.EncodeToString(x[:])
+		return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+	}
+	return v
+}
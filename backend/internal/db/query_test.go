@@ -0,0 +1,55 @@
+package db
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckReadOnlySQL(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT count(*) FROM messages WHERE chat_id = -100;  ": "SELECT count(*) FROM messages WHERE chat_id = -100",
+		"with x as (select 1) select * from x":                  "with x as (select 1) select * from x",
+		"EXPLAIN SELECT 1":                                      "EXPLAIN SELECT 1",
+		"table tool_calls":                                      "table tool_calls",
+		"SHOW server_version":                                   "SHOW server_version",
+	} {
+		got, err := checkReadOnlySQL(query)
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v", query, got, err)
+		}
+	}
+
+	for query, reason := range map[string]string{
+		"   ;":                                        "empty",
+		"SELECT 1; DROP TABLE messages":               "one statement",
+		"DELETE FROM messages":                        "only SELECT",
+		"/* x */ SELECT 1":                            "only SELECT",
+		"SET ROLE postgres":                           "only SELECT",
+		"COPY messages TO '/tmp/x'":                   "only SELECT",
+		"SELECT set_config('role', 'none', true)":     "set_config",
+		`SELECT pg_catalog."pg_terminate_backend"(1)`: "pg_terminate_backend",
+		"select PG_SLEEP (100)":                       "pg_sleep",
+		"SELECT pg_advisory_lock(1)":                  "pg_advisory_lock",
+	} {
+		_, err := checkReadOnlySQL(query)
+		if err == nil || !strings.Contains(err.Error(), reason) {
+			t.Errorf("%q: expected an error about %q, got %v", query, reason, err)
+		}
+	}
+}
+
+func TestJSONValue(t *testing.T) {
+	if v := jsonValue([]byte(`{"a":1}`)); v != `{"a":1}` {
+		t.Errorf("expected text bytes as a string, got %v", v)
+	}
+	if v := jsonValue([]byte{0xff, 0x00}); v != `\xff00` {
+		t.Errorf("expected binary bytes as hex, got %v", v)
+	}
+	uuid := [16]byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0}
+	if v := jsonValue(uuid); v != "12345678-9abc-def0-1234-56789abcdef0" {
+		t.Errorf("unexpected uuid %v", v)
+	}
+	if v := jsonValue(int64(5)); v != int64(5) {
+		t.Errorf("expected other values unchanged, got %v", v)
+	}
+}
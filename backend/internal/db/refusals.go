@@ -0,0 +1,53 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// UserRefusal is an offer a user has declined.
+type UserRefusal struct {
+	Offer          string
+	TimesDeclined  int
+	LastDeclinedAt time.Time
+}
+
+// RecordRefusal stores (or bumps) a declined offer for a user. offer should already be normalized.
+func (d *DB) RecordRefusal(ctx context.Context, userID int64, offer string) error {
+	const query = `
+		INSERT INTO user_refusals (user_id, offer)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, offer) DO UPDATE SET
+			times_declined = user_refusals.times_declined + 1,
+			last_declined_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, userID, offer); err != nil {
+		return fmt.Errorf("record refusal: %w", err)
+	}
+	return nil
+}
+
+// GetRecentRefusals returns offers the user declined since the given time, most recent first.
+func (d *DB) GetRecentRefusals(ctx context.Context, userID int64, since time.Time, limit int) ([]UserRefusal, error) {
+	const query = `
+		SELECT offer, times_declined, last_declined_at
+		FROM user_refusals
+		WHERE user_id = $1 AND last_declined_at >= $2
+		ORDER BY last_declined_at DESC
+		LIMIT $3`
+	rows, err := d.pool.QueryContext(ctx, query, userID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get refusals: %w", err)
+	}
+	defer rows.Close()
+
+	var out []UserRefusal
+	for rows.Next() {
+		var r UserRefusal
+		if err := rows.Scan(&r.Offer, &r.TimesDeclined, &r.LastDeclinedAt); err != nil {
+			return nil, fmt.Errorf("scan refusal: %w", err)
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,55 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// MessageReplay describes an incoming Telegram message that was already processed once.
+type MessageReplay struct {
+	RequestID string  // request that first processed the message
+	Reply     *string // bot reply stored for that request; nil if none (still running, silent, or failed)
+	MediaType *string // media type of that reply, if any
+	Delivered bool    // the frontend acked sending the reply (its Telegram message_id is known)
+}
+
+// FindMessageReplay reports whether chatID/messageID was already stored as an incoming
+// (non-throttled) message and, if so, returns the bot reply produced for it. Returns nil if unseen.
+func (d *DB) FindMessageReplay(ctx context.Context, chatID, messageID int64) (*MessageReplay, error) {
+	const query = `
+		SELECT COALESCE(m.request_id, ''), r.text, r.media_type, COALESCE(r.delivered, FALSE)
+		FROM messages m
+		LEFT JOIN LATERAL (
+			SELECT b.text, b.media_type, b.message_id IS NOT NULL AS delivered
+			FROM messages b
+			WHERE b.chat_id = m.chat_id AND b.is_bot_reply AND b.request_id = m.request_id
+			ORDER BY b.id DESC
+			LIMIT 1
+		) r ON TRUE
+		WHERE m.chat_id = $1 AND m.message_id = $2
+		  AND NOT COALESCE(m.is_bot_reply, FALSE) AND NOT COALESCE(m.was_throttled, FALSE)
+		ORDER BY m.id ASC
+		LIMIT 1`
+
+	rep, err := d.scanMessageReplay(ctx, d.pool.QueryRowContext(ctx, query, chatID, messageID))
+	if err != nil {
+		return nil, fmt.Errorf("find message replay: %w", err)
+	}
+	return rep, nil
+}
+
+// scanMessageReplay reads one FindMessageReplay row, decrypting the stored reply so a replay
+// resends what the user saw rather than the sealed text.
+func (d *DB) scanMessageReplay(ctx context.Context, row interface{ Scan(...any) error }) (*MessageReplay, error) {
+	var rep MessageReplay
+	err := row.Scan(&rep.RequestID, &rep.Reply, &rep.MediaType, &rep.Delivered)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.open(ctx, rep.Reply)
+	return &rep, nil
+}
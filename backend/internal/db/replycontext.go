@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ReplyContext is what a reply needs from Postgres about the chat and the sender.
+type ReplyContext struct {
+	Messages     []Message  // recent messages of the topic, oldest first
+	Facts        []UserFact // the sender's most important facts
+	FactsTotal   int        // facts stored in total (more than len(Facts) when some were left out)
+	Summary30Day string
+	Summary7Day  string
+}
+
+// replyFactsQuery is GetTopUserFacts with the global-memory opt-in looked up in SQL, so it
+// needs no earlier round trip. Takes chat_id, user_id, limit, GlobalFactsChatID.
+const replyFactsQuery = `
+	SELECT id, chat_id, user_id, fact_text, category, importance, created_at, updated_at, COUNT(*) OVER ()
+	FROM user_facts
+	WHERE user_id = $2
+	  AND (chat_id = $1 OR (chat_id = $4 AND EXISTS (
+		SELECT 1 FROM user_settings WHERE user_id = $2 AND global_memory)))
+	ORDER BY importance DESC, updated_at DESC
+	LIMIT $3`
+
+// LoadReplyContext fetches the recent messages of a topic, the sender's top facts (global ones
+// too when they opted in) and the latest 30- and 7-day summaries in a single round trip.
+// Summaries are best effort: a failed summary query leaves it empty.
+// With a context cache set (SetContextCache), only the parts missing from it are queried.
+func (d *DB) LoadReplyContext(ctx context.Context, chatID, threadID, userID int64, messageLimit, factLimit int) (*ReplyContext, error) {
+	rc := &ReplyContext{}
+	var sums replySummaries
+	var facts replyFacts
+	var haveMsgs, haveSums, haveFacts bool
+	var mc *messageCache
+	var sumKey, factKey string
+	if d.ctxCache != nil {
+		vers, err := d.ctxCache.GetCounts(ctx,
+			fmt.Sprintf(messagesVersionKey, chatID, threadID), fmt.Sprintf(summaryVersionKey, chatID, threadID),
+			fmt.Sprintf(userVersionKey, userID), factsVersionKey)
+		if err != nil {
+			slog.WarnContext(ctx, "context cache: read versions failed", "chat_id", chatID, "error", err)
+		} else {
+			if d.messagesCacheable(threadID, messageLimit) {
+				var c messageCache
+				rc.Messages, haveMsgs, c = d.cachedMessages(ctx, chatID, threadID, messageLimit, vers[0])
+				mc = &c
+			}
+			sumKey = summariesKey(chatID, threadID, vers[1])
+			factKey = factsKey(chatID, userID, factLimit, vers[2], vers[3])
+			haveSums = d.getCached(ctx, sumKey, &sums)
+			haveFacts = d.getCached(ctx, factKey, &facts)
+		}
+	}
+
+	if !haveMsgs || !haveSums || !haveFacts {
+		q := replyQuery{chatID: chatID, threadID: threadID, userID: userID, messageLimit: messageLimit, factLimit: factLimit}
+		if !haveMsgs {
+			q.messages = &rc.Messages
+			if mc != nil {
+				q.messageLimit = d.ctxCacheMessages
+			}
+		}
+		if !haveSums {
+			q.summaries = &sums
+		}
+		if !haveFacts {
+			q.facts = &facts
+		}
+		if err := d.loadReplyContext(ctx, q); err != nil {
+			return nil, err
+		}
+		if mc != nil && !haveMsgs {
+			d.fillMessages(ctx, *mc, rc.Messages)
+			if n := len(rc.Messages); n > messageLimit {
+				rc.Messages = rc.Messages[n-messageLimit:]
+			}
+		}
+		if sumKey != "" && !haveSums {
+			d.setCached(ctx, sumKey, &sums)
+		}
+		if factKey != "" && !haveFacts {
+			d.setCached(ctx, factKey, &facts)
+		}
+	}
+
+	// Cached as stored; decrypted only here, for the reply
+	d.openMessages(ctx, rc.Messages)
+	d.openFacts(ctx, facts.Facts)
+	rc.Facts, rc.FactsTotal = facts.Facts, facts.FactsTotal
+	rc.Summary30Day, rc.Summary7Day = sums.Summary30Day, sums.Summary7Day
+	return rc, nil
+}
+
+// replyQuery selects the parts of a reply context to load; nil destinations are skipped.
+type replyQuery struct {
+	chatID, threadID, userID int64
+	messageLimit, factLimit  int
+
+	messages  *[]Message
+	summaries *replySummaries
+	facts     *replyFacts
+}
+
+// loadReplyContext batches the queries of q into one round trip.
+func (d *DB) loadReplyContext(ctx context.Context, q replyQuery) error {
+	batch := &pgx.Batch{}
+	if q.messages != nil {
+		batch.Queue(recentMessagesQuery, q.chatID, q.messageLimit, q.threadID)
+	}
+	if q.facts != nil {
+		batch.Queue(replyFactsQuery, q.chatID, q.userID, q.factLimit, GlobalFactsChatID)
+	}
+	if q.summaries != nil {
+		batch.Queue(latestSummaryQuery, q.chatID, "30day", q.threadID)
+		batch.Queue(latestSummaryQuery, q.chatID, "7day", q.threadID)
+	}
+
+	results := d.pgx.SendBatch(ctx, batch)
+	defer results.Close()
+
+	if q.messages != nil {
+		rows, err := results.Query()
+		if err != nil {
+			return fmt.Errorf("get recent messages: %w", err)
+		}
+		*q.messages, err = scanRecentMessages(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if q.facts != nil {
+		rows, err := results.Query()
+		if err != nil {
+			return fmt.Errorf("get top user facts: %w", err)
+		}
+		q.facts.Facts, q.facts.FactsTotal, err = scanTopUserFacts(rows)
+		rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	if q.summaries != nil {
+		for _, s := range []*string{&q.summaries.Summary30Day, &q.summaries.Summary7Day} {
+			if err := results.QueryRow().Scan(s); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				slog.WarnContext(ctx, "get latest summary failed", "chat_id", q.chatID, "error", err)
+			}
+		}
+	}
+	return nil
+}
@@ -2,29 +2,209 @@ package db
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os"
 )
 
-// PruneOldMessages deletes messages older than retentionDays.
-// Called on startup to enforce the configured retention policy.
-func (d *DB) PruneOldMessages(ctx context.Context, retentionDays int) (int64, error) {
+// retentionBatchSize bounds one DELETE, so a large backlog is pruned without holding long locks.
+const retentionBatchSize = 5000
+
+// PruneOldMessages deletes messages past their chat's retention: chat_settings.message_retention_days
+// where a chat set one, defaultDays otherwise. 0 days (either way) keeps messages forever.
+func (d *DB) PruneOldMessages(ctx context.Context, defaultDays int) (int64, error) {
+	const query = `
+		DELETE FROM messages WHERE id IN (
+			SELECT m.id FROM messages m
+			LEFT JOIN chat_settings cs ON cs.chat_id = m.chat_id
+			WHERE COALESCE(cs.message_retention_days, $1) > 0
+			  AND m.created_at < NOW() - INTERVAL '1 day' * COALESCE(cs.message_retention_days, $1)
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, defaultDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old messages: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old messages", "deleted", total, "default_retention_days", defaultDays)
+	}
+	return total, nil
+}
+
+// PruneOldSummaries deletes chat summaries created more than retentionDays ago (0 = keep all).
+// The latest summary of each kind per topic is kept however old, since replies still use it.
+func (d *DB) PruneOldSummaries(ctx context.Context, retentionDays int) (int64, error) {
 	if retentionDays <= 0 {
-		slog.Info("message retention disabled (0 days = keep forever)")
 		return 0, nil
 	}
+	const query = `
+		DELETE FROM chat_summaries WHERE id IN (
+			SELECT s.id FROM chat_summaries s
+			WHERE s.created_at < NOW() - INTERVAL '1 day' * $1
+			  AND EXISTS (
+				SELECT 1 FROM chat_summaries n
+				WHERE n.chat_id = s.chat_id AND n.thread_id = s.thread_id
+				  AND n.summary_type = s.summary_type AND n.period_end > s.period_end)
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, retentionDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old summaries: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old summaries", "deleted", total, "retention_days", retentionDays)
+	}
+	return total, nil
+}
+
+// PruneExpiredMediaCache deletes media_cache rows past expires_at and their files.
+func (d *DB) PruneExpiredMediaCache(ctx context.Context) (int64, error) {
+	rows, err := d.pool.QueryContext(ctx, "DELETE FROM media_cache WHERE expires_at < NOW() RETURNING file_path")
+	if err != nil {
+		return 0, fmt.Errorf("prune media cache: %w", err)
+	}
+	defer rows.Close()
+
+	var n int64
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return n, fmt.Errorf("prune media cache: %w", err)
+		}
+		n++
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			slog.WarnContext(ctx, "remove cached media file failed", "path", path, "error", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("prune media cache: %w", err)
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "pruned expired media cache", "deleted", n)
+	}
+	return n, nil
+}
 
-	result, err := d.pool.ExecContext(ctx,
-		"DELETE FROM messages WHERE created_at < NOW() - INTERVAL '1 day' * $1",
-		retentionDays,
-	)
+// deleteInBatches runs a DELETE taking (days, batch size) until it removes fewer rows than a batch.
+func (d *DB) deleteInBatches(ctx context.Context, query string, days, batch int) (int64, error) {
+	var total int64
+	for {
+		res, err := d.pool.ExecContext(ctx, query, days, batch)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if n < int64(batch) || ctx.Err() != nil {
+			return total, ctx.Err()
+		}
+	}
+}
+
+// MessageArchiver keeps a copy of messages before retention deletes them.
+type MessageArchiver interface {
+	ArchiveMessages(ctx context.Context, msgs []Message) error
+}
+
+// expiringMessagesQuery selects the messages PruneOldMessages would delete, with every column.
+// Takes default days and a limit.
+const expiringMessagesQuery = `
+	SELECT m.id, m.chat_id, m.thread_id, m.user_id, m.username, m.first_name, m.text, m.message_id,
+		m.media_type, m.file_id, m.is_bot_reply, m.request_id, m.was_throttled, m.reply_to_message_id,
+		m.sticker_emoji, m.sticker_set, m.transcript, m.created_at
+	FROM messages m
+	LEFT JOIN chat_settings cs ON cs.chat_id = m.chat_id
+	WHERE COALESCE(cs.message_retention_days, $1) > 0
+	  AND m.created_at < NOW() - INTERVAL '1 day' * COALESCE(cs.message_retention_days, $1)
+	ORDER BY m.chat_id, m.id
+	LIMIT $2`
+
+// ArchiveOldMessages is PruneOldMessages with a copy: each batch of expiring messages is handed to
+// archiver and deleted only once it was stored. Stops at the first archiving error.
+func (d *DB) ArchiveOldMessages(ctx context.Context, defaultDays int, archiver MessageArchiver) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		msgs, err := d.expiringMessages(ctx, defaultDays, retentionBatchSize)
+		if err != nil {
+			return total, err
+		}
+		if len(msgs) == 0 {
+			break
+		}
+		if err := archiver.ArchiveMessages(ctx, msgs); err != nil {
+			return total, fmt.Errorf("archive messages: %w", err)
+		}
+		ids := make([]int64, len(msgs))
+		for i, m := range msgs {
+			ids[i] = m.ID
+		}
+		res, err := d.pool.ExecContext(ctx, "DELETE FROM messages WHERE id = ANY($1)", ids)
+		if err != nil {
+			return total, fmt.Errorf("delete archived messages: %w", err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+		if len(msgs) < retentionBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "archived and pruned old messages", "deleted", total, "default_retention_days", defaultDays)
+	}
+	return total, ctx.Err()
+}
+
+func (d *DB) expiringMessages(ctx context.Context, defaultDays, limit int) ([]Message, error) {
+	rows, err := d.pool.QueryContext(ctx, expiringMessagesQuery, defaultDays, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get expiring messages: %w", err)
+	}
+	defer rows.Close()
+	var msgs []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName, &m.Text, &m.MessageID,
+			&m.MediaType, &m.FileID, &m.IsBotReply, &m.RequestID, &m.WasThrottled, &m.ReplyToMessageID,
+			&m.StickerEmoji, &m.StickerSet, &m.Transcript, &m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan expiring message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// RestoreMessages puts archived messages back under their original IDs, encrypting text and
+// transcripts that were archived as plaintext when a cipher is set. Messages still present are skipped. Returns how many were inserted.
+func (d *DB) RestoreMessages(ctx context.Context, msgs []Message) (int64, error) {
+	tx, err := d.pool.BeginTx(ctx, nil)
 	if err != nil {
-		return 0, fmt.Errorf("prune old messages: %w", err)
+		return 0, fmt.Errorf("restore messages: %w", err)
 	}
+	defer tx.Rollback()
 
-	count, _ := result.RowsAffected()
-	if count > 0 {
-		slog.Info("pruned old messages", "deleted", count, "retention_days", retentionDays)
+	const query = `
+		INSERT INTO messages (id, chat_id, thread_id, user_id, username, first_name, text, message_id,
+			media_type, file_id, is_bot_reply, request_id, was_throttled, reply_to_message_id,
+			sticker_emoji, sticker_set, transcript, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		ON CONFLICT (id) DO NOTHING`
+	var total int64
+	for _, m := range msgs {
+		res, err := tx.ExecContext(ctx, query,
+			m.ID, m.ChatID, m.ThreadID, m.UserID, m.Username, m.FirstName, d.sealArchived(m.Text), m.MessageID,
+			m.MediaType, m.FileID, m.IsBotReply, m.RequestID, m.WasThrottled, m.ReplyToMessageID,
+			m.StickerEmoji, m.StickerSet, d.sealArchived(m.Transcript), m.CreatedAt)
+		if err != nil {
+			return 0, fmt.Errorf("restore message %d: %w", m.ID, err)
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("restore messages: %w", err)
 	}
-	return count, nil
+	return total, nil
 }
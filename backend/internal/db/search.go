@@ -5,8 +5,25 @@ import (
 	"fmt"
 	"log/slog"
 	"strings"
+	"time"
 )
 
+// AnyMedia as SearchFilter.MediaType matches every message with media.
+const AnyMedia = "any"
+
+// SearchFilter narrows SearchMessages. Zero fields don't filter.
+type SearchFilter struct {
+	FromUser  string    // sender: @username, first name or user ID
+	After     time.Time // sent at or after
+	Before    time.Time // sent before
+	MediaType string    // photo, video, voice, ... or AnyMedia
+}
+
+// IsZero reports whether f filters nothing.
+func (f SearchFilter) IsZero() bool {
+	return f.FromUser == "" && f.After.IsZero() && f.Before.IsZero() && f.MediaType == ""
+}
+
 // SearchResult holds a message match from full-text search.
 type SearchResult struct {
 	ID        int64
@@ -15,17 +32,22 @@ type SearchResult struct {
 	Username  *string
 	FirstName *string
 	Text      *string
+	Transcript *string // of a voice note or audio file (summarize_audio)
 	FileID    *string
 	MessageID *int64
 	MediaType *string
 	IsBotReply bool
+	ThreadID  int64
 	Rank      float64
+	CreatedAt time.Time
 	MessageLink string // Composed Telegram deep link
 }
 
-// SearchMessages performs full-text search on the messages table for a given chat.
-// Returns results ranked by relevance with Telegram deep links composed.
-func (d *DB) SearchMessages(ctx context.Context, chatID int64, query string, limit int) ([]SearchResult, error) {
+// SearchMessages performs full-text search on the messages table for a given chat's forum topic
+// (AllThreads searches every topic), narrowed by filter. Returns results ranked by relevance with
+// Telegram deep links composed; an empty query with a filter returns the newest matches.
+// Messages in off-the-record windows are never returned.
+func (d *DB) SearchMessages(ctx context.Context, chatID, threadID int64, query string, filter SearchFilter, limit int) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 10
 	}
@@ -35,7 +57,7 @@ func (d *DB) SearchMessages(ctx context.Context, chatID int64, query string, lim
 
 	// Build the tsquery — split on spaces, join with & for AND matching
 	words := strings.Fields(query)
-	if len(words) == 0 {
+	if len(words) == 0 && filter.IsZero() {
 		return nil, nil
 	}
 
@@ -47,14 +69,21 @@ func (d *DB) SearchMessages(ctx context.Context, chatID int64, query string, lim
 	tsQuery := strings.Join(tsTerms, " & ")
 
 	const sqlQuery = `
-		SELECT id, chat_id, user_id, username, first_name, text, file_id, message_id, media_type, is_bot_reply,
-		       ts_rank(search_vector, to_tsquery('simple', $1)) AS rank
+		SELECT id, chat_id, user_id, username, first_name, text, transcript, file_id, message_id, media_type, is_bot_reply, thread_id, created_at,
+		       CASE WHEN $1 = '' THEN 0 ELSE ts_rank(search_vector, to_tsquery('simple', $1)) END AS rank
 		FROM messages
-		WHERE chat_id = $2 AND search_vector @@ to_tsquery('simple', $1)
+		WHERE chat_id = $2 AND ($4 < 0 OR thread_id = $4)
+		  AND ($1 = '' OR search_vector @@ to_tsquery('simple', $1))
+		  AND ($5 = '' OR user_id::text = $5 OR lower(username) = lower($5) OR lower(first_name) = lower($5))
+		  AND ($6::timestamptz IS NULL OR created_at >= $6)
+		  AND ($7::timestamptz IS NULL OR created_at < $7)
+		  AND ($8 = '' OR media_type = $8 OR ($8 = 'any' AND media_type IS NOT NULL))
+		  AND NOT is_off_record(chat_id, created_at)
 		ORDER BY rank DESC, created_at DESC
 		LIMIT $3`
 
-	rows, err := d.pool.QueryContext(ctx, sqlQuery, tsQuery, chatID, limit)
+	rows, err := d.pool.QueryContext(ctx, sqlQuery, tsQuery, chatID, limit, threadID,
+		strings.TrimPrefix(filter.FromUser, "@"), nullTime(filter.After), nullTime(filter.Before), filter.MediaType)
 	if err != nil {
 		return nil, fmt.Errorf("search messages: %w", err)
 	}
@@ -65,15 +94,21 @@ func (d *DB) SearchMessages(ctx context.Context, chatID int64, query string, lim
 		var r SearchResult
 		if err := rows.Scan(
 			&r.ID, &r.ChatID, &r.UserID, &r.Username, &r.FirstName,
-			&r.Text, &r.FileID, &r.MessageID, &r.MediaType, &r.IsBotReply, &r.Rank,
+			&r.Text, &r.Transcript, &r.FileID, &r.MessageID, &r.MediaType, &r.IsBotReply, &r.ThreadID, &r.CreatedAt, &r.Rank,
 		); err != nil {
 			return nil, fmt.Errorf("scan search result: %w", err)
 		}
-		r.MessageLink = ComposeMessageLink(r.ChatID, r.MessageID)
+		d.open(ctx, r.Text)
+		d.open(ctx, r.Transcript)
+		r.MessageLink = ComposeTopicMessageLink(r.ChatID, r.ThreadID, r.MessageID)
 		results = append(results, r)
 	}
 
-	slog.Info("message search", "chat_id", chatID, "query", query, "results", len(results))
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("search messages: %w", err)
+	}
+
+	slog.Info("message search", "chat_id", chatID, "thread_id", threadID, "query", query, "filter", filter, "results", len(results))
 	return results, nil
 }
 
@@ -100,3 +135,59 @@ func ComposeMessageLink(chatID int64, messageID *int64) string {
 	// For private chats (positive), links aren't supported
 	return ""
 }
+
+// ComposeTopicMessageLink is ComposeMessageLink for a message in a forum topic:
+//
+//	https://t.me/c/{chat_id_without_-100_prefix}/{thread_id}/{message_id}
+//
+// threadID 0 gives the plain message link.
+func ComposeTopicMessageLink(chatID, threadID int64, messageID *int64) string {
+	link := ComposeMessageLink(chatID, messageID)
+	if link == "" || threadID <= 0 {
+		return link
+	}
+	innerID := chatID*-1 - 1000000000000
+	return fmt.Sprintf("https://t.me/c/%d/%d/%d", innerID, threadID, *messageID)
+}
+
+// GetMessageContext returns the message with ID id in chatID together with up to before earlier
+// and after later messages of the same forum topic, oldest first. Returns nil when the message
+// doesn't exist in the chat or is in an off-the-record window; off-the-record neighbours are skipped.
+func (d *DB) GetMessageContext(ctx context.Context, chatID, id int64, before, after int) ([]Message, error) {
+	const query = `
+		WITH anchor AS (
+			SELECT id, chat_id, thread_id FROM messages
+			WHERE id = $1 AND chat_id = $2 AND NOT is_off_record(chat_id, created_at)
+		)
+		SELECT * FROM (
+			(SELECT m.id, m.chat_id, m.thread_id, m.user_id, m.username, m.first_name, m.text, m.message_id, m.media_type, m.file_id, m.is_bot_reply, m.reply_to_message_id, m.sticker_emoji, m.created_at
+			 FROM messages m JOIN anchor a ON m.chat_id = a.chat_id AND m.thread_id = a.thread_id AND m.id < a.id
+			 WHERE NOT is_off_record(m.chat_id, m.created_at)
+			 ORDER BY m.id DESC LIMIT $3)
+			UNION ALL
+			(SELECT m.id, m.chat_id, m.thread_id, m.user_id, m.username, m.first_name, m.text, m.message_id, m.media_type, m.file_id, m.is_bot_reply, m.reply_to_message_id, m.sticker_emoji, m.created_at
+			 FROM messages m JOIN anchor a ON m.chat_id = a.chat_id AND m.thread_id = a.thread_id AND m.id >= a.id
+			 WHERE m.id = a.id OR NOT is_off_record(m.chat_id, m.created_at)
+			 ORDER BY m.id ASC LIMIT $4 + 1)
+		) c
+		ORDER BY id`
+	rows, err := d.pool.QueryContext(ctx, query, id, chatID, before, after)
+	if err != nil {
+		return nil, fmt.Errorf("get message context: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName, &m.Text, &m.MessageID,
+			&m.MediaType, &m.FileID, &m.IsBotReply, &m.ReplyToMessageID, &m.StickerEmoji, &m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan message context: %w", err)
+		}
+		d.open(ctx, m.Text)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
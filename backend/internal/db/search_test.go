@@ -48,3 +48,16 @@ func TestComposeMessageLink_NilMessageID(t *testing.T) {
 		t.Errorf("expected empty link for nil message_id, got %q", link)
 	}
 }
+
+func TestComposeTopicMessageLink(t *testing.T) {
+	msgID := int64(42)
+	if link := ComposeTopicMessageLink(-1001234567890, 7, &msgID); link != "https://t.me/c/1234567890/7/42" {
+		t.Errorf("unexpected topic link: %s", link)
+	}
+	if link := ComposeTopicMessageLink(-1001234567890, 0, &msgID); link != "https://t.me/c/1234567890/42" {
+		t.Errorf("thread 0 should give the plain link, got %s", link)
+	}
+	if link := ComposeTopicMessageLink(392817811, 7, &msgID); link != "" {
+		t.Errorf("private chat should have no link, got %s", link)
+	}
+}
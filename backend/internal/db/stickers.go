@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StickerUsage is one entry of a chat's sticker frequency profile.
+type StickerUsage struct {
+	SetName    string
+	Emoji      string
+	Uses       int
+	LastUsedAt time.Time
+}
+
+// RecordStickerUsage increments the usage counter for a sticker (set + emoji) in a chat.
+func (d *DB) RecordStickerUsage(ctx context.Context, chatID int64, setName, emoji string) error {
+	const query = `
+		INSERT INTO sticker_usage (chat_id, set_name, emoji, uses)
+		VALUES ($1, $2, $3, 1)
+		ON CONFLICT (chat_id, set_name, emoji) DO UPDATE SET
+			uses = sticker_usage.uses + 1,
+			last_used_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, chatID, setName, emoji); err != nil {
+		return fmt.Errorf("record sticker usage: %w", err)
+	}
+	return nil
+}
+
+// GetStickerProfile returns the chat's most used stickers, most frequent first.
+func (d *DB) GetStickerProfile(ctx context.Context, chatID int64, limit int) ([]StickerUsage, error) {
+	const query = `
+		SELECT set_name, emoji, uses, last_used_at
+		FROM sticker_usage
+		WHERE chat_id = $1
+		ORDER BY uses DESC, last_used_at DESC
+		LIMIT $2`
+	rows, err := d.pool.QueryContext(ctx, query, chatID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get sticker profile: %w", err)
+	}
+	defer rows.Close()
+
+	var out []StickerUsage
+	for rows.Next() {
+		var s StickerUsage
+		if err := rows.Scan(&s.SetName, &s.Emoji, &s.Uses, &s.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("scan sticker usage: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
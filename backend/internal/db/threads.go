@@ -0,0 +1,74 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+)
+
+// threadQuery walks a reply chain: up from the anchor ($2 = Telegram message_id or $3 = row id)
+// to its root, then down from the root through every reply. Bot replies, which are stored
+// without reply_to_message_id, hang off the user message with the same request_id. $4 bounds
+// the depth both ways; $5 the rows, ancestors first.
+const threadQuery = `
+	WITH RECURSIVE anchor AS (
+		SELECT id, chat_id, message_id, reply_to_message_id, request_id, is_bot_reply FROM messages
+		WHERE chat_id = $1 AND (($2::bigint > 0 AND message_id = $2::bigint) OR ($3::bigint > 0 AND id = $3::bigint))
+		  AND NOT is_off_record(chat_id, created_at)
+		ORDER BY id LIMIT 1
+	), up AS (
+		SELECT a.*, 0 AS depth FROM anchor a
+		UNION
+		SELECT p.id, p.chat_id, p.message_id, p.reply_to_message_id, p.request_id, p.is_bot_reply, up.depth + 1
+		FROM up JOIN messages p ON p.chat_id = up.chat_id AND (
+			p.message_id = up.reply_to_message_id OR
+			(up.reply_to_message_id IS NULL AND up.is_bot_reply AND NOT p.is_bot_reply AND p.request_id = up.request_id))
+		WHERE up.depth < $4
+	), down AS (
+		SELECT r.id, r.chat_id, r.message_id, r.request_id, r.is_bot_reply, 0 AS depth
+		FROM (SELECT * FROM up ORDER BY depth DESC LIMIT 1) r
+		UNION
+		SELECT c.id, c.chat_id, c.message_id, c.request_id, c.is_bot_reply, down.depth + 1
+		FROM down JOIN messages c ON c.chat_id = down.chat_id AND (
+			c.reply_to_message_id = down.message_id OR
+			(NOT down.is_bot_reply AND c.is_bot_reply AND c.reply_to_message_id IS NULL AND c.request_id = down.request_id))
+		WHERE down.depth < $4
+	)
+	SELECT m.id, m.chat_id, m.thread_id, m.user_id, m.username, m.first_name, m.text, m.message_id, m.media_type, m.file_id, m.is_bot_reply, m.reply_to_message_id, m.sticker_emoji, m.created_at
+	FROM messages m
+	WHERE m.id IN (SELECT id FROM up UNION SELECT id FROM down)
+	  AND (m.id = (SELECT id FROM anchor) OR NOT is_off_record(m.chat_id, m.created_at))
+	ORDER BY m.id IN (SELECT id FROM up) DESC, m.id
+	LIMIT $5`
+
+// GetThread returns the reply thread a message belongs to in chatID, oldest first: its chain of
+// ancestors up to the root and every reply below the root, at most maxDepth levels each way and
+// limit messages (ancestors are kept first). The message is found by Telegram messageID or, when
+// that is 0, by row id. Returns nil when it doesn't exist or is off the record; off-the-record
+// messages in the thread are skipped.
+func (d *DB) GetThread(ctx context.Context, chatID, messageID, id int64, maxDepth, limit int) ([]Message, error) {
+	rows, err := d.pool.QueryContext(ctx, threadQuery, chatID, messageID, id, maxDepth, limit)
+	if err != nil {
+		return nil, fmt.Errorf("get thread: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName, &m.Text, &m.MessageID,
+			&m.MediaType, &m.FileID, &m.IsBotReply, &m.ReplyToMessageID, &m.StickerEmoji, &m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan thread: %w", err)
+		}
+		d.open(ctx, m.Text)
+		messages = append(messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("get thread: %w", err)
+	}
+	slices.SortFunc(messages, func(a, b Message) int { return cmp.Compare(a.ID, b.ID) })
+	return messages, nil
+}
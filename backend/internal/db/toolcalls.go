@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// ToolCall is one tool invocation as stored in tool_calls.
+type ToolCall struct {
+	RequestID  *string
+	ChatID     *int64
+	UserID     *int64
+	Tool       string
+	ArgsHash   string
+	DurationMS int
+	Output     string // truncated by the caller
+	Error      *string
+}
+
+// RecordToolCall stores one tool invocation.
+func (d *DB) RecordToolCall(ctx context.Context, c *ToolCall) error {
+	const query = `
+		INSERT INTO tool_calls (request_id, chat_id, user_id, tool, args_hash, duration_ms, output, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := d.pool.ExecContext(ctx, query, c.RequestID, c.ChatID, c.UserID, c.Tool, c.ArgsHash,
+		c.DurationMS, c.Output, c.Error); err != nil {
+		return fmt.Errorf("record tool call: %w", err)
+	}
+	return nil
+}
+
+// ToolStats aggregates the calls of one tool in a period.
+type ToolStats struct {
+	Tool          string  `json:"tool"`
+	Calls         int     `json:"calls"`
+	Errors        int     `json:"errors"`
+	Chats         int     `json:"chats"`
+	Users         int     `json:"users"`
+	DistinctArgs  int     `json:"distinct_args"`
+	AvgDurationMS float64 `json:"avg_duration_ms"`
+	P95DurationMS float64 `json:"p95_duration_ms"`
+}
+
+// ChatToolStats aggregates the tool calls made in one chat in a period.
+type ChatToolStats struct {
+	ChatID        int64   `json:"chat_id"`
+	Calls         int     `json:"calls"`
+	Errors        int     `json:"errors"`
+	Tools         int     `json:"tools"` // distinct tools used
+	TopTool       string  `json:"top_tool"`
+	AvgDurationMS float64 `json:"avg_duration_ms"`
+}
+
+// ToolCallReport is the admin view of tool_calls for a period.
+type ToolCallReport struct {
+	Since time.Time       `json:"since"`
+	Until time.Time       `json:"until"`
+	Tools []ToolStats     `json:"tools"`
+	Chats []ChatToolStats `json:"chats"`
+}
+
+// ToolCallStats returns per-tool and per-chat (top topChats by calls) statistics for calls in
+// [since, until). chatID and tool narrow both lists when set (0 / "").
+func (d *DB) ToolCallStats(ctx context.Context, since, until time.Time, chatID int64, tool string, topChats int) (*ToolCallReport, error) {
+	r := &ToolCallReport{Since: since, Until: until, Tools: []ToolStats{}, Chats: []ChatToolStats{}}
+
+	const byTool = `
+		SELECT tool, COUNT(*), COUNT(error), COUNT(DISTINCT chat_id), COUNT(DISTINCT user_id), COUNT(DISTINCT args_hash),
+			AVG(duration_ms), percentile_cont(0.95) WITHIN GROUP (ORDER BY duration_ms)
+		FROM tool_calls
+		WHERE created_at >= $1 AND created_at < $2
+		  AND ($3 = 0 OR chat_id = $3) AND ($4 = '' OR tool = $4)
+		GROUP BY tool
+		ORDER BY 2 DESC, tool`
+	rows, err := d.pool.QueryContext(ctx, byTool, since, until, chatID, tool)
+	if err != nil {
+		return nil, fmt.Errorf("tool call stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var s ToolStats
+		if err := rows.Scan(&s.Tool, &s.Calls, &s.Errors, &s.Chats, &s.Users, &s.DistinctArgs, &s.AvgDurationMS, &s.P95DurationMS); err != nil {
+			return nil, fmt.Errorf("scan tool stats: %w", err)
+		}
+		r.Tools = append(r.Tools, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("tool call stats: %w", err)
+	}
+
+	const byChat = `
+		SELECT chat_id, COUNT(*), COUNT(error), COUNT(DISTINCT tool), MODE() WITHIN GROUP (ORDER BY tool), AVG(duration_ms)
+		FROM tool_calls
+		WHERE created_at >= $1 AND created_at < $2 AND chat_id IS NOT NULL
+		  AND ($3 = 0 OR chat_id = $3) AND ($4 = '' OR tool = $4)
+		GROUP BY chat_id
+		ORDER BY 2 DESC, chat_id
+		LIMIT $5`
+	chatRows, err := d.pool.QueryContext(ctx, byChat, since, until, chatID, tool, topChats)
+	if err != nil {
+		return nil, fmt.Errorf("tool call chat stats: %w", err)
+	}
+	defer chatRows.Close()
+	for chatRows.Next() {
+		var c ChatToolStats
+		if err := chatRows.Scan(&c.ChatID, &c.Calls, &c.Errors, &c.Tools, &c.TopTool, &c.AvgDurationMS); err != nil {
+			return nil, fmt.Errorf("scan chat tool stats: %w", err)
+		}
+		r.Chats = append(r.Chats, c)
+	}
+	if err := chatRows.Err(); err != nil {
+		return nil, fmt.Errorf("tool call chat stats: %w", err)
+	}
+	return r, nil
+}
+
+// PruneOldToolCalls deletes tool_calls rows older than retentionDays (0 = keep all).
+func (d *DB) PruneOldToolCalls(ctx context.Context, retentionDays int) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	const query = `
+		DELETE FROM tool_calls WHERE id IN (
+			SELECT id FROM tool_calls
+			WHERE created_at < NOW() - INTERVAL '1 day' * $1
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, retentionDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old tool calls: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old tool calls", "deleted", total, "retention_days", retentionDays)
+	}
+	return total, nil
+}
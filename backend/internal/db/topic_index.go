@@ -0,0 +1,192 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// TopicSegment is a stretch of a chat topic's history about one subject, as stored in
+// chat_topics_index by the topic indexing job.
+type TopicSegment struct {
+	ID             int64
+	ChatID         int64
+	ThreadID       int64
+	Title          string
+	Summary        string
+	Keywords       []string // dictionary forms, for search
+	FirstMessageID int64    // messages.id of the first and last message covered
+	LastMessageID  int64
+	MessageCount   int
+	StartedAt      time.Time
+	EndedAt        time.Time
+	Rank           float64 // search relevance (SearchTopicSegments only)
+}
+
+// TopicIndexCandidates returns the chat topics with at least minNew messages since their last
+// indexed one, written after since, busiest first. Off-the-record messages don't count.
+func (d *DB) TopicIndexCandidates(ctx context.Context, minNew int, since time.Time) ([]ChatThread, error) {
+	const query = `
+		SELECT m.chat_id, m.thread_id
+		FROM messages m
+		LEFT JOIN (
+			SELECT chat_id, thread_id, MAX(last_message_id) AS last_id
+			FROM chat_topics_index
+			GROUP BY chat_id, thread_id
+		) t ON t.chat_id = m.chat_id AND t.thread_id = m.thread_id
+		WHERE m.created_at > $2
+		  AND m.id > COALESCE(t.last_id, 0)
+		  AND NOT is_off_record(m.chat_id, m.created_at)
+		GROUP BY m.chat_id, m.thread_id
+		HAVING COUNT(*) >= $1
+		ORDER BY COUNT(*) DESC`
+	rows, err := d.pool.QueryContext(ctx, query, minNew, since)
+	if err != nil {
+		return nil, fmt.Errorf("topic index candidates: %w", err)
+	}
+	defer rows.Close()
+	var threads []ChatThread
+	for rows.Next() {
+		var t ChatThread
+		if err := rows.Scan(&t.ChatID, &t.ThreadID); err != nil {
+			return nil, fmt.Errorf("scan chat thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+	return threads, rows.Err()
+}
+
+// UnindexedMessages returns up to limit of a chat topic's messages after its last indexed one
+// (and written after since), oldest first. Off-the-record messages are skipped.
+func (d *DB) UnindexedMessages(ctx context.Context, t ChatThread, since time.Time, limit int) ([]Message, error) {
+	const query = `
+		SELECT id, chat_id, thread_id, user_id, username, first_name, text, message_id, media_type, is_bot_reply, request_id, was_throttled, reply_to_message_id, sticker_emoji, sticker_set, created_at
+		FROM messages
+		WHERE chat_id = $1 AND thread_id = $2 AND created_at > $3
+		  AND id > COALESCE((SELECT MAX(last_message_id) FROM chat_topics_index WHERE chat_id = $1 AND thread_id = $2), 0)
+		  AND NOT is_off_record(chat_id, created_at)
+		ORDER BY id ASC
+		LIMIT $4`
+	rows, err := d.pool.QueryContext(ctx, query, t.ChatID, t.ThreadID, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("unindexed messages: %w", err)
+	}
+	defer rows.Close()
+	var messages []Message
+	for rows.Next() {
+		var m Message
+		if err := rows.Scan(
+			&m.ID, &m.ChatID, &m.ThreadID, &m.UserID, &m.Username, &m.FirstName,
+			&m.Text, &m.MessageID, &m.MediaType, &m.IsBotReply,
+			&m.RequestID, &m.WasThrottled, &m.ReplyToMessageID, &m.StickerEmoji, &m.StickerSet, &m.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		d.open(ctx, m.Text)
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// InsertTopicSegments stores one indexing pass's segments together, so the index never ends
+// between two segments of a pass.
+func (d *DB) InsertTopicSegments(ctx context.Context, segments []TopicSegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+	const query = `
+		INSERT INTO chat_topics_index (chat_id, thread_id, title, summary, keywords, first_message_id, last_message_id, message_count, started_at, ended_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+	for _, s := range segments {
+		if _, err := tx.ExecContext(ctx, query, s.ChatID, s.ThreadID, s.Title, s.Summary, strings.Join(s.Keywords, ", "),
+			s.FirstMessageID, s.LastMessageID, s.MessageCount, s.StartedAt, s.EndedAt); err != nil {
+			return fmt.Errorf("insert topic segment: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// SearchTopicSegments finds a chat topic's segments (AllThreads: every topic) matching any word of
+// query by prefix, most relevant first and then most recent.
+func (d *DB) SearchTopicSegments(ctx context.Context, chatID, threadID int64, query string, limit int) ([]TopicSegment, error) {
+	tsQuery := topicTSQuery(query)
+	if tsQuery == "" {
+		return nil, nil
+	}
+	const sqlQuery = `
+		SELECT id, chat_id, thread_id, title, summary, keywords, first_message_id, last_message_id, message_count, started_at, ended_at,
+		       ts_rank(search_vector, to_tsquery('simple', $1)) AS rank
+		FROM chat_topics_index
+		WHERE chat_id = $2 AND ($3 < 0 OR thread_id = $3)
+		  AND search_vector @@ to_tsquery('simple', $1)
+		ORDER BY rank DESC, ended_at DESC
+		LIMIT $4`
+	rows, err := d.pool.QueryContext(ctx, sqlQuery, tsQuery, chatID, threadID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("search topic segments: %w", err)
+	}
+	defer rows.Close()
+	var out []TopicSegment
+	for rows.Next() {
+		var s TopicSegment
+		var keywords string
+		if err := rows.Scan(&s.ID, &s.ChatID, &s.ThreadID, &s.Title, &s.Summary, &keywords, &s.FirstMessageID,
+			&s.LastMessageID, &s.MessageCount, &s.StartedAt, &s.EndedAt, &s.Rank); err != nil {
+			return nil, fmt.Errorf("scan topic segment: %w", err)
+		}
+		s.Keywords = splitKeywords(keywords)
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+// topicTSQuery turns free text into a tsquery matching any of its words by prefix; "" when it has
+// no words. Punctuation is dropped so it can't break the tsquery syntax.
+func topicTSQuery(query string) string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		words[i] = w + ":*"
+	}
+	return strings.Join(words, " | ")
+}
+
+func splitKeywords(s string) []string {
+	var out []string
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// PruneOldTopicSegments deletes segments that ended past their chat's message retention, like
+// PruneOldMessages: the index must not outlive the messages it describes.
+func (d *DB) PruneOldTopicSegments(ctx context.Context, defaultDays int) (int64, error) {
+	const query = `
+		DELETE FROM chat_topics_index WHERE id IN (
+			SELECT t.id FROM chat_topics_index t
+			LEFT JOIN chat_settings cs ON cs.chat_id = t.chat_id
+			WHERE COALESCE(cs.message_retention_days, $1) > 0
+			  AND t.ended_at < NOW() - INTERVAL '1 day' * COALESCE(cs.message_retention_days, $1)
+			LIMIT $2
+		)`
+	total, err := d.deleteInBatches(ctx, query, defaultDays, retentionBatchSize)
+	if err != nil {
+		return total, fmt.Errorf("prune old topic segments: %w", err)
+	}
+	if total > 0 {
+		slog.InfoContext(ctx, "pruned old topic segments", "deleted", total, "default_retention_days", defaultDays)
+	}
+	return total, nil
+}
@@ -0,0 +1,31 @@
+package db
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTopicTSQuery(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{"відпустка", "відпустка:*"},
+		{"Відпустка, Карпати!", "відпустка:* | карпати:*"},
+		{"it's a & b:*", "it:* | s:* | a:* | b:*"},
+		{"  !? ", ""},
+	}
+	for _, tt := range tests {
+		if got := topicTSQuery(tt.query); got != tt.want {
+			t.Errorf("topicTSQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestSplitKeywords(t *testing.T) {
+	if got := splitKeywords("відпустка, карпати,, "); !slices.Equal(got, []string{"відпустка", "карпати"}) {
+		t.Errorf("splitKeywords = %q", got)
+	}
+	if got := splitKeywords(""); got != nil {
+		t.Errorf("expected no keywords, got %q", got)
+	}
+}
@@ -0,0 +1,120 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Chat topic kinds.
+const (
+	TopicEvent    = "event"     // upcoming or ongoing event ("exam on Friday")
+	TopicJoke     = "joke"      // running joke; reused, never done
+	TopicFollowUp = "follow_up" // something to ask about later ("ask how the exam went")
+)
+
+// topicJokeGap is the minimum time between two uses of the same running joke.
+const topicJokeGap = 72 * time.Hour
+
+// ChatTopic is a hint the proactive runner can turn into an explicit prompt.
+type ChatTopic struct {
+	ID         int64      `json:"id"`
+	ChatID     int64      `json:"chat_id"`
+	Kind       string     `json:"kind"`
+	Text       string     `json:"text"`
+	DueAt      *time.Time `json:"due_at,omitempty"`
+	Source     string     `json:"source"`
+	CreatedBy  *int64     `json:"created_by,omitempty"`
+	UsedCount  int        `json:"used_count"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	Done       bool       `json:"done"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ValidTopicKind reports whether kind is one of the chat topic kinds.
+func ValidTopicKind(kind string) bool {
+	return kind == TopicEvent || kind == TopicJoke || kind == TopicFollowUp
+}
+
+const chatTopicColumns = `id, chat_id, kind, text, due_at, source, created_by, used_count, last_used_at, done, created_at`
+
+func scanChatTopic(row interface{ Scan(...any) error }) (ChatTopic, error) {
+	var t ChatTopic
+	err := row.Scan(&t.ID, &t.ChatID, &t.Kind, &t.Text, &t.DueAt, &t.Source, &t.CreatedBy, &t.UsedCount, &t.LastUsedAt, &t.Done, &t.CreatedAt)
+	return t, err
+}
+
+// InsertChatTopic stores a topic and returns its id.
+func (d *DB) InsertChatTopic(ctx context.Context, t *ChatTopic) (int64, error) {
+	const query = `
+		INSERT INTO chat_topics (chat_id, kind, text, due_at, source, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id`
+	var id int64
+	if err := d.pool.QueryRowContext(ctx, query, t.ChatID, t.Kind, t.Text, t.DueAt, t.Source, t.CreatedBy).Scan(&id); err != nil {
+		return 0, fmt.Errorf("insert chat topic: %w", err)
+	}
+	return id, nil
+}
+
+// ListChatTopics returns all of a chat's topics, open ones first, then by due time.
+func (d *DB) ListChatTopics(ctx context.Context, chatID int64) ([]ChatTopic, error) {
+	query := `SELECT ` + chatTopicColumns + ` FROM chat_topics WHERE chat_id = $1
+		ORDER BY done, due_at NULLS LAST, created_at`
+	return d.queryChatTopics(ctx, "list chat topics", query, chatID)
+}
+
+// DueChatTopics returns the chat's open topics that may be brought up at now: past their due
+// time (or without one), and for running jokes not used in the last three days. Dated items come
+// first, earliest due first.
+func (d *DB) DueChatTopics(ctx context.Context, chatID int64, now time.Time) ([]ChatTopic, error) {
+	query := `SELECT ` + chatTopicColumns + ` FROM chat_topics
+		WHERE chat_id = $1 AND NOT done
+			AND (due_at IS NULL OR due_at <= $2)
+			AND (kind <> 'joke' OR last_used_at IS NULL OR last_used_at < $3)
+		ORDER BY due_at NULLS LAST, created_at
+		LIMIT 20`
+	return d.queryChatTopics(ctx, "due chat topics", query, chatID, now, now.Add(-topicJokeGap))
+}
+
+func (d *DB) queryChatTopics(ctx context.Context, op, query string, args ...any) ([]ChatTopic, error) {
+	rows, err := d.pool.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+	topics := []ChatTopic{}
+	for rows.Next() {
+		t, err := scanChatTopic(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%s: scan: %w", op, err)
+		}
+		topics = append(topics, t)
+	}
+	return topics, rows.Err()
+}
+
+// MarkChatTopicUsed records that a topic was brought up; events and follow-ups are then done.
+func (d *DB) MarkChatTopicUsed(ctx context.Context, id int64) error {
+	_, err := d.pool.ExecContext(ctx, `
+		UPDATE chat_topics
+		SET used_count = used_count + 1, last_used_at = NOW(), done = (kind <> 'joke')
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("mark chat topic used: %w", err)
+	}
+	return nil
+}
+
+// DeleteChatTopic removes a topic. Returns false if the chat has no such topic.
+func (d *DB) DeleteChatTopic(ctx context.Context, chatID, id int64) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, `DELETE FROM chat_topics WHERE id = $1 AND chat_id = $2`, id, chatID)
+	if err != nil {
+		return false, fmt.Errorf("delete chat topic: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("delete chat topic: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,24 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// SetMessageTranscript stores the transcript of a user's voice note or audio file (Telegram
+// message messageID in chatID), where search_messages finds it. It reports whether the message
+// was found.
+func (d *DB) SetMessageTranscript(ctx context.Context, chatID, messageID int64, transcript string) (bool, error) {
+	res, err := d.pool.ExecContext(ctx, `
+		UPDATE messages SET transcript = $3
+		WHERE chat_id = $1 AND message_id = $2 AND NOT is_bot_reply`,
+		chatID, messageID, d.seal(d.scrubbed(&transcript)))
+	if err != nil {
+		return false, fmt.Errorf("set message transcript: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("set message transcript: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LLMUsage is one Gemini call as stored in llm_usage.
+type LLMUsage struct {
+	RequestID    *string
+	ChatID       *int64
+	Purpose      string // "chat", "summary", "search", "consolidation", ...
+	Model        string
+	PromptTokens int
+	OutputTokens int // candidates plus thinking tokens (both billed as output)
+	Error        *string
+}
+
+// RecordLLMUsage stores one Gemini call.
+func (d *DB) RecordLLMUsage(ctx context.Context, u *LLMUsage) error {
+	const query = `
+		INSERT INTO llm_usage (request_id, chat_id, purpose, model, prompt_tokens, output_tokens, error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+	if _, err := d.pool.ExecContext(ctx, query, u.RequestID, u.ChatID, u.Purpose, u.Model, u.PromptTokens, u.OutputTokens, u.Error); err != nil {
+		return fmt.Errorf("record llm usage: %w", err)
+	}
+	return nil
+}
+
+// ChatActivity is one chat's message volume in a report period.
+type ChatActivity struct {
+	ChatID   int64 `json:"chat_id"`
+	Messages int   `json:"messages"` // from users
+	Replies  int   `json:"replies"`  // from the bot
+}
+
+// ActivityReport aggregates what the bot did in [Since, Until).
+type ActivityReport struct {
+	Since             time.Time      `json:"since"`
+	Until             time.Time      `json:"until"`
+	RepliesSent       int            `json:"replies_sent"`
+	ThrottledMessages int            `json:"throttled_messages"`
+	ActiveChats       int            `json:"active_chats"`
+	TopChats          []ChatActivity `json:"top_chats"`
+	LLMCalls          int            `json:"llm_calls"`
+	LLMErrors         int            `json:"llm_errors"`
+	PromptTokens      int64          `json:"prompt_tokens"`
+	OutputTokens      int64          `json:"output_tokens"`
+	NewFacts          int            `json:"new_facts"`
+}
+
+// GetActivityReport collects message, LLM usage and memory statistics for [since, until),
+// with the topChats busiest chats by bot replies.
+func (d *DB) GetActivityReport(ctx context.Context, since, until time.Time, topChats int) (*ActivityReport, error) {
+	r := &ActivityReport{Since: since, Until: until, TopChats: []ChatActivity{}}
+
+	const totals = `
+		SELECT COUNT(*) FILTER (WHERE is_bot_reply), COUNT(*) FILTER (WHERE was_throttled), COUNT(DISTINCT chat_id)
+		FROM messages
+		WHERE created_at >= $1 AND created_at < $2`
+	if err := d.pool.QueryRowContext(ctx, totals, since, until).Scan(&r.RepliesSent, &r.ThrottledMessages, &r.ActiveChats); err != nil {
+		return nil, fmt.Errorf("activity report messages: %w", err)
+	}
+
+	const top = `
+		SELECT chat_id, COUNT(*) FILTER (WHERE NOT is_bot_reply), COUNT(*) FILTER (WHERE is_bot_reply)
+		FROM messages
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY chat_id
+		ORDER BY 3 DESC, 2 DESC
+		LIMIT $3`
+	rows, err := d.pool.QueryContext(ctx, top, since, until, topChats)
+	if err != nil {
+		return nil, fmt.Errorf("activity report top chats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c ChatActivity
+		if err := rows.Scan(&c.ChatID, &c.Messages, &c.Replies); err != nil {
+			return nil, fmt.Errorf("scan chat activity: %w", err)
+		}
+		r.TopChats = append(r.TopChats, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("activity report top chats: %w", err)
+	}
+
+	const usage = `
+		SELECT COUNT(*), COUNT(error), COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(output_tokens), 0)
+		FROM llm_usage
+		WHERE created_at >= $1 AND created_at < $2`
+	if err := d.pool.QueryRowContext(ctx, usage, since, until).Scan(&r.LLMCalls, &r.LLMErrors, &r.PromptTokens, &r.OutputTokens); err != nil {
+		return nil, fmt.Errorf("activity report usage: %w", err)
+	}
+
+	const facts = `SELECT COUNT(*) FROM user_facts WHERE created_at >= $1 AND created_at < $2`
+	if err := d.pool.QueryRowContext(ctx, facts, since, until).Scan(&r.NewFacts); err != nil {
+		return nil, fmt.Errorf("activity report facts: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// UserSettings holds per-user preferences stored in user_settings (shared across chats).
+type UserSettings struct {
+	UserID    int64
+	Language  *string
+	UpdatedAt time.Time
+
+	GlobalMemory   bool    // opted in to facts stored with GlobalFactsChatID
+	PersonalDigest bool    // opted in to the weekly personal digest DM
+	Timezone       *string // IANA zone set with set_timezone; nil means the chat's zone
+}
+
+// Location returns the user's timezone, or fallback when none is stored or it cannot be loaded.
+func (s *UserSettings) Location(fallback *time.Location) *time.Location {
+	if s == nil || s.Timezone == nil || *s.Timezone == "" {
+		return fallback
+	}
+	loc, err := time.LoadLocation(*s.Timezone)
+	if err != nil {
+		return fallback
+	}
+	return loc
+}
+
+// GetUserSettings returns the stored preferences for a user, or nil if none exist.
+func (d *DB) GetUserSettings(ctx context.Context, userID int64) (*UserSettings, error) {
+	const query = `SELECT user_id, language, updated_at, global_memory, personal_digest, timezone FROM user_settings WHERE user_id = $1`
+	var s UserSettings
+	err := d.pool.QueryRowContext(ctx, query, userID).Scan(&s.UserID, &s.Language, &s.UpdatedAt, &s.GlobalMemory, &s.PersonalDigest, &s.Timezone)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user settings: %w", err)
+	}
+	return &s, nil
+}
+
+// SetUserLanguage stores the preferred reply language for a user.
+func (d *DB) SetUserLanguage(ctx context.Context, userID int64, lang string) error {
+	const query = `
+		INSERT INTO user_settings (user_id, language)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET language = EXCLUDED.language, updated_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, userID, lang); err != nil {
+		return fmt.Errorf("set user language: %w", err)
+	}
+	return nil
+}
+
+// SetUserTimezone stores the user's IANA timezone; an empty tz clears it.
+func (d *DB) SetUserTimezone(ctx context.Context, userID int64, tz string) error {
+	const query = `
+		INSERT INTO user_settings (user_id, timezone)
+		VALUES ($1, NULLIF($2, ''))
+		ON CONFLICT (user_id) DO UPDATE SET timezone = EXCLUDED.timezone, updated_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, userID, tz); err != nil {
+		return fmt.Errorf("set user timezone: %w", err)
+	}
+	return nil
+}
+
+// HasGlobalMemory reports whether the user opted in to cross-chat memories.
+func (d *DB) HasGlobalMemory(ctx context.Context, userID int64) (bool, error) {
+	s, err := d.GetUserSettings(ctx, userID)
+	if err != nil || s == nil {
+		return false, err
+	}
+	return s.GlobalMemory, nil
+}
+
+// SetGlobalMemory turns cross-chat memories on or off for a user. Turning them off also
+// deletes the user's global facts, so opting out really forgets them.
+func (d *DB) SetGlobalMemory(ctx context.Context, userID int64, enabled bool) error {
+	tx, err := d.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("set global memory: %w", err)
+	}
+	defer tx.Rollback()
+
+	const query = `
+		INSERT INTO user_settings (user_id, global_memory)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET global_memory = EXCLUDED.global_memory, updated_at = NOW()`
+	if _, err := tx.ExecContext(ctx, query, userID, enabled); err != nil {
+		return fmt.Errorf("set global memory: %w", err)
+	}
+	if !enabled {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM user_facts WHERE chat_id = $1 AND user_id = $2`, GlobalFactsChatID, userID); err != nil {
+			return fmt.Errorf("delete global facts: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	d.invalidateContext(ctx, userVersionKey, userID)
+	return nil
+}
+
+// SetPersonalDigest turns the weekly personal digest DM on or off for a user.
+func (d *DB) SetPersonalDigest(ctx context.Context, userID int64, enabled bool) error {
+	const query = `
+		INSERT INTO user_settings (user_id, personal_digest)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET personal_digest = EXCLUDED.personal_digest, updated_at = NOW()`
+	if _, err := d.pool.ExecContext(ctx, query, userID, enabled); err != nil {
+		return fmt.Errorf("set personal digest: %w", err)
+	}
+	return nil
+}
+
+// ListPersonalDigestUsers returns the users who opted in to the personal digest, with their
+// stored language preference.
+func (d *DB) ListPersonalDigestUsers(ctx context.Context) ([]UserSettings, error) {
+	const query = `
+		SELECT user_id, language, updated_at, global_memory, personal_digest, timezone
+		FROM user_settings
+		WHERE personal_digest
+		ORDER BY user_id`
+	rows, err := d.pool.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("list personal digest users: %w", err)
+	}
+	defer rows.Close()
+	var out []UserSettings
+	for rows.Next() {
+		var s UserSettings
+		if err := rows.Scan(&s.UserID, &s.Language, &s.UpdatedAt, &s.GlobalMemory, &s.PersonalDigest, &s.Timezone); err != nil {
+			return nil, fmt.Errorf("scan user settings: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
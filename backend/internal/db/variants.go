@@ -0,0 +1,91 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ReplyVariant is one reply as stored in reply_variants: the variant it was generated with and
+// its latency, length and tool use.
+type ReplyVariant struct {
+	RequestID   string
+	ChatID      int64
+	Variant     string // "control" or "canary"
+	Model       string
+	Temperature float64
+	LatencyMS   int
+	ReplyLength int
+	ToolCalls   int
+	Failed      bool
+}
+
+// RecordReplyVariant stores the variant of one reply.
+func (d *DB) RecordReplyVariant(ctx context.Context, v *ReplyVariant) error {
+	const query = `
+		INSERT INTO reply_variants (request_id, chat_id, variant, model, temperature, latency_ms, reply_length, tool_calls, failed)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (request_id) DO NOTHING`
+	if _, err := d.pool.ExecContext(ctx, query, v.RequestID, v.ChatID, v.Variant, v.Model, v.Temperature,
+		v.LatencyMS, v.ReplyLength, v.ToolCalls, v.Failed); err != nil {
+		return fmt.Errorf("record reply variant: %w", err)
+	}
+	return nil
+}
+
+// VariantStats aggregates the replies of one variant and model in a period.
+type VariantStats struct {
+	Variant         string  `json:"variant"`
+	Model           string  `json:"model"`
+	Replies         int     `json:"replies"`
+	Failures        int     `json:"failures"`
+	AvgLatencyMS    float64 `json:"avg_latency_ms"`
+	P50LatencyMS    float64 `json:"p50_latency_ms"`
+	P95LatencyMS    float64 `json:"p95_latency_ms"`
+	AvgReplyLength  float64 `json:"avg_reply_length"`
+	AvgToolCalls    float64 `json:"avg_tool_calls"`
+	ToolUseRate     float64 `json:"tool_use_rate"` // share of replies with at least one tool call
+	AvgPromptTokens float64 `json:"avg_prompt_tokens"`
+	AvgOutputTokens float64 `json:"avg_output_tokens"`
+}
+
+// CompareReplyVariants returns per-variant statistics for replies in [since, until). Failed
+// replies count towards Failures only; the averages cover successful ones.
+func (d *DB) CompareReplyVariants(ctx context.Context, since, until time.Time) ([]VariantStats, error) {
+	const query = `
+		SELECT v.variant, v.model, COUNT(*), COUNT(*) FILTER (WHERE v.failed),
+			COALESCE(AVG(v.latency_ms) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(percentile_cont(0.5) WITHIN GROUP (ORDER BY v.latency_ms) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY v.latency_ms) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(AVG(v.reply_length) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(AVG(v.tool_calls) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(AVG(CASE WHEN v.tool_calls > 0 THEN 1.0 ELSE 0.0 END) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(AVG(u.prompt_tokens) FILTER (WHERE NOT v.failed), 0),
+			COALESCE(AVG(u.output_tokens) FILTER (WHERE NOT v.failed), 0)
+		FROM reply_variants v
+		LEFT JOIN (
+			SELECT request_id, SUM(prompt_tokens) AS prompt_tokens, SUM(output_tokens) AS output_tokens
+			FROM llm_usage
+			WHERE purpose = 'chat' AND created_at >= $1
+			GROUP BY request_id
+		) u ON u.request_id = v.request_id
+		WHERE v.created_at >= $1 AND v.created_at < $2
+		GROUP BY v.variant, v.model
+		ORDER BY v.variant, v.model`
+	rows, err := d.pool.QueryContext(ctx, query, since, until)
+	if err != nil {
+		return nil, fmt.Errorf("compare reply variants: %w", err)
+	}
+	defer rows.Close()
+
+	out := []VariantStats{}
+	for rows.Next() {
+		var s VariantStats
+		if err := rows.Scan(&s.Variant, &s.Model, &s.Replies, &s.Failures, &s.AvgLatencyMS, &s.P50LatencyMS, &s.P95LatencyMS,
+			&s.AvgReplyLength, &s.AvgToolCalls, &s.ToolUseRate, &s.AvgPromptTokens, &s.AvgOutputTokens); err != nil {
+			return nil, fmt.Errorf("scan variant stats: %w", err)
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
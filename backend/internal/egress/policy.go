@@ -0,0 +1,232 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+// Sentinel errors returned when a request violates the policy.
+var (
+	ErrSchemeNotAllowed = errors.New("egress: only http and https are allowed")
+	ErrDomainNotAllowed = errors.New("egress: domain not in allow-list")
+	ErrBlockedAddress   = errors.New("egress: destination resolves to a private or reserved address")
+	ErrResponseTooLarge = errors.New("egress: response exceeds size limit")
+)
+
+const maxRedirects = 5
+
+// cgnatRange is the carrier-grade NAT block (100.64.0.0/10), not covered by net.IP.IsPrivate.
+var cgnatRange = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// Policy is the single place that decides what network tools may reach and how much they may read.
+// Every tool that makes outbound HTTP calls, webhook and MCP tools included, must go through
+// Policy.Client, Policy.Transport or Policy.Get.
+type Policy struct {
+	AllowedDomains   []string // empty = any public host
+	InternalHosts    []string // hosts (or IPs, or CIDRs) that may resolve to private addresses
+	MaxResponseBytes int64
+	Timeout          time.Duration
+	AllowPrivate     bool // allow private/loopback destinations (local development only)
+
+	once      sync.Once
+	transport *http.Transport
+	client    *http.Client
+}
+
+// NewPolicy builds the policy from EGRESS_* configuration.
+func NewPolicy(cfg *config.Config) *Policy {
+	p := &Policy{
+		AllowedDomains:   cfg.EgressAllowedDomains,
+		InternalHosts:    cfg.EgressInternalHosts,
+		MaxResponseBytes: int64(cfg.EgressMaxResponseBytes),
+		Timeout:          time.Duration(cfg.EgressTimeoutSeconds) * time.Second,
+		AllowPrivate:     cfg.EgressAllowPrivateNetworks,
+	}
+	if p.MaxResponseBytes <= 0 {
+		p.MaxResponseBytes = 2 << 20
+	}
+	if p.Timeout <= 0 {
+		p.Timeout = 10 * time.Second
+	}
+	return p
+}
+
+// CheckURL parses raw and verifies its scheme and host against the allow-list.
+func (p *Policy) CheckURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("egress: parse url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, ErrSchemeNotAllowed
+	}
+	if !p.domainAllowed(u.Hostname()) {
+		return nil, fmt.Errorf("%w: %s", ErrDomainNotAllowed, u.Hostname())
+	}
+	return u, nil
+}
+
+// domainAllowed matches host exactly or as a subdomain of an allow-list entry. Internal hosts are
+// always allowed. Entries may be written as "example.com" or "*.example.com".
+func (p *Policy) domainAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	if len(p.AllowedDomains) == 0 || p.internal(host) {
+		return true
+	}
+	return matchHost(host, p.AllowedDomains)
+}
+
+// internal reports whether host is on the operator's internal allow-list, so it may resolve to a
+// private address. Entries match like AllowedDomains; CIDR entries match IP literals.
+func (p *Policy) internal(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if ip := net.ParseIP(host); ip != nil {
+		for _, e := range p.InternalHosts {
+			if _, n, err := net.ParseCIDR(strings.TrimSpace(e)); err == nil && n.Contains(ip) {
+				return true
+			}
+		}
+	}
+	return matchHost(host, p.InternalHosts)
+}
+
+func matchHost(host string, entries []string) bool {
+	for _, d := range entries {
+		d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "*."))
+		if d == "" {
+			continue
+		}
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedIP reports whether ip is loopback, private, link-local, CGNAT, multicast, or unspecified.
+func blockedIP(ip net.IP) bool {
+	if ip == nil {
+		return true
+	}
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		cgnatRange.Contains(ip)
+}
+
+// init builds the shared transport and client on first use, so every caller reuses connections.
+// The address check runs in the dialer's Control hook, i.e. on the IP actually being connected
+// to, so DNS rebinding cannot bypass it. Internal hosts skip it: the dial target is still the
+// operator-listed name, so the exemption can't be borrowed by another host.
+func (p *Policy) init() {
+	p.once.Do(func() {
+		open := &net.Dialer{Timeout: p.Timeout}
+		strict := &net.Dialer{
+			Timeout: p.Timeout,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if blockedIP(net.ParseIP(host)) {
+					return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+				}
+				return nil
+			},
+		}
+		p.transport = &http.Transport{
+			Proxy: nil, // never route around the policy via HTTP(S)_PROXY
+			DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return nil, err
+				}
+				if p.AllowPrivate || p.internal(host) {
+					return open.DialContext(ctx, network, address)
+				}
+				return strict.DialContext(ctx, network, address)
+			},
+			TLSHandshakeTimeout: p.Timeout,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     30 * time.Second,
+		}
+		p.client = &http.Client{
+			Timeout:       p.Timeout,
+			Transport:     p.transport,
+			CheckRedirect: p.CheckRedirect,
+		}
+	})
+}
+
+// Transport returns the shared transport. Callers that need no overall timeout (streaming MCP
+// responses, calls bounded by their context) build a client on it with CheckRedirect.
+func (p *Policy) Transport() http.RoundTripper {
+	p.init()
+	return p.transport
+}
+
+// Client returns the shared http.Client enforcing the policy, with EGRESS_TIMEOUT_SECONDS as
+// its overall timeout.
+func (p *Policy) Client() *http.Client {
+	p.init()
+	return p.client
+}
+
+// CheckRedirect re-validates every redirect against the allow-list; use it as
+// http.Client.CheckRedirect.
+func (p *Policy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("egress: stopped after %d redirects", maxRedirects)
+	}
+	_, err := p.CheckURL(req.URL.String())
+	return err
+}
+
+// Do validates req.URL, sends it with the policy client, and reads at most MaxResponseBytes of the body.
+func (p *Policy) Do(req *http.Request) (body []byte, status int, err error) {
+	if _, err := p.CheckURL(req.URL.String()); err != nil {
+		return nil, 0, err
+	}
+	resp, err := p.Client().Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err = io.ReadAll(io.LimitReader(resp.Body, p.MaxResponseBytes+1))
+	if err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("egress: read body: %w", err)
+	}
+	if int64(len(body)) > p.MaxResponseBytes {
+		return nil, resp.StatusCode, ErrResponseTooLarge
+	}
+	return body, resp.StatusCode, nil
+}
+
+// Get is a convenience wrapper for a GET request through the policy.
+func (p *Policy) Get(ctx context.Context, rawURL string, header http.Header) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("egress: build request: %w", err)
+	}
+	for k, vs := range header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+	return p.Do(req)
+}
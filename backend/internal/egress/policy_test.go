@@ -0,0 +1,133 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckURL_AllowList(t *testing.T) {
+	p := &Policy{AllowedDomains: []string{"example.com", "*.weather.gov"}}
+
+	allowed := []string{
+		"https://example.com/a",
+		"https://api.example.com/b",
+		"http://forecast.weather.gov/",
+		"https://weather.gov",
+	}
+	for _, u := range allowed {
+		if _, err := p.CheckURL(u); err != nil {
+			t.Errorf("expected %s to be allowed, got %v", u, err)
+		}
+	}
+
+	denied := []string{
+		"https://evil.com",
+		"https://notexample.com",
+		"https://example.com.evil.com",
+	}
+	for _, u := range denied {
+		if _, err := p.CheckURL(u); !errors.Is(err, ErrDomainNotAllowed) {
+			t.Errorf("expected %s to be denied, got %v", u, err)
+		}
+	}
+}
+
+func TestCheckURL_Scheme(t *testing.T) {
+	p := &Policy{}
+	for _, u := range []string{"file:///etc/passwd", "gopher://x", "ftp://example.com"} {
+		if _, err := p.CheckURL(u); !errors.Is(err, ErrSchemeNotAllowed) {
+			t.Errorf("expected scheme rejection for %s, got %v", u, err)
+		}
+	}
+}
+
+func TestCheckURL_EmptyAllowListAllowsAny(t *testing.T) {
+	p := &Policy{}
+	if _, err := p.CheckURL("https://anything.example.org/path"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestBlockedIP(t *testing.T) {
+	blocked := []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "172.16.5.5", "169.254.169.254", "100.64.0.1", "::1", "fe80::1", "0.0.0.0"}
+	for _, s := range blocked {
+		if !blockedIP(net.ParseIP(s)) {
+			t.Errorf("expected %s to be blocked", s)
+		}
+	}
+	for _, s := range []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"} {
+		if blockedIP(net.ParseIP(s)) {
+			t.Errorf("expected %s to be allowed", s)
+		}
+	}
+}
+
+func TestGet_BlocksLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	defer srv.Close()
+
+	p := &Policy{MaxResponseBytes: 1024, Timeout: 2 * time.Second}
+	_, _, err := p.Get(context.Background(), srv.URL, nil)
+	if err == nil || !strings.Contains(err.Error(), ErrBlockedAddress.Error()) {
+		t.Errorf("expected blocked address error, got %v", err)
+	}
+}
+
+func TestGet_SizeLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer srv.Close()
+
+	p := &Policy{MaxResponseBytes: 10, Timeout: 2 * time.Second, AllowPrivate: true}
+	if _, _, err := p.Get(context.Background(), srv.URL, nil); !errors.Is(err, ErrResponseTooLarge) {
+		t.Errorf("expected ErrResponseTooLarge, got %v", err)
+	}
+
+	p.MaxResponseBytes = 100
+	body, status, err := p.Get(context.Background(), srv.URL, nil)
+	if err != nil || status != 200 || len(body) != 100 {
+		t.Errorf("expected full body, got len=%d status=%d err=%v", len(body), status, err)
+	}
+}
+
+func TestInternalHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	p := &Policy{AllowedDomains: []string{"example.com"}, InternalHosts: []string{"127.0.0.0/8"}, MaxResponseBytes: 10, Timeout: 2 * time.Second}
+	body, _, err := p.Get(context.Background(), srv.URL, nil)
+	if err != nil || string(body) != "ok" {
+		t.Errorf("expected an internal CIDR to reach loopback, got %q %v", body, err)
+	}
+
+	p = &Policy{InternalHosts: []string{"hooks.internal", "*.svc.cluster.local"}}
+	for host, want := range map[string]bool{
+		"hooks.internal":             true,
+		"api.svc.cluster.local":      true,
+		"evilhooks.internal":         false,
+		"127.0.0.1":                  false,
+		"svc.cluster.local.evil.com": false,
+	} {
+		if got := p.internal(host); got != want {
+			t.Errorf("internal(%s) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestClient_Shared(t *testing.T) {
+	p := &Policy{Timeout: time.Second}
+	if p.Client() != p.Client() || p.Client().Transport != p.Transport() {
+		t.Error("expected one shared client and transport")
+	}
+}
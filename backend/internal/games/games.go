@@ -0,0 +1,150 @@
+// Package games holds the rules of the chat games: dice, Russian roulette and trivia. The game
+// tools keep their state and scores; everything here is pure so it can be tested with a fixed rnd.
+package games
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rnd returns a number in [0, n); rand.IntN in production.
+type Rnd func(n int) int
+
+// Dice limits.
+const (
+	MaxDice      = 20
+	MaxSides     = 1000
+	MaxModifier  = 1000
+	defaultSides = 6
+)
+
+// Roll is the outcome of a dice roll.
+type Roll struct {
+	Notation string `json:"notation"`
+	Rolls    []int  `json:"rolls"`
+	Modifier int    `json:"modifier,omitempty"`
+	Total    int    `json:"total"`
+}
+
+var diceRe = regexp.MustCompile(`^(\d*)[dDкК](\d+)\s*(?:([+-])\s*(\d+))?$`)
+
+// ParseDice reads dice notation: "d20", "2d6", "3d8+2" ("к" works for "d" too). Empty means 1d6.
+func ParseDice(s string) (count, sides, mod int, err error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 1, defaultSides, 0, nil
+	}
+	m := diceRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, 0, fmt.Errorf("dice must look like 2d6 or d20+3")
+	}
+	count = 1
+	if m[1] != "" {
+		count, _ = strconv.Atoi(m[1])
+	}
+	sides, _ = strconv.Atoi(m[2])
+	if m[4] != "" {
+		mod, _ = strconv.Atoi(m[4])
+		if m[3] == "-" {
+			mod = -mod
+		}
+	}
+	if count < 1 || count > MaxDice || sides < 2 || sides > MaxSides || mod < -MaxModifier || mod > MaxModifier {
+		return 0, 0, 0, fmt.Errorf("at most %d dice of 2-%d sides, modifier within ±%d", MaxDice, MaxSides, MaxModifier)
+	}
+	return count, sides, mod, nil
+}
+
+// RollDice rolls dice given in notation (see ParseDice).
+func RollDice(notation string, rnd Rnd) (Roll, error) {
+	count, sides, mod, err := ParseDice(notation)
+	if err != nil {
+		return Roll{}, err
+	}
+	r := Roll{Notation: fmt.Sprintf("%dd%d", count, sides), Rolls: make([]int, count), Modifier: mod, Total: mod}
+	if mod > 0 {
+		r.Notation += fmt.Sprintf("+%d", mod)
+	} else if mod < 0 {
+		r.Notation += strconv.Itoa(mod)
+	}
+	for i := range r.Rolls {
+		r.Rolls[i] = rnd(sides) + 1
+		r.Total += r.Rolls[i]
+	}
+	return r, nil
+}
+
+// Chambers is the size of the revolver's cylinder.
+const Chambers = 6
+
+// Revolver is a cylinder with one bullet, spun once and then pulled until it fires.
+type Revolver struct {
+	Left   int `json:"left"`   // chambers not yet pulled
+	Bullet int `json:"bullet"` // pulls until the bullet, 0 = the next one fires
+}
+
+// NewRevolver loads one bullet and spins the cylinder.
+func NewRevolver(rnd Rnd) Revolver {
+	return Revolver{Left: Chambers, Bullet: rnd(Chambers)}
+}
+
+// Pull pulls the trigger and reports whether it fired. The last chamber always fires; reload
+// with NewRevolver after a shot.
+func (r *Revolver) Pull() bool {
+	if r.Bullet <= 0 || r.Left <= 1 {
+		r.Left, r.Bullet = 0, 0
+		return true
+	}
+	r.Bullet--
+	r.Left--
+	return false
+}
+
+// Trivia is an open trivia question with its accepted answers.
+type Trivia struct {
+	Question string   `json:"question"`
+	Answers  []string `json:"answers"`
+	AskedBy  int64    `json:"asked_by,omitempty"`
+}
+
+// Check reports whether guess matches one of the accepted answers, ignoring case, punctuation,
+// extra spaces and apostrophe variants.
+func (t Trivia) Check(guess string) bool {
+	g := NormalizeAnswer(guess)
+	if g == "" {
+		return false
+	}
+	for _, a := range t.Answers {
+		if NormalizeAnswer(a) == g {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeAnswer lowercases s, drops punctuation (apostrophes included, since ' ’ ʼ are all used
+// in Ukrainian) and collapses spaces; "ё" is folded to "е".
+func NormalizeAnswer(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r == 'ё':
+			r = 'е'
+		case unicode.IsSpace(r) || r == '-':
+			space = b.Len() > 0
+			continue
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,87 @@
+package games
+
+import (
+	"reflect"
+	"testing"
+)
+
+// seq returns a Rnd yielding vals in order, each reduced mod n.
+func seq(vals ...int) Rnd {
+	i := 0
+	return func(n int) int {
+		v := vals[i%len(vals)] % n
+		i++
+		return v
+	}
+}
+
+func TestParseDice(t *testing.T) {
+	tests := []struct {
+		in                string
+		count, sides, mod int
+		wantErr           bool
+	}{
+		{"", 1, 6, 0, false},
+		{"d20", 1, 20, 0, false},
+		{"2d6", 2, 6, 0, false},
+		{"3d8+2", 3, 8, 2, false},
+		{"1d10 - 4", 1, 10, -4, false},
+		{"2к6", 2, 6, 0, false},
+		{"21d6", 0, 0, 0, true},
+		{"1d1", 0, 0, 0, true},
+		{"0d6", 0, 0, 0, true},
+		{"roll", 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		c, s, m, err := ParseDice(tt.in)
+		if (err != nil) != tt.wantErr || c != tt.count || s != tt.sides || m != tt.mod {
+			t.Errorf("ParseDice(%q) = %d, %d, %d, %v", tt.in, c, s, m, err)
+		}
+	}
+}
+
+func TestRollDice(t *testing.T) {
+	r, err := RollDice("3d6-1", seq(0, 5, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Roll{Notation: "3d6-1", Rolls: []int{1, 6, 3}, Modifier: -1, Total: 9}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("got %+v, want %+v", r, want)
+	}
+}
+
+func TestRevolver(t *testing.T) {
+	r := NewRevolver(seq(2))
+	for i := 0; i < 2; i++ {
+		if r.Pull() {
+			t.Fatalf("pull %d should click", i+1)
+		}
+	}
+	if !r.Pull() {
+		t.Error("third pull should fire")
+	}
+
+	// The last chamber always fires
+	r = Revolver{Left: 1, Bullet: 3}
+	if !r.Pull() {
+		t.Error("last chamber should fire")
+	}
+}
+
+func TestTriviaCheck(t *testing.T) {
+	q := Trivia{Question: "Capital of Ukraine?", Answers: []string{"Київ", "Kyiv"}}
+	for _, guess := range []string{"київ", " KYIV! ", "Київ."} {
+		if !q.Check(guess) {
+			t.Errorf("%q should match", guess)
+		}
+	}
+	for _, guess := range []string{"", "Львів", "Kyiv city"} {
+		if q.Check(guess) {
+			t.Errorf("%q should not match", guess)
+		}
+	}
+	if NormalizeAnswer("Об’єднане  Королівство") != NormalizeAnswer("об'єднане королівство") {
+		t.Error("apostrophe variants and spaces should normalize the same")
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// AckReplyRequest is sent by the frontend after it delivered a bot reply to Telegram.
+type AckReplyRequest struct {
+	RequestID string `json:"request_id"`
+	ChatID    int64  `json:"chat_id"`
+	MessageID int64  `json:"message_id"`
+	FileID    string `json:"file_id,omitempty"`
+}
+
+// AckReply backfills the Telegram message_id (and file_id for media) of the stored bot reply,
+// so links, edits, and reactions to bot messages resolve like any other message.
+// POST /api/v1/ack_reply — 200 {"status":"ok"}, 404 if no stored reply matches request_id.
+func (h *Handler) AckReply(w http.ResponseWriter, r *http.Request) {
+	var req AckReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.RequestID == "" || req.ChatID == 0 || req.MessageID <= 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "request_id, chat_id and message_id are required")
+		return
+	}
+	ctx := logging.WithChat(logging.WithRequestID(r.Context(), req.RequestID), req.ChatID, 0)
+
+	n, err := h.db.UpdateBotReplyDelivery(ctx, req.ChatID, req.RequestID, req.MessageID, strPtr(req.FileID))
+	if err != nil {
+		slog.ErrorContext(ctx, "ack reply failed", "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if n == 0 {
+		slog.WarnContext(ctx, "ack for unknown bot reply", "message_id", req.MessageID)
+		apierror.Write(w, r, apierror.NotFound, "reply not found")
+		return
+	}
+
+	slog.InfoContext(ctx, "bot reply acknowledged", "message_id", req.MessageID, "has_file_id", req.FileID != "")
+	writeJSON(w, map[string]string{"status": "ok"})
+}
@@ -2,32 +2,64 @@ package handler
 
 import (
 	"encoding/json"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/archive"
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"github.com/ThatHunky/gryag/backend/internal/reporting"
+	"github.com/ThatHunky/gryag/backend/internal/tools"
 )
 
 // AdminHandler provides management endpoints for bot administrators.
 type AdminHandler struct {
-	db     *db.DB
-	config *config.Config
+	db        *db.DB
+	config    *config.Config
+	settings  *chatsettings.Store
+	i18n      *i18n.Bundle
+	cache     *cache.Cache   // optional; rate limit boosts
+	archive   *archive.Store // optional; message archives (RETENTION_ARCHIVE_DIR)
+	tracer    tracer         // optional; debug traces (DEBUG_TRACE)
 	startTime time.Time
 }
 
 // NewAdminHandler creates a new admin handler.
-func NewAdminHandler(cfg *config.Config, database *db.DB) *AdminHandler {
+func NewAdminHandler(cfg *config.Config, database *db.DB, settings *chatsettings.Store, bundle *i18n.Bundle) *AdminHandler {
 	return &AdminHandler{
 		db:        database,
 		config:    cfg,
+		settings:  settings,
+		i18n:      bundle,
 		startTime: time.Now(),
 	}
 }
 
+// SetCache enables the rate limit boost endpoints.
+func (a *AdminHandler) SetCache(c *cache.Cache) {
+	a.cache = c
+}
+
+// SetArchive enables the archive endpoints.
+func (a *AdminHandler) SetArchive(s *archive.Store) {
+	a.archive = s
+}
+
+// SetTracer enables the trace and replay endpoints.
+func (a *AdminHandler) SetTracer(t tracer) {
+	a.tracer = t
+}
+
 // isAdmin checks if the requesting user is an admin.
 func (a *AdminHandler) isAdmin(userID int64) bool {
 	for _, id := range a.config.AdminIDs {
@@ -38,21 +70,65 @@ func (a *AdminHandler) isAdmin(userID int64) bool {
 	return false
 }
 
-// Stats returns server statistics.
-func (a *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+// decodeAdmin reads the JSON body, checks that its user_id is an admin, and decodes the body into v
+// (v may be nil). On failure it writes the error response and returns false.
+func (a *AdminHandler) decodeAdmin(w http.ResponseWriter, r *http.Request, action string, v any) (int64, bool) {
 	requestID := r.Header.Get("X-Request-ID")
 
-	var req struct {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return 0, false
+	}
+	var auth struct {
 		UserID int64 `json:"user_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"invalid payload"}`, http.StatusBadRequest)
+	if err := json.Unmarshal(body, &auth); err != nil {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return 0, false
+	}
+	if v != nil {
+		if err := json.Unmarshal(body, v); err != nil {
+			apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+			return 0, false
+		}
+	}
+
+	if !a.isAdmin(auth.UserID) {
+		slog.Warn("unauthorized admin access attempt", "action", action, "user_id", auth.UserID, "request_id", requestID)
+		apierror.Write(w, r, apierror.Forbidden, "unauthorized")
+		return 0, false
+	}
+	return auth.UserID, true
+}
+
+// Config returns the effective configuration after defaults and validation, with secrets masked,
+// plus the values that were rejected at startup.
+// POST /api/v1/admin/config — {"user_id": ...}
+func (a *AdminHandler) Config(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.decodeAdmin(w, r, "config", nil); !ok {
 		return
 	}
+	issues := a.config.Issues
+	if issues == nil {
+		issues = []config.Issue{}
+	}
+	writeJSON(w, map[string]any{
+		"config":          a.config.Redacted(),
+		"validation_mode": a.config.ValidationMode,
+		"issues":          issues,
+	})
+}
+
+// writeJSON encodes any value as a JSON response.
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
 
-	if !a.isAdmin(req.UserID) {
-		slog.Warn("unauthorized admin access attempt", "user_id", req.UserID, "request_id", requestID)
-		http.Error(w, `{"error":"unauthorized"}`, http.StatusForbidden)
+// Stats returns server statistics.
+func (a *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.decodeAdmin(w, r, "stats", nil); !ok {
 		return
 	}
 
@@ -71,41 +147,508 @@ func (a *AdminHandler) Stats(w http.ResponseWriter, r *http.Request) {
 		"default_lang":    a.config.DefaultLang,
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(stats)
+	writeJSON(w, stats)
 }
 
 // ReloadPersona re-reads the persona file from disk (hot-swap).
 func (a *AdminHandler) ReloadPersona(w http.ResponseWriter, r *http.Request) {
-	requestID := r.Header.Get("X-Request-ID")
+	userID, ok := a.decodeAdmin(w, r, "reload_persona", nil)
+	if !ok {
+		return
+	}
+
+	// Verify the persona file is readable
+	if _, err := os.ReadFile(a.config.PersonaFile); err != nil {
+		slog.Error("persona file not readable", "path", a.config.PersonaFile, "error", err)
+		apierror.Write(w, r, apierror.Internal, "persona file not readable")
+		return
+	}
+
+	slog.Info("persona reload requested", "user_id", userID, "path", a.config.PersonaFile)
+
+	writeJSON(w, map[string]string{
+		"status":  "ok",
+		"message": "Persona will be reloaded on next request.",
+		"file":    a.config.PersonaFile,
+	})
+}
 
+// ReloadLocales re-reads all locale files from LOCALE_DIR. If any file is invalid the
+// previously loaded strings stay active and the parse error is returned.
+// POST /api/v1/admin/reload_locales — {"user_id": ...}
+func (a *AdminHandler) ReloadLocales(w http.ResponseWriter, r *http.Request) {
+	userID, ok := a.decodeAdmin(w, r, "reload_locales", nil)
+	if !ok {
+		return
+	}
+
+	if err := a.i18n.Reload(); err != nil {
+		slog.Error("locale reload failed", "dir", a.config.LocaleDir, "error", err)
+		apierror.Write(w, r, apierror.Unprocessable, err.Error())
+		return
+	}
+
+	languages := a.i18n.Languages()
+	slog.Info("locales reloaded", "user_id", userID, "languages", languages)
+	writeJSON(w, map[string]any{
+		"status":    "ok",
+		"languages": languages,
+	})
+}
+
+// ── Chat settings ───────────────────────────────────────────────────────
+
+// GetChatSettings returns the stored overrides and effective settings for one chat,
+// or all chats with overrides when chat_id is omitted.
+// POST /api/v1/admin/chat_settings — {"user_id": ..., "chat_id": ...}
+func (a *AdminHandler) GetChatSettings(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		UserID int64 `json:"user_id"`
+		ChatID int64 `json:"chat_id"`
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, `{"error":"invalid payload"}`, http.StatusBadRequest)
+	if _, ok := a.decodeAdmin(w, r, "chat_settings_get", &req); !ok {
 		return
 	}
+	ctx := r.Context()
 
-	if !a.isAdmin(req.UserID) {
-		slog.Warn("unauthorized persona reload attempt", "user_id", req.UserID, "request_id", requestID)
-		http.Error(w, `{"error":"unauthorized"}`, http.StatusForbidden)
+	if req.ChatID == 0 {
+		all, err := a.db.ListChatSettings(ctx)
+		if err != nil {
+			slog.Error("list chat settings failed", "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		writeJSON(w, map[string]any{"chats": all})
 		return
 	}
 
-	// Verify the persona file is readable
-	if _, err := os.ReadFile(a.config.PersonaFile); err != nil {
-		slog.Error("persona file not readable", "path", a.config.PersonaFile, "error", err)
-		http.Error(w, `{"error":"persona file not readable"}`, http.StatusInternalServerError)
+	overrides, err := a.settings.Overrides(ctx, req.ChatID)
+	if err != nil {
+		slog.Error("get chat settings failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{
+		"overrides": overrides,
+		"effective": chatsettings.Resolve(a.config, req.ChatID, overrides),
+	})
+}
+
+// PutChatSettings replaces the overrides for a chat. Omitted fields inherit the env defaults.
+// PUT /api/v1/admin/chat_settings — {"user_id": ..., "chat_id": ..., "language": "en", ...}
+func (a *AdminHandler) PutChatSettings(w http.ResponseWriter, r *http.Request) {
+	var req db.ChatSettings
+	userID, ok := a.decodeAdmin(w, r, "chat_settings_put", &req)
+	if !ok {
+		return
+	}
+	if err := chatsettings.Validate(&req); err != nil {
+		apierror.Write(w, r, apierror.InvalidArgument, err.Error())
+		return
+	}
+	if req.ActivePersona != nil && *req.ActivePersona != "" && *req.ActivePersona != chatsettings.DefaultPersona {
+		p, err := a.db.GetPersona(r.Context(), *req.ActivePersona)
+		if err != nil {
+			slog.Error("get persona failed", "persona", *req.ActivePersona, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		if p == nil {
+			apierror.Write(w, r, apierror.InvalidArgument, "unknown persona")
+			return
+		}
+	}
+	if err := a.settings.Save(r.Context(), &req); err != nil {
+		slog.Error("save chat settings failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("chat settings updated", "chat_id", req.ChatID, "user_id", userID)
+	writeJSON(w, map[string]any{
+		"status":    "ok",
+		"effective": chatsettings.Resolve(a.config, req.ChatID, &req),
+	})
+}
+
+// DeleteChatSettings resets a chat to the env defaults.
+// DELETE /api/v1/admin/chat_settings — {"user_id": ..., "chat_id": ...}
+func (a *AdminHandler) DeleteChatSettings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "chat_settings_delete", &req)
+	if !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	if err := a.settings.Reset(r.Context(), req.ChatID); err != nil {
+		slog.Error("reset chat settings failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("chat settings reset", "chat_id", req.ChatID, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// ── Personas ────────────────────────────────────────────────────────────
+
+// ListPersonas returns all stored personas (the built-in "default" persona is the PERSONA_FILE).
+// POST /api/v1/admin/personas — {"user_id": ...}
+func (a *AdminHandler) ListPersonas(w http.ResponseWriter, r *http.Request) {
+	if _, ok := a.decodeAdmin(w, r, "personas_list", nil); !ok {
+		return
+	}
+	personas, err := a.db.ListPersonas(r.Context())
+	if err != nil {
+		slog.Error("list personas failed", "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"default": chatsettings.DefaultPersona, "personas": personas})
+}
+
+// PutPersona creates or replaces a named persona.
+// PUT /api/v1/admin/personas — {"user_id": ..., "name": "polite-assistant", "description": "...", "prompt": "..."}
+func (a *AdminHandler) PutPersona(w http.ResponseWriter, r *http.Request) {
+	var req db.Persona
+	userID, ok := a.decodeAdmin(w, r, "personas_put", &req)
+	if !ok {
 		return
 	}
+	if err := chatsettings.ValidatePersona(&req); err != nil {
+		apierror.Write(w, r, apierror.InvalidArgument, err.Error())
+		return
+	}
+	if err := a.settings.SavePersona(r.Context(), &req); err != nil {
+		slog.Error("save persona failed", "persona", req.Name, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("persona saved", "persona", req.Name, "user_id", userID, "prompt_length", len(req.Prompt))
+	writeJSON(w, map[string]string{"status": "ok"})
+}
 
-	slog.Info("persona reload requested", "user_id", req.UserID, "path", a.config.PersonaFile)
+// DeletePersona removes a named persona; chats using it revert to the default.
+// DELETE /api/v1/admin/personas — {"user_id": ..., "name": ...}
+func (a *AdminHandler) DeletePersona(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "personas_delete", &req)
+	if !ok {
+		return
+	}
+	if req.Name == "" || req.Name == chatsettings.DefaultPersona {
+		apierror.Write(w, r, apierror.InvalidArgument, "a stored persona name is required")
+		return
+	}
+	if err := a.settings.DeletePersona(r.Context(), req.Name); err != nil {
+		slog.Error("delete persona failed", "persona", req.Name, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("persona deleted", "persona", req.Name, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
 
+// writeJSONStatus encodes v as JSON with an explicit status code.
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "ok",
-		"message": "Persona will be reloaded on next request.",
-		"file":    a.config.PersonaFile,
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// ListOffRecordWindows handles POST /api/v1/admin/off_record: lists a chat's off-the-record windows.
+func (a *AdminHandler) ListOffRecordWindows(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "off_record_list", &req); !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	windows, err := a.db.ListOffRecordWindows(r.Context(), req.ChatID)
+	if err != nil {
+		slog.Error("list off-record windows failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "windows": windows})
+}
+
+// PutOffRecordWindow handles PUT /api/v1/admin/off_record. Without id it opens a window
+// (starts_at defaults to now, no ends_at = until closed); with id it closes that window at ends_at (default now).
+func (a *AdminHandler) PutOffRecordWindow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID   int64      `json:"chat_id"`
+		ID       int64      `json:"id"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+		Reason   string     `json:"reason"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "off_record_put", &req)
+	if !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	now := time.Now()
+
+	if req.ID != 0 {
+		endsAt := now
+		if req.EndsAt != nil {
+			endsAt = *req.EndsAt
+		}
+		closed, err := a.db.CloseOffRecordWindow(r.Context(), req.ChatID, req.ID, endsAt)
+		if err != nil {
+			slog.Error("close off-record window failed", "chat_id", req.ChatID, "id", req.ID, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		if !closed {
+			apierror.Write(w, r, apierror.NotFound, "window not found or ends_at before its start")
+			return
+		}
+		slog.Info("off-record window closed", "chat_id", req.ChatID, "id", req.ID, "user_id", userID)
+		writeJSON(w, map[string]any{"status": "ok", "id": req.ID})
+		return
+	}
+
+	win := db.OffRecordWindow{ChatID: req.ChatID, StartsAt: now, EndsAt: req.EndsAt, Reason: req.Reason, CreatedBy: &userID}
+	if req.StartsAt != nil {
+		win.StartsAt = *req.StartsAt
+	}
+	if win.EndsAt != nil && !win.EndsAt.After(win.StartsAt) {
+		apierror.Write(w, r, apierror.InvalidArgument, "ends_at must be after starts_at")
+		return
+	}
+	id, err := a.db.InsertOffRecordWindow(r.Context(), &win)
+	if err != nil {
+		slog.Error("create off-record window failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("off-record window opened", "chat_id", req.ChatID, "id", id, "user_id", userID)
+	writeJSON(w, map[string]any{"status": "ok", "id": id})
+}
+
+// DeleteOffRecordWindow handles DELETE /api/v1/admin/off_record: removes a window, so its messages count again.
+func (a *AdminHandler) DeleteOffRecordWindow(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+		ID     int64 `json:"id"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "off_record_delete", &req)
+	if !ok {
+		return
+	}
+	if req.ChatID == 0 || req.ID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id and id are required")
+		return
+	}
+	deleted, err := a.db.DeleteOffRecordWindow(r.Context(), req.ChatID, req.ID)
+	if err != nil {
+		slog.Error("delete off-record window failed", "chat_id", req.ChatID, "id", req.ID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if !deleted {
+		apierror.Write(w, r, apierror.NotFound, "window not found")
+		return
+	}
+	slog.Info("off-record window deleted", "chat_id", req.ChatID, "id", req.ID, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// ListChatTopics handles POST /api/v1/admin/chat_topics: lists a chat's topic hints.
+func (a *AdminHandler) ListChatTopics(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "chat_topics_list", &req); !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	topics, err := a.db.ListChatTopics(r.Context(), req.ChatID)
+	if err != nil {
+		slog.Error("list chat topics failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "topics": topics})
+}
+
+// PutChatTopic handles PUT /api/v1/admin/chat_topics: adds a topic hint. due_at is RFC3339 or a
+// local date/time ("2026-05-30 18:00") in the chat's timezone.
+func (a *AdminHandler) PutChatTopic(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64  `json:"chat_id"`
+		Kind   string `json:"kind"`
+		Text   string `json:"text"`
+		DueAt  string `json:"due_at"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "chat_topics_put", &req)
+	if !ok {
+		return
+	}
+	req.Text = strings.TrimSpace(req.Text)
+	if req.ChatID == 0 || req.Text == "" {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id and text are required")
+		return
+	}
+	if !db.ValidTopicKind(req.Kind) {
+		apierror.Write(w, r, apierror.InvalidArgument, "kind must be event, joke or follow_up")
+		return
+	}
+	topic := db.ChatTopic{ChatID: req.ChatID, Kind: req.Kind, Text: req.Text, Source: "admin", CreatedBy: &userID}
+	if req.DueAt != "" {
+		loc := time.UTC
+		if a.settings != nil {
+			loc = a.settings.Get(r.Context(), req.ChatID).Location()
+		}
+		due, err := tools.ParseDueAt(req.DueAt, loc)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidArgument, "invalid due_at")
+			return
+		}
+		topic.DueAt = due
+	}
+	id, err := a.db.InsertChatTopic(r.Context(), &topic)
+	if err != nil {
+		slog.Error("create chat topic failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("chat topic added", "chat_id", req.ChatID, "id", id, "kind", req.Kind, "user_id", userID)
+	writeJSON(w, map[string]any{"status": "ok", "id": id})
+}
+
+// DeleteChatTopic handles DELETE /api/v1/admin/chat_topics: removes a topic hint.
+func (a *AdminHandler) DeleteChatTopic(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+		ID     int64 `json:"id"`
+	}
+	userID, ok := a.decodeAdmin(w, r, "chat_topics_delete", &req)
+	if !ok {
+		return
+	}
+	if req.ChatID == 0 || req.ID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id and id are required")
+		return
+	}
+	deleted, err := a.db.DeleteChatTopic(r.Context(), req.ChatID, req.ID)
+	if err != nil {
+		slog.Error("delete chat topic failed", "chat_id", req.ChatID, "id", req.ID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if !deleted {
+		apierror.Write(w, r, apierror.NotFound, "topic not found")
+		return
+	}
+	slog.Info("chat topic deleted", "chat_id", req.ChatID, "id", req.ID, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// Analytics handles POST /api/v1/admin/analytics: the get_chat_stats numbers for a chat over
+// the last days (default 7).
+func (a *AdminHandler) Analytics(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+		Days   int   `json:"days"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "analytics", &req); !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 1 || req.Days > tools.MaxStatsDays {
+		apierror.Write(w, r, apierror.InvalidArgument, "days must be 1-90")
+		return
+	}
+	tz := chatsettings.DefaultTimezone
+	if a.settings != nil {
+		tz = a.settings.Get(r.Context(), req.ChatID).Location().String()
+	}
+	until := time.Now()
+	stats, err := a.db.GetChatStats(r.Context(), req.ChatID, until.AddDate(0, 0, -req.Days), until, tz, tools.StatsTopN)
+	if err != nil {
+		slog.Error("chat analytics failed", "chat_id", req.ChatID, "days", req.Days, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"days": req.Days, "timezone": tz, "stats": stats})
+}
+
+// UserProfile handles POST /api/v1/admin/user/{id}: everything stored about a user, with all
+// their facts, in one chat ({"chat_id": n}) or across every chat (chat_id omitted).
+func (a *AdminHandler) UserProfile(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64 `json:"chat_id"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "user_profile", &req); !ok {
+		return
+	}
+	userID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil || userID <= 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "invalid user id")
+		return
+	}
+	profile, err := a.db.GetUserProfile(r.Context(), req.ChatID, userID, true)
+	if err != nil {
+		slog.Error("user profile failed", "chat_id", req.ChatID, "target_user_id", userID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, profile)
+}
+
+// maxReportDays bounds the window of an on-demand activity report.
+const maxReportDays = 90
+
+// Report handles POST /api/v1/admin/report: the activity report (the weekly DM) for the last
+// days (default 7), as rendered text plus the raw numbers.
+func (a *AdminHandler) Report(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Days int `json:"days"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "report", &req); !ok {
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 1 || req.Days > maxReportDays {
+		apierror.Write(w, r, apierror.InvalidArgument, "days must be 1-90")
+		return
+	}
+	until := time.Now()
+	report, err := a.db.GetActivityReport(r.Context(), until.AddDate(0, 0, -req.Days), until, a.config.ActivityReportTopChats)
+	if err != nil {
+		slog.Error("activity report failed", "days", req.Days, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	prices := reporting.Prices{InputPerMTok: a.config.LLMPriceInputPerMTok, OutputPerMTok: a.config.LLMPriceOutputPerMTok}
+	writeJSON(w, map[string]any{
+		"text":               reporting.Render(a.i18n, a.config.DefaultLang, report, prices),
+		"report":             report,
+		"estimated_cost_usd": reporting.EstimateCost(report.PromptTokens, report.OutputTokens, prices),
 	})
 }
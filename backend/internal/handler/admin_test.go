@@ -0,0 +1,299 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/archive"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+func newTestAdmin() *AdminHandler {
+	return NewAdminHandler(&config.Config{AdminIDs: []int64{111}, DefaultLang: "uk", GeminiTemperature: 0.9}, nil, nil, nil)
+}
+
+func TestAdmin_Unauthorized(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("POST", "/api/v1/admin/stats", strings.NewReader(`{"user_id": 222}`))
+	w := httptest.NewRecorder()
+
+	a.Stats(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", w.Code)
+	}
+}
+
+func TestAdmin_InvalidPayload(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("PUT", "/api/v1/admin/chat_settings", strings.NewReader("not json"))
+	w := httptest.NewRecorder()
+
+	a.PutChatSettings(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestAdmin_Stats(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("POST", "/api/v1/admin/stats", strings.NewReader(`{"user_id": 111}`))
+	w := httptest.NewRecorder()
+
+	a.Stats(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected 200, got %d", w.Code)
+	}
+	if !strings.Contains(w.Body.String(), "uptime") {
+		t.Errorf("expected stats body, got %s", w.Body.String())
+	}
+}
+
+func TestAdmin_PutChatSettings_Validation(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("PUT", "/api/v1/admin/chat_settings", strings.NewReader(`{"user_id": 111, "chat_id": 5, "temperature": 9}`))
+	w := httptest.NewRecorder()
+
+	a.PutChatSettings(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for out-of-range temperature, got %d", w.Code)
+	}
+}
+
+func TestAdmin_ReloadLocales(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(`{"hello": "Привіт"}`), 0644)
+	bundle, err := i18n.NewBundle(dir, "uk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := NewAdminHandler(&config.Config{AdminIDs: []int64{111}, LocaleDir: dir}, nil, nil, bundle)
+
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(`{"hello": "Здоров"}`), 0644)
+	req := httptest.NewRequest("POST", "/api/v1/admin/reload_locales", strings.NewReader(`{"user_id": 111}`))
+	w := httptest.NewRecorder()
+	a.ReloadLocales(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := bundle.T("uk", "hello"); got != "Здоров" {
+		t.Errorf("expected reloaded string, got %q", got)
+	}
+
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(`{broken`), 0644)
+	w = httptest.NewRecorder()
+	a.ReloadLocales(w, httptest.NewRequest("POST", "/api/v1/admin/reload_locales", strings.NewReader(`{"user_id": 111}`)))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("expected 422 for broken locale, got %d", w.Code)
+	}
+	if got := bundle.T("uk", "hello"); got != "Здоров" {
+		t.Errorf("expected previous strings to remain, got %q", got)
+	}
+}
+
+func TestAdmin_PutPersona_Validation(t *testing.T) {
+	a := newTestAdmin()
+	for _, body := range []string{
+		`{"user_id": 111, "name": "default", "prompt": "x"}`,
+		`{"user_id": 111, "name": "Bad Name", "prompt": "x"}`,
+		`{"user_id": 111, "name": "polite"}`,
+	} {
+		req := httptest.NewRequest("PUT", "/api/v1/admin/personas", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.PutPersona(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestAdmin_OffRecord_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		method, body string
+		handler      http.HandlerFunc
+		want         int
+	}{
+		{"POST", `{"user_id": 222, "chat_id": 5}`, a.ListOffRecordWindows, http.StatusForbidden},
+		{"POST", `{"user_id": 111}`, a.ListOffRecordWindows, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111}`, a.PutOffRecordWindow, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111, "chat_id": 5, "starts_at": "2026-05-02T10:00:00Z", "ends_at": "2026-05-01T10:00:00Z"}`, a.PutOffRecordWindow, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111, "chat_id": 5, "starts_at": "yesterday"}`, a.PutOffRecordWindow, http.StatusBadRequest},
+		{"DELETE", `{"user_id": 111, "chat_id": 5}`, a.DeleteOffRecordWindow, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/admin/off_record", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		tt.handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_ChatTopics_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		method, body string
+		handler      http.HandlerFunc
+		want         int
+	}{
+		{"POST", `{"user_id": 222, "chat_id": 5}`, a.ListChatTopics, http.StatusForbidden},
+		{"POST", `{"user_id": 111}`, a.ListChatTopics, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111, "chat_id": 5, "kind": "event"}`, a.PutChatTopic, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111, "chat_id": 5, "kind": "rumor", "text": "x"}`, a.PutChatTopic, http.StatusBadRequest},
+		{"PUT", `{"user_id": 111, "chat_id": 5, "kind": "event", "text": "exam", "due_at": "friday"}`, a.PutChatTopic, http.StatusBadRequest},
+		{"DELETE", `{"user_id": 111, "chat_id": 5}`, a.DeleteChatTopic, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/admin/chat_topics", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		tt.handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_Archives(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("POST", "/api/v1/admin/archives", strings.NewReader(`{"user_id": 111, "chat_id": 5}`))
+	w := httptest.NewRecorder()
+	a.ListArchives(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("archiving disabled: expected 404, got %d", w.Code)
+	}
+
+	a.SetArchive(archive.NewStore(t.TempDir()))
+	tests := []struct {
+		body    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{`{"user_id": 222, "chat_id": 5}`, a.ListArchives, http.StatusForbidden},
+		{`{"user_id": 111}`, a.QueryArchives, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5, "from": "yesterday"}`, a.QueryArchives, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5, "file": "../../etc/passwd"}`, a.QueryArchives, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5}`, a.ListArchives, http.StatusOK},
+		{`{"user_id": 111, "chat_id": 5, "query": "hi"}`, a.QueryArchives, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/admin/archives", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		tt.handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_Analytics_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"user_id": 222, "chat_id": 5}`, http.StatusForbidden},
+		{`{"user_id": 111}`, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5, "days": 91}`, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5, "days": -1}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/admin/analytics", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		a.Analytics(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_UserProfile_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		id   string
+		body string
+		want int
+	}{
+		{"42", `{"user_id": 222}`, http.StatusForbidden},
+		{"abc", `{"user_id": 111}`, http.StatusBadRequest},
+		{"-5", `{"user_id": 111, "chat_id": 5}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/v1/admin/user/"+tt.id, strings.NewReader(tt.body))
+		req.SetPathValue("id", tt.id)
+		w := httptest.NewRecorder()
+		a.UserProfile(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.id, tt.body, tt.want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_Report_Validation(t *testing.T) {
+	a := newTestAdmin()
+	for body, want := range map[string]int{
+		`{"user_id": 222}`:              http.StatusForbidden,
+		`{"user_id": 111, "days": -1}`:  http.StatusBadRequest,
+		`{"user_id": 111, "days": 365}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/report", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.Report(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
+
+func TestAdmin_Config(t *testing.T) {
+	a := NewAdminHandler(&config.Config{AdminIDs: []int64{111}, GeminiAPIKey: "secret-key", ValidationMode: config.ValidationWarn,
+		Issues: []config.Issue{{Key: "BACKEND_PORT", Value: "eighty", Problem: "not an integer", Fallback: "27710"}}}, nil, nil, nil)
+	req := httptest.NewRequest("POST", "/api/v1/admin/config", strings.NewReader(`{"user_id": 111}`))
+	w := httptest.NewRecorder()
+
+	a.Config(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "secret-key") {
+		t.Errorf("config response leaks a secret: %s", body)
+	}
+	if !strings.Contains(body, `"key":"BACKEND_PORT"`) || !strings.Contains(body, `"validation_mode":"warn"`) {
+		t.Errorf("expected issues and mode in body, got %s", body)
+	}
+}
+
+func TestAdmin_Block_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		body    string
+		handler http.HandlerFunc
+		want    int
+	}{
+		{`{"user_id": 222, "blocked_user_id": 333}`, a.PutBlockedUser, http.StatusForbidden},
+		{`{"user_id": 222}`, a.ListBlockedUsers, http.StatusForbidden},
+		{`{"user_id": 111, "chat_id": 5}`, a.PutBlockedUser, http.StatusBadRequest},
+		{`{"user_id": 111, "blocked_user_id": 111}`, a.PutBlockedUser, http.StatusBadRequest},
+		{`{"user_id": 111, "blocked_user_id": 333, "expires_at": "2020-01-01T00:00:00Z"}`, a.PutBlockedUser, http.StatusBadRequest},
+		{`{"user_id": 111, "chat_id": 5}`, a.DeleteBlockedUser, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/api/v1/admin/block", strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		tt.handler(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
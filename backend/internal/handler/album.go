@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"google.golang.org/genai"
+)
+
+const (
+	albumMaxParts = 10 // Telegram's album limit
+	albumMaxWait  = 10 * time.Second
+	albumPoll     = 100 * time.Millisecond
+	albumTTL      = time.Minute
+)
+
+// albumPart is one album item as buffered in Redis.
+type albumPart struct {
+	MessageID   int64  `json:"message_id"`
+	Text        string `json:"text,omitempty"`
+	MediaType   string `json:"media_type,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+	MediaBase64 string `json:"media_base64,omitempty"`
+	IsCommand   bool   `json:"is_command,omitempty"`
+	MentionsBot bool   `json:"mentions_bot,omitempty"`
+	ReplyToBot  bool   `json:"reply_to_bot,omitempty"`
+}
+
+// album holds the other items of the album the current message opened.
+type album []albumPart
+
+type albumContextKey struct{}
+
+func albumFromContext(ctx context.Context) album {
+	a, _ := ctx.Value(albumContextKey{}).(album)
+	return a
+}
+
+// Albums answers a Telegram album (items sharing media_group_id) as one request. The first item
+// waits up to ALBUM_WAIT_MS for the next one (at most 10s in all) and goes on with the others in
+// its context; later items are only stored and get a 204. It wraps the rate limiter, so an album
+// takes one token and the waiting item holds no queue lock.
+func (h *Handler) Albums(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h.cache == nil || h.config.AlbumWaitMS <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var req ProcessRequest
+		if json.Unmarshal(body, &req) != nil || req.MediaGroupID == "" {
+			next.ServeHTTP(w, r) // not an album; bad payloads are the rate limiter's to refuse
+			return
+		}
+		if len(h.config.AllowedChatIDs) > 0 && !slices.Contains(h.config.AllowedChatIDs, req.ChatID) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		requestID := r.Header.Get("X-Request-ID")
+		ctx := logging.WithRequestID(r.Context(), requestID)
+		ctx = logging.With(ctx, "chat_id", req.ChatID, "media_group_id", req.MediaGroupID)
+
+		part, _ := json.Marshal(albumPart{
+			MessageID: req.MessageID, Text: req.Text, MediaType: req.MediaType, MimeType: req.MimeType,
+			MediaBase64: req.MediaBase64, IsCommand: req.IsCommand, MentionsBot: req.MentionsBot, ReplyToBot: req.ReplyToBot,
+		})
+		n, err := h.cache.AddAlbumPart(ctx, req.ChatID, req.MediaGroupID, string(part), albumTTL)
+		if err != nil {
+			slog.WarnContext(ctx, "album buffer failed, answering the item alone", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if n > 1 {
+			slog.InfoContext(ctx, "album item buffered", "position", n)
+			msg := incomingMessage(&req, requestID)
+			h.trackMood(ctx, msg)
+			if _, err := h.db.InsertMessage(ctx, msg); err != nil {
+				slog.ErrorContext(ctx, "failed to store album item", "error", err)
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		h.waitForAlbum(ctx, req.ChatID, req.MediaGroupID)
+		items, err := h.cache.TakeAlbum(ctx, req.ChatID, req.MediaGroupID)
+		if err != nil {
+			slog.WarnContext(ctx, "album collect failed, answering the item alone", "error", err)
+		}
+		var others album
+		for _, item := range items {
+			var p albumPart
+			if json.Unmarshal([]byte(item), &p) == nil && p.MessageID != req.MessageID {
+				others = append(others, p)
+			}
+		}
+		slog.InfoContext(ctx, "album collected", "items", len(others)+1)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), albumContextKey{}, others)))
+	})
+}
+
+// waitForAlbum returns once no new item has joined the album for ALBUM_WAIT_MS, the album is
+// full, albumMaxWait has passed or ctx is done.
+func (h *Handler) waitForAlbum(ctx context.Context, chatID int64, groupID string) {
+	quiet := time.Duration(h.config.AlbumWaitMS) * time.Millisecond
+	start := time.Now()
+	size, grew := int64(1), start
+	for size < albumMaxParts && time.Since(grew) < quiet && time.Since(start) < albumMaxWait {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(albumPoll):
+		}
+		n, err := h.cache.AlbumSize(ctx, chatID, groupID)
+		if err != nil {
+			return
+		}
+		if n > size {
+			size, grew = n, time.Now()
+		}
+	}
+}
+
+// apply folds the other items into the request: a caption when the first item has none, and the
+// trigger facts of any item (the bot may be mentioned in a later caption).
+func (a album) apply(req *ProcessRequest) {
+	for _, p := range a {
+		if req.Text == "" && p.Text != "" {
+			req.Text = p.Text
+		}
+		req.IsCommand = req.IsCommand || p.IsCommand
+		req.MentionsBot = req.MentionsBot || p.MentionsBot
+		req.ReplyToBot = req.ReplyToBot || p.ReplyToBot
+	}
+}
+
+// mediaParts decodes the media of the other items, at most max of them.
+func (a album) mediaParts(ctx context.Context, max int) []*genai.Part {
+	var parts []*genai.Part
+	for _, p := range a {
+		if len(parts) >= max {
+			break
+		}
+		if p.MediaBase64 == "" {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(p.MediaBase64)
+		if err != nil {
+			slog.WarnContext(ctx, "failed to decode album item media", "message_id", p.MessageID, "error", err)
+			continue
+		}
+		parts = append(parts, genai.NewPartFromBytes(data, inferMimeType(p.MediaType, p.MimeType)))
+	}
+	return parts
+}
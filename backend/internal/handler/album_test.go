@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestAlbums_PassThrough(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	const body = `{"chat_id": -100, "message_id": 7, "media_group_id": "g1", "text": "look"}`
+
+	for name, h := range map[string]*Handler{
+		"no cache":       {config: &config.Config{AlbumWaitMS: 1500}},
+		"disabled":       {config: &config.Config{AlbumWaitMS: 0}, cache: cache.Wrap(client)},
+		"redis down":     {config: &config.Config{AlbumWaitMS: 1500}, cache: cache.Wrap(client)},
+		"chat not known": {config: &config.Config{AlbumWaitMS: 1500, AllowedChatIDs: []int64{-200}}, cache: cache.Wrap(client)},
+	} {
+		var got string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			data, _ := io.ReadAll(r.Body)
+			got = string(data)
+			if len(albumFromContext(r.Context())) != 0 {
+				t.Errorf("%s: expected no album in the context", name)
+			}
+		})
+		h.Albums(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", "/api/v1/process", strings.NewReader(body)))
+		if got != body {
+			t.Errorf("%s: expected the body passed on unchanged, got %q", name, got)
+		}
+	}
+}
+
+func TestAlbum_Apply(t *testing.T) {
+	req := &ProcessRequest{MessageID: 1}
+	album{{MessageID: 2}, {MessageID: 3, Text: "@gryag what is this?", MentionsBot: true}, {MessageID: 4, Text: "later"}}.apply(req)
+	if req.Text != "@gryag what is this?" || !req.MentionsBot || req.IsCommand {
+		t.Errorf("expected the first caption and the mention, got %+v", req)
+	}
+
+	req = &ProcessRequest{Text: "own caption"}
+	album{{Text: "other"}}.apply(req)
+	if req.Text != "own caption" {
+		t.Errorf("expected the request's own caption kept, got %q", req.Text)
+	}
+}
+
+func TestAlbum_MediaParts(t *testing.T) {
+	img := base64.StdEncoding.EncodeToString([]byte("jpeg"))
+	a := album{
+		{MessageID: 2, MediaType: "photo", MediaBase64: img},
+		{MessageID: 3, Text: "no media"},
+		{MessageID: 4, MediaType: "photo", MediaBase64: "%%%"},
+		{MessageID: 5, MediaType: "video", MimeType: "video/mp4", MediaBase64: img},
+		{MessageID: 6, MediaType: "photo", MediaBase64: img},
+	}
+	parts := a.mediaParts(context.Background(), 2)
+	if len(parts) != 2 || parts[0].InlineData.MIMEType != "image/jpeg" || parts[1].InlineData.MIMEType != "video/mp4" {
+		t.Fatalf("expected two decoded parts, got %+v", parts)
+	}
+	if string(parts[0].InlineData.Data) != "jpeg" {
+		t.Errorf("unexpected data %q", parts[0].InlineData.Data)
+	}
+	if got := a.mediaParts(context.Background(), 0); len(got) != 0 {
+		t.Errorf("expected nothing past the media cap, got %d parts", len(got))
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/archive"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	// defaultArchiveQueryLimit and maxArchiveQueryLimit bound POST /api/v1/admin/archives/query.
+	defaultArchiveQueryLimit = 100
+	maxArchiveQueryLimit     = 1000
+	// maxArchiveRestore bounds one restore; narrow from/to (or pick a file) to restore more.
+	maxArchiveRestore = 50000
+)
+
+// archiveRequest is the body shared by the archive endpoints.
+type archiveRequest struct {
+	ChatID int64  `json:"chat_id"`
+	File   string `json:"file"`
+	From   string `json:"from"` // RFC 3339
+	To     string `json:"to"`   // RFC 3339, exclusive
+	Query  string `json:"query"`
+	Limit  int    `json:"limit"`
+}
+
+// decodeArchive checks the admin, that archiving is on and that chat_id and from/to are valid.
+func (a *AdminHandler) decodeArchive(w http.ResponseWriter, r *http.Request, action string) (archiveRequest, archive.Query, bool) {
+	var req archiveRequest
+	if _, ok := a.decodeAdmin(w, r, action, &req); !ok {
+		return req, archive.Query{}, false
+	}
+	if a.archive == nil {
+		apierror.Write(w, r, apierror.Disabled, "archiving is disabled")
+		return req, archive.Query{}, false
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return req, archive.Query{}, false
+	}
+	q := archive.Query{File: req.File, Text: req.Query, Limit: req.Limit}
+	for _, f := range []struct {
+		raw string
+		dst *time.Time
+	}{{req.From, &q.From}, {req.To, &q.To}} {
+		if f.raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, f.raw)
+		if err != nil {
+			apierror.Write(w, r, apierror.InvalidArgument, "from and to must be RFC 3339 times")
+			return req, archive.Query{}, false
+		}
+		*f.dst = t
+	}
+	return req, q, true
+}
+
+// writeArchiveError maps archive errors to responses.
+func writeArchiveError(w http.ResponseWriter, r *http.Request, chatID int64, err error) {
+	if errors.Is(err, archive.ErrInvalidName) {
+		apierror.Write(w, r, apierror.InvalidArgument, "invalid file")
+		return
+	}
+	slog.ErrorContext(r.Context(), "archive request failed", "chat_id", chatID, "error", err)
+	apierror.Write(w, r, apierror.Internal, "internal error")
+}
+
+// ListArchives handles POST /api/v1/admin/archives: lists a chat's archive files.
+func (a *AdminHandler) ListArchives(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := a.decodeArchive(w, r, "archives_list")
+	if !ok {
+		return
+	}
+	files, err := a.archive.List(req.ChatID)
+	if err != nil {
+		writeArchiveError(w, r, req.ChatID, err)
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "files": files})
+}
+
+// QueryArchives handles POST /api/v1/admin/archives/query: archived messages of a chat,
+// optionally from one file, within from/to and containing query.
+func (a *AdminHandler) QueryArchives(w http.ResponseWriter, r *http.Request) {
+	req, q, ok := a.decodeArchive(w, r, "archives_query")
+	if !ok {
+		return
+	}
+	if q.Limit <= 0 {
+		q.Limit = defaultArchiveQueryLimit
+	}
+	q.Limit = min(q.Limit, maxArchiveQueryLimit)
+	records, err := a.archive.Query(r.Context(), req.ChatID, q)
+	if err != nil {
+		writeArchiveError(w, r, req.ChatID, err)
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "messages": records})
+}
+
+// RestoreArchives handles POST /api/v1/admin/archives/restore: puts archived messages (same
+// filters as the query, without a limit) back into the message log. Messages already there are
+// skipped. Raise the chat's message_retention_days first, or the next retention run archives them again.
+func (a *AdminHandler) RestoreArchives(w http.ResponseWriter, r *http.Request) {
+	req, q, ok := a.decodeArchive(w, r, "archives_restore")
+	if !ok {
+		return
+	}
+	q.Limit = maxArchiveRestore + 1
+	records, err := a.archive.Query(r.Context(), req.ChatID, q)
+	if err != nil {
+		writeArchiveError(w, r, req.ChatID, err)
+		return
+	}
+	if len(records) > maxArchiveRestore {
+		apierror.Write(w, r, apierror.InvalidArgument, "too many messages; narrow from/to or pick a file")
+		return
+	}
+	msgs := make([]db.Message, len(records))
+	for i, rec := range records {
+		msgs[i] = rec.Message()
+	}
+	restored, err := a.db.RestoreMessages(r.Context(), msgs)
+	if err != nil {
+		writeArchiveError(w, r, req.ChatID, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "archived messages restored", "chat_id", req.ChatID, "restored", restored, "matched", len(records))
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "matched": len(records), "restored": restored})
+}
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// blockRequest is the body shared by the block endpoints. chat_id 0 (or omitted) means every chat.
+type blockRequest struct {
+	ChatID        int64      `json:"chat_id"`
+	BlockedUserID int64      `json:"blocked_user_id"`
+	Reason        string     `json:"reason"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+}
+
+// ListBlockedUsers handles POST /api/v1/admin/block: the active blocks applying in chat_id, or all
+// of them without chat_id.
+func (a *AdminHandler) ListBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	var req blockRequest
+	if _, ok := a.decodeAdmin(w, r, "block_list", &req); !ok {
+		return
+	}
+	blocked, err := a.db.ListBlockedUsers(r.Context(), req.ChatID)
+	if err != nil {
+		slog.Error("list blocked users failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "blocked": blocked})
+}
+
+// PutBlockedUser handles PUT /api/v1/admin/block: the bot stops answering blocked_user_id in chat_id
+// (every chat without it) until expires_at or until unblocked. Their messages are still stored.
+func (a *AdminHandler) PutBlockedUser(w http.ResponseWriter, r *http.Request) {
+	var req blockRequest
+	userID, ok := a.decodeAdmin(w, r, "block_put", &req)
+	if !ok {
+		return
+	}
+	if req.BlockedUserID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "blocked_user_id is required")
+		return
+	}
+	if a.isAdmin(req.BlockedUserID) {
+		apierror.Write(w, r, apierror.InvalidArgument, "admins cannot be blocked")
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		apierror.Write(w, r, apierror.InvalidArgument, "expires_at must be in the future")
+		return
+	}
+	b := db.BlockedUser{UserID: req.BlockedUserID, ChatID: req.ChatID, Reason: req.Reason, BlockedBy: &userID, ExpiresAt: req.ExpiresAt}
+	if err := a.db.BlockUser(r.Context(), &b); err != nil {
+		slog.Error("block user failed", "chat_id", req.ChatID, "blocked_user_id", req.BlockedUserID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.Info("user blocked", "chat_id", req.ChatID, "blocked_user_id", req.BlockedUserID, "user_id", userID, "expires_at", req.ExpiresAt)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// DeleteBlockedUser handles DELETE /api/v1/admin/block: lifts the block of blocked_user_id in chat_id
+// (the global block without it).
+func (a *AdminHandler) DeleteBlockedUser(w http.ResponseWriter, r *http.Request) {
+	var req blockRequest
+	userID, ok := a.decodeAdmin(w, r, "block_delete", &req)
+	if !ok {
+		return
+	}
+	if req.BlockedUserID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "blocked_user_id is required")
+		return
+	}
+	removed, err := a.db.UnblockUser(r.Context(), req.BlockedUserID, req.ChatID)
+	if err != nil {
+		slog.Error("unblock user failed", "chat_id", req.ChatID, "blocked_user_id", req.BlockedUserID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if !removed {
+		apierror.Write(w, r, apierror.NotFound, "block not found")
+		return
+	}
+	slog.Info("user unblocked", "chat_id", req.ChatID, "blocked_user_id", req.BlockedUserID, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
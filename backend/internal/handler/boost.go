@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+)
+
+// Rate limit boost bounds: one week at most, and factors up to 100× (0 lifts the limits).
+const (
+	defaultBoostMinutes = 60
+	maxBoostMinutes     = 7 * 24 * 60
+	maxBoostFactor      = 100
+)
+
+// boostRequest is the body shared by the rate limit boost endpoints. boost_user_id 0 (or
+// omitted) means the whole chat.
+type boostRequest struct {
+	ChatID      int64 `json:"chat_id"`
+	BoostUserID int64 `json:"boost_user_id"`
+	Minutes     int   `json:"minutes"`
+	Factor      int   `json:"factor"`
+}
+
+// decodeBoost decodes and checks a boost request; on failure it writes the error response.
+func (a *AdminHandler) decodeBoost(w http.ResponseWriter, r *http.Request, action string) (boostRequest, int64, bool) {
+	var req boostRequest
+	userID, ok := a.decodeAdmin(w, r, action, &req)
+	if !ok {
+		return req, 0, false
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return req, 0, false
+	}
+	return req, userID, true
+}
+
+// boostsAvailable writes 503 when there is no Redis to keep boosts in.
+func (a *AdminHandler) boostsAvailable(w http.ResponseWriter, r *http.Request) bool {
+	if a.cache == nil {
+		apierror.Write(w, r, apierror.Unavailable, "rate limit boosts are unavailable")
+		return false
+	}
+	return true
+}
+
+// GetRateBoost handles POST /api/v1/admin/rate_limit_boost: the active boosts of chat_id and,
+// with boost_user_id, of that user in it.
+func (a *AdminHandler) GetRateBoost(w http.ResponseWriter, r *http.Request) {
+	req, _, ok := a.decodeBoost(w, r, "rate_boost_get")
+	if !ok || !a.boostsAvailable(w, r) {
+		return
+	}
+	chat, user, err := a.cache.RateBoosts(r.Context(), req.ChatID, req.BoostUserID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "get rate boost failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]*cache.RateBoost{"chat": chat, "user": user})
+}
+
+// PutRateBoost handles PUT /api/v1/admin/rate_limit_boost: for the next minutes (default 60), the
+// chat and user limits of chat_id (or only the user limit of boost_user_id in it) are multiplied
+// by factor, or lifted with factor 0. A new boost replaces the old one.
+func (a *AdminHandler) PutRateBoost(w http.ResponseWriter, r *http.Request) {
+	req, userID, ok := a.decodeBoost(w, r, "rate_boost_put")
+	if !ok {
+		return
+	}
+	if req.Minutes == 0 {
+		req.Minutes = defaultBoostMinutes
+	}
+	if req.Minutes < 1 || req.Minutes > maxBoostMinutes {
+		apierror.Write(w, r, apierror.InvalidArgument, "minutes must be 1-10080")
+		return
+	}
+	if req.Factor == 1 || req.Factor < 0 || req.Factor > maxBoostFactor {
+		apierror.Write(w, r, apierror.InvalidArgument, "factor must be 0 (no limit) or 2-100")
+		return
+	}
+	if !a.boostsAvailable(w, r) {
+		return
+	}
+	b := &cache.RateBoost{
+		ChatID: req.ChatID, UserID: req.BoostUserID, Factor: req.Factor,
+		Until: time.Now().Add(time.Duration(req.Minutes) * time.Minute).UTC(), SetBy: userID,
+	}
+	if err := a.cache.SetRateBoost(r.Context(), b); err != nil {
+		slog.ErrorContext(r.Context(), "set rate boost failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.InfoContext(r.Context(), "rate limits boosted", "chat_id", req.ChatID, "boost_user_id", req.BoostUserID,
+		"factor", req.Factor, "until", b.Until, "user_id", userID)
+	writeJSON(w, b)
+}
+
+// DeleteRateBoost handles DELETE /api/v1/admin/rate_limit_boost: ends the boost of chat_id (or of
+// boost_user_id in it) early.
+func (a *AdminHandler) DeleteRateBoost(w http.ResponseWriter, r *http.Request) {
+	req, userID, ok := a.decodeBoost(w, r, "rate_boost_delete")
+	if !ok || !a.boostsAvailable(w, r) {
+		return
+	}
+	if err := a.cache.ClearRateBoost(r.Context(), req.ChatID, req.BoostUserID); err != nil {
+		slog.ErrorContext(r.Context(), "clear rate boost failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.InfoContext(r.Context(), "rate limit boost cleared", "chat_id", req.ChatID, "boost_user_id", req.BoostUserID, "user_id", userID)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
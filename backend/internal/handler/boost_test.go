@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin_RateBoost_Validation(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		handler http.HandlerFunc
+		body    string
+		want    int
+	}{
+		{a.PutRateBoost, `{"user_id": 222, "chat_id": -100}`, http.StatusForbidden},
+		{a.PutRateBoost, `{"user_id": 111}`, http.StatusBadRequest},
+		{a.GetRateBoost, `{"user_id": 111}`, http.StatusBadRequest},
+		{a.DeleteRateBoost, `{"user_id": 111}`, http.StatusBadRequest},
+		{a.PutRateBoost, `{"user_id": 111, "chat_id": -100, "minutes": 20000}`, http.StatusBadRequest},
+		{a.PutRateBoost, `{"user_id": 111, "chat_id": -100, "factor": 1}`, http.StatusBadRequest},
+		{a.PutRateBoost, `{"user_id": 111, "chat_id": -100, "factor": 101}`, http.StatusBadRequest},
+		{a.PutRateBoost, `{"user_id": 111, "chat_id": -100}`, http.StatusServiceUnavailable}, // no Redis
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		tt.handler(w, httptest.NewRequest("PUT", "/api/v1/admin/rate_limit_boost", strings.NewReader(tt.body)))
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.body, tt.want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// Reply variants (reply_variants.variant).
+const (
+	variantControl = "control"
+	variantCanary  = "canary"
+)
+
+// replyVariant is the model and temperature one reply is generated with.
+type replyVariant struct {
+	Name        string
+	Model       string
+	Temperature *float64 // nil = the chat's temperature
+}
+
+// pickVariant routes a reply to the canary when its chat is in CANARY_CHAT_IDS or roll (in [0, 1))
+// falls within CANARY_PERCENT; everything else is the control.
+func pickVariant(cfg *config.Config, chatID int64, roll float64) replyVariant {
+	control := replyVariant{Name: variantControl, Model: cfg.GeminiModel}
+	if !cfg.CanaryEnabled() {
+		return control
+	}
+	if !slices.Contains(cfg.CanaryChatIDs, chatID) && roll*100 >= float64(cfg.CanaryPercent) {
+		return control
+	}
+	v := replyVariant{Name: variantCanary, Model: cfg.GeminiModel}
+	if cfg.CanaryModel != "" {
+		v.Model = cfg.CanaryModel
+	}
+	if cfg.CanaryTemperature >= 0 {
+		t := cfg.CanaryTemperature
+		v.Temperature = &t
+	}
+	return v
+}
+
+// recordVariant stores which variant generated a reply (best effort; only while a canary is set).
+func (h *Handler) recordVariant(ctx context.Context, v *db.ReplyVariant) {
+	if !h.config.CanaryEnabled() || v.RequestID == "" {
+		return
+	}
+	if err := h.db.RecordReplyVariant(ctx, v); err != nil {
+		slog.WarnContext(ctx, "record reply variant failed", "error", err)
+	}
+}
+
+// Canary handles POST /api/v1/admin/canary: latency, reply length, tool use and token numbers
+// per variant for the last days (default 7), next to the current canary settings.
+func (a *AdminHandler) Canary(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Days int `json:"days"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "canary", &req); !ok {
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 1 || req.Days > maxReportDays {
+		apierror.Write(w, r, apierror.InvalidArgument, "days must be 1-90")
+		return
+	}
+	until := time.Now()
+	stats, err := a.db.CompareReplyVariants(r.Context(), until.AddDate(0, 0, -req.Days), until)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "compare reply variants failed", "days", req.Days, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	canary := map[string]any{
+		"enabled":  a.config.CanaryEnabled(),
+		"model":    a.config.CanaryModel,
+		"percent":  a.config.CanaryPercent,
+		"chat_ids": a.config.CanaryChatIDs,
+	}
+	if a.config.CanaryTemperature >= 0 {
+		canary["temperature"] = a.config.CanaryTemperature
+	}
+	writeJSON(w, map[string]any{"days": req.Days, "canary": canary, "variants": stats})
+}
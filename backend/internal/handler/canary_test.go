@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+func TestPickVariant(t *testing.T) {
+	base := config.Config{GeminiModel: "flash", CanaryTemperature: -1}
+	tests := []struct {
+		name      string
+		model     string
+		temp      float64
+		percent   int
+		chats     []int64
+		chatID    int64
+		roll      float64
+		want      string
+		wantModel string
+		wantTemp  bool
+	}{
+		{"disabled", "", -1, 50, nil, 1, 0.1, variantControl, "flash", false},
+		{"nothing to vary", "", -1, 0, []int64{1}, 1, 0.1, variantControl, "flash", false},
+		{"within percent", "pro", -1, 10, nil, 1, 0.05, variantCanary, "pro", false},
+		{"outside percent", "pro", -1, 10, nil, 1, 0.10, variantControl, "flash", false},
+		{"canary chat", "pro", -1, 0, []int64{7}, 7, 0.99, variantCanary, "pro", false},
+		{"other chat", "pro", -1, 0, []int64{7}, 8, 0.0, variantControl, "flash", false},
+		{"temperature only", "", 0.3, 100, nil, 1, 0.5, variantCanary, "flash", true},
+	}
+	for _, tt := range tests {
+		cfg := base
+		cfg.CanaryModel, cfg.CanaryTemperature, cfg.CanaryPercent, cfg.CanaryChatIDs = tt.model, tt.temp, tt.percent, tt.chats
+		v := pickVariant(&cfg, tt.chatID, tt.roll)
+		if v.Name != tt.want || v.Model != tt.wantModel || (v.Temperature != nil) != tt.wantTemp {
+			t.Errorf("%s: got %+v", tt.name, v)
+		}
+		if tt.wantTemp && *v.Temperature != tt.temp {
+			t.Errorf("%s: temperature %v, want %v", tt.name, *v.Temperature, tt.temp)
+		}
+	}
+}
+
+func TestAdmin_Canary_Validation(t *testing.T) {
+	a := newTestAdmin()
+	for body, want := range map[string]int{
+		`{"user_id": 222}`:             http.StatusForbidden,
+		`{"user_id": 111, "days": -1}`: http.StatusBadRequest,
+		`{"user_id": 111, "days": 91}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/canary", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.Canary(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	rpprof "runtime/pprof"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+)
+
+// maxGoroutineDump caps the stack dump returned by Debug; a leak can mean millions of goroutines.
+const maxGoroutineDump = 4 << 20
+
+// maxGCPauses is how many of the most recent GC pauses Debug returns.
+const maxGCPauses = 16
+
+// Debug handles POST /api/v1/admin/debug: the runtime state for chasing leaks in production —
+// a dump of every goroutine's stack, GC and heap statistics, and the configuration with secrets
+// masked. Body {"user_id", "stacks"}; stacks=false leaves the dump out.
+func (a *AdminHandler) Debug(w http.ResponseWriter, r *http.Request) {
+	req := struct {
+		Stacks *bool `json:"stacks"`
+	}{}
+	userID, ok := a.decodeAdmin(w, r, "debug", &req)
+	if !ok {
+		return
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	gc := debug.GCStats{PauseQuantiles: make([]time.Duration, 5)} // min, 25%, 50%, 75%, max
+	debug.ReadGCStats(&gc)
+	pauses := gc.Pause
+	if len(pauses) > maxGCPauses {
+		pauses = pauses[:maxGCPauses]
+	}
+	recent := make([]float64, len(pauses))
+	for i, p := range pauses {
+		recent[i] = float64(p.Microseconds()) / 1000
+	}
+
+	resp := map[string]any{
+		"uptime_seconds": time.Since(a.startTime).Seconds(),
+		"go_version":     runtime.Version(),
+		"num_cpu":        runtime.NumCPU(),
+		"gomaxprocs":     runtime.GOMAXPROCS(0),
+		"goroutines":     runtime.NumGoroutine(),
+		"memory": map[string]any{
+			"heap_alloc_bytes":    m.HeapAlloc,
+			"heap_inuse_bytes":    m.HeapInuse,
+			"heap_idle_bytes":     m.HeapIdle,
+			"heap_released_bytes": m.HeapReleased,
+			"heap_objects":        m.HeapObjects,
+			"stack_inuse_bytes":   m.StackInuse,
+			"sys_bytes":           m.Sys,
+			"total_alloc_bytes":   m.TotalAlloc,
+			"mallocs":             m.Mallocs,
+			"frees":               m.Frees,
+			"next_gc_bytes":       m.NextGC,
+			"gc_cpu_fraction":     m.GCCPUFraction,
+			"memory_limit_bytes":  debug.SetMemoryLimit(-1),
+		},
+		"gc": map[string]any{
+			"num_gc":             gc.NumGC,
+			"last_gc":            gc.LastGC,
+			"pause_total_ms":     float64(gc.PauseTotal.Microseconds()) / 1000,
+			"recent_pauses_ms":   recent, // newest first
+			"pause_quantiles_ms": durationsMS(gc.PauseQuantiles),
+		},
+		"config": a.config.Redacted(),
+	}
+	if req.Stacks == nil || *req.Stacks {
+		dump, truncated := goroutineDump()
+		resp["goroutine_dump"] = dump
+		resp["goroutine_dump_truncated"] = truncated
+	}
+	slog.InfoContext(r.Context(), "runtime debug snapshot", "user_id", userID, "goroutines", runtime.NumGoroutine())
+	writeJSON(w, resp)
+}
+
+func durationsMS(ds []time.Duration) []float64 {
+	out := make([]float64, len(ds))
+	for i, d := range ds {
+		out[i] = float64(d.Microseconds()) / 1000
+	}
+	return out
+}
+
+// goroutineDump returns every goroutine's stack, as a panic prints them, cut at maxGoroutineDump.
+func goroutineDump() (string, bool) {
+	var buf bytes.Buffer
+	rpprof.Lookup("goroutine").WriteTo(&buf, 2)
+	if buf.Len() > maxGoroutineDump {
+		return string(buf.Bytes()[:maxGoroutineDump]), true
+	}
+	return buf.String(), false
+}
+
+// Pprof serves net/http/pprof under /debug/pprof/ (ENABLE_PPROF). The API auth middleware guards
+// the path like /api/, and the user_id query parameter must be an admin, e.g.
+// /debug/pprof/heap?user_id=123 or /debug/pprof/profile?seconds=30&user_id=123.
+func (a *AdminHandler) Pprof(w http.ResponseWriter, r *http.Request) {
+	userID, _ := strconv.ParseInt(r.URL.Query().Get("user_id"), 10, 64)
+	if !a.isAdmin(userID) {
+		slog.WarnContext(r.Context(), "unauthorized admin access attempt", "action", "pprof", "user_id", userID)
+		apierror.Write(w, r, apierror.Forbidden, "unauthorized")
+		return
+	}
+	switch strings.TrimPrefix(r.URL.Path, "/debug/pprof/") {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default: // the index, and named profiles (heap, goroutine, allocs, block, mutex, ...)
+		pprof.Index(w, r)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+func TestAdmin_Debug(t *testing.T) {
+	a := NewAdminHandler(&config.Config{AdminIDs: []int64{111}, GeminiAPIKey: "secret-key"}, nil, nil, nil)
+
+	w := httptest.NewRecorder()
+	a.Debug(w, httptest.NewRequest("POST", "/api/v1/admin/debug", strings.NewReader(`{"user_id": 111}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "secret-key") {
+		t.Error("debug snapshot leaks a secret")
+	}
+	var got struct {
+		Goroutines int            `json:"goroutines"`
+		Dump       string         `json:"goroutine_dump"`
+		GC         map[string]any `json:"gc"`
+		Memory     map[string]any `json:"memory"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Goroutines == 0 || !strings.Contains(got.Dump, "TestAdmin_Debug") || got.GC["num_gc"] == nil || got.Memory["heap_alloc_bytes"] == nil {
+		t.Errorf("incomplete snapshot: %s", w.Body.String()[:min(w.Body.Len(), 500)])
+	}
+
+	w = httptest.NewRecorder()
+	a.Debug(w, httptest.NewRequest("POST", "/api/v1/admin/debug", strings.NewReader(`{"user_id": 111, "stacks": false}`)))
+	if strings.Contains(w.Body.String(), "goroutine_dump") {
+		t.Error("stacks=false should leave the dump out")
+	}
+}
+
+func TestAdmin_Pprof(t *testing.T) {
+	a := newTestAdmin()
+	tests := []struct {
+		url  string
+		want int
+	}{
+		{"/debug/pprof/", http.StatusForbidden},
+		{"/debug/pprof/heap?user_id=222", http.StatusForbidden},
+		{"/debug/pprof/?user_id=111", http.StatusOK},
+		{"/debug/pprof/goroutine?debug=1&user_id=111", http.StatusOK},
+		{"/debug/pprof/cmdline?user_id=111", http.StatusOK},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		a.Pprof(w, httptest.NewRequest("GET", tt.url, nil))
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.url, tt.want, w.Code)
+		}
+	}
+}
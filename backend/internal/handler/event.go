@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// Event types accepted by the event endpoint.
+const (
+	EventPoll       = "poll"        // a poll message in a chat, or a poll state update (chat_id omitted)
+	EventPollAnswer = "poll_answer" // a user voted or retracted a vote in a non-anonymous poll
+	EventKarma      = "karma"       // a user reacted to a message or replied "+"/"-" to it (ENABLE_KARMA)
+)
+
+// EventRequest is a non-message Telegram update forwarded by the frontend.
+// Events are stored for context only; they never produce a reply.
+type EventRequest struct {
+	Type       string             `json:"type"`
+	ChatID     int64              `json:"chat_id,omitempty"`
+	MessageID  int64              `json:"message_id,omitempty"`
+	UserID     int64              `json:"user_id,omitempty"`
+	Username   string             `json:"username,omitempty"`
+	FirstName  string             `json:"first_name,omitempty"`
+	Poll       *PollPayload       `json:"poll,omitempty"`
+	PollAnswer *PollAnswerPayload `json:"poll_answer,omitempty"`
+	Karma      *KarmaPayload      `json:"karma,omitempty"`
+}
+
+// PollPayload mirrors the Telegram Poll object.
+type PollPayload struct {
+	ID              string   `json:"id"`
+	Question        string   `json:"question"`
+	Options         []string `json:"options"`
+	OptionVotes     []int64  `json:"option_votes,omitempty"`
+	TotalVoterCount int      `json:"total_voter_count"`
+	IsAnonymous     bool     `json:"is_anonymous"`
+	IsClosed        bool     `json:"is_closed"`
+}
+
+// PollAnswerPayload mirrors the Telegram PollAnswer object; empty option_ids is a retracted vote.
+type PollAnswerPayload struct {
+	PollID    string  `json:"poll_id"`
+	OptionIDs []int64 `json:"option_ids"`
+}
+
+// KarmaPayload is a vote by user_id on the message message_id. Delta is -1, 0 (reaction removed) or 1.
+type KarmaPayload struct {
+	Source       string `json:"source"` // "reaction" or "reply"
+	Delta        int    `json:"delta"`
+	TargetUserID int64  `json:"target_user_id,omitempty"` // author, when the frontend knows it
+}
+
+// Event ingests non-message updates (polls, poll answers and karma votes).
+// POST /api/v1/event — 200 {"status":"ok"}, 400 on invalid payload, 404 if the referenced poll is unknown.
+func (h *Handler) Event(w http.ResponseWriter, r *http.Request) {
+	ctx := logging.WithRequestID(r.Context(), r.Header.Get("X-Request-ID"))
+
+	var req EventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return
+	}
+	defer r.Body.Close()
+
+	if req.ChatID != 0 {
+		ctx = logging.WithChat(ctx, req.ChatID, req.UserID)
+	} else if req.UserID != 0 {
+		ctx = logging.With(ctx, logging.KeyUserID, req.UserID)
+	}
+	switch req.Type {
+	case EventPoll:
+		p := req.Poll
+		if p == nil || p.ID == "" {
+			apierror.Write(w, r, apierror.InvalidArgument, "poll.id is required")
+			return
+		}
+		if req.ChatID == 0 {
+			// State update: Telegram does not say which chat the poll belongs to.
+			n, err := h.db.UpdatePollState(ctx, p.ID, p.OptionVotes, p.TotalVoterCount, p.IsClosed)
+			if err != nil {
+				slog.ErrorContext(ctx, "poll state update failed", "poll_id", p.ID, "error", err)
+				apierror.Write(w, r, apierror.Internal, "internal error")
+				return
+			}
+			if n == 0 {
+				apierror.Write(w, r, apierror.NotFound, "poll not found")
+				return
+			}
+			slog.InfoContext(ctx, "poll state updated", "poll_id", p.ID, "total_voters", p.TotalVoterCount, "closed", p.IsClosed)
+			break
+		}
+		if p.Question == "" || len(p.Options) == 0 {
+			apierror.Write(w, r, apierror.InvalidArgument, "poll.question and poll.options are required")
+			return
+		}
+		poll := &db.Poll{
+			PollID:      p.ID,
+			ChatID:      req.ChatID,
+			MessageID:   int64Ptr(req.MessageID),
+			Question:    p.Question,
+			Options:     p.Options,
+			OptionVotes: p.OptionVotes,
+			TotalVoters: p.TotalVoterCount,
+			IsAnonymous: p.IsAnonymous,
+			IsClosed:    p.IsClosed,
+			CreatedBy:   int64Ptr(req.UserID),
+		}
+		if err := h.db.UpsertPoll(ctx, poll); err != nil {
+			slog.ErrorContext(ctx, "store poll failed", "poll_id", p.ID, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		slog.InfoContext(ctx, "poll stored", "poll_id", p.ID, "options", len(p.Options))
+
+	case EventPollAnswer:
+		a := req.PollAnswer
+		if a == nil || a.PollID == "" || req.UserID == 0 {
+			apierror.Write(w, r, apierror.InvalidArgument, "poll_answer.poll_id and user_id are required")
+			return
+		}
+		found, err := h.db.RecordPollAnswer(ctx, &db.PollAnswer{
+			PollID:    a.PollID,
+			UserID:    req.UserID,
+			Username:  strPtr(req.Username),
+			FirstName: strPtr(req.FirstName),
+			OptionIDs: a.OptionIDs,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "store poll answer failed", "poll_id", a.PollID, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		if !found {
+			apierror.Write(w, r, apierror.NotFound, "poll not found")
+			return
+		}
+		slog.InfoContext(ctx, "poll answer stored", "poll_id", a.PollID, "retracted", len(a.OptionIDs) == 0)
+
+	case EventKarma:
+		k := req.Karma
+		if k == nil || req.ChatID == 0 || req.MessageID == 0 || req.UserID == 0 {
+			apierror.Write(w, r, apierror.InvalidArgument, "karma, chat_id, message_id and user_id are required")
+			return
+		}
+		if !db.ValidKarmaSource(k.Source) || k.Delta < -1 || k.Delta > 1 {
+			apierror.Write(w, r, apierror.InvalidArgument, "karma.source must be reaction or reply and karma.delta -1, 0 or 1")
+			return
+		}
+		if !h.config.EnableKarma {
+			writeJSON(w, map[string]string{"status": "ignored"})
+			return
+		}
+		applied, err := h.db.ApplyKarmaVote(ctx, db.KarmaVote{
+			ChatID:       req.ChatID,
+			MessageID:    req.MessageID,
+			VoterID:      req.UserID,
+			Source:       k.Source,
+			TargetUserID: k.TargetUserID,
+			Delta:        k.Delta,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "store karma vote failed", "message_id", req.MessageID, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		if !applied {
+			writeJSON(w, map[string]string{"status": "ignored"})
+			return
+		}
+		slog.InfoContext(ctx, "karma vote stored", "message_id", req.MessageID, "source", k.Source, "delta", k.Delta)
+
+	default:
+		apierror.Write(w, r, apierror.InvalidArgument, "unknown event type")
+		return
+	}
+
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// int64Ptr returns nil for zero, matching strPtr.
+func int64Ptr(v int64) *int64 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+func TestEvent_Validation(t *testing.T) {
+	h := &Handler{}
+
+	// All of these are rejected before touching the database
+	bodies := []string{
+		"not json",
+		`{"type":"reaction"}`,
+		`{"type":"poll","chat_id":5}`,
+		`{"type":"poll","chat_id":5,"poll":{"id":"p1","question":"","options":[]}}`,
+		`{"type":"poll_answer","poll_answer":{"poll_id":"p1","option_ids":[0]}}`,
+		`{"type":"karma","chat_id":5,"user_id":7,"karma":{"source":"reply","delta":1}}`,
+		`{"type":"karma","chat_id":5,"message_id":9,"user_id":7,"karma":{"source":"sticker","delta":1}}`,
+		`{"type":"karma","chat_id":5,"message_id":9,"user_id":7,"karma":{"source":"reaction","delta":5}}`,
+	}
+	for _, body := range bodies {
+		req := httptest.NewRequest("POST", "/api/v1/event", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.Event(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("expected 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestEvent_KarmaDisabled(t *testing.T) {
+	h := &Handler{config: &config.Config{}}
+	body := `{"type":"karma","chat_id":5,"message_id":9,"user_id":7,"karma":{"source":"reaction","delta":1}}`
+	req := httptest.NewRequest("POST", "/api/v1/event", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.Event(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ignored") {
+		t.Errorf("expected 200 ignored with karma disabled, got %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+// followUpTimeout bounds the background extraction of one reply's promises.
+const followUpTimeout = 30 * time.Second
+
+// trackCommitments stores the promises the bot made in a reply as follow-ups, for a proactive
+// turn to bring up once due. It runs after the reply is sent and only for replies with wording
+// of a promise (llm.MayContainCommitment). In privacy mode the model reads both with pseudonyms.
+func (h *Handler) trackCommitments(ctx context.Context, req *ProcessRequest, requestID, reply string, loc *time.Location, privacy *llm.Privacy) {
+	if h.llm == nil || !h.config.FollowUpsEnabled() || !llm.MayContainCommitment(reply) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), followUpTimeout)
+		defer cancel()
+		commitments, err := h.llm.ExtractCommitments(ctx, privacy.Hide(req.Text), privacy.Hide(reply), time.Now().In(loc))
+		if err != nil {
+			slog.WarnContext(ctx, "extract commitments failed", "error", err)
+			return
+		}
+		for _, c := range commitments {
+			f := &db.FollowUp{ChatID: req.ChatID, UserID: req.UserID, RequestID: &requestID, Text: c.Text, DueAt: c.DueAt}
+			if _, err := h.db.InsertFollowUp(ctx, f); err != nil {
+				slog.WarnContext(ctx, "store follow-up failed", "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "follow-up stored", "due_at", c.DueAt)
+		}
+	}()
+}
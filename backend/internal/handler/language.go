@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+// pickUserLanguage decides a user's language preference: the language they are writing in right now
+// when detection is confident, else their stored preference, else Telegram's language_code.
+func pickUserLanguage(detected string, detectedOK bool, stored, telegramCode string) string {
+	if detectedOK {
+		return detected
+	}
+	if stored != "" {
+		return stored
+	}
+	return i18n.Normalize(telegramCode)
+}
+
+// loadUserSettings returns the sender's stored preferences, or nil when there are none or they
+// could not be loaded (best effort).
+func (h *Handler) loadUserSettings(ctx context.Context, userID int64) *db.UserSettings {
+	if userID == 0 || h.db == nil {
+		return nil
+	}
+	us, err := h.db.GetUserSettings(ctx, userID)
+	if err != nil {
+		slog.WarnContext(ctx, "load user settings failed", "error", err)
+		return nil
+	}
+	return us
+}
+
+// resolveReplyLanguage returns the language to answer this message in and persists the user's
+// preference when it changed. Falls back to the chat's language when nothing is known about the user.
+func (h *Handler) resolveReplyLanguage(ctx context.Context, userID int64, us *db.UserSettings, text, telegramCode, chatLang string) string {
+	detected, ok := i18n.Detect(text)
+	if userID == 0 || h.db == nil {
+		if pref := pickUserLanguage(detected, ok, "", telegramCode); pref != "" {
+			return pref
+		}
+		return chatLang
+	}
+
+	stored := ""
+	if us != nil && us.Language != nil {
+		stored = *us.Language
+	}
+
+	pref := pickUserLanguage(detected, ok, stored, telegramCode)
+	if pref == "" {
+		return chatLang
+	}
+	if pref != stored {
+		if err := h.db.SetUserLanguage(ctx, userID, pref); err != nil {
+			slog.WarnContext(ctx, "store user language failed", "error", err)
+		}
+	}
+	return pref
+}
@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+)
+
+// mentionCounterTTL keeps a day's counter slightly longer than a day so it never expires mid-day.
+const mentionCounterTTL = 26 * time.Hour
+
+// randFloat is the source for the mention reply roll (replaced in tests).
+var randFloat = rand.Float64
+
+// kyivLocation is used to bucket daily caps by the chat's local day.
+var kyivLocation = func() *time.Location {
+	for _, name := range []string{"Europe/Kyiv", "Europe/Kiev"} {
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}()
+
+// mentionsBotName reports whether text contains a word starting with one of the bot's name stems,
+// so inflected forms ("гряже", "грягу") match but names embedded mid-word do not.
+func mentionsBotName(text string, names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		for _, n := range names {
+			if n != "" && strings.HasPrefix(w, strings.ToLower(n)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func mentionCounterKey(chatID int64, now time.Time) string {
+	return fmt.Sprintf("mention_replies:%d:%s", chatID, now.In(kyivLocation).Format("2006-01-02"))
+}
+
+// allowIndirectReply decides whether to answer a message that does not address the bot (a name
+// mention or a random interjection): a roll against probability, bounded by the chat's daily cap.
+// The counter is only incremented for replies that pass, so the cap counts actual replies.
+func (h *Handler) allowIndirectReply(ctx context.Context, s *chatsettings.Settings, probability float64) bool {
+	if probability <= 0 {
+		return false
+	}
+	key := mentionCounterKey(s.ChatID, time.Now())
+	if s.MentionDailyCap > 0 && h.cache != nil {
+		n, err := h.cache.GetCount(ctx, key)
+		if err != nil {
+			slog.WarnContext(ctx, "mention counter read failed", "error", err)
+			return false // fail closed: an indirect mention never needs an answer
+		}
+		if n >= int64(s.MentionDailyCap) {
+			return false
+		}
+	}
+	if randFloat() >= probability {
+		return false
+	}
+	if h.cache != nil {
+		if _, err := h.cache.IncrCount(ctx, key, mentionCounterTTL); err != nil {
+			slog.WarnContext(ctx, "mention counter increment failed", "error", err)
+		}
+	}
+	return true
+}
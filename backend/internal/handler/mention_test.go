@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+)
+
+func TestMentionsBotName(t *testing.T) {
+	names := []string{"гряг", "гряж", "gryag"}
+	tests := map[string]bool{
+		"а що гряг думає?":      true,
+		"Гряже, скажи":          true,
+		"спитайте в грягу":      true,
+		"GRYAG is down again":   true,
+		"звичайне повідомлення": false,
+		"багрягний захід":       false, // stem inside another word
+		"":                      false,
+	}
+	for text, want := range tests {
+		if got := mentionsBotName(text, names); got != want {
+			t.Errorf("mentionsBotName(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestAllowMentionReply_Probability(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+
+	h := &Handler{}
+	s := &chatsettings.Settings{ChatID: 1, MentionReplyProbability: 0.3}
+
+	randFloat = func() float64 { return 0.1 }
+	if !h.allowIndirectReply(context.Background(), s, s.MentionReplyProbability) {
+		t.Error("expected reply when roll is under the probability")
+	}
+	randFloat = func() float64 { return 0.5 }
+	if h.allowIndirectReply(context.Background(), s, s.MentionReplyProbability) {
+		t.Error("expected silence when roll is over the probability")
+	}
+	s.MentionReplyProbability = 0
+	randFloat = func() float64 { return 0 }
+	if h.allowIndirectReply(context.Background(), s, s.MentionReplyProbability) {
+		t.Error("expected silence when probability is 0")
+	}
+}
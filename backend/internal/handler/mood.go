@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
+)
+
+// trackMood scores an incoming message's tone onto msg (stored with it) and folds it into the
+// chat's rolling mood. Messages with nothing to score, like uncaptioned photos, are left alone.
+func (h *Handler) trackMood(ctx context.Context, msg *db.Message) {
+	if !h.config.EnableMoodTracking {
+		return
+	}
+	text := ""
+	if msg.Text != nil {
+		text = *msg.Text
+	}
+	if msg.StickerEmoji != nil {
+		text += " " + *msg.StickerEmoji
+	}
+	if text == "" {
+		return
+	}
+	s := sentiment.Analyze(text)
+	msg.SentimentValence, msg.SentimentHeat = &s.Valence, &s.Heat
+	if err := sentiment.Record(ctx, h.cache, msg.ChatID, s, time.Now()); err != nil {
+		slog.WarnContext(ctx, "record chat mood failed", "error", err)
+	}
+}
+
+// chatMood returns the chat's current mood for the instructions; nil when mood tracking is off
+// or it can't be read.
+func (h *Handler) chatMood(ctx context.Context, chatID int64) *sentiment.Mood {
+	if !h.config.EnableMoodTracking {
+		return nil
+	}
+	m, err := sentiment.Load(ctx, h.cache, chatID, time.Now())
+	if err != nil {
+		slog.WarnContext(ctx, "load chat mood failed", "error", err)
+		return nil
+	}
+	return &m
+}
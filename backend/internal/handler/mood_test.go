@@ -0,0 +1,33 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestTrackMood_TagsMessage(t *testing.T) {
+	text := "ти ідіот!!"
+	msg := &db.Message{ChatID: 1, Text: &text}
+	(&Handler{config: &config.Config{}}).trackMood(context.Background(), msg)
+	if msg.SentimentValence != nil {
+		t.Error("messages should not be scored with mood tracking off")
+	}
+
+	h := &Handler{config: &config.Config{EnableMoodTracking: true}}
+	h.trackMood(context.Background(), msg)
+	if msg.SentimentValence == nil || msg.SentimentHeat == nil {
+		t.Fatal("expected the message to be scored")
+	}
+	if *msg.SentimentValence >= 0 || *msg.SentimentHeat == 0 {
+		t.Errorf("expected a negative, heated score, got %v / %v", *msg.SentimentValence, *msg.SentimentHeat)
+	}
+
+	photo := &db.Message{ChatID: 1}
+	h.trackMood(context.Background(), photo)
+	if photo.SentimentValence != nil {
+		t.Error("a message without text or sticker has nothing to score")
+	}
+}
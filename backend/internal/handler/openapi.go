@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/ThatHunky/gryag/backend/internal/apispec"
+)
+
+// openAPIDocument is the encoded OpenAPI document; the operation table is fixed at build time.
+var openAPIDocument = sync.OnceValue(func() []byte {
+	b, err := json.Marshal(apispec.Document())
+	if err != nil {
+		panic("encode openapi document: " + err.Error())
+	}
+	return b
+})
+
+// OpenAPI serves the OpenAPI 3 description of the API.
+// GET /api/v1/openapi.json
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDocument())
+}
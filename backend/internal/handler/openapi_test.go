@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/apispec"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// TestOpenAPI_SchemasMatchPayloads keeps the documented request schemas in step with the
+// payload structs: every JSON field a handler decodes must be described.
+func TestOpenAPI_SchemasMatchPayloads(t *testing.T) {
+	tests := []struct {
+		method, path string
+		payload      any
+		skip         []string
+	}{
+		{http.MethodPost, "/api/v1/process", ProcessRequest{}, nil},
+		{http.MethodPost, "/api/v1/ack_reply", AckReplyRequest{}, nil},
+		{http.MethodPost, "/api/v1/event", EventRequest{}, nil},
+		{http.MethodPost, "/api/v1/proactive/ack", ProactiveAckRequest{}, nil},
+		{http.MethodPut, "/api/v1/admin/chat_settings", db.ChatSettings{}, []string{"updated_at"}},
+		{http.MethodPut, "/api/v1/admin/block", blockRequest{}, nil},
+	}
+	for _, tt := range tests {
+		op := apispec.Find(tt.method, tt.path)
+		if op == nil || op.Request == nil {
+			t.Errorf("%s %s: no documented body", tt.method, tt.path)
+			continue
+		}
+		typ := reflect.TypeOf(tt.payload)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" || slices.Contains(tt.skip, name) {
+				continue
+			}
+			if op.Request.Properties[name] == nil {
+				t.Errorf("%s %s: field %s is not in the schema", tt.method, tt.path, name)
+			}
+		}
+	}
+}
+
+// TestOpenAPI_ResponsesMatch does the same for the process responses.
+func TestOpenAPI_ResponsesMatch(t *testing.T) {
+	for path, payload := range map[string]any{
+		"/api/v1/process": ProcessResponse{},
+		"/api/v2/process": ProcessResponseV2{},
+	} {
+		op := apispec.Find(http.MethodPost, path)
+		typ := reflect.TypeOf(payload)
+		for i := 0; i < typ.NumField(); i++ {
+			name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			if name == "" || name == "-" {
+				continue
+			}
+			if op.Response.Properties[name] == nil {
+				t.Errorf("%s: response field %s is not in the schema", path, name)
+			}
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	w := httptest.NewRecorder()
+	OpenAPI(w, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc["openapi"] == nil || doc["paths"] == nil || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected document: %.200s", w.Body.String())
+	}
+}
@@ -0,0 +1,165 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// ProactiveCommand handles the /proactive chat command: shows or changes the chat's proactive
+// message settings. Anyone may view them; changes need a chat admin (checked by the frontend via
+// Telegram), a bot admin, or a private chat.
+// POST /api/v1/proactive/settings — {"chat_id": ..., "user_id": ..., "is_chat_admin": true, "args": "quiet 23-8"}
+func (a *AdminHandler) ProactiveCommand(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID      int64  `json:"chat_id"`
+		UserID      int64  `json:"user_id"`
+		IsChatAdmin bool   `json:"is_chat_admin"`
+		Args        string `json:"args"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return
+	}
+	ctx := r.Context()
+	overrides, err := a.settings.Overrides(ctx, req.ChatID)
+	if err != nil {
+		slog.Error("get chat settings failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	lang := chatsettings.Resolve(a.config, req.ChatID, overrides).Language
+
+	args := strings.TrimSpace(req.Args)
+	if args != "" && !strings.EqualFold(args, "status") {
+		if !req.IsChatAdmin && !a.isAdmin(req.UserID) && req.ChatID != req.UserID {
+			writeJSON(w, map[string]string{"reply": a.t(lang, "proactive.forbidden")})
+			return
+		}
+		if overrides == nil {
+			overrides = &db.ChatSettings{ChatID: req.ChatID}
+		}
+		if !applyProactiveArgs(overrides, args) {
+			writeJSON(w, map[string]string{"reply": a.t(lang, "proactive.usage")})
+			return
+		}
+		if err := chatsettings.Validate(overrides); err != nil {
+			writeJSON(w, map[string]string{"reply": a.t(lang, "proactive.invalid", err.Error())})
+			return
+		}
+		if err := a.settings.Save(ctx, overrides); err != nil {
+			slog.Error("save chat settings failed", "chat_id", req.ChatID, "error", err)
+			apierror.Write(w, r, apierror.Internal, "internal error")
+			return
+		}
+		slog.Info("proactive settings updated", "chat_id", req.ChatID, "user_id", req.UserID, "args", args)
+	}
+	writeJSON(w, map[string]string{"reply": a.proactiveStatus(lang, chatsettings.Resolve(a.config, req.ChatID, overrides))})
+}
+
+// applyProactiveArgs applies one /proactive subcommand to the overrides. Returns false when the
+// arguments don't parse.
+//
+//	on | off
+//	interval <min> [max]   minutes, 0 = no limit; "interval off" clears both
+//	quiet <start>-<end>    hours in the chat's timezone; "quiet off" clears them
+//	tz <Area/City>
+func applyProactiveArgs(o *db.ChatSettings, args string) bool {
+	fields := strings.Fields(strings.ToLower(args))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "on", "off":
+		if len(fields) != 1 {
+			return false
+		}
+		on := fields[0] == "on"
+		o.ProactiveEnabled = &on
+	case "interval":
+		if len(fields) == 2 && fields[1] == "off" {
+			o.ProactiveMinIntervalMinutes, o.ProactiveMaxIntervalMinutes = nil, nil
+			return true
+		}
+		if len(fields) < 2 || len(fields) > 3 {
+			return false
+		}
+		minutes := make([]int, 0, 2)
+		for _, f := range fields[1:] {
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return false
+			}
+			minutes = append(minutes, n)
+		}
+		o.ProactiveMinIntervalMinutes = &minutes[0]
+		if len(minutes) == 2 {
+			o.ProactiveMaxIntervalMinutes = &minutes[1]
+		}
+	case "quiet":
+		if len(fields) != 2 {
+			return false
+		}
+		if fields[1] == "off" {
+			o.ProactiveQuietStart, o.ProactiveQuietEnd = nil, nil
+			return true
+		}
+		startStr, endStr, ok := strings.Cut(fields[1], "-")
+		if !ok {
+			return false
+		}
+		start, err1 := strconv.Atoi(startStr)
+		end, err2 := strconv.Atoi(endStr)
+		if err1 != nil || err2 != nil {
+			return false
+		}
+		o.ProactiveQuietStart, o.ProactiveQuietEnd = &start, &end
+	case "tz":
+		// IANA names are case-sensitive, so take the original spelling
+		orig := strings.Fields(args)
+		if len(orig) != 2 {
+			return false
+		}
+		o.Timezone = &orig[1]
+	default:
+		return false
+	}
+	return true
+}
+
+// proactiveStatus renders the chat's effective proactive settings.
+func (a *AdminHandler) proactiveStatus(lang string, s *chatsettings.Settings) string {
+	state := a.t(lang, "proactive.off")
+	if s.ProactiveEnabled {
+		state = a.t(lang, "proactive.on")
+	}
+	minutes := func(n int) string {
+		if n == 0 {
+			return a.t(lang, "proactive.none")
+		}
+		if a.i18n == nil {
+			return "proactive.minutes"
+		}
+		return a.i18n.TN(lang, "proactive.minutes", n)
+	}
+	quiet := a.t(lang, "proactive.none")
+	if s.ProactiveQuietStart != s.ProactiveQuietEnd {
+		quiet = fmt.Sprintf("%02d:00–%02d:00", s.ProactiveQuietStart, s.ProactiveQuietEnd)
+	}
+	return a.t(lang, "proactive.status", state, minutes(s.ProactiveMinIntervalMinutes), minutes(s.ProactiveMaxIntervalMinutes), quiet, s.Timezone)
+}
+
+// t translates key for lang; without a bundle it returns the key.
+func (a *AdminHandler) t(lang, key string, args ...string) string {
+	if a.i18n == nil {
+		return key
+	}
+	return a.i18n.T(lang, key, args...)
+}
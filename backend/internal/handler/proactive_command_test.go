@@ -0,0 +1,67 @@
+package handler
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+func TestApplyProactiveArgs(t *testing.T) {
+	o := &db.ChatSettings{ChatID: 5}
+	for _, args := range []string{"off", "interval 60 240", "quiet 23-8", "tz Europe/Warsaw"} {
+		if !applyProactiveArgs(o, args) {
+			t.Fatalf("%q should parse", args)
+		}
+	}
+	if o.ProactiveEnabled == nil || *o.ProactiveEnabled {
+		t.Error("expected proactive off")
+	}
+	if *o.ProactiveMinIntervalMinutes != 60 || *o.ProactiveMaxIntervalMinutes != 240 {
+		t.Errorf("unexpected interval %d-%d", *o.ProactiveMinIntervalMinutes, *o.ProactiveMaxIntervalMinutes)
+	}
+	if *o.ProactiveQuietStart != 23 || *o.ProactiveQuietEnd != 8 {
+		t.Errorf("unexpected quiet hours %d-%d", *o.ProactiveQuietStart, *o.ProactiveQuietEnd)
+	}
+	if *o.Timezone != "Europe/Warsaw" {
+		t.Errorf("timezone should keep its case, got %q", *o.Timezone)
+	}
+	if err := chatsettings.Validate(o); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+
+	applyProactiveArgs(o, "quiet off")
+	applyProactiveArgs(o, "interval off")
+	if o.ProactiveQuietStart != nil || o.ProactiveMinIntervalMinutes != nil || o.ProactiveMaxIntervalMinutes != nil {
+		t.Error("off should clear the overrides")
+	}
+
+	for _, bad := range []string{"sometimes", "on now", "interval", "interval soon", "quiet 23", "quiet a-b", "tz"} {
+		if applyProactiveArgs(&db.ChatSettings{ChatID: 5}, bad) {
+			t.Errorf("%q should not parse", bad)
+		}
+	}
+}
+
+func TestProactiveStatus(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{
+		"proactive.status": "{0}|{1}|{2}|{3}|{4}",
+		"proactive.on": "on", "proactive.off": "off", "proactive.none": "none", "proactive.minutes": {"one": "{0} minute", "other": "{0} minutes"}
+	}`), 0644)
+	bundle, err := i18n.NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := NewAdminHandler(&config.Config{DefaultLang: "en"}, nil, nil, bundle)
+
+	start, end, minGap := 23, 8, 90
+	s := chatsettings.Resolve(a.config, 5, &db.ChatSettings{ChatID: 5, ProactiveQuietStart: &start, ProactiveQuietEnd: &end, ProactiveMinIntervalMinutes: &minGap})
+	if got, want := a.proactiveStatus("en", s), "on|90 minutes|none|23:00–08:00|Europe/Kyiv"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
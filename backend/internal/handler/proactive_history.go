@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+)
+
+const (
+	defaultDeliveryLimit = 20
+	maxDeliveryLimit     = 200
+)
+
+// ProactiveHistory handles POST /api/v1/admin/proactive_history: the chat's delivered proactive
+// items (proactive messages, digests, reports, research results), newest first, optionally of
+// one source.
+func (a *AdminHandler) ProactiveHistory(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChatID int64  `json:"chat_id"`
+		Source string `json:"source"`
+		Limit  int    `json:"limit"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "proactive_history", &req); !ok {
+		return
+	}
+	if req.ChatID == 0 {
+		apierror.Write(w, r, apierror.InvalidArgument, "chat_id is required")
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultDeliveryLimit
+	}
+	if req.Limit < 1 || req.Limit > maxDeliveryLimit {
+		apierror.Write(w, r, apierror.InvalidArgument, "limit must be 1-200")
+		return
+	}
+	deliveries, err := a.db.ProactiveDeliveries(r.Context(), req.ChatID, req.Source, req.Limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "list proactive deliveries failed", "chat_id", req.ChatID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, map[string]any{"chat_id": req.ChatID, "deliveries": deliveries})
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin_ProactiveHistory_Validation(t *testing.T) {
+	a := newTestAdmin()
+	for body, want := range map[string]int{
+		`{"user_id": 222, "chat_id": -100}`:               http.StatusForbidden,
+		`{"user_id": 111}`:                                http.StatusBadRequest,
+		`{"user_id": 111, "chat_id": -100, "limit": -1}`:  http.StatusBadRequest,
+		`{"user_id": 111, "chat_id": -100, "limit": 201}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/proactive_history", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.ProactiveHistory(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+)
+
+const (
+	// defaultProactiveWait is how long GET /api/v1/proactive waits without ?wait=.
+	defaultProactiveWait = 5 * time.Second
+	// proactiveKeepAlive is how often the SSE stream sends a comment while the queue is empty.
+	proactiveKeepAlive = 15 * time.Second
+)
+
+// proactiveWait parses ?wait= (seconds, or a duration like "30s") capped at PROACTIVE_LONG_POLL_MAX_SECONDS.
+func (h *Handler) proactiveWait(raw string) (time.Duration, error) {
+	wait := defaultProactiveWait
+	if raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil {
+			wait = time.Duration(n) * time.Second
+		} else if d, err := time.ParseDuration(raw); err == nil {
+			wait = d
+		} else {
+			return 0, fmt.Errorf("invalid wait")
+		}
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait")
+	}
+	if maxWait := time.Duration(h.config.ProactiveLongPollMaxSeconds) * time.Second; wait > maxWait {
+		wait = maxWait
+	}
+	return wait, nil
+}
+
+func (h *Handler) proactiveAckTimeout() time.Duration {
+	return time.Duration(h.config.ProactiveAckTimeoutSeconds) * time.Second
+}
+
+// recordDelivery adds a delivered message to the proactive delivery history. Items popped
+// without ack are recorded when popped, the others when acknowledged.
+func (h *Handler) recordDelivery(ctx context.Context, item cache.ProactiveItem) {
+	text := deliveryText(item)
+	if h.db == nil || item.Kind != cache.ProactiveMessage || text == "" {
+		return
+	}
+	if err := h.db.RecordProactiveDelivery(ctx, item.ChatID, text, item.Source); err != nil {
+		slog.WarnContext(ctx, "record proactive delivery failed", "id", item.ID, "error", err)
+	}
+}
+
+// deliveryText is how an item is kept in the delivery history: its text, marked "[photo]" or
+// "[document]" when it carried media.
+func deliveryText(item cache.ProactiveItem) string {
+	text := strings.TrimSpace(item.Reply)
+	if item.MediaBase64 == "" {
+		return text
+	}
+	return strings.TrimSpace("[" + item.MediaType + "] " + text)
+}
+
+// Proactive pops one proactive item from the queue and returns it for the frontend to send to Telegram.
+// GET /api/v1/proactive?wait=30&ack=true — long-polls up to wait (default 5s), then 200 with
+// {"id", "kind", "source", "chat_id", "reply", "media_base64", "media_type"} or 204 if nothing arrived. With ack=true the item must be
+// confirmed via POST /api/v1/proactive/ack or it is redelivered; without it the item is removed on delivery.
+func (h *Handler) Proactive(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	wait, err := h.proactiveWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		apierror.Write(w, r, apierror.InvalidArgument, "invalid wait")
+		return
+	}
+	var ackTimeout time.Duration
+	if ack, _ := strconv.ParseBool(r.URL.Query().Get("ack")); ack {
+		ackTimeout = h.proactiveAckTimeout()
+	}
+	ctx := r.Context()
+	item, ok, err := h.cache.PopProactive(ctx, wait, ackTimeout)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.ErrorContext(ctx, "pop proactive item failed", "error", err)
+		}
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if ackTimeout <= 0 {
+		h.recordDelivery(ctx, item)
+	}
+	writeJSON(w, item)
+}
+
+// ProactiveStream streams proactive items as server-sent events ("proactive" events whose data is
+// the item JSON, with the item ID as the event id). Every item must be confirmed via
+// POST /api/v1/proactive/ack; items not acknowledged within PROACTIVE_ACK_TIMEOUT_SECONDS are sent again.
+// GET /api/v1/proactive/stream
+func (h *Handler) ProactiveStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		apierror.Write(w, r, apierror.Internal, "streaming unsupported")
+		return
+	}
+	// The stream outlives the server's WriteTimeout.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		slog.Warn("proactive stream: cannot clear write deadline", "error", err)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	slog.InfoContext(ctx, "proactive stream opened", "remote", r.RemoteAddr)
+	defer slog.InfoContext(ctx, "proactive stream closed", "remote", r.RemoteAddr)
+	for ctx.Err() == nil {
+		item, ok, err := h.cache.PopProactive(ctx, proactiveKeepAlive, h.proactiveAckTimeout())
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			slog.ErrorContext(ctx, "pop proactive item failed", "error", err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if !ok {
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			continue
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			slog.ErrorContext(ctx, "encode proactive item failed", "id", item.ID, "error", err)
+			continue
+		}
+		// An item whose write fails stays pending and is redelivered after the ack timeout.
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: proactive\ndata: %s\n\n", item.ID, data); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+// ProactiveAckRequest confirms that a proactive item was handled.
+type ProactiveAckRequest struct {
+	ID string `json:"id"`
+}
+
+// AckProactive removes a delivered item from the queue so it is not sent again.
+// POST /api/v1/proactive/ack {"id"} — 200 {"status":"ok"}; unknown IDs are ignored.
+func (h *Handler) AckProactive(w http.ResponseWriter, r *http.Request) {
+	var req ProactiveAckRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
+		return
+	}
+	defer r.Body.Close()
+	req.ID = strings.TrimSpace(req.ID)
+	if req.ID == "" {
+		apierror.Write(w, r, apierror.InvalidArgument, "id is required")
+		return
+	}
+	ctx := r.Context()
+	item, pending, err := h.cache.PendingProactive(ctx, req.ID)
+	if err != nil {
+		slog.WarnContext(ctx, "read acknowledged proactive item failed", "id", req.ID, "error", err)
+	}
+	if err := h.cache.AckProactive(ctx, req.ID); err != nil {
+		slog.ErrorContext(ctx, "ack proactive item failed", "id", req.ID, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	if pending {
+		h.recordDelivery(ctx, item)
+	}
+	writeJSON(w, map[string]string{"status": "ok"})
+}
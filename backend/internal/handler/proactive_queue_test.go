@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+func TestProactiveWait(t *testing.T) {
+	h := &Handler{config: &config.Config{ProactiveLongPollMaxSeconds: 30}}
+	tests := []struct {
+		raw  string
+		want time.Duration
+		err  bool
+	}{
+		{"", defaultProactiveWait, false},
+		{"10", 10 * time.Second, false},
+		{"1500ms", 1500 * time.Millisecond, false},
+		{"0", 0, false},
+		{"90", 30 * time.Second, false},
+		{"-1", 0, true},
+		{"soon", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := h.proactiveWait(tt.raw)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("proactiveWait(%q) = %v, %v; want %v, err=%v", tt.raw, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestAckProactive_Validation(t *testing.T) {
+	h := &Handler{config: &config.Config{}}
+	for _, body := range []string{`not json`, `{"id": "  "}`} {
+		req := httptest.NewRequest("POST", "/api/v1/proactive/ack", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		h.AckProactive(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", body, w.Code)
+		}
+	}
+}
+
+func TestDeliveryText(t *testing.T) {
+	for want, item := range map[string]cache.ProactiveItem{
+		"hello":          {Reply: " hello "},
+		"[photo] a meme": {Reply: "a meme", MediaBase64: "aGk=", MediaType: "photo"},
+		"[document]":     {MediaBase64: "aGk=", MediaType: "document"},
+		"":               {Reply: "  "},
+	} {
+		if got := deliveryText(item); got != want {
+			t.Errorf("deliveryText(%+v) = %q, want %q", item, got, want)
+		}
+	}
+}
@@ -9,12 +9,16 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
 	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
 	"github.com/ThatHunky/gryag/backend/internal/i18n"
 	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
 	"github.com/ThatHunky/gryag/backend/internal/tools"
+	"github.com/ThatHunky/gryag/backend/internal/watermark"
 	"google.golang.org/genai"
 )
 
@@ -33,6 +37,40 @@ type ProcessRequest struct {
 	MimeType          string  `json:"mime_type"`
 	ReplyToMessageID  *int64  `json:"reply_to_message_id,omitempty"`
 	ReplyToText       string  `json:"reply_to_text,omitempty"`
+	LanguageCode      string  `json:"language_code,omitempty"` // Telegram user's client language
+	StickerEmoji      string  `json:"sticker_emoji,omitempty"`
+	StickerSet        string  `json:"sticker_set,omitempty"`
+	// Forwarded messages: the original author (user name or post signature), the chat or channel
+	// it came from, and when it was first sent (RFC 3339)
+	ForwardedFrom     string `json:"forwarded_from,omitempty"`
+	ForwardedFromChat string `json:"forwarded_from_chat,omitempty"`
+	ForwardDate       string `json:"forward_date,omitempty"`
+	// MediaGroupID is set on every item of a Telegram album; the items are answered together (see Albums).
+	MediaGroupID string `json:"media_group_id,omitempty"`
+	// MessageThreadID is the forum topic the message was posted in (only for topic messages).
+	MessageThreadID *int64 `json:"message_thread_id,omitempty"`
+	// Facts the trigger policy decides on (see evaluateTrigger). ChatType is Telegram's chat type
+	// ("private", "group", "supergroup"); without it the frontend's own Addressed decision is used.
+	ChatType    string `json:"chat_type,omitempty"`
+	IsCommand   bool   `json:"is_command,omitempty"`   // a command for this bot (no @suffix, or its own)
+	MentionsBot bool   `json:"mentions_bot,omitempty"` // @username or text mention of the bot
+	ReplyToBot  bool   `json:"reply_to_bot,omitempty"`
+	// Addressed is false when the message is neither an @mention of, a reply to, nor a command for
+	// the bot (nil = frontend doesn't say; treated as addressed). Only read when ChatType is empty.
+	Addressed *bool `json:"addressed,omitempty"`
+}
+
+// forward returns the forward metadata for the prompt, or nil when the message isn't forwarded.
+// The original date is shown in loc; one that doesn't parse is left out.
+func (req *ProcessRequest) forward(loc *time.Location) *llm.Forward {
+	if req.ForwardedFrom == "" && req.ForwardedFromChat == "" && req.ForwardDate == "" {
+		return nil
+	}
+	f := &llm.Forward{From: req.ForwardedFrom, Chat: req.ForwardedFromChat}
+	if t, err := time.Parse(time.RFC3339, req.ForwardDate); err == nil {
+		f.Date = t.In(loc)
+	}
+	return f
 }
 
 type ProcessResponse struct {
@@ -41,6 +79,12 @@ type ProcessResponse struct {
 	MediaURL    string `json:"media_url,omitempty"`
 	MediaType   string `json:"media_type,omitempty"`
 	MediaBase64 string `json:"media_base64,omitempty"`
+	// MessageThreadID echoes the request's forum topic so the reply is sent to the same topic.
+	MessageThreadID *int64 `json:"message_thread_id,omitempty"`
+	// Delete lists messages the frontend should delete after sending the reply (request_delete).
+	Delete []tools.DeleteAction `json:"delete,omitempty"`
+
+	meta *replyMeta // how the reply was made; only the v2 envelope carries it
 }
 
 // Handler wires all subsystems together for request processing.
@@ -52,10 +96,11 @@ type Handler struct {
 	executor *tools.Executor
 	config   *config.Config
 	bundle   *i18n.Bundle
+	settings *chatsettings.Store
 }
 
 // New creates a new request handler with all dependencies.
-func New(cfg *config.Config, database *db.DB, c *cache.Cache, llmClient *llm.Client, reg *tools.Registry, exe *tools.Executor, bundle *i18n.Bundle) *Handler {
+func New(cfg *config.Config, database *db.DB, c *cache.Cache, llmClient *llm.Client, reg *tools.Registry, exe *tools.Executor, bundle *i18n.Bundle, settings *chatsettings.Store) *Handler {
 	return &Handler{
 		db:       database,
 		cache:    c,
@@ -64,107 +109,275 @@ func New(cfg *config.Config, database *db.DB, c *cache.Cache, llmClient *llm.Cli
 		executor: exe,
 		config:   cfg,
 		bundle:   bundle,
+		settings: settings,
+	}
+}
+
+// chatSettings returns the effective settings for a chat (defaults when no store is wired).
+func (h *Handler) chatSettings(ctx context.Context, chatID int64) *chatsettings.Settings {
+	if h.settings == nil {
+		return chatsettings.Resolve(h.config, chatID, nil)
 	}
+	return h.settings.Get(ctx, chatID)
 }
 
 // Process handles the /api/v1/process endpoint — the main entry point for messages.
 func (h *Handler) Process(w http.ResponseWriter, r *http.Request) {
+	h.process(w, r, respondJSON)
+}
+
+// process stores a message and generates its reply; respond writes the reply in the version's
+// format. Silent outcomes (throttled, not triggered, shadow mode) are a 204 in every version.
+func (h *Handler) process(w http.ResponseWriter, r *http.Request, respond func(http.ResponseWriter, *ProcessResponse)) {
 	requestID := r.Header.Get("X-Request-ID")
-	logger := slog.With("request_id", requestID)
+	ctx := logging.WithRequestID(r.Context(), requestID)
 
 	var req ProcessRequest
+	album := albumFromContext(r.Context())
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		logger.Warn("invalid request payload", "error", err)
-		http.Error(w, `{"error":"invalid payload"}`, http.StatusBadRequest)
+		slog.WarnContext(ctx, "invalid request payload", "error", err)
+		apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
 		return
 	}
 	defer r.Body.Close()
 
-	logger.Info("processing message",
-		"chat_id", req.ChatID,
-		"user_id", req.UserID,
+	userID := int64(0)
+	if req.UserID != nil {
+		userID = *req.UserID
+	}
+	ctx = logging.WithChat(ctx, req.ChatID, userID)
+	threadID := int64(0)
+	if req.MessageThreadID != nil {
+		threadID = *req.MessageThreadID
+		ctx = logging.With(ctx, "thread_id", threadID)
+	}
+
+	slog.InfoContext(ctx, "processing message",
 		"text_length", len(req.Text),
 		"has_media", req.MediaBase64 != "",
 		"media_type", req.MediaType,
 	)
 
-	ctx := r.Context()
+	settings := h.chatSettings(ctx, req.ChatID)
 
-	// 1. Log the incoming message to PostgreSQL (even if later throttled at tool level)
-	userID := int64(0)
-	if req.UserID != nil {
-		userID = *req.UserID
+	// 0. Replay protection: the same Telegram message submitted again (frontend restart, webhook
+	// retry) gets the stored reply, or silence, but never a second generated answer.
+	if req.MessageID > 0 {
+		rep, err := h.db.FindMessageReplay(ctx, req.ChatID, req.MessageID)
+		if err != nil {
+			slog.WarnContext(ctx, "replay check failed", "error", err)
+		} else if rep != nil {
+			if resp := replayResponse(rep); resp != nil {
+				resp.MessageThreadID = req.MessageThreadID
+				slog.InfoContext(ctx, "duplicate message, replaying stored reply", "message_id", req.MessageID, "original_request_id", rep.RequestID)
+				respond(w, resp)
+				return
+			}
+			slog.InfoContext(ctx, "duplicate message with no undelivered reply, staying silent", "message_id", req.MessageID, "original_request_id", rep.RequestID)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
 	}
-	msgRecord := &db.Message{
-		ChatID:           req.ChatID,
-		UserID:           req.UserID,
-		Username:         strPtr(req.Username),
-		FirstName:        strPtr(req.FirstName),
-		Text:             strPtr(req.Text),
-		MessageID:        &req.MessageID,
-		RequestID:        &requestID,
-		FileID:           strPtr(req.FileID),
-		MediaType:        strPtr(req.MediaType),
-		ReplyToMessageID: req.ReplyToMessageID,
+
+	// 1. Log the incoming message to PostgreSQL (even if later throttled at tool level), tagged
+	// with its tone for the chat's mood
+	msg := incomingMessage(&req, requestID)
+	h.trackMood(ctx, msg)
+	if _, err := h.db.InsertMessage(ctx, msg); err != nil {
+		slog.ErrorContext(ctx, "failed to store incoming message", "error", err)
+	}
+	if req.MediaType == "sticker" {
+		if err := h.db.RecordStickerUsage(ctx, req.ChatID, req.StickerSet, req.StickerEmoji); err != nil {
+			slog.WarnContext(ctx, "failed to record sticker usage", "error", err)
+		}
 	}
-	if _, err := h.db.InsertMessage(ctx, msgRecord); err != nil {
-		logger.Error("failed to store incoming message", "error", err)
+
+	// The rest of an album (stored by their own requests) counts as part of this message
+	album.apply(&req)
+
+	// Trigger policy: only messages that trigger a reply reach Gemini; everything else is stored silently.
+	if !h.shouldReply(ctx, &req, settings) {
+		w.WriteHeader(http.StatusNoContent)
+		return
 	}
 
+	// Reply language: per-user preference (detected or from Telegram) over the chat's language;
+	// likewise the user's timezone (set_timezone) over the chat's
+	userSettings := h.loadUserSettings(ctx, userID)
+	lang := h.resolveReplyLanguage(ctx, userID, userSettings, req.Text, req.LanguageCode, settings.Language)
+	loc := userSettings.Location(settings.Location())
+	ctx = context.WithValue(ctx, tools.RequestLanguageKey, lang)
+	ctx = context.WithValue(ctx, tools.RequestLocationKey, loc)
+	ctx = context.WithValue(ctx, tools.RequestUserIDKey, userID)
+	ctx = context.WithValue(ctx, tools.RequestChatIDKey, req.ChatID)
+	ctx = context.WithValue(ctx, tools.RequestThreadIDKey, threadID)
+	ctx = context.WithValue(ctx, tools.RequestDisabledToolsKey, settings.DisabledTools)
+
 	// 2. Build Dynamic Instructions from DB context
-	di, err := llm.NewDynamicInstructions(ctx, h.db, req.ChatID, userID, req.Username, req.FirstName, req.Text, h.config.ImmediateContextSize, req.ReplyToMessageID, req.ReplyToText)
+	di, err := llm.NewDynamicInstructions(ctx, h.db, req.ChatID, threadID, userID, req.Username, req.FirstName, req.Text, h.config.ImmediateContextPool(), req.ReplyToMessageID, req.ReplyToText)
+	// Privacy mode: the model sees pseudonyms, and tool calls and the reply are mapped back.
+	// Without the mapping the prompt would carry real names, so the request fails instead
+	var privacy *llm.Privacy
+	if err == nil && settings.PrivacyMode {
+		privacy, err = llm.LoadPrivacy(ctx, h.db, di)
+		di.Pseudonymized = true
+	}
 	if err != nil {
-		logger.Error("failed to build dynamic instructions", "error", err)
+		slog.ErrorContext(ctx, "failed to build dynamic instructions", "error", err)
 		reply := "Internal error building context."
 		if h.bundle != nil {
-			reply = h.bundle.T(h.config.DefaultLang, "error.context_build")
+			reply = h.bundle.T(lang, "error.context_build")
+		}
+		resp := &ProcessResponse{Reply: reply, RequestID: requestID, MessageThreadID: req.MessageThreadID}
+		if settings.ShadowMode {
+			respondShadow(ctx, w, resp, nil)
+			return
 		}
-		respondJSON(w, &ProcessResponse{Reply: reply, RequestID: requestID})
+		respond(w, resp)
 		return
 	}
+	di.KeepImportant(h.config.ImmediateContextSize)
+	if req.MediaType == "sticker" {
+		// Stickers arrive without text; describe them so the model knows what was sent
+		di.CurrentMessage = llm.StickerLabel(req.StickerEmoji, req.StickerSet)
+	}
 	di.ToolsDescription = h.registry.GetToolDescription()
+	di.ReplyLanguage = lang
+	di.SetLocation(time.Now(), loc)
+	di.Forward = req.forward(loc)
+	if h.config.NotesInContext > 0 {
+		if notes, err := h.db.ListChatNotes(ctx, req.ChatID, "", h.config.NotesInContext); err == nil {
+			di.Notes = notes
+		} else {
+			slog.WarnContext(ctx, "load chat notes failed", "error", err)
+		}
+	}
+	if h.config.UserProfileInContext && userID != 0 {
+		// The profile carries the karma too, so one load covers both blocks
+		if p, err := h.db.GetUserProfile(ctx, req.ChatID, userID, false); err == nil {
+			di.UserProfile = p
+			if h.config.EnableKarma {
+				di.UserKarma = p.Karma
+			}
+		} else {
+			slog.WarnContext(ctx, "load user profile failed", "error", err)
+		}
+	} else if h.config.EnableKarma {
+		if k, err := h.db.GetKarma(ctx, req.ChatID, userID); err == nil {
+			di.UserKarma = k
+		} else {
+			slog.WarnContext(ctx, "load karma failed", "error", err)
+		}
+	}
+	di.Mood = h.chatMood(ctx, req.ChatID)
 
 	// Inject current message media into context (Section 8.6) so the model can see/hear it
 	if req.MediaBase64 != "" {
 		data, err := base64.StdEncoding.DecodeString(req.MediaBase64)
 		if err != nil {
-			logger.Warn("failed to decode media_base64", "error", err)
+			slog.WarnContext(ctx, "failed to decode media_base64", "error", err)
 		} else {
 			mime := inferMimeType(req.MediaType, req.MimeType)
 			di.MediaParts = []*genai.Part{genai.NewPartFromBytes(data, mime)}
 		}
 	}
+	if len(album) > 0 {
+		di.MediaParts = append(di.MediaParts, album.mediaParts(ctx, h.config.MediaBufferMax-len(di.MediaParts))...)
+	}
+	// Media over MEDIA_INLINE_MAX_BYTES goes through the Files API; uploads are deleted once answered
+	defer h.offloadMedia(ctx, di.MediaParts)()
 
-	// Pass request media (base64) in context for edit_image(use_context_image=true)
+	// Pass request media (base64) in context for edit_image(use_context_image=true) and summarize_audio
 	if req.MediaBase64 != "" {
 		ctx = context.WithValue(ctx, tools.RequestMediaBase64Key, req.MediaBase64)
+		ctx = context.WithValue(ctx, tools.RequestMediaMimeTypeKey, inferMimeType(req.MediaType, req.MimeType))
 	}
+	ctx = context.WithValue(ctx, tools.RequestMessageIDKey, req.MessageID)
 
-	// 3. Get the registered tools for the API call
-	genaiTools := h.registry.GetTools()
+	// 3. Get the registered tools for the API call (minus tools disabled for this chat)
+	genaiTools := h.registry.GetToolsForChat(settings)
+	genOpts := llm.GenerateOptions{Persona: settings.Persona, Temperature: &settings.Temperature}
+	if h.settings != nil {
+		genOpts.Persona = h.settings.SystemPrompt(ctx, settings)
+	}
+	// Canary (CANARY_*): some replies use another model or temperature, tagged in reply_variants
+	variant := pickVariant(h.config, req.ChatID, randFloat())
+	if variant.Name == variantCanary {
+		if variant.Model != h.config.GeminiModel {
+			genOpts.Model = variant.Model
+		}
+		if variant.Temperature != nil {
+			genOpts.Temperature = variant.Temperature
+		}
+		ctx = logging.With(ctx, "variant", variant.Name)
+	}
 
 	// 4. Initial conversation history payload
 	contents := []*genai.Content{
 		{
 			Role:  "user",
-			Parts: di.BuildParts(),
+			Parts: privacy.HideParts(di.BuildParts()),
 		},
 	}
 
 	reply := ""
 	mediaBase64 := ""
 	mediaType := ""
+	var deletes []tools.DeleteAction
+	var shadowCalls []string // shadow mode: tool calls, for the log
+	toolUsage := []ToolUsage{}
+
+	// Debug trace (DEBUG_TRACE): stored when the request ends, however it ends
+	var trace *debugTrace
+	if h.config.DebugTrace && h.cache != nil && requestID != "" {
+		trace = &debugTrace{
+			RequestID: requestID, ChatID: req.ChatID, UserID: userID, ThreadID: threadID, CreatedAt: time.Now(),
+			Language: lang, Persona: genOpts.Persona, Model: genOpts.Model, Temperature: genOpts.Temperature, DisabledTools: settings.DisabledTools,
+		}
+		defer func() {
+			trace.Contents, trace.Reply = contents, reply
+			h.saveTrace(ctx, trace)
+		}()
+	}
 
-	// 5. Tool execution loop (max 5 iterations to prevent infinite loops)
-	for i := 0; i < 5; i++ {
-		resp, err := h.llm.GenerateResponse(ctx, contents, genaiTools)
+	// 5. Tool execution loop (MAX_TOOL_ITERATIONS model turns), bounded by the loop deadline;
+	// each Gemini call gets its own deadline too. A loop that runs out of turns or repeats a call
+	// ends with one more turn in which the model must answer without tools.
+	loopCtx, cancelLoop := withStageTimeout(ctx, h.config.ToolLoopTimeoutSeconds)
+	defer cancelLoop()
+	loopStart := time.Now()
+	toolCalls := 0
+	replyVariant := func(failed bool) *db.ReplyVariant {
+		return &db.ReplyVariant{
+			RequestID: requestID, ChatID: req.ChatID, Variant: variant.Name, Model: variant.Model,
+			Temperature: *genOpts.Temperature, LatencyMS: int(time.Since(loopStart).Milliseconds()),
+			ReplyLength: len([]rune(reply)), ToolCalls: toolCalls, Failed: failed,
+		}
+	}
+	guard := newLoopGuard(h.config.MaxRepeatedToolCalls)
+	answerNow := false
+	for i := 0; i < h.config.MaxToolIterations; i++ {
+		callCtx, cancelCall := withStageTimeout(loopCtx, h.config.LLMCallTimeoutSeconds)
+		resp, err := h.llm.GenerateResponseWithOptions(callCtx, contents, genaiTools, genOpts)
+		cancelCall()
+		if err != nil && (reply != "" || mediaBase64 != "") {
+			// Keep what an earlier round already produced rather than failing the whole reply.
+			slog.WarnContext(ctx, "gemini generation failed, sending partial reply", "error", err, "iteration", i)
+			break
+		}
 		if err != nil {
-			logger.Error("gemini generation failed", "error", err)
+			slog.ErrorContext(ctx, "gemini generation failed", "error", err)
+			h.recordVariant(ctx, replyVariant(true))
 			reply := "Error generating response."
 			if h.bundle != nil {
-				reply = h.bundle.T(h.config.DefaultLang, "error.generation_failed")
+				reply = h.bundle.T(lang, "error.generation_failed")
+			}
+			resp := &ProcessResponse{Reply: reply, RequestID: requestID, MessageThreadID: req.MessageThreadID}
+			if settings.ShadowMode {
+				respondShadow(ctx, w, resp, shadowCalls)
+				return
 			}
-			respondJSON(w, &ProcessResponse{Reply: reply, RequestID: requestID})
+			respond(w, resp)
 			return
 		}
 
@@ -184,36 +397,65 @@ func (h *Handler) Process(w http.ResponseWriter, r *http.Request) {
 				reply += part.Text
 			} else if part.FunctionCall != nil {
 				hasToolCall = true
-				res := h.HandleToolCall(ctx, part.FunctionCall)
+				toolCalls++
+				var res *tools.ToolResult
+				toolStart := time.Now()
+				if !guard.allow(part.FunctionCall) {
+					// The model is stuck repeating itself: don't run the call again
+					res = &tools.ToolResult{Name: part.FunctionCall.Name, Output: repeatedCallOutput}
+				} else if settings.ShadowMode && !tools.ReadOnly(part.FunctionCall.Name) {
+					// Shadow mode: tools that change state are skipped, the model is told they worked
+					res = &tools.ToolResult{Name: part.FunctionCall.Name, Output: shadowToolOutput}
+					shadowCalls = append(shadowCalls, shadowCallLabel(part.FunctionCall, true))
+				} else {
+					res = h.HandleToolCall(loopCtx, privacy.RevealCall(part.FunctionCall))
+					if settings.ShadowMode {
+						shadowCalls = append(shadowCalls, shadowCallLabel(part.FunctionCall, false))
+					}
+				}
+				trace.recordToolCall(part.FunctionCall, res.Output, res.Error)
+				toolUsage = append(toolUsage, ToolUsage{Name: part.FunctionCall.Name, OK: res.Error == "", Error: res.Error,
+					DurationMS: time.Since(toolStart).Milliseconds()})
 
-				returnToModel := res.Output
+				returnToModel := privacy.Hide(res.Output)
 
 				// Intercept image output: set response media and store in media_cache for edit by media_id
 				responsePayload := map[string]any{"result": returnToModel}
+				if res.Error != "" {
+					responsePayload["error"], responsePayload["error_kind"] = privacy.Hide(res.Error), res.ErrorKind
+				}
 				if part.FunctionCall.Name == "generate_image" || part.FunctionCall.Name == "edit_image" {
-					var raw struct {
-						MediaBase64 string `json:"media_base64"`
-						MediaType   string `json:"media_type"`
-					}
-					if err := json.Unmarshal([]byte(res.Output), &raw); err == nil && raw.MediaBase64 != "" {
-						mediaBase64 = raw.MediaBase64
-						if raw.MediaType != "" {
-							mediaType = raw.MediaType
-						} else {
-							mediaType = "photo"
-						}
+					if img, ok := tools.ParseImageOutput(res.Output); ok {
+						mediaBase64, mediaType = img.MediaBase64, img.MediaType
 						returnToModel = "Image generated successfully. It has been attached to the chat for the user to see."
+						data, decErr := base64.StdEncoding.DecodeString(img.MediaBase64)
 						// Store in media_cache; pass media_id only in structured response so the model can use it for edit_image but must not echo it
-						if data, decErr := base64.StdEncoding.DecodeString(raw.MediaBase64); decErr == nil && h.config.MediaCacheDir != "" {
+						if decErr == nil && h.config.MediaCacheDir != "" && !settings.ShadowMode {
 							if mid, insErr := h.db.InsertMediaCache(ctx, h.config.MediaCacheDir, req.ChatID, req.UserID, data, h.config.MediaCacheTTLHours); insErr == nil {
 								returnToModel = "Image generated and attached to the chat. To edit later, call edit_image with the media_id from this response. Do not mention or show the media_id to the user—it is internal only."
 								responsePayload["media_id"] = mid
 							}
 						}
+						// Label the copy sent to the chat; the cached original stays clean so edits don't stack badges
+						if decErr == nil && settings.WatermarkEnabled {
+							if stamped, wmErr := watermark.Stamp(data, settings.WatermarkLabel); wmErr == nil {
+								mediaBase64 = base64.StdEncoding.EncodeToString(stamped)
+							} else {
+								slog.WarnContext(ctx, "watermark failed, sending image unlabeled", "error", wmErr)
+							}
+						}
 						responsePayload["result"] = returnToModel
 					}
 				}
 
+				// Intercept delete actions: the frontend deletes the message after sending the reply
+				if part.FunctionCall.Name == "request_delete" {
+					if action, ok := tools.ParseDeleteAction(res.Output); ok {
+						deletes = append(deletes, action)
+						responsePayload["result"] = "Deletion accepted; the message will be deleted after your reply is sent."
+					}
+				}
+
 				toolResponses = append(toolResponses, genai.NewPartFromFunctionResponse(part.FunctionCall.Name, responsePayload))
 			}
 		}
@@ -227,13 +469,50 @@ func (h *Handler) Process(w http.ResponseWriter, r *http.Request) {
 			Role:  "user",
 			Parts: toolResponses,
 		})
+		if guard.stuck != "" || i == h.config.MaxToolIterations-1 {
+			answerNow = true
+			break
+		}
 	}
+	if answerNow {
+		slog.WarnContext(ctx, "tool loop cut short, asking for an answer", "repeated_tool", guard.stuck, "tool_calls", toolCalls)
+		text, answered, err := h.answerNow(loopCtx, contents, genaiTools, genOpts)
+		contents = answered
+		reply += text
+		if err != nil {
+			slog.WarnContext(ctx, "final answer after the tool loop failed, sending partial reply", "error", err)
+			if reply == "" && mediaBase64 == "" {
+				reply = "Error generating response."
+				if h.bundle != nil {
+					reply = h.bundle.T(lang, "error.generation_failed")
+				}
+			}
+		}
+	}
+
+	reply = privacy.Reveal(reply)
+
+	h.recordVariant(ctx, replyVariant(false))
 
 	resp := &ProcessResponse{
 		Reply:       reply,
 		RequestID:   requestID,
 		MediaBase64: mediaBase64,
 		MediaType:   mediaType,
+
+		MessageThreadID: req.MessageThreadID,
+		Delete:          deletes,
+
+		meta: &replyMeta{Tools: toolUsage, Model: variant.Model, LatencyMS: time.Since(loopStart).Milliseconds()},
+	}
+	if h.config.CanaryEnabled() {
+		resp.meta.Variant = variant.Name
+	}
+
+	// Shadow mode: nothing is sent, so nothing is stored as the bot's reply either
+	if settings.ShadowMode {
+		respondShadow(ctx, w, resp, shadowCalls)
+		return
 	}
 
 	// 6. Store the bot's reply in the message log
@@ -242,13 +521,24 @@ func (h *Handler) Process(w http.ResponseWriter, r *http.Request) {
 		Text:       &reply,
 		IsBotReply: true,
 		RequestID:  &requestID,
+		MediaType:  strPtr(mediaType),
+		ThreadID:   threadID,
 	}
 	if _, err := h.db.InsertMessage(ctx, botReply); err != nil {
-		logger.Error("failed to store bot reply", "error", err)
+		slog.ErrorContext(ctx, "failed to store bot reply", "error", err)
 	}
+	h.trackCommitments(ctx, &req, requestID, reply, loc, privacy)
+
+	slog.InfoContext(ctx, "reply generated", "reply_length", len(reply), "has_media", mediaBase64 != "")
+	respond(w, resp)
+}
 
-	logger.Info("reply generated", "reply_length", len(reply), "has_media", mediaBase64 != "")
-	respondJSON(w, resp)
+// withStageTimeout derives a context for one stage of a reply; seconds <= 0 adds no deadline.
+func withStageTimeout(ctx context.Context, seconds int) (context.Context, context.CancelFunc) {
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
 }
 
 // HandleToolCall processes a function call from Gemini and returns the tool result.
@@ -263,6 +553,52 @@ func respondJSON(w http.ResponseWriter, resp *ProcessResponse) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// offloadMedia replaces inline parts over MEDIA_INLINE_MAX_BYTES with Files API uploads, in place,
+// and returns a func that deletes the uploads. A part whose upload fails stays inline.
+func (h *Handler) offloadMedia(ctx context.Context, parts []*genai.Part) (cleanup func()) {
+	var cleanups []func()
+	for i, p := range parts {
+		if h.llm == nil || p.InlineData == nil || len(p.InlineData.Data) <= h.config.MediaInlineMaxBytes {
+			continue
+		}
+		uploaded, done, err := h.llm.MediaPart(ctx, p.InlineData.Data, p.InlineData.MIMEType)
+		if err != nil {
+			slog.WarnContext(ctx, "media upload failed, sending it inline", "bytes", len(p.InlineData.Data), "error", err)
+			continue
+		}
+		parts[i] = uploaded
+		cleanups = append(cleanups, done)
+	}
+	return func() {
+		for _, done := range cleanups {
+			done()
+		}
+	}
+}
+
+// incomingMessage is the stored record of an incoming message.
+func incomingMessage(req *ProcessRequest, requestID string) *db.Message {
+	threadID := int64(0)
+	if req.MessageThreadID != nil {
+		threadID = *req.MessageThreadID
+	}
+	return &db.Message{
+		ChatID:           req.ChatID,
+		UserID:           req.UserID,
+		Username:         strPtr(req.Username),
+		FirstName:        strPtr(req.FirstName),
+		Text:             strPtr(req.Text),
+		MessageID:        &req.MessageID,
+		RequestID:        &requestID,
+		FileID:           strPtr(req.FileID),
+		MediaType:        strPtr(req.MediaType),
+		ReplyToMessageID: req.ReplyToMessageID,
+		StickerEmoji:     strPtr(req.StickerEmoji),
+		StickerSet:       strPtr(req.StickerSet),
+		ThreadID:         threadID,
+	}
+}
+
 // strPtr returns a pointer to a string, or nil if empty.
 func strPtr(s string) *string {
 	if s == "" {
@@ -285,6 +621,8 @@ func inferMimeType(mediaType, mimeType string) string {
 		return "video/mp4"
 	case "voice":
 		return "audio/ogg"
+	case "audio":
+		return "audio/mpeg"
 	case "sticker":
 		return "image/webp"
 	default:
@@ -297,20 +635,3 @@ func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	fmt.Fprintf(w, `{"status":"ok"}`)
 }
-
-// Proactive pops one proactive message from the queue and returns it for the frontend to send to Telegram.
-// GET /api/v1/proactive — 200 with {"chat_id": ..., "reply": ...} or 204 if queue empty.
-func (h *Handler) Proactive(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-	ctx := r.Context()
-	chatID, reply, ok := h.cache.PopProactive(ctx, 5*time.Second)
-	if !ok {
-		w.WriteHeader(http.StatusNoContent)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{"chat_id": chatID, "reply": reply})
-}
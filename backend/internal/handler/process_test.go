@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"google.golang.org/genai"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -94,6 +99,22 @@ func TestRespondJSON_MediaBase64(t *testing.T) {
 	}
 }
 
+// TestRespondJSON_MessageThreadID verifies the forum topic is echoed for topic messages and omitted otherwise.
+func TestRespondJSON_MessageThreadID(t *testing.T) {
+	thread := int64(7)
+	w := httptest.NewRecorder()
+	respondJSON(w, &ProcessResponse{Reply: "hi", RequestID: "req-1", MessageThreadID: &thread})
+	if !strings.Contains(w.Body.String(), `"message_thread_id":7`) {
+		t.Errorf("expected message_thread_id in %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	respondJSON(w, &ProcessResponse{Reply: "hi", RequestID: "req-2"})
+	if strings.Contains(w.Body.String(), "message_thread_id") {
+		t.Errorf("message_thread_id should be omitted outside topics: %s", w.Body.String())
+	}
+}
+
 // TestRespondJSON_MediaBase64_Document verifies that media_type "document" is serialized for send-as-file.
 func TestRespondJSON_MediaBase64_Document(t *testing.T) {
 	w := httptest.NewRecorder()
@@ -135,6 +156,9 @@ func TestInferMimeType(t *testing.T) {
 	if inferMimeType("voice", "") != "audio/ogg" {
 		t.Error("voice should be audio/ogg")
 	}
+	if inferMimeType("audio", "") != "audio/mpeg" {
+		t.Error("audio should be audio/mpeg")
+	}
 	if inferMimeType("", "image/png") != "image/png" {
 		t.Error("mime_type should be used when set")
 	}
@@ -142,3 +166,86 @@ func TestInferMimeType(t *testing.T) {
 		t.Error("mime_type should override media_type")
 	}
 }
+
+func TestAckReply_InvalidPayload(t *testing.T) {
+	h := &Handler{}
+
+	req := httptest.NewRequest("POST", "/api/v1/ack_reply", strings.NewReader("not json"))
+	w := httptest.NewRecorder()
+	h.AckReply(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+
+	// Missing message_id is rejected before touching the database
+	req = httptest.NewRequest("POST", "/api/v1/ack_reply", strings.NewReader(`{"request_id":"r1","chat_id":5}`))
+	w = httptest.NewRecorder()
+	h.AckReply(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for missing message_id, got %d", w.Code)
+	}
+}
+
+func TestPickUserLanguage(t *testing.T) {
+	tests := []struct {
+		detected   string
+		detectedOK bool
+		stored     string
+		tgCode     string
+		want       string
+	}{
+		{"en", true, "uk", "uk", "en"}, // writing English now beats stored preference
+		{"", false, "uk", "en-US", "uk"},
+		{"", false, "", "en-US", "en"},
+		{"", false, "", "", ""},
+	}
+	for _, tt := range tests {
+		got := pickUserLanguage(tt.detected, tt.detectedOK, tt.stored, tt.tgCode)
+		if got != tt.want {
+			t.Errorf("pickUserLanguage(%q, %v, %q, %q) = %q, want %q", tt.detected, tt.detectedOK, tt.stored, tt.tgCode, got, tt.want)
+		}
+	}
+}
+
+func TestRespondShadow(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondShadow(context.Background(), w, &ProcessResponse{Reply: "would say this", RequestID: "r1"}, []string{"remember_memory({}) [not run]"})
+	if w.Code != http.StatusNoContent || w.Body.Len() != 0 {
+		t.Errorf("expected an empty 204, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestShadowCallLabel(t *testing.T) {
+	fc := &genai.FunctionCall{Name: "remember_memory", Args: map[string]any{"fact": "likes tea"}}
+	if got := shadowCallLabel(fc, true); got != `remember_memory({"fact":"likes tea"}) [not run]` {
+		t.Errorf("skipped label = %q", got)
+	}
+	long := &genai.FunctionCall{Name: "translate", Args: map[string]any{"text": strings.Repeat("ї", 1000)}}
+	if got := shadowCallLabel(long, false); len([]rune(got)) > maxShadowArgsLen+len("translate()")+1 || strings.Contains(got, "[not run]") {
+		t.Errorf("expected a truncated label for a run tool, got %d runes", len([]rune(got)))
+	}
+}
+
+func TestProcessRequest_Forward(t *testing.T) {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	if f := (&ProcessRequest{Text: "hi"}).forward(kyiv); f != nil {
+		t.Errorf("expected no forward for a regular message, got %+v", f)
+	}
+	req := &ProcessRequest{ForwardedFromChat: "Новини (@news_ua)", ForwardDate: "2026-10-05T09:30:00+00:00"}
+	f := req.forward(kyiv)
+	if f == nil || f.Chat != "Новини (@news_ua)" || f.Date.Format("15:04") != "12:30" {
+		t.Errorf("expected the channel and the date in Kyiv time, got %+v", f)
+	}
+	if f := (&ProcessRequest{ForwardedFrom: "Taras", ForwardDate: "yesterday"}).forward(kyiv); f == nil || !f.Date.IsZero() {
+		t.Errorf("expected a bad date to be dropped, got %+v", f)
+	}
+}
+
+func TestOffloadMedia_WithoutClient(t *testing.T) {
+	h := &Handler{config: &config.Config{MediaInlineMaxBytes: 2}}
+	parts := []*genai.Part{genai.NewPartFromBytes([]byte("jpeg"), "image/jpeg")}
+	h.offloadMedia(context.Background(), parts)()
+	if parts[0].InlineData == nil {
+		t.Error("expected media kept inline when there is no Gemini client")
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/tools"
+)
+
+// Segment types of the v2 reply envelope.
+const (
+	SegmentText     = "text"
+	SegmentPhoto    = "photo"
+	SegmentDocument = "document"
+	SegmentVoice    = "voice"
+)
+
+// ParseModeMarkdown marks segment text as the model's Markdown, which the frontend converts to
+// Telegram HTML (md_to_tg).
+const ParseModeMarkdown = "markdown"
+
+// ProcessResponseV2 is the /api/v2/process reply envelope: the reply as ordered segments plus
+// what the frontend may add around it.
+type ProcessResponseV2 struct {
+	RequestID       string               `json:"request_id"`
+	Segments        []ReplySegment       `json:"segments"`
+	ParseMode       string               `json:"parse_mode"`
+	Reactions       []string             `json:"reactions,omitempty"` // suggested reactions to the user's message
+	MessageThreadID *int64               `json:"message_thread_id,omitempty"`
+	Delete          []tools.DeleteAction `json:"delete,omitempty"`
+	Meta            *replyMeta           `json:"meta,omitempty"`
+}
+
+// ReplySegment is one message to send: text, or media with an optional caption in Text.
+type ReplySegment struct {
+	Type        string `json:"type"`
+	Text        string `json:"text,omitempty"`
+	MediaBase64 string `json:"media_base64,omitempty"`
+	MediaURL    string `json:"media_url,omitempty"`
+	MimeType    string `json:"mime_type,omitempty"`
+}
+
+// replyMeta describes how a reply was generated.
+type replyMeta struct {
+	Tools     []ToolUsage `json:"tools"`
+	Model     string      `json:"model"`
+	Variant   string      `json:"variant,omitempty"` // canary or control, while a canary is configured
+	LatencyMS int64       `json:"latency_ms"`        // generate/tool loop
+}
+
+// ToolUsage is one tool call made for a reply.
+type ToolUsage struct {
+	Name       string `json:"name"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// ProcessV2 handles /api/v2/process: the same processing as v1 with the reply as a segmented envelope.
+func (h *Handler) ProcessV2(w http.ResponseWriter, r *http.Request) {
+	h.process(w, r, respondV2)
+}
+
+// respondV2 encodes a reply as the v2 envelope.
+func respondV2(w http.ResponseWriter, resp *ProcessResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(envelope(resp))
+}
+
+// envelope converts a reply to the v2 format. Generated media becomes its own segment with the
+// text as its caption, as v1 frontends send it; a caption too long for Telegram follows as text.
+func envelope(resp *ProcessResponse) *ProcessResponseV2 {
+	env := &ProcessResponseV2{
+		RequestID:       resp.RequestID,
+		Segments:        []ReplySegment{},
+		ParseMode:       ParseModeMarkdown,
+		Reactions:       suggestedReactions(resp.Reply),
+		MessageThreadID: resp.MessageThreadID,
+		Delete:          resp.Delete,
+		Meta:            resp.meta,
+	}
+	text := resp.Reply
+	if resp.MediaBase64 != "" || resp.MediaURL != "" {
+		seg := ReplySegment{Type: SegmentPhoto, MediaBase64: resp.MediaBase64, MediaURL: resp.MediaURL}
+		switch resp.MediaType {
+		case SegmentDocument, SegmentVoice:
+			seg.Type = resp.MediaType
+		}
+		if len([]rune(text)) <= maxCaptionRunes {
+			seg.Text, text = text, ""
+		}
+		env.Segments = append(env.Segments, seg)
+	}
+	if text != "" {
+		env.Segments = append(env.Segments, ReplySegment{Type: SegmentText, Text: text})
+	}
+	return env
+}
+
+// maxCaptionRunes is Telegram's media caption limit.
+const maxCaptionRunes = 1024
+
+// telegramReactions are the emoji Telegram accepts as message reactions, without variation selectors.
+var telegramReactions = strings.Fields("👍 👎 ❤ 🔥 🥰 👏 😁 🤔 🤯 😱 🤬 😢 🎉 🤩 🤮 💩 🙏 👌 🕊 🤡 🥱 🥴 😍 🐳 ❤‍🔥 🌚 🌭 💯 🤣 ⚡ 🍌 🏆 💔 🤨 😐 🍓 🍾 💋 🖕 😈 😴 😭 🤓 👻 👨‍💻 👀 🎃 🙈 😇 😨 🤝 ✍ 🤗 🫡 🎅 🎄 ☃ 💅 🤪 🗿 🆒 💘 🙉 🦄 😘 💊 🙊 😎 👾 🤷‍♂ 🤷 🤷‍♀ 😡")
+
+// suggestedReactions offers a reply that is nothing but a reaction emoji as a reaction too, so the
+// frontend can react to the message instead of sending a one-emoji message.
+func suggestedReactions(reply string) []string {
+	emoji := strings.ReplaceAll(strings.TrimSpace(reply), "\uFE0F", "")
+	for _, r := range telegramReactions {
+		if emoji == r {
+			return []string{r}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEnvelope(t *testing.T) {
+	long := strings.Repeat("а", maxCaptionRunes+1)
+	tests := []struct {
+		name string
+		resp ProcessResponse
+		want []ReplySegment
+	}{
+		{"text", ProcessResponse{Reply: "привіт"}, []ReplySegment{{Type: SegmentText, Text: "привіт"}}},
+		{"empty", ProcessResponse{}, []ReplySegment{}},
+		{"photo with caption", ProcessResponse{Reply: "ось", MediaBase64: "AAA", MediaType: "photo"},
+			[]ReplySegment{{Type: SegmentPhoto, Text: "ось", MediaBase64: "AAA"}}},
+		{"document", ProcessResponse{MediaBase64: "AAA", MediaType: "document"},
+			[]ReplySegment{{Type: SegmentDocument, MediaBase64: "AAA"}}},
+		{"long caption", ProcessResponse{Reply: long, MediaURL: "https://x/y.png"},
+			[]ReplySegment{{Type: SegmentPhoto, MediaURL: "https://x/y.png"}, {Type: SegmentText, Text: long}}},
+	}
+	for _, tt := range tests {
+		env := envelope(&tt.resp)
+		if env.ParseMode != ParseModeMarkdown || len(env.Segments) != len(tt.want) {
+			t.Errorf("%s: got %+v", tt.name, env)
+			continue
+		}
+		for i := range tt.want {
+			if env.Segments[i] != tt.want[i] {
+				t.Errorf("%s: segment %d = %+v, want %+v", tt.name, i, env.Segments[i], tt.want[i])
+			}
+		}
+	}
+}
+
+func TestSuggestedReactions(t *testing.T) {
+	for reply, want := range map[string]string{
+		"👍":         "👍",
+		" ❤️ ":      "❤",
+		"🔥 круто":   "",
+		"🦖":         "",
+		"just text": "",
+	} {
+		got := suggestedReactions(reply)
+		if (want == "" && got != nil) || (want != "" && (len(got) != 1 || got[0] != want)) {
+			t.Errorf("%q: got %q, want %q", reply, got, want)
+		}
+	}
+}
+
+func TestRespondV2(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondV2(w, &ProcessResponse{Reply: "ok", RequestID: "r1", meta: &replyMeta{Tools: []ToolUsage{{Name: "calculator", OK: true}}, Model: "flash"}})
+	var got map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	meta, _ := got["meta"].(map[string]any)
+	if got["request_id"] != "r1" || got["parse_mode"] != "markdown" || meta == nil || meta["model"] != "flash" {
+		t.Errorf("unexpected envelope: %s", w.Body.String())
+	}
+	if _, ok := got["reply"]; ok {
+		t.Error("the v2 envelope has no flat reply")
+	}
+
+	w = httptest.NewRecorder()
+	respondJSON(w, &ProcessResponse{Reply: "ok", RequestID: "r1", meta: &replyMeta{Model: "flash"}})
+	if strings.Contains(w.Body.String(), "meta") {
+		t.Errorf("v1 must not carry the metadata: %s", w.Body.String())
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// Query handles POST /api/v1/admin/query: one read-only SQL statement (ENABLE_ADMIN_QUERY), run
+// in a read-only transaction as ADMIN_QUERY_ROLE, with row, size and time limits. Every query is
+// logged with the admin who ran it.
+func (a *AdminHandler) Query(w http.ResponseWriter, r *http.Request) {
+	// config.Load turns the endpoint off without a role; this guards configs built elsewhere
+	if !a.config.EnableAdminQuery || a.config.AdminQueryRole == "" {
+		apierror.Write(w, r, apierror.Disabled, "admin query is disabled")
+		return
+	}
+	var req struct {
+		SQL string `json:"sql"`
+	}
+	adminID, ok := a.decodeAdmin(w, r, "query", &req)
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		apierror.Write(w, r, apierror.InvalidArgument, "sql is required")
+		return
+	}
+	res, err := a.db.ReadOnlyQuery(r.Context(), req.SQL, db.QueryLimits{
+		Role:     a.config.AdminQueryRole,
+		MaxRows:  a.config.AdminQueryMaxRows,
+		MaxBytes: a.config.AdminQueryMaxBytes,
+		Timeout:  time.Duration(a.config.AdminQueryTimeoutSeconds) * time.Second,
+	})
+	if errors.Is(err, db.ErrQueryRejected) {
+		slog.WarnContext(r.Context(), "admin query rejected", "admin_id", adminID, "sql", req.SQL, "error", err)
+		apierror.Write(w, r, apierror.InvalidArgument, err.Error())
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "admin query failed", "admin_id", adminID, "sql", req.SQL, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	slog.InfoContext(r.Context(), "admin query", "admin_id", adminID, "sql", req.SQL,
+		"rows", len(res.Rows), "truncated", res.Truncated, "duration_ms", res.DurationMS)
+	writeJSON(w, res)
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin_Query_Validation(t *testing.T) {
+	a := newTestAdmin()
+	req := httptest.NewRequest("POST", "/api/v1/admin/query", strings.NewReader(`{"user_id": 111, "sql": "SELECT 1"}`))
+	w := httptest.NewRecorder()
+	a.Query(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 while disabled, got %d", w.Code)
+	}
+
+	a.config.EnableAdminQuery = true
+	req = httptest.NewRequest("POST", "/api/v1/admin/query", strings.NewReader(`{"user_id": 111, "sql": "SELECT 1"}`))
+	w = httptest.NewRecorder()
+	a.Query(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without ADMIN_QUERY_ROLE, got %d", w.Code)
+	}
+
+	a.config.AdminQueryRole = "gryag_readonly"
+	for body, want := range map[string]int{
+		`{"user_id": 222, "sql": "SELECT 1"}`:             http.StatusForbidden,
+		`{"user_id": 111, "sql": "  "}`:                   http.StatusBadRequest,
+		`{"user_id": 111, "sql": "DELETE FROM messages"}`: http.StatusBadRequest,
+		`{"user_id": 111, "sql": "SELECT 1; SELECT 2"}`:   http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/query", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.Query(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// readyCheckTimeout bounds each dependency check of a readiness probe.
+const readyCheckTimeout = 2 * time.Second
+
+// Pinger is a dependency the readiness probe checks (*db.DB, *cache.Cache, *llm.Client).
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// DependencyStatus is the result of one readiness check.
+type DependencyStatus struct {
+	Status    string `json:"status"` // "ok" or "error"
+	Error     string `json:"error,omitempty"`
+	LatencyMS int64  `json:"latency_ms"`
+	Cached    bool   `json:"cached,omitempty"` // result reused from an earlier probe
+}
+
+// ReadyHandler serves the readiness probe: unlike /health it actually checks Postgres, Redis
+// and, optionally, Gemini.
+type ReadyHandler struct {
+	db       Pinger
+	cache    Pinger
+	gemini   Pinger          // nil = not checked
+	cacheTTL time.Duration   // how long a Gemini result is reused
+	fallback map[string]bool // dependencies the backend can run without for a while (AllowDegraded)
+
+	mu         sync.Mutex
+	lastGemini DependencyStatus
+	lastAt     time.Time
+}
+
+// NewReadyHandler creates the readiness probe; gemini may be nil to skip that check.
+func NewReadyHandler(database, cache, gemini Pinger, geminiCacheTTL time.Duration) *ReadyHandler {
+	return &ReadyHandler{db: database, cache: cache, gemini: gemini, cacheTTL: geminiCacheTTL}
+}
+
+// AllowDegraded lets the probe pass while the named dependency ("redis") fails, because the
+// backend has a fallback for it. The response then says "degraded" and lists it.
+func (h *ReadyHandler) AllowDegraded(name string) {
+	if h.fallback == nil {
+		h.fallback = make(map[string]bool)
+	}
+	h.fallback[name] = true
+}
+
+// Ready checks every dependency and returns their status.
+// GET /ready — 200 {"status":"ok","checks":{...}}, 200 {"status":"degraded","degraded":[...],...} if
+// only dependencies with a fallback failed, or 503 {"status":"unavailable",...} if any other check failed.
+func (h *ReadyHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	checks := map[string]DependencyStatus{
+		"postgres": ping(ctx, h.db),
+		"redis":    ping(ctx, h.cache),
+	}
+	if h.gemini != nil {
+		checks["gemini"] = h.checkGemini(ctx)
+	}
+
+	status, code := "ok", http.StatusOK
+	var degraded []string
+	for name, c := range checks {
+		if c.Status == "ok" {
+			continue
+		}
+		slog.WarnContext(ctx, "readiness check failed", "dependency", name, "error", c.Error)
+		if h.fallback[name] {
+			degraded = append(degraded, name)
+		} else {
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+	}
+	body := map[string]any{"status": status, "checks": checks}
+	if len(degraded) > 0 {
+		slices.Sort(degraded)
+		body["degraded"] = degraded
+		if code == http.StatusOK {
+			body["status"] = "degraded"
+		}
+	}
+	writeJSONStatus(w, code, body)
+}
+
+// checkGemini pings Gemini at most once per cacheTTL; probes in between reuse the result.
+func (h *ReadyHandler) checkGemini(ctx context.Context) DependencyStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.lastAt.IsZero() && time.Since(h.lastAt) < h.cacheTTL {
+		s := h.lastGemini
+		s.Cached = true
+		return s
+	}
+	h.lastGemini = ping(ctx, h.gemini)
+	h.lastAt = time.Now()
+	return h.lastGemini
+}
+
+func ping(ctx context.Context, p Pinger) DependencyStatus {
+	if p == nil {
+		return DependencyStatus{Status: "error", Error: "not configured"}
+	}
+	ctx, cancel := context.WithTimeout(ctx, readyCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	err := p.Ping(ctx)
+	s := DependencyStatus{Status: "ok", LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		s.Status, s.Error = "error", err.Error()
+	}
+	return s
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakePinger struct {
+	err   error
+	calls int
+}
+
+func (f *fakePinger) Ping(context.Context) error {
+	f.calls++
+	return f.err
+}
+
+func TestReady(t *testing.T) {
+	get := func(h *ReadyHandler) (int, map[string]DependencyStatus) {
+		w := httptest.NewRecorder()
+		h.Ready(w, httptest.NewRequest("GET", "/ready", nil))
+		var body struct {
+			Checks map[string]DependencyStatus `json:"checks"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return w.Code, body.Checks
+	}
+
+	code, checks := get(NewReadyHandler(&fakePinger{}, &fakePinger{}, nil, time.Minute))
+	if code != http.StatusOK || len(checks) != 2 {
+		t.Errorf("expected 200 with postgres and redis, got %d %+v", code, checks)
+	}
+
+	code, checks = get(NewReadyHandler(&fakePinger{}, &fakePinger{err: errors.New("connection refused")}, nil, time.Minute))
+	if code != http.StatusServiceUnavailable || checks["redis"].Status != "error" || checks["postgres"].Status != "ok" {
+		t.Errorf("expected 503 with redis failing, got %d %+v", code, checks)
+	}
+
+	h := NewReadyHandler(&fakePinger{}, &fakePinger{err: errors.New("connection refused")}, nil, time.Minute)
+	h.AllowDegraded("redis")
+	w := httptest.NewRecorder()
+	h.Ready(w, httptest.NewRequest("GET", "/ready", nil))
+	var body struct {
+		Status   string   `json:"status"`
+		Degraded []string `json:"degraded"`
+	}
+	json.NewDecoder(w.Body).Decode(&body)
+	if w.Code != http.StatusOK || body.Status != "degraded" || len(body.Degraded) != 1 {
+		t.Errorf("expected 200 degraded with the Redis fallback, got %d %+v", w.Code, body)
+	}
+
+	gemini := &fakePinger{}
+	h = NewReadyHandler(&fakePinger{}, &fakePinger{}, gemini, time.Minute)
+	get(h)
+	code, checks = get(h)
+	if code != http.StatusOK || !checks["gemini"].Cached || gemini.calls != 1 {
+		t.Errorf("expected the second probe to reuse the Gemini result, got %d %+v after %d calls", code, checks, gemini.calls)
+	}
+}
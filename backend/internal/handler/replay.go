@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// replayResponse builds the response for a message that was already processed (frontend restart,
+// webhook retry). It returns nil when there is nothing to resend: no stored reply, or a reply the
+// frontend already acked as sent. The duplicate then gets a silent 204 rather than a second answer.
+// Generated media is not stored, so a replayed reply carries only its text.
+func replayResponse(rep *db.MessageReplay) *ProcessResponse {
+	if rep == nil || rep.Delivered || rep.Reply == nil || *rep.Reply == "" {
+		return nil
+	}
+	return &ProcessResponse{Reply: *rep.Reply, RequestID: rep.RequestID}
+}
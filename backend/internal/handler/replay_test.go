@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestReplayResponse(t *testing.T) {
+	if replayResponse(nil) != nil {
+		t.Error("unseen message should not replay")
+	}
+	if replayResponse(&db.MessageReplay{RequestID: "r1"}) != nil {
+		t.Error("seen message without a stored reply should stay silent")
+	}
+	empty := ""
+	if replayResponse(&db.MessageReplay{RequestID: "r1", Reply: &empty}) != nil {
+		t.Error("empty stored reply should stay silent")
+	}
+
+	reply := "привіт"
+	if replayResponse(&db.MessageReplay{RequestID: "r1", Reply: &reply, Delivered: true}) != nil {
+		t.Error("a reply already delivered must not be sent twice")
+	}
+
+	photo := "photo"
+	resp := replayResponse(&db.MessageReplay{RequestID: "r1", Reply: &reply, MediaType: &photo})
+	if resp == nil || resp.Reply != reply || resp.RequestID != "r1" {
+		t.Fatalf("unexpected replay response: %+v", resp)
+	}
+	if resp.MediaBase64 != "" || resp.MediaType != "" {
+		t.Errorf("replayed reply must not claim media it cannot resend: %+v", resp)
+	}
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/genai"
+)
+
+// shadowToolOutput is what the model gets for a tool skipped in shadow mode.
+const shadowToolOutput = "Done."
+
+// maxShadowArgsLen bounds the logged arguments of one tool call.
+const maxShadowArgsLen = 300
+
+// shadowCallLabel formats a tool call for the shadow log: name(args), marked when it was skipped.
+func shadowCallLabel(fc *genai.FunctionCall, skipped bool) string {
+	args, _ := json.Marshal(fc.Args)
+	if r := []rune(string(args)); len(r) > maxShadowArgsLen {
+		args = []byte(string(r[:maxShadowArgsLen]) + "…")
+	}
+	label := fc.Name + "(" + string(args) + ")"
+	if skipped {
+		label += " [not run]"
+	}
+	return label
+}
+
+// respondShadow logs the reply a shadow-mode chat would have got and answers 204, like any
+// other message the bot stays silent on.
+func respondShadow(ctx context.Context, w http.ResponseWriter, resp *ProcessResponse, calls []string) {
+	slog.InfoContext(ctx, "shadow mode reply withheld",
+		"reply", resp.Reply,
+		"tool_calls", calls,
+		"has_media", resp.MediaBase64 != "",
+		"deletes", len(resp.Delete),
+	)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,40 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+)
+
+// toolCallTopChats is how many chats the tool call report lists.
+const toolCallTopChats = 20
+
+// ToolCalls handles POST /api/v1/admin/tool_calls: per-tool and per-chat statistics from the
+// tool_calls audit for the last days (default 7), optionally for one chat and/or tool.
+func (a *AdminHandler) ToolCalls(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Days   int    `json:"days"`
+		ChatID int64  `json:"chat_id"`
+		Tool   string `json:"tool"`
+	}
+	if _, ok := a.decodeAdmin(w, r, "tool_calls", &req); !ok {
+		return
+	}
+	if req.Days == 0 {
+		req.Days = 7
+	}
+	if req.Days < 1 || req.Days > maxReportDays {
+		apierror.Write(w, r, apierror.InvalidArgument, "days must be 1-90")
+		return
+	}
+	until := time.Now()
+	report, err := a.db.ToolCallStats(r.Context(), until.AddDate(0, 0, -req.Days), until, req.ChatID, req.Tool, toolCallTopChats)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "tool call stats failed", "days", req.Days, "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return
+	}
+	writeJSON(w, report)
+}
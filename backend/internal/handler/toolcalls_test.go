@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAdmin_ToolCalls_Validation(t *testing.T) {
+	a := newTestAdmin()
+	for body, want := range map[string]int{
+		`{"user_id": 222}`:             http.StatusForbidden,
+		`{"user_id": 111, "days": -1}`: http.StatusBadRequest,
+		`{"user_id": 111, "days": 91}`: http.StatusBadRequest,
+	} {
+		req := httptest.NewRequest("POST", "/api/v1/admin/tool_calls", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		a.ToolCalls(w, req)
+		if w.Code != want {
+			t.Errorf("%s: expected %d, got %d", body, want, w.Code)
+		}
+	}
+}
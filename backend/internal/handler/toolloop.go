@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"google.golang.org/genai"
+)
+
+// answerNowInstruction is sent with the last tool results when the tool loop is cut short
+// (MAX_TOOL_ITERATIONS reached or the model repeating a call), for a final turn without tools.
+const answerNowInstruction = "Stop calling tools. Answer the user now with what you already have. " +
+	"If the tools did not give you what you needed, say so briefly instead of guessing."
+
+// repeatedCallOutput replaces the result of a call made more than MAX_REPEATED_TOOL_CALLS times.
+const repeatedCallOutput = "Not run again: you already made this exact call. Use the earlier result."
+
+// loopGuard watches the tool loop of one reply: it counts identical calls (same tool and
+// arguments) and remembers the first one the model repeated too often.
+type loopGuard struct {
+	maxRepeats int
+	seen       map[string]int
+	stuck      string // tool name of the repeated call; empty while the loop is healthy
+}
+
+func newLoopGuard(maxRepeats int) *loopGuard {
+	return &loopGuard{maxRepeats: maxRepeats, seen: make(map[string]int)}
+}
+
+// allow records a call and reports whether it may run. A call already made maxRepeats times
+// is refused and marks the loop as stuck.
+func (g *loopGuard) allow(fc *genai.FunctionCall) bool {
+	args, _ := json.Marshal(fc.Args) // map keys are sorted, so equal arguments give equal keys
+	key := fc.Name + " " + string(args)
+	g.seen[key]++
+	if g.seen[key] > g.maxRepeats {
+		if g.stuck == "" {
+			g.stuck = fc.Name
+		}
+		return false
+	}
+	return true
+}
+
+// answerNow asks the model for a final text answer after the tool loop was cut short. The
+// instruction joins the last tool results; the returned contents include the model's answer.
+func (h *Handler) answerNow(ctx context.Context, contents []*genai.Content, genaiTools []*genai.Tool, opts llm.GenerateOptions) (string, []*genai.Content, error) {
+	last := contents[len(contents)-1]
+	last.Parts = append(last.Parts, genai.NewPartFromText(answerNowInstruction))
+
+	callCtx, cancel := withStageTimeout(ctx, h.config.LLMCallTimeoutSeconds)
+	defer cancel()
+	opts.NoToolCalls = true
+	resp, err := h.llm.GenerateResponseWithOptions(callCtx, contents, genaiTools, opts)
+	if err != nil {
+		return "", contents, err
+	}
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", contents, nil
+	}
+	content := resp.Candidates[0].Content
+	text := ""
+	for _, part := range content.Parts {
+		text += part.Text
+	}
+	if text == "" {
+		slog.WarnContext(ctx, "final answer after the tool loop was empty")
+	}
+	return text, append(contents, content), nil
+}
@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestLoopGuard(t *testing.T) {
+	g := newLoopGuard(2)
+	call := func(name string, args map[string]any) bool {
+		return g.allow(&genai.FunctionCall{Name: name, Args: args})
+	}
+
+	if !call("search_web", map[string]any{"query": "погода", "lang": "uk"}) {
+		t.Fatal("first call must run")
+	}
+	if !call("search_web", map[string]any{"lang": "uk", "query": "погода"}) {
+		t.Fatal("second identical call must run with maxRepeats 2")
+	}
+	if !call("search_web", map[string]any{"query": "новини"}) || !call("calculator", map[string]any{"query": "погода"}) {
+		t.Error("calls with other arguments or tools are not repeats")
+	}
+	if g.stuck != "" {
+		t.Fatalf("loop marked stuck too early: %q", g.stuck)
+	}
+	if call("search_web", map[string]any{"query": "погода", "lang": "uk"}) {
+		t.Error("third identical call must be refused")
+	}
+	if g.stuck != "search_web" {
+		t.Errorf("expected search_web as the stuck call, got %q", g.stuck)
+	}
+	call("calculator", map[string]any{"query": "погода"})
+	call("calculator", map[string]any{"query": "погода"})
+	if g.stuck != "search_web" {
+		t.Errorf("the first stuck call is kept, got %q", g.stuck)
+	}
+}
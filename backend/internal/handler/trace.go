@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"google.golang.org/genai"
+)
+
+const (
+	traceKeyPrefix = "trace:"
+	// maxTraceToolOutput bounds each recorded tool output (generated images are base64).
+	maxTraceToolOutput = 4000
+)
+
+// errNoTrace is returned when no trace is stored for a request (DEBUG_TRACE off, expired or unknown).
+var errNoTrace = errors.New("no trace for this request")
+
+// debugTrace is everything one reply was generated from, stored with DEBUG_TRACE.
+// Contents holds the whole conversation sent to Gemini: the assembled prompt, each model turn
+// (with its function calls) and the function responses that followed.
+type debugTrace struct {
+	RequestID     string           `json:"request_id"`
+	ChatID        int64            `json:"chat_id"`
+	UserID        int64            `json:"user_id"`
+	ThreadID      int64            `json:"thread_id,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	Language      string           `json:"language"`
+	Persona       string           `json:"persona"`
+	Model         string           `json:"model,omitempty"` // canary model; empty = GEMINI_MODEL
+	Temperature   *float64         `json:"temperature,omitempty"`
+	DisabledTools []string         `json:"disabled_tools,omitempty"`
+	Contents      []*genai.Content `json:"contents"`
+	ToolCalls     []traceToolCall  `json:"tool_calls,omitempty"`
+	Reply         string           `json:"reply"`
+}
+
+// traceToolCall is one executed tool call with its raw result.
+type traceToolCall struct {
+	Name   string         `json:"name"`
+	Args   map[string]any `json:"args,omitempty"`
+	Output string         `json:"output,omitempty"`
+	Error  string         `json:"error,omitempty"`
+}
+
+// turnOutput is what a model turn said: text and the function calls it asked for.
+type turnOutput struct {
+	Text          string                `json:"text,omitempty"`
+	FunctionCalls []*genai.FunctionCall `json:"function_calls,omitempty"`
+}
+
+// replayResult compares a recorded model turn with a fresh generation from the same history.
+type replayResult struct {
+	RequestID string      `json:"request_id"`
+	Turn      int         `json:"turn"`
+	Turns     int         `json:"turns"`
+	Original  *turnOutput `json:"original,omitempty"`
+	Replayed  turnOutput  `json:"replayed"`
+}
+
+// tracer loads and replays stored traces; *Handler implements it for the admin endpoints.
+type tracer interface {
+	loadTrace(ctx context.Context, requestID string) (*debugTrace, error)
+	replay(ctx context.Context, t *debugTrace, turn int) (*replayResult, error)
+}
+
+func traceKey(requestID string) string {
+	return traceKeyPrefix + requestID
+}
+
+// recordToolCall appends a tool call to the trace; t may be nil (tracing off).
+func (t *debugTrace) recordToolCall(fc *genai.FunctionCall, output, errMsg string) {
+	if t == nil {
+		return
+	}
+	if r := []rune(output); len(r) > maxTraceToolOutput {
+		output = string(r[:maxTraceToolOutput]) + "…"
+	}
+	t.ToolCalls = append(t.ToolCalls, traceToolCall{Name: fc.Name, Args: fc.Args, Output: output, Error: errMsg})
+}
+
+// saveTrace stores a trace for DEBUG_TRACE_TTL_HOURS (best effort).
+func (h *Handler) saveTrace(ctx context.Context, t *debugTrace) {
+	ttl := time.Duration(max(h.config.DebugTraceTTLHours, 1)) * time.Hour
+	if err := h.cache.SetJSON(ctx, traceKey(t.RequestID), t, ttl); err != nil {
+		slog.WarnContext(ctx, "store debug trace failed", "error", err)
+	}
+}
+
+// loadTrace returns the stored trace of a request, or errNoTrace.
+func (h *Handler) loadTrace(ctx context.Context, requestID string) (*debugTrace, error) {
+	var t debugTrace
+	found, err := h.cache.GetJSON(ctx, traceKey(requestID), &t)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errNoTrace
+	}
+	return &t, nil
+}
+
+// replay regenerates model turn number turn (0 = the first answer to the prompt) from the
+// recorded history, with the recorded persona, model and temperature and today's tool declarations.
+// Tools are not executed: later turns reuse the recorded function responses.
+func (h *Handler) replay(ctx context.Context, t *debugTrace, turn int) (*replayResult, error) {
+	history, original, turns, err := replayHistory(t.Contents, turn)
+	if err != nil {
+		return nil, err
+	}
+	opts := llm.GenerateOptions{Persona: t.Persona, Temperature: t.Temperature, Model: t.Model}
+	resp, err := h.llm.GenerateResponseWithOptions(ctx, history, h.registry.GetToolsExcept(t.DisabledTools), opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &replayResult{RequestID: t.RequestID, Turn: turn, Turns: turns}
+	if original != nil {
+		out := summarizeTurn(original)
+		res.Original = &out
+	}
+	if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
+		res.Replayed = summarizeTurn(resp.Candidates[0].Content)
+	}
+	return res, nil
+}
+
+// replayHistory cuts the recorded contents before model turn number turn: the history up to and
+// including the user content that turn answered, and the recorded turn itself (nil if the request
+// failed before it). Also returns how many user contents (replayable turns) there are.
+func replayHistory(contents []*genai.Content, turn int) ([]*genai.Content, *genai.Content, int, error) {
+	var userIdx []int
+	for i, c := range contents {
+		if c != nil && c.Role == "user" {
+			userIdx = append(userIdx, i)
+		}
+	}
+	if turn < 0 || turn >= len(userIdx) {
+		return nil, nil, len(userIdx), fmt.Errorf("turn must be between 0 and %d", len(userIdx)-1)
+	}
+	end := userIdx[turn] + 1
+	var original *genai.Content
+	if end < len(contents) && contents[end] != nil && contents[end].Role == "model" {
+		original = contents[end]
+	}
+	return contents[:end], original, len(userIdx), nil
+}
+
+// summarizeTurn collects the text and function calls of a model turn.
+func summarizeTurn(c *genai.Content) turnOutput {
+	var out turnOutput
+	for _, p := range c.Parts {
+		if p.Text != "" && !p.Thought {
+			out.Text += p.Text
+		}
+		if p.FunctionCall != nil {
+			out.FunctionCalls = append(out.FunctionCalls, p.FunctionCall)
+		}
+	}
+	return out
+}
+
+// decodeTrace checks the admin and loads the trace named in the path. On failure it writes the
+// error response and returns nil.
+func (a *AdminHandler) decodeTrace(w http.ResponseWriter, r *http.Request, action string, v any) *debugTrace {
+	if _, ok := a.decodeAdmin(w, r, action, v); !ok {
+		return nil
+	}
+	if a.tracer == nil {
+		apierror.Write(w, r, apierror.Disabled, "debug tracing is disabled")
+		return nil
+	}
+	t, err := a.tracer.loadTrace(r.Context(), r.PathValue("request_id"))
+	if errors.Is(err, errNoTrace) {
+		apierror.Write(w, r, apierror.NotFound, "no trace for this request")
+		return nil
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "load debug trace failed", "error", err)
+		apierror.Write(w, r, apierror.Internal, "internal error")
+		return nil
+	}
+	return t
+}
+
+// Trace handles POST /api/v1/admin/trace/{request_id}: the stored prompt, tool calls and reply.
+func (a *AdminHandler) Trace(w http.ResponseWriter, r *http.Request) {
+	if t := a.decodeTrace(w, r, "trace", nil); t != nil {
+		writeJSON(w, t)
+	}
+}
+
+// Replay handles POST /api/v1/admin/replay/{request_id}: regenerates one model turn of a traced
+// request ({"turn": n}, default 0) and returns it next to the recorded one.
+func (a *AdminHandler) Replay(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Turn int `json:"turn"`
+	}
+	t := a.decodeTrace(w, r, "replay", &req)
+	if t == nil {
+		return
+	}
+	if _, _, _, err := replayHistory(t.Contents, req.Turn); err != nil {
+		apierror.Write(w, r, apierror.InvalidArgument, err.Error())
+		return
+	}
+	res, err := a.tracer.replay(r.Context(), t, req.Turn)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "replay failed", "request_id", t.RequestID, "error", err)
+		apierror.Write(w, r, apierror.Upstream, "replay failed")
+		return
+	}
+	slog.InfoContext(r.Context(), "request replayed", "request_id", t.RequestID, "turn", req.Turn)
+	writeJSON(w, res)
+}
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+// traceContents is a recorded request with one tool round: prompt, call, result, answer.
+func traceContents() []*genai.Content {
+	return []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText("prompt")}},
+		{Role: "model", Parts: []*genai.Part{genai.NewPartFromFunctionCall("time_info", map[string]any{"location": "Kyiv"})}},
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromFunctionResponse("time_info", map[string]any{"result": "13:00"})}},
+		{Role: "model", Parts: []*genai.Part{genai.NewPartFromText("Зараз 13:00")}},
+	}
+}
+
+func TestReplayHistory(t *testing.T) {
+	contents := traceContents()
+	history, original, turns, err := replayHistory(contents, 0)
+	if err != nil || len(history) != 1 || turns != 2 {
+		t.Fatalf("turn 0: history %d, turns %d, err %v", len(history), turns, err)
+	}
+	if out := summarizeTurn(original); len(out.FunctionCalls) != 1 || out.FunctionCalls[0].Name != "time_info" {
+		t.Errorf("turn 0 original = %+v, want the time_info call", out)
+	}
+	history, original, _, err = replayHistory(contents, 1)
+	if err != nil || len(history) != 3 || summarizeTurn(original).Text != "Зараз 13:00" {
+		t.Errorf("turn 1: history %d, original %+v, err %v", len(history), original, err)
+	}
+	if _, original, _, _ := replayHistory(contents[:3], 1); original != nil {
+		t.Error("a turn that was never answered has no original")
+	}
+	for _, turn := range []int{-1, 2} {
+		if _, _, _, err := replayHistory(contents, turn); err == nil {
+			t.Errorf("turn %d should be out of range", turn)
+		}
+	}
+}
+
+func TestDebugTrace_RecordToolCall(t *testing.T) {
+	var none *debugTrace
+	none.recordToolCall(&genai.FunctionCall{Name: "x"}, "out", "") // tracing off: no panic
+
+	tr := &debugTrace{}
+	tr.recordToolCall(&genai.FunctionCall{Name: "generate_image"}, strings.Repeat("A", maxTraceToolOutput+10), "")
+	if len(tr.ToolCalls) != 1 || len([]rune(tr.ToolCalls[0].Output)) != maxTraceToolOutput+1 {
+		t.Errorf("expected one call with its output truncated, got %+v", len(tr.ToolCalls))
+	}
+}
+
+func TestDebugTrace_JSONRoundTrip(t *testing.T) {
+	in := &debugTrace{RequestID: "r1", Contents: traceContents(), Reply: "Зараз 13:00"}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out debugTrace
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Contents) != 4 || out.Contents[1].Parts[0].FunctionCall.Args["location"] != "Kyiv" {
+		t.Errorf("contents did not survive a round trip: %s", data)
+	}
+}
+
+type fakeTracer struct{ trace *debugTrace }
+
+func (f *fakeTracer) loadTrace(_ context.Context, requestID string) (*debugTrace, error) {
+	if f.trace == nil || f.trace.RequestID != requestID {
+		return nil, errNoTrace
+	}
+	return f.trace, nil
+}
+
+func (f *fakeTracer) replay(_ context.Context, t *debugTrace, turn int) (*replayResult, error) {
+	return &replayResult{RequestID: t.RequestID, Turn: turn, Replayed: turnOutput{Text: "again"}}, nil
+}
+
+func TestAdmin_TraceAndReplay(t *testing.T) {
+	a := newTestAdmin()
+	call := func(h http.HandlerFunc, id, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/v1/admin/replay/"+id, strings.NewReader(body))
+		req.SetPathValue("request_id", id)
+		w := httptest.NewRecorder()
+		h(w, req)
+		return w
+	}
+	if w := call(a.Trace, "r1", `{"user_id": 111}`); w.Code != http.StatusNotFound {
+		t.Errorf("tracing disabled: expected 404, got %d", w.Code)
+	}
+
+	a.SetTracer(&fakeTracer{trace: &debugTrace{RequestID: "r1", Contents: traceContents()}})
+	tests := []struct {
+		handler http.HandlerFunc
+		id      string
+		body    string
+		want    int
+	}{
+		{a.Trace, "r1", `{"user_id": 222}`, http.StatusForbidden},
+		{a.Trace, "missing", `{"user_id": 111}`, http.StatusNotFound},
+		{a.Trace, "r1", `{"user_id": 111}`, http.StatusOK},
+		{a.Replay, "r1", `{"user_id": 111, "turn": 5}`, http.StatusBadRequest},
+		{a.Replay, "r1", `{"user_id": 111, "turn": 1}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		if w := call(tt.handler, tt.id, tt.body); w.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d (%s)", tt.id, tt.body, tt.want, w.Code, w.Body.String())
+		}
+	}
+	if w := call(a.Replay, "r1", `{"user_id": 111}`); !strings.Contains(w.Body.String(), `"again"`) {
+		t.Errorf("expected the replayed turn, got %s", w.Body.String())
+	}
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+// Trigger reasons, logged with every reply decision.
+const (
+	triggerPrivate      = "private"
+	triggerCommand      = "command"
+	triggerMention      = "mention"      // @username or a text mention of the bot
+	triggerReplyToBot   = "reply_to_bot" // a reply to one of the bot's messages
+	triggerAddressed    = "addressed"    // an older frontend's own decision (addressed: true or absent)
+	triggerKeyword      = "keyword"      // TRIGGER_KEYWORDS
+	triggerName         = "name"         // BOT_NAMES, with the chat's mention_reply_probability
+	triggerInterjection = "interjection" // INTERJECTION_PROBABILITY
+)
+
+// triggerDecision is why the bot may answer a message, and the chance it does (1 = always).
+// An empty Reason means the message does not trigger a reply.
+type triggerDecision struct {
+	Reason      string
+	Probability float64
+}
+
+// direct reports whether the decision answers without a roll or the daily cap.
+func (d triggerDecision) direct() bool {
+	return d.Reason != "" && d.Probability >= 1
+}
+
+// evaluateTrigger decides whether a message can get a reply from the facts the frontend reports
+// about it and the configured triggers. Requests without chat_type come from frontends that decide
+// themselves and only send addressed.
+func evaluateTrigger(req *ProcessRequest, cfg *config.Config, s *chatsettings.Settings) triggerDecision {
+	if req.ChatType == "" {
+		if req.Addressed == nil || *req.Addressed {
+			return triggerDecision{triggerAddressed, 1}
+		}
+	} else {
+		switch {
+		case req.ChatType == "private":
+			return triggerDecision{triggerPrivate, 1}
+		case req.IsCommand:
+			return triggerDecision{triggerCommand, 1}
+		case req.MentionsBot:
+			return triggerDecision{triggerMention, 1}
+		case req.ReplyToBot && cfg.TriggerReplyToBot:
+			return triggerDecision{triggerReplyToBot, 1}
+		}
+	}
+	if matchesKeyword(req.Text, cfg.TriggerKeywords) {
+		return triggerDecision{triggerKeyword, 1}
+	}
+	if mentionsBotName(req.Text, cfg.BotNames) {
+		return triggerDecision{triggerName, s.MentionReplyProbability}
+	}
+	if cfg.InterjectionProbability > 0 {
+		return triggerDecision{triggerInterjection, cfg.InterjectionProbability}
+	}
+	return triggerDecision{}
+}
+
+// matchesKeyword reports whether text contains one of the keywords: single words match like bot
+// names (a word starting with them), phrases as a case-insensitive substring.
+func matchesKeyword(text string, keywords []string) bool {
+	lower := strings.ToLower(text)
+	for _, k := range keywords {
+		if strings.ContainsAny(k, " \t") {
+			if strings.Contains(lower, strings.ToLower(k)) {
+				return true
+			}
+		} else if mentionsBotName(text, []string{k}) {
+			return true
+		}
+	}
+	return false
+}
+
+// triggerContextKey holds the reply decision Triggered made for a request.
+type triggerContextKey struct{}
+
+// Triggered decides whether a /process body gets a reply, rolling name mentions and interjections
+// once within the chat's daily cap, and returns ctx carrying the decision for shouldReply. The
+// rate limiter asks it first (RateLimiter.SetTriggerCheck), so only messages that will be answered
+// take the chat's and user's limits. Bodies that don't decode count as triggered and are left for
+// the handler to refuse.
+func (h *Handler) Triggered(ctx context.Context, body []byte) (context.Context, bool) {
+	var req ProcessRequest
+	if json.Unmarshal(body, &req) != nil {
+		return ctx, true
+	}
+	albumFromContext(ctx).apply(&req)
+	reply := h.decideReply(ctx, &req, h.chatSettings(ctx, req.ChatID))
+	return context.WithValue(ctx, triggerContextKey{}, reply), reply
+}
+
+// shouldReply applies the trigger policy, or returns the decision Triggered already made for the
+// request so a probability is never rolled twice.
+func (h *Handler) shouldReply(ctx context.Context, req *ProcessRequest, s *chatsettings.Settings) bool {
+	if reply, ok := ctx.Value(triggerContextKey{}).(bool); ok {
+		return reply
+	}
+	return h.decideReply(ctx, req, s)
+}
+
+// decideReply makes the reply decision: direct triggers always reply, name mentions and
+// interjections roll their probability within the chat's daily cap.
+func (h *Handler) decideReply(ctx context.Context, req *ProcessRequest, s *chatsettings.Settings) bool {
+	d := evaluateTrigger(req, h.config, s)
+	switch {
+	case d.Reason == "":
+		slog.InfoContext(ctx, "not triggered, staying silent")
+		return false
+	case d.direct():
+		slog.InfoContext(ctx, "triggered", "trigger", d.Reason)
+		return true
+	case h.allowIndirectReply(ctx, s, d.Probability):
+		slog.InfoContext(ctx, "triggered", "trigger", d.Reason, "probability", d.Probability)
+		return true
+	}
+	slog.InfoContext(ctx, "trigger roll failed, staying silent", "trigger", d.Reason)
+	return false
+}
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/middleware"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestEvaluateTrigger(t *testing.T) {
+	cfg := &config.Config{
+		BotNames:          []string{"гряг"},
+		TriggerReplyToBot: true,
+		TriggerKeywords:   []string{"погод", "хто винен"},
+	}
+	s := &chatsettings.Settings{ChatID: 1, MentionReplyProbability: 0.3}
+	no, yes := false, true
+	tests := []struct {
+		name string
+		req  ProcessRequest
+		want triggerDecision
+	}{
+		{"private", ProcessRequest{ChatType: "private", Text: "привіт"}, triggerDecision{triggerPrivate, 1}},
+		{"command", ProcessRequest{ChatType: "supergroup", IsCommand: true, Text: "/stats"}, triggerDecision{triggerCommand, 1}},
+		{"mention", ProcessRequest{ChatType: "group", MentionsBot: true}, triggerDecision{triggerMention, 1}},
+		{"reply to bot", ProcessRequest{ChatType: "group", ReplyToBot: true}, triggerDecision{triggerReplyToBot, 1}},
+		{"keyword word", ProcessRequest{ChatType: "group", Text: "яка завтра погода?"}, triggerDecision{triggerKeyword, 1}},
+		{"keyword phrase", ProcessRequest{ChatType: "group", Text: "Ну і хто винен?"}, triggerDecision{triggerKeyword, 1}},
+		{"name", ProcessRequest{ChatType: "group", Text: "а гряг що скаже"}, triggerDecision{triggerName, 0.3}},
+		{"nothing", ProcessRequest{ChatType: "group", Text: "просто текст"}, triggerDecision{}},
+		{"legacy addressed", ProcessRequest{Addressed: &yes, Text: "просто текст"}, triggerDecision{triggerAddressed, 1}},
+		{"legacy absent", ProcessRequest{Text: "просто текст"}, triggerDecision{triggerAddressed, 1}},
+		{"legacy not addressed", ProcessRequest{Addressed: &no, Text: "гряг?"}, triggerDecision{triggerName, 0.3}},
+		{"chat_type wins over addressed", ProcessRequest{ChatType: "group", Addressed: &yes, Text: "просто текст"}, triggerDecision{}},
+	}
+	for _, tt := range tests {
+		if got := evaluateTrigger(&tt.req, cfg, s); got != tt.want {
+			t.Errorf("%s: got %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	cfg.TriggerReplyToBot = false
+	if got := evaluateTrigger(&ProcessRequest{ChatType: "group", ReplyToBot: true}, cfg, s); got.Reason != "" {
+		t.Errorf("reply to bot with the trigger off: got %+v", got)
+	}
+	cfg.InterjectionProbability = 0.05
+	if got := evaluateTrigger(&ProcessRequest{ChatType: "group", Text: "просто текст"}, cfg, s); got != (triggerDecision{triggerInterjection, 0.05}) {
+		t.Errorf("interjection: got %+v", got)
+	}
+}
+
+func TestShouldReply(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+	randFloat = func() float64 { return 0.5 }
+
+	h := &Handler{config: &config.Config{BotNames: []string{"гряг"}}}
+	s := &chatsettings.Settings{ChatID: 1, MentionReplyProbability: 0.3}
+	ctx := context.Background()
+	if !h.shouldReply(ctx, &ProcessRequest{ChatType: "group", MentionsBot: true}, s) {
+		t.Error("direct triggers should always reply")
+	}
+	if h.shouldReply(ctx, &ProcessRequest{ChatType: "group", Text: "гряг"}, s) {
+		t.Error("name mention over the probability should stay silent")
+	}
+	randFloat = func() float64 { return 0.1 }
+	if !h.shouldReply(ctx, &ProcessRequest{ChatType: "group", Text: "гряг"}, s) {
+		t.Error("name mention under the probability should reply")
+	}
+	if h.shouldReply(ctx, &ProcessRequest{ChatType: "group", Text: "текст"}, s) {
+		t.Error("untriggered messages should stay silent")
+	}
+}
+
+func TestTriggered(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+	randFloat = func() float64 { return 0.5 }
+
+	h := &Handler{config: &config.Config{BotNames: []string{"гряг"}, MentionReplyProbability: 0.3}}
+	ctx := context.Background()
+	for body, want := range map[string]bool{
+		`{"chat_id": -1, "chat_type": "group", "text": "просто текст"}`:  false,
+		`{"chat_id": -1, "chat_type": "group", "text": "гряг, привіт"}`:  false, // lost the roll
+		`{"chat_id": -1, "chat_type": "group", "mentions_bot": true}`:    true,
+		`{"chat_id": 5, "chat_type": "private", "text": "просто текст"}`: true,
+		`not json`: true,
+	} {
+		if _, got := h.Triggered(ctx, []byte(body)); got != want {
+			t.Errorf("Triggered(%s) = %v, want %v", body, got, want)
+		}
+	}
+	// A mention in another album item triggers the whole album
+	albumCtx := context.WithValue(ctx, albumContextKey{}, album{{MentionsBot: true}})
+	if _, ok := h.Triggered(albumCtx, []byte(`{"chat_id": -1, "chat_type": "group"}`)); !ok {
+		t.Error("expected the album's mention to count")
+	}
+
+	// The handler reuses the decision instead of rolling again
+	randFloat = func() float64 { return 0.1 }
+	decided, ok := h.Triggered(ctx, []byte(`{"chat_id": -1, "chat_type": "group", "text": "гряг, привіт"}`))
+	randFloat = func() float64 { return 0.9 }
+	if !ok || !h.shouldReply(decided, &ProcessRequest{ChatType: "group", Text: "гряг, привіт"}, h.chatSettings(ctx, -1)) {
+		t.Error("expected the won roll to stand in shouldReply")
+	}
+}
+
+func TestTriggered_RateLimitsOnlyReplies(t *testing.T) {
+	orig := randFloat
+	defer func() { randFloat = orig }()
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	cfg := &config.Config{
+		RedisFallback:            config.RedisFallbackMemory,
+		RateLimitGlobalPerMinute: 1,
+		RateLimitGlobalBurst:     1,
+		InterjectionProbability:  0.2,
+	}
+	h := &Handler{config: cfg}
+	rl := middleware.NewRateLimiter(cache.Wrap(client), nil, cfg, nil)
+	rl.SetTriggerCheck(h.Triggered)
+	var replies []bool
+	srv := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replies = append(replies, h.shouldReply(r.Context(), &ProcessRequest{ChatType: "group"}, h.chatSettings(r.Context(), -100)))
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func() int {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/process", strings.NewReader(`{"chat_id": -100, "chat_type": "group", "text": "просто текст"}`)))
+		return rec.Code
+	}
+
+	// Every group message is an interjection candidate; the lost rolls take no tokens
+	randFloat = func() float64 { return 0.9 }
+	for range 5 {
+		if code := send(); code != http.StatusOK {
+			t.Fatalf("expected chatter to reach the handler, got %d", code)
+		}
+	}
+	randFloat = func() float64 { return 0.1 }
+	if code := send(); code != http.StatusOK {
+		t.Fatalf("expected the interjection to get the chat's only token, got %d", code)
+	}
+	if len(replies) != 6 || replies[4] || !replies[5] {
+		t.Errorf("expected five silent messages and one reply, got %v", replies)
+	}
+}
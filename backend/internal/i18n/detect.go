@@ -0,0 +1,105 @@
+package i18n
+
+import (
+	"strings"
+	"unicode"
+)
+
+// minDetectLetters is the minimum number of letters before Detect trusts its guess.
+const minDetectLetters = 12
+
+// languageNames maps language codes to the English names used in prompt hints.
+var languageNames = map[string]string{
+	"uk": "Ukrainian",
+	"en": "English",
+	"ru": "Russian",
+	"pl": "Polish",
+	"de": "German",
+	"fr": "French",
+	"es": "Spanish",
+	"it": "Italian",
+}
+
+// LanguageName returns a human-readable name for a language code, or the code itself if unknown.
+func LanguageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// Normalize turns a Telegram/IETF language tag ("en-US", "UK") into a bare lowercase code ("en", "uk").
+func Normalize(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	return code
+}
+
+// Detect guesses the language of text with a cheap script/letter heuristic.
+// It only answers when reasonably sure: Ukrainian-only letters (і ї є ґ) mean "uk",
+// Russian-only letters (ы э ъ ё) mean "ru", mostly-Latin text means "en".
+// Short or ambiguous text returns ok=false.
+func Detect(text string) (lang string, ok bool) {
+	var letters, latin, cyrillic, ukOnly, ruOnly int
+	for _, r := range strings.ToLower(text) {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Cyrillic, r):
+			cyrillic++
+			switch r {
+			case 'і', 'ї', 'є', 'ґ':
+				ukOnly++
+			case 'ы', 'э', 'ъ', 'ё':
+				ruOnly++
+			}
+		}
+	}
+	if letters < minDetectLetters {
+		return "", false
+	}
+	if cyrillic*10 >= letters*7 {
+		switch {
+		case ukOnly > 0 && ruOnly == 0:
+			return "uk", true
+		case ruOnly > 0 && ukOnly == 0:
+			return "ru", true
+		}
+		return "", false
+	}
+	if latin*10 >= letters*8 {
+		return "en", true
+	}
+	return "", false
+}
+
+// minDominantVotes is how many messages must be recognized before DominantLanguage answers.
+const minDominantVotes = 5
+
+// DominantLanguage returns the language most of texts are written in (by Detect), when at least
+// minDominantVotes texts were recognized and the winner has more than half of them.
+func DominantLanguage(texts []string) (lang string, ok bool) {
+	votes := make(map[string]int)
+	total := 0
+	for _, t := range texts {
+		if l, ok := Detect(t); ok {
+			votes[l]++
+			total++
+		}
+	}
+	if total < minDominantVotes {
+		return "", false
+	}
+	for l, n := range votes {
+		if n*2 > total {
+			return l, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,56 @@
+package i18n
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		wantOK bool
+	}{
+		{"Привіт, як справи? Що нового в чаті?", "uk", true},
+		{"Привет, как дела? Что нового в этом чате?", "ru", true},
+		{"Hey, what's new in this chat today?", "en", true},
+		{"ок", "", false},
+		{"Добрий день колеги", "", false}, // Cyrillic without distinguishing letters
+		{"😂😂😂 123 !!!", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Detect(tt.text)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Detect(%q) = (%q, %v), want (%q, %v)", tt.text, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{"en-US": "en", "UK": "uk", " pt_BR ": "pt", "": ""}
+	for in, want := range cases {
+		if got := Normalize(in); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLanguageName(t *testing.T) {
+	if LanguageName("uk") != "Ukrainian" {
+		t.Errorf("expected Ukrainian, got %s", LanguageName("uk"))
+	}
+	if LanguageName("xx") != "xx" {
+		t.Errorf("expected unknown code to pass through, got %s", LanguageName("xx"))
+	}
+}
+
+func TestDominantLanguage(t *testing.T) {
+	uk := "Привіт, як справи? Що нового в чаті?"
+	en := "Hey, what's new in this chat today?"
+	if got, ok := DominantLanguage([]string{uk, uk, uk, en, "ок", en, uk}); !ok || got != "uk" {
+		t.Errorf("expected uk, got (%q, %v)", got, ok)
+	}
+	if _, ok := DominantLanguage([]string{uk, uk, en}); ok {
+		t.Error("expected no answer with too few recognized messages")
+	}
+	if _, ok := DominantLanguage([]string{uk, uk, uk, en, en, en}); ok {
+		t.Error("expected no answer without a majority")
+	}
+}
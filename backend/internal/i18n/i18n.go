@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 )
@@ -17,43 +18,68 @@ type Locale struct {
 }
 
 // Bundle manages multiple locales and provides string lookups.
+// Locales can be reloaded from disk at runtime with Reload.
 type Bundle struct {
-	locales    map[string]*Locale
+	mu          sync.RWMutex
+	dir         string
+	locales     map[string]*Locale
 	defaultLang string
+	loaded      dirState // fingerprint of the directory at the last successful load
 }
 
 // NewBundle creates a new i18n bundle from a directory of JSON locale files.
 // Each file should be named like "uk.json", "en.json", etc.
 func NewBundle(localeDir, defaultLang string) (*Bundle, error) {
-	b := &Bundle{
-		locales:     make(map[string]*Locale),
+	state := snapshotDir(localeDir)
+	locales, err := loadLocaleDir(localeDir, defaultLang)
+	if err != nil {
+		return nil, err
+	}
+	return &Bundle{
+		dir:         localeDir,
+		locales:     locales,
 		defaultLang: defaultLang,
+		loaded:      state,
+	}, nil
+}
+
+// Reload re-reads every locale file from disk and swaps them in atomically.
+// On any error (bad JSON, missing default locale) the currently loaded locales stay in place.
+func (b *Bundle) Reload() error {
+	state := snapshotDir(b.dir)
+	locales, err := loadLocaleDir(b.dir, b.defaultLang)
+	if err != nil {
+		return err
 	}
+	b.mu.Lock()
+	b.locales = locales
+	b.loaded = state
+	b.mu.Unlock()
+	return nil
+}
 
-	entries, err := os.ReadDir(localeDir)
+// loadLocaleDir parses all *.json files in dir and checks the default locale is present.
+func loadLocaleDir(dir, defaultLang string) (map[string]*Locale, error) {
+	entries, err := os.ReadDir(dir)
 	if err != nil {
-		return nil, fmt.Errorf("read locale dir %s: %w", localeDir, err)
+		return nil, fmt.Errorf("read locale dir %s: %w", dir, err)
 	}
 
+	locales := make(map[string]*Locale)
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
 			continue
 		}
 
 		lang := strings.TrimSuffix(entry.Name(), ".json")
-		path := localeDir + "/" + entry.Name()
+		path := dir + "/" + entry.Name()
 
-		data, err := os.ReadFile(path)
+		strings, err := loadLocaleFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("read locale file %s: %w", path, err)
-		}
-
-		var strings map[string]string
-		if err := json.Unmarshal(data, &strings); err != nil {
-			return nil, fmt.Errorf("parse locale file %s: %w", path, err)
+			return nil, err
 		}
 
-		b.locales[lang] = &Locale{
+		locales[lang] = &Locale{
 			strings: strings,
 			lang:    lang,
 		}
@@ -61,40 +87,102 @@ func NewBundle(localeDir, defaultLang string) (*Bundle, error) {
 		slog.Info("loaded locale", "lang", lang, "keys", len(strings))
 	}
 
-	if _, ok := b.locales[defaultLang]; !ok {
-		return nil, fmt.Errorf("default locale %q not found in %s", defaultLang, localeDir)
+	if _, ok := locales[defaultLang]; !ok {
+		return nil, fmt.Errorf("default locale %q not found in %s", defaultLang, dir)
+	}
+
+	return locales, nil
+}
+
+// loadLocaleFile reads one locale JSON file. Values may be strings or nested objects;
+// nested keys are flattened with dots, so {"memory": {"stored": "..."}} is looked up as "memory.stored".
+func loadLocaleFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read locale file %s: %w", path, err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse locale file %s: %w", path, err)
+	}
+
+	flat := make(map[string]string, len(raw))
+	if err := flatten("", raw, flat); err != nil {
+		return nil, fmt.Errorf("parse locale file %s: %w", path, err)
+	}
+	return flat, nil
+}
+
+// flatten copies nested string values from src into dst under dotted keys.
+func flatten(prefix string, src map[string]any, dst map[string]string) error {
+	for k, v := range src {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch val := v.(type) {
+		case string:
+			dst[key] = val
+		case map[string]any:
+			if err := flatten(key, val, dst); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("key %q: expected string or object, got %T", key, v)
+		}
 	}
+	return nil
+}
 
-	return b, nil
+// lookup returns the raw template for key in lang, without fallback.
+func (b *Bundle) lookup(lang, key string) (string, bool) {
+	b.mu.RLock()
+	locale, ok := b.locales[lang]
+	b.mu.RUnlock()
+	if !ok {
+		return "", false
+	}
+	locale.mu.RLock()
+	defer locale.mu.RUnlock()
+	s, ok := locale.strings[key]
+	return s, ok
 }
 
 // T translates a key using the given language, falling back to the default.
 // Supports simple placeholder substitution: {0}, {1}, etc.
 func (b *Bundle) T(lang, key string, args ...string) string {
 	// Try requested language
-	if locale, ok := b.locales[lang]; ok {
-		locale.mu.RLock()
-		if s, ok := locale.strings[key]; ok {
-			locale.mu.RUnlock()
-			return substitute(s, args)
-		}
-		locale.mu.RUnlock()
+	if s, ok := b.lookup(lang, key); ok {
+		return substitute(s, args)
 	}
 
 	// Fall back to default
-	if locale, ok := b.locales[b.defaultLang]; ok {
-		locale.mu.RLock()
-		if s, ok := locale.strings[key]; ok {
-			locale.mu.RUnlock()
-			return substitute(s, args)
-		}
-		locale.mu.RUnlock()
+	if s, ok := b.lookup(b.defaultLang, key); ok {
+		return substitute(s, args)
 	}
 
 	// Key not found — return the key itself
 	return key
 }
 
+// TN translates a pluralized key for count n. The locale defines one entry per CLDR category
+// under the key, e.g. {"pruned": {"one": "{0} повідомлення", "few": "...", "many": "...", "other": "..."}}.
+// {0} is replaced with n, and args fill {1}, {2}, etc. Missing categories fall back to "other",
+// then to the default language.
+func (b *Bundle) TN(lang, key string, n int, args ...string) string {
+	all := append([]string{strconv.Itoa(n)}, args...)
+	for _, l := range []string{lang, b.defaultLang} {
+		if s, ok := b.lookup(l, key+"."+PluralCategory(l, n)); ok {
+			return substitute(s, all)
+		}
+		if s, ok := b.lookup(l, key+".other"); ok {
+			return substitute(s, all)
+		}
+	}
+	return key
+}
+
 // substitute replaces {0}, {1}, etc. with the corresponding args.
 func substitute(template string, args []string) string {
 	result := template
@@ -106,6 +194,8 @@ func substitute(template string, args []string) string {
 
 // Languages returns all loaded language codes.
 func (b *Bundle) Languages() []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	langs := make([]string, 0, len(b.locales))
 	for lang := range b.locales {
 		langs = append(langs, lang)
@@ -115,6 +205,8 @@ func (b *Bundle) Languages() []string {
 
 // HasLanguage checks if a language is loaded.
 func (b *Bundle) HasLanguage(lang string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
 	_, ok := b.locales[lang]
 	return ok
 }
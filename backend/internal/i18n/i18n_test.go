@@ -1,9 +1,11 @@
 package i18n
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func setupTestLocales(t *testing.T) string {
@@ -119,3 +121,131 @@ func TestBundle_MissingDefaultLocale(t *testing.T) {
 		t.Error("expected error for missing default locale 'fr'")
 	}
 }
+
+func TestPluralCategory_Ukrainian(t *testing.T) {
+	tests := map[int]string{
+		1: "one", 21: "one", 101: "one",
+		2: "few", 3: "few", 4: "few", 22: "few", 34: "few",
+		0: "many", 5: "many", 11: "many", 12: "many", 14: "many", 25: "many", 111: "many",
+	}
+	for n, want := range tests {
+		if got := PluralCategory("uk", n); got != want {
+			t.Errorf("PluralCategory(uk, %d) = %s, want %s", n, got, want)
+		}
+	}
+}
+
+func TestPluralCategory_English(t *testing.T) {
+	if PluralCategory("en", 1) != "one" || PluralCategory("en", 0) != "other" || PluralCategory("en", 21) != "other" {
+		t.Error("unexpected English plural categories")
+	}
+}
+
+func TestBundle_NestedKeysAndPlurals(t *testing.T) {
+	dir := t.TempDir()
+	uk := `{
+		"flat": "плоский",
+		"retention": {
+			"pruned": {
+				"one": "Видалено {0} повідомлення в {1}.",
+				"few": "Видалено {0} повідомлення в {1}.",
+				"many": "Видалено {0} повідомлень в {1}."
+			}
+		}
+	}`
+	en := `{
+		"retention": {"pruned": {"one": "Pruned {0} message.", "other": "Pruned {0} messages."}}
+	}`
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(uk), 0644)
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(en), 0644)
+
+	b, err := NewBundle(dir, "uk")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := b.T("uk", "flat"); got != "плоский" {
+		t.Errorf("flat key broken: %q", got)
+	}
+	if got := b.T("en", "retention.pruned.other"); got != "Pruned {0} messages." {
+		t.Errorf("nested key lookup failed: %q", got)
+	}
+	if got := b.TN("uk", "retention.pruned", 21, "чаті"); got != "Видалено 21 повідомлення в чаті." {
+		t.Errorf("uk one: %q", got)
+	}
+	if got := b.TN("uk", "retention.pruned", 12, "чаті"); got != "Видалено 12 повідомлень в чаті." {
+		t.Errorf("uk many: %q", got)
+	}
+	if got := b.TN("en", "retention.pruned", 1); got != "Pruned 1 message." {
+		t.Errorf("en one: %q", got)
+	}
+	if got := b.TN("en", "retention.pruned", 5); got != "Pruned 5 messages." {
+		t.Errorf("en other: %q", got)
+	}
+	if got := b.TN("en", "missing.key", 5); got != "missing.key" {
+		t.Errorf("missing plural key should return the key, got %q", got)
+	}
+}
+
+func TestBundle_InvalidLocaleValue(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"count": 5}`), 0644)
+	if _, err := NewBundle(dir, "en"); err == nil {
+		t.Error("expected error for non-string locale value")
+	}
+}
+
+func TestBundle_Reload(t *testing.T) {
+	dir := setupTestLocales(t)
+	b, err := NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"farewell": "Bye!"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "pl.json"), []byte(`{"farewell": "Do widzenia."}`), 0644)
+	if err := b.Reload(); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if got := b.T("en", "farewell"); got != "Bye!" {
+		t.Errorf("expected reloaded string, got %q", got)
+	}
+	if !b.HasLanguage("pl") {
+		t.Error("expected new locale to be loaded")
+	}
+
+	// A broken file keeps the previous locales in place
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{broken`), 0644)
+	if err := b.Reload(); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+	if got := b.T("en", "farewell"); got != "Bye!" {
+		t.Errorf("expected previous strings after failed reload, got %q", got)
+	}
+}
+
+func TestBundle_Watch(t *testing.T) {
+	dir := setupTestLocales(t)
+	b, err := NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Watch(ctx, 10*time.Millisecond)
+
+	path := filepath.Join(dir, "en.json")
+	os.WriteFile(path, []byte(`{"farewell": "See ya."}`), 0644)
+	future := time.Now().Add(time.Hour)
+	os.Chtimes(path, future, future)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if b.T("en", "farewell") == "See ya." {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("expected watcher to reload locales, got %q", b.T("en", "farewell"))
+}
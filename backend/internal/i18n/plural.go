@@ -0,0 +1,48 @@
+package i18n
+
+// Plural categories as defined by CLDR.
+const (
+	PluralOne   = "one"
+	PluralFew   = "few"
+	PluralMany  = "many"
+	PluralOther = "other"
+)
+
+// PluralCategory returns the CLDR plural category of the integer n for lang.
+// Covers the languages we ship or are likely to add; unknown languages use the English rule.
+func PluralCategory(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	mod10, mod100 := n%10, n%100
+	switch lang {
+	case "uk", "ru", "be":
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	case "pl":
+		switch {
+		case n == 1:
+			return PluralOne
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return PluralFew
+		default:
+			return PluralMany
+		}
+	case "fr":
+		if n == 0 || n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	default:
+		if n == 1 {
+			return PluralOne
+		}
+		return PluralOther
+	}
+}
@@ -0,0 +1,67 @@
+package i18n
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Watch polls the locale directory every interval and reloads the bundle when any *.json file
+// changes (newest modification time or file count). It blocks until ctx is done.
+// Polling avoids an fsnotify dependency and also works on bind mounts, where inotify events are unreliable.
+func (b *Bundle) Watch(ctx context.Context, interval time.Duration) {
+	logger := slog.With("component", "i18n_watch", "dir", b.dir)
+	var failed dirState // last state that failed to load; not retried until it changes again
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		cur := snapshotDir(b.dir)
+		b.mu.RLock()
+		unchanged := cur == b.loaded
+		b.mu.RUnlock()
+		if unchanged || cur == failed {
+			continue
+		}
+		if err := b.Reload(); err != nil {
+			failed = cur
+			logger.Error("locale auto-reload failed, keeping previous locales", "error", err)
+			continue
+		}
+		logger.Info("locales auto-reloaded", "languages", b.Languages())
+	}
+}
+
+// dirState is a cheap fingerprint of the locale directory.
+type dirState struct {
+	files  int
+	newest time.Time
+}
+
+func snapshotDir(dir string) dirState {
+	var s dirState
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return s
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		s.files++
+		if info.ModTime().After(s.newest) {
+			s.newest = info.ModTime()
+		}
+	}
+	return s
+}
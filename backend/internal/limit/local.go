@@ -0,0 +1,104 @@
+package limit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// maxBuckets bounds the buckets Buckets keeps; past it, full (idle) buckets are dropped.
+const maxBuckets = 10000
+
+// Buckets is an in-process token bucket per key: the per-instance stand-in for the Redis rate
+// limiter while Redis is unreachable. Each replica counts on its own.
+type Buckets struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+	rate   float64 // tokens per nanosecond
+	burst  float64
+}
+
+// NewBuckets returns an empty set of buckets.
+func NewBuckets() *Buckets {
+	return &Buckets{buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Take takes a token from key's bucket, which holds up to burst tokens and refills rate every
+// per. It returns whether a token was left, the whole tokens remaining and, when refused, how
+// long until the next one. A bucket with no refill rate allows nothing.
+func (b *Buckets) Take(key string, rate int, per time.Duration, burst int) (ok bool, remaining int, wait time.Duration) {
+	if rate <= 0 || per <= 0 {
+		return false, 0, per
+	}
+	burst = max(burst, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	bk := b.buckets[key]
+	if bk == nil {
+		if len(b.buckets) >= maxBuckets {
+			b.prune(now)
+		}
+		bk = &bucket{tokens: float64(burst), at: now}
+		b.buckets[key] = bk
+	}
+	bk.rate, bk.burst = float64(rate)/float64(per), float64(burst)
+	bk.refill(now)
+	if bk.tokens < 1 {
+		return false, 0, time.Duration(math.Ceil((1 - bk.tokens) / bk.rate))
+	}
+	bk.tokens--
+	return true, int(bk.tokens), 0
+}
+
+func (bk *bucket) refill(now time.Time) {
+	if d := now.Sub(bk.at); d > 0 {
+		bk.tokens = min(bk.burst, bk.tokens+float64(d)*bk.rate)
+	}
+	bk.at = now
+}
+
+// prune drops buckets that have refilled completely; they are the same as new ones.
+func (b *Buckets) prune(now time.Time) {
+	for key, bk := range b.buckets {
+		if bk.refill(now); bk.tokens >= bk.burst {
+			delete(b.buckets, key)
+		}
+	}
+}
+
+// Locks is a set of in-process exclusive locks: the per-instance stand-in for the Redis queue
+// lock while Redis is unreachable.
+type Locks struct {
+	mu   sync.Mutex
+	held map[string]bool
+}
+
+// NewLocks returns an empty set of locks.
+func NewLocks() *Locks {
+	return &Locks{held: make(map[string]bool)}
+}
+
+// TryLock takes key if nobody holds it and reports whether it did.
+func (l *Locks) TryLock(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.held[key] {
+		return false
+	}
+	l.held[key] = true
+	return true
+}
+
+// Unlock releases key.
+func (l *Locks) Unlock(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.held, key)
+}
@@ -0,0 +1,64 @@
+package limit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestBuckets(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBuckets()
+	b.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, left, _ := b.Take("k", 1, time.Minute, 3); !ok || left != 2-i {
+			t.Fatalf("request %d: expected allowed with %d left, got %v/%d", i, 2-i, ok, left)
+		}
+	}
+	ok, _, wait := b.Take("k", 1, time.Minute, 3)
+	if ok || wait != time.Minute {
+		t.Fatalf("expected a refusal with a minute to wait, got %v/%v", ok, wait)
+	}
+	if ok, _, _ := b.Take("other", 1, time.Minute, 3); !ok {
+		t.Error("expected keys to have their own buckets")
+	}
+
+	now = now.Add(30 * time.Second)
+	if ok, _, wait := b.Take("k", 1, time.Minute, 3); ok || wait != 30*time.Second {
+		t.Errorf("expected half a token after 30s, got %v/%v", ok, wait)
+	}
+	now = now.Add(30 * time.Second)
+	if ok, _, _ := b.Take("k", 1, time.Minute, 3); !ok {
+		t.Error("expected a refilled token after a minute")
+	}
+	if ok, _, _ := b.Take("k", 0, time.Minute, 3); ok {
+		t.Error("expected a bucket without refill to allow nothing")
+	}
+}
+
+func TestBuckets_Prune(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := NewBuckets()
+	b.now = func() time.Time { return now }
+	for i := 0; i < maxBuckets; i++ {
+		b.Take(fmt.Sprint(i), 1, time.Second, 1)
+	}
+	b.Take("busy", 1, time.Hour, 2)
+	now = now.Add(time.Second)
+	b.Take("new", 1, time.Second, 1)
+	if len(b.buckets) != 2 || b.buckets["busy"] == nil {
+		t.Errorf("expected only the busy bucket to survive pruning, got %d buckets", len(b.buckets))
+	}
+}
+
+func TestLocks(t *testing.T) {
+	l := NewLocks()
+	if !l.TryLock("a") || l.TryLock("a") || !l.TryLock("b") {
+		t.Fatal("expected one holder per key")
+	}
+	l.Unlock("a")
+	if !l.TryLock("a") {
+		t.Error("expected the lock to be free after Unlock")
+	}
+}
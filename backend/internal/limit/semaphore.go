@@ -0,0 +1,66 @@
+// Package limit bounds how much work runs at once.
+package limit
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrBusy is returned when no slot frees up within the allowed wait.
+var ErrBusy = errors.New("all slots busy")
+
+// Semaphore caps concurrent holders. A nil *Semaphore is unlimited.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore returns a semaphore with n slots, or nil (unlimited) when n <= 0.
+func NewSemaphore(n int) *Semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return &Semaphore{slots: make(chan struct{}, n)}
+}
+
+// Acquire takes a slot, waiting at most wait for one to free up. It returns ErrBusy when the
+// wait runs out and ctx.Err() when ctx ends first. Every successful Acquire must be paired with Release.
+func (s *Semaphore) Acquire(ctx context.Context, wait time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if wait <= 0 {
+		return ErrBusy
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot taken by Acquire.
+func (s *Semaphore) Release() {
+	if s == nil {
+		return
+	}
+	<-s.slots
+}
+
+// InUse returns how many slots are taken.
+func (s *Semaphore) InUse() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.slots)
+}
@@ -0,0 +1,52 @@
+package limit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSemaphore(t *testing.T) {
+	ctx := context.Background()
+	s := NewSemaphore(2)
+	for i := 0; i < 2; i++ {
+		if err := s.Acquire(ctx, 0); err != nil {
+			t.Fatalf("acquire %d: %v", i, err)
+		}
+	}
+	if err := s.Acquire(ctx, 10*time.Millisecond); !errors.Is(err, ErrBusy) {
+		t.Errorf("expected ErrBusy when full, got %v", err)
+	}
+
+	// A slot freed while waiting is taken.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		s.Release()
+	}()
+	if err := s.Acquire(ctx, time.Second); err != nil {
+		t.Errorf("expected a freed slot, got %v", err)
+	}
+	if s.InUse() != 2 {
+		t.Errorf("expected 2 in use, got %d", s.InUse())
+	}
+
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Acquire(cctx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSemaphore_Unlimited(t *testing.T) {
+	s := NewSemaphore(0)
+	for i := 0; i < 100; i++ {
+		if err := s.Acquire(context.Background(), 0); err != nil {
+			t.Fatalf("unlimited semaphore refused: %v", err)
+		}
+	}
+	s.Release()
+	if s.InUse() != 0 {
+		t.Errorf("expected 0 in use, got %d", s.InUse())
+	}
+}
@@ -0,0 +1,60 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// AudioSummary is the transcript and summary of a voice note or audio file.
+type AudioSummary struct {
+	Language   string `json:"language"`
+	Transcript string `json:"transcript"`
+	Summary    string `json:"summary"`
+}
+
+const audioSummaryInstruction = `You transcribe and summarize voice notes and audio files sent in a group chat. Transcribe the speech verbatim in its original language, keeping profanity and slang; mark unclear parts as [unclear] and leave out filler sounds. Then summarize what the speaker says in a few sentences in the requested language. If there is no speech, leave the transcript empty and describe the audio in the summary.
+Respond with JSON only: {"language": "<ISO 639-1 code of the speech>", "transcript": "<transcript>", "summary": "<summary>"}`
+
+// SummarizeAudio transcribes and summarizes audio at temperature 0, without the persona. The
+// summary is written in lang. Audio over MEDIA_INLINE_MAX_BYTES is uploaded (see MediaPart).
+func (c *Client) SummarizeAudio(ctx context.Context, data []byte, mimeType, lang string) (*AudioSummary, error) {
+	part, cleanup, err := c.MediaPart(ctx, data, mimeType)
+	if err != nil {
+		return nil, fmt.Errorf("summarize audio: %w", err)
+	}
+	defer cleanup()
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(audioSummaryInstruction)},
+		},
+		Temperature:      genai.Ptr(float32(0)),
+		ResponseMIMEType: "application/json",
+		ThinkingConfig:   &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{part, genai.NewPartFromText("Summary language: " + lang)}},
+	}
+	resp, err := c.generate(ctx, "summarize_audio", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("summarize audio: %w", err)
+	}
+	return parseAudioSummary(extractText(resp))
+}
+
+// parseAudioSummary decodes the model's JSON, tolerating a Markdown code fence.
+func parseAudioSummary(text string) (*AudioSummary, error) {
+	var s AudioSummary
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &s); err != nil {
+		return nil, fmt.Errorf("parse audio summary: %w", err)
+	}
+	s.Transcript, s.Summary = strings.TrimSpace(s.Transcript), strings.TrimSpace(s.Summary)
+	if s.Transcript == "" && s.Summary == "" {
+		return nil, fmt.Errorf("parse audio summary: empty response")
+	}
+	return &s, nil
+}
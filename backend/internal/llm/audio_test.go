@@ -0,0 +1,18 @@
+package llm
+
+import "testing"
+
+func TestParseAudioSummary(t *testing.T) {
+	s, err := parseAudioSummary("```json\n{\"language\": \"uk\", \"transcript\": \" привіт, це я \", \"summary\": \"Greets the chat.\"}\n```")
+	if err != nil || s.Language != "uk" || s.Transcript != "привіт, це я" || s.Summary != "Greets the chat." {
+		t.Fatalf("unexpected summary %+v, %v", s, err)
+	}
+	if s, err := parseAudioSummary(`{"transcript": "", "summary": "Only music."}`); err != nil || s.Summary != "Only music." {
+		t.Errorf("expected audio without speech to be accepted, got %+v, %v", s, err)
+	}
+	for _, bad := range []string{`not json`, `{"transcript": " ", "summary": ""}`} {
+		if _, err := parseAudioSummary(bad); err == nil {
+			t.Errorf("expected %q to be rejected", bad)
+		}
+	}
+}
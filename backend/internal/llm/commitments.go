@@ -0,0 +1,101 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Commitment is something the bot promised in a reply to do later.
+type Commitment struct {
+	Text  string    // what to do, written as an instruction to the bot
+	DueAt time.Time // when to do it
+}
+
+const (
+	// maxCommitments caps the promises kept from one reply.
+	maxCommitments = 3
+	// maxCommitmentDelay is the furthest ahead a promise is kept; later ones are dropped.
+	maxCommitmentDelay = 30 * 24 * time.Hour
+)
+
+// commitmentStems are word stems (lowercase) of promises to come back to something. Replies
+// without any are not sent to the model.
+var commitmentStems = []string{
+	"нагада", "подивлю", "перевірю", "розкажу", "напишу", "повернус", "пізніше", "згодом", "завтра",
+	"i'll", "i will", "remind", "later", "tomorrow", "get back",
+}
+
+// MayContainCommitment reports whether a reply has wording of a promise to do something later,
+// so that only those are checked by ExtractCommitments.
+func MayContainCommitment(reply string) bool {
+	lower := strings.ToLower(reply)
+	for _, s := range commitmentStems {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+const commitmentInstruction = `You read a chat bot's reply to a user and find promises the bot made to do something later: remind someone, check or look into something, come back to a topic ("нагадаю завтра", "подивлюсь пізніше", "I'll check tomorrow"). Ignore offers and questions ("want me to remind you?"), things already done in the reply, and plans of other people.
+For each promise give what the bot should do, written as a short instruction to the bot in the reply's language (e.g. "Нагадай Олені про дантиста о 15:00"), and when, as an RFC 3339 time with the offset of the current time given. "Later" without a time means three hours from now; "tomorrow" without a time means tomorrow at 10:00.
+Respond with JSON only: {"commitments": [{"text": "<instruction>", "due_at": "<RFC 3339>"}]}, with an empty list when there are none.`
+
+// ExtractCommitments finds the promises the bot made in reply (to userMessage). now should be in
+// the user's timezone, so "tomorrow at 10" means their morning. The call is cheap:
+// temperature 0, no thinking, short JSON output.
+func (c *Client) ExtractCommitments(ctx context.Context, userMessage, reply string, now time.Time) ([]Commitment, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(commitmentInstruction)},
+		},
+		Temperature:      genai.Ptr(float32(0)),
+		ResponseMIMEType: "application/json",
+		ThinkingConfig:   &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	prompt := fmt.Sprintf("Current time: %s\n\nUser message:\n%s\n\nBot reply:\n%s",
+		now.Format(time.RFC3339), userMessage, reply)
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(prompt)}},
+	}
+	resp, err := c.generate(ctx, "extract_commitments", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("extract commitments: %w", err)
+	}
+	return parseCommitments(extractText(resp), now)
+}
+
+// parseCommitments decodes the model's JSON, tolerating a Markdown code fence. Entries without
+// text or a readable time, or due more than 30 days ahead, are skipped; past times become now.
+func parseCommitments(text string, now time.Time) ([]Commitment, error) {
+	var out struct {
+		Commitments []struct {
+			Text  string `json:"text"`
+			DueAt string `json:"due_at"`
+		} `json:"commitments"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &out); err != nil {
+		return nil, fmt.Errorf("parse commitments: %w", err)
+	}
+	var commitments []Commitment
+	for _, c := range out.Commitments {
+		c.Text = strings.TrimSpace(c.Text)
+		due, err := time.Parse(time.RFC3339, strings.TrimSpace(c.DueAt))
+		if c.Text == "" || err != nil || due.Sub(now) > maxCommitmentDelay {
+			continue
+		}
+		if due.Before(now) {
+			due = now
+		}
+		commitments = append(commitments, Commitment{Text: c.Text, DueAt: due})
+		if len(commitments) == maxCommitments {
+			break
+		}
+	}
+	return commitments, nil
+}
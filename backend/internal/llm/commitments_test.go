@@ -0,0 +1,51 @@
+package llm
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMayContainCommitment(t *testing.T) {
+	for reply, want := range map[string]bool{
+		"Добре, нагадаю завтра зранку":    true,
+		"Подивлюсь пізніше і скажу":       true,
+		"I'll check the schedule tonight": true,
+		"Погода сьогодні сонячна":         false,
+		"Here is the answer.":             false,
+	} {
+		if got := MayContainCommitment(reply); got != want {
+			t.Errorf("MayContainCommitment(%q) = %v, want %v", reply, got, want)
+		}
+	}
+}
+
+func TestParseCommitments(t *testing.T) {
+	now := time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC)
+	text := "```json\n" + `{"commitments": [
+		{"text": "Нагадай Олені про дантиста", "due_at": "2026-10-18T10:00:00+03:00"},
+		{"text": "Check the match score", "due_at": "2026-10-17T09:00:00Z"},
+		{"text": "", "due_at": "2026-10-18T10:00:00Z"},
+		{"text": "Someday", "due_at": "2027-01-01T00:00:00Z"},
+		{"text": "No time", "due_at": "soon"}
+	]}` + "\n```"
+	got, err := parseCommitments(text, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected two commitments, got %+v", got)
+	}
+	if got[0].Text != "Нагадай Олені про дантиста" || !got[0].DueAt.Equal(time.Date(2026, 10, 18, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected first commitment %+v", got[0])
+	}
+	if !got[1].DueAt.Equal(now) {
+		t.Errorf("expected a past time moved to now, got %v", got[1].DueAt)
+	}
+
+	if got, err := parseCommitments(`{"commitments": []}`, now); err != nil || len(got) != 0 {
+		t.Errorf("expected none, got %+v, %v", got, err)
+	}
+	if _, err := parseCommitments("not json", now); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
@@ -0,0 +1,71 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"google.golang.org/genai"
+)
+
+// FactMerge is one group of stored facts the model decided say the same thing.
+type FactMerge struct {
+	KeepID     int64   `json:"keep_id"`
+	RemoveIDs  []int64 `json:"remove_ids"`
+	Text       string  `json:"text"`
+	Category   string  `json:"category"`
+	Importance int     `json:"importance"`
+}
+
+const consolidateInstruction = `You maintain a memory of facts about one chat member. Find facts that duplicate, overlap or contradict each other and merge each such group into one fact.
+Rules:
+- Only merge facts that are about the same thing. Leave unrelated facts alone; do not mention them.
+- When facts contradict, keep the newest information (higher updated date wins).
+- Write the merged text in the language of the original facts, concise, third person.
+- category is one of: preference, bio, event, joke. importance is 1 (trivia) to 5 (core identity).
+Respond with JSON only: {"merges":[{"keep_id":1,"remove_ids":[2,3],"text":"...","category":"bio","importance":3}]}. Use {"merges":[]} when nothing should change.`
+
+// ConsolidateFacts asks the model which of a user's facts should be merged. The result is
+// not validated against the input; callers must check ids (see the consolidation runner).
+func (c *Client) ConsolidateFacts(ctx context.Context, facts []db.UserFact) ([]FactMerge, error) {
+	if len(facts) < 2 {
+		return nil, nil
+	}
+	var b strings.Builder
+	for _, f := range facts {
+		fmt.Fprintf(&b, "- id=%d category=%s importance=%d updated=%s: %s\n",
+			f.ID, f.Category, f.Importance, f.UpdatedAt.Format("2006-01-02"), f.FactText)
+	}
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(consolidateInstruction)},
+		},
+		Temperature:      genai.Ptr(float32(0.1)),
+		ResponseMIMEType: "application/json",
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText("Facts:\n" + b.String())}},
+	}
+	resp, err := c.generate(ctx, "consolidation", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("consolidate facts: %w", err)
+	}
+	return parseFactMerges(extractText(resp))
+}
+
+// parseFactMerges decodes the model's JSON answer, tolerating a Markdown code fence around it.
+func parseFactMerges(text string) ([]FactMerge, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	var out struct {
+		Merges []FactMerge `json:"merges"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &out); err != nil {
+		return nil, fmt.Errorf("parse fact merges: %w", err)
+	}
+	return out.Merges, nil
+}
@@ -0,0 +1,20 @@
+package llm
+
+import "testing"
+
+func TestParseFactMerges(t *testing.T) {
+	merges, err := parseFactMerges("```json\n{\"merges\":[{\"keep_id\":1,\"remove_ids\":[2,3],\"text\":\"Lives in Lviv\",\"category\":\"bio\",\"importance\":4}]}\n```")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(merges) != 1 || merges[0].KeepID != 1 || len(merges[0].RemoveIDs) != 2 || merges[0].Text != "Lives in Lviv" {
+		t.Errorf("unexpected merges: %+v", merges)
+	}
+
+	if merges, err := parseFactMerges(`{"merges":[]}`); err != nil || len(merges) != 0 {
+		t.Errorf("empty merges: %v, %v", merges, err)
+	}
+	if _, err := parseFactMerges("I merged them for you!"); err == nil {
+		t.Error("expected error for non-JSON answer")
+	}
+}
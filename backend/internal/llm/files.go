@@ -0,0 +1,115 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// uploadPrefix starts the display name of every upload, so the sweep only deletes our own.
+	uploadPrefix = "gryag-"
+	// fileActivePoll is how often an upload is checked until Gemini has processed it.
+	fileActivePoll = time.Second
+	// uploadMaxAge is when the sweep deletes an upload whose request didn't (crash, failed delete).
+	// Requests are far shorter; Gemini itself would keep the file for 48 hours.
+	uploadMaxAge = time.Hour
+	// uploadSweepInterval is how often the sweep runs.
+	uploadSweepInterval = 15 * time.Minute
+)
+
+// MediaPart returns media as a request part: inline up to MEDIA_INLINE_MAX_BYTES, else uploaded
+// through the Files API and passed by URI. cleanup deletes the upload (a no-op for inline data)
+// and must be called once the requests using the part are done.
+func (c *Client) MediaPart(ctx context.Context, data []byte, mimeType string) (part *genai.Part, cleanup func(), err error) {
+	if len(data) <= c.config.MediaInlineMaxBytes {
+		return genai.NewPartFromBytes(data, mimeType), func() {}, nil
+	}
+	start := time.Now()
+	file, err := c.genai.Files.Upload(ctx, bytes.NewReader(data), &genai.UploadFileConfig{
+		MIMEType:    mimeType,
+		DisplayName: fmt.Sprintf("%s%d", uploadPrefix, start.UnixNano()),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("upload %d bytes: %w", len(data), err)
+	}
+	name := file.Name
+	cleanup = func() {
+		// The request may be cancelled by now; the upload is deleted regardless
+		if _, err := c.genai.Files.Delete(context.WithoutCancel(ctx), name, nil); err != nil {
+			slog.WarnContext(ctx, "delete uploaded file failed", "file", name, "error", err)
+		}
+	}
+	for file.State == genai.FileStateProcessing {
+		select {
+		case <-ctx.Done():
+			cleanup()
+			return nil, nil, ctx.Err()
+		case <-time.After(fileActivePoll):
+		}
+		if file, err = c.genai.Files.Get(ctx, name, nil); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("check upload: %w", err)
+		}
+	}
+	if file.State == genai.FileStateFailed {
+		cleanup()
+		return nil, nil, fmt.Errorf("gemini could not process the upload %s", name)
+	}
+	slog.InfoContext(ctx, "media uploaded", "file", name, "mime_type", mimeType, "bytes", len(data), "took", time.Since(start))
+	return genai.NewPartFromURI(file.URI, file.MIMEType), cleanup, nil
+}
+
+// staleUploads returns the names of our uploads created more than maxAge before now.
+func staleUploads(files []*genai.File, now time.Time, maxAge time.Duration) []string {
+	var names []string
+	for _, f := range files {
+		if strings.HasPrefix(f.DisplayName, uploadPrefix) && !f.CreateTime.IsZero() && now.Sub(f.CreateTime) > maxAge {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// SweepUploads deletes uploads left behind by requests that didn't clean up, and returns how
+// many it deleted.
+func (c *Client) SweepUploads(ctx context.Context) (int, error) {
+	var files []*genai.File
+	for f, err := range c.genai.Files.All(ctx) {
+		if err != nil {
+			return 0, fmt.Errorf("list uploads: %w", err)
+		}
+		files = append(files, f)
+	}
+	deleted := 0
+	for _, name := range staleUploads(files, time.Now(), uploadMaxAge) {
+		if _, err := c.genai.Files.Delete(ctx, name, nil); err != nil {
+			slog.WarnContext(ctx, "delete stale upload failed", "file", name, "error", err)
+			continue
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
+// UploadSweeper runs SweepUploads every 15 minutes until ctx is done. Every replica may run it:
+// deleting an upload twice only logs a warning.
+func (c *Client) UploadSweeper(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(uploadSweepInterval):
+		}
+		if n, err := c.SweepUploads(ctx); err != nil {
+			slog.WarnContext(ctx, "upload sweep failed", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "stale uploads deleted", "count", n)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package llm
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestStaleUploads(t *testing.T) {
+	now := time.Now()
+	files := []*genai.File{
+		{Name: "files/old", DisplayName: uploadPrefix + "1", CreateTime: now.Add(-2 * time.Hour)},
+		{Name: "files/new", DisplayName: uploadPrefix + "2", CreateTime: now.Add(-time.Minute)},
+		{Name: "files/other", DisplayName: "someone-else", CreateTime: now.Add(-2 * time.Hour)},
+		{Name: "files/undated", DisplayName: uploadPrefix + "3"},
+	}
+	if got := staleUploads(files, now, uploadMaxAge); !slices.Equal(got, []string{"files/old"}) {
+		t.Errorf("expected only our old upload, got %v", got)
+	}
+}
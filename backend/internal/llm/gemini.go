@@ -6,9 +6,12 @@ import (
 	"log/slog"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/ThatHunky/gryag/backend/internal/metrics"
 	"google.golang.org/genai"
 )
 
@@ -19,14 +22,88 @@ type Client struct {
 	genai  *genai.Client
 	config *config.Config
 	persona string
+	usage  UsageStore
+	calls  *metrics.Window
+}
+
+// UsageStore records the token usage of every Gemini call (implemented by *db.DB).
+type UsageStore interface {
+	RecordLLMUsage(ctx context.Context, u *db.LLMUsage) error
+}
+
+// maxUsageErrorLen bounds the error text stored for a failed call.
+const maxUsageErrorLen = 500
+
+// Ping checks that the Gemini API is reachable with the configured key (a one-model list call;
+// not recorded as usage).
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.genai.Models.List(ctx, &genai.ListModelsConfig{PageSize: 1}); err != nil {
+		return fmt.Errorf("gemini list models: %w", err)
+	}
+	return nil
+}
+
+// SetUsageStore enables usage recording; nil (the default) records nothing.
+func (c *Client) SetUsageStore(s UsageStore) {
+	c.usage = s
+}
+
+// SetMetrics records every call's latency and outcome in w (read by proactive health checks).
+func (c *Client) SetMetrics(w *metrics.Window) {
+	c.calls = w
+}
+
+// generate calls Gemini and records the call's usage under purpose. Every request goes through here.
+func (c *Client) generate(ctx context.Context, purpose string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return c.generateWith(ctx, purpose, c.config.GeminiModel, contents, config)
+}
+
+// generateWith is generate with another model (canary replies).
+func (c *Client) generateWith(ctx context.Context, purpose, model string, contents []*genai.Content, config *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	start := time.Now()
+	resp, err := c.genai.Models.GenerateContent(ctx, model, contents, config)
+	c.calls.Record(time.Since(start), err != nil)
+	if c.usage != nil {
+		if recErr := c.usage.RecordLLMUsage(ctx, usageRecord(ctx, purpose, model, resp, err)); recErr != nil {
+			slog.WarnContext(ctx, "record llm usage failed", "error", recErr)
+		}
+	}
+	return resp, err
+}
+
+// usageRecord builds the llm_usage row for one call; request and chat come from the logging context.
+func usageRecord(ctx context.Context, purpose, model string, resp *genai.GenerateContentResponse, err error) *db.LLMUsage {
+	u := &db.LLMUsage{Purpose: purpose, Model: model}
+	if v, ok := logging.Value(ctx, logging.KeyRequestID); ok {
+		id := v.String()
+		u.RequestID = &id
+	}
+	if v, ok := logging.Value(ctx, logging.KeyChatID); ok && v.Kind() == slog.KindInt64 {
+		id := v.Int64()
+		u.ChatID = &id
+	}
+	if resp != nil && resp.UsageMetadata != nil {
+		m := resp.UsageMetadata
+		u.PromptTokens = int(m.PromptTokenCount)
+		u.OutputTokens = int(m.CandidatesTokenCount + m.ThoughtsTokenCount)
+	}
+	if err != nil {
+		msg := err.Error()
+		if len(msg) > maxUsageErrorLen {
+			msg = msg[:maxUsageErrorLen]
+		}
+		u.Error = &msg
+	}
+	return u
 }
 
 // NewClient creates a new Gemini LLM client.
 func NewClient(cfg *config.Config) (*Client, error) {
 	ctx := context.Background()
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  cfg.GeminiAPIKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     cfg.GeminiAPIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: logging.NewHTTPClient(), // forwards X-Request-ID
 	})
 	if err != nil {
 		return nil, fmt.Errorf("genai client: %w", err)
@@ -51,18 +128,49 @@ func NewClient(cfg *config.Config) (*Client, error) {
 	}, nil
 }
 
+// GenerateOptions carries per-request overrides of the client defaults (e.g. from chat settings).
+type GenerateOptions struct {
+	Persona     string   // replaces the persona file when non-empty
+	Temperature *float64 // replaces GEMINI_TEMPERATURE when set
+	Model       string   // replaces GEMINI_MODEL when non-empty (CANARY_MODEL)
+	NoToolCalls bool     // the tools stay declared but the model must answer in text
+}
+
 // GenerateResponse sends a conversation history to Gemini and returns the full response.
 func (c *Client) GenerateResponse(ctx context.Context, contents []*genai.Content, tools []*genai.Tool) (*genai.GenerateContentResponse, error) {
-	logger := slog.With("model", c.config.GeminiModel)
+	return c.GenerateResponseWithOptions(ctx, contents, tools, GenerateOptions{})
+}
+
+// GenerateResponseWithOptions is GenerateResponse with per-request persona/temperature overrides.
+func (c *Client) GenerateResponseWithOptions(ctx context.Context, contents []*genai.Content, tools []*genai.Tool, opts GenerateOptions) (*genai.GenerateContentResponse, error) {
+	model := c.config.GeminiModel
+	if opts.Model != "" {
+		model = opts.Model
+	}
+	logger := slog.With("model", model)
+
+	persona := c.persona
+	if opts.Persona != "" {
+		persona = opts.Persona
+	}
+	temperature := c.config.GeminiTemperature
+	if opts.Temperature != nil {
+		temperature = *opts.Temperature
+	}
 
 	config := &genai.GenerateContentConfig{
 		// Section 14.1: SystemInstruction is the persona — separated from the conversation array
 		SystemInstruction: &genai.Content{
-			Parts: []*genai.Part{genai.NewPartFromText(c.persona)},
+			Parts: []*genai.Part{genai.NewPartFromText(persona)},
 		},
-		Temperature:      genai.Ptr(float32(c.config.GeminiTemperature)),
+		Temperature:      genai.Ptr(float32(temperature)),
 		Tools:            tools,
 	}
+	if opts.NoToolCalls {
+		config.ToolConfig = &genai.ToolConfig{
+			FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingConfigModeNone},
+		}
+	}
 
 	if c.config.GeminiThinkingBudget > 0 {
 		config.ThinkingConfig = &genai.ThinkingConfig{
@@ -70,12 +178,12 @@ func (c *Client) GenerateResponse(ctx context.Context, contents []*genai.Content
 		}
 	}
 
-	resp, err := c.genai.Models.GenerateContent(ctx, c.config.GeminiModel, contents, config)
+	resp, err := c.generateWith(ctx, "chat", model, contents, config)
 	if err != nil {
 		return nil, fmt.Errorf("generate content: %w", err)
 	}
 
-	logger.Info("generation complete")
+	logger.InfoContext(ctx, "generation complete")
 	return resp, nil
 }
 
@@ -93,7 +201,7 @@ func (c *Client) RouteIntent(ctx context.Context, message string, tools []*genai
 		ResponseMIMEType: "application/json",
 	}
 
-	resp, err := c.genai.Models.GenerateContent(ctx, c.config.GeminiModel, []*genai.Content{
+	resp, err := c.generate(ctx, "routing", []*genai.Content{
 		{
 			Role:  "user",
 			Parts: []*genai.Part{genai.NewPartFromText(message)},
@@ -108,10 +216,25 @@ func (c *Client) RouteIntent(ctx context.Context, message string, tools []*genai
 
 // SummarizeChat produces a short factual summary of a chat log for the given window (e.g. "7-day", "30-day").
 // Messages are formatted like the immediate context block; input is truncated to maxSummaryInputChars.
-func (c *Client) SummarizeChat(ctx context.Context, messages []db.Message, windowLabel string) (string, error) {
+// opts fixes the output language and can hide participants' names.
+func (c *Client) SummarizeChat(ctx context.Context, messages []db.Message, windowLabel string, opts SummaryOptions) (string, error) {
 	if len(messages) == 0 {
 		return "", nil
 	}
+	chatLog := formatChatLog(messages, opts.Anonymize)
+	systemInstruction := "You are a summarization assistant. Summarize the following chat log concisely and factually. Preserve key topics, decisions, and context. " + opts.rules() + " Output only the summary, no preamble."
+	userContent := "Summarize this " + windowLabel + " conversation:\n\n" + chatLog
+	return c.summarize(ctx, systemInstruction, userContent)
+}
+
+// formatChatLog renders messages one per line for summarization prompts, keeping the newest
+// maxSummaryInputChars characters. With anonymize, senders become "Member N" and their names and
+// @usernames are replaced in message texts too.
+func formatChatLog(messages []db.Message, anonymize bool) string {
+	var p *pseudonyms
+	if anonymize {
+		p = newPseudonyms(messages)
+	}
 	var b strings.Builder
 	for _, msg := range messages {
 		name := "Unknown"
@@ -121,9 +244,9 @@ func (c *Client) SummarizeChat(ctx context.Context, messages []db.Message, windo
 		if msg.Username != nil {
 			name += " (@" + *msg.Username + ")"
 		}
-		text := ""
-		if msg.Text != nil {
-			text = *msg.Text
+		text := messageText(&msg)
+		if p != nil {
+			name, text = p.name(&msg), p.replacer.Replace(text)
 		}
 		prefix := ""
 		if msg.IsBotReply {
@@ -138,8 +261,11 @@ func (c *Client) SummarizeChat(ctx context.Context, messages []db.Message, windo
 	if len(chatLog) > maxSummaryInputChars {
 		chatLog = chatLog[len(chatLog)-maxSummaryInputChars:]
 	}
-	systemInstruction := "You are a summarization assistant. Summarize the following chat log concisely and factually. Preserve key topics, decisions, and context. Use the same language as the chat or English. Output only the summary, no preamble."
-	userContent := "Summarize this " + windowLabel + " conversation:\n\n" + chatLog
+	return chatLog
+}
+
+// summarize runs one summarization request (low temperature, plain text out).
+func (c *Client) summarize(ctx context.Context, systemInstruction, userContent string) (string, error) {
 	config := &genai.GenerateContentConfig{
 		SystemInstruction: &genai.Content{
 			Parts: []*genai.Part{genai.NewPartFromText(systemInstruction)},
@@ -149,7 +275,7 @@ func (c *Client) SummarizeChat(ctx context.Context, messages []db.Message, windo
 	contents := []*genai.Content{
 		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(userContent)}},
 	}
-	resp, err := c.genai.Models.GenerateContent(ctx, c.config.GeminiModel, contents, config)
+	resp, err := c.generate(ctx, "summary", contents, config)
 	if err != nil {
 		return "", fmt.Errorf("summarize chat: %w", err)
 	}
@@ -166,7 +292,7 @@ func (c *Client) SearchWithGrounding(ctx context.Context, query string) (string,
 	contents := []*genai.Content{
 		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(query)}},
 	}
-	resp, err := c.genai.Models.GenerateContent(ctx, c.config.GeminiModel, contents, config)
+	resp, err := c.generate(ctx, "search", contents, config)
 	if err != nil {
 		return "", fmt.Errorf("grounding request: %w", err)
 	}
@@ -0,0 +1,33 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"google.golang.org/genai"
+)
+
+func TestUsageRecord(t *testing.T) {
+	ctx := logging.WithRequestID(logging.WithChat(context.Background(), -100, 0), "req-1")
+	resp := &genai.GenerateContentResponse{UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount: 1200, CandidatesTokenCount: 80, ThoughtsTokenCount: 20,
+	}}
+	u := usageRecord(ctx, "chat", "gemini-2.5-flash", resp, nil)
+	if u.Purpose != "chat" || u.PromptTokens != 1200 || u.OutputTokens != 100 || u.Error != nil {
+		t.Errorf("unexpected record: %+v", u)
+	}
+	if u.RequestID == nil || *u.RequestID != "req-1" || u.ChatID == nil || *u.ChatID != -100 {
+		t.Errorf("expected request and chat from context, got %v %v", u.RequestID, u.ChatID)
+	}
+
+	u = usageRecord(context.Background(), "summary", "m", nil, errors.New(strings.Repeat("x", 2*maxUsageErrorLen)))
+	if u.RequestID != nil || u.ChatID != nil || u.PromptTokens != 0 {
+		t.Errorf("expected empty attribution, got %+v", u)
+	}
+	if u.Error == nil || len(*u.Error) != maxUsageErrorLen {
+		t.Errorf("expected truncated error")
+	}
+}
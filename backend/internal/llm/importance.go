@@ -0,0 +1,130 @@
+package llm
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// contextKeepRecent is how many of the newest messages the immediate context always keeps, so
+// the conversation right before the current message is never thinned out.
+const contextKeepRecent = 10
+
+// lowInfoRunes is the longest text that can count as noise ("+1", "ok", "))").
+const lowInfoRunes = 3
+
+// selectImportant keeps budget of the messages (oldest first): the newest contextKeepRecent,
+// then the best scoring earlier ones, returned in their original order. With no more messages
+// than budget they are returned as they are.
+func selectImportant(messages []db.Message, budget int) []db.Message {
+	if budget <= 0 || len(messages) <= budget {
+		return messages
+	}
+	scores := messageScores(messages)
+	keep := make([]bool, len(messages))
+	recent := min(contextKeepRecent, budget)
+	for i := len(messages) - recent; i < len(messages); i++ {
+		keep[i] = true
+	}
+	older := make([]int, len(messages)-recent)
+	for i := range older {
+		older[i] = i
+	}
+	// Best first; on a tie the newer message wins
+	sort.SliceStable(older, func(a, b int) bool {
+		if scores[older[a]] != scores[older[b]] {
+			return scores[older[a]] > scores[older[b]]
+		}
+		return older[a] > older[b]
+	})
+	for _, i := range older[:budget-recent] {
+		keep[i] = true
+	}
+	out := make([]db.Message, 0, budget)
+	for i := range messages {
+		if keep[i] {
+			out = append(out, messages[i])
+		}
+	}
+	return out
+}
+
+// messageScores rates how much each message tells the model about the conversation: longer
+// text, media, messages that got the bot to answer, bot replies and reply chains score higher;
+// stickers, throttled messages and one-word noise lower. Newer messages get a small bonus.
+func messageScores(messages []db.Message) []float64 {
+	replies := make(map[int64]int)    // message_id -> replies to it in the window
+	answered := make(map[string]bool) // request IDs the bot replied to
+	for i := range messages {
+		m := &messages[i]
+		if m.ReplyToMessageID != nil {
+			replies[*m.ReplyToMessageID]++
+		}
+		if m.IsBotReply && m.RequestID != nil && *m.RequestID != "" {
+			answered[*m.RequestID] = true
+		}
+	}
+
+	scores := make([]float64, len(messages))
+	for i := range messages {
+		m := &messages[i]
+		text := ""
+		if m.Text != nil {
+			text = strings.TrimSpace(*m.Text)
+		}
+		s := float64(min(len([]rune(text)), 200)) / 100 // up to 2 for length
+		if isLowInfo(text) {
+			s -= 1
+		}
+		switch {
+		case m.MediaType == nil || *m.MediaType == "":
+		case *m.MediaType == "sticker":
+			s -= 0.5
+		default:
+			s += 1
+		}
+		if m.IsBotReply {
+			s += 1.5
+		} else if m.RequestID != nil && answered[*m.RequestID] {
+			s += 2 // addressed the bot
+		}
+		if m.ReplyToMessageID != nil {
+			s += 1
+		}
+		if m.MessageID != nil {
+			s += float64(min(replies[*m.MessageID], 2))
+		}
+		if m.WasThrottled {
+			s -= 0.5
+		}
+		scores[i] = s + float64(i)/float64(len(messages))
+	}
+	return scores
+}
+
+// isLowInfo reports whether text says next to nothing: empty, or up to lowInfoRunes characters
+// with at most two letters or digits ("+1", "ok", "))", "👍").
+func isLowInfo(text string) bool {
+	r := []rune(text)
+	if len(r) == 0 {
+		return true
+	}
+	if len(r) > lowInfoRunes {
+		return false
+	}
+	alnum := 0
+	for _, c := range r {
+		if unicode.IsLetter(c) || unicode.IsDigit(c) {
+			alnum++
+		}
+	}
+	return alnum <= 2
+}
+
+// KeepImportant thins the recent messages down to budget, preferring informative ones over noise
+// (see selectImportant). Load more than budget (IMMEDIATE_CONTEXT_CANDIDATES) for it to choose.
+func (di *DynamicInstructions) KeepImportant(budget int) {
+	di.RecentMessages = selectImportant(di.RecentMessages, budget)
+}
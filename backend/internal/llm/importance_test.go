@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func textMessage(id int64, text string) db.Message {
+	return db.Message{MessageID: &id, Text: &text}
+}
+
+func TestSelectImportant_PlainWhenWithinBudget(t *testing.T) {
+	msgs := []db.Message{textMessage(1, "+1"), textMessage(2, "ok")}
+	if got := selectImportant(msgs, 5); len(got) != 2 {
+		t.Errorf("expected all messages within the budget, got %d", len(got))
+	}
+	if got := selectImportant(msgs, 0); len(got) != 2 {
+		t.Errorf("expected no selection without a budget, got %d", len(got))
+	}
+}
+
+func TestSelectImportant_DropsNoiseKeepsRecent(t *testing.T) {
+	var msgs []db.Message
+	// 20 older messages: noise with one informative line and one message the bot answered
+	for i := int64(1); i <= 20; i++ {
+		msgs = append(msgs, textMessage(i, "+1"))
+	}
+	msgs[4] = textMessage(5, "The meetup moved to Saturday at the usual place, bring your own snacks")
+	req := "req-1"
+	msgs[9].RequestID = &req
+	bot := textMessage(0, "Sure")
+	bot.IsBotReply, bot.RequestID = true, &req
+	msgs = append(msgs, bot)
+	// 10 recent noise messages are always kept
+	for i := int64(21); i <= 30; i++ {
+		msgs = append(msgs, textMessage(i, "))"))
+	}
+
+	got := selectImportant(msgs, 13)
+	if len(got) != 13 {
+		t.Fatalf("expected 13 messages, got %d", len(got))
+	}
+	var ids []string
+	for _, m := range got {
+		ids = append(ids, fmt.Sprint(*m.MessageID))
+	}
+	want := "[5 10 0 21 22 23 24 25 26 27 28 29 30]"
+	if fmt.Sprint(ids) != want {
+		t.Errorf("expected %s in order, got %v", want, ids)
+	}
+}
+
+func TestMessageScores(t *testing.T) {
+	sticker := "sticker"
+	photo := "photo"
+	parent := textMessage(1, "who is coming tonight?")
+	reply := textMessage(2, "me")
+	reply.ReplyToMessageID = parent.MessageID
+	withSticker := textMessage(3, "")
+	withSticker.MediaType = &sticker
+	withPhoto := textMessage(4, "")
+	withPhoto.MediaType = &photo
+
+	s := messageScores([]db.Message{parent, reply, withSticker, withPhoto})
+	if s[2] >= s[3] {
+		t.Errorf("expected a photo to outscore a sticker, got %v", s)
+	}
+	if s[0] <= s[2] || s[1] <= s[2] {
+		t.Errorf("expected a reply chain to outscore a sticker, got %v", s)
+	}
+}
+
+func TestIsLowInfo(t *testing.T) {
+	for text, want := range map[string]bool{"": true, "+1": true, "ok": true, "))": true, "👍": true, "так": false, "see you at 5": false} {
+		if got := isLowInfo(text); got != want {
+			t.Errorf("isLowInfo(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
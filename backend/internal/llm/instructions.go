@@ -3,18 +3,25 @@ package llm
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
 	"google.golang.org/genai"
 )
 
 // DynamicInstructions assembles the full prompt per Section 8 of the architecture.
 type DynamicInstructions struct {
 	// Section 8.2: Current time and chat info
-	CurrentTime string
+	CurrentTime string // in the server's zone until SetLocation picks the user's or chat's
 	ChatName    string
 	ChatID      int64
+	ThreadID    int64 // forum topic; 0 = regular chat or the General topic
+
+	// Privacy mode: people appear as pseudonyms (see Privacy)
+	Pseudonymized bool
 
 	// Section 8.3: Tools block (built separately via registry)
 	ToolsDescription string
@@ -26,12 +33,39 @@ type DynamicInstructions struct {
 	// Section 8.4 + 8.6: Immediate chat context (last N messages)
 	RecentMessages []db.Message
 
+	// Most used stickers in this chat (the chat's "sticker language")
+	StickerProfile []db.StickerUsage
+
+	// Polls in this chat active within the last pollLookback, with tallies
+	Polls []db.PollResult
+
+	// Notes the chat asked to keep (save_note), recently updated first
+	Notes []db.ChatNote
+
 	// Section 8.5: Current user context
 	UserFacts []db.UserFact
 	UserID    int64
 	Username  string
 	FirstName string
 
+	// Total facts stored for the user; more than len(UserFacts) when only the top ones were loaded
+	UserFactsTotal int
+
+	// Offers the current user declined recently ("don't offer X")
+	UserRefusals []db.UserRefusal
+
+	// The current user's profile in this chat (USER_PROFILE_IN_CONTEXT); nil = none or disabled
+	UserProfile *db.UserProfile
+
+	// The current user's karma in this chat (ENABLE_KARMA); nil = none or disabled
+	UserKarma *db.KarmaEntry
+
+	// Reply language hint (code, e.g. "uk"); empty = no hint
+	ReplyLanguage string
+
+	// The chat's rolling mood (ENABLE_MOOD_TRACKING); nil or neutral = no block
+	Mood *sentiment.Mood
+
 	// Section 8.6: Multi-media buffer (up to 10 media items)
 	MediaParts []*genai.Part
 
@@ -39,6 +73,41 @@ type DynamicInstructions struct {
 	CurrentMessage   string
 	ReplyToMessageID *int64
 	ReplyToText      string
+	Forward          *Forward // nil = the user wrote the message
+}
+
+// Forward says where a forwarded current message came from.
+type Forward struct {
+	From string    // original author: a user's name or a post's signature
+	Chat string    // chat or channel it was posted in
+	Date time.Time // when it was first sent; zero = unknown
+}
+
+// describe renders the forward as one prompt line.
+func (f *Forward) describe() string {
+	var src []string
+	if f.From != "" {
+		src = append(src, f.From)
+	}
+	if f.Chat != "" {
+		src = append(src, "in "+f.Chat)
+	}
+	line := "\nForwarded"
+	if len(src) > 0 {
+		line += " from " + strings.Join(src, " ")
+	}
+	if !f.Date.IsZero() {
+		line += ", originally sent " + f.Date.Format("2006-01-02 15:04")
+	}
+	return line + ". The user shared this; they did not write it. Read it as something they want your take on."
+}
+
+// currentTimeLayout is how the Current Time block shows the time.
+const currentTimeLayout = "15:04 Monday, 02/01/2006"
+
+// SetLocation renders CurrentTime as t in loc, naming the zone so the model knows whose clock it is.
+func (di *DynamicInstructions) SetLocation(t time.Time, loc *time.Location) {
+	di.CurrentTime = t.In(loc).Format(currentTimeLayout) + " (" + loc.String() + ")"
 }
 
 // NewDynamicInstructions creates a DynamicInstructions from the database context.
@@ -46,6 +115,7 @@ func NewDynamicInstructions(
 	ctx context.Context,
 	database *db.DB,
 	chatID int64,
+	threadID int64,
 	userID int64,
 	username, firstName, text string,
 	contextSize int,
@@ -53,8 +123,9 @@ func NewDynamicInstructions(
 	replyToText string,
 ) (*DynamicInstructions, error) {
 	di := &DynamicInstructions{
-		CurrentTime:      time.Now().Format("15:04 Monday, 02/01/2006"),
+		CurrentTime:      time.Now().Format(currentTimeLayout),
 		ChatID:           chatID,
+		ThreadID:         threadID,
 		UserID:           userID,
 		Username:         username,
 		FirstName:        firstName,
@@ -63,26 +134,40 @@ func NewDynamicInstructions(
 		ReplyToText:      replyToText,
 	}
 
-	// Load recent messages for immediate context
-	messages, err := database.GetRecentMessages(ctx, chatID, contextSize)
+	// Recent messages of this forum topic, the user's facts (plus cross-chat facts if they opted
+	// in) and the latest 30-day and 7-day summaries (Section 8.4) come in one round trip
+	rc, err := database.LoadReplyContext(ctx, chatID, threadID, userID, contextSize, maxContextFacts)
 	if err != nil {
-		return nil, fmt.Errorf("get recent messages: %w", err)
+		return nil, fmt.Errorf("load reply context: %w", err)
 	}
-	di.RecentMessages = messages
+	di.RecentMessages = rc.Messages
+	di.UserFacts = rc.Facts
+	di.UserFactsTotal = rc.FactsTotal
+	di.Summary30Day = rc.Summary30Day
+	di.Summary7Day = rc.Summary7Day
 
-	// Load user facts for current user context
-	facts, err := database.GetUserFacts(ctx, chatID, userID)
-	if err != nil {
-		return nil, fmt.Errorf("get user facts: %w", err)
+	// Load recent polls so the model can comment on ongoing votes (best effort)
+	if polls, err := database.GetRecentPollResults(ctx, chatID, time.Now().Add(-pollLookback), maxContextPolls); err == nil {
+		di.Polls = polls
 	}
-	di.UserFacts = facts
 
-	// Load latest 30-day and 7-day summaries (Section 8.4)
-	if s30, err := database.GetLatestSummary(ctx, chatID, "30day"); err == nil {
-		di.Summary30Day = s30
+	// Load the sticker frequency profile (best effort)
+	if profile, err := database.GetStickerProfile(ctx, chatID, maxStickerProfile); err == nil {
+		di.StickerProfile = profile
 	}
-	if s7, err := database.GetLatestSummary(ctx, chatID, "7day"); err == nil {
-		di.Summary7Day = s7
+
+	if len(rc.Facts) > 0 {
+		// Facts shown to the model count as used, so the nightly decay keeps them (best effort)
+		ids := make([]int64, len(rc.Facts))
+		for i, f := range rc.Facts {
+			ids[i] = f.ID
+		}
+		_ = database.TouchUserFacts(ctx, ids)
+	}
+
+	// Load recently declined offers (best effort)
+	if refusals, err := database.GetRecentRefusals(ctx, userID, time.Now().Add(-refusalLookback), maxContextRefusals); err == nil {
+		di.UserRefusals = refusals
 	}
 
 	return di, nil
@@ -99,6 +184,12 @@ func (di *DynamicInstructions) BuildParts() []*genai.Part {
 	if di.ChatName != "" {
 		timeBlock += fmt.Sprintf("\nChat Name: %s", di.ChatName)
 	}
+	if di.ThreadID > 0 {
+		timeBlock += fmt.Sprintf("\nForum Topic ID: %d (context, summaries and search cover this topic only)", di.ThreadID)
+	}
+	if di.Pseudonymized {
+		timeBlock += "\nPrivacy mode: people here appear as Member N (@MemberN, user_id N). Call them that and use those IDs in tools; never guess real names."
+	}
 	parts = append(parts, genai.NewPartFromText(timeBlock))
 
 	// 2. Tools Block (Section 8.3) — injected as descriptive text
@@ -120,45 +211,80 @@ func (di *DynamicInstructions) BuildParts() []*genai.Part {
 		parts = append(parts, genai.NewPartFromText(contextBlock))
 	}
 
-	// 4. Immediate Chat Context (Section 8.4 bottom)
+	// 4. Immediate Chat Context (Section 8.4 bottom), rendered with reply threading
 	if len(di.RecentMessages) > 0 {
-		chatLog := "# Immediate Chat Context\n"
-		for _, msg := range di.RecentMessages {
-			name := "Unknown"
-			if msg.FirstName != nil {
-				name = *msg.FirstName
-			}
-			if msg.Username != nil {
-				name += " (@" + *msg.Username + ")"
-			}
+		parts = append(parts, genai.NewPartFromText("# Immediate Chat Context\n"+renderThreadedLog(di.RecentMessages)))
+	}
 
-			text := ""
-			if msg.Text != nil {
-				text = *msg.Text
-			}
+	// 4a. Sticker language of the chat
+	if len(di.StickerProfile) > 0 {
+		stickerBlock := "# Chat Sticker Language\nMost used stickers here (emoji, set, uses):\n"
+		for _, st := range di.StickerProfile {
+			stickerBlock += fmt.Sprintf("- %s — %d\n", StickerLabel(st.Emoji, st.SetName), st.Uses)
+		}
+		parts = append(parts, genai.NewPartFromText(stickerBlock))
+	}
 
-			prefix := ""
-			if msg.IsBotReply {
-				prefix = "[BOT] "
-			}
-			if msg.WasThrottled {
-				prefix = "[THROTTLED] "
-			}
+	// 4b. Polls
+	if len(di.Polls) > 0 {
+		parts = append(parts, genai.NewPartFromText("# Chat Polls\n"+renderPolls(di.Polls)))
+	}
 
-			chatLog += fmt.Sprintf("%s%s: %s\n", prefix, name, text)
-		}
-		parts = append(parts, genai.NewPartFromText(chatLog))
+	// 4c. Chat notes
+	if len(di.Notes) > 0 {
+		parts = append(parts, genai.NewPartFromText("# Chat Notes\nSaved with save_note; trust these over older messages:\n"+renderNotes(di.Notes)))
 	}
 
-	// 5. Current User Context (Section 8.5)
+	// 5. Current User Context (Section 8.5): who the user is here, then what is known about them
+	if block := profileBlock(di.UserProfile); block != "" {
+		parts = append(parts, genai.NewPartFromText(block))
+	}
 	if len(di.UserFacts) > 0 {
 		factsBlock := fmt.Sprintf("# Current User Context (user_id: %d)\n", di.UserID)
 		for _, f := range di.UserFacts {
-			factsBlock += fmt.Sprintf("- %s\n", f.FactText)
+			if f.ChatID == db.GlobalFactsChatID && f.Category != "" {
+				factsBlock += fmt.Sprintf("- [%s, global] %s\n", f.Category, f.FactText)
+			} else if f.Category != "" {
+				factsBlock += fmt.Sprintf("- [%s] %s\n", f.Category, f.FactText)
+			} else {
+				factsBlock += fmt.Sprintf("- %s\n", f.FactText)
+			}
+		}
+		if hidden := di.UserFactsTotal - len(di.UserFacts); hidden > 0 {
+			factsBlock += fmt.Sprintf("(%d less important facts omitted; use recall_memories to see all)\n", hidden)
 		}
 		parts = append(parts, genai.NewPartFromText(factsBlock))
 	}
 
+	// 5a. Offers the user declined — avoid nagging
+	if len(di.UserRefusals) > 0 {
+		offers := make([]string, len(di.UserRefusals))
+		for i, r := range di.UserRefusals {
+			offers[i] = r.Offer
+		}
+		parts = append(parts, genai.NewPartFromText(
+			"# Don't Offer\nThis user declined these before. Do not suggest them again unless they ask: "+strings.Join(offers, "; ")))
+	}
+
+	// 5b. The user's karma, for flavor
+	if di.UserKarma != nil {
+		parts = append(parts, genai.NewPartFromText(fmt.Sprintf(
+			"# Karma\nThis user's karma here is %d (rank %d in the chat). You may tease or praise them for it when it fits; don't bring it up every time.",
+			di.UserKarma.Score, di.UserKarma.Rank)))
+	}
+
+	// 5c. Reply language hint for mixed-language chats
+	if di.ReplyLanguage != "" {
+		parts = append(parts, genai.NewPartFromText(fmt.Sprintf(
+			"# Reply Language\nAnswer in %s (%s) unless the user explicitly asks for another language.",
+			i18n.LanguageName(di.ReplyLanguage), di.ReplyLanguage)))
+	}
+
+	// 5d. Chat mood, so the tone of the reply fits the room
+	if block := moodBlock(di.Mood); block != "" {
+		parts = append(parts, genai.NewPartFromText(block))
+	}
+
 	// 6. Multi-Media Buffer (Section 8.6)
 	// Up to 10 media parts injected directly as genai.Part entries
 	parts = append(parts, di.MediaParts...)
@@ -169,6 +295,9 @@ func (di *DynamicInstructions) BuildParts() []*genai.Part {
 		msgBlock += fmt.Sprintf(" (@%s)", di.Username)
 	}
 	msgBlock += fmt.Sprintf(" [user_id: %d]\nMessage: %s", di.UserID, di.CurrentMessage)
+	if di.Forward != nil {
+		msgBlock += di.Forward.describe()
+	}
 	if di.ReplyToText != "" {
 		if di.ReplyToMessageID != nil {
 			msgBlock += fmt.Sprintf("\nReplying to (message_id %d): %s", *di.ReplyToMessageID, di.ReplyToText)
@@ -182,3 +311,235 @@ func (di *DynamicInstructions) BuildParts() []*genai.Part {
 
 	return parts
 }
+
+// maxContextFacts caps how many user facts (most important first) go into every prompt.
+const maxContextFacts = 15
+
+// refusalLookback and maxContextRefusals bound which declined offers are shown; old refusals expire.
+const (
+	refusalLookback    = 60 * 24 * time.Hour
+	maxContextRefusals = 10
+)
+
+// maxStickerProfile is how many of the chat's top stickers are listed in the prompt.
+const maxStickerProfile = 8
+
+// moodBlock renders the chat's mood as guidance for the reply; "" when it is neutral or unknown.
+func moodBlock(m *sentiment.Mood) string {
+	if m == nil {
+		return ""
+	}
+	switch m.Label() {
+	case sentiment.Heated:
+		return "# Chat Mood\nThe chat is heated right now: people are arguing or shouting. Stay calm, don't joke at anyone's expense and don't take sides; if you speak, help cool it down."
+	case sentiment.Tense:
+		return "# Chat Mood\nThe chat feels tense or gloomy lately. Read the room: keep jokes gentle and don't poke at sore spots."
+	case sentiment.Cheerful:
+		return "# Chat Mood\nThe chat is in a cheerful mood. Playful replies fit."
+	}
+	return ""
+}
+
+// newUserMessages is how many messages a user may have written in the chat and still be new here.
+const newUserMessages = 5
+
+// profileBlock renders the user's profile in a few lines; "" without one.
+// Facts have their own block, so only their count is shown.
+func profileBlock(p *db.UserProfile) string {
+	if p == nil {
+		return ""
+	}
+	var lines []string
+	switch {
+	case p.Messages <= newUserMessages:
+		lines = append(lines, fmt.Sprintf("- New here: %d messages in this chat so far", p.Messages))
+	case p.FirstSeen != nil:
+		lines = append(lines, fmt.Sprintf("- Messages in this chat: %d, writing here since %s", p.Messages, p.FirstSeen.Format("January 2006")))
+	default:
+		lines = append(lines, fmt.Sprintf("- Messages in this chat: %d", p.Messages))
+	}
+	if p.Language != nil && *p.Language != "" {
+		lines = append(lines, fmt.Sprintf("- Preferred language: %s (%s)", i18n.LanguageName(*p.Language), *p.Language))
+	}
+	if p.Timezone != nil && *p.Timezone != "" {
+		lines = append(lines, "- Timezone: "+*p.Timezone)
+	}
+	if p.FactsTotal > 0 {
+		lines = append(lines, fmt.Sprintf("- Facts stored: %d", p.FactsTotal))
+	}
+	return "# User Profile\n" + strings.Join(lines, "\n")
+}
+
+// StickerLabel renders a sticker as text, e.g. "[sticker: 🤡 from set CoolPack]".
+func StickerLabel(emoji, setName string) string {
+	if emoji == "" {
+		emoji = "?"
+	}
+	if setName == "" {
+		return "[sticker: " + emoji + "]"
+	}
+	return "[sticker: " + emoji + " from set " + setName + "]"
+}
+
+// messageText returns the text of a stored message, describing stickers so they are not blank.
+func messageText(msg *db.Message) string {
+	text := ""
+	if msg.Text != nil {
+		text = *msg.Text
+	}
+	if msg.MediaType != nil && *msg.MediaType == "sticker" {
+		emoji, set := "", ""
+		if msg.StickerEmoji != nil {
+			emoji = *msg.StickerEmoji
+		}
+		if msg.StickerSet != nil {
+			set = *msg.StickerSet
+		}
+		label := StickerLabel(emoji, set)
+		if text == "" || text == emoji {
+			return label
+		}
+		return label + " " + text
+	}
+	return text
+}
+
+// pollLookback and maxContextPolls bound which polls are shown in the prompt.
+const (
+	pollLookback    = 7 * 24 * time.Hour
+	maxContextPolls = 3
+)
+
+// renderPolls formats polls with per-option tallies, naming voters when the poll is not anonymous.
+// maxContextNoteLen cuts long notes in the prompt; list_notes returns them in full.
+const maxContextNoteLen = 300
+
+func renderNotes(notes []db.ChatNote) string {
+	var b strings.Builder
+	for _, n := range notes {
+		body := n.Body
+		if r := []rune(body); len(r) > maxContextNoteLen {
+			body = string(r[:maxContextNoteLen]) + "…"
+		}
+		fmt.Fprintf(&b, "- [%d] %s: %s\n", n.ID, n.Title, strings.ReplaceAll(body, "\n", " "))
+	}
+	return b.String()
+}
+
+func renderPolls(polls []db.PollResult) string {
+	var b strings.Builder
+	for _, p := range polls {
+		state := "open"
+		if p.IsClosed {
+			state = "closed"
+		}
+		if p.IsAnonymous {
+			state += ", anonymous"
+		}
+		fmt.Fprintf(&b, "- %q (%s", p.Question, state)
+		if p.TotalVoters > 0 {
+			fmt.Fprintf(&b, ", %d voters", p.TotalVoters)
+		}
+		b.WriteString(")\n")
+		for i, o := range p.Results {
+			fmt.Fprintf(&b, "  %d. %s — %d", i+1, o.Text, o.Votes)
+			if len(o.Voters) > 0 {
+				fmt.Fprintf(&b, " (%s)", strings.Join(o.Voters, ", "))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// maxThreadDepth caps the indentation of nested reply chains in the immediate context.
+const maxThreadDepth = 3
+
+// threadSnippetRunes is how much of a replied-to message is quoted inline.
+const threadSnippetRunes = 60
+
+// displayName renders "First (@username)" for a stored message.
+func displayName(msg *db.Message) string {
+	name := "Unknown"
+	if msg.FirstName != nil {
+		name = *msg.FirstName
+	}
+	if msg.Username != nil {
+		name += " (@" + *msg.Username + ")"
+	}
+	if msg.IsBotReply && msg.FirstName == nil {
+		name = "Bot"
+	}
+	return name
+}
+
+// snippet returns text shortened to n runes with an ellipsis.
+func snippet(text string, n int) string {
+	r := []rune(strings.Join(strings.Fields(text), " "))
+	if len(r) <= n {
+		return string(r)
+	}
+	return string(r[:n]) + "…"
+}
+
+// renderThreadedLog formats messages oldest-first, marking replies with "↳" and indenting nested
+// reply chains so the model can follow multi-branch group conversations. Bot replies are threaded
+// under the user message that triggered them (same request_id).
+func renderThreadedLog(messages []db.Message) string {
+	byMessageID := make(map[int64]int, len(messages))
+	byRequestID := make(map[string]int, len(messages))
+	for i := range messages {
+		m := &messages[i]
+		if m.MessageID != nil && *m.MessageID != 0 {
+			byMessageID[*m.MessageID] = i
+		}
+		if !m.IsBotReply && m.RequestID != nil && *m.RequestID != "" {
+			byRequestID[*m.RequestID] = i
+		}
+	}
+
+	depth := make([]int, len(messages))
+	var b strings.Builder
+	for i := range messages {
+		msg := &messages[i]
+		text := messageText(msg)
+		prefix := ""
+		if msg.IsBotReply {
+			prefix = "[BOT] "
+		}
+		if msg.WasThrottled {
+			prefix = "[THROTTLED] "
+		}
+
+		parent := -1
+		if msg.ReplyToMessageID != nil {
+			if p, ok := byMessageID[*msg.ReplyToMessageID]; ok && p < i {
+				parent = p
+			}
+		} else if msg.IsBotReply && msg.RequestID != nil {
+			if p, ok := byRequestID[*msg.RequestID]; ok && p < i && p != i-1 {
+				// Only mark bot replies that don't directly follow their trigger; adjacent ones read fine.
+				parent = p
+			}
+		}
+
+		switch {
+		case parent >= 0:
+			depth[i] = depth[parent] + 1
+			if depth[i] > maxThreadDepth {
+				depth[i] = maxThreadDepth
+			}
+			pm := &messages[parent]
+			quoted := snippet(messageText(pm), threadSnippetRunes)
+			fmt.Fprintf(&b, "%s↳ %s%s (replying to %s: %q): %s\n",
+				strings.Repeat("  ", depth[i]-1), prefix, displayName(msg), displayName(pm), quoted, text)
+		case msg.ReplyToMessageID != nil:
+			depth[i] = 1
+			fmt.Fprintf(&b, "↳ %s%s (replying to an earlier message, id %d): %s\n",
+				prefix, displayName(msg), *msg.ReplyToMessageID, text)
+		default:
+			fmt.Fprintf(&b, "%s%s: %s\n", prefix, displayName(msg), text)
+		}
+	}
+	return b.String()
+}
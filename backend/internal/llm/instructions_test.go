@@ -1,9 +1,12 @@
 package llm
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
 	"google.golang.org/genai"
 )
 
@@ -89,6 +92,167 @@ func TestDynamicInstructions_BuildParts_WithFacts(t *testing.T) {
 	}
 }
 
+func TestDynamicInstructions_BuildParts_TopFacts(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentTime:    "10:00 Monday, 24/02/2026",
+		ChatID:         123,
+		CurrentMessage: "Test",
+		UserID:         456,
+		FirstName:      "Test",
+		UserFacts: []db.UserFact{
+			{ChatID: 123, FactText: "Backend developer", Category: "bio", Importance: 5},
+			{ChatID: 123, FactText: "Hates pineapple pizza", Category: "preference", Importance: 4},
+			{ChatID: db.GlobalFactsChatID, FactText: "Lives in Lviv", Category: "bio", Importance: 4},
+		},
+		UserFactsTotal: 30,
+	}
+	var block string
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Current User Context") {
+			block = p.Text
+		}
+	}
+	if !strings.Contains(block, "- [bio] Backend developer") || !strings.Contains(block, "- [preference] Hates pineapple pizza") {
+		t.Errorf("facts block missing categorized facts: %q", block)
+	}
+	if !strings.Contains(block, "- [bio, global] Lives in Lviv") {
+		t.Errorf("global fact should be marked: %q", block)
+	}
+	if !strings.Contains(block, "27 less important facts omitted") {
+		t.Errorf("facts block should note omitted facts: %q", block)
+	}
+}
+
+func TestDynamicInstructions_BuildParts_UserRefusals(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentTime:    "10:00 Monday, 24/02/2026",
+		ChatID:         123,
+		CurrentMessage: "Test",
+		UserID:         456,
+		FirstName:      "Test",
+		UserRefusals: []db.UserRefusal{
+			{Offer: "image generation", TimesDeclined: 2},
+			{Offer: "news digest", TimesDeclined: 1},
+		},
+	}
+	found := false
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Don't Offer") {
+			found = true
+			if !strings.Contains(p.Text, "image generation; news digest") {
+				t.Errorf("refusal block missing offers: %q", p.Text)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a # Don't Offer block")
+	}
+
+	di.UserRefusals = nil
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Don't Offer") {
+			t.Error("no refusal block expected without refusals")
+		}
+	}
+}
+
+func TestDynamicInstructions_BuildParts_Notes(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentTime: "10:00", ChatID: 123, UserID: 456, FirstName: "Test",
+		Notes: []db.ChatNote{{ID: 3, Title: "оренда", Body: "платимо\n12-го числа"}},
+	}
+	found := false
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Chat Notes") {
+			found = true
+			if !strings.Contains(p.Text, "- [3] оренда: платимо 12-го числа") {
+				t.Errorf("notes block missing the note on one line: %q", p.Text)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a # Chat Notes block")
+	}
+}
+
+func TestDynamicInstructions_BuildParts_UserKarma(t *testing.T) {
+	di := &DynamicInstructions{CurrentTime: "10:00", ChatID: 123, UserID: 456, FirstName: "Test"}
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Karma") {
+			t.Error("no karma block expected without karma")
+		}
+	}
+	di.UserKarma = &db.KarmaEntry{UserID: 456, Score: -3, Rank: 7}
+	found := false
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Karma") {
+			found = true
+			if !strings.Contains(p.Text, "karma here is -3 (rank 7") {
+				t.Errorf("karma block missing score or rank: %q", p.Text)
+			}
+		}
+	}
+	if !found {
+		t.Error("expected a # Karma block")
+	}
+}
+
+func TestDynamicInstructions_BuildParts_Mood(t *testing.T) {
+	hasMood := func(di *DynamicInstructions) string {
+		for _, p := range di.BuildParts() {
+			if strings.Contains(p.Text, "# Chat Mood") {
+				return p.Text
+			}
+		}
+		return ""
+	}
+	di := &DynamicInstructions{CurrentTime: "10:00", ChatID: 123, UserID: 456, FirstName: "Test"}
+	if hasMood(di) != "" {
+		t.Error("no mood block expected without a mood")
+	}
+	di.Mood = &sentiment.Mood{Valence: 0.1, Messages: 10}
+	if hasMood(di) != "" {
+		t.Error("no mood block expected for a neutral mood")
+	}
+	di.Mood = &sentiment.Mood{Heat: 0.7, Messages: 10}
+	if block := hasMood(di); !strings.Contains(block, "heated") {
+		t.Errorf("expected a heated mood block, got %q", block)
+	}
+}
+
+func TestProfileBlock(t *testing.T) {
+	if profileBlock(nil) != "" {
+		t.Error("no profile block expected without a profile")
+	}
+	lang, tz := "uk", "Europe/Kyiv"
+	since := time.Date(2025, 3, 14, 9, 0, 0, 0, time.UTC)
+	block := profileBlock(&db.UserProfile{Messages: 412, FirstSeen: &since, Language: &lang, Timezone: &tz, FactsTotal: 12})
+	for _, want := range []string{"# User Profile", "Messages in this chat: 412, writing here since March 2025", "(uk)", "Timezone: Europe/Kyiv", "Facts stored: 12"} {
+		if !strings.Contains(block, want) {
+			t.Errorf("profile block missing %q: %q", want, block)
+		}
+	}
+	block = profileBlock(&db.UserProfile{Messages: 2, FirstSeen: &since})
+	if !strings.Contains(block, "New here: 2 messages") || strings.Contains(block, "Facts stored") || strings.Contains(block, "Timezone") {
+		t.Errorf("unexpected block for a new user: %q", block)
+	}
+}
+
+func TestDynamicInstructions_SetLocation(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	di := &DynamicInstructions{ChatID: 123, UserID: 456, FirstName: "Test"}
+	di.SetLocation(time.Date(2026, 3, 8, 16, 30, 0, 0, time.UTC), toronto)
+	if want := "12:30 Sunday, 08/03/2026 (America/Toronto)"; di.CurrentTime != want {
+		t.Errorf("CurrentTime = %q, want %q", di.CurrentTime, want)
+	}
+	if !strings.Contains(di.BuildParts()[0].Text, "# Current Time\n12:30 Sunday, 08/03/2026 (America/Toronto)") {
+		t.Error("expected the user's local time in the Current Time block")
+	}
+}
+
 func TestDynamicInstructions_BuildParts_WithMediaParts(t *testing.T) {
 	di := &DynamicInstructions{
 		CurrentTime:    "12:00 Tuesday, 25/02/2026",
@@ -114,3 +278,153 @@ func TestDynamicInstructions_BuildParts_WithMediaParts(t *testing.T) {
 		t.Error("expected one part to have InlineData from MediaParts")
 	}
 }
+
+func TestDynamicInstructions_BuildParts_ReplyLanguage(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentTime:    "12:00 Tuesday, 25/02/2026",
+		ChatID:         1,
+		CurrentMessage: "Hi",
+		UserID:         2,
+		FirstName:      "User",
+		ReplyLanguage:  "en",
+	}
+	found := false
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Reply Language") && strings.Contains(p.Text, "English (en)") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected a reply language block naming English")
+	}
+
+	di.ReplyLanguage = ""
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Reply Language") {
+			t.Error("expected no reply language block when ReplyLanguage is empty")
+		}
+	}
+}
+
+func TestDynamicInstructions_BuildParts_Forward(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentTime:    "12:00 Tuesday, 25/02/2026",
+		ChatID:         1,
+		CurrentMessage: "Уряд оголосив нові правила",
+		UserID:         2,
+		FirstName:      "User",
+		Forward:        &Forward{From: "Editor", Chat: "Новини (@news_ua)", Date: time.Date(2026, 2, 24, 18, 5, 0, 0, time.UTC)},
+	}
+	parts := di.BuildParts()
+	last := parts[len(parts)-1].Text
+	if !strings.Contains(last, "Forwarded from Editor in Новини (@news_ua), originally sent 2026-02-24 18:05.") || !strings.Contains(last, "did not write it") {
+		t.Errorf("expected the forward line in the current message, got %q", last)
+	}
+
+	di.Forward = &Forward{}
+	if last := di.BuildParts()[len(parts)-1].Text; !strings.Contains(last, "\nForwarded. The user shared this") {
+		t.Errorf("expected a bare forward line without details, got %q", last)
+	}
+	di.Forward = nil
+	if last := di.BuildParts()[len(parts)-1].Text; strings.Contains(last, "Forwarded") {
+		t.Errorf("expected no forward line, got %q", last)
+	}
+}
+
+func TestRenderThreadedLog(t *testing.T) {
+	str := func(s string) *string { return &s }
+	id := func(i int64) *int64 { return &i }
+
+	messages := []db.Message{
+		{MessageID: id(1), FirstName: str("Olena"), Text: str("Хто йде на каву?"), RequestID: str("r1")},
+		{MessageID: id(2), FirstName: str("Petro"), Text: str("Дивіться, що я знайшов"), RequestID: str("r2")},
+		{MessageID: id(3), FirstName: str("Ivan"), Text: str("Я йду"), ReplyToMessageID: id(1), RequestID: str("r3")},
+		{MessageID: id(4), FirstName: str("Olena"), Text: str("Супер"), ReplyToMessageID: id(3), RequestID: str("r4")},
+		{MessageID: id(5), FirstName: str("Petro"), Text: str("Старе"), ReplyToMessageID: id(999), RequestID: str("r5")},
+		{IsBotReply: true, Text: str("Кава — це життя"), RequestID: str("r3")},
+	}
+
+	out := renderThreadedLog(messages)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("expected 6 lines, got %d:\n%s", len(lines), out)
+	}
+	if lines[0] != "Olena: Хто йде на каву?" {
+		t.Errorf("unexpected root line: %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[2], "↳ Ivan (replying to Olena: ") {
+		t.Errorf("expected Ivan's reply to be threaded under Olena, got %q", lines[2])
+	}
+	if !strings.HasPrefix(lines[3], "  ↳ Olena (replying to Ivan: ") {
+		t.Errorf("expected nested reply to be indented, got %q", lines[3])
+	}
+	if !strings.Contains(lines[4], "replying to an earlier message, id 999") {
+		t.Errorf("expected out-of-window reply marker, got %q", lines[4])
+	}
+	if !strings.HasPrefix(lines[5], "  ↳ [BOT] Bot (replying to Ivan: ") {
+		t.Errorf("expected bot reply threaded under its trigger, got %q", lines[5])
+	}
+}
+
+func TestDynamicInstructions_BuildParts_Polls(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentMessage: "хто переміг?",
+		Polls: []db.PollResult{{
+			Poll: db.Poll{Question: "Where to eat?", TotalVoters: 3, IsClosed: true},
+			Results: []db.PollOption{
+				{Text: "Pizza", Votes: 2, Voters: []string{"Alice", "Bob"}},
+				{Text: "Sushi", Votes: 1},
+			},
+		}},
+	}
+
+	var found string
+	for _, p := range di.BuildParts() {
+		if strings.HasPrefix(p.Text, "# Chat Polls") {
+			found = p.Text
+		}
+	}
+	if found == "" {
+		t.Fatal("expected a polls block")
+	}
+	for _, want := range []string{`"Where to eat?" (closed, 3 voters)`, "1. Pizza — 2 (Alice, Bob)", "2. Sushi — 1"} {
+		if !strings.Contains(found, want) {
+			t.Errorf("polls block missing %q:\n%s", want, found)
+		}
+	}
+}
+
+func TestMessageText_Sticker(t *testing.T) {
+	sticker, emoji, set := "sticker", "🤡", "ClownPack"
+	msg := &db.Message{MediaType: &sticker, StickerEmoji: &emoji, StickerSet: &set}
+	if got := messageText(msg); got != "[sticker: 🤡 from set ClownPack]" {
+		t.Errorf("unexpected sticker text %q", got)
+	}
+
+	msg.StickerSet = nil
+	if got := messageText(msg); got != "[sticker: 🤡]" {
+		t.Errorf("unexpected loose sticker text %q", got)
+	}
+
+	text := "plain"
+	if got := messageText(&db.Message{Text: &text}); got != "plain" {
+		t.Errorf("expected plain text, got %q", got)
+	}
+}
+
+func TestDynamicInstructions_BuildParts_StickerProfile(t *testing.T) {
+	di := &DynamicInstructions{
+		CurrentMessage: StickerLabel("🤡", "ClownPack"),
+		StickerProfile: []db.StickerUsage{{SetName: "ClownPack", Emoji: "🤡", Uses: 42}},
+	}
+
+	var found bool
+	for _, p := range di.BuildParts() {
+		if strings.Contains(p.Text, "# Chat Sticker Language") && strings.Contains(p.Text, "[sticker: 🤡 from set ClownPack] — 42") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected sticker profile block")
+	}
+}
@@ -0,0 +1,72 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ProactiveVerdict is the judge's rating of a candidate proactive message.
+type ProactiveVerdict struct {
+	Score  int    `json:"score"` // 1 (drop) to 10 (clearly worth sending)
+	Reason string `json:"reason"`
+}
+
+const proactiveJudgeInstruction = `You review a message a chat bot wants to post on its own initiative, without being asked. Rate it from 1 to 10:
+- relevance: does it fit what the chat has been talking about, or is it a natural, interesting new topic?
+- novelty: is it different from the bot's recent messages and earlier unprompted posts? Repeating a topic, joke, phrasing or structure scores low.
+- value: would members likely welcome it rather than find it noise?
+Score 1-3 for repetitive or pointless messages, 4-6 for mediocre ones, 7-10 for good ones.
+Respond with JSON only: {"score": <1-10>, "reason": "<one short sentence>"}`
+
+// JudgeProactive rates a candidate proactive message against the bot's recent replies and its
+// earlier proactive posts in the chat. The call is cheap: low temperature, no thinking, short
+// JSON output.
+func (c *Client) JudgeProactive(ctx context.Context, candidate string, recentReplies, previousPosts []string) (*ProactiveVerdict, error) {
+	var b strings.Builder
+	writeList := func(title string, items []string) {
+		fmt.Fprintf(&b, "%s:\n", title)
+		if len(items) == 0 {
+			b.WriteString("(none)\n")
+		}
+		for _, it := range items {
+			fmt.Fprintf(&b, "- %s\n", strings.ReplaceAll(it, "\n", " "))
+		}
+		b.WriteString("\n")
+	}
+	writeList("Bot's recent replies in the chat", recentReplies)
+	writeList("Bot's earlier unprompted posts in the chat", previousPosts)
+	fmt.Fprintf(&b, "Candidate message:\n%s", candidate)
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(proactiveJudgeInstruction)},
+		},
+		Temperature:      genai.Ptr(float32(0)),
+		ResponseMIMEType: "application/json",
+		ThinkingConfig:   &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(b.String())}},
+	}
+	resp, err := c.generate(ctx, "proactive_judge", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("judge proactive: %w", err)
+	}
+	return parseProactiveVerdict(extractText(resp))
+}
+
+// parseProactiveVerdict decodes the judge's JSON, tolerating a Markdown code fence.
+func parseProactiveVerdict(text string) (*ProactiveVerdict, error) {
+	var v ProactiveVerdict
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &v); err != nil {
+		return nil, fmt.Errorf("parse proactive verdict: %w", err)
+	}
+	if v.Score < 1 || v.Score > 10 {
+		return nil, fmt.Errorf("parse proactive verdict: score %d out of range", v.Score)
+	}
+	return &v, nil
+}
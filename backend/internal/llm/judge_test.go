@@ -0,0 +1,18 @@
+package llm
+
+import "testing"
+
+func TestParseProactiveVerdict(t *testing.T) {
+	v, err := parseProactiveVerdict("```json\n{\"score\": 3, \"reason\": \"repeats yesterday's joke\"}\n```")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v.Score != 3 || v.Reason != "repeats yesterday's joke" {
+		t.Errorf("unexpected verdict: %+v", v)
+	}
+	for _, bad := range []string{"", "not json", `{"score": 0}`, `{"score": 11}`} {
+		if _, err := parseProactiveVerdict(bad); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// NoTextMarker is what ExtractText's model answers for an image without readable text.
+const NoTextMarker = "NO_TEXT"
+
+const extractTextInstruction = `You are an OCR engine, not a chat participant. Copy every piece of readable text in the image exactly as written: same language, spelling, case, punctuation, emoji, numbers and links; never translate, correct, summarize or describe. Keep the layout: one line per visual line, a blank line between blocks, Markdown tables for tables, "- " for list items. For chat screenshots write each message as "Name: text" in order. Mark unreadable parts as [illegible]. Output only the text. If there is no readable text, output exactly ` + NoTextMarker + `.`
+
+// ExtractText transcribes the text in an image (or PDF) at temperature 0, without the persona.
+// It returns "" when the image has no readable text.
+func (c *Client) ExtractText(ctx context.Context, data []byte, mimeType string) (string, error) {
+	part, cleanup, err := c.MediaPart(ctx, data, mimeType)
+	if err != nil {
+		return "", fmt.Errorf("extract text: %w", err)
+	}
+	defer cleanup()
+
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(extractTextInstruction)},
+		},
+		Temperature:     genai.Ptr(float32(0)),
+		MediaResolution: genai.MediaResolutionHigh,
+		ThinkingConfig:  &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	contents := []*genai.Content{{Role: "user", Parts: []*genai.Part{part}}}
+	resp, err := c.generate(ctx, "extract_text", contents, config)
+	if err != nil {
+		return "", fmt.Errorf("extract text: %w", err)
+	}
+	return cleanExtractedText(extractText(resp)), nil
+}
+
+// cleanExtractedText drops the no-text marker and a code fence wrapped around the whole answer
+// (with its language tag); fences inside the text are the image's own.
+func cleanExtractedText(text string) string {
+	text = strings.TrimSpace(text)
+	if inner, ok := strings.CutPrefix(text, "```"); ok && strings.HasSuffix(inner, "```") {
+		if _, body, found := strings.Cut(inner, "\n"); found {
+			text = strings.TrimSpace(strings.TrimSuffix(body, "```"))
+		}
+	}
+	if text == NoTextMarker {
+		return ""
+	}
+	return text
+}
@@ -0,0 +1,17 @@
+package llm
+
+import "testing"
+
+func TestCleanExtractedText(t *testing.T) {
+	for in, want := range map[string]string{
+		"  NO_TEXT\n": "",
+		"Оплата до 15.03\nСума: 1200 грн":    "Оплата до 15.03\nСума: 1200 грн",
+		"```text\nName: hi\nBob: hey\n```":   "Name: hi\nBob: hey",
+		"```\nNO_TEXT\n```":                  "",
+		"run `make` then\n```\ngo test\n```": "run `make` then\n```\ngo test\n```",
+	} {
+		if got := cleanExtractedText(in); got != want {
+			t.Errorf("cleanExtractedText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
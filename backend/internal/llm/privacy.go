@@ -0,0 +1,312 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"google.golang.org/genai"
+)
+
+const (
+	// minInflectedNameRunes and maxNameSuffixRunes let a name match its inflected forms
+	// ("Олега", "Олегу" for "Олег"); shorter names only match whole words.
+	minInflectedNameRunes = 4
+	maxNameSuffixRunes    = 2
+	// minUserIDDigits keeps short numbers (counts, years) from being read as user IDs.
+	minUserIDDigits = 5
+)
+
+// Privacy swaps the people of a privacy-mode chat for their pseudonyms: in text sent to the model
+// names become "Member N", @usernames "@MemberN" and user IDs N, and tool call arguments and the
+// reply are mapped back. The mapping stays in the database (chat_pseudonyms). A nil *Privacy
+// leaves everything unchanged.
+type Privacy struct {
+	byID       map[int64]int
+	byAlias    map[int]int64
+	byUsername map[string]int // lowercased, without "@"
+	byName     map[string]int // lowercased first name words
+	reveal     *strings.Replacer
+}
+
+// LoadPrivacy records the people in di as pseudonyms of its chat and returns the chat's Privacy.
+func LoadPrivacy(ctx context.Context, database *db.DB, di *DynamicInstructions) (*Privacy, error) {
+	people, err := database.SyncPseudonyms(ctx, di.ChatID, di.Participants())
+	if err != nil {
+		return nil, fmt.Errorf("load pseudonyms: %w", err)
+	}
+	return NewPrivacy(people), nil
+}
+
+// Participants returns the people in the prompt with the names they used: the current user and
+// the senders of recent messages.
+func (di *DynamicInstructions) Participants() []db.Pseudonym {
+	seen := make(map[int64]int)
+	var out []db.Pseudonym
+	add := func(userID int64, firstName, username string) {
+		if userID == 0 {
+			return
+		}
+		if i, ok := seen[userID]; ok {
+			// Later messages carry the newer names
+			if firstName != "" {
+				out[i].FirstName = firstName
+			}
+			if username != "" {
+				out[i].Username = username
+			}
+			return
+		}
+		seen[userID] = len(out)
+		out = append(out, db.Pseudonym{UserID: userID, FirstName: firstName, Username: username})
+	}
+	for i := range di.RecentMessages {
+		m := &di.RecentMessages[i]
+		if m.IsBotReply || m.UserID == nil {
+			continue
+		}
+		firstName, username := "", ""
+		if m.FirstName != nil {
+			firstName = *m.FirstName
+		}
+		if m.Username != nil {
+			username = *m.Username
+		}
+		add(*m.UserID, firstName, username)
+	}
+	add(di.UserID, di.FirstName, di.Username)
+	return out
+}
+
+// NewPrivacy builds the mapping from a chat's pseudonyms.
+func NewPrivacy(people []db.Pseudonym) *Privacy {
+	p := &Privacy{
+		byID:       make(map[int64]int, len(people)),
+		byAlias:    make(map[int]int64, len(people)),
+		byUsername: make(map[string]int),
+		byName:     make(map[string]int),
+	}
+	var pairs [][2]string
+	for _, u := range people {
+		p.byID[u.UserID] = u.Alias
+		p.byAlias[u.Alias] = u.UserID
+		label, handle := memberLabel(u.Alias), "@"+memberHandle(u.Alias)
+		name := u.FirstName
+		if u.Username != "" {
+			p.byUsername[strings.ToLower(u.Username)] = u.Alias
+			if name == "" {
+				name = "@" + u.Username
+			}
+			pairs = append(pairs, [2]string{handle, "@" + u.Username})
+		} else if name != "" {
+			pairs = append(pairs, [2]string{handle, name})
+		}
+		for _, w := range nameWords(u.FirstName) {
+			p.byName[strings.ToLower(w)] = u.Alias
+		}
+		if name != "" {
+			pairs = append(pairs, [2]string{label, name})
+		}
+	}
+	// Longest first, so "Member 12" is not read as "Member 1" followed by "2"
+	sort.SliceStable(pairs, func(i, j int) bool { return len(pairs[i][0]) > len(pairs[j][0]) })
+	flat := make([]string, 0, 2*len(pairs))
+	for _, pr := range pairs {
+		flat = append(flat, pr[0], pr[1])
+	}
+	p.reveal = strings.NewReplacer(flat...)
+	return p
+}
+
+func memberLabel(alias int) string  { return fmt.Sprintf("Member %d", alias) }
+func memberHandle(alias int) string { return fmt.Sprintf("Member%d", alias) }
+
+// nameWords splits a first name into the words worth hiding; emoji and initials are dropped.
+func nameWords(name string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(name, func(r rune) bool { return !isNameRune(r) }) {
+		if utf8.RuneCountInString(w) >= 2 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+func isNameRune(r rune) bool {
+	return unicode.IsLetter(r) || r == '\'' || r == '’'
+}
+
+func isUsernameRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_')
+}
+
+// Hide replaces the chat's known names, @usernames and user IDs in text with pseudonyms.
+func (p *Privacy) Hide(text string) string {
+	if p == nil || text == "" {
+		return text
+	}
+	var b strings.Builder
+	b.Grow(len(text))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '@':
+			end := i + size
+			for end < len(text) && isUsernameRune(rune(text[end])) {
+				end++
+			}
+			if alias, ok := p.byUsername[strings.ToLower(text[i+size:end])]; ok && end > i+size {
+				b.WriteString("@" + memberHandle(alias))
+			} else {
+				b.WriteString(text[i:end])
+			}
+			i = end
+		case unicode.IsDigit(r):
+			end := i
+			for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+				end++
+			}
+			if end == i { // a non-ASCII digit
+				end = i + size
+			}
+			b.WriteString(p.hideID(text[i:end]))
+			i = end
+		case unicode.IsLetter(r):
+			end := i
+			for end < len(text) {
+				r, size := utf8.DecodeRuneInString(text[end:])
+				if !isNameRune(r) {
+					break
+				}
+				end += size
+			}
+			b.WriteString(p.hideWord(text[i:end]))
+			i = end
+		default:
+			b.WriteString(text[i : i+size])
+			i += size
+		}
+	}
+	return b.String()
+}
+
+func (p *Privacy) hideID(digits string) string {
+	if len(digits) < minUserIDDigits {
+		return digits
+	}
+	id, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return digits
+	}
+	if alias, ok := p.byID[id]; ok {
+		return strconv.Itoa(alias)
+	}
+	return digits
+}
+
+// hideWord replaces a word that is a known first name, or an inflected form of a longer one.
+func (p *Privacy) hideWord(word string) string {
+	lower := strings.ToLower(word)
+	if alias, ok := p.byName[lower]; ok {
+		return memberLabel(alias)
+	}
+	runes := []rune(lower)
+	for cut := 1; cut <= maxNameSuffixRunes && len(runes)-cut >= minInflectedNameRunes; cut++ {
+		if alias, ok := p.byName[string(runes[:len(runes)-cut])]; ok {
+			return memberLabel(alias)
+		}
+	}
+	return word
+}
+
+// HideParts hides the text of prompt parts in place and returns them.
+func (p *Privacy) HideParts(parts []*genai.Part) []*genai.Part {
+	if p == nil {
+		return parts
+	}
+	for _, part := range parts {
+		if part != nil && part.Text != "" {
+			part.Text = p.Hide(part.Text)
+		}
+	}
+	return parts
+}
+
+// Reveal puts the real names back into text the model wrote (its reply).
+func (p *Privacy) Reveal(text string) string {
+	if p == nil {
+		return text
+	}
+	return p.reveal.Replace(text)
+}
+
+// RevealCall returns a copy of a function call the model made with pseudonyms, with real user IDs
+// in its user_id arguments and real names in its strings, ready to execute.
+func (p *Privacy) RevealCall(fc *genai.FunctionCall) *genai.FunctionCall {
+	if p == nil || fc == nil {
+		return fc
+	}
+	out := *fc
+	if fc.Args != nil {
+		out.Args = p.revealArgs(fc.Args)
+	}
+	return &out
+}
+
+func (p *Privacy) revealArgs(args map[string]any) map[string]any {
+	out := make(map[string]any, len(args))
+	for k, v := range args {
+		if k == "user_id" {
+			if id, ok := p.realUserID(v); ok {
+				out[k] = id
+				continue
+			}
+		}
+		out[k] = p.revealValue(v)
+	}
+	return out
+}
+
+func (p *Privacy) revealValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return p.Reveal(v)
+	case map[string]any:
+		return p.revealArgs(v)
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = p.revealValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// realUserID maps an alias given as a user_id argument (a JSON number or numeric string) back.
+func (p *Privacy) realUserID(v any) (int64, bool) {
+	var alias int
+	switch v := v.(type) {
+	case float64:
+		alias = int(v)
+	case int:
+		alias = v
+	case int64:
+		alias = int(v)
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return 0, false
+		}
+		alias = n
+	default:
+		return 0, false
+	}
+	id, ok := p.byAlias[alias]
+	return id, ok
+}
@@ -0,0 +1,96 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"google.golang.org/genai"
+)
+
+func testPrivacy() *Privacy {
+	return NewPrivacy([]db.Pseudonym{
+		{UserID: 392817811, Alias: 1, FirstName: "Олег", Username: "oleg_k"},
+		{UserID: 555000111, Alias: 2, FirstName: "Іра"},
+		{UserID: 777000222, Alias: 12, Username: "nameless"},
+	})
+}
+
+func TestPrivacy_Hide(t *testing.T) {
+	p := testPrivacy()
+	tests := []struct{ in, want string }{
+		{"From: Олег (@oleg_k) [user_id: 392817811]", "From: Member 1 (@Member1) [user_id: 1]"},
+		{"передай Олегу, що Іра прийде", "передай Member 1, що Member 2 прийде"},
+		{"@OLEG_K і @nameless, а @someone ні", "@Member1 і @Member12, а @someone ні"},
+		{"Ірина і Іраклій тут ні до чого", "Ірина і Іраклій тут ні до чого"},
+		{"у 2024 році 12345 разів, id 3928178110", "у 2024 році 12345 разів, id 3928178110"},
+		{"олег написав", "Member 1 написав"},
+	}
+	for _, tt := range tests {
+		if got := p.Hide(tt.in); got != tt.want {
+			t.Errorf("Hide(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	var none *Privacy
+	if got := none.Hide("Олег"); got != "Олег" {
+		t.Errorf("nil Privacy changed text: %q", got)
+	}
+}
+
+func TestPrivacy_Reveal(t *testing.T) {
+	p := testPrivacy()
+	got := p.Reveal("Member 1 (@Member1) і Member 12 сперечаються, Member 2 мовчить")
+	if want := "Олег (@oleg_k) і @nameless сперечаються, Іра мовчить"; got != want {
+		t.Errorf("Reveal = %q, want %q", got, want)
+	}
+}
+
+func TestPrivacy_RevealCall(t *testing.T) {
+	p := testPrivacy()
+	fc := &genai.FunctionCall{Name: "recall_memories", Args: map[string]any{
+		"user_id": float64(1),
+		"query":   "що Member 2 казала",
+		"tags":    []any{"Member 12"},
+	}}
+	got := p.RevealCall(fc)
+	if got.Args["user_id"] != int64(392817811) {
+		t.Errorf("user_id = %v, want the real ID", got.Args["user_id"])
+	}
+	if got.Args["query"] != "що Іра казала" {
+		t.Errorf("query = %v", got.Args["query"])
+	}
+	if tags := got.Args["tags"].([]any); tags[0] != "@nameless" {
+		t.Errorf("tags = %v", tags)
+	}
+	if fc.Args["user_id"] != float64(1) {
+		t.Error("RevealCall must not change the model's call")
+	}
+	if p.RevealCall(&genai.FunctionCall{Args: map[string]any{"user_id": float64(99)}}).Args["user_id"] != float64(99) {
+		t.Error("an unknown alias should pass through unchanged")
+	}
+}
+
+func TestDynamicInstructions_Participants(t *testing.T) {
+	id, other := int64(1), int64(2)
+	oldName, newName, nick := "Old", "New", "nick"
+	di := &DynamicInstructions{
+		UserID: 3, FirstName: "Current",
+		RecentMessages: []db.Message{
+			{UserID: &id, FirstName: &oldName},
+			{IsBotReply: true},
+			{UserID: &other, Username: &nick},
+			{UserID: &id, FirstName: &newName},
+		},
+	}
+	got := di.Participants()
+	if len(got) != 3 || got[0].FirstName != "New" || got[1].Username != "nick" || got[2].UserID != 3 {
+		t.Errorf("unexpected participants: %+v", got)
+	}
+}
+
+func TestDynamicInstructions_BuildParts_Pseudonymized(t *testing.T) {
+	di := &DynamicInstructions{CurrentTime: "10:00", ChatID: 123, UserID: 456, FirstName: "Test", Pseudonymized: true}
+	if parts := di.BuildParts(); !strings.Contains(parts[0].Text, "Privacy mode") {
+		t.Errorf("expected the privacy note in the chat info block: %q", parts[0].Text)
+	}
+}
@@ -0,0 +1,157 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// Source is one web page a grounded search answer was based on.
+type Source struct {
+	Title string
+	URI   string
+}
+
+// ResearchFinding is the grounded answer to one research query.
+type ResearchFinding struct {
+	Query   string
+	Text    string
+	Sources []Source
+}
+
+const researchPlanInstruction = `You plan web research. Split the user's question into at most %d distinct web search queries that together cover it (different angles, not rephrasings). Write queries in the language most likely to find good sources.
+Respond with JSON only: {"queries":["...","..."]}`
+
+const researchSynthesisInstruction = "You write the final answer of a research task. You get the question and the findings of several web searches, each with its numbered sources. Combine them into one well-structured, factual answer: reconcile or point out contradictions, and cite sources inline as [1], [2] using the numbers given. Do not invent sources. Keep it readable in a chat (a few short paragraphs or a list). %s Output only the answer."
+
+// SearchWithSources is SearchWithGrounding that also returns the web sources the answer used.
+func (c *Client) SearchWithSources(ctx context.Context, query string) (string, []Source, error) {
+	config := &genai.GenerateContentConfig{
+		Tools: []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}},
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(query)}},
+	}
+	resp, err := c.generate(ctx, "research", contents, config)
+	if err != nil {
+		return "", nil, fmt.Errorf("grounding request: %w", err)
+	}
+	return extractText(resp), groundingSources(resp), nil
+}
+
+// groundingSources lists the web chunks of a grounded response.
+func groundingSources(resp *genai.GenerateContentResponse) []Source {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].GroundingMetadata == nil {
+		return nil
+	}
+	var out []Source
+	for _, ch := range resp.Candidates[0].GroundingMetadata.GroundingChunks {
+		if ch != nil && ch.Web != nil && ch.Web.URI != "" {
+			out = append(out, Source{Title: ch.Web.Title, URI: ch.Web.URI})
+		}
+	}
+	return out
+}
+
+// PlanResearch asks the model for up to maxQueries search queries covering question.
+func (c *Client) PlanResearch(ctx context.Context, question string, maxQueries int) ([]string, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(fmt.Sprintf(researchPlanInstruction, maxQueries))},
+		},
+		Temperature:      genai.Ptr(float32(0.3)),
+		ResponseMIMEType: "application/json",
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(question)}},
+	}
+	resp, err := c.generate(ctx, "research", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("plan research: %w", err)
+	}
+	queries, err := parseResearchPlan(extractText(resp))
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) > maxQueries {
+		queries = queries[:maxQueries]
+	}
+	return queries, nil
+}
+
+// parseResearchPlan decodes the planner's JSON, tolerating a Markdown code fence, and drops
+// empty and duplicate queries.
+func parseResearchPlan(text string) ([]string, error) {
+	var out struct {
+		Queries []string `json:"queries"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &out); err != nil {
+		return nil, fmt.Errorf("parse research plan: %w", err)
+	}
+	seen := make(map[string]bool)
+	var queries []string
+	for _, q := range out.Queries {
+		q = strings.TrimSpace(q)
+		if q == "" || seen[strings.ToLower(q)] {
+			continue
+		}
+		seen[strings.ToLower(q)] = true
+		queries = append(queries, q)
+	}
+	return queries, nil
+}
+
+// stripCodeFence removes a Markdown code fence the model sometimes wraps JSON output in.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
+
+// DedupSources returns the findings' sources in order of first appearance, each URI once.
+func DedupSources(findings []ResearchFinding) []Source {
+	seen := make(map[string]bool)
+	var out []Source
+	for _, f := range findings {
+		for _, s := range f.Sources {
+			if seen[s.URI] {
+				continue
+			}
+			seen[s.URI] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// SynthesizeResearch writes the final answer from the findings; sources are numbered as in
+// DedupSources so the caller can list them under the answer. lang fixes the output language.
+func (c *Client) SynthesizeResearch(ctx context.Context, question string, findings []ResearchFinding, lang string) (string, error) {
+	sources := DedupSources(findings)
+	index := make(map[string]int, len(sources))
+	for i, s := range sources {
+		index[s.URI] = i + 1
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Question: %s\n", question)
+	for _, f := range findings {
+		fmt.Fprintf(&b, "\nSearch: %s\n%s\n", f.Query, f.Text)
+		if len(f.Sources) > 0 {
+			b.WriteString("Sources:")
+			for _, s := range f.Sources {
+				fmt.Fprintf(&b, " [%d] %s", index[s.URI], s.Title)
+			}
+			b.WriteString("\n")
+		}
+	}
+	userContent := b.String()
+	if len(userContent) > maxSummaryInputChars {
+		userContent = userContent[:maxSummaryInputChars]
+	}
+	return c.summarize(ctx, fmt.Sprintf(researchSynthesisInstruction, summaryLanguageRule(lang)), userContent)
+}
@@ -0,0 +1,27 @@
+package llm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResearchPlan(t *testing.T) {
+	got, err := parseResearchPlan("```json\n{\"queries\":[\"EU AI Act timeline\",\" \",\"eu ai act timeline\",\"AI Act fines\"]}\n```")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := []string{"EU AI Act timeline", "AI Act fines"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, err := parseResearchPlan("not json"); err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestDedupSources(t *testing.T) {
+	a, b, c := Source{"a.com", "https://a"}, Source{"b.com", "https://b"}, Source{"c.com", "https://c"}
+	got := DedupSources([]ResearchFinding{{Sources: []Source{a, b}}, {Sources: []Source{b, c, a}}})
+	if want := []Source{a, b, c}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
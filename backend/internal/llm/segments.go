@@ -0,0 +1,103 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"google.golang.org/genai"
+)
+
+// TopicLabel is one topic the model found in a run of messages: the index range it covers
+// (into the messages passed to SegmentTopics, inclusive) and how to find it later.
+type TopicLabel struct {
+	First    int
+	Last     int
+	Title    string
+	Summary  string
+	Keywords []string
+}
+
+const (
+	// maxTopicTitleRunes, maxTopicSummaryRunes and maxTopicKeywords bound one label.
+	maxTopicTitleRunes   = 100
+	maxTopicSummaryRunes = 500
+	maxTopicKeywords     = 8
+	// segmentLineRunes bounds each message shown to the model.
+	segmentLineRunes = 300
+)
+
+const segmentInstruction = `You split a group chat log into topics: stretches of consecutive messages about one subject (a trip being planned, a game, a news story, an argument about something). Small talk and greetings belong to the topic around them; don't make a topic of a single message unless it really stands alone.
+Messages are numbered [0], [1], .... For each topic give the numbers of its first and last message (topics in order, not overlapping), a short title and a one or two sentence summary of what was said and decided, both in the chat's language, and up to 8 keywords: nouns and names in their dictionary form (e.g. "відпустка", not "відпустку"), in the chat's language, plus English ones for subjects usually named in English.
+Respond with JSON only: {"topics": [{"first": 0, "last": 12, "title": "<title>", "summary": "<summary>", "keywords": ["<keyword>"]}]}`
+
+// SegmentTopics splits messages (oldest first) into topics. The call is cheap: temperature 0,
+// no thinking, JSON output.
+func (c *Client) SegmentTopics(ctx context.Context, messages []db.Message) ([]TopicLabel, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(segmentInstruction)},
+		},
+		Temperature:      genai.Ptr(float32(0)),
+		ResponseMIMEType: "application/json",
+		ThinkingConfig:   &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(renderSegmentLog(messages))}},
+	}
+	resp, err := c.generate(ctx, "segment_topics", contents, config)
+	if err != nil {
+		return nil, fmt.Errorf("segment topics: %w", err)
+	}
+	return parseTopicLabels(extractText(resp), len(messages))
+}
+
+// renderSegmentLog numbers messages for SegmentTopics: "[i] Name: text".
+func renderSegmentLog(messages []db.Message) string {
+	var b strings.Builder
+	for i := range messages {
+		fmt.Fprintf(&b, "[%d] %s: %s\n", i, displayName(&messages[i]), snippet(messageText(&messages[i]), segmentLineRunes))
+	}
+	return b.String()
+}
+
+// parseTopicLabels decodes the model's JSON, tolerating a Markdown code fence. Topics outside
+// 0..n-1, without a title, or overlapping an earlier one are skipped.
+func parseTopicLabels(text string, n int) ([]TopicLabel, error) {
+	var out struct {
+		Topics []struct {
+			First    int      `json:"first"`
+			Last     int      `json:"last"`
+			Title    string   `json:"title"`
+			Summary  string   `json:"summary"`
+			Keywords []string `json:"keywords"`
+		} `json:"topics"`
+	}
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &out); err != nil {
+		return nil, fmt.Errorf("parse topics: %w", err)
+	}
+	var labels []TopicLabel
+	next := 0 // first message index not covered yet
+	for _, t := range out.Topics {
+		title := strings.TrimSpace(t.Title)
+		if title == "" || t.First < next || t.Last < t.First || t.Last >= n {
+			continue
+		}
+		l := TopicLabel{
+			First:   t.First,
+			Last:    t.Last,
+			Title:   snippet(title, maxTopicTitleRunes),
+			Summary: snippet(strings.TrimSpace(t.Summary), maxTopicSummaryRunes),
+		}
+		for _, k := range t.Keywords {
+			if k = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(k, ",", " "))); k != "" && len(l.Keywords) < maxTopicKeywords {
+				l.Keywords = append(l.Keywords, k)
+			}
+		}
+		labels = append(labels, l)
+		next = t.Last + 1
+	}
+	return labels, nil
+}
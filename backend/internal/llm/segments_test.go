@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestParseTopicLabels(t *testing.T) {
+	text := "```json\n" + `{"topics": [
+		{"first": 0, "last": 4, "title": "Відпустка в Карпатах", "summary": "Обрали дати.", "keywords": ["Відпустка", " карпати ", "a,b"]},
+		{"first": 3, "last": 6, "title": "Overlaps the first"},
+		{"first": 5, "last": 6, "title": " "},
+		{"first": 7, "last": 9, "title": "Футбол"},
+		{"first": 10, "last": 12, "title": "Past the end"}
+	]}` + "\n```"
+	labels, err := parseTopicLabels(text, 11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 2 {
+		t.Fatalf("expected 2 labels, got %+v", labels)
+	}
+	if l := labels[0]; l.First != 0 || l.Last != 4 || l.Title != "Відпустка в Карпатах" || !slices.Equal(l.Keywords, []string{"відпустка", "карпати", "a b"}) {
+		t.Errorf("unexpected first label: %+v", l)
+	}
+	if l := labels[1]; l.First != 7 || l.Last != 9 || l.Title != "Футбол" {
+		t.Errorf("unexpected second label: %+v", l)
+	}
+	if _, err := parseTopicLabels("not json", 3); err == nil {
+		t.Error("expected an error for invalid JSON")
+	}
+}
+
+func TestRenderSegmentLog(t *testing.T) {
+	name, text := "Олена", "їдемо   в\nКарпати?"
+	log := renderSegmentLog([]db.Message{{FirstName: &name, Text: &text}, {IsBotReply: true, Text: &text}})
+	if !strings.Contains(log, "[0] Олена: їдемо в Карпати?\n") || !strings.Contains(log, "[1] Bot: ") {
+		t.Errorf("unexpected log:\n%s", log)
+	}
+}
@@ -0,0 +1,186 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+// SummaryOptions are a chat's summary preferences.
+type SummaryOptions struct {
+	Language  string // forced output language (see SummaryLanguage); empty = follow the chat
+	Anonymize bool   // refer to people as "Member N" instead of by name
+}
+
+// rules returns the prompt sentences for the options.
+func (o SummaryOptions) rules() string {
+	r := summaryLanguageRule(o.Language)
+	if o.Anonymize {
+		r += " Participants are anonymized as Member 1, Member 2, ...; keep these labels and never guess real names."
+	}
+	return r
+}
+
+// pseudonyms maps chat members to stable "Member N" labels in order of first appearance.
+type pseudonyms struct {
+	labels   map[string]string
+	replacer *strings.Replacer
+}
+
+// minReplacedNameLen keeps very short first names from being replaced inside unrelated words.
+const minReplacedNameLen = 3
+
+func newPseudonyms(messages []db.Message) *pseudonyms {
+	p := &pseudonyms{labels: make(map[string]string)}
+	var pairs []string
+	for i := range messages {
+		m := &messages[i]
+		if m.IsBotReply {
+			continue
+		}
+		key := memberKey(m)
+		if _, ok := p.labels[key]; ok {
+			continue
+		}
+		label := fmt.Sprintf("Member %d", len(p.labels)+1)
+		p.labels[key] = label
+		if m.Username != nil && *m.Username != "" {
+			pairs = append(pairs, "@"+*m.Username, "@"+strings.ReplaceAll(label, " ", ""))
+		}
+		if m.FirstName != nil && len([]rune(*m.FirstName)) >= minReplacedNameLen {
+			pairs = append(pairs, *m.FirstName, label)
+		}
+	}
+	p.replacer = strings.NewReplacer(pairs...)
+	return p
+}
+
+// memberKey identifies a sender: user ID when known, else the display name.
+func memberKey(m *db.Message) string {
+	if m.UserID != nil {
+		return fmt.Sprint(*m.UserID)
+	}
+	if m.FirstName != nil {
+		return "name:" + *m.FirstName
+	}
+	return "unknown"
+}
+
+// name returns the label for a message's sender; the bot keeps its own role.
+func (p *pseudonyms) name(m *db.Message) string {
+	if m.IsBotReply {
+		return "Bot"
+	}
+	return p.labels[memberKey(m)]
+}
+
+const updateSummaryInstruction = "You maintain a rolling summary of a chat. You get the previous summary and the messages sent since it was written. Write the updated summary concisely and factually: keep what still matters, add new topics, decisions and context, and drop anything that happened before the start of the window. %s Output only the summary, no preamble."
+
+const combineSummariesInstruction = "You are a summarization assistant. You get consecutive summaries of shorter periods of one chat (oldest first; neighbouring periods may overlap, so do not repeat the same event twice) and the latest raw messages. Combine them into one concise, factual summary of the whole window. Preserve key topics, decisions, and context. %s Output only the summary, no preamble."
+
+// SummaryLanguage picks the language a chat summary is written in: the chat's override if set,
+// else the dominant language of messages, else fallback (the chat's reply language). Summaries go
+// into the chat's own prompt context, so an English summary of a Ukrainian chat would skew replies.
+func SummaryLanguage(override, fallback string, messages []db.Message) string {
+	if override != "" {
+		return override
+	}
+	texts := make([]string, 0, len(messages))
+	for _, m := range messages {
+		if m.Text != nil && !m.IsBotReply {
+			texts = append(texts, *m.Text)
+		}
+	}
+	if lang, ok := i18n.DominantLanguage(texts); ok {
+		return lang
+	}
+	return fallback
+}
+
+// summaryLanguageRule is the prompt sentence fixing the output language.
+func summaryLanguageRule(lang string) string {
+	if lang == "" {
+		return "Use the same language as the chat."
+	}
+	return "Write the summary in " + i18n.LanguageName(lang) + ", whatever language the messages are in."
+}
+
+// UpdateSummary rolls a previous summary forward with the messages sent since it was written,
+// so a periodic summary does not re-read the whole window. windowStart is where the updated
+// summary's window begins; older events are dropped.
+func (c *Client) UpdateSummary(ctx context.Context, previous string, messages []db.Message, windowLabel string, windowStart time.Time, opts SummaryOptions) (string, error) {
+	if len(messages) == 0 {
+		return previous, nil
+	}
+	userContent := fmt.Sprintf("Window: %s, starting %s.\n\nPrevious summary:\n%s\n\nNew messages:\n%s",
+		windowLabel, windowStart.Format("2006-01-02"), previous, formatChatLog(messages, opts.Anonymize))
+	return c.summarize(ctx, fmt.Sprintf(updateSummaryInstruction, opts.rules()), userContent)
+}
+
+// CombineSummaries builds a long-window summary from stored shorter-period summaries plus the raw
+// messages sent after the newest of them.
+func (c *Client) CombineSummaries(ctx context.Context, parts []db.ChatSummary, tail []db.Message, windowLabel string, opts SummaryOptions) (string, error) {
+	if len(parts) == 0 && len(tail) == 0 {
+		return "", nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Combine into one %s summary.\n\n", windowLabel)
+	for _, p := range parts {
+		fmt.Fprintf(&b, "Summary of %s – %s:\n%s\n\n", p.PeriodStart.Format("2006-01-02"), p.PeriodEnd.Format("2006-01-02"), p.SummaryText)
+	}
+	if len(tail) > 0 {
+		b.WriteString("Latest messages:\n")
+		b.WriteString(formatChatLog(tail, opts.Anonymize))
+	}
+	return c.summarize(ctx, fmt.Sprintf(combineSummariesInstruction, opts.rules()), b.String())
+}
+
+const personalDigestInstruction = "You write a short weekly personal digest for one chat member, %s. You get, per group chat, the messages from the last week that mention them or reply to their messages. Tell them what they missed that concerns them: who asked them something or is waiting for an answer, what was said about them, and how conversations they started went on. Keep each chat to a few sentences, headed with the chat's label; keep message links that matter. Skip chats with nothing of substance. %s Output only the digest, no preamble."
+
+// SummarizeForUser writes a personal digest from messages concerning one user (grouped by chat,
+// as returned by db.GetUserMentions). lang fixes the output language.
+func (c *Client) SummarizeForUser(ctx context.Context, messages []db.Message, name, lang string) (string, error) {
+	if len(messages) == 0 {
+		return "", nil
+	}
+	userContent := formatMentionLog(messages)
+	if len(userContent) > maxSummaryInputChars {
+		userContent = userContent[len(userContent)-maxSummaryInputChars:]
+	}
+	return c.summarize(ctx, fmt.Sprintf(personalDigestInstruction, name, summaryLanguageRule(lang)), userContent)
+}
+
+// formatMentionLog renders messages grouped by chat ("Chat 1", "Chat 2", ... in order of
+// appearance), each line with its message link when one can be built.
+func formatMentionLog(messages []db.Message) string {
+	var b strings.Builder
+	chats := 0
+	var current int64
+	for i := range messages {
+		m := &messages[i]
+		if i == 0 || m.ChatID != current {
+			current = m.ChatID
+			chats++
+			if i > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "Chat %d:\n", chats)
+		}
+		name := "Unknown"
+		if m.IsBotReply {
+			name = "Bot"
+		} else if m.FirstName != nil {
+			name = *m.FirstName
+		}
+		line := fmt.Sprintf("%s: %s", name, messageText(m))
+		if link := db.ComposeTopicMessageLink(m.ChatID, m.ThreadID, m.MessageID); link != "" {
+			line += " (" + link + ")"
+		}
+		b.WriteString(line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestSummaryLanguage(t *testing.T) {
+	msg := func(text string, bot bool) db.Message { return db.Message{Text: &text, IsBotReply: bot} }
+	uk := "Привіт, як справи? Що нового в чаті?"
+	en := "Sure, here is the summary you asked for."
+	chat := []db.Message{msg(uk, false), msg(uk, false), msg(en, true), msg(en, true), msg(uk, false), msg(uk, false), msg(uk, false)}
+
+	if got := SummaryLanguage("", "en", chat); got != "uk" {
+		t.Errorf("expected detected uk, got %q", got)
+	}
+	if got := SummaryLanguage("pl", "en", chat); got != "pl" {
+		t.Errorf("expected override pl, got %q", got)
+	}
+	if got := SummaryLanguage("", "en", chat[:2]); got != "en" {
+		t.Errorf("expected fallback en for too few messages, got %q", got)
+	}
+}
+
+func TestSummaryLanguageRule(t *testing.T) {
+	if got := summaryLanguageRule("uk"); !strings.Contains(got, "Ukrainian") {
+		t.Errorf("expected forced Ukrainian, got %q", got)
+	}
+	if got := summaryLanguageRule(""); strings.Contains(got, "English") {
+		t.Errorf("unforced rule must not offer English, got %q", got)
+	}
+}
+
+func TestFormatChatLog_Anonymize(t *testing.T) {
+	str := func(s string) *string { return &s }
+	id := func(n int64) *int64 { return &n }
+	messages := []db.Message{
+		{UserID: id(1), FirstName: str("Olena"), Username: str("olena_k"), Text: str("Hi all")},
+		{UserID: id(2), FirstName: str("Taras"), Text: str("Olena, ask @olena_k about it")},
+		{FirstName: str("Gryag"), IsBotReply: true, Text: str("Taras is right")},
+		{UserID: id(1), FirstName: str("Olena"), Text: str("ok")},
+	}
+	got := formatChatLog(messages, true)
+	for _, leaked := range []string{"Olena", "olena_k", "Taras"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("anonymized log leaks %q:\n%s", leaked, got)
+		}
+	}
+	for _, want := range []string{"Member 1: Hi all", "Member 2: Member 1, ask @Member1 about it", "[BOT] Bot: Member 2 is right", "Member 1: ok"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("anonymized log missing %q:\n%s", want, got)
+		}
+	}
+	if plain := formatChatLog(messages, false); !strings.Contains(plain, "Olena (@olena_k): Hi all") {
+		t.Errorf("plain log should keep names:\n%s", plain)
+	}
+}
+
+func TestFormatMentionLog(t *testing.T) {
+	name, text1, text2, text3 := "Olena", "@taras ти де?", "згоден з тобою", "готово"
+	id1, id2 := int64(42), int64(7)
+	messages := []db.Message{
+		{ChatID: -1001234567890, FirstName: &name, Text: &text1, MessageID: &id1},
+		{ChatID: -1001234567890, IsBotReply: true, Text: &text2},
+		{ChatID: -555, FirstName: &name, Text: &text3, MessageID: &id2},
+	}
+	want := "Chat 1:\nOlena: @taras ти де? (https://t.me/c/1234567890/42)\nBot: згоден з тобою\n\nChat 2:\nOlena: готово\n"
+	if got := formatMentionLog(messages); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
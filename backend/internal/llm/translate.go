@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"google.golang.org/genai"
+)
+
+const translateInstruction = `You are a translation engine, not a chat participant. Translate the text between <text> tags into the target language. Keep the meaning, tone, register, profanity, line breaks, emoji, links, @mentions and Markdown exactly; do not soften, explain, summarize or add anything. Translate names and slang listed in the glossary exactly as given there. Leave code, commands and URLs untranslated. Output only the translation.`
+
+// Translate translates text into target (a language code or name) at temperature 0, without the
+// persona. source may be empty to let the model detect it. Glossary entries whose term occurs in
+// the text are passed as fixed renderings; entries for target win over language-independent ones.
+func (c *Client) Translate(ctx context.Context, text, source, target string, glossary []db.GlossaryTerm) (string, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(translateInstruction)},
+		},
+		Temperature:    genai.Ptr(float32(0)),
+		ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr(int32(0))},
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromText(translationPrompt(text, source, target, glossary))}},
+	}
+	resp, err := c.generate(ctx, "translate", contents, config)
+	if err != nil {
+		return "", fmt.Errorf("translate: %w", err)
+	}
+	return strings.TrimSpace(extractText(resp)), nil
+}
+
+// translationPrompt renders the user turn of a translation request.
+func translationPrompt(text, source, target string, glossary []db.GlossaryTerm) string {
+	var b strings.Builder
+	if source == "" {
+		source = "detect it"
+	}
+	fmt.Fprintf(&b, "Source language: %s\nTarget language: %s\n", source, target)
+	if terms := GlossaryFor(text, target, glossary); len(terms) > 0 {
+		b.WriteString("Glossary (term => translation):\n")
+		for _, g := range terms {
+			fmt.Fprintf(&b, "- %s => %s\n", g.Term, g.Translation)
+		}
+	}
+	fmt.Fprintf(&b, "<text>\n%s\n</text>", text)
+	return b.String()
+}
+
+// GlossaryFor keeps the glossary entries whose term occurs in text (case-insensitive), one per
+// term: the entry for target when there is one, else the language-independent one.
+func GlossaryFor(text, target string, glossary []db.GlossaryTerm) []db.GlossaryTerm {
+	lower := strings.ToLower(text)
+	index := map[string]int{}
+	var out []db.GlossaryTerm
+	for _, g := range glossary {
+		key := strings.ToLower(g.Term)
+		if key == "" || !strings.Contains(lower, key) || (g.Lang != "" && !strings.EqualFold(g.Lang, target)) {
+			continue
+		}
+		if i, ok := index[key]; ok {
+			if g.Lang != "" {
+				out[i] = g
+			}
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, g)
+	}
+	return out
+}
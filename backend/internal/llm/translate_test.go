@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestGlossaryFor(t *testing.T) {
+	glossary := []db.GlossaryTerm{
+		{Term: "Гряг", Translation: "Gryag"},
+		{Term: "гряг", Translation: "Gryażek", Lang: "pl"},
+		{Term: "шо", Translation: "what"},
+		{Term: "бусік", Translation: "minibus", Lang: "en"},
+	}
+	got := GlossaryFor("ГРЯГ, шо там з бусіком?", "pl", glossary)
+	if len(got) != 2 {
+		t.Fatalf("got %d terms, want 2: %+v", len(got), got)
+	}
+	if got[0].Translation != "Gryażek" {
+		t.Errorf("language-specific entry should win, got %q", got[0].Translation)
+	}
+	if got[1].Term != "шо" {
+		t.Errorf("second term = %q, want шо", got[1].Term)
+	}
+	got = GlossaryFor("бусік", "EN", glossary)
+	if len(got) != 1 || got[0].Translation != "minibus" {
+		t.Errorf("target match should be case-insensitive, got %+v", got)
+	}
+	if got := GlossaryFor("нічого спільного", "en", glossary); len(got) != 0 {
+		t.Errorf("expected no terms, got %+v", got)
+	}
+}
+
+func TestTranslationPrompt(t *testing.T) {
+	p := translationPrompt("Гряг спить", "", "en", []db.GlossaryTerm{{Term: "Гряг", Translation: "Gryag"}, {Term: "кіт", Translation: "cat"}})
+	for _, want := range []string{"Source language: detect it", "Target language: en", "- Гряг => Gryag", "<text>\nГряг спить\n</text>"} {
+		if !strings.Contains(p, want) {
+			t.Errorf("prompt missing %q:\n%s", want, p)
+		}
+	}
+	if strings.Contains(p, "кіт") {
+		t.Error("terms absent from the text should not be sent")
+	}
+	if p := translationPrompt("hi", "en", "uk", nil); strings.Contains(p, "Glossary") {
+		t.Error("no glossary block expected without terms")
+	}
+}
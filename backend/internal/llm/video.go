@@ -0,0 +1,35 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const watchVideoInstruction = `You watch a video shared in a group chat for someone who doesn't want to watch it. Give the gist first in one or two sentences, then the main points in order with their timestamps (MM:SS). If a question is given, answer it from the video instead, citing timestamps. Say plainly when the video doesn't cover something; never invent content. Write in the requested language, without greetings or filler.`
+
+// WatchVideo summarizes a YouTube video (or answers question about it) in lang, using Gemini's
+// video understanding at low media resolution. Runs without the persona.
+func (c *Client) WatchVideo(ctx context.Context, videoURL, question, lang string) (string, error) {
+	config := &genai.GenerateContentConfig{
+		SystemInstruction: &genai.Content{
+			Parts: []*genai.Part{genai.NewPartFromText(watchVideoInstruction)},
+		},
+		Temperature:     genai.Ptr(float32(0.2)),
+		MediaResolution: genai.MediaResolutionLow,
+	}
+	prompt := "Language: " + lang
+	if question != "" {
+		prompt += "\nQuestion: " + question
+	}
+	contents := []*genai.Content{
+		{Role: "user", Parts: []*genai.Part{genai.NewPartFromURI(videoURL, "video/*"), genai.NewPartFromText(prompt)}},
+	}
+	resp, err := c.generate(ctx, "watch_video", contents, config)
+	if err != nil {
+		return "", fmt.Errorf("watch video: %w", err)
+	}
+	return strings.TrimSpace(extractText(resp)), nil
+}
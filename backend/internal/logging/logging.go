@@ -0,0 +1,146 @@
+// Package logging scopes structured log attributes to a context.
+//
+// Request entry points attach request_id, chat_id, user_id (and the executor attaches tool)
+// once; every slog call made with that context (slog.InfoContext, logger.WarnContext, ...)
+// then carries them, so modules no longer repeat ad-hoc attrs.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// Attribute keys attached by the helpers below.
+const (
+	KeyRequestID = "request_id"
+	KeyChatID    = "chat_id"
+	KeyUserID    = "user_id"
+	KeyTool      = "tool"
+)
+
+type attrsKeyType struct{}
+
+var attrsKey = attrsKeyType{}
+
+// With returns a copy of ctx whose log lines carry the given attributes (slog key/value pairs).
+// A key set again later replaces the earlier value.
+func With(ctx context.Context, args ...any) context.Context {
+	if len(args) == 0 {
+		return ctx
+	}
+	prev := Attrs(ctx)
+	add := argsToAttrs(args)
+	merged := make([]slog.Attr, 0, len(prev)+len(add))
+	for _, a := range prev {
+		if !hasKey(add, a.Key) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, add...)
+	return context.WithValue(ctx, attrsKey, merged)
+}
+
+// WithRequestID scopes the frontend's X-Request-ID; empty ids are not attached.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return With(ctx, KeyRequestID, requestID)
+}
+
+// WithChat scopes the chat and (when non-zero) the user the request is about.
+func WithChat(ctx context.Context, chatID, userID int64) context.Context {
+	if userID == 0 {
+		return With(ctx, KeyChatID, chatID)
+	}
+	return With(ctx, KeyChatID, chatID, KeyUserID, userID)
+}
+
+// WithTool scopes the tool being executed.
+func WithTool(ctx context.Context, name string) context.Context {
+	return With(ctx, KeyTool, name)
+}
+
+// Attrs returns the attributes scoped to ctx.
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey).([]slog.Attr)
+	return attrs
+}
+
+// Value returns the value scoped to ctx under key, e.g. KeyChatID for code that records the request's chat.
+func Value(ctx context.Context, key string) (slog.Value, bool) {
+	for _, a := range Attrs(ctx) {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	// slog.Record does the key/value pairing (including !BADKEY handling) for us.
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+func hasKey(attrs []slog.Attr, key string) bool {
+	for _, a := range attrs {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler wraps another slog.Handler and adds the context's scoped attributes to each record.
+// Attributes already on the record win, so an explicit chat_id is never duplicated.
+type Handler struct {
+	inner slog.Handler
+}
+
+// NewHandler wraps inner; install it as the default handler in main.
+func NewHandler(inner slog.Handler) *Handler {
+	return &Handler{inner: inner}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if scoped := Attrs(ctx); len(scoped) > 0 {
+		var own []string
+		r.Attrs(func(a slog.Attr) bool {
+			own = append(own, a.Key)
+			return true
+		})
+		for _, a := range scoped {
+			if !slices.Contains(own, a.Key) {
+				r.AddAttrs(a)
+			}
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name)}
+}
@@ -0,0 +1,86 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func newTestLogger(buf *bytes.Buffer) *slog.Logger {
+	return slog.New(NewHandler(slog.NewJSONHandler(buf, nil)))
+}
+
+func decodeLine(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var m map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &m); err != nil {
+		t.Fatalf("bad log line %q: %v", buf.String(), err)
+	}
+	buf.Reset()
+	return m
+}
+
+func TestHandler_AddsScopedAttrs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	ctx = WithChat(ctx, -100, 42)
+	ctx = WithTool(ctx, "calculator")
+	logger.InfoContext(ctx, "hello", "extra", 1)
+
+	m := decodeLine(t, &buf)
+	want := map[string]any{"request_id": "req-1", "chat_id": float64(-100), "user_id": float64(42), "tool": "calculator", "extra": float64(1)}
+	for k, v := range want {
+		if m[k] != v {
+			t.Errorf("%s = %v, want %v", k, m[k], v)
+		}
+	}
+}
+
+func TestHandler_RecordAttrsWinAndNoContext(t *testing.T) {
+	var buf bytes.Buffer
+	logger := newTestLogger(&buf)
+
+	ctx := WithChat(context.Background(), 1, 0)
+	logger.InfoContext(ctx, "explicit", "chat_id", 2)
+	if !bytes.Contains(buf.Bytes(), []byte(`"chat_id":2`)) || bytes.Count(buf.Bytes(), []byte(`"chat_id"`)) != 1 {
+		t.Errorf("explicit chat_id should win without duplication: %s", buf.String())
+	}
+	buf.Reset()
+
+	logger.Info("plain")
+	if m := decodeLine(t, &buf); m["chat_id"] != nil || m["user_id"] != nil {
+		t.Errorf("no scoped attrs expected without context: %v", m)
+	}
+}
+
+func TestWith_ReplacesKeys(t *testing.T) {
+	ctx := WithChat(context.Background(), 1, 5)
+	ctx = WithChat(ctx, 2, 0)
+	ctx = WithRequestID(ctx, "")
+
+	attrs := Attrs(ctx)
+	got := map[string]any{}
+	for _, a := range attrs {
+		got[a.Key] = a.Value.Any()
+	}
+	if len(attrs) != 2 || got["chat_id"] != int64(2) || got["user_id"] != int64(5) {
+		t.Errorf("attrs = %v", attrs)
+	}
+}
+
+func TestValue(t *testing.T) {
+	ctx := WithRequestID(WithChat(context.Background(), -100, 0), "req-1")
+	if v, ok := Value(ctx, KeyChatID); !ok || v.Int64() != -100 {
+		t.Errorf("chat_id = %v, %v", v, ok)
+	}
+	if v, ok := Value(ctx, KeyRequestID); !ok || v.String() != "req-1" {
+		t.Errorf("request_id = %v, %v", v, ok)
+	}
+	if _, ok := Value(ctx, KeyUserID); ok {
+		t.Error("user_id should not be set")
+	}
+}
@@ -0,0 +1,31 @@
+package logging
+
+import "net/http"
+
+// HeaderRequestID carries the request ID between the frontend, the backend and upstream APIs.
+const HeaderRequestID = "X-Request-ID"
+
+// Transport forwards the request ID scoped to an outgoing request's context as X-Request-ID, so
+// calls to upstream APIs (Gemini) can be matched with our log lines.
+type Transport struct {
+	Base http.RoundTripper // nil = http.DefaultTransport
+}
+
+// NewHTTPClient returns an http.Client that forwards request IDs; pass it to API clients.
+func NewHTTPClient() *http.Client {
+	return &http.Client{Transport: &Transport{}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if v, ok := Value(req.Context(), KeyRequestID); ok && req.Header.Get(HeaderRequestID) == "" {
+		// A RoundTripper must not modify the caller's request
+		req = req.Clone(req.Context())
+		req.Header.Set(HeaderRequestID, v.String())
+	}
+	return base.RoundTrip(req)
+}
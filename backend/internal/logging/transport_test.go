@@ -0,0 +1,32 @@
+package logging
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTransport(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(HeaderRequestID))
+	}))
+	defer srv.Close()
+	client := NewHTTPClient()
+
+	for _, ctx := range []context.Context{WithRequestID(context.Background(), "req-1"), context.Background()} {
+		req, _ := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if req.Header.Get(HeaderRequestID) != "" {
+			t.Error("the caller's request was modified")
+		}
+	}
+	if len(got) != 2 || got[0] != "req-1" || got[1] != "" {
+		t.Errorf("upstream saw request ids %q, want [req-1 \"\"]", got)
+	}
+}
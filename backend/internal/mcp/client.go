@@ -0,0 +1,188 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// protocolVersion is the MCP revision the client speaks.
+const protocolVersion = "2025-06-18"
+
+// message is a JSON-RPC 2.0 request, notification or response.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// transport carries messages to one server.
+type transport interface {
+	// roundTrip sends a request and returns the response with the same id.
+	roundTrip(ctx context.Context, req *message) (*message, error)
+	// notify sends a notification (no response).
+	notify(ctx context.Context, msg *message) error
+	close() error
+}
+
+// ToolError is a tool call the server answered with isError: the tool ran and reported a
+// problem meant for the model.
+type ToolError struct {
+	Text string
+}
+
+func (e *ToolError) Error() string {
+	return "mcp tool error: " + e.Text
+}
+
+// Tool is a tool as listed by tools/list.
+type Tool struct {
+	Name        string         `json:"name"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// client speaks MCP to one server.
+type client struct {
+	server string
+	t      transport
+	nextID atomic.Int64
+}
+
+func newClient(server string, t transport) *client {
+	return &client{server: server, t: t}
+}
+
+// call sends a request and decodes its result into out (when non-nil).
+func (c *client) call(ctx context.Context, method string, params, out any) error {
+	req := &message{JSONRPC: "2.0", ID: json.RawMessage(fmt.Sprint(c.nextID.Add(1))), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("%s: encode params: %w", method, err)
+		}
+		req.Params = data
+	}
+	resp, err := c.t.roundTrip(ctx, req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%s: %w", method, resp.Error)
+	}
+	if out != nil {
+		if err := json.Unmarshal(resp.Result, out); err != nil {
+			return fmt.Errorf("%s: decode result: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// initialize runs the MCP handshake.
+func (c *client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{},
+		"clientInfo":      map[string]string{"name": "gryag", "version": "1"},
+	}
+	var result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		Capabilities    struct {
+			Tools *struct{} `json:"tools"`
+		} `json:"capabilities"`
+	}
+	if err := c.call(ctx, "initialize", params, &result); err != nil {
+		return err
+	}
+	if result.Capabilities.Tools == nil {
+		return errors.New("server offers no tools")
+	}
+	if h, ok := c.t.(*httpTransport); ok {
+		h.setProtocol(result.ProtocolVersion)
+	}
+	return c.t.notify(ctx, &message{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// listTools returns every tool of the server, following pagination.
+func (c *client) listTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for page := 0; page < 100; page++ {
+		var params any
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var result struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := c.call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if cursor = result.NextCursor; cursor == "" {
+			break
+		}
+	}
+	return tools, nil
+}
+
+// callTool runs a tool and returns its text content. A result with isError is a *ToolError.
+func (c *client) callTool(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	if len(args) == 0 || string(args) == "null" {
+		args = json.RawMessage("{}")
+	}
+	var result struct {
+		Content []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			MimeType string `json:"mimeType"`
+			Resource *struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"resource"`
+		} `json:"content"`
+		StructuredContent json.RawMessage `json:"structuredContent"`
+		IsError           bool            `json:"isError"`
+	}
+	params := map[string]any{"name": name, "arguments": args}
+	if err := c.call(ctx, "tools/call", params, &result); err != nil {
+		return "", err
+	}
+
+	var parts []string
+	for _, item := range result.Content {
+		switch {
+		case item.Type == "text":
+			parts = append(parts, item.Text)
+		case item.Resource != nil && item.Resource.Text != "":
+			parts = append(parts, item.Resource.Text)
+		default:
+			parts = append(parts, fmt.Sprintf("[%s %s not shown]", item.Type, item.MimeType))
+		}
+	}
+	text := strings.Join(parts, "\n")
+	if text == "" && len(result.StructuredContent) > 0 {
+		text = string(result.StructuredContent)
+	}
+	if result.IsError {
+		return "", &ToolError{Text: text}
+	}
+	return text, nil
+}
@@ -0,0 +1,74 @@
+// Package mcp is a client for Model Context Protocol servers. Servers listed in MCP_CONFIG_FILE
+// are connected at startup; their tools are offered to Gemini next to the built-in ones and
+// calls are proxied to the server that owns them.
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// ServerConfig is one MCP server: a command speaking MCP over stdio, or the URL of a Streamable
+// HTTP endpoint.
+type ServerConfig struct {
+	Name    string            `json:"-"`
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"`     // added to the backend's environment
+	URL     string            `json:"url"`     // Streamable HTTP endpoint
+	Headers map[string]string `json:"headers"` // e.g. Authorization
+}
+
+// configFile is the usual MCP client format: {"mcpServers": {"name": {...}}}.
+type configFile struct {
+	Servers map[string]*ServerConfig `json:"mcpServers"`
+}
+
+var serverNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// LoadConfig reads the servers from an MCP config file, in name order. ${VAR} references in
+// env and header values are expanded from the environment, so secrets stay out of the file.
+func LoadConfig(path string) ([]ServerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read mcp config: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f configFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode mcp config: %w", err)
+	}
+
+	names := make([]string, 0, len(f.Servers))
+	for name := range f.Servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	out := make([]ServerConfig, 0, len(names))
+	for _, name := range names {
+		s := f.Servers[name]
+		if s == nil {
+			return nil, fmt.Errorf("mcp server %s: empty entry", name)
+		}
+		if !serverNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("mcp server %q: names are 1-32 letters, digits, _ or -", name)
+		}
+		if (s.Command == "") == (s.URL == "") {
+			return nil, fmt.Errorf("mcp server %s: set either command or url", name)
+		}
+		s.Name = name
+		for k, v := range s.Env {
+			s.Env[k] = os.ExpandEnv(v)
+		}
+		for k, v := range s.Headers {
+			s.Headers[k] = os.ExpandEnv(v)
+		}
+		out = append(out, *s)
+	}
+	return out, nil
+}
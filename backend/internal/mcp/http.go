@@ -0,0 +1,167 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+)
+
+// maxHTTPResponse bounds one response body from an HTTP server.
+const maxHTTPResponse = 16 << 20
+
+// httpTransport speaks Streamable HTTP: every message is POSTed to the endpoint, and the
+// response comes back as JSON or as a server-sent event stream. Requests go through the egress
+// policy; internal servers need their host in EGRESS_INTERNAL_HOSTS.
+type httpTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu       sync.Mutex
+	session  string // Mcp-Session-Id from the server, sent back on every request
+	protocol string // negotiated protocol version, sent after initialize
+}
+
+func newHTTPTransport(s ServerConfig, policy *egress.Policy) (*httpTransport, error) {
+	if _, err := policy.CheckURL(s.URL); err != nil {
+		return nil, err
+	}
+	// No overall timeout: responses may stream for as long as the call's context allows
+	client := &http.Client{Transport: policy.Transport(), CheckRedirect: policy.CheckRedirect}
+	return &httpTransport{url: s.URL, headers: s.Headers, client: client}, nil
+}
+
+func (t *httpTransport) setProtocol(v string) {
+	t.mu.Lock()
+	t.protocol = v
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) post(ctx context.Context, msg *message) (*http.Response, error) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.session != "" {
+		req.Header.Set("Mcp-Session-Id", t.session)
+	}
+	if t.protocol != "" {
+		req.Header.Set("MCP-Protocol-Version", t.protocol)
+	}
+	t.mu.Unlock()
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.session = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("http %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, req *message) (*message, error) {
+	resp, err := t.post(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body := io.LimitReader(resp.Body, maxHTTPResponse)
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/event-stream" {
+		var msg message
+		if err := json.NewDecoder(body).Decode(&msg); err != nil {
+			return nil, fmt.Errorf("decode response: %w", err)
+		}
+		return &msg, nil
+	}
+	return readEvents(body, req.ID)
+}
+
+// readEvents reads a server-sent event stream until the response with the given id arrives.
+// Other messages on the stream (progress, logging) are skipped.
+func readEvents(r io.Reader, id json.RawMessage) (*message, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxHTTPResponse)
+	var data strings.Builder
+	for scanner.Scan() {
+		line := scanner.Text()
+		if after, ok := strings.CutPrefix(line, "data:"); ok {
+			data.WriteString(strings.TrimPrefix(after, " "))
+			continue
+		}
+		if line != "" || data.Len() == 0 {
+			continue
+		}
+		// a blank line ends the event
+		var msg message
+		err := json.Unmarshal([]byte(data.String()), &msg)
+		data.Reset()
+		if err == nil && msg.Method == "" && bytes.Equal(msg.ID, id) {
+			return &msg, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("event stream ended without a response")
+}
+
+func (t *httpTransport) notify(ctx context.Context, msg *message) error {
+	resp, err := t.post(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// close ends the session; servers that don't support DELETE just ignore it.
+func (t *httpTransport) close() error {
+	t.mu.Lock()
+	session := t.session
+	t.mu.Unlock()
+	if session == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Mcp-Session-Id", session)
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
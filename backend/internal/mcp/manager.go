@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+	"google.golang.org/genai"
+)
+
+// connectTimeout bounds the handshake and tool listing of one server at startup.
+const connectTimeout = 20 * time.Second
+
+// remoteTool is a server tool as offered to the model.
+type remoteTool struct {
+	client *client
+	name   string // the server's own name for it
+	decl   *genai.FunctionDeclaration
+}
+
+// Manager holds the connected servers and routes calls to them.
+type Manager struct {
+	clients []*client
+	tools   map[string]*remoteTool
+	order   []string // offered names, in connection order
+}
+
+// Connect starts (stdio) or opens (HTTP) every configured server and lists its tools. A server
+// that fails is logged and skipped, so one broken integration doesn't keep the bot down. Tool
+// names taken by reserved (built-in) tools or an earlier server are skipped too. HTTP servers are
+// reached through policy.
+func Connect(ctx context.Context, servers []ServerConfig, reserved func(name string) bool, policy *egress.Policy) *Manager {
+	m := &Manager{tools: make(map[string]*remoteTool)}
+	for _, s := range servers {
+		c, tools, err := connect(ctx, s, policy)
+		if err != nil {
+			slog.Error("mcp server unavailable, its tools are skipped", "server", s.Name, "error", err)
+			continue
+		}
+		m.clients = append(m.clients, c)
+		added := 0
+		for _, t := range tools {
+			name := toolName(s.Name, t.Name)
+			if reserved(name) || m.tools[name] != nil {
+				slog.Warn("mcp tool name already taken, skipped", "server", s.Name, "tool", t.Name, "name", name)
+				continue
+			}
+			m.tools[name] = &remoteTool{client: c, name: t.Name, decl: declaration(name, t)}
+			m.order = append(m.order, name)
+			added++
+		}
+		slog.Info("mcp server connected", "server", s.Name, "tools", added)
+	}
+	return m
+}
+
+func connect(ctx context.Context, s ServerConfig, policy *egress.Policy) (*client, []Tool, error) {
+	ctx, cancel := context.WithTimeout(ctx, connectTimeout)
+	defer cancel()
+
+	var t transport
+	if s.URL != "" {
+		ht, err := newHTTPTransport(s, policy)
+		if err != nil {
+			return nil, nil, err
+		}
+		t = ht
+	} else {
+		st, err := startStdio(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		t = st
+	}
+	c := newClient(s.Name, t)
+	if err := c.initialize(ctx); err != nil {
+		t.close()
+		return nil, nil, fmt.Errorf("initialize: %w", err)
+	}
+	tools, err := c.listTools(ctx)
+	if err != nil {
+		t.close()
+		return nil, nil, err
+	}
+	return c, tools, nil
+}
+
+// Declarations returns the function declarations of every offered tool.
+func (m *Manager) Declarations() []*genai.FunctionDeclaration {
+	out := make([]*genai.FunctionDeclaration, 0, len(m.order))
+	for _, name := range m.order {
+		out = append(out, m.tools[name].decl)
+	}
+	return out
+}
+
+// Has reports whether name is an MCP tool.
+func (m *Manager) Has(name string) bool {
+	return m != nil && m.tools[name] != nil
+}
+
+// Call runs an MCP tool with the model's arguments (JSON) and returns its text output.
+func (m *Manager) Call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	t := m.tools[name]
+	if t == nil {
+		return "", fmt.Errorf("unknown mcp tool %s", name)
+	}
+	out, err := t.client.callTool(ctx, t.name, args)
+	if err != nil {
+		return "", fmt.Errorf("mcp %s/%s: %w", t.client.server, t.name, err)
+	}
+	return out, nil
+}
+
+// Close disconnects every server (stdio servers are stopped).
+func (m *Manager) Close() {
+	for _, c := range m.clients {
+		if err := c.t.close(); err != nil {
+			slog.Debug("mcp server close", "server", c.server, "error", err)
+		}
+	}
+}
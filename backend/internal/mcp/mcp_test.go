@@ -0,0 +1,228 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+	"google.golang.org/genai"
+)
+
+// fakeServe answers one request the way a small MCP server with two tools would.
+func fakeServe(req *message) *message {
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	var params struct {
+		Name      string         `json:"name"`
+		Arguments map[string]any `json:"arguments"`
+	}
+	json.Unmarshal(req.Params, &params)
+	switch req.Method {
+	case "initialize":
+		resp.Result = json.RawMessage(`{"protocolVersion":"2025-06-18","capabilities":{"tools":{}},"serverInfo":{"name":"fake"}}`)
+	case "tools/list":
+		resp.Result = json.RawMessage(`{"tools":[
+			{"name":"lights.set","description":"Turn a light on or off","inputSchema":{"type":"object",
+				"properties":{"room":{"type":"string","enum":["kitchen","hall"]},"on":{"type":"boolean"}},"required":["room","on"]}},
+			{"name":"status","description":"House status","inputSchema":{"type":"object"}}]}`)
+	case "tools/call":
+		switch {
+		case params.Name == "lights.set" && params.Arguments["room"] == "attic":
+			resp.Result = json.RawMessage(`{"content":[{"type":"text","text":"no light in the attic"}],"isError":true}`)
+		case params.Name == "lights.set":
+			resp.Result = json.RawMessage(fmt.Sprintf(`{"content":[{"type":"text","text":"%s is %v"}]}`, params.Arguments["room"], params.Arguments["on"]))
+		default:
+			resp.Result = json.RawMessage(`{"content":[],"structuredContent":{"doors":"locked"}}`)
+		}
+	default:
+		resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+	}
+	return resp
+}
+
+// TestHelperServer is not a test: it is the stdio server the tests below start.
+func TestHelperServer(t *testing.T) {
+	if os.Getenv("GRYAG_FAKE_MCP") != "1" {
+		t.Skip("helper process")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var req message
+		if json.Unmarshal(scanner.Bytes(), &req) != nil || len(req.ID) == 0 {
+			continue // notifications
+		}
+		data, _ := json.Marshal(fakeServe(&req))
+		fmt.Println(string(data))
+	}
+	os.Exit(0)
+}
+
+func checkManager(t *testing.T, m *Manager) {
+	t.Helper()
+	decls := m.Declarations()
+	if len(decls) != 1 || decls[0].Name != "home_lights_set" {
+		t.Fatalf("expected home_lights_set (status is taken), got %v", decls)
+	}
+	room := decls[0].Parameters.Properties["room"]
+	if room == nil || room.Type != genai.TypeString || len(room.Enum) != 2 || len(decls[0].Parameters.Required) != 2 {
+		t.Errorf("unexpected converted schema: %+v", decls[0].Parameters)
+	}
+
+	ctx := context.Background()
+	out, err := m.Call(ctx, "home_lights_set", json.RawMessage(`{"room":"kitchen","on":true}`))
+	if err != nil || out != "kitchen is true" {
+		t.Errorf("unexpected call result %q, %v", out, err)
+	}
+	_, err = m.Call(ctx, "home_lights_set", json.RawMessage(`{"room":"attic","on":true}`))
+	var toolErr *ToolError
+	if !errors.As(err, &toolErr) || toolErr.Text != "no light in the attic" {
+		t.Errorf("expected a ToolError, got %v", err)
+	}
+	if m.Has("home_status") || !m.Has("home_lights_set") {
+		t.Error("Has does not match the offered tools")
+	}
+}
+
+func reserveStatus(name string) bool { return name == "home_status" }
+
+func TestConnect_Stdio(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := Connect(context.Background(), []ServerConfig{{
+		Name: "home", Command: exe, Args: []string{"-test.run=^TestHelperServer$"}, Env: map[string]string{"GRYAG_FAKE_MCP": "1"},
+	}}, reserveStatus, nil)
+	defer m.Close()
+	checkManager(t, m)
+}
+
+func TestConnect_HTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req message
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method != "initialize" && r.Header.Get("Mcp-Session-Id") != "s1" {
+			http.Error(w, "no session", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Mcp-Session-Id", "s1")
+		if len(req.ID) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, _ := json.Marshal(fakeServe(&req))
+		if req.Method == "tools/call" { // answer calls as an event stream, after a progress notification
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\"}\n\ndata: %s\n\n", data)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	policy := &egress.Policy{InternalHosts: []string{"127.0.0.1"}}
+	m := Connect(context.Background(), []ServerConfig{{Name: "home", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}}, reserveStatus, policy)
+	defer m.Close()
+	checkManager(t, m)
+
+	broken := Connect(context.Background(), []ServerConfig{{Name: "home", URL: srv.URL}}, reserveStatus, policy)
+	if len(broken.Declarations()) != 0 {
+		t.Error("a server that fails the handshake must be skipped")
+	}
+	blocked := Connect(context.Background(), []ServerConfig{{Name: "home", URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}}, reserveStatus, &egress.Policy{})
+	if len(blocked.Declarations()) != 0 {
+		t.Error("a loopback server not in EGRESS_INTERNAL_HOSTS must be skipped")
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	write := func(body string) string {
+		path := filepath.Join(dir, "mcp.json")
+		os.WriteFile(path, []byte(body), 0644)
+		return path
+	}
+	t.Setenv("HA_TOKEN", "secret")
+
+	servers, err := LoadConfig(write(`{"mcpServers": {
+		"web": {"url": "https://mcp.example.com/mcp", "headers": {"Authorization": "Bearer ${HA_TOKEN}"}},
+		"home": {"command": "ha-mcp", "args": ["--stdio"], "env": {"TOKEN": "${HA_TOKEN}"}}}}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(servers) != 2 || servers[0].Name != "home" || servers[1].Name != "web" {
+		t.Fatalf("expected home and web in name order, got %+v", servers)
+	}
+	if servers[0].Env["TOKEN"] != "secret" || servers[1].Headers["Authorization"] != "Bearer secret" {
+		t.Error("expected ${VAR} expanded in env and headers")
+	}
+
+	for _, bad := range []string{
+		`{"mcpServers": {"x": {}}}`,
+		`{"mcpServers": {"x": {"command": "a", "url": "http://b"}}}`,
+		`{"mcpServers": {"bad name": {"command": "a"}}}`,
+		`{"mcpServers": {"x": {"command": "a", "cwd": "/"}}}`,
+	} {
+		if _, err := LoadConfig(write(bad)); err == nil {
+			t.Errorf("expected %s to be rejected", bad)
+		}
+	}
+}
+
+func TestConvertSchema(t *testing.T) {
+	var node map[string]any
+	json.Unmarshal([]byte(`{"type":"object","$schema":"http://json-schema.org/draft-07/schema#","additionalProperties":false,
+		"properties":{
+			"query":{"type":"string","description":"Search text"},
+			"limit":{"type":["integer","null"],"minimum":1,"maximum":50},
+			"tags":{"type":"array","items":{"type":"string"}},
+			"since":{"anyOf":[{"type":"string","format":"date-time"},{"type":"null"}],"description":"Start"},
+			"filter":{"properties":{"kind":{"enum":["a","b"]}}}
+		},
+		"required":["query","missing"]}`), &node)
+	s := convertSchema(node)
+
+	if s.Type != genai.TypeObject || len(s.Required) != 1 || s.Required[0] != "query" {
+		t.Errorf("unexpected object: %+v", s)
+	}
+	if l := s.Properties["limit"]; l.Type != genai.TypeInteger || l.Nullable == nil || *l.Minimum != 1 || *l.Maximum != 50 {
+		t.Errorf("unexpected limit: %+v", l)
+	}
+	if tags := s.Properties["tags"]; tags.Type != genai.TypeArray || tags.Items.Type != genai.TypeString {
+		t.Errorf("unexpected tags: %+v", tags)
+	}
+	if since := s.Properties["since"]; since.Type != genai.TypeString || since.Nullable == nil || since.Description != "Start" {
+		t.Errorf("expected a nullable string for since, got %+v", since)
+	}
+	if f := s.Properties["filter"]; f.Type != genai.TypeObject || f.Properties["kind"].Type != genai.TypeString || len(f.Properties["kind"].Enum) != 2 {
+		t.Errorf("expected types guessed for filter, got %+v", f)
+	}
+	if strings.Join(s.PropertyOrdering, ",") != "filter,limit,query,since,tags" {
+		t.Errorf("unexpected ordering %v", s.PropertyOrdering)
+	}
+}
+
+func TestToolName(t *testing.T) {
+	for in, want := range map[string]string{
+		"lights.set":            "home_lights_set",
+		"get-state":             "home_get_state",
+		strings.Repeat("x", 80): "home_" + strings.Repeat("x", 59),
+	} {
+		if got := toolName("home", in); got != want {
+			t.Errorf("toolName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package mcp
+
+import (
+	"cmp"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+var schemaTypes = map[string]genai.Type{
+	"string":  genai.TypeString,
+	"number":  genai.TypeNumber,
+	"integer": genai.TypeInteger,
+	"boolean": genai.TypeBoolean,
+	"array":   genai.TypeArray,
+	"object":  genai.TypeObject,
+}
+
+// declaration converts an MCP tool into a Gemini function declaration under the given name.
+func declaration(name string, t Tool) *genai.FunctionDeclaration {
+	desc := t.Description
+	if desc == "" {
+		desc = t.Title
+	}
+	decl := &genai.FunctionDeclaration{Name: name, Description: desc}
+	if params := convertSchema(t.InputSchema); params != nil && len(params.Properties) > 0 {
+		decl.Parameters = params
+	}
+	return decl
+}
+
+// convertSchema maps a JSON Schema node onto genai.Schema. It is lenient: keywords Gemini has no
+// use for ($schema, additionalProperties, default, ...) are dropped, "null" in a type list makes
+// the node nullable, anyOf/oneOf become AnyOf, and a node without a type is guessed from its
+// other keywords (string when nothing fits).
+func convertSchema(node map[string]any) *genai.Schema {
+	if node == nil {
+		return nil
+	}
+	s := &genai.Schema{}
+	s.Description, _ = node["description"].(string)
+	if title, _ := node["title"].(string); s.Description == "" {
+		s.Description = title
+	}
+
+	typ := ""
+	switch v := node["type"].(type) {
+	case string:
+		typ = v
+	case []any:
+		for _, item := range v {
+			if name, _ := item.(string); name == "null" {
+				s.Nullable = genai.Ptr(true)
+			} else if typ == "" {
+				typ = name
+			}
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options, ok := node[key].([]any); ok && typ == "" {
+			for _, o := range options {
+				if m, ok := o.(map[string]any); ok {
+					if t, _ := m["type"].(string); t == "null" {
+						s.Nullable = genai.Ptr(true)
+						continue
+					}
+					s.AnyOf = append(s.AnyOf, convertSchema(m))
+				}
+			}
+			if len(s.AnyOf) == 1 { // {"anyOf": [X, {"type": "null"}]} is a nullable X
+				only := s.AnyOf[0]
+				only.Nullable, only.Description = s.Nullable, cmp.Or(s.Description, only.Description)
+				return only
+			}
+			if len(s.AnyOf) > 0 {
+				return s
+			}
+		}
+	}
+	if typ == "" {
+		switch {
+		case node["properties"] != nil:
+			typ = "object"
+		case node["items"] != nil:
+			typ = "array"
+		default:
+			typ = "string"
+		}
+	}
+	s.Type = schemaTypes[typ]
+	if s.Type == "" {
+		s.Type = genai.TypeString
+	}
+
+	if values, ok := node["enum"].([]any); ok && s.Type == genai.TypeString {
+		for _, v := range values {
+			if str, ok := v.(string); ok {
+				s.Enum = append(s.Enum, str)
+			}
+		}
+	}
+	if f, ok := node["minimum"].(float64); ok {
+		s.Minimum = &f
+	}
+	if f, ok := node["maximum"].(float64); ok {
+		s.Maximum = &f
+	}
+
+	switch s.Type {
+	case genai.TypeArray:
+		items, _ := node["items"].(map[string]any)
+		if s.Items = convertSchema(items); s.Items == nil {
+			s.Items = &genai.Schema{Type: genai.TypeString}
+		}
+	case genai.TypeObject:
+		props, _ := node["properties"].(map[string]any)
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if p, ok := props[name].(map[string]any); ok {
+				if s.Properties == nil {
+					s.Properties = make(map[string]*genai.Schema, len(props))
+				}
+				s.Properties[name] = convertSchema(p)
+				s.PropertyOrdering = append(s.PropertyOrdering, name)
+			}
+		}
+		if required, ok := node["required"].([]any); ok {
+			for _, r := range required {
+				if name, _ := r.(string); s.Properties[name] != nil && !slices.Contains(s.Required, name) {
+					s.Required = append(s.Required, name)
+				}
+			}
+		}
+	}
+	return s
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// maxToolName is Gemini's limit on function names.
+const maxToolName = 64
+
+// toolName is the name a server's tool is offered under: "<server>_<tool>", with characters
+// Gemini does not accept replaced by "_".
+func toolName(server, tool string) string {
+	name := invalidNameChars.ReplaceAllString(server+"_"+tool, "_")
+	if len(name) > maxToolName {
+		name = name[:maxToolName]
+	}
+	return strings.TrimRight(name, "_")
+}
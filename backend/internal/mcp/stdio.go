@@ -0,0 +1,159 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// maxStdioMessage bounds one newline-delimited message from a stdio server.
+const maxStdioMessage = 16 << 20
+
+// stopGrace is how long a stdio server may take to exit after its stdin is closed.
+const stopGrace = 2 * time.Second
+
+// stdioTransport runs the server as a child process and exchanges newline-delimited JSON-RPC
+// messages over its stdin and stdout. The server's stderr goes to the backend's stderr.
+type stdioTransport struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	pending map[string]chan *message
+	done    chan struct{} // closed when stdout ends
+	err     error         // why it ended
+}
+
+func startStdio(s ServerConfig) (*stdioTransport, error) {
+	cmd := exec.Command(s.Command, s.Args...)
+	cmd.Env = os.Environ()
+	for k, v := range s.Env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", s.Command, err)
+	}
+	t := &stdioTransport{cmd: cmd, stdin: stdin, pending: make(map[string]chan *message), done: make(chan struct{})}
+	go t.read(s.Name, stdout)
+	return t, nil
+}
+
+// read delivers responses to their callers and answers the server's own requests until
+// stdout closes.
+func (t *stdioTransport) read(server string, stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxStdioMessage)
+	for scanner.Scan() {
+		var msg message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			slog.Warn("mcp server wrote an invalid message", "server", server, "error", err)
+			continue
+		}
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			t.answer(&msg)
+		case msg.Method == "" && len(msg.ID) > 0:
+			t.mu.Lock()
+			ch := t.pending[string(msg.ID)]
+			delete(t.pending, string(msg.ID))
+			t.mu.Unlock()
+			if ch != nil {
+				ch <- &msg
+			}
+		}
+		// notifications (logging, list changes) are ignored
+	}
+	t.mu.Lock()
+	t.err = scanner.Err()
+	if t.err == nil {
+		t.err = errors.New("server closed its output")
+	}
+	t.mu.Unlock()
+	close(t.done)
+}
+
+// answer replies to a request from the server: ping is supported, nothing else.
+func (t *stdioTransport) answer(req *message) {
+	resp := &message{JSONRPC: "2.0", ID: req.ID}
+	if req.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &rpcError{Code: -32601, Message: "method not found"}
+	}
+	if err := t.write(resp); err != nil {
+		slog.Warn("mcp reply to server request failed", "method", req.Method, "error", err)
+	}
+}
+
+func (t *stdioTransport) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+func (t *stdioTransport) roundTrip(ctx context.Context, req *message) (*message, error) {
+	ch := make(chan *message, 1)
+	id := string(req.ID)
+	t.mu.Lock()
+	t.pending[id] = ch
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+
+	if err := t.write(req); err != nil {
+		return nil, err
+	}
+	select {
+	case resp := <-ch:
+		return resp, nil
+	case <-t.done:
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		return nil, fmt.Errorf("server exited: %w", t.err)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (t *stdioTransport) notify(_ context.Context, msg *message) error {
+	return t.write(msg)
+}
+
+// close ends the server: stdin is closed so it can exit on its own, and it is killed if it
+// is still running after stopGrace.
+func (t *stdioTransport) close() error {
+	t.stdin.Close()
+	select {
+	case <-t.done:
+	case <-time.After(stopGrace):
+		t.cmd.Process.Kill()
+	}
+	return t.cmd.Wait()
+}
@@ -0,0 +1,109 @@
+// Package metrics keeps small in-process rolling windows of call outcomes (e.g. every Gemini call),
+// used to tell whether the bot is currently healthy.
+package metrics
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxSamples bounds memory when calls are very frequent; the oldest samples are dropped first.
+const maxSamples = 10_000
+
+// Sample is one recorded call.
+type Sample struct {
+	At      time.Time
+	Latency time.Duration
+	Failed  bool
+}
+
+// Stats summarizes the samples inside a window.
+type Stats struct {
+	Count      int
+	Errors     int
+	ErrorRate  float64       // Errors / Count; 0 when Count is 0
+	P95Latency time.Duration // 95th percentile latency of all samples
+}
+
+// Window records calls and reports stats over the last span. Safe for concurrent use; a nil
+// *Window records nothing and reports empty stats.
+type Window struct {
+	mu      sync.Mutex
+	span    time.Duration
+	samples []Sample
+}
+
+// NewWindow creates a window covering the last span.
+func NewWindow(span time.Duration) *Window {
+	return &Window{span: span}
+}
+
+// Span returns how far back the window looks.
+func (w *Window) Span() time.Duration {
+	if w == nil {
+		return 0
+	}
+	return w.span
+}
+
+// Record adds one call that took latency and failed or succeeded.
+func (w *Window) Record(latency time.Duration, failed bool) {
+	w.record(time.Now(), latency, failed)
+}
+
+func (w *Window) record(at time.Time, latency time.Duration, failed bool) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.prune(at)
+	if len(w.samples) >= maxSamples {
+		w.samples = w.samples[1:]
+	}
+	w.samples = append(w.samples, Sample{At: at, Latency: latency, Failed: failed})
+}
+
+// Stats returns the stats of the calls recorded within the span.
+func (w *Window) Stats() Stats {
+	return w.stats(time.Now())
+}
+
+func (w *Window) stats(now time.Time) Stats {
+	if w == nil {
+		return Stats{}
+	}
+	w.mu.Lock()
+	w.prune(now)
+	latencies := make([]time.Duration, len(w.samples))
+	var s Stats
+	for i, sample := range w.samples {
+		latencies[i] = sample.Latency
+		if sample.Failed {
+			s.Errors++
+		}
+	}
+	w.mu.Unlock()
+
+	s.Count = len(latencies)
+	if s.Count == 0 {
+		return s
+	}
+	s.ErrorRate = float64(s.Errors) / float64(s.Count)
+	slices.Sort(latencies)
+	s.P95Latency = latencies[(s.Count*95+99)/100-1]
+	return s
+}
+
+// prune drops samples older than the span. Callers hold mu.
+func (w *Window) prune(now time.Time) {
+	cutoff := now.Add(-w.span)
+	i := 0
+	for i < len(w.samples) && w.samples[i].At.Before(cutoff) {
+		i++
+	}
+	if i > 0 {
+		w.samples = slices.Delete(w.samples, 0, i)
+	}
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWindow_Stats(t *testing.T) {
+	w := NewWindow(10 * time.Minute)
+	now := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	// Outside the window: ignored.
+	w.record(now.Add(-11*time.Minute), time.Minute, true)
+	for i := 1; i <= 20; i++ {
+		w.record(now.Add(-time.Duration(i)*time.Second), time.Duration(i)*time.Second, i%5 == 0)
+	}
+	s := w.stats(now)
+	if s.Count != 20 || s.Errors != 4 {
+		t.Fatalf("expected 20 calls with 4 errors, got %+v", s)
+	}
+	if s.ErrorRate != 0.2 {
+		t.Errorf("expected error rate 0.2, got %v", s.ErrorRate)
+	}
+	if s.P95Latency != 19*time.Second {
+		t.Errorf("expected p95 19s, got %v", s.P95Latency)
+	}
+	if s := w.stats(now.Add(time.Hour)); s.Count != 0 || s.ErrorRate != 0 {
+		t.Errorf("expected empty stats after the span, got %+v", s)
+	}
+}
+
+func TestWindow_Nil(t *testing.T) {
+	var w *Window
+	w.Record(time.Second, true)
+	if s := w.Stats(); s.Count != 0 {
+		t.Errorf("nil window should report nothing, got %+v", s)
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIAuth(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	a := NewAPIAuth("s3cret", 5*time.Minute)
+	a.now = func() time.Time { return now }
+
+	var gotBody string
+	h := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	signed := func(ts time.Time, secret, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/api/v1/process?x=1", strings.NewReader(body))
+		stamp := strconv.FormatInt(ts.Unix(), 10)
+		r.Header.Set(TimestampHeader, stamp)
+		r.Header.Set(SignatureHeader, Signature(secret, Sign(stamp, "POST", "/api/v1/process?x=1", []byte(body))))
+		return r
+	}
+	withSecret := func(secret string) *http.Request {
+		r := httptest.NewRequest("GET", "/api/v1/proactive", nil)
+		r.Header.Set(SecretHeader, secret)
+		return r
+	}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"health is open", httptest.NewRequest("GET", "/health", nil), http.StatusOK},
+		{"no credentials", httptest.NewRequest("GET", "/api/v1/proactive", nil), http.StatusUnauthorized},
+		{"pprof needs credentials", httptest.NewRequest("GET", "/debug/pprof/heap?user_id=1", nil), http.StatusUnauthorized},
+		{"shared secret", withSecret("s3cret"), http.StatusOK},
+		{"wrong secret", withSecret("nope"), http.StatusUnauthorized},
+		{"signed", signed(now.Add(-time.Minute), "s3cret", `{"chat_id":1}`), http.StatusOK},
+		{"signed with wrong key", signed(now, "nope", `{"chat_id":1}`), http.StatusUnauthorized},
+		{"stale signature", signed(now.Add(-10*time.Minute), "s3cret", `{}`), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, tt.req)
+		if w.Code != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, w.Code)
+		}
+	}
+
+	// The handler still sees the body of a verified request.
+	gotBody = ""
+	h.ServeHTTP(httptest.NewRecorder(), signed(now, "s3cret", `{"chat_id":1}`))
+	if gotBody != `{"chat_id":1}` {
+		t.Errorf("expected body to reach the handler, got %q", gotBody)
+	}
+
+	// A tampered body fails verification.
+	r := signed(now, "s3cret", `{"chat_id":1}`)
+	r.Body = io.NopCloser(strings.NewReader(`{"chat_id":2}`))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("tampered body: expected 401, got %d", w.Code)
+	}
+}
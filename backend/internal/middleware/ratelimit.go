@@ -7,29 +7,57 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
 	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/limit"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
 )
 
 // RateLimiter is an HTTP middleware that enforces tiered rate limiting
 // and exclusive queue locking per Section 10 of the architecture.
 type RateLimiter struct {
-	cache  *cache.Cache
-	db     *db.DB
-	config *config.Config
+	cache    *cache.Cache
+	db       *db.DB
+	config   *config.Config
+	settings *chatsettings.Store // per-chat rate limit exemptions
+	slots    *limit.Semaphore    // MAX_CONCURRENT_REQUESTS; nil = unlimited
+	// Per-instance stand-ins while Redis is unreachable (REDIS_FALLBACK=memory); nil = fail open
+	local *limit.Buckets
+	locks *limit.Locks
+	// triggered decides whether a message gets a reply; nil = every message may
+	triggered func(ctx context.Context, body []byte) (context.Context, bool)
+}
+
+// SetTriggerCheck makes messages that won't get a reply skip the limits: they are only stored
+// by the handler, so they take no tokens, queue lock or concurrency slot. triggered makes the
+// whole decision, probability rolls included, and returns the context the handler reads it from.
+func (rl *RateLimiter) SetTriggerCheck(triggered func(ctx context.Context, body []byte) (context.Context, bool)) {
+	rl.triggered = triggered
 }
 
 // NewRateLimiter creates a new rate limiting middleware.
-func NewRateLimiter(c *cache.Cache, d *db.DB, cfg *config.Config) *RateLimiter {
-	return &RateLimiter{
-		cache:  c,
-		db:     d,
-		config: cfg,
+func NewRateLimiter(c *cache.Cache, d *db.DB, cfg *config.Config, settings *chatsettings.Store) *RateLimiter {
+	rl := &RateLimiter{
+		cache:    c,
+		db:       d,
+		config:   cfg,
+		settings: settings,
+		slots:    limit.NewSemaphore(cfg.MaxConcurrentRequests),
+	}
+	if cfg.RedisFallback != config.RedisFallbackOpen {
+		rl.local = limit.NewBuckets()
+		rl.locks = limit.NewLocks()
 	}
+	return rl
 }
 
 // Middleware returns the HTTP middleware handler.
@@ -39,28 +67,29 @@ func NewRateLimiter(c *cache.Cache, d *db.DB, cfg *config.Config) *RateLimiter {
 func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		requestID := r.Header.Get("X-Request-ID")
-		logger := slog.With("request_id", requestID)
+		ctx := logging.WithRequestID(r.Context(), requestID)
 
 		// Read the full body so we can both parse it here and pass it downstream.
 		bodyBytes, err := io.ReadAll(r.Body)
 		r.Body.Close()
 		if err != nil {
-			logger.Warn("failed to read request body", "error", err)
-			http.Error(w, `{"error":"invalid payload"}`, http.StatusBadRequest)
+			slog.WarnContext(ctx, "failed to read request body", "error", err)
+			apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
 			return
 		}
 
-		var payload struct {
-			ChatID int64  `json:"chat_id"`
-			UserID *int64 `json:"user_id"`
-			Text   string `json:"text"`
-		}
+		var payload requestPayload
 		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
-			http.Error(w, `{"error":"invalid payload"}`, http.StatusBadRequest)
+			apierror.Write(w, r, apierror.InvalidPayload, "invalid payload")
 			return
 		}
 
-		ctx := r.Context()
+		userID := int64(0)
+		if payload.UserID != nil {
+			userID = *payload.UserID
+		}
+		ctx = logging.WithChat(ctx, payload.ChatID, userID)
+		threadID := threadIDOf(payload)
 
 		// ── Check 0: Chat/group whitelist (if configured) ───────────────
 		if len(rl.config.AllowedChatIDs) > 0 {
@@ -72,91 +101,255 @@ func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
 				}
 			}
 			if !allowed {
-				logger.Info("chat_not_allowed", "chat_id", payload.ChatID)
+				slog.InfoContext(ctx, "chat_not_allowed")
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 		}
 
-		// ── Check 1: Global Chat Rate Limit ───────────────────────────
-		chatKey := fmt.Sprintf("rl:chat:%d", payload.ChatID)
-		chatResult, err := rl.cache.CheckRateLimit(ctx, chatKey, rl.config.RateLimitGlobalPerMinute, time.Minute)
-		if err != nil {
-			logger.Error("chat rate limit check failed", "error", err)
-			// On error, allow the request through (fail-open for rate limiting)
-		} else if !chatResult.Allowed {
-			logger.Info("throttled_chat",
-				"chat_id", payload.ChatID,
-				"retry_in", chatResult.RetryIn,
-			)
-			rl.logThrottledMessage(ctx, payload.ChatID, payload.UserID, payload.Text, requestID)
-			// Strict silence — return 204 No Content (Section 10)
-			w.WriteHeader(http.StatusNoContent)
-			return
+		// ── Check 0b: Blocked users (admin /api/v1/admin/block) ─────────
+		// Silent like throttling, and before the limits so a blocked user doesn't use up the chat's.
+		if userID != 0 {
+			blocked, err := rl.db.IsUserBlocked(ctx, payload.ChatID, userID)
+			if err != nil {
+				slog.ErrorContext(ctx, "blocked user check failed", "error", err)
+				// fail-open, like the rate limits
+			} else if blocked {
+				slog.InfoContext(ctx, "blocked_user")
+				rl.logThrottledMessage(ctx, payload, requestID)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
 		}
 
-		// ── Check 2: Per-User Rate Limit ──────────────────────────────
-		if payload.UserID != nil {
-			userKey := fmt.Sprintf("rl:user:%d:%d", payload.ChatID, *payload.UserID)
-			userResult, err := rl.cache.CheckRateLimit(ctx, userKey, rl.config.RateLimitUserPerMinute, time.Minute)
+		// ── Check 0c: Trigger policy ─────────────────────────────────────
+		// Messages that won't be answered (ordinary chatter, lost probability rolls) are stored
+		// without touching the limits, so they can't use up the budget of a real mention.
+		if rl.triggered != nil {
+			var reply bool
+			if ctx, reply = rl.triggered(ctx, bodyBytes); !reply {
+				slog.DebugContext(ctx, "no reply, skipping rate limits")
+				next.ServeHTTP(w, withPayload(ctx, r, payload, bodyBytes))
+				return
+			}
+		}
+
+		// Exempt users (config, admins, chat_settings) skip checks 1 and 2; admin boosts raise or
+		// lift them for a while (POST /api/v1/admin/rate_limit_boost).
+		var chatBucket, userBucket *cache.Bucket
+		if reason := rl.exemption(ctx, payload.ChatID, userID); reason != "" {
+			slog.DebugContext(ctx, "rate_limit_exempt", "reason", reason)
+		} else {
+			chatBoost, userBoost, err := rl.cache.RateBoosts(ctx, payload.ChatID, userID)
 			if err != nil {
-				logger.Error("user rate limit check failed", "error", err)
-			} else if !userResult.Allowed {
-				logger.Info("throttled_user",
-					"user_id", *payload.UserID,
-					"chat_id", payload.ChatID,
-					"retry_in", userResult.RetryIn,
-				)
-				rl.logThrottledMessage(ctx, payload.ChatID, payload.UserID, payload.Text, requestID)
+				slog.ErrorContext(ctx, "rate boost lookup failed", "error", err)
+			}
+			if userBoost == nil {
+				userBoost = chatBoost
+			}
+			chatBucket = bucket(rl.config.RateLimitGlobalPerMinute, rl.config.RateLimitGlobalBurst, chatBoost)
+			if userID != 0 {
+				userBucket = bucket(rl.config.RateLimitUserPerMinute, rl.config.RateLimitUserBurst, userBoost)
+			}
+		}
+		var tightest rateState
+
+		// ── Check 1: Global Chat Rate Limit (per forum topic) ─────────
+		if chatBucket != nil {
+			chatKey := fmt.Sprintf("rl:bucket:chat:%d", payload.ChatID)
+			if threadID != 0 {
+				chatKey = fmt.Sprintf("rl:bucket:chat:%d:%d", payload.ChatID, threadID)
+			}
+			chatResult, err := rl.takeToken(ctx, chatKey, *chatBucket)
+			if err != nil {
+				slog.ErrorContext(ctx, "chat rate limit check failed", "error", err)
+				// REDIS_FALLBACK=open: allow the request through
+			} else if tightest.note("chat", chatResult); !chatResult.Allowed {
+				slog.InfoContext(ctx, "throttled_chat", "retry_in", chatResult.RetryIn)
+				rl.logThrottledMessage(ctx, payload, requestID)
+				tightest.setHeaders(w)
+				// Strict silence — return 204 No Content (Section 10)
 				w.WriteHeader(http.StatusNoContent)
 				return
 			}
 		}
 
+		// ── Check 2: Per-User Rate Limit (whole chat, so topics can't be used to dodge it) ──
+		if userBucket != nil {
+			userKey := fmt.Sprintf("rl:bucket:user:%d:%d", payload.ChatID, userID)
+			userResult, err := rl.takeToken(ctx, userKey, *userBucket)
+			if err != nil {
+				slog.ErrorContext(ctx, "user rate limit check failed", "error", err)
+			} else if tightest.note("user", userResult); !userResult.Allowed {
+				slog.InfoContext(ctx, "throttled_user", "retry_in", userResult.RetryIn)
+				rl.logThrottledMessage(ctx, payload, requestID)
+				tightest.setHeaders(w)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		tightest.setHeaders(w)
+
 		// ── Check 3: Queue Lock (Exclusive Processing) ────────────────
-		locked, err := rl.cache.AcquireLock(ctx, payload.ChatID, 2*time.Minute)
-		if err != nil {
-			logger.Error("queue lock check failed", "error", err)
-		} else if !locked {
-			logger.Info("queue_locked",
-				"chat_id", payload.ChatID,
-			)
-			rl.logThrottledMessage(ctx, payload.ChatID, payload.UserID, payload.Text, requestID)
+		release, locked := rl.acquireLock(ctx, payload.ChatID, threadID)
+		if !locked {
+			slog.InfoContext(ctx, "queue_locked")
+			rl.logThrottledMessage(ctx, payload, requestID)
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
-
 		// Ensure the lock is released when processing completes
-		defer func() {
-			if err := rl.cache.ReleaseLock(ctx, payload.ChatID); err != nil {
-				logger.Error("failed to release queue lock", "error", err)
-			}
-		}()
+		defer release()
 
-		// Restore body for downstream handler (Process needs full JSON).
-		// Do this after WithContext so the request we pass has the body set.
-		ctx = context.WithValue(ctx, payloadKey{}, payload)
-		r = r.WithContext(ctx)
-		r.Body = io.NopCloser(bytes.NewReader(bodyBytes))
-		r.ContentLength = int64(len(bodyBytes))
+		// ── Check 4: Backpressure (global concurrency cap) ────────────
+		if err := rl.slots.Acquire(ctx, time.Duration(rl.config.ConcurrencyWaitMS)*time.Millisecond); err != nil {
+			slog.WarnContext(ctx, "overloaded", "in_flight", rl.slots.InUse(), "error", err)
+			rl.logThrottledMessage(ctx, payload, requestID)
+			w.Header().Set("Retry-After", "5")
+			apierror.Write(w, r, apierror.Overloaded, "overloaded")
+			return
+		}
+		defer rl.slots.Release()
 
 		// Pass through to the actual handler
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, withPayload(ctx, r, payload, bodyBytes))
 	})
 }
 
+// withPayload returns r for the downstream handler: the parsed payload in its context and the
+// body restored (Process needs the full JSON). The body is set after WithContext so the request
+// passed on has it.
+func withPayload(ctx context.Context, r *http.Request, payload requestPayload, body []byte) *http.Request {
+	r = r.WithContext(context.WithValue(ctx, payloadKey{}, payload))
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return r
+}
+
+// takeToken takes a token from the Redis bucket at key, or from the in-memory one of this
+// instance when Redis fails and REDIS_FALLBACK=memory.
+func (rl *RateLimiter) takeToken(ctx context.Context, key string, b cache.Bucket) (*cache.RateLimitResult, error) {
+	res, err := rl.cache.TakeToken(ctx, key, b)
+	if err == nil || rl.local == nil {
+		return res, err
+	}
+	slog.WarnContext(ctx, "rate limit check failed, using the in-memory limiter", "key", key, "error", err)
+	ok, remaining, wait := rl.local.Take(key, b.Rate, b.Per, b.Burst)
+	return &cache.RateLimitResult{Allowed: ok, Limit: max(b.Burst, 1), Remaining: remaining, RetryIn: wait}, nil
+}
+
+// acquireLock takes the queue lock of a chat (forum topic), in Redis or, when Redis fails and
+// REDIS_FALLBACK=memory, in this instance. With REDIS_FALLBACK=open a failure lets the request
+// through unlocked. The returned release must be called once processing is done.
+func (rl *RateLimiter) acquireLock(ctx context.Context, chatID, threadID int64) (release func(), ok bool) {
+	locked, err := rl.cache.AcquireLock(ctx, chatID, threadID, 2*time.Minute)
+	if err == nil {
+		return func() {
+			if err := rl.cache.ReleaseLock(ctx, chatID, threadID); err != nil {
+				slog.ErrorContext(ctx, "failed to release queue lock", "error", err)
+			}
+		}, locked
+	}
+	if rl.locks == nil {
+		slog.ErrorContext(ctx, "queue lock check failed", "error", err)
+		return func() {}, true
+	}
+	slog.WarnContext(ctx, "queue lock check failed, using the in-memory lock", "error", err)
+	key := fmt.Sprintf("%d:%d", chatID, threadID)
+	if !rl.locks.TryLock(key) {
+		return nil, false
+	}
+	return func() { rl.locks.Unlock(key) }, true
+}
+
+// bucket is a tier's token bucket (burst 0 = perMinute) with an admin boost applied, or nil when
+// the boost lifts the limit.
+func bucket(perMinute, burst int, boost *cache.RateBoost) *cache.Bucket {
+	if burst <= 0 {
+		burst = perMinute
+	}
+	rate, ok := boost.Limit(perMinute)
+	if !ok {
+		return nil
+	}
+	burst, _ = boost.Limit(burst)
+	return &cache.Bucket{Rate: rate, Per: time.Minute, Burst: burst}
+}
+
+// rateState tracks the tier closest to its limit, which the response headers report.
+type rateState struct {
+	scope  string
+	result *cache.RateLimitResult
+}
+
+// note records r for scope if it is the tightest result so far (a refusal always is).
+func (s *rateState) note(scope string, r *cache.RateLimitResult) {
+	if s.result == nil || !r.Allowed || r.Remaining < s.result.Remaining {
+		s.scope, s.result = scope, r
+	}
+}
+
+// setHeaders exposes the tightest tier to the frontend: X-RateLimit-Scope (chat or user),
+// X-RateLimit-Limit (burst), X-RateLimit-Remaining, and Retry-After (seconds) when refused.
+func (s *rateState) setHeaders(w http.ResponseWriter) {
+	if s.result == nil {
+		return
+	}
+	h := w.Header()
+	h.Set("X-RateLimit-Scope", s.scope)
+	h.Set("X-RateLimit-Limit", strconv.Itoa(s.result.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(s.result.Remaining))
+	if !s.result.Allowed {
+		h.Set("Retry-After", strconv.Itoa(int(math.Ceil(s.result.RetryIn.Seconds()))))
+	}
+}
+
+// exemption returns why a user skips the chat and user rate limits (config, admin or
+// chat_settings), or "" when they don't. Blocks, the queue lock and backpressure still apply.
+func (rl *RateLimiter) exemption(ctx context.Context, chatID, userID int64) string {
+	switch {
+	case userID == 0:
+		return ""
+	case slices.Contains(rl.config.RateLimitExemptUserIDs, userID):
+		return "config"
+	case rl.config.AdminBypassRateLimits && slices.Contains(rl.config.AdminIDs, userID):
+		return "admin"
+	case rl.settings != nil && slices.Contains(rl.settings.Get(ctx, chatID).RateLimitExemptUsers, userID):
+		return "chat_settings"
+	}
+	return ""
+}
+
 // logThrottledMessage writes a throttled message to PostgreSQL for context (Section 10).
-func (rl *RateLimiter) logThrottledMessage(ctx context.Context, chatID int64, userID *int64, text, requestID string) {
+func (rl *RateLimiter) logThrottledMessage(ctx context.Context, p requestPayload, requestID string) {
 	msg := &db.Message{
-		ChatID:       chatID,
-		UserID:       userID,
-		Text:         &text,
+		ChatID:       p.ChatID,
+		UserID:       p.UserID,
+		Text:         &p.Text,
 		RequestID:    &requestID,
 		WasThrottled: true,
+		ThreadID:     threadIDOf(p),
 	}
 	if _, err := rl.db.InsertMessage(ctx, msg); err != nil {
-		slog.Error("failed to log throttled message", "error", err)
+		slog.ErrorContext(ctx, "failed to log throttled message", "error", err)
+	}
+}
+
+// requestPayload is the part of the /process body the rate limiter needs.
+type requestPayload struct {
+	ChatID          int64  `json:"chat_id"`
+	UserID          *int64 `json:"user_id"`
+	Text            string `json:"text"`
+	MessageThreadID *int64 `json:"message_thread_id"`
+}
+
+// threadIDOf returns the payload's forum topic, or 0 for messages outside topics.
+func threadIDOf(p requestPayload) int64 {
+	if p.MessageThreadID == nil {
+		return 0
 	}
+	return *p.MessageThreadID
 }
 
 // payloadKey is a context key for the parsed request payload.
@@ -164,11 +357,7 @@ type payloadKey struct{}
 
 // GetPayload retrieves the parsed payload from the request context.
 func GetPayload(ctx context.Context) (chatID int64, userID *int64, text string, ok bool) {
-	p, exists := ctx.Value(payloadKey{}).(struct {
-		ChatID int64  `json:"chat_id"`
-		UserID *int64 `json:"user_id"`
-		Text   string `json:"text"`
-	})
+	p, exists := ctx.Value(payloadKey{}).(requestPayload)
 	if !exists {
 		return 0, nil, "", false
 	}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestRateLimiter_Exemption(t *testing.T) {
+	cfg := &config.Config{AdminIDs: []int64{1}, RateLimitExemptUserIDs: []int64{2}}
+	rl := &RateLimiter{config: cfg}
+	ctx := context.Background()
+
+	if got := rl.exemption(ctx, -100, 2); got != "config" {
+		t.Errorf("expected a config exemption, got %q", got)
+	}
+	if got := rl.exemption(ctx, -100, 1); got != "" {
+		t.Errorf("admins are only exempt with ADMIN_BYPASS_RATE_LIMITS, got %q", got)
+	}
+	cfg.AdminBypassRateLimits = true
+	if got := rl.exemption(ctx, -100, 1); got != "admin" {
+		t.Errorf("expected an admin exemption, got %q", got)
+	}
+	if got := rl.exemption(ctx, -100, 3); got != "" {
+		t.Errorf("expected no exemption, got %q", got)
+	}
+	if got := rl.exemption(ctx, -100, 0); got != "" {
+		t.Errorf("expected no exemption without a user, got %q", got)
+	}
+}
+
+func TestBucket(t *testing.T) {
+	if b := bucket(3, 0, nil); b.Rate != 3 || b.Burst != 3 || b.Per != time.Minute {
+		t.Errorf("expected burst to default to the rate, got %+v", b)
+	}
+	if b := bucket(3, 10, &cache.RateBoost{Factor: 2}); b.Rate != 6 || b.Burst != 20 {
+		t.Errorf("expected a boost to scale rate and burst, got %+v", b)
+	}
+	if b := bucket(3, 10, &cache.RateBoost{Factor: 0}); b != nil {
+		t.Errorf("expected a lifted limit, got %+v", b)
+	}
+}
+
+func TestRateState_Headers(t *testing.T) {
+	var s rateState
+	s.note("chat", &cache.RateLimitResult{Allowed: true, Limit: 10, Remaining: 7})
+	s.note("user", &cache.RateLimitResult{Allowed: true, Limit: 3, Remaining: 2})
+	rec := httptest.NewRecorder()
+	s.setHeaders(rec)
+	if h := rec.Header(); h.Get("X-RateLimit-Scope") != "user" || h.Get("X-RateLimit-Limit") != "3" || h.Get("X-RateLimit-Remaining") != "2" || h.Get("Retry-After") != "" {
+		t.Errorf("unexpected headers %v", h)
+	}
+
+	s.note("chat", &cache.RateLimitResult{Allowed: false, Limit: 10, Remaining: 5, RetryIn: 1200 * time.Millisecond})
+	rec = httptest.NewRecorder()
+	s.setHeaders(rec)
+	if h := rec.Header(); h.Get("X-RateLimit-Scope") != "chat" || h.Get("Retry-After") != "2" {
+		t.Errorf("expected the refusing tier with Retry-After rounded up, got %v", h)
+	}
+
+	rec = httptest.NewRecorder()
+	(&rateState{}).setHeaders(rec)
+	if len(rec.Header()) != 0 {
+		t.Errorf("expected no headers without a check, got %v", rec.Header())
+	}
+}
+
+func TestRateLimiter_RedisFallback(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	ctx := context.Background()
+	b := cache.Bucket{Rate: 1, Per: time.Minute, Burst: 1}
+
+	rl := NewRateLimiter(cache.Wrap(client), nil, &config.Config{RedisFallback: config.RedisFallbackMemory}, nil)
+	if res, err := rl.takeToken(ctx, "k", b); err != nil || !res.Allowed || res.Limit != 1 {
+		t.Fatalf("expected the in-memory bucket to allow the first request, got %+v, %v", res, err)
+	}
+	if res, err := rl.takeToken(ctx, "k", b); err != nil || res.Allowed || res.RetryIn <= 0 {
+		t.Errorf("expected the in-memory bucket to refuse the second request, got %+v, %v", res, err)
+	}
+	release, ok := rl.acquireLock(ctx, -100, 0)
+	if !ok {
+		t.Fatal("expected the in-memory lock")
+	}
+	if _, ok := rl.acquireLock(ctx, -100, 0); ok {
+		t.Error("expected the in-memory lock to be exclusive")
+	}
+	release()
+	if _, ok := rl.acquireLock(ctx, -100, 0); !ok {
+		t.Error("expected the in-memory lock to be free after release")
+	}
+
+	rl = NewRateLimiter(cache.Wrap(client), nil, &config.Config{RedisFallback: config.RedisFallbackOpen}, nil)
+	if _, err := rl.takeToken(ctx, "k", b); err == nil {
+		t.Error("expected the Redis error with REDIS_FALLBACK=open")
+	}
+	if _, ok := rl.acquireLock(ctx, -100, 0); !ok {
+		t.Error("expected requests through unlocked with REDIS_FALLBACK=open")
+	}
+}
+
+func TestRateLimiter_SkipsUntriggered(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	cfg := &config.Config{RedisFallback: config.RedisFallbackMemory, RateLimitGlobalPerMinute: 1, RateLimitGlobalBurst: 1}
+	rl := NewRateLimiter(cache.Wrap(client), nil, cfg, nil)
+	rl.SetTriggerCheck(func(ctx context.Context, body []byte) (context.Context, bool) {
+		return ctx, strings.Contains(string(body), "mention")
+	})
+	served := 0
+	h := rl.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served++
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(text string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/process", strings.NewReader(`{"chat_id": -100, "text": "`+text+`"}`)))
+		return rec.Code
+	}
+
+	for range 5 {
+		if code := send("chatter"); code != http.StatusOK {
+			t.Fatalf("expected chatter to reach the handler, got %d", code)
+		}
+	}
+	if code := send("mention"); code != http.StatusOK || served != 6 {
+		t.Errorf("expected the mention to get the chat's only token, got %d after %d served", code, served)
+	}
+}
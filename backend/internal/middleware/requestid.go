@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/google/uuid"
+)
+
+// maxRequestIDLen bounds a client's X-Request-ID; longer IDs are replaced.
+const maxRequestIDLen = 128
+
+// RequestID gives every request an X-Request-ID: the client's when it is usable, else a new
+// UUID. The ID is set on the request header (handlers read it there), scoped to the context
+// for slog and outgoing Gemini calls, and echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(logging.HeaderRequestID)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+			r.Header.Set(logging.HeaderRequestID, id)
+		}
+		w.Header().Set(logging.HeaderRequestID, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID accepts IDs of letters, digits and ._:- only, so a client cannot inject
+// anything into log lines or headers.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '.' || c == '_' || c == ':' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
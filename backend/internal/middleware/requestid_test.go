@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	var gotHeader, gotScoped string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(logging.HeaderRequestID)
+		v, _ := logging.Value(r.Context(), logging.KeyRequestID)
+		gotScoped = v.String()
+	}))
+
+	tests := []struct {
+		name, in string
+		keep     bool
+	}{
+		{"client id kept", "3f2b9c1e-7d4a-4e0b-9f6a-1c2d3e4f5a6b", true},
+		{"missing", "", false},
+		{"unsafe characters", "abc\n\"level\":\"ERROR\"", false},
+		{"too long", strings.Repeat("a", maxRequestIDLen+1), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/api/v1/process", nil)
+			if tt.in != "" {
+				r.Header.Set(logging.HeaderRequestID, tt.in)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			resp := w.Header().Get(logging.HeaderRequestID)
+			if tt.keep && resp != tt.in {
+				t.Errorf("response id = %q, want the client's", resp)
+			}
+			if !tt.keep && (resp == tt.in || len(resp) != 36) {
+				t.Errorf("response id = %q, want a new UUID", resp)
+			}
+			if gotHeader != resp || gotScoped != resp {
+				t.Errorf("handler saw header %q and scoped %q, response has %q", gotHeader, gotScoped, resp)
+			}
+		})
+	}
+}
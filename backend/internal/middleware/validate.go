@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/apispec"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// invalidPayload is the body of a 400 from Validate.
+// Validate checks JSON request bodies against the operation schemas in apispec before they reach
+// the handlers, answering 400 with every problem found ({"error":"invalid payload","code":"invalid_payload","details":[...]}, see apierror).
+// Requests without a documented body pass through untouched.
+func Validate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		op := apispec.Find(r.Method, r.URL.Path)
+		if op == nil || op.Request == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		body, err := io.ReadAll(r.Body)
+		r.Body.Close()
+		if err != nil {
+			rejectPayload(w, r, apispec.FieldError{Problem: "body could not be read"})
+			return
+		}
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			rejectPayload(w, r, apispec.FieldError{Problem: "body is not valid JSON"})
+			return
+		}
+		if dec.More() {
+			rejectPayload(w, r, apispec.FieldError{Problem: "body must be a single JSON value"})
+			return
+		}
+		if errs := op.Request.Validate(v); len(errs) > 0 {
+			rejectPayload(w, r, errs...)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func rejectPayload(w http.ResponseWriter, r *http.Request, errs ...apispec.FieldError) {
+	ctx := logging.WithRequestID(r.Context(), r.Header.Get("X-Request-ID"))
+	slog.WarnContext(ctx, "request rejected by schema", "method", r.Method, "path", r.URL.Path, "problems", errs)
+	apierror.WriteDetails(w, r, apierror.InvalidPayload, "invalid payload", errs)
+}
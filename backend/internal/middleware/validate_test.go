@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/apierror"
+	"github.com/ThatHunky/gryag/backend/internal/apispec"
+)
+
+func TestValidate(t *testing.T) {
+	var gotBody string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+	h := Validate(next)
+
+	tests := []struct {
+		method, path, body string
+		want               int
+		details            string
+	}{
+		{"POST", "/api/v1/ack_reply", `{"request_id": "r1", "chat_id": -100, "message_id": 5}`, http.StatusOK, ""},
+		{"POST", "/api/v1/ack_reply", `{"request_id": "r1", "chat_id": -100}`, http.StatusBadRequest, "message_id: is required"},
+		{"POST", "/api/v1/ack_reply", `{"request_id": "r1", "chat_id": -100, "message_id": 0}`, http.StatusBadRequest, "message_id: must be at least 1"},
+		{"POST", "/api/v1/process", `{"chat_id": 1, "user_id": null, "text": "hi"}`, http.StatusOK, ""},
+		{"POST", "/api/v1/process", `{"chat_id": 1,`, http.StatusBadRequest, ": body is not valid JSON"},
+		{"PUT", "/api/v1/admin/chat_settings", `{"user_id": 111, "chat_id": 1, "temperature": 5}`, http.StatusBadRequest, "temperature: must be at most 2"},
+		{"POST", "/api/v1/admin/replay/r1", `{"user_id": 111, "turn": -1}`, http.StatusBadRequest, "turn: must be at least 0"},
+		{"GET", "/api/v1/proactive", ``, http.StatusOK, ""},
+		{"POST", "/api/v1/unknown", `not json`, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		gotBody = ""
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s %s: expected %d, got %d (%s)", tt.method, tt.path, tt.body, tt.want, w.Code, w.Body.String())
+			continue
+		}
+		if tt.want == http.StatusOK {
+			if gotBody != tt.body {
+				t.Errorf("%s %s: handler got body %q, want %q", tt.method, tt.path, gotBody, tt.body)
+			}
+			continue
+		}
+		var resp struct {
+			Error   string               `json:"error"`
+			Code    apierror.Code        `json:"code"`
+			Details []apispec.FieldError `json:"details"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Error != "invalid payload" || resp.Code != apierror.InvalidPayload {
+			t.Errorf("%s: unexpected error body %s", tt.path, w.Body.String())
+			continue
+		}
+		var details []string
+		for _, d := range resp.Details {
+			details = append(details, d.Field+": "+d.Problem)
+		}
+		if strings.Join(details, "|") != tt.details {
+			t.Errorf("%s %s: details %q, want %q", tt.path, tt.body, details, tt.details)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package proactive
+
+import (
+	"fmt"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// followUpLine is the instruction that makes the proactive turn keep a promise from an earlier
+// reply.
+func followUpLine(f *db.FollowUp) string {
+	line := fmt.Sprintf("Earlier in this chat you promised to do this later, and it is due now: %s.", f.Text)
+	if f.UserID != nil {
+		line += fmt.Sprintf(" The promise was made to user_id %d; address them.", *f.UserID)
+	}
+	return line + " Keep the promise this turn (remind, report back or look it up with your tools), briefly and in character."
+}
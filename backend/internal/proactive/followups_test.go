@@ -0,0 +1,19 @@
+package proactive
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestFollowUpLine(t *testing.T) {
+	line := followUpLine(&db.FollowUp{Text: "Нагадай Олені про дантиста"})
+	if !strings.Contains(line, "Нагадай Олені про дантиста.") || strings.Contains(line, "user_id") {
+		t.Errorf("unexpected line %q", line)
+	}
+	user := int64(42)
+	if line := followUpLine(&db.FollowUp{Text: "Check the score", UserID: &user}); !strings.Contains(line, "user_id 42") {
+		t.Errorf("expected the user in %q", line)
+	}
+}
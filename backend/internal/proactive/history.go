@@ -0,0 +1,42 @@
+package proactive
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	// promptDeliveries is how many earlier deliveries the proactive prompt lists.
+	promptDeliveries = 5
+	// promptDeliveryRunes caps each listed delivery; digests and research results run long.
+	promptDeliveryRunes = 300
+)
+
+// recentDeliveries returns the chat's last delivered proactive items, newest first.
+func (r *Runner) recentDeliveries(ctx context.Context, chatID int64) []db.ProactiveDelivery {
+	deliveries, err := r.db.ProactiveDeliveries(ctx, chatID, "", promptDeliveries)
+	if err != nil {
+		slog.WarnContext(ctx, "read proactive deliveries failed", "chat_id", chatID, "error", err)
+	}
+	return deliveries
+}
+
+// deliveriesLine lists earlier deliveries for the model not to repeat, or "" without any.
+func deliveriesLine(deliveries []db.ProactiveDelivery) string {
+	if len(deliveries) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("You already posted these in this chat unprompted (newest first). Don't repeat their topics or wording:")
+	for _, d := range deliveries {
+		text := strings.Join(strings.Fields(d.Text), " ")
+		if runes := []rune(text); len(runes) > promptDeliveryRunes {
+			text = string(runes[:promptDeliveryRunes]) + "…"
+		}
+		b.WriteString("\n- [" + d.DeliveredAt.UTC().Format("2006-01-02") + "] " + text)
+	}
+	return b.String()
+}
@@ -0,0 +1,26 @@
+package proactive
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestDeliveriesLine(t *testing.T) {
+	if got := deliveriesLine(nil); got != "" {
+		t.Errorf("expected nothing without deliveries, got %q", got)
+	}
+	at := time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)
+	got := deliveriesLine([]db.ProactiveDelivery{
+		{Text: "Anyone watched\nthe match?", DeliveredAt: at},
+		{Text: strings.Repeat("я", promptDeliveryRunes+10), DeliveredAt: at},
+	})
+	if !strings.Contains(got, "\n- [2026-10-01] Anyone watched the match?") {
+		t.Errorf("expected a dated one-line entry, got %q", got)
+	}
+	if !strings.HasSuffix(got, strings.Repeat("я", promptDeliveryRunes)+"…") {
+		t.Errorf("expected a long delivery cut, got %q", got)
+	}
+}
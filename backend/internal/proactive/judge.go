@@ -0,0 +1,90 @@
+package proactive
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// judgeRecentReplies is how many of the bot's latest replies the judge compares against.
+const judgeRecentReplies = 5
+
+func historyKey(chatID int64) string {
+	return fmt.Sprintf("proactive:history:%d", chatID)
+}
+
+// recentPosts returns the chat's last proactive messages, oldest first.
+func (r *Runner) recentPosts(ctx context.Context, chatID int64) []string {
+	var posts []string
+	if _, err := r.cache.GetJSON(ctx, historyKey(chatID), &posts); err != nil {
+		slog.WarnContext(ctx, "read proactive history failed", "chat_id", chatID, "error", err)
+	}
+	return posts
+}
+
+// rememberPost appends a sent proactive message to the chat's history, keeping the newest
+// PROACTIVE_HISTORY_SIZE.
+func (r *Runner) rememberPost(ctx context.Context, chatID int64, posts []string, reply string) {
+	posts = append(posts, reply)
+	if n := r.cfg.ProactiveHistorySize; n > 0 && len(posts) > n {
+		posts = posts[len(posts)-n:]
+	}
+	if err := r.cache.SetJSON(ctx, historyKey(chatID), posts, lastSentTTL); err != nil {
+		slog.WarnContext(ctx, "record proactive history failed", "chat_id", chatID, "error", err)
+	}
+}
+
+// botReplies returns the texts of the last n bot replies in messages, oldest first.
+func botReplies(messages []db.Message, n int) []string {
+	var out []string
+	for i := len(messages) - 1; i >= 0 && len(out) < n; i-- {
+		if messages[i].IsBotReply && messages[i].Text != nil && *messages[i].Text != "" {
+			out = append(out, *messages[i].Text)
+		}
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// isRepeat reports whether candidate is, up to case and spacing, one of the earlier texts.
+func isRepeat(candidate string, earlier []string) bool {
+	norm := func(s string) string { return strings.Join(strings.Fields(strings.ToLower(s)), " ") }
+	c := norm(candidate)
+	for _, e := range earlier {
+		if norm(e) == c {
+			return true
+		}
+	}
+	return false
+}
+
+// passesJudge decides whether a candidate proactive message is worth sending: exact repeats are
+// dropped outright, then a cheap LLM pass scores relevance and novelty against the bot's recent
+// replies and earlier proactive posts. Without PROACTIVE_JUDGE_MIN_SCORE only repeats are dropped.
+func (r *Runner) passesJudge(ctx context.Context, reply string, messages []db.Message, posts []string) bool {
+	replies := botReplies(messages, judgeRecentReplies)
+	if isRepeat(reply, posts) || isRepeat(reply, replies) {
+		slog.InfoContext(ctx, "proactive message dropped", "reason", "repeat")
+		return false
+	}
+	if r.cfg.ProactiveJudgeMinScore <= 0 {
+		return true
+	}
+	verdict, err := r.llm.JudgeProactive(ctx, reply, replies, posts)
+	if err != nil {
+		slog.WarnContext(ctx, "proactive judge failed, message dropped", "error", err)
+		return false
+	}
+	if verdict.Score < r.cfg.ProactiveJudgeMinScore {
+		slog.InfoContext(ctx, "proactive message dropped", "reason", "low score",
+			"score", verdict.Score, "min_score", r.cfg.ProactiveJudgeMinScore, "judge_reason", verdict.Reason)
+		return false
+	}
+	slog.InfoContext(ctx, "proactive message approved", "score", verdict.Score)
+	return true
+}
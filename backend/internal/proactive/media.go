@@ -0,0 +1,48 @@
+package proactive
+
+import (
+	"context"
+	"encoding/base64"
+	"log/slog"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/tools"
+	"github.com/ThatHunky/gryag/backend/internal/watermark"
+	"google.golang.org/genai"
+)
+
+// mediaLine invites the model to attach a generated picture this turn.
+const mediaLine = "This turn you may attach a picture: if something in the chat suggests a meme or an image, call generate_image with a prompt for it and keep your text to a short caption. Skip it if nothing fits."
+
+// mediaToolResult is what the model sees instead of the image data.
+const mediaToolResult = "Image generated. It will be attached to your message; reply with a short caption or nothing."
+
+// hasTool reports whether the declarations offered to the model include name.
+func hasTool(genaiTools []*genai.Tool, name string) bool {
+	for _, t := range genaiTools {
+		for _, fd := range t.FunctionDeclarations {
+			if fd.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// stampImage labels a generated image for the chat when its watermark is on, as chat replies are.
+func stampImage(ctx context.Context, img tools.ImageOutput, s *chatsettings.Settings) tools.ImageOutput {
+	if !s.WatermarkEnabled {
+		return img
+	}
+	data, err := base64.StdEncoding.DecodeString(img.MediaBase64)
+	if err != nil {
+		return img
+	}
+	stamped, err := watermark.Stamp(data, s.WatermarkLabel)
+	if err != nil {
+		slog.WarnContext(ctx, "watermark failed, sending image unlabeled", "error", err)
+		return img
+	}
+	img.MediaBase64 = base64.StdEncoding.EncodeToString(stamped)
+	return img
+}
@@ -0,0 +1,31 @@
+package proactive
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/tools"
+	"google.golang.org/genai"
+)
+
+func TestHasTool(t *testing.T) {
+	decls := []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{Name: "search_web"}, {Name: "generate_image"}}}}
+	if !hasTool(decls, "generate_image") {
+		t.Error("expected generate_image to be found")
+	}
+	if hasTool(decls, "edit_image") || hasTool(nil, "generate_image") {
+		t.Error("expected missing tools not to be found")
+	}
+}
+
+func TestStampImage_Unlabeled(t *testing.T) {
+	img := tools.ImageOutput{MediaBase64: "aGk=", MediaType: "photo"}
+	if got := stampImage(context.Background(), img, &chatsettings.Settings{}); got != img {
+		t.Errorf("expected the image unchanged without a watermark, got %+v", got)
+	}
+	// Data that is not an image is sent as it is rather than dropped
+	if got := stampImage(context.Background(), img, &chatsettings.Settings{WatermarkEnabled: true, WatermarkLabel: "AI"}); got != img {
+		t.Errorf("expected an undecodable image sent unlabeled, got %+v", got)
+	}
+}
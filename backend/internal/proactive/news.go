@@ -0,0 +1,13 @@
+package proactive
+
+import "fmt"
+
+// newsLine is the instruction of a news turn: the configured instruction plus one query hint
+// picked with r, a uniform random number in [0, 1).
+func newsLine(instruction string, queries []string, r float64) string {
+	if len(queries) == 0 {
+		return instruction
+	}
+	q := queries[int(r*float64(len(queries)))%len(queries)]
+	return fmt.Sprintf("%s Suggested query: %q.", instruction, q)
+}
@@ -0,0 +1,16 @@
+package proactive
+
+import "testing"
+
+func TestNewsLine(t *testing.T) {
+	if got := newsLine("Search the news.", nil, 0.5); got != "Search the news." {
+		t.Errorf("expected the bare instruction without hints, got %q", got)
+	}
+	queries := []string{"Kyiv news", "tech news"}
+	if got := newsLine("Search the news.", queries, 0); got != `Search the news. Suggested query: "Kyiv news".` {
+		t.Errorf("unexpected line %q", got)
+	}
+	if got := newsLine("Search the news.", queries, 0.99); got != `Search the news. Suggested query: "tech news".` {
+		t.Errorf("unexpected line %q", got)
+	}
+}
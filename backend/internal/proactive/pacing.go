@@ -0,0 +1,124 @@
+package proactive
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
+)
+
+const (
+	// overdueWeight is how much likelier a chat is to be picked once its max interval has passed.
+	overdueWeight = 4.0
+	// maxSilenceFactor caps how much a long-quiet chat is preferred over one that just went quiet.
+	maxSilenceFactor = 4.0
+	// tenseFactor is how much less likely a chat with a tense mood is to be picked.
+	tenseFactor = 0.5
+	// lastSentTTL keeps the last-sent time a bit longer than the longest allowed interval.
+	lastSentTTL = 8 * 24 * time.Hour
+)
+
+func lastSentKey(chatID int64) string {
+	return fmt.Sprintf("proactive:last:%d", chatID)
+}
+
+// chatWeight returns the relative chance of picking a chat for a proactive message at now, given
+// when it last got one (zero = never or unknown). 0 means the chat must be skipped.
+func chatWeight(s *chatsettings.Settings, now, last time.Time) float64 {
+	if !s.ProactiveEnabled || s.InQuietHours(now) {
+		return 0
+	}
+	since := now.Sub(last)
+	if minGap := time.Duration(s.ProactiveMinIntervalMinutes) * time.Minute; minGap > 0 && !last.IsZero() && since < minGap {
+		return 0
+	}
+	if maxGap := time.Duration(s.ProactiveMaxIntervalMinutes) * time.Minute; maxGap > 0 && (last.IsZero() || since >= maxGap) {
+		return overdueWeight
+	}
+	return 1
+}
+
+// silenceFactor scales a chat's weight by how long it has been since a person wrote in it: 0 while
+// the conversation is active (under minSilence), then growing with the silence up to
+// maxSilenceFactor. minSilence 0 turns the guard off.
+func silenceFactor(silence, minSilence time.Duration) float64 {
+	if minSilence <= 0 {
+		return 1
+	}
+	if silence < minSilence {
+		return 0
+	}
+	return math.Min(float64(silence)/float64(minSilence), maxSilenceFactor)
+}
+
+// moodFactor scales a chat's weight by its mood: a heated chat (an argument) is skipped, so the bot
+// doesn't barge in with a joke, and a tense one is picked less often.
+func moodFactor(m sentiment.Mood) float64 {
+	switch m.Label() {
+	case sentiment.Heated:
+		return 0
+	case sentiment.Tense:
+		return tenseFactor
+	}
+	return 1
+}
+
+// pickWeighted picks one chat by weight; r is a uniform random number in [0, 1). Returns false
+// when every weight is 0.
+func pickWeighted(chatIDs []int64, weights []float64, r float64) (int64, bool) {
+	var total float64
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return 0, false
+	}
+	target := r * total
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if target < w {
+			return chatIDs[i], true
+		}
+		target -= w
+	}
+	// Rounding: fall back to the last chat with a weight
+	for i := len(weights) - 1; i >= 0; i-- {
+		if weights[i] > 0 {
+			return chatIDs[i], true
+		}
+	}
+	return 0, false
+}
+
+// lastSent returns when the chat last got a proactive message (zero when unknown).
+func (r *Runner) lastSent(ctx context.Context, chatID int64) time.Time {
+	var t time.Time
+	if _, err := r.cache.GetJSON(ctx, lastSentKey(chatID), &t); err != nil {
+		slog.WarnContext(ctx, "read last proactive time failed", "chat_id", chatID, "error", err)
+	}
+	return t
+}
+
+// chatMood returns the chat's current mood; neutral when mood tracking is off or unknown.
+func (r *Runner) chatMood(ctx context.Context, chatID int64, now time.Time) sentiment.Mood {
+	if !r.cfg.EnableMoodTracking {
+		return sentiment.Mood{}
+	}
+	m, err := sentiment.Load(ctx, r.cache, chatID, now)
+	if err != nil {
+		slog.WarnContext(ctx, "read chat mood failed", "chat_id", chatID, "error", err)
+	}
+	return m
+}
+
+func (r *Runner) markSent(ctx context.Context, chatID int64, at time.Time) {
+	if err := r.cache.SetJSON(ctx, lastSentKey(chatID), at, lastSentTTL); err != nil {
+		slog.WarnContext(ctx, "record last proactive time failed", "chat_id", chatID, "error", err)
+	}
+}
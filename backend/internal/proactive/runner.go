@@ -3,22 +3,24 @@ package proactive
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"math/rand"
 	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
 	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/ThatHunky/gryag/backend/internal/metrics"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
 	"github.com/ThatHunky/gryag/backend/internal/tools"
 	"google.golang.org/genai"
 )
 
-const (
-	proactiveBlock = "You are initiating without being asked. You may reply to something recent in the chat, or start a new topic. Keep it short and in character. If you have nothing to add, output nothing."
-	newsSearchLine = "This turn you MUST conduct a news search: call the search_web tool with a relevant query (e.g. trending or topical), then share something from the results in your reply."
-)
+const proactiveBlock = "You are initiating without being asked. You may reply to something recent in the chat, or start a new topic. Keep it short and in character. If you have nothing to add, output nothing."
 
 // Runner runs one proactive message attempt: pick a chat, call the LLM with proactive instructions, push to queue if reply.
 type Runner struct {
@@ -28,28 +30,97 @@ type Runner struct {
 	registry *tools.Registry
 	executor *tools.Executor
 	cache    *cache.Cache
+	settings *chatsettings.Store
+	health   *metrics.Window // recent Gemini calls; nil = no health gate
 }
 
-// NewRunner creates a proactive runner.
-func NewRunner(cfg *config.Config, database *db.DB, llmClient *llm.Client, reg *tools.Registry, exe *tools.Executor, c *cache.Cache) *Runner {
-	return &Runner{cfg: cfg, db: database, llm: llmClient, registry: reg, executor: exe, cache: c}
+// NewRunner creates a proactive runner. health is the window of recent Gemini calls (may be nil).
+func NewRunner(cfg *config.Config, database *db.DB, llmClient *llm.Client, reg *tools.Registry, exe *tools.Executor, c *cache.Cache, settings *chatsettings.Store, health *metrics.Window) *Runner {
+	return &Runner{cfg: cfg, db: database, llm: llmClient, registry: reg, executor: exe, cache: c, settings: settings, health: health}
+}
+
+// unhealthyReason returns why proactive runs should pause given recent Gemini call stats, or ""
+// when they may go ahead. With fewer than ProactiveHealthMinCalls calls there is too little data
+// to judge, so runs go ahead.
+func unhealthyReason(s metrics.Stats, cfg *config.Config) string {
+	if s.Count == 0 || s.Count < cfg.ProactiveHealthMinCalls {
+		return ""
+	}
+	if cfg.ProactiveMaxErrorRate > 0 && s.ErrorRate > cfg.ProactiveMaxErrorRate {
+		return fmt.Sprintf("error rate %.2f over %.2f", s.ErrorRate, cfg.ProactiveMaxErrorRate)
+	}
+	if maxLatency := time.Duration(cfg.ProactiveMaxP95LatencyMS) * time.Millisecond; maxLatency > 0 && s.P95Latency > maxLatency {
+		return fmt.Sprintf("p95 latency %s over %s", s.P95Latency.Round(time.Millisecond), maxLatency)
+	}
+	return ""
 }
 
 // RunOne picks a recent chat, runs the proactive LLM flow with tools, and pushes a message to the queue if the model replies.
 func (r *Runner) RunOne(ctx context.Context) {
-	logger := slog.With("component", "proactive")
+	ctx = logging.With(ctx, "component", "proactive")
+
+	// Don't start conversations while direct questions are failing or slow to answer.
+	stats := r.health.Stats()
+	if reason := unhealthyReason(stats, r.cfg); reason != "" {
+		slog.WarnContext(ctx, "proactive run skipped, gemini unhealthy",
+			"reason", reason, "calls", stats.Count, "window", r.health.Span())
+		return
+	}
 
-	chatIDs, err := r.db.GetRecentChatIDs(ctx, 7*24*time.Hour)
+	activity, err := r.db.GetRecentChatLastHuman(ctx, 7*24*time.Hour)
 	if err != nil {
-		logger.Error("get recent chat ids failed", "error", err)
+		slog.ErrorContext(ctx, "get recent chat activity failed", "error", err)
 		return
 	}
-	if len(chatIDs) == 0 {
+	// Weight chats by their proactive settings: opted-out chats, chats in quiet hours and chats
+	// inside their min interval are skipped; chats past their max interval are preferred. Chats
+	// where people are talking right now or arguing are skipped too, and quieter, calmer chats are
+	// preferred, as are chats with topic hints or follow-ups due.
+	now := time.Now()
+	minSilence := time.Duration(r.cfg.ProactiveMinSilenceMinutes) * time.Minute
+	chatIDs := make([]int64, len(activity))
+	weights := make([]float64, len(activity))
+	dueTopics := make(map[int64][]db.ChatTopic)
+	dueFollowUps := make(map[int64][]db.FollowUp)
+	moods := make(map[int64]sentiment.Mood)
+	for i, a := range activity {
+		chatIDs[i] = a.ChatID
+		weights[i] = chatWeight(r.settings.Get(ctx, a.ChatID), now, r.lastSent(ctx, a.ChatID)) *
+			silenceFactor(now.Sub(a.LastHumanAt), minSilence)
+		if weights[i] > 0 {
+			moods[a.ChatID] = r.chatMood(ctx, a.ChatID, now)
+			weights[i] *= moodFactor(moods[a.ChatID])
+		}
+		if weights[i] == 0 {
+			continue
+		}
+		topics, err := r.db.DueChatTopics(ctx, a.ChatID, now)
+		if err != nil {
+			slog.WarnContext(ctx, "load chat topics failed", "chat_id", a.ChatID, "error", err)
+		} else if len(topics) > 0 {
+			dueTopics[a.ChatID] = topics
+		}
+		if r.cfg.FollowUpsEnabled() {
+			followUps, err := r.db.DueFollowUps(ctx, a.ChatID, now)
+			if err != nil {
+				slog.WarnContext(ctx, "load follow-ups failed", "chat_id", a.ChatID, "error", err)
+			} else if len(followUps) > 0 {
+				dueFollowUps[a.ChatID] = followUps
+			}
+		}
+		if len(dueTopics[a.ChatID]) > 0 || len(dueFollowUps[a.ChatID]) > 0 {
+			weights[i] *= topicBoost
+		}
+	}
+	chatID, ok := pickWeighted(chatIDs, weights, rand.Float64())
+	if !ok {
 		return
 	}
 
-	chatID := chatIDs[rand.Intn(len(chatIDs))]
-	messages, err := r.db.GetRecentMessages(ctx, chatID, r.cfg.ImmediateContextSize)
+	ctx = logging.WithChat(ctx, chatID, 0)
+	settings := r.settings.Get(ctx, chatID)
+	// Proactive messages are posted without a topic, so forum groups get them in General (thread 0)
+	messages, err := r.db.GetRecentMessages(ctx, chatID, 0, r.cfg.ImmediateContextSize)
 	if err != nil || len(messages) == 0 {
 		return
 	}
@@ -70,31 +141,66 @@ func (r *Runner) RunOne(ctx context.Context) {
 		}
 	}
 
-	di, err := llm.NewDynamicInstructions(ctx, r.db, chatID, userID, username, firstName, "[Proactive turn]", r.cfg.ImmediateContextSize, nil, "")
+	di, err := llm.NewDynamicInstructions(ctx, r.db, chatID, 0, userID, username, firstName, "[Proactive turn]", r.cfg.ImmediateContextPool(), nil, "")
 	if err != nil {
-		logger.Error("dynamic instructions failed", "error", err)
+		slog.ErrorContext(ctx, "dynamic instructions failed", "error", err)
 		return
 	}
+	di.KeepImportant(r.cfg.ImmediateContextSize)
+	// Privacy mode: pseudonyms in the prompt, real names in tool calls and the message. Without
+	// the mapping the chat is skipped rather than sent with real names
+	var privacy *llm.Privacy
+	if settings.PrivacyMode {
+		if privacy, err = llm.LoadPrivacy(ctx, r.db, di); err != nil {
+			slog.ErrorContext(ctx, "privacy mode pseudonyms failed, skipping", "error", err)
+			return
+		}
+		di.Pseudonymized = true
+	}
 	di.ToolsDescription = r.registry.GetToolDescription()
+	if mood, ok := moods[chatID]; ok && r.cfg.EnableMoodTracking {
+		di.Mood = &mood
+	}
 
 	parts := di.BuildParts()
+	genaiTools := r.registry.GetToolsForChat(settings)
+	// A due follow-up (the earliest) or topic hint makes the turn about it; otherwise it sometimes
+	// shares news or a picture.
 	proactiveText := proactiveBlock
-	if rand.Float32() < 0.30 {
-		proactiveText += "\n\n" + newsSearchLine
+	var followUp *db.FollowUp
+	var topic *db.ChatTopic
+	if fus := dueFollowUps[chatID]; len(fus) > 0 {
+		followUp = &fus[0]
+	} else {
+		topic = pickTopic(dueTopics[chatID], rand.Float64())
+	}
+	if followUp != nil {
+		proactiveText += "\n\n" + followUpLine(followUp)
+	} else if topic != nil {
+		proactiveText += "\n\n" + topicLine(topic)
+	} else if rand.Float64() < settings.ProactiveNewsProbability {
+		proactiveText += "\n\n" + newsLine(r.cfg.ProactiveNewsInstruction, settings.ProactiveNewsQueries, rand.Float64())
+	} else if rand.Float64() < r.cfg.ProactiveMediaProbability && hasTool(genaiTools, "generate_image") {
+		proactiveText += "\n\n" + mediaLine
+	}
+	if line := deliveriesLine(r.recentDeliveries(ctx, chatID)); line != "" {
+		proactiveText += "\n\n" + line
 	}
 	// Prepend proactive instruction
-	parts = append([]*genai.Part{genai.NewPartFromText(proactiveText)}, parts...)
+	parts = privacy.HideParts(append([]*genai.Part{genai.NewPartFromText(proactiveText)}, parts...))
 
 	contents := []*genai.Content{
 		{Role: "user", Parts: parts},
 	}
-	genaiTools := r.registry.GetTools()
+	ctx = context.WithValue(ctx, tools.RequestDisabledToolsKey, settings.DisabledTools)
+	genOpts := llm.GenerateOptions{Persona: r.settings.SystemPrompt(ctx, settings), Temperature: &settings.Temperature}
 
 	reply := ""
+	var media tools.ImageOutput // set when the model generated an image
 	for i := 0; i < 5; i++ {
-		resp, err := r.llm.GenerateResponse(ctx, contents, genaiTools)
+		resp, err := r.llm.GenerateResponseWithOptions(ctx, contents, genaiTools, genOpts)
 		if err != nil {
-			logger.Error("proactive generation failed", "error", err)
+			slog.ErrorContext(ctx, "proactive generation failed", "error", err)
 			return
 		}
 		if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
@@ -110,11 +216,18 @@ func (r *Runner) RunOne(ctx context.Context) {
 				reply += part.Text
 			} else if part.FunctionCall != nil {
 				hasToolCall = true
-				args, _ := json.Marshal(part.FunctionCall.Args)
+				args, _ := json.Marshal(privacy.RevealCall(part.FunctionCall).Args)
 				res := r.executor.Execute(ctx, part.FunctionCall.Name, args)
-				payload := map[string]any{"result": res.Output}
+				payload := map[string]any{"result": privacy.Hide(res.Output)}
 				if res.Error != "" {
-					payload["error"] = res.Error
+					payload["error"], payload["error_kind"] = privacy.Hide(res.Error), res.ErrorKind
+				}
+				// Generated images go out with the message; the model only learns that it worked
+				if name := part.FunctionCall.Name; name == "generate_image" || name == "edit_image" {
+					if img, ok := tools.ParseImageOutput(res.Output); ok {
+						media = stampImage(ctx, img, settings)
+						payload["result"] = mediaToolResult
+					}
 				}
 				toolResponses = append(toolResponses, genai.NewPartFromFunctionResponse(part.FunctionCall.Name, payload))
 			}
@@ -126,15 +239,43 @@ func (r *Runner) RunOne(ctx context.Context) {
 		contents = append(contents, &genai.Content{Role: "user", Parts: toolResponses})
 	}
 
-	reply = trimSpace(reply)
-	if reply == "" {
+	reply = trimSpace(privacy.Reveal(reply))
+	if reply == "" && media.MediaBase64 == "" {
+		return
+	}
+	// A picture without a caption has nothing for the judge to compare
+	posts := r.recentPosts(ctx, chatID)
+	if reply != "" && !r.passesJudge(ctx, reply, messages, posts) {
 		return
 	}
-	if err := r.cache.PushProactive(ctx, cache.ProactiveItem{ChatID: chatID, Reply: reply}); err != nil {
-		logger.Error("push proactive failed", "error", err)
+	item := cache.ProactiveItem{Source: cache.SourceProactive, ChatID: chatID, Reply: reply,
+		MediaBase64: media.MediaBase64, MediaType: media.MediaType}
+	if err := r.cache.PushProactive(ctx, item); err != nil {
+		slog.ErrorContext(ctx, "push proactive failed", "error", err)
 		return
 	}
-	logger.Info("proactive message queued", "chat_id", chatID, "reply_length", len(reply))
+	r.markSent(ctx, chatID, time.Now())
+	if reply != "" {
+		r.rememberPost(ctx, chatID, posts, reply)
+	}
+	if topic != nil {
+		if err := r.db.MarkChatTopicUsed(ctx, topic.ID); err != nil {
+			slog.WarnContext(ctx, "mark chat topic used failed", "topic_id", topic.ID, "error", err)
+		}
+	}
+	if followUp != nil {
+		if err := r.db.MarkFollowUpDone(ctx, followUp.ID); err != nil {
+			slog.WarnContext(ctx, "mark follow-up done failed", "follow_up_id", followUp.ID, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "proactive message queued", "reply_length", len(reply), "has_media", media.MediaBase64 != "", "topic_id", topicID(topic), "follow_up", followUp != nil)
+}
+
+func topicID(t *db.ChatTopic) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.ID
 }
 
 func trimSpace(s string) string {
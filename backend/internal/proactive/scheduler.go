@@ -12,9 +12,13 @@ const (
 	defaultMinInterval = 30 * time.Minute
 	defaultMaxInterval = 4 * time.Hour
 	checkInterval      = 15 * time.Minute
+	// runLockTTL is the cluster-wide gap between proactive runs: each replica keeps its own random
+	// timer, but a run only happens when no other replica ran within this window.
+	runLockTTL = defaultMinInterval
 )
 
-// Scheduler runs the proactive loop: only during active hours (Kyiv), at random intervals.
+// Scheduler runs the proactive loop: only during active hours (Kyiv), at random intervals. With
+// several replicas at most one run happens per runLockTTL across all of them.
 func Scheduler(ctx context.Context, r *Runner, startHour, endHour int) {
 	logger := slog.With("component", "proactive_scheduler")
 	kyiv, err := time.LoadLocation("Europe/Kyiv")
@@ -32,7 +36,14 @@ func Scheduler(ctx context.Context, r *Runner, startHour, endHour int) {
 		inWindow := withinActiveHours(hour, startHour, endHour)
 
 		if inWindow {
-			r.RunOne(ctx)
+			lock, err := r.cache.AcquireJobLock(ctx, "proactive", runLockTTL)
+			if err != nil {
+				logger.Warn("acquire job lock failed", "error", err)
+			} else if lock != nil {
+				r.RunOne(ctx)
+			} else {
+				logger.Info("proactive run skipped, another replica ran recently")
+			}
 			delay := randomDuration(defaultMinInterval, defaultMaxInterval)
 			logger.Info("next proactive run scheduled", "in", delay)
 			select {
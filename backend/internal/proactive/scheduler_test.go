@@ -2,6 +2,13 @@ package proactive
 
 import (
 	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/metrics"
+	"github.com/ThatHunky/gryag/backend/internal/sentiment"
 )
 
 func TestWithinActiveHours(t *testing.T) {
@@ -28,3 +35,148 @@ func TestWithinActiveHours(t *testing.T) {
 		}
 	}
 }
+
+func TestUnhealthyReason(t *testing.T) {
+	cfg := &config.Config{ProactiveMaxErrorRate: 0.2, ProactiveMaxP95LatencyMS: 20000, ProactiveHealthMinCalls: 5}
+	tests := []struct {
+		name  string
+		stats metrics.Stats
+		want  bool
+	}{
+		{"no calls", metrics.Stats{}, false},
+		{"too few calls", metrics.Stats{Count: 3, Errors: 3, ErrorRate: 1}, false},
+		{"healthy", metrics.Stats{Count: 10, Errors: 1, ErrorRate: 0.1, P95Latency: 5 * time.Second}, false},
+		{"error rate", metrics.Stats{Count: 10, Errors: 3, ErrorRate: 0.3}, true},
+		{"slow", metrics.Stats{Count: 10, P95Latency: 25 * time.Second}, true},
+	}
+	for _, tt := range tests {
+		if got := unhealthyReason(tt.stats, cfg) != ""; got != tt.want {
+			t.Errorf("%s: unhealthy = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	off := &config.Config{ProactiveHealthMinCalls: 5}
+	if r := unhealthyReason(metrics.Stats{Count: 10, Errors: 10, ErrorRate: 1, P95Latency: time.Hour}, off); r != "" {
+		t.Errorf("zero thresholds should disable the checks, got %q", r)
+	}
+}
+
+func TestChatWeight(t *testing.T) {
+	now := time.Date(2026, 7, 1, 12, 0, 0, 0, time.UTC)
+	s := &chatsettings.Settings{ProactiveEnabled: true, Timezone: "UTC", ProactiveMinIntervalMinutes: 60, ProactiveMaxIntervalMinutes: 240}
+	tests := []struct {
+		name string
+		last time.Time
+		want float64
+	}{
+		{"never sent", time.Time{}, overdueWeight},
+		{"inside min interval", now.Add(-30 * time.Minute), 0},
+		{"between min and max", now.Add(-2 * time.Hour), 1},
+		{"past max interval", now.Add(-5 * time.Hour), overdueWeight},
+	}
+	for _, tt := range tests {
+		if got := chatWeight(s, now, tt.last); got != tt.want {
+			t.Errorf("%s: weight = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	quiet := *s
+	quiet.ProactiveQuietStart, quiet.ProactiveQuietEnd = 11, 13
+	if w := chatWeight(&quiet, now, time.Time{}); w != 0 {
+		t.Errorf("quiet hours should skip the chat, got %v", w)
+	}
+	off := *s
+	off.ProactiveEnabled = false
+	if w := chatWeight(&off, now, time.Time{}); w != 0 {
+		t.Errorf("opted-out chat should be skipped, got %v", w)
+	}
+}
+
+func TestPickWeighted(t *testing.T) {
+	ids := []int64{1, 2, 3}
+	if _, ok := pickWeighted(ids, []float64{0, 0, 0}, 0.5); ok {
+		t.Error("expected no pick when every weight is 0")
+	}
+	weights := []float64{1, 0, 3}
+	for r, want := range map[float64]int64{0: 1, 0.2: 1, 0.25: 3, 0.99: 3} {
+		if got, _ := pickWeighted(ids, weights, r); got != want {
+			t.Errorf("r=%v: picked %d, want %d", r, got, want)
+		}
+	}
+}
+
+func TestSilenceFactor(t *testing.T) {
+	gap := 10 * time.Minute
+	tests := []struct {
+		silence time.Duration
+		want    float64
+	}{
+		{2 * time.Minute, 0},
+		{10 * time.Minute, 1},
+		{20 * time.Minute, 2},
+		{5 * time.Hour, maxSilenceFactor},
+	}
+	for _, tt := range tests {
+		if got := silenceFactor(tt.silence, gap); got != tt.want {
+			t.Errorf("silence %s: factor = %v, want %v", tt.silence, got, tt.want)
+		}
+	}
+	if got := silenceFactor(time.Minute, 0); got != 1 {
+		t.Errorf("guard off should not change the weight, got %v", got)
+	}
+}
+
+func TestMoodFactor(t *testing.T) {
+	tests := []struct {
+		mood sentiment.Mood
+		want float64
+	}{
+		{sentiment.Mood{}, 1},
+		{sentiment.Mood{Heat: 0.8, Messages: 5}, 0},
+		{sentiment.Mood{Valence: -0.6, Messages: 5}, tenseFactor},
+		{sentiment.Mood{Valence: 0.6, Messages: 5}, 1},
+	}
+	for _, tt := range tests {
+		if got := moodFactor(tt.mood); got != tt.want {
+			t.Errorf("mood %+v (%q): factor = %v, want %v", tt.mood, tt.mood.Label(), got, tt.want)
+		}
+	}
+}
+
+func TestBotRepliesAndRepeats(t *testing.T) {
+	str := func(s string) *string { return &s }
+	messages := []db.Message{
+		{Text: str("first"), IsBotReply: true},
+		{Text: str("hi bot")},
+		{Text: str("second"), IsBotReply: true},
+		{Text: str("third"), IsBotReply: true},
+	}
+	got := botReplies(messages, 2)
+	if len(got) != 2 || got[0] != "second" || got[1] != "third" {
+		t.Errorf("expected the last two bot replies oldest first, got %v", got)
+	}
+	if !isRepeat("  Good  MORNING, chat ", []string{"other", "good morning, chat"}) {
+		t.Error("expected a repeat up to case and spacing")
+	}
+	if isRepeat("good morning", []string{"good morning, chat"}) {
+		t.Error("different texts are not repeats")
+	}
+}
+
+func TestPickTopic(t *testing.T) {
+	joke1 := db.ChatTopic{ID: 1, Kind: db.TopicJoke}
+	joke2 := db.ChatTopic{ID: 2, Kind: db.TopicJoke}
+	follow := db.ChatTopic{ID: 3, Kind: db.TopicFollowUp}
+
+	if got := pickTopic([]db.ChatTopic{joke1, follow, joke2}, 0.9); got == nil || got.ID != 3 {
+		t.Errorf("expected the follow-up to win, got %+v", got)
+	}
+	if got := pickTopic([]db.ChatTopic{joke1, joke2}, 0.5); got != nil {
+		t.Errorf("jokes are only used jokeChance of the time, got %+v", got)
+	}
+	if got := pickTopic([]db.ChatTopic{joke1, joke2}, 0.2); got == nil || got.ID != 2 {
+		t.Errorf("expected the second joke for r=0.2, got %+v", got)
+	}
+	if got := pickTopic(nil, 0); got != nil {
+		t.Errorf("expected no topic, got %+v", got)
+	}
+}
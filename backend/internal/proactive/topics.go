@@ -0,0 +1,43 @@
+package proactive
+
+import (
+	"fmt"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	// topicBoost is how much likelier a chat with due topics is to be picked.
+	topicBoost = 2.0
+	// jokeChance is how often a running joke is used when a chat has no due event or follow-up.
+	jokeChance = 0.3
+)
+
+// pickTopic chooses the topic to build this proactive turn around, or nil. Events and follow-ups
+// (dated ones first, as DueChatTopics orders them) always win; otherwise a running joke is used
+// jokeChance of the time. r is a uniform random number in [0, 1).
+func pickTopic(topics []db.ChatTopic, r float64) *db.ChatTopic {
+	var jokes []db.ChatTopic
+	for i := range topics {
+		if topics[i].Kind != db.TopicJoke {
+			return &topics[i]
+		}
+		jokes = append(jokes, topics[i])
+	}
+	if len(jokes) == 0 || r >= jokeChance {
+		return nil
+	}
+	return &jokes[int(r/jokeChance*float64(len(jokes)))]
+}
+
+// topicLine is the instruction that makes the proactive turn about the topic.
+func topicLine(t *db.ChatTopic) string {
+	switch t.Kind {
+	case db.TopicEvent:
+		return fmt.Sprintf("This turn, bring up this event in the chat: %s. Remind people of it or ask about it, as fits its timing.", t.Text)
+	case db.TopicFollowUp:
+		return fmt.Sprintf("This turn, follow up on this: %s. Ask how it went.", t.Text)
+	default:
+		return fmt.Sprintf("This chat has a running joke: %s. Work it in naturally this turn.", t.Text)
+	}
+}
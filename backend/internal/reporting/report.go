@@ -0,0 +1,47 @@
+// Package reporting builds the weekly activity report (replies, top chats, Gemini usage and
+// spend, new facts) and delivers it to admins' DMs through the proactive queue.
+package reporting
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+// Prices are USD per million tokens.
+type Prices struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// EstimateCost returns the approximate USD spend for the given token counts.
+func EstimateCost(promptTokens, outputTokens int64, p Prices) float64 {
+	return float64(promptTokens)/1e6*p.InputPerMTok + float64(outputTokens)/1e6*p.OutputPerMTok
+}
+
+// Render formats a report as plain text in lang.
+func Render(b *i18n.Bundle, lang string, r *db.ActivityReport, p Prices) string {
+	var sb strings.Builder
+	line := func(s string) {
+		sb.WriteString(s)
+		sb.WriteByte('\n')
+	}
+	line(b.T(lang, "report.title", r.Since.Format("2006-01-02"), r.Until.Format("2006-01-02")))
+	line("")
+	line(b.T(lang, "report.requests", b.TN(lang, "report.replies_sent", r.RepliesSent), b.TN(lang, "report.throttled", r.ThrottledMessages)))
+	line(b.TN(lang, "report.chats", r.ActiveChats))
+	if len(r.TopChats) > 0 {
+		line(b.T(lang, "report.top_chats"))
+		for _, c := range r.TopChats {
+			line(b.T(lang, "report.top_chat_line", strconv.FormatInt(c.ChatID, 10), b.TN(lang, "report.replies", c.Replies), b.TN(lang, "report.messages", c.Messages)))
+		}
+	}
+	line(b.T(lang, "report.llm", b.TN(lang, "report.llm_calls", r.LLMCalls), b.TN(lang, "report.llm_errors", r.LLMErrors)))
+	line(b.T(lang, "report.tokens",
+		strconv.FormatInt(r.PromptTokens, 10), strconv.FormatInt(r.OutputTokens, 10),
+		strconv.FormatFloat(EstimateCost(r.PromptTokens, r.OutputTokens, p), 'f', 2, 64)))
+	line(b.TN(lang, "report.facts", r.NewFacts))
+	return strings.TrimRight(sb.String(), "\n")
+}
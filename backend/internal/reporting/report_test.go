@@ -0,0 +1,82 @@
+package reporting
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+func TestEstimateCost(t *testing.T) {
+	got := EstimateCost(2_000_000, 400_000, Prices{InputPerMTok: 0.30, OutputPerMTok: 2.50})
+	if math.Abs(got-1.6) > 1e-9 {
+		t.Errorf("expected 1.60, got %f", got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{
+		"report.title": "Report {0} - {1}",
+		"report.requests": "{0}; {1}",
+		"report.replies_sent": {"one": "{0} reply", "other": "{0} replies"},
+		"report.throttled": {"one": "{0} throttled", "other": "{0} throttled"},
+		"report.chats": {"one": "{0} chat", "other": "{0} chats"},
+		"report.top_chats": "top:",
+		"report.top_chat_line": "{0}: {1}/{2}",
+		"report.replies": {"other": "{0}r"},
+		"report.messages": {"other": "{0}m"},
+		"report.llm": "{0}; {1}",
+		"report.llm_calls": {"one": "{0} call", "other": "{0} calls"},
+		"report.llm_errors": {"one": "{0} error", "other": "{0} errors"},
+		"report.tokens": "tokens {0}/{1} ${2}",
+		"report.facts": {"one": "{0} fact", "other": "{0} facts"}
+	}`), 0644)
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(`{
+		"report.facts": {"one": "{0} новий факт", "few": "{0} нові факти", "many": "{0} нових фактів"}
+	}`), 0644)
+	bundle, err := i18n.NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	until := time.Date(2026, 3, 9, 10, 0, 0, 0, time.UTC)
+	r := &db.ActivityReport{
+		Since: until.Add(-Period), Until: until,
+		RepliesSent: 120, ThrottledMessages: 4, ActiveChats: 1,
+		TopChats: []db.ChatActivity{{ChatID: -100, Messages: 500, Replies: 80}},
+		LLMCalls: 150, LLMErrors: 1,
+		PromptTokens: 1_000_000, OutputTokens: 100_000,
+		NewFacts: 7,
+	}
+	got := Render(bundle, "en", r, Prices{InputPerMTok: 0.30, OutputPerMTok: 2.50})
+	for _, want := range []string{
+		"Report 2026-03-02 - 2026-03-09",
+		"120 replies; 4 throttled",
+		"1 chat\n",
+		"top:\n-100: 80r/500m",
+		"150 calls; 1 error",
+		"tokens 1000000/100000 $0.55",
+		"7 facts",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+
+	for n, want := range map[int]string{1: "1 новий факт", 3: "3 нові факти", 7: "7 нових фактів", 11: "11 нових фактів", 21: "21 новий факт"} {
+		r.NewFacts = n
+		if got := Render(bundle, "uk", r, Prices{}); !strings.Contains(got, want) {
+			t.Errorf("uk report missing %q:\n%s", want, got)
+		}
+	}
+
+	r.TopChats = nil
+	if got := Render(bundle, "en", r, Prices{}); strings.Contains(got, "top:") {
+		t.Errorf("empty top chats should be omitted:\n%s", got)
+	}
+}
@@ -0,0 +1,85 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	lastRunKey = "activity_report:last_run"
+
+	// Period is the window each report covers.
+	Period = 7 * 24 * time.Hour
+)
+
+// Runner assembles the report and queues it for every admin.
+type Runner struct {
+	db     *db.DB
+	cache  *cache.Cache
+	bundle *i18n.Bundle
+	config *config.Config
+}
+
+// NewRunner creates an activity report runner.
+func NewRunner(database *db.DB, c *cache.Cache, bundle *i18n.Bundle, cfg *config.Config) *Runner {
+	return &Runner{db: database, cache: c, bundle: bundle, config: cfg}
+}
+
+// Prices returns the configured token prices.
+func (r *Runner) Prices() Prices {
+	return Prices{InputPerMTok: r.config.LLMPriceInputPerMTok, OutputPerMTok: r.config.LLMPriceOutputPerMTok}
+}
+
+// Build collects the report for the period ending at until.
+func (r *Runner) Build(ctx context.Context, until time.Time, period time.Duration) (*db.ActivityReport, error) {
+	return r.db.GetActivityReport(ctx, until.Add(-period), until, r.config.ActivityReportTopChats)
+}
+
+// RunOnce builds the last week's report and pushes it to each admin's DM.
+func (r *Runner) RunOnce(ctx context.Context) {
+	ctx = logging.With(ctx, "component", "activity_report")
+	if len(r.config.AdminIDs) == 0 {
+		slog.WarnContext(ctx, "no ADMIN_IDS configured; activity report skipped")
+		return
+	}
+	report, err := r.Build(ctx, time.Now(), Period)
+	if err != nil {
+		slog.ErrorContext(ctx, "build activity report failed", "error", err)
+		return
+	}
+	text := Render(r.bundle, r.config.DefaultLang, report, r.Prices())
+	for _, adminID := range r.config.AdminIDs {
+		// A user's private chat ID equals their user ID.
+		if err := r.cache.PushProactive(ctx, cache.ProactiveItem{Source: cache.SourceActivityReport, ChatID: adminID, Reply: text}); err != nil {
+			slog.ErrorContext(ctx, "queue activity report failed", "admin_id", adminID, "error", err)
+		}
+	}
+	slog.InfoContext(ctx, "activity report queued", "admins", len(r.config.AdminIDs), "replies", report.RepliesSent, "llm_calls", report.LLMCalls)
+}
+
+// SetLastRun records the current time as the last sent report.
+func (r *Runner) SetLastRun(ctx context.Context) error {
+	return r.cache.Client().Set(ctx, lastRunKey, time.Now().Unix(), 0).Err()
+}
+
+// GetLastRun returns the Unix time of the last run (0 if never run).
+func (r *Runner) GetLastRun(ctx context.Context) (int64, error) {
+	val, err := r.cache.Client().Get(ctx, lastRunKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
@@ -0,0 +1,65 @@
+package reporting
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+const pollInterval = 1 * time.Minute
+
+// minRunGap keeps the report to one per week even if the run hour is seen twice (restart, DST).
+const minRunGap = 6 * 24 * time.Hour
+
+// jobLockTTL bounds how long one replica holds the run.
+const jobLockTTL = 30 * time.Minute
+
+// Scheduler sends the activity report once a week on ActivityReportWeekday at ActivityReportHour (Kyiv).
+func Scheduler(ctx context.Context, r *Runner, cfg *config.Config) {
+	logger := slog.With("component", "activity_report_scheduler")
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		kyiv, err = time.LoadLocation("Europe/Kiev")
+		if err != nil {
+			logger.Error("could not load Kyiv timezone", "error", err)
+			return
+		}
+	}
+	weekday := time.Weekday(cfg.ActivityReportWeekday % 7)
+	runHour := cfg.ActivityReportHour
+	if runHour < 0 || runHour > 23 {
+		runHour = 10
+	}
+
+	for {
+		now := time.Now().In(kyiv)
+		if now.Weekday() == weekday && now.Hour() == runHour {
+			// With several replicas only the lock holder checks and sends; the others see the new last run.
+			lock, err := r.cache.AcquireJobLock(ctx, "activity_report", jobLockTTL)
+			if err != nil {
+				logger.Warn("acquire job lock failed", "error", err)
+			} else if lock != nil {
+				last, err := r.GetLastRun(ctx)
+				if err != nil {
+					logger.Warn("get last run failed", "error", err)
+				} else if last == 0 || now.Sub(time.Unix(last, 0)) >= minRunGap {
+					logger.Info("sending activity report")
+					r.RunOnce(ctx)
+					_ = r.SetLastRun(ctx)
+				}
+				if err := r.cache.ReleaseJobLock(ctx, lock); err != nil {
+					logger.Warn("release job lock failed", "error", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+			continue
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Package retention runs the daily data retention job: delete messages past their chat's
+// retention, old chat summaries, old tool call audit rows and expired media cache entries.
+package retention
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"github.com/redis/go-redis/v9"
+)
+
+const lastRunKey = "retention:last_run"
+
+// Runner performs one retention pass.
+type Runner struct {
+	db      *db.DB
+	cache   *cache.Cache
+	config  *config.Config
+	archive db.MessageArchiver // optional; expiring messages are archived before deletion
+}
+
+// NewRunner creates a retention runner.
+func NewRunner(database *db.DB, c *cache.Cache, cfg *config.Config) *Runner {
+	return &Runner{db: database, cache: c, config: cfg}
+}
+
+// SetArchive makes retention archive messages before deleting them.
+func (r *Runner) SetArchive(a db.MessageArchiver) {
+	r.archive = a
+}
+
+// RunOnce prunes (or archives) messages (per-chat message_retention_days, else MessageRetentionDays),
+// summaries older than SummaryRetentionDays, tool calls older than ToolCallRetentionDays,
+// proactive deliveries and follow-ups on the message schedule and expired media. Each step is best effort.
+func (r *Runner) RunOnce(ctx context.Context) {
+	ctx = logging.With(ctx, "component", "retention")
+
+	var messages int64
+	var err error
+	if r.archive != nil {
+		messages, err = r.db.ArchiveOldMessages(ctx, r.config.MessageRetentionDays, r.archive)
+	} else {
+		messages, err = r.db.PruneOldMessages(ctx, r.config.MessageRetentionDays)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "message retention failed", "error", err)
+	}
+	summaries, err := r.db.PruneOldSummaries(ctx, r.config.SummaryRetentionDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "summary retention failed", "error", err)
+	}
+	toolCalls, err := r.db.PruneOldToolCalls(ctx, r.config.ToolCallRetentionDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "tool call retention failed", "error", err)
+	}
+	deliveries, err := r.db.PruneOldProactiveDeliveries(ctx, r.config.MessageRetentionDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "proactive delivery retention failed", "error", err)
+	}
+	followUps, err := r.db.PruneOldFollowUps(ctx, r.config.MessageRetentionDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "follow-up retention failed", "error", err)
+	}
+	topics, err := r.db.PruneOldTopicSegments(ctx, r.config.MessageRetentionDays)
+	if err != nil {
+		slog.ErrorContext(ctx, "topic index retention failed", "error", err)
+	}
+	media, err := r.db.PruneExpiredMediaCache(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "media cache retention failed", "error", err)
+	}
+	slog.InfoContext(ctx, "retention finished", "messages", messages, "summaries", summaries, "tool_calls", toolCalls, "proactive_deliveries", deliveries, "follow_ups", followUps, "topic_segments", topics, "media", media)
+}
+
+// SetLastRun records the current time as the last completed retention run.
+func (r *Runner) SetLastRun(ctx context.Context) error {
+	return r.cache.Client().Set(ctx, lastRunKey, time.Now().Unix(), 0).Err()
+}
+
+// GetLastRun returns the Unix time of the last run (0 if never run).
+func (r *Runner) GetLastRun(ctx context.Context) (int64, error) {
+	val, err := r.cache.Client().Get(ctx, lastRunKey).Result()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
@@ -0,0 +1,72 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+)
+
+const pollInterval = 1 * time.Minute
+
+// minRunGap keeps the job to one run per day even if the run hour is seen twice (restart, DST).
+const minRunGap = 20 * time.Hour
+
+// overdueAfter runs the job outside its hour when the last run is this old (first start, or the
+// process was down at the run hour).
+const overdueAfter = 36 * time.Hour
+
+// jobLockTTL bounds how long one replica holds the run.
+const jobLockTTL = time.Hour
+
+// due reports whether a run should start at now, given the last run (Unix seconds, 0 = never).
+func due(now time.Time, last int64, runHour int) bool {
+	if last == 0 {
+		return true
+	}
+	since := now.Sub(time.Unix(last, 0))
+	return since >= overdueAfter || (now.Hour() == runHour && since >= minRunGap)
+}
+
+// Scheduler runs retention once a day at RetentionRunHour (Kyiv), and right away when it is overdue.
+func Scheduler(ctx context.Context, r *Runner, cfg *config.Config) {
+	logger := slog.With("component", "retention_scheduler")
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		kyiv, err = time.LoadLocation("Europe/Kiev")
+		if err != nil {
+			logger.Error("could not load Kyiv timezone", "error", err)
+			return
+		}
+	}
+
+	for {
+		now := time.Now().In(kyiv)
+		if last, err := r.GetLastRun(ctx); err != nil {
+			logger.Warn("get last run failed", "error", err)
+		} else if due(now, last, cfg.RetentionRunHour) {
+			// With several replicas only the lock holder re-checks and runs.
+			lock, err := r.cache.AcquireJobLock(ctx, "retention", jobLockTTL)
+			if err != nil {
+				logger.Warn("acquire job lock failed", "error", err)
+			} else if lock != nil {
+				if last, err := r.GetLastRun(ctx); err == nil && due(now, last, cfg.RetentionRunHour) {
+					logger.Info("running retention")
+					r.RunOnce(ctx)
+					_ = r.SetLastRun(ctx)
+				}
+				if err := r.cache.ReleaseJobLock(ctx, lock); err != nil {
+					logger.Warn("release job lock failed", "error", err)
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+			continue
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package retention
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDue(t *testing.T) {
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 10, 0, 0, kyiv) }
+	const runHour = 5
+
+	cases := []struct {
+		name string
+		now  time.Time
+		last int64
+		want bool
+	}{
+		{"never ran", at(10, 14), 0, true},
+		{"run hour, ran yesterday", at(10, 5), at(9, 5).Unix(), true},
+		{"run hour, already ran today", at(10, 5), at(10, 5).Add(-5 * time.Minute).Unix(), false},
+		{"other hour, ran yesterday", at(10, 14), at(9, 5).Unix(), false},
+		{"other hour, overdue", at(10, 18), at(9, 5).Unix(), true},
+	}
+	for _, c := range cases {
+		if got := due(c.now, c.last, runHour); got != c.want {
+			t.Errorf("%s: due = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package scrub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Handler wraps another slog.Handler and scrubs the message and the string and error attributes
+// of each record (groups included) before passing it on. Numbers, such as chat and user IDs, are
+// left as they are.
+type Handler struct {
+	inner slog.Handler
+	s     *Scrubber
+}
+
+// NewHandler wraps inner; install it under logging.Handler so scoped attributes are scrubbed too.
+func NewHandler(inner slog.Handler, s *Scrubber) *Handler {
+	return &Handler{inner: inner, s: s}
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.s.Scrub(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, out)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	scrubbed := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		scrubbed[i] = h.attr(a)
+	}
+	return &Handler{inner: h.inner.WithAttrs(scrubbed), s: h.s}
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{inner: h.inner.WithGroup(name), s: h.s}
+}
+
+func (h *Handler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.s.Scrub(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		scrubbed := make([]slog.Attr, len(group))
+		for i, g := range group {
+			scrubbed[i] = h.attr(g)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(scrubbed...)}
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.s.Scrub(err.Error()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
@@ -0,0 +1,99 @@
+// Package scrub masks personal data (emails, phone numbers, payment card numbers and
+// operator-defined patterns) in log output and stored message text.
+package scrub
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Masks that replace what a rule matched.
+const (
+	MaskEmail    = "[email]"
+	MaskPhone    = "[phone]"
+	MaskCard     = "[card]"
+	MaskRedacted = "[redacted]" // operator-defined patterns
+)
+
+// rule masks the matches of re that pass check (nil = all of them).
+type rule struct {
+	re    *regexp.Regexp
+	mask  string
+	check func(match string) bool
+}
+
+// Built-in rules, in the order they apply: cards before phones, whose digit runs they contain.
+var builtin = []rule{
+	{re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`), mask: MaskEmail},
+	// 13-19 digits, optionally grouped by spaces or dashes, passing the Luhn check
+	{re: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`), mask: MaskCard, check: luhn},
+	// International numbers with a leading +, and the common local formats: 0XX XXX XX XX
+	// (Ukraine) and XXX-XXX-XXXX. Bare digit runs are left alone: they are usually IDs.
+	{re: regexp.MustCompile(`\+\d(?:[ ()-]{0,2}\d){7,14}\b`), mask: MaskPhone},
+	{re: regexp.MustCompile(`\(?\b0\d{2}\)?[ -]?\d{3}[ -]?\d{2}[ -]?\d{2}\b`), mask: MaskPhone},
+	{re: regexp.MustCompile(`\b\d{3}[ .-]\d{3}[ .-]\d{4}\b`), mask: MaskPhone},
+}
+
+// Scrubber masks personal data in text. A nil *Scrubber leaves text unchanged.
+type Scrubber struct {
+	rules []rule
+}
+
+// New returns a Scrubber with the built-in rules plus patterns (regular expressions, masked as
+// MaskRedacted).
+func New(patterns []string) (*Scrubber, error) {
+	s := &Scrubber{rules: append([]rule{}, builtin...)}
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("scrub pattern %q: %w", p, err)
+		}
+		s.rules = append(s.rules, rule{re: re, mask: MaskRedacted})
+	}
+	return s, nil
+}
+
+// Scrub returns text with every match masked.
+func (s *Scrubber) Scrub(text string) string {
+	if s == nil || text == "" {
+		return text
+	}
+	for _, r := range s.rules {
+		text = r.re.ReplaceAllStringFunc(text, func(m string) string {
+			if r.check != nil && !r.check(m) {
+				return m
+			}
+			return r.mask
+		})
+	}
+	return text
+}
+
+// ScrubPtr scrubs a stored text field; nil stays nil.
+func (s *Scrubber) ScrubPtr(text *string) *string {
+	if s == nil || text == nil {
+		return text
+	}
+	out := s.Scrub(*text)
+	return &out
+}
+
+// luhn reports whether the digits in s pass the Luhn checksum of payment card numbers.
+func luhn(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
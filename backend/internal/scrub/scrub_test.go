@@ -0,0 +1,58 @@
+package scrub
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestScrub(t *testing.T) {
+	s, err := New([]string{`ІПН\s*\d{10}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ in, want string }{
+		{"пиши на oleg.k+bot@mail.example.com", "пиши на [email]"},
+		{"картка 4111 1111 1111 1111, дякую", "картка [card], дякую"},
+		{"card 5500-0000-0000-0004", "card [card]"},
+		{"не картка 4111 1111 1111 1112", "не картка 4111 1111 1111 1112"},
+		{"дзвони +380 67 123 45 67 або 067 123 45 67", "дзвони [phone] або [phone]"},
+		{"call (067) 123-45-67 or 212-555-0123", "call [phone] or [phone]"},
+		{"user 392817811 in chat -1002604868951, 2024 year", "user 392817811 in chat -1002604868951, 2024 year"},
+		{"мій ІПН 1234567890", "мій [redacted]"},
+		{"@oleg_k says hi", "@oleg_k says hi"},
+	}
+	for _, tt := range tests {
+		if got := s.Scrub(tt.in); got != tt.want {
+			t.Errorf("Scrub(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+	var none *Scrubber
+	if got := none.Scrub("a@b.com"); got != "a@b.com" {
+		t.Errorf("nil Scrubber changed text: %q", got)
+	}
+	if _, err := New([]string{"("}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, _ := New(nil)
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(slog.NewJSONHandler(&buf, nil), s)).With("contact", "a@b.com")
+	logger.Info("mail from a@b.com", "user_id", 392817811, "error", errors.New("bad card 4111111111111111"),
+		slog.Group("req", "text", "call +380671234567"))
+	out := buf.String()
+	for _, leak := range []string{"a@b.com", "4111111111111111", "+380671234567"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("log line leaks %q: %s", leak, out)
+		}
+	}
+	for _, want := range []string{`"msg":"mail from [email]"`, `"user_id":392817811`, `"contact":"[email]"`, `"text":"call [phone]"`, `"error":"bad card [card]"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log line missing %s: %s", want, out)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package sentiment
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+)
+
+// Mood labels, from the most to the least careful.
+const (
+	Heated   = "heated"   // an argument or shouting match: don't joke, don't take sides
+	Tense    = "tense"    // irritated or gloomy: keep jokes gentle
+	Cheerful = "cheerful" // playful and upbeat
+	Neutral  = ""
+)
+
+const (
+	// moodAlpha is how much each new message moves the rolling mood.
+	moodAlpha = 0.25
+	// moodHalfLife is how fast the mood fades back to neutral while the chat is quiet.
+	moodHalfLife = 30 * time.Minute
+	// minMoodMessages is how many scored messages a mood needs before it gets a label.
+	minMoodMessages = 3
+	// heatedAt, tenseHeatAt, tenseValenceAt and cheerfulAt are the label thresholds.
+	heatedAt       = 0.45
+	tenseHeatAt    = 0.25
+	tenseValenceAt = -0.3
+	cheerfulAt     = 0.3
+	// moodTTL drops the mood of a chat nobody wrote in for a day; it has faded by then anyway.
+	moodTTL = 24 * time.Hour
+)
+
+// Mood is a chat's rolling tone: an exponential moving average of its messages' scores that
+// fades toward neutral over time.
+type Mood struct {
+	Valence   float64   `json:"valence"`
+	Heat      float64   `json:"heat"`
+	Messages  int       `json:"messages"` // scored messages folded in so far
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// At returns the mood as of now, faded by the time since its last update.
+func (m Mood) At(now time.Time) Mood {
+	if m.UpdatedAt.IsZero() || !now.After(m.UpdatedAt) {
+		return m
+	}
+	keep := math.Pow(0.5, float64(now.Sub(m.UpdatedAt))/float64(moodHalfLife))
+	m.Valence *= keep
+	m.Heat *= keep
+	m.UpdatedAt = now
+	return m
+}
+
+// Add folds a message scored at into the mood.
+func (m Mood) Add(s Score, at time.Time) Mood {
+	m = m.At(at)
+	m.Valence += moodAlpha * (s.Valence - m.Valence)
+	m.Heat += moodAlpha * (s.Heat - m.Heat)
+	m.Messages++
+	if at.After(m.UpdatedAt) {
+		m.UpdatedAt = at
+	}
+	return m
+}
+
+// Label names the mood (Heated, Tense, Cheerful), or Neutral when there is too little to go on.
+func (m Mood) Label() string {
+	switch {
+	case m.Messages < minMoodMessages:
+		return Neutral
+	case m.Heat >= heatedAt:
+		return Heated
+	case m.Heat >= tenseHeatAt || m.Valence <= tenseValenceAt:
+		return Tense
+	case m.Valence >= cheerfulAt:
+		return Cheerful
+	}
+	return Neutral
+}
+
+func moodKey(chatID int64) string {
+	return fmt.Sprintf("mood:chat:%d", chatID)
+}
+
+// Load returns a chat's mood as of now; the zero Mood when there is none (or no cache).
+func Load(ctx context.Context, c *cache.Cache, chatID int64, now time.Time) (Mood, error) {
+	var m Mood
+	if c == nil {
+		return m, nil
+	}
+	if _, err := c.GetJSON(ctx, moodKey(chatID), &m); err != nil {
+		return Mood{}, err
+	}
+	return m.At(now), nil
+}
+
+// Record folds a message's score into its chat's mood. Concurrent messages may overwrite each
+// other's update; the mood is a rough signal and one lost message doesn't change it much.
+func Record(ctx context.Context, c *cache.Cache, chatID int64, s Score, at time.Time) error {
+	if c == nil {
+		return nil
+	}
+	var m Mood
+	if _, err := c.GetJSON(ctx, moodKey(chatID), &m); err != nil {
+		return err
+	}
+	return c.SetJSON(ctx, moodKey(chatID), m.Add(s, at), moodTTL)
+}
@@ -0,0 +1,149 @@
+// Package sentiment scores the tone of chat messages with a small rule-based lexicon (Ukrainian,
+// Russian and English) and keeps a rolling mood per chat, so replies and proactive messages can
+// read the room.
+package sentiment
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Score is the tone of one message.
+type Score struct {
+	Valence float64 // -1 (negative) .. 1 (positive); 0 = neutral or no signal
+	Heat    float64 // 0 (calm) .. 1 (heated: insults, swearing, shouting)
+}
+
+const (
+	// valenceScale is how many net sentiment words make a message fully positive or negative.
+	valenceScale = 3.0
+	// insultHeat, swearHeat, shoutHeat, exclaimHeat and angryEmojiHeat are what each signal adds
+	// to a message's heat.
+	insultHeat     = 0.4
+	swearHeat      = 0.2
+	shoutHeat      = 0.3
+	exclaimHeat    = 0.2
+	angryEmojiHeat = 0.3
+	// minShoutLetters is how many letters a message needs before all caps counts as shouting.
+	minShoutLetters = 6
+)
+
+// Word stems, matched at the start of a lowercased word. Short or ambiguous words are in the
+// exact-match sets instead so "сума" doesn't read as "сумно".
+var (
+	positiveStems = []string{
+		"дяку", "спасиб", "класн", "супер", "круто", "крутий", "чудов", "прекрасн", "гарн", "люблю", "любим",
+		"радий", "радію", "вітаю", "молодець", "молодц", "красав", "кайф", "ахах", "хаха", "хех",
+		"thank", "great", "awesome", "amazing", "love", "nice", "cool", "glad", "congrat", "haha", "lmao",
+	}
+	positiveWords = set("клас", "топ", "лол", "кльово", "ура", "рад", "рада", "yay", "lol", "good", "fun", "wow")
+
+	negativeStems = []string{
+		"поган", "жах", "сумн", "сумую", "нудн", "втомив", "втомил", "бісит", "дратує", "дратуєш",
+		"ненавид", "розчаров", "засмуч", "плохо", "ужас", "грустн", "бесит", "ненавиж", "надоел",
+		"terrible", "awful", "horrible", "hate", "annoy", "disappoint", "tired", "boring", "worst",
+	}
+	negativeWords = set("sad", "bad", "ugh", "нажаль", "шкода", "біда", "капець", "нудно")
+
+	// insultStems count as negative and heated.
+	insultStems = []string{
+		"дурн", "дурак", "дура", "ідіот", "идиот", "дебіл", "дебил", "придур", "тупий", "тупа", "тупой",
+		"кретин", "клоун", "заткн", "замовкн", "лох", "чмо", "мудак", "мудил", "виродок",
+		"idiot", "stupid", "moron", "dumb", "loser", "clown",
+	}
+	// swearStems add heat without changing valence: friends swear at each other happily too.
+	swearStems = []string{
+		"бля", "хуй", "хуе", "хує", "пізд", "пизд", "сука", "сучк", "єбан", "ебан", "йобан", "нахер",
+		"fuck", "shit", "damn", "wtf",
+	}
+)
+
+var (
+	positiveEmoji = []string{"😂", "🤣", "😄", "😁", "😊", "😍", "🥰", "❤", "👍", "🔥", "🎉", "😎", "🙂", "😃"}
+	negativeEmoji = []string{"😢", "😭", "😞", "😔", "💔", "😩", "😒", "👎", "🙁"}
+	angryEmoji    = []string{"😡", "🤬", "👿", "😤", "💢", "🖕"}
+)
+
+func set(words ...string) map[string]bool {
+	m := make(map[string]bool, len(words))
+	for _, w := range words {
+		m[w] = true
+	}
+	return m
+}
+
+func hasStem(word string, stems []string) bool {
+	for _, s := range stems {
+		if strings.HasPrefix(word, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func countEmoji(text string, emoji []string) int {
+	n := 0
+	for _, e := range emoji {
+		n += strings.Count(text, e)
+	}
+	return n
+}
+
+// Analyze scores text. Text without any signal (plain statements, links, empty) is neutral.
+func Analyze(text string) Score {
+	var pos, neg, insults, swears float64
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	}) {
+		switch {
+		case hasStem(word, insultStems):
+			insults++
+		case hasStem(word, swearStems):
+			swears++
+		case positiveWords[word] || hasStem(word, positiveStems):
+			pos++
+		case negativeWords[word] || hasStem(word, negativeStems):
+			neg++
+		}
+	}
+	pos += float64(countEmoji(text, positiveEmoji))
+	neg += float64(countEmoji(text, negativeEmoji))
+	angry := float64(countEmoji(text, angryEmoji))
+
+	heat := insults*insultHeat + swears*swearHeat + angry*angryEmojiHeat
+	if isShouting(text) {
+		heat += shoutHeat
+	}
+	if strings.Contains(text, "!!") || strings.Contains(text, "?!") {
+		heat += exclaimHeat
+	}
+	return Score{
+		Valence: clamp((pos-neg-insults-angry)/valenceScale, -1, 1),
+		Heat:    clamp(heat, 0, 1),
+	}
+}
+
+// isShouting reports whether text is long enough and written in all caps.
+func isShouting(text string) bool {
+	letters, upper := 0, 0
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		if unicode.IsUpper(r) {
+			upper++
+		}
+	}
+	return letters >= minShoutLetters && upper == letters
+}
+
+func clamp(v, lo, hi float64) float64 {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,72 @@
+package sentiment
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		positive      bool // valence > 0
+		negative      bool // valence < 0
+		heatedAtLeast float64
+		calmerThan    float64
+	}{
+		{name: "neutral", text: "завтра о сьомій біля метро", calmerThan: 0.01},
+		{name: "sum is not sad", text: "сума 200 гривень", calmerThan: 0.01},
+		{name: "thanks", text: "дякую, це супер 😂", positive: true, calmerThan: 0.01},
+		{name: "english", text: "thanks, that's awesome", positive: true, calmerThan: 0.01},
+		{name: "gloomy", text: "сумно і нудно, все погано", negative: true, calmerThan: 0.01},
+		{name: "insult", text: "ти ідіот, заткнись!!", negative: true, heatedAtLeast: 0.8},
+		{name: "shouting", text: "ЧОМУ НІХТО НЕ ВІДПОВІДАЄ", heatedAtLeast: 0.3},
+		{name: "angry emoji", text: "ну звісно 🤬", negative: true, heatedAtLeast: 0.3},
+		{name: "friendly swearing", text: "бля, ахаха, клас", positive: true, heatedAtLeast: 0.2},
+	}
+	for _, tt := range tests {
+		s := Analyze(tt.text)
+		if tt.positive != (s.Valence > 0) || tt.negative != (s.Valence < 0) {
+			t.Errorf("%s: valence = %v, want positive=%v negative=%v", tt.name, s.Valence, tt.positive, tt.negative)
+		}
+		if s.Heat < tt.heatedAtLeast {
+			t.Errorf("%s: heat = %v, want at least %v", tt.name, s.Heat, tt.heatedAtLeast)
+		}
+		if tt.calmerThan > 0 && s.Heat >= tt.calmerThan {
+			t.Errorf("%s: heat = %v, want under %v", tt.name, s.Heat, tt.calmerThan)
+		}
+		if s.Valence < -1 || s.Valence > 1 || s.Heat < 0 || s.Heat > 1 {
+			t.Errorf("%s: score out of range: %+v", tt.name, s)
+		}
+	}
+}
+
+func TestMood(t *testing.T) {
+	start := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	var m Mood
+	hot := Analyze("ти ідіот, заткнись!!")
+	for i := 0; i < 2; i++ {
+		m = m.Add(hot, start.Add(time.Duration(i)*time.Minute))
+	}
+	if m.Label() != Neutral {
+		t.Errorf("two messages are too few for a label, got %q", m.Label())
+	}
+	for i := 2; i < 6; i++ {
+		m = m.Add(hot, start.Add(time.Duration(i)*time.Minute))
+	}
+	if m.Label() != Heated {
+		t.Fatalf("expected a heated mood after an argument, got %q (%+v)", m.Label(), m)
+	}
+	if got := m.At(start.Add(2 * time.Hour)); got.Label() != Neutral {
+		t.Errorf("the mood should fade after two quiet hours, got %q (%+v)", got.Label(), got)
+	}
+
+	// Friendly messages cool a heated chat down
+	calm := Analyze("дякую, все клас 😊")
+	for i := 0; i < 10; i++ {
+		m = m.Add(calm, start.Add(time.Duration(6+i)*time.Minute))
+	}
+	if m.Label() != Cheerful {
+		t.Errorf("expected a cheerful mood after friendly messages, got %q (%+v)", m.Label(), m)
+	}
+}
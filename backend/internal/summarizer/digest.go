@@ -0,0 +1,142 @@
+package summarizer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+const (
+	// digestSentKey marks a chat's digest for one Kyiv date as sent, so restarts don't repeat it.
+	digestSentKey = "digest:sent:%d:%s"
+	digestSentTTL = 36 * time.Hour
+	digestWindow  = 24 * time.Hour
+)
+
+// DigestRunner pushes the morning digest (a summary of the last 24 hours) to opted-in chats and
+// the weekly personal digest to opted-in users.
+type DigestRunner struct {
+	*Runner
+	bundle *i18n.Bundle
+}
+
+// NewDigestRunner wraps a summarizer runner; bundle localizes the digest title.
+func NewDigestRunner(r *Runner, bundle *i18n.Bundle) *DigestRunner {
+	return &DigestRunner{Runner: r, bundle: bundle}
+}
+
+// RunDigests sends the digest to every chat with digest_enabled whose digest_hour (Kyiv) is now's
+// hour. Only chats with stored settings can have opted in, so those are the candidates.
+func (d *DigestRunner) RunDigests(ctx context.Context, now time.Time) {
+	ctx = logging.With(ctx, "component", "digest")
+	overrides, err := d.db.ListChatSettings(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list chat settings", "error", err)
+		return
+	}
+	sent := 0
+	for _, o := range overrides {
+		if o.DigestEnabled == nil || !*o.DigestEnabled {
+			continue
+		}
+		cs := d.chatSettings(ctx, o.ChatID)
+		if !cs.DigestEnabled || cs.DigestHour != now.Hour() {
+			continue
+		}
+		if d.sendDigest(logging.WithChat(ctx, o.ChatID, 0), o.ChatID, now) {
+			sent++
+		}
+	}
+	slog.InfoContext(ctx, "daily digest finished", "hour", now.Hour(), "sent", sent)
+}
+
+// sendDigest summarizes the chat's last 24 hours (all topics) and queues it. Returns whether a
+// digest was queued.
+func (d *DigestRunner) sendDigest(ctx context.Context, chatID int64, now time.Time) bool {
+	ok, err := d.cache.Client().SetNX(ctx, fmt.Sprintf(digestSentKey, chatID, now.Format("2006-01-02")), 1, digestSentTTL).Result()
+	if err != nil {
+		slog.WarnContext(ctx, "digest dedupe check failed", "error", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	limit := d.config.SummaryMaxMessagesPerWindow
+	if limit <= 0 {
+		limit = 2000
+	}
+	messages, err := d.db.GetMessagesInRange(ctx, chatID, db.AllThreads, now.Add(-digestWindow), now, limit)
+	if err != nil {
+		slog.ErrorContext(ctx, "get messages in range failed", "error", err)
+		return false
+	}
+	if len(messages) < max(d.config.DailyDigestMinMessages, 1) {
+		slog.InfoContext(ctx, "digest skipped, chat too quiet", "messages", len(messages))
+		return false
+	}
+	cs := d.chatSettings(ctx, chatID)
+	summary, err := d.llm.SummarizeChat(ctx, messages, "24-hour", d.summaryOptions(ctx, db.ChatThread{ChatID: chatID, ThreadID: db.AllThreads}, cs, messages))
+	if err != nil {
+		slog.ErrorContext(ctx, "summarize digest failed", "error", err)
+		return false
+	}
+	if summary == "" {
+		return false
+	}
+	text := digestText(d.bundle, cs.Language, now, summary)
+	if err := d.cache.PushProactive(ctx, cache.ProactiveItem{Source: cache.SourceDigest, ChatID: chatID, Reply: text}); err != nil {
+		slog.ErrorContext(ctx, "push digest failed", "error", err)
+		return false
+	}
+	slog.InfoContext(ctx, "digest queued", "messages", len(messages))
+	return true
+}
+
+// digestText prepends the localized title to the summary.
+func digestText(b *i18n.Bundle, lang string, now time.Time, summary string) string {
+	return b.T(lang, "digest.title", now.Format("2006-01-02")) + "\n\n" + summary
+}
+
+// DigestScheduler checks once per Kyiv hour which chats get their morning digest (see RunDigests,
+// with ENABLE_DAILY_DIGEST) and whose weekly personal digest is due (see RunPersonalDigests,
+// with ENABLE_PERSONAL_DIGEST).
+func DigestScheduler(ctx context.Context, d *DigestRunner, cfg *config.Config) {
+	logger := slog.With("component", "digest_scheduler")
+	kyiv, err := time.LoadLocation("Europe/Kyiv")
+	if err != nil {
+		kyiv, err = time.LoadLocation("Europe/Kiev")
+		if err != nil {
+			logger.Error("could not load Kyiv timezone", "error", err)
+			return
+		}
+	}
+
+	lastHour := -1
+	for {
+		now := time.Now().In(kyiv)
+		// Sends are deduplicated per chat and date, so re-running an hour after a restart is harmless.
+		if now.Hour() != lastHour {
+			lastHour = now.Hour()
+			if cfg.EnableDailyDigest {
+				runHourOnce(ctx, d.cache, "digest", now, func() { d.RunDigests(ctx, now) })
+			}
+			if cfg.EnablePersonalDigest {
+				runHourOnce(ctx, d.cache, "personal_digest", now, func() { d.RunPersonalDigests(ctx, now) })
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+			continue
+		}
+	}
+}
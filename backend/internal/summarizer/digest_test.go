@@ -0,0 +1,56 @@
+package summarizer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+func TestDigestText(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{"digest.title": "Digest for {0}"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "uk.json"), []byte(`{"digest.title": "Дайджест за {0}"}`), 0644)
+	bundle, err := i18n.NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Date(2026, 3, 9, 9, 0, 0, 0, time.UTC)
+	if got, want := digestText(bundle, "uk", now, "Обговорили реліз."), "Дайджест за 2026-03-09\n\nОбговорили реліз."; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got, want := digestText(bundle, "de", now, "x"), "Digest for 2026-03-09\n\nx"; got != want {
+		t.Errorf("unknown language should fall back to default, got %q", got)
+	}
+}
+
+func TestPersonalDigestDue(t *testing.T) {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	// Sunday 2026-03-01 18:00 in Kyiv is 11:00 in Toronto.
+	now := time.Date(2026, 3, 1, 18, 0, 0, 0, kyiv)
+	toronto := "America/Toronto"
+	bad := "Nowhere/Zone"
+	tests := []struct {
+		name string
+		u    db.UserSettings
+		hour int
+		want bool
+	}{
+		{"no timezone uses Kyiv", db.UserSettings{}, 18, true},
+		{"no timezone, other hour", db.UserSettings{}, 11, false},
+		{"own timezone", db.UserSettings{Timezone: &toronto}, 11, true},
+		{"own timezone, Kyiv hour", db.UserSettings{Timezone: &toronto}, 18, false},
+		{"invalid timezone falls back", db.UserSettings{Timezone: &bad}, 18, true},
+	}
+	for _, tt := range tests {
+		if got := personalDigestDue(&tt.u, now, 0, tt.hour); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if personalDigestDue(&db.UserSettings{}, now, 1, 18) {
+		t.Error("Monday digest should not be due on Sunday")
+	}
+}
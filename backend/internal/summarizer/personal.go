@@ -0,0 +1,110 @@
+package summarizer
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+const (
+	// personalDigestSentKey marks a user's digest for one ISO week as sent.
+	personalDigestSentKey = "personal_digest:sent:%d:%d-%02d"
+	personalDigestSentTTL = 8 * 24 * time.Hour
+	personalDigestWindow  = 7 * 24 * time.Hour
+	// maxPersonalDigestMessages caps how many mentions and replies one digest reads.
+	maxPersonalDigestMessages = 300
+)
+
+// personalDigestDue reports whether it is PersonalDigestWeekday at PersonalDigestHour in the user's
+// timezone (set_timezone), else in now's zone (Kyiv).
+func personalDigestDue(u *db.UserSettings, now time.Time, weekday, hour int) bool {
+	local := now.In(u.Location(now.Location()))
+	return local.Weekday() == time.Weekday(weekday%7) && local.Hour() == hour
+}
+
+// RunPersonalDigests DMs every opted-in user whose digest is due a digest of last week's mentions
+// of and replies to them, through the proactive queue (a user's private chat_id is their user ID).
+func (d *DigestRunner) RunPersonalDigests(ctx context.Context, now time.Time) {
+	ctx = logging.With(ctx, "component", "personal_digest")
+	users, err := d.db.ListPersonalDigestUsers(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to list personal digest users", "error", err)
+		return
+	}
+	due, sent := 0, 0
+	for _, u := range users {
+		if !personalDigestDue(&u, now, d.config.PersonalDigestWeekday, d.config.PersonalDigestHour) {
+			continue
+		}
+		due++
+		if d.sendPersonalDigest(logging.With(ctx, "user_id", u.UserID), &u, now) {
+			sent++
+		}
+	}
+	if due > 0 {
+		slog.InfoContext(ctx, "personal digests finished", "users", due, "sent", sent)
+	}
+}
+
+// sendPersonalDigest builds and queues one user's digest. Returns whether one was queued.
+func (d *DigestRunner) sendPersonalDigest(ctx context.Context, u *db.UserSettings, now time.Time) bool {
+	year, week := now.In(u.Location(now.Location())).ISOWeek()
+	ok, err := d.cache.Client().SetNX(ctx, fmt.Sprintf(personalDigestSentKey, u.UserID, year, week), 1, personalDigestSentTTL).Result()
+	if err != nil {
+		slog.WarnContext(ctx, "personal digest dedupe check failed", "error", err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+	username, firstName, err := d.db.GetUserHandle(ctx, u.UserID)
+	if err != nil {
+		slog.ErrorContext(ctx, "get user handle failed", "error", err)
+		return false
+	}
+	messages, err := d.db.GetUserMentions(ctx, u.UserID, username, now.Add(-personalDigestWindow), now, maxPersonalDigestMessages)
+	if err != nil {
+		slog.ErrorContext(ctx, "get user mentions failed", "error", err)
+		return false
+	}
+	if len(messages) == 0 {
+		return false // nothing concerned them this week
+	}
+	lang := d.config.DefaultLang
+	if u.Language != nil && *u.Language != "" {
+		lang = *u.Language
+	}
+	summary, err := d.llm.SummarizeForUser(ctx, messages, displayName(username, firstName), lang)
+	if err != nil {
+		slog.ErrorContext(ctx, "summarize personal digest failed", "error", err)
+		return false
+	}
+	if summary == "" {
+		return false
+	}
+	text := d.bundle.T(lang, "digest.personal_title") + "\n\n" + summary
+	if err := d.cache.PushProactive(ctx, cache.ProactiveItem{Source: cache.SourcePersonalDigest, ChatID: u.UserID, Reply: text}); err != nil {
+		slog.ErrorContext(ctx, "push personal digest failed", "error", err)
+		return false
+	}
+	slog.InfoContext(ctx, "personal digest queued", "messages", len(messages))
+	return true
+}
+
+// displayName is how the digest prompt refers to the user.
+func displayName(username, firstName string) string {
+	switch {
+	case firstName != "" && username != "":
+		return firstName + " (@" + username + ")"
+	case firstName != "":
+		return firstName
+	case username != "":
+		return "@" + username
+	}
+	return "the user"
+}
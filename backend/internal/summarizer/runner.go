@@ -2,64 +2,129 @@ package summarizer
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"log/slog"
-	"strconv"
+	"slices"
 	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
 	"github.com/ThatHunky/gryag/backend/internal/llm"
-	"github.com/redis/go-redis/v9"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
 )
 
 const (
-	lastRunKey7day  = "summary:last_run:7day"
-	lastRunKey30day = "summary:last_run:30day"
+	// thresholdCooldownKey blocks repeated threshold-triggered runs for one chat topic (e.g. when the LLM keeps failing).
+	thresholdCooldownKey = "summary:threshold_cooldown:%d:%d"
+	thresholdCooldown    = 1 * time.Hour
 )
 
 // Runner runs summarization for 7-day or 30-day windows.
 type Runner struct {
-	db     *db.DB
-	cache  *cache.Cache
-	llm    *llm.Client
-	config *config.Config
+	db       *db.DB
+	cache    *cache.Cache
+	llm      *llm.Client
+	settings *chatsettings.Store // optional; per-chat summary language override
+	config   *config.Config
 }
 
-// NewRunner creates a summarizer runner.
-func NewRunner(database *db.DB, c *cache.Cache, llmClient *llm.Client, cfg *config.Config) *Runner {
-	return &Runner{db: database, cache: c, llm: llmClient, config: cfg}
+// NewRunner creates a summarizer runner. settings may be nil (env defaults for every chat).
+func NewRunner(database *db.DB, c *cache.Cache, llmClient *llm.Client, settings *chatsettings.Store, cfg *config.Config) *Runner {
+	return &Runner{db: database, cache: c, llm: llmClient, settings: settings, config: cfg}
 }
 
-// RunOne runs summarization for the given type ("7day" or "30day") for all eligible chats.
-func (r *Runner) RunOne(ctx context.Context, summaryType string) {
-	logger := slog.With("component", "summarizer", "summary_type", summaryType)
-	var since time.Duration
-	var windowLabel string
-	var periodStart, periodEnd time.Time
-	if summaryType == "7day" {
-		since = 7 * 24 * time.Hour
-		windowLabel = "7-day"
-		periodEnd = time.Now()
-		periodStart = periodEnd.Add(-since)
-	} else if summaryType == "30day" {
-		since = 30 * 24 * time.Hour
-		windowLabel = "30-day"
-		periodEnd = time.Now()
-		periodStart = periodEnd.Add(-since)
-	} else {
-		logger.Warn("unknown summary type, skipping")
-		return
+// languageSampleSize is how many recent messages are checked when the summarized ones are too few
+// to tell the chat's language.
+const languageSampleSize = 100
+
+// dueSlack lets a chat summarized a little after its run hour be due again at the same hour
+// interval days later.
+const dueSlack = 2 * time.Hour
+
+// chatSettings returns the effective settings for a chat (env defaults when no store is wired).
+func (r *Runner) chatSettings(ctx context.Context, chatID int64) *chatsettings.Settings {
+	if r.settings != nil {
+		return r.settings.Get(ctx, chatID)
+	}
+	return chatsettings.Resolve(r.config, chatID, nil)
+}
+
+// summaryOptions returns how to write a chat topic's summary. The language is the chat's
+// summary_language override, else the dominant language of sample (the messages being summarized)
+// or of the topic's recent messages, else the chat's reply language.
+func (r *Runner) summaryOptions(ctx context.Context, t db.ChatThread, st *chatsettings.Settings, sample []db.Message) llm.SummaryOptions {
+	opts := llm.SummaryOptions{Anonymize: st.SummaryAnonymize}
+	if opts.Language = llm.SummaryLanguage(st.SummaryLanguage, "", sample); opts.Language != "" {
+		return opts
+	}
+	recent, err := r.db.GetRecentMessages(ctx, t.ChatID, t.ThreadID, languageSampleSize)
+	if err != nil {
+		slog.WarnContext(ctx, "get recent messages for language detection failed", "error", err)
 	}
+	opts.Language = llm.SummaryLanguage("", st.Language, recent)
+	return opts
+}
+
+// isDue reports whether a summary last written at last (zero = never) should run again at now.
+func isDue(last, now time.Time, intervalDays int) bool {
+	return last.IsZero() || now.Sub(last) >= time.Duration(intervalDays)*24*time.Hour-dueSlack
+}
 
-	chatIDs, err := r.db.GetRecentChatIDs(ctx, since)
+// RunDue summarizes every chat topic whose own schedule says so: the chat has summaries enabled,
+// now is its run hour (Kyiv), and its interval has passed since its last summary. 7-day summaries
+// follow the chat's summary_interval_days, 30-day ones Summary30DayIntervalDays.
+func (r *Runner) RunDue(ctx context.Context, now time.Time) {
+	ctx = logging.With(ctx, "component", "summarizer")
+	states, err := r.db.GetSummaryStates(ctx, 30*24*time.Hour)
 	if err != nil {
-		logger.Error("failed to get recent chat IDs", "error", err)
+		slog.ErrorContext(ctx, "failed to get summary states", "error", err)
 		return
 	}
-	if len(chatIDs) == 0 {
-		logger.Info("no chats to summarize")
+	interval30 := r.config.Summary30DayIntervalDays
+	if interval30 <= 0 {
+		interval30 = 12
+	}
+	limit := r.config.SummaryMaxMessagesPerWindow
+	if limit <= 0 {
+		limit = 2000
+	}
+	ran := 0
+	for _, st := range states {
+		cs := r.chatSettings(ctx, st.ChatID)
+		runHour := cs.SummaryRunHour
+		if runHour < 0 || runHour > 23 {
+			runHour = 3
+		}
+		if !cs.SummaryEnabled || runHour != now.Hour() {
+			continue
+		}
+		if isDue(st.Last7Day, now, max(cs.SummaryIntervalDays, 1)) {
+			r.summarizeChat(logging.With(ctx, "summary_type", "7day"), st.ChatThread, cs, "7day", "7-day", now.Add(-7*24*time.Hour), now, limit)
+			ran++
+		}
+		if isDue(st.Last30Day, now, interval30) {
+			r.summarizeChat(logging.With(ctx, "summary_type", "30day"), st.ChatThread, cs, "30day", "30-day", now.Add(-30*24*time.Hour), now, limit)
+			ran++
+		}
+	}
+	slog.InfoContext(ctx, "scheduled summarization finished", "hour", now.Hour(), "chats", len(states), "runs", ran)
+}
+
+// RunThreshold builds an out-of-schedule 7-day summary for every chat that accumulated at least
+// SummaryMessageThreshold messages since its latest 7-day summary, so very active chats don't
+// wait for the nightly run. Each chat is retried at most once per thresholdCooldown.
+func (r *Runner) RunThreshold(ctx context.Context) {
+	threshold := r.config.SummaryMessageThreshold
+	if threshold <= 0 {
+		return
+	}
+	ctx = logging.With(ctx, "component", "summarizer", "summary_type", "7day", "trigger", "threshold")
+
+	threads, err := r.db.GetChatsWithUnsummarizedMessages(ctx, threshold)
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to find chats over threshold", "error", err)
 		return
 	}
 
@@ -67,55 +132,138 @@ func (r *Runner) RunOne(ctx context.Context, summaryType string) {
 	if limit <= 0 {
 		limit = 2000
 	}
-
-	for _, chatID := range chatIDs {
-		messages, err := r.db.GetMessagesInRange(ctx, chatID, periodStart, periodEnd, limit)
+	for _, t := range threads {
+		ok, err := r.cache.Client().SetNX(ctx, fmt.Sprintf(thresholdCooldownKey, t.ChatID, t.ThreadID), 1, thresholdCooldown).Result()
 		if err != nil {
-			logger.Error("get messages in range failed", "chat_id", chatID, "error", err)
+			slog.WarnContext(ctx, "threshold cooldown check failed", "chat_id", t.ChatID, "thread_id", t.ThreadID, "error", err)
 			continue
 		}
-		if len(messages) == 0 {
+		if !ok {
 			continue
 		}
-		summary, err := r.llm.SummarizeChat(ctx, messages, windowLabel)
-		if err != nil {
-			logger.Error("summarize chat failed", "chat_id", chatID, "error", err)
+		cs := r.chatSettings(ctx, t.ChatID)
+		if !cs.SummaryEnabled {
 			continue
 		}
-		if summary == "" {
-			continue
+		slog.InfoContext(ctx, "message threshold reached, summarizing", "chat_id", t.ChatID, "thread_id", t.ThreadID, "threshold", threshold)
+		periodEnd := time.Now()
+		r.summarizeChat(ctx, t, cs, "7day", "7-day", periodEnd.Add(-7*24*time.Hour), periodEnd, limit)
+	}
+}
+
+// summarizeChat summarizes one chat topic's messages in [periodStart, periodEnd] and stores the result.
+// Forum topics are summarized separately, so each topic's context only carries its own summary.
+// Summaries are built hierarchically to avoid re-reading the whole window: a 7-day run rolls the
+// previous 7-day summary forward with only the newer messages, and a 30-day run combines stored
+// 7-day summaries plus the raw tail after them. Without a usable stored summary the raw window is read.
+func (r *Runner) summarizeChat(ctx context.Context, t db.ChatThread, cs *chatsettings.Settings, summaryType, windowLabel string, periodStart, periodEnd time.Time, limit int) {
+	ctx = logging.WithChat(ctx, t.ChatID, 0)
+	if t.ThreadID != 0 {
+		ctx = logging.With(ctx, "thread_id", t.ThreadID)
+	}
+	var (
+		summary string
+		read    int
+		mode    = "full"
+		err     error
+	)
+	switch summaryType {
+	case "7day":
+		prev, prevErr := r.db.GetSummariesInRange(ctx, t.ChatID, t.ThreadID, "7day", periodStart, periodEnd)
+		if prevErr != nil {
+			slog.WarnContext(ctx, "get previous summary failed, summarizing from scratch", "error", prevErr)
 		}
-		_, err = r.db.InsertChatSummary(ctx, chatID, summaryType, summary, periodStart, periodEnd)
-		if err != nil {
-			logger.Error("insert chat summary failed", "chat_id", chatID, "error", err)
-			continue
+		if len(prev) > 0 {
+			last := prev[len(prev)-1]
+			messages, msgErr := r.db.GetMessagesInRange(ctx, t.ChatID, t.ThreadID, last.PeriodEnd, periodEnd, limit)
+			if msgErr != nil {
+				slog.ErrorContext(ctx, "get messages in range failed", "error", msgErr)
+				return
+			}
+			if len(messages) == 0 {
+				return // nothing new since the previous summary
+			}
+			mode, read = "incremental", len(messages)
+			summary, err = r.llm.UpdateSummary(ctx, last.SummaryText, messages, windowLabel, periodStart, r.summaryOptions(ctx, t, cs, messages))
+			break
 		}
-		logger.Info("summary stored", "chat_id", chatID, "messages", len(messages))
+		summary, read, err = r.summarizeRaw(ctx, t, cs, windowLabel, periodStart, periodEnd, limit)
+	case "30day":
+		weekly, weeklyErr := r.db.GetSummariesInRange(ctx, t.ChatID, t.ThreadID, "7day", periodStart, periodEnd)
+		if weeklyErr != nil {
+			slog.WarnContext(ctx, "get 7-day summaries failed, summarizing from scratch", "error", weeklyErr)
+		}
+		if parts := chainSummaries(weekly, periodStart, chainOverlap); len(parts) > 0 {
+			tail, msgErr := r.db.GetMessagesInRange(ctx, t.ChatID, t.ThreadID, parts[len(parts)-1].PeriodEnd, periodEnd, limit)
+			if msgErr != nil {
+				slog.ErrorContext(ctx, "get messages in range failed", "error", msgErr)
+				return
+			}
+			mode, read = "combined", len(tail)
+			summary, err = r.llm.CombineSummaries(ctx, parts, tail, windowLabel, r.summaryOptions(ctx, t, cs, tail))
+			break
+		}
+		summary, read, err = r.summarizeRaw(ctx, t, cs, windowLabel, periodStart, periodEnd, limit)
+	default:
+		summary, read, err = r.summarizeRaw(ctx, t, cs, windowLabel, periodStart, periodEnd, limit)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "summarize chat failed", "mode", mode, "error", err)
+		return
+	}
+	if summary == "" {
+		return
+	}
+	_, err = r.db.InsertChatSummary(ctx, t.ChatID, t.ThreadID, summaryType, summary, periodStart, periodEnd)
+	if err != nil {
+		slog.ErrorContext(ctx, "insert chat summary failed", "error", err)
+		return
 	}
+	slog.InfoContext(ctx, "summary stored", "mode", mode, "messages", read)
 }
 
-// SetLastRun records the last run time for the given summary type in Redis.
-func (r *Runner) SetLastRun(ctx context.Context, summaryType string) error {
-	key := lastRunKey7day
-	if summaryType == "30day" {
-		key = lastRunKey30day
+// summarizeRaw summarizes every message in the window (the non-incremental path).
+func (r *Runner) summarizeRaw(ctx context.Context, t db.ChatThread, cs *chatsettings.Settings, windowLabel string, periodStart, periodEnd time.Time, limit int) (string, int, error) {
+	messages, err := r.db.GetMessagesInRange(ctx, t.ChatID, t.ThreadID, periodStart, periodEnd, limit)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(messages) == 0 {
+		return "", 0, nil
 	}
-	return r.cache.Client().Set(ctx, key, time.Now().Unix(), 0).Err()
+	summary, err := r.llm.SummarizeChat(ctx, messages, windowLabel, r.summaryOptions(ctx, t, cs, messages))
+	return summary, len(messages), err
 }
 
-// GetLastRun returns the last run Unix timestamp for the given type, or 0 if never run.
-func (r *Runner) GetLastRun(ctx context.Context, summaryType string) (int64, error) {
-	key := lastRunKey7day
-	if summaryType == "30day" {
-		key = lastRunKey30day
+// chainOverlap is how far consecutive 7-day summaries may overlap and still both be used for a
+// 30-day summary (7-day runs happen every few days, so their windows overlap).
+const chainOverlap = 4 * 24 * time.Hour
+
+// chainSummaries picks, from summaries sorted by period end, a chain that covers the window from
+// the newest summary back towards since. Each next (older) summary is the one that ends soonest at
+// or after the start of the previous pick, as long as it overlaps by at most overlap; otherwise the
+// latest one ending before that start (leaving a gap). Returned oldest first.
+func chainSummaries(summaries []db.ChatSummary, since time.Time, overlap time.Duration) []db.ChatSummary {
+	if len(summaries) == 0 {
+		return nil
 	}
-	val, err := r.cache.Client().Get(ctx, key).Result()
-	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return 0, nil
+	last := len(summaries) - 1
+	chain := []db.ChatSummary{summaries[last]}
+	for cursor := summaries[last].PeriodStart; cursor.After(since); {
+		// First candidate (older than the last pick) ending at or after cursor.
+		j := 0
+		for j < last && summaries[j].PeriodEnd.Before(cursor) {
+			j++
+		}
+		if j == last || summaries[j].PeriodEnd.After(cursor.Add(overlap)) {
+			j--
+		}
+		if j < 0 {
+			break
 		}
-		return 0, err
+		chain = append(chain, summaries[j])
+		cursor, last = summaries[j].PeriodStart, j
 	}
-	t, _ := strconv.ParseInt(val, 10, 64)
-	return t, nil
+	slices.Reverse(chain)
+	return chain
 }
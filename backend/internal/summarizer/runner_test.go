@@ -0,0 +1,74 @@
+package summarizer
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestRunThreshold_Disabled(t *testing.T) {
+	// With the threshold off, nothing touches the database or cache (both nil here).
+	r := NewRunner(nil, nil, nil, nil, &config.Config{SummaryMessageThreshold: 0})
+	r.RunThreshold(context.Background())
+}
+
+func TestChainSummaries(t *testing.T) {
+	day := 24 * time.Hour
+	end := time.Date(2026, 5, 31, 3, 0, 0, 0, time.UTC)
+	weekly := func(id int64, endDaysAgo int) db.ChatSummary {
+		e := end.Add(-time.Duration(endDaysAgo) * day)
+		return db.ChatSummary{ID: id, PeriodStart: e.Add(-7 * day), PeriodEnd: e}
+	}
+	// 7-day summaries every 3 days over the last month, oldest first.
+	var all []db.ChatSummary
+	for i, ago := range []int{27, 24, 21, 18, 15, 12, 9, 6, 3, 0} {
+		all = append(all, weekly(int64(i+1), ago))
+	}
+	since := end.Add(-30 * day)
+
+	chain := chainSummaries(all, since, 4*day)
+	var ids []int64
+	for _, s := range chain {
+		ids = append(ids, s.ID)
+	}
+	// Newest (0 days ago), then the one ending soonest after its start (6 days ago), and so on.
+	if want := []int64{2, 4, 6, 8, 10}; !slices.Equal(ids, want) {
+		t.Errorf("chain = %v, want %v", ids, want)
+	}
+
+	if got := chainSummaries(nil, since, 4*day); len(got) != 0 {
+		t.Errorf("expected empty chain, got %v", got)
+	}
+	if got := chainSummaries(all[9:], since, 4*day); len(got) != 1 || got[0].ID != 10 {
+		t.Errorf("single summary chain = %v", got)
+	}
+	// A gap (no summary ending between 3 and 20 days ago) still uses the older summary.
+	gap := []db.ChatSummary{weekly(1, 20), weekly(2, 2), weekly(3, 0)}
+	if got := chainSummaries(gap, since, 4*day); len(got) != 2 || got[0].ID != 1 || got[1].ID != 3 {
+		t.Errorf("gap chain = %v", got)
+	}
+}
+
+func TestIsDue(t *testing.T) {
+	now := time.Date(2026, 5, 31, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		last time.Time
+		days int
+		want bool
+	}{
+		{time.Time{}, 3, true},
+		{now.Add(-3 * 24 * time.Hour), 3, true},
+		{now.Add(-3*24*time.Hour + 40*time.Minute), 3, true}, // last run finished a bit after the hour
+		{now.Add(-2 * 24 * time.Hour), 3, false},
+		{now.Add(-time.Hour), 1, false},
+	}
+	for _, tt := range tests {
+		if got := isDue(tt.last, now, tt.days); got != tt.want {
+			t.Errorf("isDue(%v, %d) = %v, want %v", tt.last, tt.days, got, tt.want)
+		}
+	}
+}
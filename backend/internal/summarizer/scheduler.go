@@ -5,13 +5,36 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/cache"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 )
 
 const pollInterval = 1 * time.Minute
 
-// Scheduler runs summarization daily at SummaryRunHour (Kyiv). 7-day runs every Summary7DayIntervalDays,
-// 30-day every Summary30DayIntervalDays.
+// thresholdCheckInterval is how often chats are checked against SummaryMessageThreshold.
+const thresholdCheckInterval = 10 * time.Minute
+
+// hourSlotTTL keeps an hourly job's lock (see runHourOnce) until well after the hour is over.
+const hourSlotTTL = 2 * time.Hour
+
+// runHourOnce runs fn for now's Kyiv hour on only one replica: the first to take the hour's job
+// lock runs it, and the lock is left to expire so no replica repeats it within the hour.
+func runHourOnce(ctx context.Context, c *cache.Cache, job string, now time.Time, fn func()) {
+	lock, err := c.AcquireJobLock(ctx, job+":"+now.Format("2006-01-02T15"), hourSlotTTL)
+	if err != nil {
+		slog.WarnContext(ctx, "acquire job lock failed", "job", job, "error", err)
+		return
+	}
+	if lock != nil {
+		fn()
+	}
+}
+
+// Scheduler checks once per Kyiv hour which chats are due for a summary (see RunDue): each chat
+// runs at its own summary_run_hour (default SummaryRunHour) every summary_interval_days (default
+// Summary7DayIntervalDays); 30-day summaries every Summary30DayIntervalDays. Between runs, chats
+// exceeding SummaryMessageThreshold unsummarized messages get an extra 7-day summary. With several
+// replicas each hour runs on one of them; threshold runs are deduplicated per chat.
 func Scheduler(ctx context.Context, r *Runner, cfg *config.Config) {
 	logger := slog.With("component", "summarizer_scheduler")
 	kyiv, err := time.LoadLocation("Europe/Kyiv")
@@ -22,51 +45,19 @@ func Scheduler(ctx context.Context, r *Runner, cfg *config.Config) {
 			return
 		}
 	}
-	runHour := cfg.SummaryRunHour
-	if runHour < 0 || runHour > 23 {
-		runHour = 3
-	}
-	interval7 := cfg.Summary7DayIntervalDays
-	if interval7 <= 0 {
-		interval7 = 3
-	}
-	interval30 := cfg.Summary30DayIntervalDays
-	if interval30 <= 0 {
-		interval30 = 12
-	}
 
+	var lastThresholdCheck time.Time
+	lastHour := -1
 	for {
 		now := time.Now().In(kyiv)
-		hour := now.Hour()
-		if hour == runHour {
-			// Run at 3 AM Kyiv: check if 7-day and/or 30-day intervals have elapsed
-			run7 := false
-			last7, err := r.GetLastRun(ctx, "7day")
-			if err != nil {
-				logger.Warn("get last run 7day failed", "error", err)
-			} else {
-				elapsed := now.Unix() - last7
-				run7 = last7 == 0 || elapsed >= int64(interval7*24*3600)
-			}
-			if run7 {
-				logger.Info("running 7-day summarization")
-				r.RunOne(ctx, "7day")
-				_ = r.SetLastRun(ctx, "7day")
-			}
-
-			run30 := false
-			last30, err := r.GetLastRun(ctx, "30day")
-			if err != nil {
-				logger.Warn("get last run 30day failed", "error", err)
-			} else {
-				elapsed := now.Unix() - last30
-				run30 = last30 == 0 || elapsed >= int64(interval30*24*3600)
-			}
-			if run30 {
-				logger.Info("running 30-day summarization")
-				r.RunOne(ctx, "30day")
-				_ = r.SetLastRun(ctx, "30day")
-			}
+		if cfg.SummaryMessageThreshold > 0 && now.Sub(lastThresholdCheck) >= thresholdCheckInterval {
+			lastThresholdCheck = now
+			r.RunThreshold(ctx)
+		}
+		// Due checks read the stored summaries, so re-running an hour after a restart is harmless.
+		if now.Hour() != lastHour {
+			lastHour = now.Hour()
+			runHourOnce(ctx, r.cache, "summarizer", now, func() { r.RunDue(ctx, now) })
 		}
 
 		select {
@@ -0,0 +1,54 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxStoredTranscript bounds the transcript kept on the message row for search.
+	maxStoredTranscript = 20000
+	// maxTranscriptOutput bounds the transcript returned to the model; the summary covers the rest.
+	maxTranscriptOutput = 4000
+	// maxSearchTranscript bounds a transcript shown in search_messages results.
+	maxSearchTranscript = 500
+)
+
+// summarizeAudio runs summarize_audio: transcribes and summarizes the voice note or audio file of
+// the current message, and stores the transcript on its row so search_messages finds it later.
+func (e *Executor) summarizeAudio(ctx context.Context, _ json.RawMessage) (string, error) {
+	b64, _ := ctx.Value(RequestMediaBase64Key).(string)
+	mimeType, _ := ctx.Value(RequestMediaMimeTypeKey).(string)
+	if b64 == "" || !strings.HasPrefix(mimeType, "audio/") {
+		return "", fmt.Errorf("%w: the current message has no voice note or audio file", ErrInvalidArgs)
+	}
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return "", fmt.Errorf("decode audio: %w", err)
+	}
+	s, err := e.llmClient.SummarizeAudio(ctx, data, mimeType, requestLanguage(ctx, e.lang))
+	if err != nil {
+		return "", err
+	}
+
+	stored := false
+	if chatID, messageID := requestChatID(ctx), requestMessageID(ctx); chatID != 0 && messageID != 0 && s.Transcript != "" {
+		stored, err = e.db.SetMessageTranscript(ctx, chatID, messageID, truncateRunes(s.Transcript, maxStoredTranscript))
+		if err != nil {
+			slog.WarnContext(ctx, "store transcript failed", "error", err)
+		}
+	}
+	data, _ = json.Marshal(map[string]any{
+		"language":             s.Language,
+		"summary":              s.Summary,
+		"transcript":           truncateRunes(s.Transcript, maxTranscriptOutput),
+		"transcript_truncated": utf8.RuneCountInString(s.Transcript) > maxTranscriptOutput,
+		"transcript_saved":     stored,
+	})
+	return string(data), nil
+}
@@ -0,0 +1,21 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSummarizeAudio_NeedsAudio(t *testing.T) {
+	e := &Executor{}
+	for name, ctx := range map[string]context.Context{
+		"no media": context.Background(),
+		"a photo": context.WithValue(context.WithValue(context.Background(),
+			RequestMediaBase64Key, "anBlZw=="), RequestMediaMimeTypeKey, "image/jpeg"),
+		"no mime type": context.WithValue(context.Background(), RequestMediaBase64Key, "b2dn"),
+	} {
+		if _, err := e.summarizeAudio(ctx, nil); !errors.Is(err, ErrInvalidArgs) {
+			t.Errorf("%s: expected invalid args, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// AuditStore records every tool invocation (implemented by *db.DB).
+type AuditStore interface {
+	RecordToolCall(ctx context.Context, c *db.ToolCall) error
+}
+
+// maxAuditOutputRunes bounds the tool output kept in the audit table.
+const maxAuditOutputRunes = 500
+
+// SetAuditStore enables the tool_calls audit; nil (the default) records nothing.
+func (e *Executor) SetAuditStore(s AuditStore) {
+	e.audit = s
+}
+
+// recordCall writes one invocation to the audit store. It runs after the call has finished, so
+// it does not use the tool's deadline; failures are only logged.
+func (e *Executor) recordCall(ctx context.Context, name string, args json.RawMessage, start time.Time, result *ToolResult, timedOut bool) {
+	if e.audit == nil {
+		return
+	}
+	c := auditRecord(ctx, name, args, time.Since(start), result, timedOut)
+	if err := e.audit.RecordToolCall(context.WithoutCancel(ctx), c); err != nil {
+		slog.WarnContext(ctx, "record tool call failed", "error", err)
+	}
+}
+
+// auditRecord builds the tool_calls row for one call; request, chat and user come from the
+// logging context. A timed-out call is recorded as an error even though the model got a notice.
+func auditRecord(ctx context.Context, name string, args json.RawMessage, took time.Duration, result *ToolResult, timedOut bool) *db.ToolCall {
+	sum := sha256.Sum256(args)
+	c := &db.ToolCall{
+		Tool:       name,
+		ArgsHash:   hex.EncodeToString(sum[:]),
+		DurationMS: int(took.Milliseconds()),
+		Output:     truncateRunes(result.Output, maxAuditOutputRunes),
+	}
+	if v, ok := logging.Value(ctx, logging.KeyRequestID); ok {
+		id := v.String()
+		c.RequestID = &id
+	}
+	if v, ok := logging.Value(ctx, logging.KeyChatID); ok && v.Kind() == slog.KindInt64 {
+		id := v.Int64()
+		c.ChatID = &id
+	}
+	if v, ok := logging.Value(ctx, logging.KeyUserID); ok && v.Kind() == slog.KindInt64 {
+		id := v.Int64()
+		c.UserID = &id
+	}
+	switch {
+	case result.Error != "":
+		msg := truncateRunes(result.Error, maxAuditOutputRunes)
+		c.Error = &msg
+	case timedOut:
+		msg := "timed out"
+		c.Error = &msg
+	}
+	return c
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+type fakeAudit struct{ calls []*db.ToolCall }
+
+func (f *fakeAudit) RecordToolCall(_ context.Context, c *db.ToolCall) error {
+	f.calls = append(f.calls, c)
+	return nil
+}
+
+func TestAuditRecord(t *testing.T) {
+	ctx := logging.WithChat(logging.WithRequestID(context.Background(), "req-1"), -100, 42)
+	long := strings.Repeat("x", maxAuditOutputRunes+10)
+	c := auditRecord(ctx, "calculator", json.RawMessage(`{"expression":"2+2"}`), 1500*time.Millisecond, &ToolResult{Output: long}, false)
+
+	if c.RequestID == nil || *c.RequestID != "req-1" || c.ChatID == nil || *c.ChatID != -100 || c.UserID == nil || *c.UserID != 42 {
+		t.Errorf("request, chat or user not taken from the context: %+v", c)
+	}
+	if c.Tool != "calculator" || c.DurationMS != 1500 || c.Error != nil {
+		t.Errorf("unexpected record: %+v", c)
+	}
+	if len(c.ArgsHash) != 64 {
+		t.Errorf("expected a SHA-256 hex hash, got %q", c.ArgsHash)
+	}
+	if n := len([]rune(c.Output)); n != maxAuditOutputRunes+1 { // plus the ellipsis
+		t.Errorf("expected output truncated to %d runes, got %d", maxAuditOutputRunes, n)
+	}
+
+	same := auditRecord(context.Background(), "calculator", json.RawMessage(`{"expression":"2+2"}`), 0, &ToolResult{}, true)
+	if same.ArgsHash != c.ArgsHash {
+		t.Error("same arguments must hash the same")
+	}
+	if same.RequestID != nil || same.ChatID != nil || same.UserID != nil {
+		t.Errorf("background calls have no request, chat or user: %+v", same)
+	}
+	if same.Error == nil || *same.Error != "timed out" {
+		t.Errorf("expected a timed-out call to be recorded as an error, got %v", same.Error)
+	}
+}
+
+func TestExecutor_Audit(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+
+	audit := &fakeAudit{}
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	executor.SetAuditStore(audit)
+	executor.Execute(context.Background(), "nonexistent_tool", json.RawMessage(`{}`))
+
+	if len(audit.calls) != 1 {
+		t.Fatalf("expected one audited call, got %d", len(audit.calls))
+	}
+	if c := audit.calls[0]; c.Tool != "nonexistent_tool" || c.Error == nil {
+		t.Errorf("expected the failed call to be audited with its error, got %+v", c)
+	}
+}
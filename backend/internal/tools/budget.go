@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+// unbudgetedTools return media the handler takes out of the output before the model sees it.
+var unbudgetedTools = map[string]bool{
+	"generate_image": true,
+	"edit_image":     true,
+}
+
+// fitOutput applies the tool's TOOL_OUTPUT_MAX_CHARS / TOOL_OUTPUT_LIMITS cap to its output.
+func (e *Executor) fitOutput(ctx context.Context, name, output string) string {
+	if unbudgetedTools[name] {
+		return output
+	}
+	fitted := fitOutput(output, e.config.ToolOutputLimit(name))
+	if len(fitted) != len(output) {
+		slog.InfoContext(ctx, "tool output truncated", "chars", utf8.RuneCountInString(output), "kept", utf8.RuneCountInString(fitted))
+	}
+	return fitted
+}
+
+// fitOutput shortens a tool's output to limit characters (0 = no cap). JSON stays valid where it
+// can: a top-level array keeps its leading (top-ranked) entries and ends with a
+// {"truncated": true, "omitted": n} entry; an object keeps the leading entries of its largest
+// array and gets "truncated" and "omitted" fields. Anything else is cut at limit and a
+// "[truncated: n more characters]" line is added.
+func fitOutput(output string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(output) <= limit {
+		return output
+	}
+	trimmed := strings.TrimSpace(output)
+	switch {
+	case strings.HasPrefix(trimmed, "["):
+		var items []json.RawMessage
+		if json.Unmarshal([]byte(trimmed), &items) == nil {
+			if out, ok := fitArray(items, limit); ok {
+				return out
+			}
+		}
+	case strings.HasPrefix(trimmed, "{"):
+		var obj map[string]json.RawMessage
+		if json.Unmarshal([]byte(trimmed), &obj) == nil {
+			if out, ok := fitObject(obj, limit); ok {
+				return out
+			}
+		}
+	}
+	return cutText(output, limit)
+}
+
+// fitArray keeps as many leading items as fit in limit together with the marker entry.
+func fitArray(items []json.RawMessage, limit int) (string, bool) {
+	kept := keepLeading(items, limit-2) // brackets
+	for ; kept > 0; kept-- {
+		out := append(items[:kept:kept], truncatedMarker(len(items)-kept))
+		data, _ := json.Marshal(out)
+		if utf8.RuneCount(data) <= limit {
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+// fitObject trims the object's largest array so the whole object fits in limit.
+func fitObject(obj map[string]json.RawMessage, limit int) (string, bool) {
+	field, size := "", 0
+	for name, raw := range obj {
+		if len(raw) > size && strings.HasPrefix(strings.TrimSpace(string(raw)), "[") {
+			field, size = name, len(raw)
+		}
+	}
+	if field == "" {
+		return "", false
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(obj[field], &items) != nil {
+		return "", false
+	}
+	rest := 0
+	for name, raw := range obj {
+		if name != field {
+			rest += utf8.RuneCount(raw) + utf8.RuneCountInString(name) + 4 // quotes, colon, comma
+		}
+	}
+	for kept := min(len(items)-1, keepLeading(items, limit-rest)); kept > 0; kept-- {
+		list, _ := json.Marshal(items[:kept])
+		obj[field] = list
+		obj["truncated"] = json.RawMessage("true")
+		obj["omitted"] = json.RawMessage(fmt.Sprint(len(items) - kept))
+		data, _ := json.Marshal(obj)
+		if utf8.RuneCount(data) <= limit {
+			return string(data), true
+		}
+	}
+	return "", false
+}
+
+// keepLeading returns how many leading items fit in budget characters with separating commas,
+// as an upper bound to start from.
+func keepLeading(items []json.RawMessage, budget int) int {
+	used := 0
+	for i, item := range items {
+		used += utf8.RuneCount(item) + 1
+		if used > budget {
+			return i
+		}
+	}
+	return len(items)
+}
+
+func truncatedMarker(omitted int) json.RawMessage {
+	return json.RawMessage(fmt.Sprintf(`{"truncated":true,"omitted":%d}`, omitted))
+}
+
+// cutText keeps the first limit characters and says how many were dropped.
+func cutText(s string, limit int) string {
+	r := []rune(s)
+	return string(r[:limit]) + fmt.Sprintf("\n[truncated: %d more characters]", len(r)-limit)
+}
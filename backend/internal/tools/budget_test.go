@@ -0,0 +1,86 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitOutput_Array(t *testing.T) {
+	var entries []map[string]any
+	for i := range 50 {
+		entries = append(entries, map[string]any{"id": i, "text": strings.Repeat("повідомлення ", 5)})
+	}
+	data, _ := json.Marshal(entries)
+
+	out := fitOutput(string(data), 1000)
+	if n := utf8.RuneCountInString(out); n > 1000 {
+		t.Fatalf("output is %d characters, limit 1000", n)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("truncated array is not valid JSON: %v", err)
+	}
+	last := got[len(got)-1]
+	if last["truncated"] != true || last["omitted"] != float64(50-(len(got)-1)) {
+		t.Errorf("expected a truncation marker last, got %v", last)
+	}
+	if got[0]["id"] != float64(0) || got[len(got)-2]["id"] != float64(len(got)-2) {
+		t.Error("expected the leading entries to be kept in order")
+	}
+}
+
+func TestFitOutput_Object(t *testing.T) {
+	var notes []string
+	for i := range 40 {
+		notes = append(notes, fmt.Sprintf("note %d %s", i, strings.Repeat("x", 40)))
+	}
+	data, _ := json.Marshal(map[string]any{"chat": "test", "notes": notes})
+
+	out := fitOutput(string(data), 500)
+	if n := utf8.RuneCountInString(out); n > 500 {
+		t.Fatalf("output is %d characters, limit 500", n)
+	}
+	var got struct {
+		Chat      string   `json:"chat"`
+		Notes     []string `json:"notes"`
+		Truncated bool     `json:"truncated"`
+		Omitted   int      `json:"omitted"`
+	}
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("truncated object is not valid JSON: %v", err)
+	}
+	if got.Chat != "test" || !got.Truncated || len(got.Notes)+got.Omitted != 40 || got.Notes[0] != notes[0] {
+		t.Errorf("unexpected truncated object: %+v", got)
+	}
+}
+
+func TestFitOutput_Text(t *testing.T) {
+	if out := fitOutput("short", 100); out != "short" {
+		t.Errorf("output under the limit must be unchanged, got %q", out)
+	}
+	if out := fitOutput(strings.Repeat("я", 300), 0); utf8.RuneCountInString(out) != 300 {
+		t.Error("limit 0 must not cap")
+	}
+	out := fitOutput(strings.Repeat("я", 300), 100)
+	if !strings.HasPrefix(out, strings.Repeat("я", 100)+"\n") || !strings.HasSuffix(out, "[truncated: 200 more characters]") {
+		t.Errorf("unexpected cut: %q", out)
+	}
+	// One entry too large for the limit on its own falls back to a plain cut
+	huge, _ := json.Marshal([]string{strings.Repeat("a", 500)})
+	if out := fitOutput(string(huge), 100); !strings.Contains(out, "[truncated:") {
+		t.Errorf("expected a plain cut, got %q", out)
+	}
+}
+
+func TestExecutor_FitOutputSkipsImages(t *testing.T) {
+	e := &Executor{}
+	big := strings.Repeat("A", 100)
+	for _, name := range []string{"generate_image", "edit_image"} {
+		if unbudgetedTools[name] != true || e.fitOutput(t.Context(), name, big) != big {
+			t.Errorf("%s output must not be capped", name)
+		}
+	}
+}
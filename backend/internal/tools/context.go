@@ -1,7 +1,97 @@
 package tools
 
+import (
+	"context"
+	"slices"
+)
+
 // RequestMediaBase64Key is the context key for the current request's media (base64) when the user sent an attachment.
 // Used by edit_image with use_context_image to get the image from the current message.
 var RequestMediaBase64Key = &requestMediaKeyType{}
 
-type requestMediaKeyType struct{}
\ No newline at end of file
+type requestMediaKeyType struct{}
+
+// RequestMediaMimeTypeKey is the context key for the MIME type of the current request's media.
+// summarize_audio only takes audio/* media.
+var RequestMediaMimeTypeKey = &requestMediaMimeTypeKeyType{}
+
+type requestMediaMimeTypeKeyType struct{}
+
+// RequestMessageIDKey is the context key for the Telegram message_id of the current message.
+var RequestMessageIDKey = &requestMessageIDKeyType{}
+
+type requestMessageIDKeyType struct{}
+
+// requestMessageID returns the Telegram message_id of the current message, or 0 if unknown.
+func requestMessageID(ctx context.Context) int64 {
+	id, _ := ctx.Value(RequestMessageIDKey).(int64)
+	return id
+}
+
+// RequestLanguageKey is the context key for the language code tool output should be localized to
+// (the reply language resolved for the current user/chat). Falls back to DEFAULT_LANG when absent.
+var RequestLanguageKey = &requestLanguageKeyType{}
+
+type requestLanguageKeyType struct{}
+
+// RequestUserIDKey is the context key for the Telegram user who sent the current message.
+// Tools that change a user's own privacy settings act only on this user, whatever the model passes.
+var RequestUserIDKey = &requestUserIDKeyType{}
+
+type requestUserIDKeyType struct{}
+
+// requestUserID returns the sender of the current message, or 0 if unknown.
+func requestUserID(ctx context.Context) int64 {
+	id, _ := ctx.Value(RequestUserIDKey).(int64)
+	return id
+}
+
+// RequestChatIDKey is the context key for the chat the current message was sent in.
+// request_delete only acts in this chat, whatever the model passes.
+var RequestChatIDKey = &requestChatIDKeyType{}
+
+type requestChatIDKeyType struct{}
+
+// requestChatID returns the chat of the current message, or 0 if unknown (e.g. proactive turns).
+func requestChatID(ctx context.Context) int64 {
+	id, _ := ctx.Value(RequestChatIDKey).(int64)
+	return id
+}
+
+// RequestThreadIDKey is the context key for the forum topic (message_thread_id) of the current message.
+// search_messages stays inside this topic unless asked to search all topics.
+var RequestThreadIDKey = &requestThreadIDKeyType{}
+
+type requestThreadIDKeyType struct{}
+
+// requestThreadID returns the current message's forum topic, or 0 if none.
+func requestThreadID(ctx context.Context) int64 {
+	id, _ := ctx.Value(RequestThreadIDKey).(int64)
+	return id
+}
+
+// RequestDisabledToolsKey is the context key for the tools disabled in the current chat
+// (chat_settings.disabled_tools). The executor refuses them even if the model calls one anyway.
+var RequestDisabledToolsKey = &requestDisabledToolsKeyType{}
+
+type requestDisabledToolsKeyType struct{}
+
+// requestToolDisabled reports whether the current chat has the named tool disabled.
+func requestToolDisabled(ctx context.Context, name string) bool {
+	disabled, _ := ctx.Value(RequestDisabledToolsKey).([]string)
+	return slices.Contains(disabled, name)
+}
+
+// requestLanguage returns the per-request language from ctx, or fallback if none was set.
+func requestLanguage(ctx context.Context, fallback string) string {
+	if lang, ok := ctx.Value(RequestLanguageKey).(string); ok && lang != "" {
+		return lang
+	}
+	return fallback
+}
+
+// RequestLocationKey is the context key for the *time.Location of the user who sent the current
+// message (their set_timezone zone, else the chat's). Dates the user types are read in this zone.
+var RequestLocationKey = &requestLocationKeyType{}
+
+type requestLocationKeyType struct{}
@@ -0,0 +1,181 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// declFileVersion is the only declaration file format understood so far.
+const declFileVersion = 1
+
+// declFile is one tool declaration file: {"version": 1, "tools": [...]}.
+type declFile struct {
+	Version int        `json:"version"`
+	Tools   []declTool `json:"tools"`
+}
+
+type declTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Parameters  *declSchema `json:"parameters"`
+}
+
+// declSchema is the subset of genai.Schema the registry uses, with lowercase or uppercase type names.
+type declSchema struct {
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	Enum        []string               `json:"enum"`
+	Items       *declSchema            `json:"items"`
+	Properties  map[string]*declSchema `json:"properties"`
+	Required    []string               `json:"required"`
+}
+
+var declTypes = map[string]genai.Type{
+	"STRING":  genai.TypeString,
+	"NUMBER":  genai.TypeNumber,
+	"INTEGER": genai.TypeInteger,
+	"BOOLEAN": genai.TypeBoolean,
+	"ARRAY":   genai.TypeArray,
+	"OBJECT":  genai.TypeObject,
+}
+
+// LoadDeclarationFiles replaces the descriptions and parameter schemas of registered tools with
+// the ones in dir/*.json, so tool prompts can be tuned without a rebuild. The Go implementation
+// still decides what a tool does. Files are applied in name order (a later file wins). Tools that
+// are not registered (unknown or disabled by a feature toggle) are skipped. Any invalid file
+// rejects the whole directory and leaves the registry unchanged. Returns the number of overrides.
+func (r *Registry) LoadDeclarationFiles(dir string) (int, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(paths)
+	overrides := make(map[string]*genai.FunctionDeclaration)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return 0, fmt.Errorf("read %s: %w", path, err)
+		}
+		decls, err := parseDeclarations(data)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		for _, d := range decls {
+			if !r.HasTool(d.Name) {
+				slog.Warn("tool declaration skipped: tool not registered", "file", filepath.Base(path), "tool", d.Name)
+				continue
+			}
+			overrides[d.Name] = d
+		}
+	}
+	for name, d := range overrides {
+		r.register(name, d)
+	}
+	return len(overrides), nil
+}
+
+// parseDeclarations decodes and validates one declaration file.
+func parseDeclarations(data []byte) ([]*genai.FunctionDeclaration, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f declFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if f.Version != declFileVersion {
+		return nil, fmt.Errorf("unsupported version %d (want %d)", f.Version, declFileVersion)
+	}
+	seen := make(map[string]bool, len(f.Tools))
+	out := make([]*genai.FunctionDeclaration, 0, len(f.Tools))
+	for i, t := range f.Tools {
+		if t.Name == "" {
+			return nil, fmt.Errorf("tools[%d]: name is required", i)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("tool %s declared twice", t.Name)
+		}
+		seen[t.Name] = true
+		decl, err := t.declaration()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, decl)
+	}
+	return out, nil
+}
+
+// declaration validates one declared tool and converts it.
+func (t *declTool) declaration() (*genai.FunctionDeclaration, error) {
+	if strings.TrimSpace(t.Description) == "" {
+		return nil, fmt.Errorf("tool %s: description is required", t.Name)
+	}
+	decl := &genai.FunctionDeclaration{Name: t.Name, Description: t.Description}
+	if t.Parameters != nil {
+		if !strings.EqualFold(t.Parameters.Type, "object") {
+			return nil, fmt.Errorf("tool %s: parameters must be an object", t.Name)
+		}
+		schema, err := t.Parameters.toGenai("parameters")
+		if err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		decl.Parameters = schema
+	}
+	return decl, nil
+}
+
+// toGenai validates a schema node and converts it; path names the node in errors.
+func (s *declSchema) toGenai(path string) (*genai.Schema, error) {
+	typ, ok := declTypes[strings.ToUpper(s.Type)]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown type %q", path, s.Type)
+	}
+	out := &genai.Schema{Type: typ, Description: s.Description}
+	if len(s.Enum) > 0 {
+		if typ != genai.TypeString {
+			return nil, fmt.Errorf("%s: enum is only allowed for strings", path)
+		}
+		out.Enum = s.Enum
+	}
+	switch typ {
+	case genai.TypeArray:
+		if s.Items == nil {
+			return nil, fmt.Errorf("%s: array needs items", path)
+		}
+		items, err := s.Items.toGenai(path + ".items")
+		if err != nil {
+			return nil, err
+		}
+		out.Items = items
+	case genai.TypeObject:
+		out.Properties = make(map[string]*genai.Schema, len(s.Properties))
+		for name, p := range s.Properties {
+			if p == nil {
+				return nil, fmt.Errorf("%s.%s: empty schema", path, name)
+			}
+			prop, err := p.toGenai(path + "." + name)
+			if err != nil {
+				return nil, err
+			}
+			out.Properties[name] = prop
+		}
+		for _, name := range s.Required {
+			if _, ok := s.Properties[name]; !ok {
+				return nil, fmt.Errorf("%s: required property %q is not defined", path, name)
+			}
+		}
+		out.Required = s.Required
+	default:
+		if s.Items != nil || len(s.Properties) > 0 || len(s.Required) > 0 {
+			return nil, fmt.Errorf("%s: items/properties/required only apply to arrays and objects", path)
+		}
+	}
+	return out, nil
+}
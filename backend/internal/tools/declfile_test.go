@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseDeclarations(t *testing.T) {
+	decls, err := parseDeclarations([]byte(`{
+		"version": 1,
+		"tools": [{
+			"name": "time_info",
+			"description": "Local time for a place.",
+			"parameters": {
+				"type": "object",
+				"properties": {
+					"location": {"type": "string", "description": "City"},
+					"units": {"type": "string", "enum": ["metric", "imperial"]},
+					"days": {"type": "array", "items": {"type": "integer"}}
+				},
+				"required": ["location"]
+			}
+		}]
+	}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decls) != 1 || decls[0].Name != "time_info" {
+		t.Fatalf("unexpected declarations: %+v", decls)
+	}
+	p := decls[0].Parameters
+	if p.Type != genai.TypeObject || p.Properties["location"].Type != genai.TypeString ||
+		p.Properties["days"].Items.Type != genai.TypeInteger || len(p.Properties["units"].Enum) != 2 {
+		t.Errorf("unexpected schema: %+v", p)
+	}
+}
+
+func TestParseDeclarations_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"version":          `{"version": 2, "tools": []}`,
+		"unknown field":    `{"version": 1, "tools": [{"name": "x", "description": "d", "descripton": "typo"}]}`,
+		"no name":          `{"version": 1, "tools": [{"description": "d"}]}`,
+		"no description":   `{"version": 1, "tools": [{"name": "x"}]}`,
+		"duplicate":        `{"version": 1, "tools": [{"name": "x", "description": "d"}, {"name": "x", "description": "d"}]}`,
+		"bad type":         `{"version": 1, "tools": [{"name": "x", "description": "d", "parameters": {"type": "object", "properties": {"a": {"type": "text"}}}}]}`,
+		"not object":       `{"version": 1, "tools": [{"name": "x", "description": "d", "parameters": {"type": "string"}}]}`,
+		"missing required": `{"version": 1, "tools": [{"name": "x", "description": "d", "parameters": {"type": "object", "required": ["a"]}}]}`,
+		"array no items":   `{"version": 1, "tools": [{"name": "x", "description": "d", "parameters": {"type": "object", "properties": {"a": {"type": "array"}}}}]}`,
+		"enum on integer":  `{"version": 1, "tools": [{"name": "x", "description": "d", "parameters": {"type": "object", "properties": {"a": {"type": "integer", "enum": ["1"]}}}}]}`,
+	}
+	for name, data := range tests {
+		if _, err := parseDeclarations([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestRegistry_LoadDeclarationFiles(t *testing.T) {
+	cfg := loadTestConfig(t)
+	r := NewRegistry(cfg)
+	before := r.Count()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "10-memory.json"), []byte(`{"version": 1, "tools": [
+		{"name": "forget_memory", "description": "first"},
+		{"name": "no_such_tool", "description": "skipped"}
+	]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "20-override.json"), []byte(`{"version": 1, "tools": [{"name": "forget_memory", "description": "second"}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte(`ignored`), 0644)
+
+	n, err := r.LoadDeclarationFiles(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n != 1 || r.Count() != before || r.HasTool("no_such_tool") {
+		t.Errorf("expected one override and no new tools, got n=%d count=%d", n, r.Count())
+	}
+	if !strings.Contains(r.GetToolDescription(), "forget_memory: second") {
+		t.Errorf("expected later file to win:\n%s", r.GetToolDescription())
+	}
+
+	// An invalid file rejects the whole directory.
+	os.WriteFile(filepath.Join(dir, "30-broken.json"), []byte(`{"version": 1, "tools": [{"name": "calculator", "description": ""}]}`), 0644)
+	os.WriteFile(filepath.Join(dir, "20-override.json"), []byte(`{"version": 1, "tools": [{"name": "forget_memory", "description": "third"}]}`), 0644)
+	if _, err := r.LoadDeclarationFiles(dir); err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+	if !strings.Contains(r.GetToolDescription(), "forget_memory: second") {
+		t.Error("registry should be unchanged after a failed load")
+	}
+}
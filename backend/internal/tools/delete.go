@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+// DeleteAction asks the frontend to delete one Telegram message (returned in the process response).
+type DeleteAction struct {
+	ChatID    int64 `json:"chat_id"`
+	MessageID int64 `json:"message_id"`
+}
+
+// deleteOutput is request_delete's tool output when the deletion is allowed.
+type deleteOutput struct {
+	Delete DeleteAction `json:"delete"`
+}
+
+// ParseDeleteAction extracts the action from a successful request_delete output.
+func ParseDeleteAction(output string) (DeleteAction, bool) {
+	var out deleteOutput
+	if err := json.Unmarshal([]byte(output), &out); err != nil || out.Delete.MessageID == 0 {
+		return DeleteAction{}, false
+	}
+	return out.Delete, true
+}
+
+// canDelete reports whether requester may have msg deleted: anyone may remove the bot's own
+// replies and their own messages; other people's messages need an admin.
+func canDelete(requester int64, msg *db.Message, admins []int64) bool {
+	if slices.Contains(admins, requester) {
+		return true
+	}
+	if msg == nil || requester == 0 {
+		return false
+	}
+	return msg.IsBotReply || (msg.UserID != nil && *msg.UserID == requester)
+}
+
+// requestDelete checks whether the current user may delete a message of the current chat, audits
+// the attempt, and on success returns the delete action for the frontend to execute.
+func (e *Executor) requestDelete(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		MessageID int64  `json:"message_id"`
+		Reason    string `json:"reason"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID, requester := requestChatID(ctx), requestUserID(ctx)
+	if chatID == 0 || params.MessageID <= 0 {
+		return e.t(ctx, "tool.delete_not_found"), nil
+	}
+	msg, err := e.db.GetMessageByTelegramID(ctx, chatID, params.MessageID)
+	if err != nil {
+		return "", err
+	}
+	allowed := canDelete(requester, msg, e.config.AdminIDs)
+
+	audit := &db.MessageDeletion{ChatID: chatID, MessageID: params.MessageID, RequestedBy: requester, Allowed: allowed}
+	if msg != nil {
+		audit.TargetUserID, audit.TargetIsBot = msg.UserID, msg.IsBotReply
+	}
+	if reason := strings.TrimSpace(params.Reason); reason != "" {
+		audit.Reason = &reason
+	}
+	if v, ok := logging.Value(ctx, logging.KeyRequestID); ok {
+		id := v.String()
+		audit.RequestID = &id
+	}
+	if err := e.db.InsertMessageDeletion(ctx, audit); err != nil {
+		// Never delete without an audit entry.
+		return "", fmt.Errorf("audit deletion: %w", err)
+	}
+	slog.InfoContext(ctx, "message deletion requested", "message_id", params.MessageID, "allowed", allowed, "target_is_bot", audit.TargetIsBot)
+
+	switch {
+	case allowed:
+		b, err := json.Marshal(deleteOutput{Delete: DeleteAction{ChatID: chatID, MessageID: params.MessageID}})
+		return string(b), err
+	case msg == nil:
+		return e.t(ctx, "tool.delete_not_found"), nil
+	default:
+		return e.t(ctx, "tool.delete_forbidden"), nil
+	}
+}
@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestCanDelete(t *testing.T) {
+	author, other := int64(5), int64(6)
+	admins := []int64{111}
+	tests := []struct {
+		name      string
+		requester int64
+		msg       *db.Message
+		want      bool
+	}{
+		{"own message", 5, &db.Message{UserID: &author}, true},
+		{"someone else's message", 5, &db.Message{UserID: &other}, false},
+		{"bot reply", 5, &db.Message{IsBotReply: true}, true},
+		{"admin, someone else's message", 111, &db.Message{UserID: &other}, true},
+		{"admin, unknown message", 111, nil, true},
+		{"unknown message", 5, nil, false},
+		{"unknown requester", 0, &db.Message{IsBotReply: true}, false},
+	}
+	for _, tt := range tests {
+		if got := canDelete(tt.requester, tt.msg, admins); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDeleteAction(t *testing.T) {
+	a, ok := ParseDeleteAction(`{"delete":{"chat_id":-100,"message_id":42}}`)
+	if !ok || a.ChatID != -100 || a.MessageID != 42 {
+		t.Errorf("unexpected action %+v (ok=%v)", a, ok)
+	}
+	if _, ok := ParseDeleteAction("You can only delete your own messages."); ok {
+		t.Error("plain text output should not parse as an action")
+	}
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+)
+
+// setPersonalDigest opts the requesting user in to or out of the weekly personal digest DM.
+func (e *Executor) setPersonalDigest(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	userID := requestUserID(ctx)
+	if userID == 0 {
+		return "", fmt.Errorf("%w: no requesting user", ErrUnavailable)
+	}
+	if err := e.db.SetPersonalDigest(ctx, userID, params.Enabled); err != nil {
+		return "", err
+	}
+	slog.InfoContext(ctx, "personal digest setting changed", "enabled", params.Enabled)
+	if params.Enabled {
+		return e.t(ctx, "digest.personal_enabled"), nil
+	}
+	return e.t(ctx, "digest.personal_disabled"), nil
+}
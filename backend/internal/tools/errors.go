@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+	"github.com/ThatHunky/gryag/backend/internal/mcp"
+	"google.golang.org/genai"
+)
+
+// Kinds of tool failure. Tools wrap them (fmt.Errorf("%w: title is required", ErrInvalidArgs))
+// so the executor can tell the model what went wrong without passing on internal details such
+// as DSNs, file paths or upstream responses, which only go to the log.
+var (
+	ErrInvalidArgs = errors.New("invalid arguments")
+	ErrNotFound    = errors.New("not found")
+	ErrForbidden   = errors.New("not allowed")
+	ErrRateLimited = errors.New("rate limited")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Error kinds reported in ToolResult.ErrorKind.
+const (
+	KindInvalidArgs = "invalid_args"
+	KindNotFound    = "not_found"
+	KindForbidden   = "forbidden"
+	KindRateLimited = "rate_limited"
+	KindUnavailable = "unavailable"
+	KindExternal    = "external"
+	KindUnknownTool = "unknown_tool"
+	KindInternal    = "internal"
+)
+
+// errorKind classifies a tool error. Errors from the database, the egress policy, Gemini and
+// the network are recognized without wrapping; anything else is internal.
+func errorKind(err error) string {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var apiErr genai.APIError
+	var netErr net.Error
+	var mcpErr *mcp.ToolError
+	switch {
+	case errors.As(err, &mcpErr):
+		return KindExternal
+	case errors.Is(err, ErrInvalidArgs), errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return KindInvalidArgs
+	case errors.Is(err, ErrNotFound), errors.Is(err, sql.ErrNoRows):
+		return KindNotFound
+	case errors.Is(err, ErrForbidden), errors.Is(err, db.ErrOffRecord),
+		errors.Is(err, egress.ErrSchemeNotAllowed), errors.Is(err, egress.ErrDomainNotAllowed), errors.Is(err, egress.ErrBlockedAddress):
+		return KindForbidden
+	case errors.Is(err, ErrRateLimited), errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests:
+		return KindRateLimited
+	case errors.Is(err, ErrUnavailable), errors.Is(err, egress.ErrResponseTooLarge), errors.Is(err, context.DeadlineExceeded),
+		errors.As(err, &apiErr) && apiErr.Code >= 500, errors.As(err, &netErr):
+		return KindUnavailable
+	}
+	return KindInternal
+}
+
+// errorMessage is the localized text the model gets for a failed tool. Only invalid-argument
+// and MCP tool errors keep their own text: tools write it for the model, and JSON decode errors
+// describe nothing but the arguments.
+func (e *Executor) errorMessage(ctx context.Context, name, kind string, err error) string {
+	switch kind {
+	case KindInvalidArgs:
+		return e.t(ctx, "tool.error.invalid_args", name, err.Error())
+	case KindExternal:
+		var mcpErr *mcp.ToolError
+		errors.As(err, &mcpErr)
+		return e.t(ctx, "tool.error.external", name, truncateRunes(mcpErr.Text, maxAuditOutputRunes))
+	case KindNotFound:
+		return e.t(ctx, "tool.error.not_found", name)
+	case KindForbidden:
+		return e.t(ctx, "tool.error.forbidden", name)
+	case KindRateLimited:
+		return e.t(ctx, "tool.error.rate_limited", name)
+	case KindUnavailable:
+		return e.t(ctx, "tool.error.unavailable", name)
+	}
+	return e.t(ctx, "tool.internal_error", name)
+}
@@ -0,0 +1,70 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+	"google.golang.org/genai"
+)
+
+func TestErrorKind(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), &struct{}{})
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("%w: title is required", ErrInvalidArgs), KindInvalidArgs},
+		{fmt.Errorf("parse args: %w", syntaxErr), KindInvalidArgs},
+		{fmt.Errorf("get note: %w", sql.ErrNoRows), KindNotFound},
+		{fmt.Errorf("fetch: %w", egress.ErrDomainNotAllowed), KindForbidden},
+		{db.ErrOffRecord, KindForbidden},
+		{fmt.Errorf("search: %w", genai.APIError{Code: 429}), KindRateLimited},
+		{fmt.Errorf("search: %w", genai.APIError{Code: 503}), KindUnavailable},
+		{fmt.Errorf("%w: no current chat", ErrUnavailable), KindUnavailable},
+		{context.DeadlineExceeded, KindUnavailable},
+		{errors.New("dial postgres://gryag:secret@db:5432: connection refused"), KindInternal},
+	}
+	for _, tt := range tests {
+		if got := errorKind(tt.err); got != tt.want {
+			t.Errorf("errorKind(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestExecutor_ErrorsAreSafe(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "en.json"), []byte(`{
+		"tool.error.invalid_args": "{0}: bad arguments ({1})",
+		"tool.internal_error": "{0} failed"
+	}`), 0644)
+	bundle, err := i18n.NewBundle(dir, "en")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	e := NewExecutor(cfg, nil, bundle, nil, nil)
+
+	res := e.Execute(context.Background(), "time_info", json.RawMessage(`{}`))
+	if res.ErrorKind != KindInvalidArgs || res.Error != "time_info: bad arguments (invalid arguments: location is required)" {
+		t.Errorf("unexpected invalid-args result: %q (%s)", res.Error, res.ErrorKind)
+	}
+
+	leak := errors.New("open /var/lib/gryag/secret.db: permission denied")
+	if msg := e.errorMessage(context.Background(), "search_messages", errorKind(leak), leak); msg != "search_messages failed" || strings.Contains(msg, "/var/lib") {
+		t.Errorf("internal details must not reach the model, got %q", msg)
+	}
+}
@@ -3,13 +3,20 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
 	"github.com/ThatHunky/gryag/backend/internal/i18n"
 	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
 )
 
 // Executor dispatches tool calls from the LLM to their concrete implementations.
@@ -17,60 +24,125 @@ type Executor struct {
 	memory    *MemoryTool
 	imageGen  *ImageGenTool
 	sandbox   *SandboxTool
+	timeInfo  *TimeInfoTool
 	db        *db.DB
 	config    *config.Config
 	i18n      *i18n.Bundle
 	lang      string
-	llmClient *llm.Client // optional; used for search_web (Gemini Grounding)
+	llmClient *llm.Client         // optional; used for search_web (Gemini Grounding)
+	settings  *chatsettings.Store // optional; used for switch_persona
+	cache     *cache.Cache        // optional; proactive queue for deep_research progress and results
+	audit     AuditStore          // optional; tool_calls audit of every invocation
+	external  []ExternalTools     // optional; webhook and MCP server tools, first match wins
+}
+
+// ExternalTools runs tools implemented outside the backend (*WebhookTools, *mcp.Manager).
+type ExternalTools interface {
+	Has(name string) bool
+	Call(ctx context.Context, name string, args json.RawMessage) (string, error)
 }
 
 // NewExecutor creates a new tool executor with all implementations wired up.
 // llmClient can be nil; when set, it is used for the search_web tool (Gemini Grounding).
-func NewExecutor(cfg *config.Config, database *db.DB, bundle *i18n.Bundle, llmClient *llm.Client) *Executor {
+// settings can be nil; when set, it backs the switch_persona tool.
+func NewExecutor(cfg *config.Config, database *db.DB, bundle *i18n.Bundle, llmClient *llm.Client, settings *chatsettings.Store) *Executor {
 	return &Executor{
 		memory:    NewMemoryTool(database, bundle, cfg.DefaultLang),
 		imageGen:  NewImageGenTool(cfg, database),
 		sandbox:   NewSandboxTool(cfg),
+		timeInfo:  NewTimeInfoTool(bundle, cfg.DefaultLang),
 		db:        database,
 		config:    cfg,
 		i18n:      bundle,
 		lang:      cfg.DefaultLang,
 		llmClient: llmClient,
+		settings:  settings,
 	}
 }
 
+// SetCache wires the Redis cache used by background tools (deep_research posts through the
+// proactive queue). Without it those tools are unavailable.
+func (e *Executor) SetCache(c *cache.Cache) {
+	e.cache = c
+}
+
+// AddExternalTools routes calls to tools registered with Registry.RegisterExternal. Sources are
+// asked in the order they were added, which should match the order they were registered in.
+func (e *Executor) AddExternalTools(x ExternalTools) {
+	e.external = append(e.external, x)
+}
+
 // ToolResult holds the result of a tool execution.
 type ToolResult struct {
 	Name   string `json:"name"`
 	Output string `json:"output"`
-	Error  string `json:"error,omitempty"`
+	Error  string `json:"error,omitempty"` // safe, localized text for the model
+	// ErrorKind classifies Error: invalid_args, not_found, forbidden, rate_limited, unavailable,
+	// external (an MCP tool reported an error), unknown_tool or internal
+	ErrorKind string `json:"error_kind,omitempty"`
 }
 
-// t is a helper for translation within the executor.
-func (e *Executor) t(key string, args ...string) string {
+// t is a helper for translation within the executor, in the request's language.
+func (e *Executor) t(ctx context.Context, key string, args ...string) string {
 	if e.i18n == nil {
 		return key
 	}
-	return e.i18n.T(e.lang, key, args...)
+	return e.i18n.T(requestLanguage(ctx, e.lang), key, args...)
+}
+
+// tn is t for a pluralized key with count n (see i18n.Bundle.TN).
+func (e *Executor) tn(ctx context.Context, key string, n int, args ...string) string {
+	if e.i18n == nil {
+		return key
+	}
+	return e.i18n.TN(requestLanguage(ctx, e.lang), key, n, args...)
 }
 
 // Execute runs a tool by name with the given arguments (JSON).
 // Each tool execution is wrapped in an isolated error boundary (Section 15.3).
 func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessage) *ToolResult {
-	logger := slog.With("tool", name)
-	logger.Info("executing tool", "args_length", len(args))
+	ctx = logging.WithTool(ctx, name)
+	slog.InfoContext(ctx, "executing tool", "args_length", len(args))
 
 	result := &ToolResult{Name: name}
 
+	// Audit every call, including unknown tools, panics and timeouts (runs after the recovery below)
+	start := time.Now()
+	timedOut := false
+	defer func() { e.recordCall(ctx, name, args, start, result, timedOut) }()
+
+	if d := time.Duration(e.config.ToolTimeoutSeconds) * time.Second; d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+
 	// Recover from panics — feature isolation per Section 15.3
 	defer func() {
 		if r := recover(); r != nil {
-			logger.Error("tool panicked", "panic", r)
-			result.Error = e.t("tool.internal_error", name)
+			slog.ErrorContext(ctx, "tool panicked", "panic", r)
+			result.Error = e.t(ctx, "tool.internal_error", name)
+			result.ErrorKind = KindInternal
 			result.Output = ""
 		}
 	}()
 
+	// Per-chat toggles: a hidden tool stays off even if the model calls it anyway
+	if requestToolDisabled(ctx, name) {
+		result.Error = e.t(ctx, "tool.disabled", name)
+		result.ErrorKind = KindForbidden
+		return result
+	}
+
+	// REDIS_FAIL_CLOSED_TOOLS: while Redis is down the limits in front of these are per instance at
+	// best, so expensive tools are refused rather than risk a stampede
+	if e.cache.Degraded() && slices.Contains(e.config.RedisFailClosedTools, name) {
+		slog.WarnContext(ctx, "tool refused while redis is unavailable")
+		result.Error = e.t(ctx, "tool.degraded", name)
+		result.ErrorKind = KindUnavailable
+		return result
+	}
+
 	var output string
 	var err error
 
@@ -80,15 +152,27 @@ func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessag
 		output, err = e.memory.RecallMemories(ctx, args)
 	case "remember_memory":
 		output, err = e.memory.RememberMemory(ctx, args)
+	case "update_memory":
+		output, err = e.memory.UpdateMemory(ctx, args)
 	case "forget_memory":
 		output, err = e.memory.ForgetMemory(ctx, args)
+	case "set_global_memory":
+		output, err = e.memory.SetGlobalMemory(ctx, args)
+	case "set_timezone":
+		output, err = e.setTimezone(ctx, args)
+	case "remember_refusal":
+		output, err = e.memory.RememberRefusal(ctx, args)
+
+	// Time, daylight and holidays (embedded data, no network)
+	case "time_info":
+		output, err = e.timeInfo.Lookup(ctx, args)
 
 	// Web search (Gemini Grounding)
 	case "search_web":
 		if !e.config.EnableWebSearch {
-			output = e.t("tool.unknown", name)
+			output = e.t(ctx, "tool.unknown", name)
 		} else if e.llmClient == nil {
-			output = e.t("tool.search_web_not_configured")
+			output = e.t(ctx, "tool.search_web_not_configured")
 		} else {
 			var params struct {
 				Query string `json:"query"`
@@ -102,48 +186,78 @@ func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessag
 			}
 		}
 
+	// Multi-search research, runs in the background
+	case "deep_research":
+		if !e.config.EnableWebSearch || !e.config.EnableDeepResearch || e.llmClient == nil || e.cache == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.startDeepResearch(ctx, args)
+		}
+
+	// YouTube videos (Gemini video understanding)
+	case "watch_video":
+		if !e.config.EnableWatchVideo || e.llmClient == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.watchVideo(ctx, args)
+		}
+
 	// Message search
 	case "search_messages":
-		var params struct {
-			ChatID int64  `json:"chat_id"`
-			Query  string `json:"query"`
-			Limit  int    `json:"limit"`
+		output, err = e.searchMessages(ctx, args)
+	case "get_message_context":
+		output, err = e.getMessageContext(ctx, args)
+	case "get_thread":
+		output, err = e.getThread(ctx, args)
+	case "get_chat_stats":
+		output, err = e.getChatStats(ctx, args)
+
+	// Chat notes
+	case "save_note":
+		output, err = e.saveNote(ctx, args)
+	case "list_notes":
+		output, err = e.listNotes(ctx, args)
+	case "delete_note":
+		output, err = e.deleteNote(ctx, args)
+
+	// On-demand chat summary
+	case "summarize_recent":
+		if e.llmClient == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.summarizeRecent(ctx, args)
 		}
-		if jsonErr := json.Unmarshal(args, &params); jsonErr == nil {
-			if params.Limit == 0 {
-				params.Limit = 10
-			}
-			results, searchErr := e.db.SearchMessages(ctx, params.ChatID, params.Query, params.Limit)
-			if searchErr != nil {
-				err = searchErr
-			} else if len(results) == 0 {
-				output = e.t("search.no_results")
-			} else {
-				type searchEntry struct {
-					Text      string  `json:"text,omitempty"`
-					From      string  `json:"from"`
-					FileID    string  `json:"file_id,omitempty"`
-					MediaType string  `json:"media_type,omitempty"`
-					Link      string  `json:"message_link,omitempty"`
-					Rank      float64 `json:"relevance"`
-				}
-				entries := make([]searchEntry, len(results))
-				for i, r := range results {
-					e := searchEntry{Rank: r.Rank, Link: r.MessageLink}
-					if r.Text != nil { e.Text = *r.Text }
-					if r.FirstName != nil { e.From = *r.FirstName }
-					if r.Username != nil { e.From += " (@" + *r.Username + ")" }
-					if r.FileID != nil { e.FileID = *r.FileID }
-					if r.MediaType != nil { e.MediaType = *r.MediaType }
-					entries[i] = e
-				}
-				data, _ := json.Marshal(entries)
-				output = string(data)
-			}
+
+	// Translation with the chat's glossary
+	case "translate":
+		if e.llmClient == nil {
+			output = e.t(ctx, "tool.unknown", name)
 		} else {
-			err = jsonErr
+			output, err = e.translate(ctx, args)
+		}
+	case "set_glossary_term":
+		output, err = e.setGlossaryTerm(ctx, args)
+
+	// Voice notes and audio files
+	case "summarize_audio":
+		if e.llmClient == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.summarizeAudio(ctx, args)
+		}
+
+	// Text in screenshots and photos (OCR)
+	case "extract_text":
+		if e.llmClient == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.extractText(ctx, args)
 		}
 
+	// Message deletion (executed by the frontend)
+	case "request_delete":
+		output, err = e.requestDelete(ctx, args)
+
 	// Calculator — evaluated via sandbox for safety
 	case "calculator":
 		var params struct {
@@ -160,13 +274,13 @@ func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessag
 	// Image generation
 	case "generate_image":
 		if !e.config.EnableImageGeneration {
-			output = e.t("image.disabled")
+			output = e.t(ctx, "image.disabled")
 		} else {
 			output, err = e.imageGen.GenerateImage(ctx, args)
 		}
 	case "edit_image":
 		if !e.config.EnableImageGeneration {
-			output = e.t("image.disabled")
+			output = e.t(ctx, "image.disabled")
 		} else {
 			output, err = e.imageGen.EditImage(ctx, args)
 		}
@@ -174,21 +288,98 @@ func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessag
 	// Code sandbox
 	case "run_python_code":
 		if !e.config.EnableSandbox {
-			output = e.t("sandbox.disabled")
+			output = e.t(ctx, "sandbox.disabled")
 		} else {
 			output, err = e.sandbox.RunPythonCode(ctx, codeArgs(args))
 		}
 
+	// Persona switching
+	case "switch_persona":
+		if !e.config.EnablePersonaSwitch || e.settings == nil {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.switchPersona(ctx, args)
+		}
+
+	// Karma
+	case "get_karma", "karma_leaderboard":
+		if !e.config.EnableKarma {
+			output = e.t(ctx, "tool.unknown", name)
+		} else if name == "get_karma" {
+			output, err = e.getKarma(ctx, args)
+		} else {
+			output, err = e.karmaLeaderboard(ctx, args)
+		}
+
+	// Games
+	case "roll_dice":
+		if !e.config.EnableGames {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.rollDice(ctx, args)
+		}
+	case "russian_roulette", "trivia_question", "game_scores":
+		switch {
+		case !e.config.EnableGames || e.cache == nil:
+			output = e.t(ctx, "tool.unknown", name)
+		case name == "russian_roulette":
+			output, err = e.russianRoulette(ctx, args)
+		case name == "trivia_question":
+			output, err = e.triviaQuestion(ctx, args)
+		default:
+			output, err = e.gameScores(ctx, args)
+		}
+
+	// Proactive topic hints
+	case "add_chat_topic":
+		if !e.config.EnableProactiveMessaging {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.addChatTopic(ctx, args)
+		}
+
+	// Past conversations from the topic index
+	case "recall_topic":
+		if !e.config.EnableTopicIndex {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.recallTopic(ctx, args)
+		}
+
+	// Weekly personal digest opt-in
+	case "set_personal_digest":
+		if !e.config.EnablePersonalDigest {
+			output = e.t(ctx, "tool.unknown", name)
+		} else {
+			output, err = e.setPersonalDigest(ctx, args)
+		}
+
 	default:
-		result.Error = e.t("tool.unknown", name)
+		// Webhook and MCP server tools (built-in names always win)
+		i := slices.IndexFunc(e.external, func(x ExternalTools) bool { return x.Has(name) })
+		if i < 0 {
+			result.Error = e.t(ctx, "tool.unknown", name)
+			result.ErrorKind = KindUnknownTool
+			return result
+		}
+		output, err = e.external[i].Call(ctx, name, args)
+	}
+
+	// A tool cut off by its deadline tells the model so, instead of failing silently.
+	if ctx.Err() == context.DeadlineExceeded && (err != nil || output == "") {
+		slog.WarnContext(ctx, "tool timed out", "error", err)
+		result.Output = e.t(ctx, "tool.timeout", name)
+		timedOut = true
 		return result
 	}
 
 	if err != nil {
-		logger.Error("tool execution failed", "error", err)
-		result.Error = err.Error()
+		// The full error is logged only; the model gets a classified, localized message
+		result.ErrorKind = errorKind(err)
+		slog.ErrorContext(ctx, "tool execution failed", "error", err, "kind", result.ErrorKind)
+		result.Error = e.errorMessage(ctx, name, result.ErrorKind, err)
 	} else {
-		result.Output = output
+		result.Output = e.fitOutput(ctx, name, output)
 	}
 
 	return result
@@ -198,3 +389,32 @@ func (e *Executor) Execute(ctx context.Context, name string, args json.RawMessag
 func codeArgs(args json.RawMessage) json.RawMessage {
 	return args
 }
+
+// switchPersona sets the chat's active persona; unknown names return the available ones.
+func (e *Executor) switchPersona(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		ChatID  int64  `json:"chat_id"`
+		Persona string `json:"persona"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	name := strings.ToLower(strings.TrimSpace(params.Persona))
+	if params.ChatID == 0 || name == "" {
+		return "", fmt.Errorf("%w: chat_id and persona are required", ErrInvalidArgs)
+	}
+
+	err := e.settings.SetActivePersona(ctx, params.ChatID, name)
+	if errors.Is(err, chatsettings.ErrUnknownPersona) {
+		names, listErr := e.settings.PersonaNames(ctx)
+		if listErr != nil {
+			return "", listErr
+		}
+		return e.t(ctx, "persona.unknown", name, strings.Join(names, ", ")), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	slog.InfoContext(ctx, "persona switched", "chat_id", params.ChatID, "persona", name)
+	return e.t(ctx, "persona.switched", name), nil
+}
@@ -3,10 +3,15 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
 	"testing"
+	"time"
 
+	"github.com/ThatHunky/gryag/backend/internal/cache"
 	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/mcp"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestExecutor_UnknownTool(t *testing.T) {
@@ -14,7 +19,7 @@ func TestExecutor_UnknownTool(t *testing.T) {
 	defer os.Unsetenv("GEMINI_API_KEY")
 	cfg, _ := config.Load()
 
-	executor := NewExecutor(cfg, nil, nil, nil)
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
 	result := executor.Execute(context.Background(), "nonexistent_tool", json.RawMessage(`{}`))
 
 	if result.Error == "" {
@@ -31,7 +36,7 @@ func TestExecutor_DisabledSandbox(t *testing.T) {
 	}()
 	cfg, _ := config.Load()
 
-	executor := NewExecutor(cfg, nil, nil, nil)
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
 	args := json.RawMessage(`{"code": "print('hello')"}`)
 	result := executor.Execute(context.Background(), "run_python_code", args)
 
@@ -53,7 +58,7 @@ func TestExecutor_DisabledImageGen(t *testing.T) {
 	}()
 	cfg, _ := config.Load()
 
-	executor := NewExecutor(cfg, nil, nil, nil)
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
 	args := json.RawMessage(`{"prompt": "a cat wearing a hat"}`)
 	result := executor.Execute(context.Background(), "generate_image", args)
 
@@ -65,3 +70,107 @@ func TestExecutor_DisabledImageGen(t *testing.T) {
 	}
 }
 
+
+func TestExecutor_SwitchPersonaDisabled(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	result := executor.Execute(context.Background(), "switch_persona", json.RawMessage(`{"chat_id": 1, "persona": "polite"}`))
+	if result.Output != "tool.unknown" {
+		t.Errorf("expected unknown tool output when disabled, got %q / %q", result.Output, result.Error)
+	}
+}
+
+func TestExecutor_ToolTimeout(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	os.Setenv("SANDBOX_MAX_CONCURRENT", "1")
+	defer func() {
+		os.Unsetenv("GEMINI_API_KEY")
+		os.Unsetenv("SANDBOX_MAX_CONCURRENT")
+	}()
+	cfg, _ := config.Load()
+
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	// Occupy the only sandbox slot so the call waits until its deadline.
+	if err := executor.sandbox.slots.Acquire(context.Background(), 0); err != nil {
+		t.Fatal(err)
+	}
+	defer executor.sandbox.slots.Release()
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	result := executor.Execute(ctx, "run_python_code", json.RawMessage(`{"code": "print(1)"}`))
+	if result.Error != "" || result.Output != "tool.timeout" {
+		t.Errorf("expected the timeout message, got output %q error %q", result.Output, result.Error)
+	}
+}
+
+func TestExecutor_DisabledForChat(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	ctx := context.WithValue(context.Background(), RequestDisabledToolsKey, []string{"time_info"})
+	result := executor.Execute(ctx, "time_info", json.RawMessage(`{"location": "Kyiv"}`))
+	if result.Error != "tool.disabled" || result.ErrorKind != KindForbidden || result.Output != "" {
+		t.Errorf("expected the disabled tool to be refused, got %+v", result)
+	}
+}
+
+func TestExecutor_RedisFailClosed(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+	cfg.RedisFailClosedTools = []string{"time_info"}
+
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer client.Close()
+	c := cache.Wrap(client)
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	executor.SetCache(c)
+
+	ctx := context.Background()
+	if result := executor.Execute(ctx, "time_info", json.RawMessage(`{"location": "Kyiv"}`)); result.Error != "" {
+		t.Fatalf("expected the tool to run while Redis is up, got %+v", result)
+	}
+	if c.Ping(ctx) == nil {
+		t.Skip("something is listening on port 1")
+	}
+	result := executor.Execute(ctx, "time_info", json.RawMessage(`{"location": "Kyiv"}`))
+	if result.Error != "tool.degraded" || result.ErrorKind != KindUnavailable {
+		t.Errorf("expected the tool refused while Redis is down, got %+v", result)
+	}
+}
+
+type fakeExternal map[string]string
+
+func (f fakeExternal) Has(name string) bool { _, ok := f[name]; return ok }
+
+func (f fakeExternal) Call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	if f[name] == "" {
+		return "", fmt.Errorf("mcp home/%s: %w", name, &mcp.ToolError{Text: "device offline"})
+	}
+	return f[name], nil
+}
+
+func TestExecutor_ExternalTools(t *testing.T) {
+	os.Setenv("GEMINI_API_KEY", "test-key")
+	defer os.Unsetenv("GEMINI_API_KEY")
+	cfg, _ := config.Load()
+
+	executor := NewExecutor(cfg, nil, nil, nil, nil)
+	executor.AddExternalTools(fakeExternal{"home_lights": "on", "home_door": "", "time_info": "shadowed"})
+
+	if result := executor.Execute(context.Background(), "home_lights", json.RawMessage(`{}`)); result.Output != "on" || result.Error != "" {
+		t.Errorf("expected the external tool to run, got %+v", result)
+	}
+	if result := executor.Execute(context.Background(), "home_door", json.RawMessage(`{}`)); result.ErrorKind != KindExternal {
+		t.Errorf("expected an external error, got %+v", result)
+	}
+	if result := executor.Execute(context.Background(), "time_info", json.RawMessage(`{"location": "Kyiv"}`)); result.Output == "shadowed" {
+		t.Error("built-in tools must win over external ones")
+	}
+}
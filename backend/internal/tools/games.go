@@ -0,0 +1,191 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/games"
+)
+
+const (
+	// revolverKey and triviaKey hold a chat topic's game state in Redis.
+	revolverKey = "game:roulette:%d:%d"
+	triviaKey   = "game:trivia:%d:%d"
+
+	revolverTTL = 24 * time.Hour   // an untouched revolver is put away
+	triviaTTL   = 15 * time.Minute // an unanswered question expires
+
+	maxTriviaAnswers  = 10
+	maxGameScoresList = 10
+)
+
+// rollDice runs roll_dice.
+func (e *Executor) rollDice(_ context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Dice string `json:"dice"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	roll, err := games.RollDice(params.Dice, rand.IntN)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(roll)
+	return string(data), nil
+}
+
+// gameTopic returns the chat and topic of the request; games are played per topic.
+func gameTopic(ctx context.Context) (chatID, threadID, userID int64, err error) {
+	chatID, userID = requestChatID(ctx), requestUserID(ctx)
+	if chatID == 0 || userID == 0 {
+		return 0, 0, 0, fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	return chatID, requestThreadID(ctx), userID, nil
+}
+
+// russianRoulette runs russian_roulette: the requesting user pulls the trigger of the topic's revolver.
+func (e *Executor) russianRoulette(ctx context.Context, _ json.RawMessage) (string, error) {
+	chatID, threadID, userID, err := gameTopic(ctx)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf(revolverKey, chatID, threadID)
+	var r games.Revolver
+	if ok, err := e.cache.GetJSON(ctx, key, &r); err != nil {
+		return "", err
+	} else if !ok || r.Left == 0 {
+		r = games.NewRevolver(rand.IntN)
+	}
+	bang := r.Pull()
+	if bang {
+		err = e.cache.Delete(ctx, key)
+	} else {
+		err = e.cache.SetJSON(ctx, key, r, revolverTTL)
+	}
+	if err != nil {
+		return "", err
+	}
+	wins, losses := 1, 0
+	if bang {
+		wins, losses = 0, 1
+	}
+	score, err := e.db.AddGameScore(ctx, chatID, userID, db.GameRoulette, wins, losses)
+	if err != nil {
+		return "", err
+	}
+	result := map[string]any{"survived_total": score.Wins, "shot_total": score.Losses}
+	if bang {
+		result["result"] = "bang"
+		result["note"] = "the revolver is reloaded and spun for the next player"
+	} else {
+		result["result"] = "click"
+		result["chambers_left"] = r.Left
+	}
+	data, _ := json.Marshal(result)
+	return string(data), nil
+}
+
+// triviaQuestion runs trivia_question: ask stores a question with its accepted answers, answer
+// checks the requesting user's guess (a right one scores and closes the question), reveal closes it.
+func (e *Executor) triviaQuestion(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Action   string   `json:"action"`
+		Question string   `json:"question"`
+		Answers  []string `json:"answers"`
+		Answer   string   `json:"answer"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID, threadID, userID, err := gameTopic(ctx)
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf(triviaKey, chatID, threadID)
+	var open games.Trivia
+	hasOpen, err := e.cache.GetJSON(ctx, key, &open)
+	if err != nil {
+		return "", err
+	}
+	reply := func(v map[string]any) (string, error) {
+		data, _ := json.Marshal(v)
+		return string(data), nil
+	}
+
+	switch params.Action {
+	case "ask":
+		if hasOpen {
+			return reply(map[string]any{"status": "already_open", "question": open.Question})
+		}
+		q := games.Trivia{Question: strings.TrimSpace(params.Question), AskedBy: userID}
+		for _, a := range params.Answers {
+			if a = strings.TrimSpace(a); a != "" && len(q.Answers) < maxTriviaAnswers {
+				q.Answers = append(q.Answers, a)
+			}
+		}
+		if q.Question == "" || len(q.Answers) == 0 {
+			return "", fmt.Errorf("%w: question and at least one answer are required", ErrInvalidArgs)
+		}
+		if err := e.cache.SetJSON(ctx, key, q, triviaTTL); err != nil {
+			return "", err
+		}
+		return reply(map[string]any{"status": "asked", "expires_in_minutes": int(triviaTTL.Minutes())})
+
+	case "answer":
+		if !hasOpen {
+			return reply(map[string]any{"status": "no_open_question"})
+		}
+		if !open.Check(params.Answer) {
+			return reply(map[string]any{"status": "wrong"})
+		}
+		if err := e.cache.Delete(ctx, key); err != nil {
+			return "", err
+		}
+		score, err := e.db.AddGameScore(ctx, chatID, userID, db.GameTrivia, 1, 0)
+		if err != nil {
+			return "", err
+		}
+		return reply(map[string]any{"status": "correct", "answers": open.Answers, "user_total": score.Wins})
+
+	case "reveal":
+		if !hasOpen {
+			return reply(map[string]any{"status": "no_open_question"})
+		}
+		if err := e.cache.Delete(ctx, key); err != nil {
+			return "", err
+		}
+		return reply(map[string]any{"status": "revealed", "question": open.Question, "answers": open.Answers})
+
+	default:
+		return "", fmt.Errorf("%w: action must be ask, answer or reveal", ErrInvalidArgs)
+	}
+}
+
+// gameScores runs game_scores: the best players of a game in the current chat.
+func (e *Executor) gameScores(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Game string `json:"game"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	if params.Game != db.GameTrivia && params.Game != db.GameRoulette {
+		return "", fmt.Errorf("%w: game must be trivia or roulette", ErrInvalidArgs)
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	scores, err := e.db.GetGameScores(ctx, chatID, params.Game, maxGameScoresList)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{"game": params.Game, "scores": scores})
+	return string(data), nil
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
 	"google.golang.org/genai"
 )
 
@@ -48,15 +49,16 @@ func (ig *ImageGenTool) GenerateImage(ctx context.Context, args json.RawMessage)
 	if params.AsDocument {
 		mediaType = "document"
 	}
-	slog.Info("generating image", "prompt_length", len(params.Prompt), "aspect_ratio", params.AspectRatio, "as_document", params.AsDocument)
+	slog.InfoContext(ctx, "generating image", "prompt_length", len(params.Prompt), "aspect_ratio", params.AspectRatio, "as_document", params.AsDocument)
 
 	if ig.config.GeminiAPIKey == "" {
 		return "Image generation is not configured. Set GEMINI_API_KEY.", nil
 	}
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  ig.config.GeminiAPIKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     ig.config.GeminiAPIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: logging.NewHTTPClient(),
 	})
 	if err != nil {
 		return "", fmt.Errorf("genai client: %w", err)
@@ -67,7 +69,7 @@ func (ig *ImageGenTool) GenerateImage(ctx context.Context, args json.RawMessage)
 		if allowedAspectRatios[params.AspectRatio] {
 			genConfig.ImageConfig = &genai.ImageConfig{AspectRatio: params.AspectRatio}
 		} else {
-			slog.Warn("ignoring unsupported aspect_ratio", "aspect_ratio", params.AspectRatio)
+			slog.WarnContext(ctx, "ignoring unsupported aspect_ratio", "aspect_ratio", params.AspectRatio)
 		}
 	}
 
@@ -147,8 +149,9 @@ func (ig *ImageGenTool) EditImage(ctx context.Context, args json.RawMessage) (st
 	}
 
 	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  ig.config.GeminiAPIKey,
-		Backend: genai.BackendGeminiAPI,
+		APIKey:     ig.config.GeminiAPIKey,
+		Backend:    genai.BackendGeminiAPI,
+		HTTPClient: logging.NewHTTPClient(),
 	})
 	if err != nil {
 		return "", fmt.Errorf("genai client: %w", err)
@@ -187,3 +190,21 @@ func (ig *ImageGenTool) EditImage(ctx context.Context, args json.RawMessage) (st
 	}
 	return "API returned no image data", nil
 }
+
+// ImageOutput is the image a generate_image or edit_image call produced.
+type ImageOutput struct {
+	MediaBase64 string `json:"media_base64"`
+	MediaType   string `json:"media_type"` // "photo" or "document"
+}
+
+// ParseImageOutput extracts the image from a successful generate_image or edit_image output.
+func ParseImageOutput(output string) (ImageOutput, bool) {
+	var img ImageOutput
+	if err := json.Unmarshal([]byte(output), &img); err != nil || img.MediaBase64 == "" {
+		return ImageOutput{}, false
+	}
+	if img.MediaType == "" {
+		img.MediaType = "photo"
+	}
+	return img, true
+}
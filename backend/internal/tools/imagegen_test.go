@@ -81,3 +81,18 @@ func TestEditImage_ParsesAspectRatio(t *testing.T) {
 		t.Errorf("unexpected output: %s", out)
 	}
 }
+
+func TestParseImageOutput(t *testing.T) {
+	img, ok := ParseImageOutput(`{"media_base64": "aGk=", "media_type": "document"}`)
+	if !ok || img.MediaBase64 != "aGk=" || img.MediaType != "document" {
+		t.Errorf("unexpected %+v, %v", img, ok)
+	}
+	if img, ok = ParseImageOutput(`{"media_base64": "aGk="}`); !ok || img.MediaType != "photo" {
+		t.Errorf("expected photo by default, got %+v", img)
+	}
+	for _, out := range []string{"Image generation is not configured. Set GEMINI_API_KEY.", `{"media_type": "photo"}`} {
+		if _, ok := ParseImageOutput(out); ok {
+			t.Errorf("expected no image in %q", out)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	defaultKarmaLeaderboard = 10
+	maxKarmaLeaderboard     = 25
+)
+
+// getKarma runs get_karma: a user's karma and rank in the current chat (the requesting user by default).
+func (e *Executor) getKarma(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	userID := params.UserID
+	if userID == 0 {
+		userID = requestUserID(ctx)
+	}
+	if userID == 0 {
+		return "", fmt.Errorf("%w: user_id is required", ErrInvalidArgs)
+	}
+	entry, err := e.db.GetKarma(ctx, chatID, userID)
+	if err != nil {
+		return "", err
+	}
+	if entry == nil {
+		data, _ := json.Marshal(map[string]any{"user_id": userID, "score": 0, "note": "no votes yet"})
+		return string(data), nil
+	}
+	data, _ := json.Marshal(entry)
+	return string(data), nil
+}
+
+// karmaLeaderboard runs karma_leaderboard: the users with the most (or least) karma in the current chat.
+func (e *Executor) karmaLeaderboard(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Limit  int  `json:"limit"`
+		Lowest bool `json:"lowest"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultKarmaLeaderboard
+	}
+	entries, err := e.db.GetKarmaLeaderboard(ctx, chatID, min(limit, maxKarmaLeaderboard), params.Lowest)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{"leaderboard": entries})
+	return string(data), nil
+}
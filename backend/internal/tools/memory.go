@@ -3,14 +3,17 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/db"
 	"github.com/ThatHunky/gryag/backend/internal/i18n"
 )
 
-// MemoryTool handles recall_memories, remember_memory, forget_memory operations.
+// MemoryTool handles recall_memories, remember_memory, update_memory, forget_memory operations.
 type MemoryTool struct {
 	db   *db.DB
 	i18n *i18n.Bundle
@@ -22,15 +25,15 @@ func NewMemoryTool(database *db.DB, bundle *i18n.Bundle, lang string) *MemoryToo
 	return &MemoryTool{db: database, i18n: bundle, lang: lang}
 }
 
-// t is a shorthand for translation.
-func (m *MemoryTool) t(key string, args ...string) string {
+// t is a shorthand for translation in the request's language.
+func (m *MemoryTool) t(ctx context.Context, key string, args ...string) string {
 	if m.i18n == nil {
 		return key
 	}
-	return m.i18n.T(m.lang, key, args...)
+	return m.i18n.T(requestLanguage(ctx, m.lang), key, args...)
 }
 
-// RecallMemories retrieves all stored facts for a user in a chat.
+// RecallMemories retrieves all stored facts for a user in a chat, plus their global facts if they opted in.
 func (m *MemoryTool) RecallMemories(ctx context.Context, args json.RawMessage) (string, error) {
 	var params struct {
 		UserID int64 `json:"user_id"`
@@ -44,48 +47,157 @@ func (m *MemoryTool) RecallMemories(ctx context.Context, args json.RawMessage) (
 	if err != nil {
 		return "", fmt.Errorf("get user facts: %w", err)
 	}
+	if global, err := m.db.HasGlobalMemory(ctx, params.UserID); err != nil {
+		return "", fmt.Errorf("get global memory setting: %w", err)
+	} else if global && params.ChatID != db.GlobalFactsChatID {
+		globalFacts, err := m.db.GetUserFacts(ctx, db.GlobalFactsChatID, params.UserID)
+		if err != nil {
+			return "", fmt.Errorf("get global facts: %w", err)
+		}
+		facts = append(facts, globalFacts...)
+	}
 
 	if len(facts) == 0 {
-		return m.t("memory.none"), nil
+		return m.t(ctx, "memory.none"), nil
 	}
 
 	type memoryEntry struct {
-		ID   int64  `json:"memory_id"`
-		Text string `json:"memory_text"`
+		ID         int64  `json:"memory_id"`
+		Text       string `json:"memory_text"`
+		Category   string `json:"category"`
+		Importance int    `json:"importance"`
+		Scope      string `json:"scope"`
 	}
 
 	entries := make([]memoryEntry, len(facts))
+	ids := make([]int64, len(facts))
 	for i, f := range facts {
-		entries[i] = memoryEntry{ID: f.ID, Text: f.FactText}
+		entries[i] = memoryEntry{ID: f.ID, Text: f.FactText, Category: f.Category, Importance: f.Importance, Scope: factScope(f.ChatID)}
+		ids[i] = f.ID
+	}
+	if err := m.db.TouchUserFacts(ctx, ids); err != nil {
+		slog.WarnContext(ctx, "touch recalled memories failed", "error", err)
 	}
 
 	result, _ := json.Marshal(entries)
-	slog.Info("recalled memories", "user_id", params.UserID, "count", len(facts))
+	slog.InfoContext(ctx, "recalled memories", "user_id", params.UserID, "count", len(facts))
 	return string(result), nil
 }
 
-// RememberMemory stores a new fact about a user.
+// Memory scopes accepted by remember_memory.
+const (
+	scopeChat   = "chat"
+	scopeGlobal = "global"
+)
+
+// factScope names the scope of a fact stored under chatID.
+func factScope(chatID int64) string {
+	if chatID == db.GlobalFactsChatID {
+		return scopeGlobal
+	}
+	return scopeChat
+}
+
+// RememberMemory stores a new fact about a user. A near-identical existing fact
+// is updated in place instead, so rewordings don't pile up as duplicates.
+// scope "global" stores it for every chat, but only for users who opted in.
 func (m *MemoryTool) RememberMemory(ctx context.Context, args json.RawMessage) (string, error) {
 	var params struct {
 		UserID     int64  `json:"user_id"`
 		ChatID     int64  `json:"chat_id"`
 		MemoryText string `json:"memory_text"`
+		Category   string `json:"category"`
+		Importance int    `json:"importance"`
+		Scope      string `json:"scope"`
 	}
 	if err := json.Unmarshal(args, &params); err != nil {
 		return "", fmt.Errorf("parse args: %w", err)
 	}
 
-	id, err := m.db.InsertUserFact(ctx, params.ChatID, params.UserID, params.MemoryText)
+	if params.Scope == scopeGlobal {
+		global, err := m.db.HasGlobalMemory(ctx, params.UserID)
+		if err != nil {
+			return "", fmt.Errorf("get global memory setting: %w", err)
+		}
+		if !global {
+			return m.t(ctx, "memory.global_not_enabled"), nil
+		}
+		// The fact comes from this chat, so its off-the-record window still applies.
+		if off, err := m.db.IsOffRecord(ctx, params.ChatID, time.Now()); err != nil {
+			return "", err
+		} else if off {
+			return m.t(ctx, "memory.off_record"), nil
+		}
+		params.ChatID = db.GlobalFactsChatID
+	}
+
+	facts, err := m.db.GetUserFacts(ctx, params.ChatID, params.UserID)
+	if err != nil {
+		return "", fmt.Errorf("get user facts: %w", err)
+	}
+	if existing, ok := db.FindNearDuplicateFact(facts, params.MemoryText); ok {
+		if db.SameFactText(existing.FactText, params.MemoryText) {
+			return m.t(ctx, "memory.duplicate"), nil
+		}
+		if _, err := m.db.UpdateUserFact(ctx, existing.ID, params.MemoryText); err != nil {
+			if errors.Is(err, db.ErrDuplicateFact) {
+				return m.t(ctx, "memory.duplicate"), nil
+			}
+			if errors.Is(err, db.ErrOffRecord) {
+				return m.t(ctx, "memory.off_record"), nil
+			}
+			return "", fmt.Errorf("update fact: %w", err)
+		}
+		slog.InfoContext(ctx, "updated near-duplicate memory", "user_id", params.UserID, "fact_id", existing.ID)
+		return m.t(ctx, "memory.updated", fmt.Sprintf("%d", existing.ID)), nil
+	}
+
+	id, err := m.db.InsertUserFact(ctx, params.ChatID, params.UserID, params.MemoryText,
+		db.NormalizeFactCategory(params.Category), db.ClampImportance(params.Importance))
+	if errors.Is(err, db.ErrOffRecord) {
+		return m.t(ctx, "memory.off_record"), nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("insert fact: %w", err)
 	}
 
 	if id == 0 {
-		return m.t("memory.duplicate"), nil
+		return m.t(ctx, "memory.duplicate"), nil
 	}
 
-	slog.Info("stored memory", "user_id", params.UserID, "fact_id", id)
-	return m.t("memory.stored", fmt.Sprintf("%d", id)), nil
+	slog.InfoContext(ctx, "stored memory", "user_id", params.UserID, "fact_id", id)
+	return m.t(ctx, "memory.stored", fmt.Sprintf("%d", id)), nil
+}
+
+// UpdateMemory replaces the text of an existing memory (a correction).
+func (m *MemoryTool) UpdateMemory(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		MemoryID   int64  `json:"memory_id"`
+		MemoryText string `json:"memory_text"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	if params.MemoryID == 0 || strings.TrimSpace(params.MemoryText) == "" {
+		return "", fmt.Errorf("%w: memory_id and memory_text are required", ErrInvalidArgs)
+	}
+
+	found, err := m.db.UpdateUserFact(ctx, params.MemoryID, params.MemoryText)
+	if errors.Is(err, db.ErrDuplicateFact) {
+		return m.t(ctx, "memory.duplicate"), nil
+	}
+	if errors.Is(err, db.ErrOffRecord) {
+		return m.t(ctx, "memory.off_record"), nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("update fact: %w", err)
+	}
+	if !found {
+		return m.t(ctx, "memory.not_found", fmt.Sprintf("%d", params.MemoryID)), nil
+	}
+
+	slog.InfoContext(ctx, "updated memory", "memory_id", params.MemoryID)
+	return m.t(ctx, "memory.updated", fmt.Sprintf("%d", params.MemoryID)), nil
 }
 
 // ForgetMemory deletes a specific memory by ID.
@@ -101,6 +213,66 @@ func (m *MemoryTool) ForgetMemory(ctx context.Context, args json.RawMessage) (st
 		return "", fmt.Errorf("delete fact: %w", err)
 	}
 
-	slog.Info("forgot memory", "memory_id", params.MemoryID)
-	return m.t("memory.forgotten", fmt.Sprintf("%d", params.MemoryID)), nil
+	slog.InfoContext(ctx, "forgot memory", "memory_id", params.MemoryID)
+	return m.t(ctx, "memory.forgotten", fmt.Sprintf("%d", params.MemoryID)), nil
+}
+
+// maxOfferRunes bounds a stored refusal so it stays a short label, not a transcript.
+const maxOfferRunes = 80
+
+// normalizeOffer lowercases and trims an offer label so "Image generation." and "image generation" match.
+func normalizeOffer(offer string) string {
+	offer = strings.ToLower(strings.Join(strings.Fields(offer), " "))
+	offer = strings.Trim(offer, " .,!?;:\"'«»")
+	if r := []rune(offer); len(r) > maxOfferRunes {
+		offer = string(r[:maxOfferRunes])
+	}
+	return offer
+}
+
+// RememberRefusal records that a user declined an offer, so it is not suggested to them again.
+func (m *MemoryTool) RememberRefusal(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		UserID int64  `json:"user_id"`
+		Offer  string `json:"offer"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	offer := normalizeOffer(params.Offer)
+	if params.UserID == 0 || offer == "" {
+		return "", fmt.Errorf("%w: user_id and offer are required", ErrInvalidArgs)
+	}
+
+	if err := m.db.RecordRefusal(ctx, params.UserID, offer); err != nil {
+		return "", fmt.Errorf("record refusal: %w", err)
+	}
+
+	slog.InfoContext(ctx, "stored refusal", "user_id", params.UserID, "offer", offer)
+	return m.t(ctx, "refusal.stored", offer), nil
+}
+
+// SetGlobalMemory turns cross-chat memories on or off for the user who sent the message.
+// It never acts on anyone else: opting in is the user's own privacy choice.
+func (m *MemoryTool) SetGlobalMemory(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Enabled bool `json:"enabled"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	userID := requestUserID(ctx)
+	if userID == 0 {
+		return "", fmt.Errorf("%w: no requesting user", ErrUnavailable)
+	}
+
+	if err := m.db.SetGlobalMemory(ctx, userID, params.Enabled); err != nil {
+		return "", fmt.Errorf("set global memory: %w", err)
+	}
+
+	slog.InfoContext(ctx, "global memory setting changed", "enabled", params.Enabled)
+	if params.Enabled {
+		return m.t(ctx, "memory.global_enabled"), nil
+	}
+	return m.t(ctx, "memory.global_disabled"), nil
 }
@@ -0,0 +1,67 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeOffer(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Image generation.", "image generation"},
+		{"  news   DIGEST ", "news digest"},
+		{"«Генерація картинок»", "генерація картинок"},
+		{"?!", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeOffer(tt.in); got != tt.want {
+			t.Errorf("normalizeOffer(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("я", 200)
+	if got := []rune(normalizeOffer(long)); len(got) != maxOfferRunes {
+		t.Errorf("expected %d runes, got %d", maxOfferRunes, len(got))
+	}
+}
+
+func TestMemoryTool_RememberRefusalValidation(t *testing.T) {
+	m := NewMemoryTool(nil, nil, "en")
+	for _, args := range []string{`{"user_id":0,"offer":"x"}`, `{"user_id":1,"offer":"  . "}`, `not json`} {
+		if _, err := m.RememberRefusal(context.Background(), []byte(args)); err == nil {
+			t.Errorf("expected error for %s", args)
+		}
+	}
+}
+
+func TestMemoryTool_UpdateMemoryValidation(t *testing.T) {
+	m := NewMemoryTool(nil, nil, "en")
+	for _, args := range []string{`{"memory_id":0,"memory_text":"x"}`, `{"memory_id":1,"memory_text":"  "}`, `not json`} {
+		if _, err := m.UpdateMemory(context.Background(), []byte(args)); err == nil {
+			t.Errorf("expected error for %s", args)
+		}
+	}
+}
+
+func TestMemoryTool_SetGlobalMemoryRequiresSender(t *testing.T) {
+	m := NewMemoryTool(nil, nil, "en")
+	// Without the requesting user in ctx the tool must refuse rather than guess whose setting to change.
+	if _, err := m.SetGlobalMemory(context.Background(), []byte(`{"enabled":true}`)); err == nil {
+		t.Error("expected error without a requesting user")
+	}
+	ctx := context.WithValue(context.Background(), RequestUserIDKey, int64(42))
+	if _, err := m.SetGlobalMemory(ctx, []byte(`not json`)); err == nil {
+		t.Error("expected error for bad args")
+	}
+}
+
+func TestFactScope(t *testing.T) {
+	if got := factScope(0); got != scopeGlobal {
+		t.Errorf("factScope(0) = %q, want global", got)
+	}
+	if got := factScope(-100123); got != scopeChat {
+		t.Errorf("factScope(chat) = %q, want chat", got)
+	}
+}
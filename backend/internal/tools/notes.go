@@ -0,0 +1,150 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	maxNoteTitleLen = 100
+	maxNoteBodyLen  = 2000
+	// maxChatNotes bounds a chat's notes; past it, old notes must be deleted first.
+	maxChatNotes     = 200
+	defaultNotesList = 20
+	maxNotesList     = 50
+)
+
+// canDeleteNote reports whether requester may delete a note: its author or an admin.
+func canDeleteNote(requester int64, n *db.ChatNote, admins []int64) bool {
+	if slices.Contains(admins, requester) {
+		return true
+	}
+	return requester != 0 && n.AuthorID != nil && *n.AuthorID == requester
+}
+
+// saveNote runs save_note: stores a note for the current chat, updating the one with the same title.
+func (e *Executor) saveNote(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID, userID := requestChatID(ctx), requestUserID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	n := &db.ChatNote{
+		ChatID: chatID,
+		Title:  truncateRunes(strings.TrimSpace(params.Title), maxNoteTitleLen),
+		Body:   truncateRunes(strings.TrimSpace(params.Body), maxNoteBodyLen),
+	}
+	if n.Title == "" || n.Body == "" {
+		return "", fmt.Errorf("%w: title and body are required", ErrInvalidArgs)
+	}
+	existing, err := e.db.GetChatNote(ctx, chatID, 0, n.Title)
+	if err != nil {
+		return "", err
+	}
+	if existing == nil {
+		count, err := e.db.CountChatNotes(ctx, chatID)
+		if err != nil {
+			return "", err
+		}
+		if count >= maxChatNotes {
+			return "", fmt.Errorf("this chat already has %d notes; delete some first", count)
+		}
+	}
+	if userID != 0 {
+		n.AuthorID = &userID
+		if _, first, err := e.db.GetUserHandle(ctx, userID); err == nil && first != "" {
+			n.AuthorName = &first
+		}
+	}
+	id, created, err := e.db.SaveChatNote(ctx, n)
+	if err != nil {
+		return "", err
+	}
+	status := "updated"
+	if created {
+		status = "saved"
+	}
+	data, _ := json.Marshal(map[string]any{"status": status, "id": id, "title": n.Title})
+	return string(data), nil
+}
+
+// listNotes runs list_notes: the current chat's notes, optionally matching a query.
+func (e *Executor) listNotes(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultNotesList
+	}
+	notes, err := e.db.ListChatNotes(ctx, chatID, strings.TrimSpace(params.Query), min(limit, maxNotesList))
+	if err != nil {
+		return "", err
+	}
+	type noteEntry struct {
+		ID      int64  `json:"id"`
+		Title   string `json:"title"`
+		Body    string `json:"body"`
+		Author  string `json:"author,omitempty"`
+		Updated string `json:"updated"`
+	}
+	entries := make([]noteEntry, len(notes))
+	for i, n := range notes {
+		entries[i] = noteEntry{ID: n.ID, Title: n.Title, Body: n.Body, Updated: n.UpdatedAt.Format("2006-01-02")}
+		if n.AuthorName != nil {
+			entries[i].Author = *n.AuthorName
+		}
+	}
+	data, _ := json.Marshal(map[string]any{"notes": entries})
+	return string(data), nil
+}
+
+// deleteNote runs delete_note: removes a note of the current chat by id or title, if the user
+// wrote it or is an admin.
+func (e *Executor) deleteNote(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		ID    int64  `json:"id"`
+		Title string `json:"title"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	n, err := e.db.GetChatNote(ctx, chatID, params.ID, strings.TrimSpace(params.Title))
+	if err != nil {
+		return "", err
+	}
+	if n == nil {
+		return `{"status":"not_found"}`, nil
+	}
+	if !canDeleteNote(requestUserID(ctx), n, e.config.AdminIDs) {
+		return `{"status":"not_allowed","reason":"only the note's author or a bot admin can delete it"}`, nil
+	}
+	if _, err := e.db.DeleteChatNote(ctx, chatID, n.ID); err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{"status": "deleted", "id": n.ID, "title": n.Title})
+	return string(data), nil
+}
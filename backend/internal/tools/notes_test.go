@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestCanDeleteNote(t *testing.T) {
+	author := int64(5)
+	note := &db.ChatNote{AuthorID: &author}
+	admins := []int64{1}
+	tests := []struct {
+		requester int64
+		note      *db.ChatNote
+		want      bool
+	}{
+		{5, note, true},
+		{1, note, true},
+		{7, note, false},
+		{0, note, false},
+		{7, &db.ChatNote{}, false},
+		{1, &db.ChatNote{}, true},
+	}
+	for _, tt := range tests {
+		if got := canDeleteNote(tt.requester, tt.note, admins); got != tt.want {
+			t.Errorf("canDeleteNote(%d, %+v) = %v, want %v", tt.requester, tt.note, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxExtractedText bounds the text extract_text returns.
+const maxExtractedText = 8000
+
+// extractText runs extract_text: copies the text out of the image attached to the current
+// message, or of a generated image (media_id), keeping its layout.
+func (e *Executor) extractText(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		MediaID string `json:"media_id"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	data, mimeType, err := e.ocrSource(ctx, strings.TrimSpace(params.MediaID))
+	if err != nil {
+		return "", err
+	}
+	text, err := e.llmClient.ExtractText(ctx, data, mimeType)
+	if err != nil {
+		return "", err
+	}
+	out, _ := json.Marshal(map[string]any{
+		"text":      truncateRunes(text, maxExtractedText),
+		"no_text":   text == "",
+		"truncated": utf8.RuneCountInString(text) > maxExtractedText,
+	})
+	return string(out), nil
+}
+
+// ocrSource returns the image extract_text reads: the media_cache entry when mediaID is set (only
+// from the current chat), else the current message's image or PDF.
+func (e *Executor) ocrSource(ctx context.Context, mediaID string) ([]byte, string, error) {
+	if mediaID != "" {
+		entry, err := e.db.GetMediaCacheByID(ctx, mediaID)
+		if err != nil {
+			return nil, "", err
+		}
+		if entry == nil || (requestChatID(ctx) != 0 && entry.ChatID != requestChatID(ctx)) {
+			return nil, "", fmt.Errorf("%w: media_id is expired or unknown", ErrNotFound)
+		}
+		data, err := os.ReadFile(entry.FilePath)
+		if err != nil {
+			return nil, "", fmt.Errorf("read cached image: %w", err)
+		}
+		return data, http.DetectContentType(data), nil
+	}
+	b64, _ := ctx.Value(RequestMediaBase64Key).(string)
+	mimeType, _ := ctx.Value(RequestMediaMimeTypeKey).(string)
+	if b64 == "" || !(strings.HasPrefix(mimeType, "image/") || mimeType == "application/pdf") {
+		return nil, "", fmt.Errorf("%w: the current message has no image or PDF; pass media_id for a generated image", ErrInvalidArgs)
+	}
+	data, err := base64.StdEncoding.DecodeString(b64)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode image: %w", err)
+	}
+	return data, mimeType, nil
+}
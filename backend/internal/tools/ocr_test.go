@@ -0,0 +1,29 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOCRSource_ContextMedia(t *testing.T) {
+	e := &Executor{}
+	with := func(b64, mimeType string) context.Context {
+		return context.WithValue(context.WithValue(context.Background(), RequestMediaBase64Key, b64), RequestMediaMimeTypeKey, mimeType)
+	}
+	data, mimeType, err := e.ocrSource(with("cG5n", "image/png"), "")
+	if err != nil || string(data) != "png" || mimeType != "image/png" {
+		t.Errorf("unexpected source %q %q, %v", data, mimeType, err)
+	}
+	if _, _, err := e.ocrSource(with("JVBERg==", "application/pdf"), ""); err != nil {
+		t.Errorf("expected a PDF to be accepted, got %v", err)
+	}
+	for name, ctx := range map[string]context.Context{
+		"no media":   context.Background(),
+		"voice note": with("b2dn", "audio/ogg"),
+	} {
+		if _, _, err := e.ocrSource(ctx, ""); !errors.Is(err, ErrInvalidArgs) {
+			t.Errorf("%s: expected invalid args, got %v", name, err)
+		}
+	}
+}
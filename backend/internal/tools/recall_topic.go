@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+const (
+	defaultRecallTopics = 5
+	maxRecallTopics     = 10
+)
+
+// recallTopic runs recall_topic: the past conversations of the current chat topic (every topic
+// with all_topics) about query, from the topic index, most relevant first.
+func (e *Executor) recallTopic(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Query     string `json:"query"`
+		Limit     int    `json:"limit"`
+		AllTopics bool   `json:"all_topics"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	if strings.TrimSpace(params.Query) == "" {
+		return "", fmt.Errorf("%w: query is required", ErrInvalidArgs)
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultRecallTopics
+	}
+	limit = min(limit, maxRecallTopics)
+	threadID := requestThreadID(ctx)
+	if params.AllTopics {
+		threadID = db.AllThreads
+	}
+	segments, err := e.db.SearchTopicSegments(ctx, chatID, threadID, params.Query, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(segments) == 0 {
+		return e.t(ctx, "topics.no_results"), nil
+	}
+
+	type topicEntry struct {
+		Title    string   `json:"title"`
+		Summary  string   `json:"summary,omitempty"`
+		Keywords []string `json:"keywords,omitempty"`
+		From     string   `json:"from"`
+		To       string   `json:"to"`
+		Messages int      `json:"messages"`
+		FirstID  int64    `json:"first_id"`
+	}
+	loc := e.location(ctx, chatID)
+	entries := make([]topicEntry, len(segments))
+	for i, s := range segments {
+		entries[i] = topicEntry{
+			Title:    s.Title,
+			Summary:  s.Summary,
+			Keywords: s.Keywords,
+			From:     s.StartedAt.In(loc).Format("2006-01-02 15:04"),
+			To:       s.EndedAt.In(loc).Format("2006-01-02 15:04"),
+			Messages: s.MessageCount,
+			FirstID:  s.FirstMessageID,
+		}
+	}
+	data, _ := json.Marshal(entries)
+	return string(data), nil
+}
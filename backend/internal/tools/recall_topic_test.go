@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+func TestRecallTopic_Validation(t *testing.T) {
+	cfg := loadTestConfig(t)
+	cfg.EnableTopicIndex = true
+	e := NewExecutor(cfg, nil, nil, nil, nil)
+
+	res := e.Execute(context.Background(), "recall_topic", json.RawMessage(`{"query": "відпустка"}`))
+	if res.ErrorKind != KindUnavailable {
+		t.Errorf("without a chat: kind = %q (%s), want %q", res.ErrorKind, res.Error, KindUnavailable)
+	}
+
+	ctx := context.WithValue(context.Background(), RequestChatIDKey, int64(-100))
+	res = e.Execute(ctx, "recall_topic", json.RawMessage(`{"query": "  "}`))
+	if res.ErrorKind != KindInvalidArgs {
+		t.Errorf("empty query: kind = %q (%s), want %q", res.ErrorKind, res.Error, KindInvalidArgs)
+	}
+
+	cfg.EnableTopicIndex = false
+	res = e.Execute(ctx, "recall_topic", json.RawMessage(`{"query": "відпустка"}`))
+	if res.Output != "tool.unknown" {
+		t.Errorf("disabled: output = %q, want the unknown tool message", res.Output)
+	}
+}
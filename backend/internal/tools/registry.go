@@ -1,6 +1,10 @@
 package tools
 
 import (
+	"slices"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
 	"google.golang.org/genai"
 )
@@ -41,11 +45,47 @@ func NewRegistry(cfg *config.Config) *Registry {
 				"user_id":     {Type: genai.TypeInteger, Description: "Telegram user ID"},
 				"chat_id":     {Type: genai.TypeInteger, Description: "Telegram chat ID"},
 				"memory_text": {Type: genai.TypeString, Description: "The fact or memory to store about the user"},
+				"category": {
+					Type:        genai.TypeString,
+					Enum:        []string{"preference", "bio", "event", "joke"},
+					Description: "Optional. preference = likes/habits, bio = who they are (job, city, family), event = something that happened or is planned, joke = running gags/nicknames. Default bio.",
+				},
+				"importance": {Type: genai.TypeInteger, Description: "Optional. 1 (trivia) to 5 (core to who they are). Only the most important facts are always in context. Default 3."},
+				"scope": {
+					Type:        genai.TypeString,
+					Enum:        []string{"chat", "global"},
+					Description: "Optional. chat = only this chat (default). global = remembered in every chat; only for users who turned on global memory, and only for things about the person themselves, never chat-specific gossip.",
+				},
 			},
 			Required: []string{"user_id", "chat_id", "memory_text"},
 		},
 	})
 
+	r.register("set_global_memory", &genai.FunctionDeclaration{
+		Name:        "set_global_memory",
+		Description: "Turn cross-chat memory on or off for the user who sent the current message, when THEY explicitly ask (e.g. 'remember me in other chats too', 'stop remembering me everywhere'). Never call it on someone else's behalf. Turning it off deletes their global memories.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"enabled": {Type: genai.TypeBoolean, Description: "true to opt in, false to opt out"},
+			},
+			Required: []string{"enabled"},
+		},
+	})
+
+	r.register("update_memory", &genai.FunctionDeclaration{
+		Name:        "update_memory",
+		Description: "Correct or refresh a stored memory in place (e.g. the user moved cities or changed jobs). MUST call recall_memories first to get the memory_id. Prefer this over forget_memory + remember_memory.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"memory_id":   {Type: genai.TypeInteger, Description: "The ID of the memory to update"},
+				"memory_text": {Type: genai.TypeString, Description: "The corrected fact, replacing the old text"},
+			},
+			Required: []string{"memory_id", "memory_text"},
+		},
+	})
+
 	r.register("forget_memory", &genai.FunctionDeclaration{
 		Name:        "forget_memory",
 		Description: "Delete a specific stored memory by ID. MUST call recall_memories first to get the memory_id.",
@@ -58,6 +98,44 @@ func NewRegistry(cfg *config.Config) *Registry {
 		},
 	})
 
+	r.register("remember_refusal", &genai.FunctionDeclaration{
+		Name:        "remember_refusal",
+		Description: "Record that the user declined something you offered or suggested (e.g. 'no, I don't need a picture'), so you stop offering it. Use a short generic label for the offer.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"user_id": {Type: genai.TypeInteger, Description: "Telegram user ID"},
+				"offer":   {Type: genai.TypeString, Description: "Short label of what was declined, e.g. 'image generation', 'running code', 'news digest'"},
+			},
+			Required: []string{"user_id", "offer"},
+		},
+	})
+
+	r.register("time_info", &genai.FunctionDeclaration{
+		Name:        "time_info",
+		Description: "Get the current local time, UTC offset, sunrise/sunset, and public holidays (today, tomorrow, upcoming) for a city or country. Use for 'котра година в Торонто', 'чи завтра вихідний', 'коли захід сонця' instead of guessing or searching the web.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"location": {Type: genai.TypeString, Description: "City or country name in any language (e.g. 'Київ', 'Toronto', 'Poland') or an IANA timezone (e.g. 'America/Toronto')"},
+				"date":     {Type: genai.TypeString, Description: "Optional. Date to check (YYYY-MM-DD) instead of today"},
+			},
+			Required: []string{"location"},
+		},
+	})
+
+	r.register("set_timezone", &genai.FunctionDeclaration{
+		Name:        "set_timezone",
+		Description: "Store the timezone of the user who sent the current message, when THEY say where they live or which zone they are in (e.g. 'я тепер у Торонто', 'my timezone is CET'). Their current time and the dates they mention are then read in that zone instead of the chat's. Never call it on someone else's behalf.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"timezone": {Type: genai.TypeString, Description: "City or country name in any language (e.g. 'Торонто', 'Poland') or an IANA timezone (e.g. 'America/Toronto'). Empty string to go back to the chat's timezone."},
+			},
+			Required: []string{"timezone"},
+		},
+	})
+
 	r.register("calculator", &genai.FunctionDeclaration{
 		Name:        "calculator",
 		Description: "Perform mathematical calculations.",
@@ -72,15 +150,170 @@ func NewRegistry(cfg *config.Config) *Registry {
 
 	r.register("search_messages", &genai.FunctionDeclaration{
 		Name:        "search_messages",
-		Description: "Search through chat message history. Returns matching messages with links and file IDs for media. Use this to recall what someone said or find a specific message/photo/video. You can include the message link in your reply so the user can jump to it.",
+		Description: "Search through chat message history. Returns matching messages with links, send times and file IDs for media. Use this to recall what someone said or find a specific message/photo/video; narrow by sender, dates and media type (e.g. the photo Olena sent last week: from_user, after, media_type=photo). You can include the message link in your reply so the user can jump to it.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"chat_id":    {Type: genai.TypeInteger, Description: "Telegram chat ID to search in"},
+				"query":      {Type: genai.TypeString, Description: "Search query (words to find in messages). Optional when a filter is given"},
+				"limit":      {Type: genai.TypeInteger, Description: "Max results to return (default 10, max 50)"},
+				"all_topics": {Type: genai.TypeBoolean, Description: "Optional. In forum groups, search every topic instead of only the current one"},
+				"from_user":  {Type: genai.TypeString, Description: "Optional. Only messages from this sender: @username, first name or user ID"},
+				"after":      {Type: genai.TypeString, Description: "Optional. Only messages sent at or after this date/time: 2006-01-02, 2006-01-02 15:04 (chat's timezone) or RFC 3339"},
+				"before":     {Type: genai.TypeString, Description: "Optional. Only messages sent before this date/time, same formats as after"},
+				"media_type": {Type: genai.TypeString, Description: "Optional. Only messages with this media: photo, video, document, voice, video_note, sticker, animation, or any"},
+			},
+			Required: []string{"chat_id"},
+		},
+	})
+
+	r.register("get_message_context", &genai.FunctionDeclaration{
+		Name:        "get_message_context",
+		Description: "Read the conversation around a message found with search_messages: the messages just before and after it in the same topic. Use it before quoting or retelling what was said, so you don't take a single line out of context.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"id":     {Type: genai.TypeInteger, Description: "The id of a search_messages result"},
+				"before": {Type: genai.TypeInteger, Description: "Optional. Messages before it (default 5, max 20)"},
+				"after":  {Type: genai.TypeInteger, Description: "Optional. Messages after it (default 5, max 20)"},
+			},
+			Required: []string{"id"},
+		},
+	})
+
+	r.register("get_thread", &genai.FunctionDeclaration{
+		Name:        "get_thread",
+		Description: "Read a whole reply thread: the chain of replies a message continues, up to where it started, and every reply below it, oldest first. Use it when a message replies to something you can't see (\"replying to an earlier message, id N\") or a long back-and-forth started before the recent messages.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"message_id": {Type: genai.TypeInteger, Description: "Telegram message id, e.g. the N of \"replying to an earlier message, id N\""},
+				"id":         {Type: genai.TypeInteger, Description: "Or the id of a search_messages result"},
+				"limit":      {Type: genai.TypeInteger, Description: "Optional. Max messages (default 30, max 100)"},
+			},
+		},
+	})
+
+	r.register("get_chat_stats", &genai.FunctionDeclaration{
+		Name:        "get_chat_stats",
+		Description: "Activity statistics of this chat: messages per user, the most active hours, top emoji and top words over the last days. Use it for questions like 'хто найбільше пише?' or 'коли тут найактивніше?' and quote the real numbers.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"days": {Type: genai.TypeInteger, Description: "Optional. Period in days (default 7, max 90)"},
+			},
+		},
+	})
+
+	r.register("save_note", &genai.FunctionDeclaration{
+		Name:        "save_note",
+		Description: "Save a note for this chat when users ask you to write something down ('запиши, що оренда — 12-го числа'): dates, codes, agreements, lists. Saving under an existing title replaces that note. For facts about a person use remember_memory instead.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"title": {Type: genai.TypeString, Description: "Short title to find it by, e.g. 'оренда'"},
+				"body":  {Type: genai.TypeString, Description: "The note itself"},
+			},
+			Required: []string{"title", "body"},
+		},
+	})
+
+	r.register("list_notes", &genai.FunctionDeclaration{
+		Name:        "list_notes",
+		Description: "List this chat's saved notes, recently updated first, to answer questions like 'коли платимо оренду?'.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"query": {Type: genai.TypeString, Description: "Optional. Only notes whose title or text contains this"},
+				"limit": {Type: genai.TypeInteger, Description: "Optional. Max notes (default 20, max 50)"},
+			},
+		},
+	})
+
+	r.register("delete_note", &genai.FunctionDeclaration{
+		Name:        "delete_note",
+		Description: "Delete one of this chat's notes by id or title. Only its author or a bot admin can delete it.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"id":    {Type: genai.TypeInteger, Description: "Note id from list_notes"},
+				"title": {Type: genai.TypeString, Description: "Or the note's title"},
+			},
+		},
+	})
+
+	r.register("summarize_recent", &genai.FunctionDeclaration{
+		Name:        "summarize_recent",
+		Description: "Summarize what was said in this chat (current forum topic) over a recent window. Use when a user asks what they missed, e.g. 'що я пропустив за день?'. Defaults to the last 24 hours; at most 7 days.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"chat_id": {Type: genai.TypeInteger, Description: "Telegram chat ID to summarize"},
+				"hours":   {Type: genai.TypeInteger, Description: "Optional. Window length in hours (added to days)"},
+				"days":    {Type: genai.TypeInteger, Description: "Optional. Window length in days (max 7)"},
+			},
+			Required: []string{"chat_id"},
+		},
+	})
+
+	r.register("translate", &genai.FunctionDeclaration{
+		Name:        "translate",
+		Description: "Translate text faithfully into another language (e.g. 'переклади на англійську', 'what does this Polish message say?'). Runs without your persona at temperature 0 and applies the chat's glossary for names and slang. Quote the returned translation as is; do not rewrite it in your own style.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"text":   {Type: genai.TypeString, Description: "The exact text to translate (max 4000 characters)"},
+				"source": {Type: genai.TypeString, Description: "Optional. Source language code (e.g. 'uk'); omit to detect"},
+				"target": {Type: genai.TypeString, Description: "Target language code (e.g. 'en', 'pl', 'de')"},
+			},
+			Required: []string{"text", "target"},
+		},
+	})
+
+	r.register("summarize_audio", &genai.FunctionDeclaration{
+		Name:        "summarize_audio",
+		Description: "Transcribe and summarize the voice note or audio file attached to the current message, however long (e.g. 'шо він там наговорив?', 'дай коротко'). Returns the transcript and a summary in the reply language; the transcript is saved so search_messages finds it later. Only works on audio attached to the message you are answering.",
+		Parameters: &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: map[string]*genai.Schema{},
+		},
+	})
+
+	r.register("extract_text", &genai.FunctionDeclaration{
+		Name:        "extract_text",
+		Description: "Copy the text out of the screenshot, photo or PDF attached to the current message exactly as written, keeping its layout (e.g. 'скопіюй текст зі скріна', 'перепиши що там написано'). Use instead of describing the image whenever the user needs the actual text. Quote the returned text as is.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"media_id": {Type: genai.TypeString, Description: "Optional. media_id of an image you generated or edited earlier, instead of the attached one. Never show it to the user"},
+			},
+		},
+	})
+
+	r.register("set_glossary_term", &genai.FunctionDeclaration{
+		Name:        "set_glossary_term",
+		Description: "Add, change or remove how translate renders a name or slang word in this chat, when a user asks (e.g. 'перекладай \"Гряг\" як \"Gryag\"'). The glossary is shared by the whole chat.",
 		Parameters: &genai.Schema{
 			Type: genai.TypeObject,
 			Properties: map[string]*genai.Schema{
-				"chat_id": {Type: genai.TypeInteger, Description: "Telegram chat ID to search in"},
-				"query":   {Type: genai.TypeString, Description: "Search query (words to find in messages)"},
-				"limit":   {Type: genai.TypeInteger, Description: "Max results to return (default 10, max 50)"},
+				"term":        {Type: genai.TypeString, Description: "The name or word as written in the source text"},
+				"translation": {Type: genai.TypeString, Description: "How to render it; empty string removes the entry"},
+				"lang":        {Type: genai.TypeString, Description: "Optional. Target language code the entry is for; omit for every language"},
 			},
-			Required: []string{"chat_id", "query"},
+			Required: []string{"term", "translation"},
+		},
+	})
+
+	r.register("request_delete", &genai.FunctionDeclaration{
+		Name:        "request_delete",
+		Description: "Delete a message in this chat when a user asks, e.g. to remove one of your own replies they found offensive or a message they sent by mistake. Users may delete your replies and their own messages; only admins may delete other people's messages. The deletion is carried out after your reply is sent.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"message_id": {Type: genai.TypeInteger, Description: "Telegram message_id to delete (e.g. of the message the user replied to)"},
+				"reason":     {Type: genai.TypeString, Description: "Optional. Short reason, kept in the audit log"},
+			},
+			Required: []string{"message_id"},
 		},
 	})
 
@@ -98,6 +331,35 @@ func NewRegistry(cfg *config.Config) *Registry {
 		})
 	}
 
+	if cfg.EnableWebSearch && cfg.EnableDeepResearch {
+		r.register("deep_research", &genai.FunctionDeclaration{
+			Name:        "deep_research",
+			Description: "Research a question that needs more than one web search (comparisons, overviews, 'what's known about...', fact-checking with several sources). Runs several searches in the background and posts a sourced answer to the chat in a minute or two; tell the user it is on its way. Use search_web instead for simple lookups.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"question": {Type: genai.TypeString, Description: "The full research question, with any context needed to understand it on its own"},
+				},
+				Required: []string{"question"},
+			},
+		})
+	}
+
+	if cfg.EnableWatchVideo {
+		r.register("watch_video", &genai.FunctionDeclaration{
+			Name:        "watch_video",
+			Description: "Watch a YouTube video and summarize it with timestamps, or answer a question about it. Use when someone shares a YouTube link and asks what it is about ('тлдр?', 'про що відео?', 'шо там на 5 хвилині?'). Public videos only.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"url":      {Type: genai.TypeString, Description: "The YouTube link (youtube.com/watch, youtu.be, shorts or live)"},
+					"question": {Type: genai.TypeString, Description: "Optional. A specific question to answer from the video instead of a general summary"},
+				},
+				Required: []string{"url"},
+			},
+		})
+	}
+
 	// Feature-toggled tools
 
 	if cfg.EnableImageGeneration {
@@ -145,6 +407,137 @@ func NewRegistry(cfg *config.Config) *Registry {
 		})
 	}
 
+	if cfg.EnablePersonaSwitch {
+		r.register("switch_persona", &genai.FunctionDeclaration{
+			Name:        "switch_persona",
+			Description: "Switch this chat to another named persona (personality/system prompt). Only call when users explicitly ask to change your mode or style. Use persona \"default\" to switch back; an unknown name returns the list of available personas.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"chat_id": {Type: genai.TypeInteger, Description: "Telegram chat ID"},
+					"persona": {Type: genai.TypeString, Description: "Persona name, e.g. \"default\" or \"polite-assistant\""},
+				},
+				Required: []string{"chat_id", "persona"},
+			},
+		})
+	}
+
+	if cfg.EnableKarma {
+		r.register("get_karma", &genai.FunctionDeclaration{
+			Name:        "get_karma",
+			Description: "A user's karma in this chat and their rank. Karma goes up and down with reactions and '+'/'-' replies to their messages.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"user_id": {Type: genai.TypeInteger, Description: "Optional. The user to look up; defaults to the user you are answering"},
+				},
+			},
+		})
+		r.register("karma_leaderboard", &genai.FunctionDeclaration{
+			Name:        "karma_leaderboard",
+			Description: "The karma leaderboard of this chat: users with the most karma, or the least with lowest=true.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"limit":  {Type: genai.TypeInteger, Description: "Optional. How many users (default 10, max 25)"},
+					"lowest": {Type: genai.TypeBoolean, Description: "Optional. List the lowest karma first"},
+				},
+			},
+		})
+	}
+
+	if cfg.EnableGames {
+		r.register("roll_dice", &genai.FunctionDeclaration{
+			Name:        "roll_dice",
+			Description: "Roll dice for the chat (games, decisions, 'кинь кубик'). Returns each die and the total; announce the result in your own voice.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"dice": {Type: genai.TypeString, Description: "Optional. Dice notation like 2d6, d20 or 3d8+2 (default 1d6)"},
+				},
+			},
+		})
+		r.register("russian_roulette", &genai.FunctionDeclaration{
+			Name:        "russian_roulette",
+			Description: "The user you are answering pulls the trigger of this chat's (toy) revolver: one bullet in six chambers, spun once, so each click makes the next pull riskier. Returns click or bang and their totals. Narrate it dramatically and in character, as a comic party game; keep it playful, no gore.",
+			Parameters: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{},
+			},
+		})
+		r.register("trivia_question", &genai.FunctionDeclaration{
+			Name:        "trivia_question",
+			Description: "Run a trivia round in this chat. action=ask: store a question you made up with all accepted answers (short, in the forms people would type, in each language the chat uses) before posting it; never reveal the answers. action=answer: check a user's guess when they answer (the user you are answering gets the point). action=reveal: give up and show the answers. Questions expire after 15 minutes.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"action":   {Type: genai.TypeString, Description: "ask, answer or reveal"},
+					"question": {Type: genai.TypeString, Description: "For ask: the question"},
+					"answers":  {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}, Description: "For ask: accepted answers, e.g. [\"Київ\", \"Kyiv\"]"},
+					"answer":   {Type: genai.TypeString, Description: "For answer: the user's guess as they wrote it"},
+				},
+				Required: []string{"action"},
+			},
+		})
+		r.register("game_scores", &genai.FunctionDeclaration{
+			Name:        "game_scores",
+			Description: "The chat's best players of a game: trivia (right answers) or roulette (survived pulls and shots).",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"game": {Type: genai.TypeString, Description: "trivia or roulette"},
+				},
+				Required: []string{"game"},
+			},
+		})
+	}
+
+	if cfg.EnableProactiveMessaging {
+		r.register("add_chat_topic", &genai.FunctionDeclaration{
+			Name:        "add_chat_topic",
+			Description: "Remember something this chat may want to hear about later, so you can bring it up on your own: an upcoming event (exam, trip, match), a running joke, or a follow-up ('ask how the interview went'). Use it when someone mentions a future plan or a recurring joke forms, not for facts about people (use remember_memory).",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"kind":   {Type: genai.TypeString, Enum: []string{"event", "joke", "follow_up"}, Description: "event, joke or follow_up"},
+					"text":   {Type: genai.TypeString, Description: "What to bring up, e.g. 'Olena's driving test' or 'ask Taras how the job interview went'"},
+					"due_at": {Type: genai.TypeString, Description: "Optional: not before this time, as 2006-01-02 or 2006-01-02 15:04 in the chat's local time (e.g. the day after the exam for a follow-up). Omit for running jokes."},
+				},
+				Required: []string{"kind", "text"},
+			},
+		})
+	}
+
+	if cfg.EnableTopicIndex {
+		r.register("recall_topic", &genai.FunctionDeclaration{
+			Name:        "recall_topic",
+			Description: "Find past conversations of this chat about a subject, from an index of topics: when it was discussed, for how long and a summary of what was said. Use it for 'коли ми востаннє говорили про відпустку?' or 'what did we decide about the trip?' instead of searching messages; use get_message_context with first_id to read how a conversation started. The newest hour or two may not be indexed yet.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"query":      {Type: genai.TypeString, Description: "Subject keywords in dictionary form, e.g. 'відпустка карпати'; any of them may match"},
+					"limit":      {Type: genai.TypeInteger, Description: "Optional. Max conversations (default 5, max 10)"},
+					"all_topics": {Type: genai.TypeBoolean, Description: "Optional. In forum groups, search every topic instead of only the current one"},
+				},
+				Required: []string{"query"},
+			},
+		})
+	}
+
+	if cfg.EnablePersonalDigest {
+		r.register("set_personal_digest", &genai.FunctionDeclaration{
+			Name:        "set_personal_digest",
+			Description: "Turn the weekly personal digest on or off for the user who sent the current message, when THEY ask (e.g. 'send me a weekly recap of what I missed'). The digest is a private message about their mentions and replies to them across group chats; they must have started a private chat with the bot to receive it. Never call it on someone else's behalf.",
+			Parameters: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"enabled": {Type: genai.TypeBoolean, Description: "true to opt in, false to opt out"},
+				},
+				Required: []string{"enabled"},
+			},
+		})
+	}
+
 	return r
 }
 
@@ -170,6 +563,34 @@ func (r *Registry) GetTools() []*genai.Tool {
 	}
 }
 
+// GetToolsForChat is the per-request view of the registry: the tools the chat's settings allow
+// (disabled_tools left out). Returns nil when nothing remains.
+func (r *Registry) GetToolsForChat(s *chatsettings.Settings) []*genai.Tool {
+	return r.GetToolsExcept(s.DisabledTools)
+}
+
+// GetToolsExcept returns the same tool group as GetTools without the named tools, in name
+// order (used for per-chat tool toggles and trace replays). Returns nil when nothing remains.
+func (r *Registry) GetToolsExcept(disabled []string) []*genai.Tool {
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+	var decls []*genai.FunctionDeclaration
+	for name, d := range r.tools {
+		if !skip[name] {
+			decls = append(decls, d)
+		}
+	}
+	if len(decls) == 0 {
+		return nil
+	}
+	slices.SortFunc(decls, func(a, b *genai.FunctionDeclaration) int { return strings.Compare(a.Name, b.Name) })
+	return []*genai.Tool{
+		{FunctionDeclarations: decls},
+	}
+}
+
 // GetToolNames returns the names of all registered tools (for building the tools block text).
 func (r *Registry) GetToolNames() []string {
 	names := make([]string, 0, len(r.tools))
@@ -195,7 +616,35 @@ func (r *Registry) HasTool(name string) bool {
 	return ok
 }
 
+// RegisterExternal adds tools implemented outside the backend (webhooks, MCP servers). Names already
+// registered are skipped. Returns the number added.
+func (r *Registry) RegisterExternal(decls []*genai.FunctionDeclaration) int {
+	n := 0
+	for _, d := range decls {
+		if !r.HasTool(d.Name) {
+			r.register(d.Name, d)
+			n++
+		}
+	}
+	return n
+}
+
 // Count returns the number of registered tools.
 func (r *Registry) Count() int {
 	return len(r.tools)
 }
+
+// readOnlyTools change no stored state; they are the only tools run in shadow mode.
+var readOnlyTools = map[string]bool{
+	"recall_memories": true, "time_info": true, "calculator": true, "search_messages": true,
+	"get_message_context": true, "get_thread": true, "get_chat_stats": true, "list_notes": true, "summarize_recent": true,
+	"translate": true, "extract_text": true, "search_web": true, "watch_video": true, "deep_research": true, "generate_image": true,
+	"edit_image": true, "run_python_code": true, "get_karma": true, "karma_leaderboard": true,
+	"game_scores": true, "recall_topic": true,
+}
+
+// ReadOnly reports whether the named tool leaves stored state (memories, notes, settings, games,
+// messages) untouched.
+func ReadOnly(name string) bool {
+	return readOnlyTools[name]
+}
@@ -2,9 +2,13 @@ package tools
 
 import (
 	"os"
+	"slices"
+	"strings"
 	"testing"
 
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
 	"github.com/ThatHunky/gryag/backend/internal/config"
+	"google.golang.org/genai"
 )
 
 func loadTestConfig(t *testing.T) *config.Config {
@@ -23,9 +27,9 @@ func TestRegistry_AllToolsRegistered(t *testing.T) {
 	r := NewRegistry(cfg)
 
 	// With defaults (sandbox + image gen + web search enabled), we expect:
-	// recall_memories, remember_memory, forget_memory, calculator,
-	// search_messages, search_web, generate_image, edit_image, run_python_code = 9
-	expected := 9
+	// recall_memories, remember_memory, update_memory, forget_memory, set_global_memory, remember_refusal,
+	// time_info, set_timezone, calculator, search_messages, get_message_context, get_thread, get_chat_stats, save_note, list_notes, delete_note, summarize_recent, translate, summarize_audio, extract_text, set_glossary_term, request_delete, search_web, generate_image, edit_image, run_python_code = 26
+	expected := 26
 	if r.Count() != expected {
 		t.Errorf("expected %d tools, got %d", expected, r.Count())
 		t.Logf("registered tools: %v", r.GetToolNames())
@@ -46,9 +50,9 @@ func TestRegistry_FeatureToggles(t *testing.T) {
 	r := NewRegistry(cfg)
 
 	// With sandbox + image gen disabled (web search still enabled by default), we expect:
-	// recall_memories, remember_memory, forget_memory, calculator,
-	// search_messages, search_web = 6
-	expected := 6
+	// recall_memories, remember_memory, update_memory, forget_memory, set_global_memory, remember_refusal,
+	// time_info, set_timezone, calculator, search_messages, get_message_context, get_thread, get_chat_stats, save_note, list_notes, delete_note, summarize_recent, translate, summarize_audio, extract_text, set_glossary_term, request_delete, search_web = 23
+	expected := 23
 	if r.Count() != expected {
 		t.Errorf("expected %d tools, got %d", expected, r.Count())
 		t.Logf("registered tools: %v", r.GetToolNames())
@@ -93,3 +97,167 @@ func TestRegistry_GetToolDescription(t *testing.T) {
 		t.Error("expected recall_memories to be registered")
 	}
 }
+
+func TestRegistry_GetToolsExcept(t *testing.T) {
+	cfg := loadTestConfig(t)
+	r := NewRegistry(cfg)
+
+	tools := r.GetToolsExcept([]string{"generate_image", "edit_image"})
+	if len(tools) != 1 {
+		t.Fatalf("expected 1 tool group, got %d", len(tools))
+	}
+	if got := len(tools[0].FunctionDeclarations); got != r.Count()-2 {
+		t.Errorf("expected %d declarations, got %d", r.Count()-2, got)
+	}
+	for _, d := range tools[0].FunctionDeclarations {
+		if d.Name == "generate_image" || d.Name == "edit_image" {
+			t.Errorf("%s should have been filtered out", d.Name)
+		}
+	}
+}
+
+func TestRegistry_GetToolsForChat(t *testing.T) {
+	cfg := loadTestConfig(t)
+	r := NewRegistry(cfg)
+
+	all := r.GetToolsForChat(&chatsettings.Settings{})
+	if len(all) != 1 || len(all[0].FunctionDeclarations) != r.Count() {
+		t.Fatalf("expected every tool without disabled ones, got %v", all)
+	}
+	decls := all[0].FunctionDeclarations
+	if !slices.IsSortedFunc(decls, func(a, b *genai.FunctionDeclaration) int { return strings.Compare(a.Name, b.Name) }) {
+		t.Error("expected the declarations in name order")
+	}
+
+	some := r.GetToolsForChat(&chatsettings.Settings{DisabledTools: []string{"generate_image", "no_such_tool"}})
+	if got := len(some[0].FunctionDeclarations); got != r.Count()-1 {
+		t.Errorf("expected %d declarations, got %d", r.Count()-1, got)
+	}
+}
+
+func TestRegistry_RegisterExternal(t *testing.T) {
+	cfg := loadTestConfig(t)
+	r := NewRegistry(cfg)
+	before := r.Count()
+
+	added := r.RegisterExternal([]*genai.FunctionDeclaration{{Name: "home_lights"}, {Name: "time_info"}})
+	if added != 1 || r.Count() != before+1 || !r.HasTool("home_lights") {
+		t.Errorf("expected only home_lights added, got %d", added)
+	}
+}
+
+func TestRegistry_PersonaSwitchToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("switch_persona") {
+		t.Error("switch_persona should be off by default")
+	}
+	cfg.EnablePersonaSwitch = true
+	if !NewRegistry(cfg).HasTool("switch_persona") {
+		t.Error("expected switch_persona when enabled")
+	}
+}
+
+func TestRegistry_DeepResearchToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("deep_research") {
+		t.Error("deep_research should be off by default")
+	}
+	cfg.EnableDeepResearch = true
+	if !NewRegistry(cfg).HasTool("deep_research") {
+		t.Error("expected deep_research when enabled")
+	}
+	cfg.EnableWebSearch = false
+	if NewRegistry(cfg).HasTool("deep_research") {
+		t.Error("deep_research needs web search")
+	}
+}
+
+func TestRegistry_WatchVideoToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("watch_video") {
+		t.Error("watch_video should be off by default")
+	}
+	cfg.EnableWatchVideo = true
+	if !NewRegistry(cfg).HasTool("watch_video") {
+		t.Error("expected watch_video when enabled")
+	}
+}
+
+func TestRegistry_ChatTopicToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("add_chat_topic") {
+		t.Error("add_chat_topic should be off without proactive messaging")
+	}
+	cfg.EnableProactiveMessaging = true
+	if !NewRegistry(cfg).HasTool("add_chat_topic") {
+		t.Error("expected add_chat_topic with proactive messaging")
+	}
+}
+
+func TestRegistry_PersonalDigestToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("set_personal_digest") {
+		t.Error("set_personal_digest should be off by default")
+	}
+	cfg.EnablePersonalDigest = true
+	if !NewRegistry(cfg).HasTool("set_personal_digest") {
+		t.Error("expected set_personal_digest when enabled")
+	}
+}
+
+func TestRegistry_KarmaToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if r := NewRegistry(cfg); r.HasTool("get_karma") || r.HasTool("karma_leaderboard") {
+		t.Error("karma tools should be off by default")
+	}
+	cfg.EnableKarma = true
+	if r := NewRegistry(cfg); !r.HasTool("get_karma") || !r.HasTool("karma_leaderboard") {
+		t.Error("expected karma tools when enabled")
+	}
+}
+
+func TestRegistry_TopicIndexToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	if NewRegistry(cfg).HasTool("recall_topic") {
+		t.Error("recall_topic should be off by default")
+	}
+	cfg.EnableTopicIndex = true
+	if !NewRegistry(cfg).HasTool("recall_topic") {
+		t.Error("expected recall_topic when the topic index is enabled")
+	}
+}
+
+func TestRegistry_GamesToggle(t *testing.T) {
+	cfg := loadTestConfig(t)
+	games := []string{"roll_dice", "russian_roulette", "trivia_question", "game_scores"}
+	r := NewRegistry(cfg)
+	for _, name := range games {
+		if r.HasTool(name) {
+			t.Errorf("%s should be off by default", name)
+		}
+	}
+	cfg.EnableGames = true
+	r = NewRegistry(cfg)
+	for _, name := range games {
+		if !r.HasTool(name) {
+			t.Errorf("expected %s when games are enabled", name)
+		}
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	cfg := loadTestConfig(t)
+	cfg.EnableDeepResearch, cfg.EnableKarma, cfg.EnableGames, cfg.EnableWatchVideo = true, true, true, true
+	cfg.EnableTopicIndex = true
+	r := NewRegistry(cfg)
+	for name := range readOnlyTools {
+		if !r.HasTool(name) {
+			t.Errorf("read-only tool %s is not registered", name)
+		}
+	}
+	for _, name := range []string{"remember_memory", "forget_memory", "save_note", "set_timezone", "request_delete", "roll_dice", "switch_persona", "summarize_audio"} {
+		if ReadOnly(name) {
+			t.Errorf("%s changes state and must not be read-only", name)
+		}
+	}
+}
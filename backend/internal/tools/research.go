@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+const (
+	// researchRunningKey allows one deep_research job per chat at a time.
+	researchRunningKey = "research:running:%d"
+	researchTimeout    = 5 * time.Minute
+	// maxResearchQuestionLen bounds the question echoed back in progress messages.
+	maxResearchQuestionLen = 200
+)
+
+// startDeepResearch starts a research job for the current chat in the background and returns at
+// once; progress and the final answer are posted to the chat through the proactive queue.
+func (e *Executor) startDeepResearch(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	question := strings.TrimSpace(params.Question)
+	chatID := requestChatID(ctx)
+	if question == "" || chatID == 0 {
+		return "Missing question or chat.", nil
+	}
+	ok, err := e.cache.Client().SetNX(ctx, fmt.Sprintf(researchRunningKey, chatID), 1, researchTimeout).Result()
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return e.t(ctx, "research.busy"), nil
+	}
+	// The job outlives the request: keep its values (request ID, chat, language) but not its deadline.
+	jobCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), researchTimeout)
+	go func() {
+		defer cancel()
+		defer e.cache.Client().Del(jobCtx, fmt.Sprintf(researchRunningKey, chatID))
+		defer func() {
+			if r := recover(); r != nil {
+				slog.ErrorContext(jobCtx, "deep research panicked", "panic", r)
+			}
+		}()
+		e.runDeepResearch(jobCtx, chatID, question)
+	}()
+	return e.t(ctx, "research.started"), nil
+}
+
+// runDeepResearch plans search queries, runs a grounded search for each, and posts a synthesized
+// answer with deduplicated sources.
+func (e *Executor) runDeepResearch(ctx context.Context, chatID int64, question string) {
+	lang := requestLanguage(ctx, e.lang)
+	post := func(text string) {
+		if err := e.cache.PushProactive(ctx, cache.ProactiveItem{Source: cache.SourceResearch, ChatID: chatID, Reply: text}); err != nil {
+			slog.ErrorContext(ctx, "push research message failed", "error", err)
+		}
+	}
+	fail := func(stage string, err error) {
+		slog.ErrorContext(ctx, "deep research failed", "stage", stage, "error", err)
+		post(e.t(ctx, "research.failed"))
+	}
+
+	queries, err := e.llmClient.PlanResearch(ctx, question, max(e.config.DeepResearchMaxQueries, 1))
+	if err != nil {
+		fail("plan", err)
+		return
+	}
+	if len(queries) == 0 {
+		queries = []string{question}
+	}
+	post(e.tn(ctx, "research.progress", len(queries), truncateRunes(question, maxResearchQuestionLen)))
+
+	var findings []llm.ResearchFinding
+	for _, q := range queries {
+		text, sources, err := e.llmClient.SearchWithSources(ctx, q)
+		if err != nil {
+			slog.WarnContext(ctx, "research search failed", "query", q, "error", err)
+			continue
+		}
+		findings = append(findings, llm.ResearchFinding{Query: q, Text: text, Sources: sources})
+	}
+	if len(findings) == 0 {
+		fail("search", fmt.Errorf("all %d searches failed", len(queries)))
+		return
+	}
+
+	answer, err := e.llmClient.SynthesizeResearch(ctx, question, findings, lang)
+	if err != nil {
+		fail("synthesize", err)
+		return
+	}
+	post(formatResearchAnswer(e.t(ctx, "research.sources"), answer, llm.DedupSources(findings)))
+	slog.InfoContext(ctx, "deep research finished", "queries", len(queries), "searches_ok", len(findings))
+}
+
+// formatResearchAnswer appends the numbered source list to the answer.
+func formatResearchAnswer(sourcesTitle, answer string, sources []llm.Source) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(answer))
+	if len(sources) > 0 {
+		b.WriteString("\n\n" + sourcesTitle + "\n")
+		for i, s := range sources {
+			title := s.Title
+			if title == "" {
+				title = s.URI
+			}
+			fmt.Fprintf(&b, "[%d] %s — %s\n", i+1, title, s.URI)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// truncateRunes shortens s to at most n runes, marking the cut with "…".
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
@@ -0,0 +1,30 @@
+package tools
+
+import (
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+func TestFormatResearchAnswer(t *testing.T) {
+	got := formatResearchAnswer("Sources:", "Answer [1] and [2].\n", []llm.Source{
+		{Title: "a.com", URI: "https://a"},
+		{URI: "https://b"},
+	})
+	want := "Answer [1] and [2].\n\nSources:\n[1] a.com — https://a\n[2] https://b — https://b"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if got := formatResearchAnswer("Sources:", "Only text", nil); got != "Only text" {
+		t.Errorf("expected no source list, got %q", got)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	if got := truncateRunes("привіт", 3); got != "при…" {
+		t.Errorf("got %q", got)
+	}
+	if got := truncateRunes("hi", 3); got != "hi" {
+		t.Errorf("got %q", got)
+	}
+}
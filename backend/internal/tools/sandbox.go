@@ -11,16 +11,21 @@ import (
 	"time"
 
 	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/limit"
 )
 
+// sandboxQueueWait is how long a run waits for a free container slot before giving up.
+const sandboxQueueWait = 15 * time.Second
+
 // SandboxTool handles secure Python code execution in the sandbox container.
 type SandboxTool struct {
 	config *config.Config
+	slots  *limit.Semaphore // SANDBOX_MAX_CONCURRENT; nil = unlimited
 }
 
 // NewSandboxTool creates a new sandbox tool.
 func NewSandboxTool(cfg *config.Config) *SandboxTool {
-	return &SandboxTool{config: cfg}
+	return &SandboxTool{config: cfg, slots: limit.NewSemaphore(cfg.SandboxMaxConcurrent)}
 }
 
 // RunPythonCode executes Python code in the locked-down sandbox container.
@@ -33,7 +38,16 @@ func (s *SandboxTool) RunPythonCode(ctx context.Context, args json.RawMessage) (
 		return "", fmt.Errorf("parse args: %w", err)
 	}
 
-	slog.Info("executing sandbox code", "code_length", len(params.Code))
+	if err := s.slots.Acquire(ctx, sandboxQueueWait); err != nil {
+		if ctx.Err() != nil {
+			return "", err
+		}
+		slog.WarnContext(ctx, "sandbox busy", "running", s.slots.InUse())
+		return "The code sandbox is busy right now. Try again in a moment.", nil
+	}
+	defer s.slots.Release()
+
+	slog.InfoContext(ctx, "executing sandbox code", "code_length", len(params.Code))
 
 	timeout := time.Duration(s.config.SandboxTimeoutSeconds) * time.Second
 	ctx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
@@ -87,6 +101,6 @@ func (s *SandboxTool) RunPythonCode(ctx context.Context, args json.RawMessage) (
 		output = output[:maxOutput] + "\n... (output truncated)"
 	}
 
-	slog.Info("sandbox execution complete", "output_length", len(output))
+	slog.InfoContext(ctx, "sandbox execution complete", "output_length", len(output))
 	return output, nil
 }
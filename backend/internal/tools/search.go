@@ -0,0 +1,263 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// searchParams are the search_messages arguments.
+type searchParams struct {
+	ChatID    int64  `json:"chat_id"`
+	Query     string `json:"query"`
+	Limit     int    `json:"limit"`
+	AllTopics bool   `json:"all_topics"`
+	FromUser  string `json:"from_user"`
+	After     string `json:"after"`
+	Before    string `json:"before"`
+	MediaType string `json:"media_type"`
+}
+
+// filter builds the DB filter; dates without a time zone are read in loc.
+func (p searchParams) filter(loc *time.Location) (db.SearchFilter, error) {
+	f := db.SearchFilter{
+		FromUser:  strings.TrimSpace(p.FromUser),
+		MediaType: strings.ToLower(strings.TrimSpace(p.MediaType)),
+	}
+	for _, b := range []struct {
+		name, raw string
+		dst       *time.Time
+	}{{"after", p.After, &f.After}, {"before", p.Before, &f.Before}} {
+		raw := strings.TrimSpace(b.raw)
+		if raw == "" {
+			continue
+		}
+		t, ok := parseLocalTime(raw, loc)
+		if !ok {
+			return f, fmt.Errorf("%s must be a date (2006-01-02), a local time (2006-01-02 15:04) or RFC 3339", b.name)
+		}
+		*b.dst = t
+	}
+	if !f.After.IsZero() && !f.Before.IsZero() && !f.After.Before(f.Before) {
+		return f, fmt.Errorf("%w: after must be earlier than before", ErrInvalidArgs)
+	}
+	return f, nil
+}
+
+// searchMessages runs search_messages: full-text search over the chat's messages, optionally
+// narrowed by sender, date range and media type.
+func (e *Executor) searchMessages(ctx context.Context, args json.RawMessage) (string, error) {
+	var params searchParams
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	if params.Limit == 0 {
+		params.Limit = 10
+	}
+	loc := e.location(ctx, params.ChatID)
+	filter, err := params.filter(loc)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(params.Query) == "" && filter.IsZero() {
+		return "", fmt.Errorf("%w: query is required unless from_user, after, before or media_type is given", ErrInvalidArgs)
+	}
+	threadID := requestThreadID(ctx)
+	if params.AllTopics {
+		threadID = db.AllThreads
+	}
+	results, err := e.db.SearchMessages(ctx, params.ChatID, threadID, params.Query, filter, params.Limit)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return e.t(ctx, "search.no_results"), nil
+	}
+
+	type searchEntry struct {
+		ID         int64   `json:"id"`
+		Text       string  `json:"text,omitempty"`
+		Transcript string  `json:"transcript,omitempty"`
+		From       string  `json:"from"`
+		FileID     string  `json:"file_id,omitempty"`
+		MediaType  string  `json:"media_type,omitempty"`
+		Link       string  `json:"message_link,omitempty"`
+		SentAt     string  `json:"sent_at"`
+		Rank       float64 `json:"relevance"`
+	}
+	entries := make([]searchEntry, len(results))
+	for i, r := range results {
+		e := searchEntry{ID: r.ID, Rank: r.Rank, Link: r.MessageLink, SentAt: r.CreatedAt.In(loc).Format("2006-01-02 15:04")}
+		if r.Text != nil {
+			e.Text = *r.Text
+		}
+		if r.Transcript != nil {
+			e.Transcript = truncateRunes(*r.Transcript, maxSearchTranscript)
+		}
+		e.From = senderName(r.FirstName, r.Username)
+		if r.FileID != nil {
+			e.FileID = *r.FileID
+		}
+		if r.MediaType != nil {
+			e.MediaType = *r.MediaType
+		}
+		entries[i] = e
+	}
+	data, _ := json.Marshal(entries)
+	return string(data), nil
+}
+
+// senderName formats a sender as "First (@username)".
+func senderName(firstName, username *string) string {
+	name := ""
+	if firstName != nil {
+		name = *firstName
+	}
+	if username != nil {
+		name += " (@" + *username + ")"
+	}
+	return name
+}
+
+const (
+	defaultContextMessages = 5
+	maxContextMessages     = 20
+)
+
+// contextCount turns a before/after argument into a count: unset means the default, and it is
+// clamped to [0, maxContextMessages].
+func contextCount(n *int) int {
+	if n == nil {
+		return defaultContextMessages
+	}
+	return max(0, min(*n, maxContextMessages))
+}
+
+// getMessageContext runs get_message_context: the messages around a search result in the current chat.
+func (e *Executor) getMessageContext(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		ID     int64 `json:"id"`
+		Before *int  `json:"before"`
+		After  *int  `json:"after"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	if params.ID <= 0 {
+		return "", fmt.Errorf("%w: id is required", ErrInvalidArgs)
+	}
+	messages, err := e.db.GetMessageContext(ctx, chatID, params.ID, contextCount(params.Before), contextCount(params.After))
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return e.t(ctx, "search.no_results"), nil
+	}
+
+	data, _ := json.Marshal(contextEntries(messages, e.location(ctx, chatID), func(m db.Message) bool { return m.ID == params.ID }))
+	return string(data), nil
+}
+
+// contextEntry is one message as get_message_context and get_thread show it.
+type contextEntry struct {
+	ID        int64  `json:"id"`
+	MessageID int64  `json:"message_id,omitempty"` // Telegram's, as "replying to ... id N" in the prompt
+	ReplyTo   int64  `json:"reply_to,omitempty"`
+	From      string `json:"from"`
+	Text      string `json:"text,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+	FileID    string `json:"file_id,omitempty"`
+	Bot       bool   `json:"is_bot,omitempty"`
+	SentAt    string `json:"sent_at"`
+	Link      string `json:"message_link,omitempty"`
+	Match     bool   `json:"match,omitempty"`
+}
+
+// contextEntries converts messages for the model, marking those match selects.
+func contextEntries(messages []db.Message, loc *time.Location, match func(db.Message) bool) []contextEntry {
+	entries := make([]contextEntry, len(messages))
+	for i, m := range messages {
+		c := contextEntry{
+			ID:     m.ID,
+			From:   senderName(m.FirstName, m.Username),
+			Bot:    m.IsBotReply,
+			SentAt: m.CreatedAt.In(loc).Format("2006-01-02 15:04"),
+			Link:   db.ComposeTopicMessageLink(m.ChatID, m.ThreadID, m.MessageID),
+			Match:  match(m),
+		}
+		if m.MessageID != nil {
+			c.MessageID = *m.MessageID
+		}
+		if m.ReplyToMessageID != nil {
+			c.ReplyTo = *m.ReplyToMessageID
+		}
+		if m.Text != nil {
+			c.Text = *m.Text
+		} else if m.StickerEmoji != nil {
+			c.Text = *m.StickerEmoji
+		}
+		if m.MediaType != nil {
+			c.MediaType = *m.MediaType
+		}
+		if m.FileID != nil {
+			c.FileID = *m.FileID
+		}
+		entries[i] = c
+	}
+	return entries
+}
+
+const (
+	defaultThreadMessages = 30
+	maxThreadMessages     = 100
+	maxThreadDepth        = 50 // reply levels followed up to the root and down from it
+)
+
+// getThread runs get_thread: the whole reply thread of a message in the current chat, oldest
+// first, so the model can follow chains that started before the immediate context.
+func (e *Executor) getThread(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		MessageID int64 `json:"message_id"`
+		ID        int64 `json:"id"`
+		Limit     int   `json:"limit"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	if params.MessageID <= 0 && params.ID <= 0 {
+		return "", fmt.Errorf("%w: message_id or id is required", ErrInvalidArgs)
+	}
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultThreadMessages
+	}
+	limit = min(limit, maxThreadMessages)
+
+	messages, err := e.db.GetThread(ctx, chatID, params.MessageID, params.ID, maxThreadDepth, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return e.t(ctx, "search.no_results"), nil
+	}
+	match := func(m db.Message) bool {
+		if params.MessageID > 0 {
+			return m.MessageID != nil && *m.MessageID == params.MessageID
+		}
+		return m.ID == params.ID
+	}
+	data, _ := json.Marshal(contextEntries(messages, e.location(ctx, chatID), match))
+	return string(data), nil
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+func TestSearchParams_Filter(t *testing.T) {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	f, err := searchParams{FromUser: " @olena ", After: "2026-10-05", Before: "2026-10-12 18:00", MediaType: "Photo"}.filter(kyiv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.FromUser != "@olena" || f.MediaType != "photo" {
+		t.Errorf("unexpected sender/media: %+v", f)
+	}
+	if !f.After.Equal(time.Date(2026, 10, 5, 0, 0, 0, 0, kyiv)) || !f.Before.Equal(time.Date(2026, 10, 12, 18, 0, 0, 0, kyiv)) {
+		t.Errorf("dates should be read in the chat's timezone, got %v / %v", f.After, f.Before)
+	}
+
+	if f, err := (searchParams{Query: "hi"}).filter(kyiv); err != nil || !f.IsZero() {
+		t.Errorf("no filters: got %+v, %v", f, err)
+	}
+	if _, err := (searchParams{After: "last week"}).filter(kyiv); err == nil {
+		t.Error("expected error for free-form date")
+	}
+	if _, err := (searchParams{After: "2026-10-12", Before: "2026-10-05"}).filter(kyiv); err == nil {
+		t.Error("expected error for after later than before")
+	}
+}
+
+func TestContextCount(t *testing.T) {
+	n := func(v int) *int { return &v }
+	tests := []struct {
+		in   *int
+		want int
+	}{
+		{nil, defaultContextMessages},
+		{n(0), 0},
+		{n(3), 3},
+		{n(100), maxContextMessages},
+		{n(-2), 0},
+	}
+	for _, tt := range tests {
+		if got := contextCount(tt.in); got != tt.want {
+			t.Errorf("contextCount(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGetThread_Args(t *testing.T) {
+	e := &Executor{}
+	if _, err := e.getThread(context.Background(), json.RawMessage(`{"message_id": 5}`)); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected unavailable without a current chat, got %v", err)
+	}
+	ctx := context.WithValue(context.Background(), RequestChatIDKey, int64(-100))
+	if _, err := e.getThread(ctx, json.RawMessage(`{"limit": 5}`)); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected invalid args without message_id or id, got %v", err)
+	}
+}
+
+func TestContextEntries(t *testing.T) {
+	text, sticker := "hi", "👍"
+	mid, reply := int64(42), int64(41)
+	at := time.Date(2026, 10, 5, 9, 30, 0, 0, time.UTC)
+	entries := contextEntries([]db.Message{
+		{ID: 1, ChatID: -1001234567890, Text: &text, MessageID: &mid, ReplyToMessageID: &reply, CreatedAt: at},
+		{ID: 2, ChatID: -1001234567890, StickerEmoji: &sticker, IsBotReply: true, CreatedAt: at},
+	}, time.UTC, func(m db.Message) bool { return m.ID == 2 })
+	if e := entries[0]; e.MessageID != 42 || e.ReplyTo != 41 || e.Text != "hi" || e.Match || e.Link == "" || e.SentAt != "2026-10-05 09:30" {
+		t.Errorf("unexpected first entry %+v", e)
+	}
+	if e := entries[1]; e.Text != "👍" || !e.Bot || !e.Match || e.Link != "" {
+		t.Errorf("unexpected second entry %+v", e)
+	}
+}
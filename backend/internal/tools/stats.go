@@ -0,0 +1,63 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+)
+
+const (
+	// MaxStatsDays bounds the period of get_chat_stats and the admin analytics endpoint.
+	MaxStatsDays = 90
+	// StatsTopN is how many users, emoji and words the chat stats list.
+	StatsTopN = 10
+
+	defaultStatsDays = 7
+)
+
+// statsDays validates a stats period in days; 0 means the default week.
+func statsDays(days int) (int, error) {
+	if days == 0 {
+		return defaultStatsDays, nil
+	}
+	if days < 1 || days > MaxStatsDays {
+		return 0, fmt.Errorf("%w: days must be 1-%d", ErrInvalidArgs, MaxStatsDays)
+	}
+	return days, nil
+}
+
+// getChatStats runs get_chat_stats: activity of the current chat's users over the last days.
+func (e *Executor) getChatStats(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Days int `json:"days"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	days, err := statsDays(params.Days)
+	if err != nil {
+		return "", err
+	}
+	tz := chatsettings.DefaultTimezone
+	if e.settings != nil {
+		tz = e.settings.Get(ctx, chatID).Location().String()
+	}
+	until := time.Now()
+	stats, err := e.db.GetChatStats(ctx, chatID, until.AddDate(0, 0, -days), until, tz, StatsTopN)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{
+		"days":     days,
+		"timezone": tz,
+		"stats":    stats,
+	})
+	return string(data), nil
+}
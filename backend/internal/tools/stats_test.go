@@ -0,0 +1,23 @@
+package tools
+
+import "testing"
+
+func TestStatsDays(t *testing.T) {
+	tests := []struct {
+		in      int
+		want    int
+		wantErr bool
+	}{
+		{0, defaultStatsDays, false},
+		{1, 1, false},
+		{MaxStatsDays, MaxStatsDays, false},
+		{MaxStatsDays + 1, 0, true},
+		{-1, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := statsDays(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("statsDays(%d) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
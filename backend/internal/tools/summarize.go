@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+const (
+	defaultSummarizeWindow = 24 * time.Hour
+	maxSummarizeWindow     = 7 * 24 * time.Hour
+)
+
+// summarizeWindow turns the tool's hours/days into a window: both add up, nothing given means
+// the last 24 hours, and anything longer than 7 days is clamped.
+func summarizeWindow(hours, days int) (time.Duration, error) {
+	if hours < 0 || days < 0 {
+		return 0, fmt.Errorf("%w: hours and days must not be negative", ErrInvalidArgs)
+	}
+	w := time.Duration(days)*24*time.Hour + time.Duration(hours)*time.Hour
+	if w == 0 {
+		return defaultSummarizeWindow, nil
+	}
+	return min(w, maxSummarizeWindow), nil
+}
+
+// windowLabel describes a window for the summarization prompt, e.g. "36-hour" (matching the nightly "7-day").
+func windowLabel(w time.Duration) string {
+	h := int(w.Hours())
+	if h%24 == 0 {
+		return strconv.Itoa(h/24) + "-day"
+	}
+	return strconv.Itoa(h) + "-hour"
+}
+
+// summarizeRecent summarizes the current topic of a chat over a recent window (the on-demand
+// counterpart of the nightly summaries).
+func (e *Executor) summarizeRecent(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		ChatID int64 `json:"chat_id"`
+		Hours  int   `json:"hours"`
+		Days   int   `json:"days"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	window, err := summarizeWindow(params.Hours, params.Days)
+	if err != nil {
+		return "", err
+	}
+	until := time.Now()
+	messages, err := e.db.GetMessagesInRange(ctx, params.ChatID, requestThreadID(ctx), until.Add(-window), until, e.config.SummaryMaxMessagesPerWindow)
+	if err != nil {
+		return "", err
+	}
+	if len(messages) == 0 {
+		return e.t(ctx, "summary.no_messages"), nil
+	}
+	opts := llm.SummaryOptions{Language: llm.SummaryLanguage("", requestLanguage(ctx, e.lang), messages)}
+	if e.settings != nil {
+		st := e.settings.Get(ctx, params.ChatID)
+		opts.Anonymize = st.SummaryAnonymize
+		if st.SummaryLanguage != "" {
+			opts.Language = st.SummaryLanguage
+		}
+	}
+	summary, err := e.llmClient.SummarizeChat(ctx, messages, windowLabel(window), opts)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{
+		"window_hours": int(window.Hours()),
+		"messages":     len(messages),
+		"summary":      summary,
+	})
+	return string(data), nil
+}
@@ -0,0 +1,36 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSummarizeWindow(t *testing.T) {
+	tests := []struct {
+		hours, days int
+		want        time.Duration
+	}{
+		{0, 0, 24 * time.Hour},
+		{6, 0, 6 * time.Hour},
+		{12, 1, 36 * time.Hour},
+		{0, 30, 7 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := summarizeWindow(tt.hours, tt.days)
+		if err != nil || got != tt.want {
+			t.Errorf("summarizeWindow(%d, %d) = %v, %v; want %v", tt.hours, tt.days, got, err, tt.want)
+		}
+	}
+	if _, err := summarizeWindow(-1, 0); err == nil {
+		t.Error("expected error for negative hours")
+	}
+}
+
+func TestWindowLabel(t *testing.T) {
+	if got := windowLabel(48 * time.Hour); got != "2-day" {
+		t.Errorf("got %q", got)
+	}
+	if got := windowLabel(6 * time.Hour); got != "6-hour" {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,298 @@
+package tools
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"time"
+	_ "time/tzdata" // the container image may not ship a zoneinfo database
+
+	"github.com/ThatHunky/gryag/backend/internal/i18n"
+)
+
+//go:embed data/timeinfo.json
+var timeInfoData []byte
+
+// upcomingHolidayWindow bounds how far ahead time_info looks for the next holidays.
+const (
+	upcomingHolidayWindow = 60
+	maxUpcomingHolidays   = 3
+)
+
+type place struct {
+	Name    string   `json:"name"`
+	Country string   `json:"country"`
+	TZ      string   `json:"tz"`
+	Lat     float64  `json:"lat"`
+	Lon     float64  `json:"lon"`
+	Aliases []string `json:"aliases"`
+}
+
+type holidayRule struct {
+	Date   string `json:"date,omitempty"`   // "MM-DD" for fixed-date holidays
+	Easter *int   `json:"easter,omitempty"` // days after Easter Sunday for movable ones
+	Name   string `json:"name"`
+}
+
+type countryHolidays struct {
+	Easter   string        `json:"easter"` // "orthodox" or "western"
+	Note     string        `json:"note,omitempty"`
+	Holidays []holidayRule `json:"holidays"`
+}
+
+// TimeInfoTool answers time, daylight and public holiday questions from embedded data,
+// so "котра година в Торонто" never needs a web search.
+type TimeInfoTool struct {
+	places    []place
+	countries map[string]countryHolidays
+	i18n      *i18n.Bundle
+	lang      string
+	now       func() time.Time
+}
+
+// NewTimeInfoTool parses the embedded place and holiday tables.
+func NewTimeInfoTool(bundle *i18n.Bundle, lang string) *TimeInfoTool {
+	t := &TimeInfoTool{i18n: bundle, lang: lang, now: time.Now}
+	var data struct {
+		Places    []place                    `json:"places"`
+		Countries map[string]countryHolidays `json:"countries"`
+	}
+	if err := json.Unmarshal(timeInfoData, &data); err != nil {
+		// The file is embedded at build time, so this only fails on a broken edit; tests catch it.
+		slog.Error("parse embedded time info data", "error", err)
+	}
+	t.places, t.countries = data.Places, data.Countries
+	return t
+}
+
+func (t *TimeInfoTool) tr(ctx context.Context, key string, args ...string) string {
+	if t.i18n == nil {
+		return key
+	}
+	return t.i18n.T(requestLanguage(ctx, t.lang), key, args...)
+}
+
+// findPlace matches a city/country alias ("Торонто", "Kyiv, Ukraine") against the embedded table.
+func (t *TimeInfoTool) findPlace(query string) (place, bool) {
+	q := strings.ToLower(strings.TrimSpace(query))
+	candidates := []string{q}
+	if i := strings.IndexAny(q, ",("); i > 0 {
+		candidates = append(candidates, strings.TrimSpace(q[:i]))
+	}
+	for _, c := range candidates {
+		for _, p := range t.places {
+			for _, a := range p.Aliases {
+				if a == c {
+					return p, true
+				}
+			}
+		}
+	}
+	return place{}, false
+}
+
+// ResolveZone maps a known place or an IANA timezone name to the IANA name.
+func (t *TimeInfoTool) ResolveZone(query string) (string, bool) {
+	if p, ok := t.findPlace(query); ok {
+		return p.TZ, true
+	}
+	name := strings.TrimSpace(query)
+	if name != "UTC" && !strings.Contains(name, "/") {
+		return "", false
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", false
+	}
+	return name, true
+}
+
+type dayInfo struct {
+	Date     string   `json:"date"`
+	Weekday  string   `json:"weekday"`
+	Weekend  bool     `json:"weekend"`
+	Holidays []string `json:"holidays,omitempty"`
+}
+
+type upcomingHoliday struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type timeInfoResult struct {
+	Location  string            `json:"location"`
+	Country   string            `json:"country,omitempty"`
+	Timezone  string            `json:"timezone"`
+	LocalTime string            `json:"local_time"`
+	UTCOffset string            `json:"utc_offset"`
+	Sunrise   string            `json:"sunrise,omitempty"`
+	Sunset    string            `json:"sunset,omitempty"`
+	Daylight  string            `json:"daylight,omitempty"` // "polar day" / "polar night" when the sun does not rise or set
+	Today     dayInfo           `json:"today"`
+	Tomorrow  dayInfo           `json:"tomorrow"`
+	Upcoming  []upcomingHoliday `json:"upcoming_holidays,omitempty"`
+	Note      string            `json:"note,omitempty"`
+}
+
+// Lookup returns current time, sunrise/sunset and holidays for a named place or IANA timezone.
+// An optional date (YYYY-MM-DD) replaces "today" for sun and holiday data.
+func (t *TimeInfoTool) Lookup(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Location string `json:"location"`
+		Date     string `json:"date"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	if strings.TrimSpace(params.Location) == "" {
+		return "", fmt.Errorf("%w: location is required", ErrInvalidArgs)
+	}
+
+	p, known := t.findPlace(params.Location)
+	if !known {
+		// Not a known city; accept a raw IANA name such as "America/Toronto" (time only).
+		if _, err := time.LoadLocation(strings.TrimSpace(params.Location)); err != nil || !strings.Contains(params.Location, "/") {
+			return t.tr(ctx, "time.unknown_location", params.Location), nil
+		}
+		p = place{Name: strings.TrimSpace(params.Location), TZ: strings.TrimSpace(params.Location)}
+	}
+	loc, err := time.LoadLocation(p.TZ)
+	if err != nil {
+		return "", fmt.Errorf("load timezone %s: %w", p.TZ, err)
+	}
+
+	now := t.now().In(loc)
+	day := now
+	if params.Date != "" {
+		d, err := time.ParseInLocation("2006-01-02", params.Date, loc)
+		if err != nil {
+			return "", fmt.Errorf("%w: date must be YYYY-MM-DD", ErrInvalidArgs)
+		}
+		day = d.Add(12 * time.Hour)
+	}
+
+	res := timeInfoResult{
+		Location:  p.Name,
+		Country:   p.Country,
+		Timezone:  p.TZ,
+		LocalTime: now.Format("2006-01-02 15:04 Monday"),
+		UTCOffset: now.Format("-07:00"),
+	}
+	if known {
+		rise, set, state := sunTimes(day, p.Lat, p.Lon)
+		if state == "" {
+			res.Sunrise, res.Sunset = rise.In(loc).Format("15:04"), set.In(loc).Format("15:04")
+		}
+		res.Daylight = state
+	}
+
+	ch, hasHolidays := t.countries[p.Country]
+	res.Today = t.describeDay(day, ch)
+	res.Tomorrow = t.describeDay(day.AddDate(0, 0, 1), ch)
+	if hasHolidays {
+		res.Note = ch.Note
+		for i := 1; i <= upcomingHolidayWindow && len(res.Upcoming) < maxUpcomingHolidays; i++ {
+			d := day.AddDate(0, 0, i)
+			for _, name := range holidaysOn(d, ch) {
+				res.Upcoming = append(res.Upcoming, upcomingHoliday{Date: d.Format("2006-01-02"), Name: name})
+			}
+		}
+	}
+
+	out, _ := json.Marshal(res)
+	slog.InfoContext(ctx, "time info", "location", p.Name, "timezone", p.TZ)
+	return string(out), nil
+}
+
+func (t *TimeInfoTool) describeDay(d time.Time, ch countryHolidays) dayInfo {
+	wd := d.Weekday()
+	return dayInfo{
+		Date:     d.Format("2006-01-02"),
+		Weekday:  wd.String(),
+		Weekend:  wd == time.Saturday || wd == time.Sunday,
+		Holidays: holidaysOn(d, ch),
+	}
+}
+
+// holidaysOn returns the names of the country's public holidays falling on d's calendar date.
+func holidaysOn(d time.Time, ch countryHolidays) []string {
+	var names []string
+	mmdd := d.Format("01-02")
+	var easter time.Time
+	if ch.Easter == "orthodox" {
+		easter = orthodoxEaster(d.Year())
+	} else {
+		easter = westernEaster(d.Year())
+	}
+	date := time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)
+	for _, h := range ch.Holidays {
+		switch {
+		case h.Date != "" && h.Date == mmdd:
+			names = append(names, h.Name)
+		case h.Easter != nil && easter.AddDate(0, 0, *h.Easter).Equal(date):
+			names = append(names, h.Name)
+		}
+	}
+	return names
+}
+
+// westernEaster returns Gregorian Easter Sunday (anonymous Gregorian algorithm), as a UTC date.
+func westernEaster(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// orthodoxEaster returns Orthodox Easter Sunday (Meeus' Julian algorithm) converted to the Gregorian calendar.
+func orthodoxEaster(year int) time.Time {
+	a, b, c := year%4, year%7, year%19
+	d := (19*c + 15) % 30
+	e := (2*a + 4*b - d + 34) % 7
+	month := (d + e + 114) / 31
+	day := (d+e+114)%31 + 1
+	julianToGregorian := year/100 - year/400 - 2 // 13 days for 1900–2099
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).AddDate(0, 0, julianToGregorian)
+}
+
+// sunTimes computes sunrise and sunset (UTC) for d's date at the given coordinates using the
+// standard sunrise equation (±1–2 min). state is "polar day" or "polar night" when there is none.
+func sunTimes(d time.Time, lat, lon float64) (rise, set time.Time, state string) {
+	const rad = math.Pi / 180
+	noon := time.Date(d.Year(), d.Month(), d.Day(), 12, 0, 0, 0, time.UTC)
+	jd := float64(noon.Unix())/86400 + 2440587.5
+	n := math.Round(jd - 2451545.0 + 0.0008)
+
+	jStar := n - lon/360
+	m := math.Mod(357.5291+0.98560028*jStar, 360)
+	c := 1.9148*math.Sin(m*rad) + 0.02*math.Sin(2*m*rad) + 0.0003*math.Sin(3*m*rad)
+	lambda := math.Mod(m+c+180+102.9372, 360)
+	transit := 2451545.0 + jStar + 0.0053*math.Sin(m*rad) - 0.0069*math.Sin(2*lambda*rad)
+
+	sinDec := math.Sin(lambda*rad) * math.Sin(23.4397*rad)
+	cosDec := math.Cos(math.Asin(sinDec))
+	cosW := (math.Sin(-0.833*rad) - math.Sin(lat*rad)*sinDec) / (math.Cos(lat*rad) * cosDec)
+	switch {
+	case cosW < -1:
+		return time.Time{}, time.Time{}, "polar day"
+	case cosW > 1:
+		return time.Time{}, time.Time{}, "polar night"
+	}
+	w := math.Acos(cosW) / rad
+
+	fromJulian := func(j float64) time.Time {
+		return time.Unix(int64(math.Round((j-2440587.5)*86400)), 0).UTC()
+	}
+	return fromJulian(transit - w/360), fromJulian(transit + w/360), ""
+}
@@ -0,0 +1,144 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEasterDates(t *testing.T) {
+	tests := []struct {
+		year              int
+		western, orthodox string
+	}{
+		{2024, "2024-03-31", "2024-05-05"},
+		{2025, "2025-04-20", "2025-04-20"},
+		{2026, "2026-04-05", "2026-04-12"},
+	}
+	for _, tt := range tests {
+		if got := westernEaster(tt.year).Format("2006-01-02"); got != tt.western {
+			t.Errorf("westernEaster(%d) = %s, want %s", tt.year, got, tt.western)
+		}
+		if got := orthodoxEaster(tt.year).Format("2006-01-02"); got != tt.orthodox {
+			t.Errorf("orthodoxEaster(%d) = %s, want %s", tt.year, got, tt.orthodox)
+		}
+	}
+}
+
+func TestSunTimes_Kyiv(t *testing.T) {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	tests := []struct {
+		date, rise, set string
+	}{
+		{"2026-06-21", "04:47", "21:13"},
+		{"2026-12-21", "07:55", "15:56"},
+	}
+	for _, tt := range tests {
+		d, _ := time.ParseInLocation("2006-01-02", tt.date, kyiv)
+		rise, set, state := sunTimes(d, 50.45, 30.52)
+		if state != "" {
+			t.Fatalf("%s: unexpected state %q", tt.date, state)
+		}
+		assertClock(t, tt.date+" sunrise", rise.In(kyiv), tt.rise)
+		assertClock(t, tt.date+" sunset", set.In(kyiv), tt.set)
+	}
+}
+
+func TestSunTimes_Polar(t *testing.T) {
+	d := time.Date(2026, 6, 21, 0, 0, 0, 0, time.UTC)
+	if _, _, state := sunTimes(d, 78.22, 15.65); state != "polar day" {
+		t.Errorf("Svalbard in June: state = %q, want polar day", state)
+	}
+	d = time.Date(2026, 12, 21, 0, 0, 0, 0, time.UTC)
+	if _, _, state := sunTimes(d, 78.22, 15.65); state != "polar night" {
+		t.Errorf("Svalbard in December: state = %q, want polar night", state)
+	}
+}
+
+// assertClock checks got is within 3 minutes of want ("15:04").
+func assertClock(t *testing.T, label string, got time.Time, want string) {
+	t.Helper()
+	w, _ := time.Parse("15:04", want)
+	diff := float64(got.Hour()*60+got.Minute()) - float64(w.Hour()*60+w.Minute())
+	if math.Abs(diff) > 3 {
+		t.Errorf("%s = %s, want ~%s", label, got.Format("15:04"), want)
+	}
+}
+
+func TestTimeInfoTool_Lookup(t *testing.T) {
+	tool := NewTimeInfoTool(nil, "en")
+	if len(tool.places) == 0 || len(tool.countries) == 0 {
+		t.Fatal("embedded time info data not loaded")
+	}
+	// 2026-08-23 10:00 UTC = 13:00 in Kyiv, the day before Independence Day.
+	tool.now = func() time.Time { return time.Date(2026, 8, 23, 10, 0, 0, 0, time.UTC) }
+
+	out, err := tool.Lookup(context.Background(), json.RawMessage(`{"location":"Київ"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res timeInfoResult
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		t.Fatalf("bad output %q: %v", out, err)
+	}
+	if res.Location != "Kyiv" || res.Timezone != "Europe/Kyiv" || res.UTCOffset != "+03:00" {
+		t.Errorf("unexpected location fields: %+v", res)
+	}
+	if res.LocalTime != "2026-08-23 13:00 Sunday" {
+		t.Errorf("local_time = %q", res.LocalTime)
+	}
+	if !res.Today.Weekend || len(res.Today.Holidays) != 0 {
+		t.Errorf("today = %+v, want weekend without holidays", res.Today)
+	}
+	if len(res.Tomorrow.Holidays) != 1 || res.Tomorrow.Holidays[0] != "Independence Day" {
+		t.Errorf("tomorrow holidays = %v, want Independence Day", res.Tomorrow.Holidays)
+	}
+	if res.Sunrise == "" || res.Sunset == "" || res.Note == "" {
+		t.Errorf("expected sunrise, sunset and martial-law note: %+v", res)
+	}
+}
+
+func TestTimeInfoTool_LookupVariants(t *testing.T) {
+	tool := NewTimeInfoTool(nil, "en")
+	tool.now = func() time.Time { return time.Date(2026, 1, 10, 12, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	// Orthodox Trinity for UA, on an explicit date.
+	out, err := tool.Lookup(ctx, json.RawMessage(`{"location":"Lviv","date":"2026-05-31"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res timeInfoResult
+	_ = json.Unmarshal([]byte(out), &res)
+	if len(res.Today.Holidays) != 1 || res.Today.Holidays[0] != "Trinity Sunday" {
+		t.Errorf("Lviv 2026-05-31 holidays = %v, want Trinity Sunday", res.Today.Holidays)
+	}
+
+	// Known city without holiday data, with a qualifier.
+	out, _ = tool.Lookup(ctx, json.RawMessage(`{"location":"Toronto, Canada"}`))
+	res = timeInfoResult{}
+	_ = json.Unmarshal([]byte(out), &res)
+	if res.Timezone != "America/Toronto" || res.UTCOffset != "-05:00" || len(res.Upcoming) != 0 {
+		t.Errorf("Toronto = %+v", res)
+	}
+
+	// Raw IANA zone: time only.
+	out, _ = tool.Lookup(ctx, json.RawMessage(`{"location":"Asia/Kolkata"}`))
+	res = timeInfoResult{}
+	_ = json.Unmarshal([]byte(out), &res)
+	if res.UTCOffset != "+05:30" || res.Sunrise != "" {
+		t.Errorf("Asia/Kolkata = %+v", res)
+	}
+
+	// Unknown place: localized message, not an error.
+	out, err = tool.Lookup(ctx, json.RawMessage(`{"location":"Atlantis"}`))
+	if err != nil || out != "time.unknown_location" {
+		t.Errorf("unknown location: out=%q err=%v", out, err)
+	}
+
+	if _, err := tool.Lookup(ctx, json.RawMessage(`{"location":"Kyiv","date":"31.05.2026"}`)); err == nil {
+		t.Error("expected error for malformed date")
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/chatsettings"
+)
+
+// location returns the zone dates typed by the requesting user are read in: their own timezone when
+// the request carries one, else the chat's, else the default.
+func (e *Executor) location(ctx context.Context, chatID int64) *time.Location {
+	if loc, ok := ctx.Value(RequestLocationKey).(*time.Location); ok && loc != nil {
+		return loc
+	}
+	if e.settings != nil {
+		return e.settings.Get(ctx, chatID).Location()
+	}
+	loc, _ := time.LoadLocation(chatsettings.DefaultTimezone)
+	return loc
+}
+
+// setTimezone stores (or with an empty timezone clears) the requesting user's timezone.
+func (e *Executor) setTimezone(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Timezone string `json:"timezone"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	userID := requestUserID(ctx)
+	if userID == 0 {
+		return "", fmt.Errorf("%w: no requesting user", ErrUnavailable)
+	}
+	if strings.TrimSpace(params.Timezone) == "" {
+		if err := e.db.SetUserTimezone(ctx, userID, ""); err != nil {
+			return "", err
+		}
+		slog.InfoContext(ctx, "user timezone cleared")
+		return e.t(ctx, "timezone.cleared"), nil
+	}
+	tz, ok := e.timeInfo.ResolveZone(params.Timezone)
+	if !ok {
+		return e.t(ctx, "time.unknown_location", params.Timezone), nil
+	}
+	if err := e.db.SetUserTimezone(ctx, userID, tz); err != nil {
+		return "", err
+	}
+	loc, _ := time.LoadLocation(tz)
+	slog.InfoContext(ctx, "user timezone set", "timezone", tz)
+	return e.t(ctx, "timezone.set", tz, time.Now().In(loc).Format("15:04")), nil
+}
@@ -0,0 +1,42 @@
+package tools
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTimeInfoTool_ResolveZone(t *testing.T) {
+	tool := NewTimeInfoTool(nil, "en")
+	tests := []struct {
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{"Торонто", "America/Toronto", true},
+		{"Kyiv, Ukraine", "Europe/Kyiv", true},
+		{" Asia/Kolkata ", "Asia/Kolkata", true},
+		{"UTC", "UTC", true},
+		{"Atlantis", "", false},
+		{"Mars/Olympus", "", false},
+		{"Local", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := tool.ResolveZone(tt.query)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ResolveZone(%q) = %q, %v, want %q, %v", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestExecutor_Location(t *testing.T) {
+	e := &Executor{}
+	if got := e.location(context.Background(), 1).String(); got != "Europe/Kyiv" {
+		t.Errorf("default location = %q, want Europe/Kyiv", got)
+	}
+	toronto, _ := time.LoadLocation("America/Toronto")
+	ctx := context.WithValue(context.Background(), RequestLocationKey, toronto)
+	if got := e.location(ctx, 1); got != toronto {
+		t.Errorf("request location = %v, want America/Toronto", got)
+	}
+}
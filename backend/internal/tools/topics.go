@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+)
+
+// maxTopicLen bounds a topic's text; it is a hint, not a note.
+const maxTopicLen = 300
+
+// ParseDueAt reads a topic's due time: RFC 3339, or "2006-01-02 15:04" / "2006-01-02" in loc.
+// An empty string means no due time.
+func ParseDueAt(s string, loc *time.Location) (*time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	t, ok := parseLocalTime(s, loc)
+	if !ok {
+		return nil, fmt.Errorf("%w: due_at must be a date (2006-01-02), a local time (2006-01-02 15:04) or RFC 3339", ErrInvalidArgs)
+	}
+	return &t, nil
+}
+
+// parseLocalTime reads RFC 3339, or a date or local date/time in loc, as the model writes them.
+func parseLocalTime(s string, loc *time.Location) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	for _, layout := range []string{"2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// addChatTopic stores a proactive topic hint for the current chat.
+func (e *Executor) addChatTopic(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Kind  string `json:"kind"`
+		Text  string `json:"text"`
+		DueAt string `json:"due_at"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", fmt.Errorf("parse args: %w", err)
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	text := strings.TrimSpace(params.Text)
+	if !db.ValidTopicKind(params.Kind) || text == "" {
+		return "", fmt.Errorf("%w: kind must be event, joke or follow_up, and text is required", ErrInvalidArgs)
+	}
+	if r := []rune(text); len(r) > maxTopicLen {
+		text = string(r[:maxTopicLen])
+	}
+	loc := e.location(ctx, chatID)
+	due, err := ParseDueAt(params.DueAt, loc)
+	if err != nil {
+		return "", err
+	}
+
+	userID := requestUserID(ctx)
+	topic := &db.ChatTopic{ChatID: chatID, Kind: params.Kind, Text: text, DueAt: due, Source: "tool"}
+	if userID != 0 {
+		topic.CreatedBy = &userID
+	}
+	id, err := e.db.InsertChatTopic(ctx, topic)
+	if err != nil {
+		return "", err
+	}
+	slog.InfoContext(ctx, "chat topic added", "topic_id", id, "kind", params.Kind)
+	return e.t(ctx, "topic.added", text), nil
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDueAt(t *testing.T) {
+	kyiv, _ := time.LoadLocation("Europe/Kyiv")
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		{"2026-10-20", time.Date(2026, 10, 20, 0, 0, 0, 0, kyiv)},
+		{"2026-10-20 18:30", time.Date(2026, 10, 20, 18, 30, 0, 0, kyiv)},
+		{"2026-10-20T18:30", time.Date(2026, 10, 20, 18, 30, 0, 0, kyiv)},
+		{"2026-10-20T15:30:00Z", time.Date(2026, 10, 20, 15, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDueAt(tt.in, kyiv)
+		if err != nil || got == nil || !got.Equal(tt.want) {
+			t.Errorf("ParseDueAt(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+	if got, err := ParseDueAt("  ", kyiv); got != nil || err != nil {
+		t.Errorf("empty due_at should mean none, got %v, %v", got, err)
+	}
+	if _, err := ParseDueAt("next friday", kyiv); err == nil {
+		t.Error("expected error for free-form date")
+	}
+}
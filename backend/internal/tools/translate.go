@@ -0,0 +1,116 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+const (
+	// maxTranslateLen bounds the text of one translate call.
+	maxTranslateLen = 4000
+	maxGlossaryTerm = 100
+	// maxGlossaryTerms bounds a chat's glossary; past it, entries must be removed first.
+	maxGlossaryTerms = 300
+)
+
+// normalizeLang trims and lowercases a language code ("UK " -> "uk").
+func normalizeLang(lang string) string {
+	return strings.ToLower(strings.TrimSpace(lang))
+}
+
+// translate runs translate: a deterministic translation (no persona) using the chat's glossary.
+func (e *Executor) translate(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Text   string `json:"text"`
+		Source string `json:"source"`
+		Target string `json:"target"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	text, target := strings.TrimSpace(params.Text), normalizeLang(params.Target)
+	if text == "" || target == "" {
+		return "", fmt.Errorf("%w: text and target are required", ErrInvalidArgs)
+	}
+	if len([]rune(text)) > maxTranslateLen {
+		return "", fmt.Errorf("text is longer than %d characters; translate it in parts", maxTranslateLen)
+	}
+	var glossary []db.GlossaryTerm
+	if chatID := requestChatID(ctx); chatID != 0 {
+		terms, err := e.db.ListGlossary(ctx, chatID, target)
+		if err != nil {
+			slog.WarnContext(ctx, "load glossary failed", "error", err)
+		}
+		glossary = llm.GlossaryFor(text, target, terms)
+	}
+	translation, err := e.llmClient.Translate(ctx, text, normalizeLang(params.Source), target, glossary)
+	if err != nil {
+		return "", err
+	}
+	data, _ := json.Marshal(map[string]any{
+		"target":         target,
+		"translation":    translation,
+		"glossary_terms": len(glossary),
+	})
+	return string(data), nil
+}
+
+// setGlossaryTerm runs set_glossary_term: adds or updates how translate renders a term in the
+// current chat, or removes the entry when translation is empty.
+func (e *Executor) setGlossaryTerm(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		Term        string `json:"term"`
+		Translation string `json:"translation"`
+		Lang        string `json:"lang"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	chatID := requestChatID(ctx)
+	if chatID == 0 {
+		return "", fmt.Errorf("%w: no current chat", ErrUnavailable)
+	}
+	g := &db.GlossaryTerm{
+		ChatID:      chatID,
+		Term:        truncateRunes(strings.TrimSpace(params.Term), maxGlossaryTerm),
+		Translation: truncateRunes(strings.TrimSpace(params.Translation), maxGlossaryTerm),
+		Lang:        normalizeLang(params.Lang),
+	}
+	if g.Term == "" {
+		return "", fmt.Errorf("%w: term is required", ErrInvalidArgs)
+	}
+	if g.Translation == "" {
+		removed, err := e.db.DeleteGlossaryTerm(ctx, chatID, g.Term, g.Lang)
+		if err != nil {
+			return "", err
+		}
+		data, _ := json.Marshal(map[string]any{"removed": removed, "term": g.Term})
+		return string(data), nil
+	}
+	count, err := e.db.CountGlossaryTerms(ctx, chatID)
+	if err != nil {
+		return "", err
+	}
+	if count >= maxGlossaryTerms {
+		return "", fmt.Errorf("this chat's glossary already has %d entries; remove some first", count)
+	}
+	if userID := requestUserID(ctx); userID != 0 {
+		g.CreatedBy = &userID
+	}
+	created, err := e.db.SetGlossaryTerm(ctx, g)
+	if err != nil {
+		return "", err
+	}
+	status := "updated"
+	if created {
+		status = "saved"
+	}
+	data, _ := json.Marshal(map[string]any{"status": status, "term": g.Term, "translation": g.Translation, "lang": g.Lang})
+	return string(data), nil
+}
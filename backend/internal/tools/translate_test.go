@@ -0,0 +1,40 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTranslate_Validation(t *testing.T) {
+	e := &Executor{}
+	ctx := context.Background()
+	long, _ := json.Marshal(map[string]string{"text": strings.Repeat("я", maxTranslateLen+1), "target": "en"})
+	for _, args := range []string{
+		`{"text":"  ","target":"en"}`,
+		`{"text":"привіт","target":" "}`,
+		string(long),
+	} {
+		if _, err := e.translate(ctx, json.RawMessage(args)); err == nil {
+			t.Errorf("translate(%.40s) should fail", args)
+		}
+	}
+}
+
+func TestSetGlossaryTerm_Validation(t *testing.T) {
+	e := &Executor{}
+	if _, err := e.setGlossaryTerm(context.Background(), json.RawMessage(`{"term":"Гряг","translation":"Gryag"}`)); err == nil {
+		t.Error("expected an error without a current chat")
+	}
+	ctx := context.WithValue(context.Background(), RequestChatIDKey, int64(-100))
+	if _, err := e.setGlossaryTerm(ctx, json.RawMessage(`{"term":" ","translation":"Gryag"}`)); err == nil {
+		t.Error("expected an error without a term")
+	}
+}
+
+func TestNormalizeLang(t *testing.T) {
+	if got := normalizeLang(" UK "); got != "uk" {
+		t.Errorf("normalizeLang = %q, want uk", got)
+	}
+}
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// videoSummaryTTL is how long a video's summary is reused for the same question and language.
+	videoSummaryTTL = 24 * time.Hour
+	// maxVideoQuestionLen bounds the question asked about a video.
+	maxVideoQuestionLen = 500
+)
+
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// youtubeVideoID extracts the video ID from a YouTube link: watch?v=, youtu.be/, /shorts/, /live/
+// and /embed/ forms, with or without www. or m.
+func youtubeVideoID(raw string) (string, bool) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), "m.")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "music.youtube.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/live/", "/embed/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id = strings.Trim(rest, "/")
+			}
+		}
+	}
+	return id, youtubeID.MatchString(id)
+}
+
+// watchVideo runs watch_video: summarizes a YouTube video, or answers a question about it.
+func (e *Executor) watchVideo(ctx context.Context, args json.RawMessage) (string, error) {
+	var params struct {
+		URL      string `json:"url"`
+		Question string `json:"question"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return "", err
+	}
+	id, ok := youtubeVideoID(params.URL)
+	if !ok {
+		return "", fmt.Errorf("%w: url must be a YouTube video link", ErrInvalidArgs)
+	}
+	question := truncateRunes(strings.TrimSpace(params.Question), maxVideoQuestionLen)
+	lang := requestLanguage(ctx, e.lang)
+
+	sum := sha256.Sum256([]byte(question))
+	key := fmt.Sprintf("video:summary:%s:%s:%s", id, lang, hex.EncodeToString(sum[:8]))
+	var summary string
+	if e.cache != nil {
+		if found, err := e.cache.GetJSON(ctx, key, &summary); err != nil {
+			slog.WarnContext(ctx, "video summary cache read failed", "error", err)
+		} else if found {
+			return videoOutput(id, summary, true), nil
+		}
+	}
+	summary, err := e.llmClient.WatchVideo(ctx, "https://www.youtube.com/watch?v="+id, question, lang)
+	if err != nil {
+		return "", err
+	}
+	if summary == "" {
+		return "", fmt.Errorf("%w: no summary for this video (private, age-restricted or unavailable?)", ErrUnavailable)
+	}
+	if e.cache != nil {
+		if err := e.cache.SetJSON(ctx, key, summary, videoSummaryTTL); err != nil {
+			slog.WarnContext(ctx, "video summary cache write failed", "error", err)
+		}
+	}
+	return videoOutput(id, summary, false), nil
+}
+
+func videoOutput(id, summary string, cached bool) string {
+	data, _ := json.Marshal(map[string]any{
+		"video_id": id,
+		"summary":  summary,
+		"cached":   cached,
+	})
+	return string(data)
+}
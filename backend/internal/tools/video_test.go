@@ -0,0 +1,41 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestYoutubeVideoID(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42s":  "dQw4w9WgXcQ",
+		"https://m.youtube.com/watch?v=dQw4w9WgXcQ":          "dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=abc":                "dQw4w9WgXcQ",
+		"https://youtube.com/shorts/dQw4w9WgXcQ":             "dQw4w9WgXcQ",
+		"https://www.youtube.com/live/dQw4w9WgXcQ?feature=x": "dQw4w9WgXcQ",
+		" https://www.youtube.com/embed/dQw4w9WgXcQ ":        "dQw4w9WgXcQ",
+	} {
+		if id, ok := youtubeVideoID(link); !ok || id != want {
+			t.Errorf("youtubeVideoID(%q) = %q, %v", link, id, ok)
+		}
+	}
+	for _, link := range []string{
+		"https://vimeo.com/123456",
+		"https://www.youtube.com/channel/UC123",
+		"https://www.youtube.com/watch?v=short",
+		"https://youtube.com.evil.example/watch?v=dQw4w9WgXcQ",
+		"youtube.com/watch?v=dQw4w9WgXcQ",
+	} {
+		if id, ok := youtubeVideoID(link); ok {
+			t.Errorf("expected %q to be rejected, got %q", link, id)
+		}
+	}
+}
+
+func TestWatchVideo_RejectsOtherLinks(t *testing.T) {
+	e := &Executor{}
+	if _, err := e.watchVideo(context.Background(), json.RawMessage(`{"url":"https://example.com/video.mp4"}`)); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected invalid args, got %v", err)
+	}
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+	"google.golang.org/genai"
+)
+
+var webhookToolName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// webhookFile is a webhook tools file: {"version": 1, "tools": [...]}. Each tool is a
+// declaration (as in declaration files) plus where to send its calls.
+type webhookFile struct {
+	Version int           `json:"version"`
+	Tools   []webhookTool `json:"tools"`
+}
+
+type webhookTool struct {
+	declTool
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+type webhook struct {
+	url     string
+	headers map[string]string
+}
+
+// WebhookTools runs tools declared in WEBHOOK_TOOLS_FILE: the model's arguments are POSTed as
+// JSON to the tool's URL, and the JSON response is the tool output. Calls go through the egress
+// policy; internal URLs need their host in EGRESS_INTERNAL_HOSTS, and responses are capped at
+// EGRESS_MAX_RESPONSE_BYTES.
+type WebhookTools struct {
+	client   *http.Client
+	maxBytes int64 // the policy's MaxResponseBytes
+	hooks    map[string]*webhook
+	decls    []*genai.FunctionDeclaration
+}
+
+// LoadWebhookTools reads and validates a webhook tools file. ${VAR} in header values is expanded
+// from the environment, so tokens stay out of the file. URLs the policy refuses are an error.
+func LoadWebhookTools(path string, policy *egress.Policy) (*WebhookTools, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f webhookFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if f.Version != declFileVersion {
+		return nil, fmt.Errorf("unsupported version %d (want %d)", f.Version, declFileVersion)
+	}
+	// No overall timeout: calls are bounded by the tool's context
+	client := &http.Client{Transport: policy.Transport(), CheckRedirect: policy.CheckRedirect}
+	w := &WebhookTools{client: client, maxBytes: policy.MaxResponseBytes, hooks: make(map[string]*webhook, len(f.Tools))}
+	for i, t := range f.Tools {
+		if !webhookToolName.MatchString(t.Name) {
+			return nil, fmt.Errorf("tools[%d]: name %q must be 1-64 letters, digits or _", i, t.Name)
+		}
+		if w.hooks[t.Name] != nil {
+			return nil, fmt.Errorf("tool %s declared twice", t.Name)
+		}
+		decl, err := t.declaration()
+		if err != nil {
+			return nil, err
+		}
+		if u, err := url.Parse(t.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("tool %s: url must be an absolute http(s) URL", t.Name)
+		}
+		if _, err := policy.CheckURL(t.URL); err != nil {
+			return nil, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		headers := make(map[string]string, len(t.Headers))
+		for k, v := range t.Headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		w.hooks[t.Name] = &webhook{url: t.URL, headers: headers}
+		w.decls = append(w.decls, decl)
+	}
+	return w, nil
+}
+
+// Declarations returns the function declarations of every webhook tool, in file order.
+func (w *WebhookTools) Declarations() []*genai.FunctionDeclaration {
+	return w.decls
+}
+
+// Has reports whether name is a webhook tool.
+func (w *WebhookTools) Has(name string) bool {
+	return w != nil && w.hooks[name] != nil
+}
+
+// Call POSTs the arguments to the tool's webhook and returns its (compacted) JSON response.
+// The tool name, request ID, chat and user go along as X-Gryag-* headers.
+func (w *WebhookTools) Call(ctx context.Context, name string, args json.RawMessage) (string, error) {
+	hook := w.hooks[name]
+	if hook == nil {
+		return "", fmt.Errorf("unknown webhook tool %s", name)
+	}
+	if len(bytes.TrimSpace(args)) == 0 {
+		args = json.RawMessage(`{}`)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.url, bytes.NewReader(args))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Gryag-Tool", name)
+	if id, ok := logging.Value(ctx, logging.KeyRequestID); ok {
+		req.Header.Set("X-Gryag-Request-Id", id.String())
+	}
+	if chatID := requestChatID(ctx); chatID != 0 {
+		req.Header.Set("X-Gryag-Chat-Id", strconv.FormatInt(chatID, 10))
+	}
+	if userID := requestUserID(ctx); userID != 0 {
+		req.Header.Set("X-Gryag-User-Id", strconv.FormatInt(userID, 10))
+	}
+	for k, v := range hook.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("webhook %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, w.maxBytes+1))
+	if err != nil {
+		return "", fmt.Errorf("webhook %s: read response: %w", name, err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", webhookStatusError(name, resp.StatusCode)
+	}
+	if int64(len(body)) > w.maxBytes {
+		return "", fmt.Errorf("webhook %s response is over %d bytes: %w", name, w.maxBytes, egress.ErrResponseTooLarge)
+	}
+	var out bytes.Buffer
+	if err := json.Compact(&out, body); err != nil {
+		return "", fmt.Errorf("webhook %s returned invalid JSON: %w", name, err)
+	}
+	return out.String(), nil
+}
+
+// webhookStatusError maps a failed webhook response onto the tool error kinds.
+func webhookStatusError(name string, code int) error {
+	switch {
+	case code == http.StatusBadRequest || code == http.StatusUnprocessableEntity:
+		return fmt.Errorf("%w: the webhook rejected the arguments (HTTP %d)", ErrInvalidArgs, code)
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return fmt.Errorf("%w: webhook %s: HTTP %d", ErrForbidden, name, code)
+	case code == http.StatusNotFound:
+		return fmt.Errorf("%w: webhook %s: HTTP %d", ErrNotFound, name, code)
+	case code == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: webhook %s: HTTP %d", ErrRateLimited, name, code)
+	case code >= 500:
+		return fmt.Errorf("%w: webhook %s: HTTP %d", ErrUnavailable, name, code)
+	}
+	return fmt.Errorf("webhook %s: HTTP %d", name, code)
+}
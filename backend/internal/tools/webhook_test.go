@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ThatHunky/gryag/backend/internal/egress"
+)
+
+func writeWebhookFile(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "webhooks.json")
+	if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadWebhookTools_Invalid(t *testing.T) {
+	for _, body := range []string{
+		`{"version": 2, "tools": []}`,
+		`{"version": 1, "tools": [{"name": "bad-name", "description": "x", "url": "http://h"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "", "url": "http://h"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "x", "url": "ftp://h"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "x", "url": "/relative"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "x", "url": "http://h", "method": "GET"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "x", "url": "http://h"}, {"name": "a", "description": "x", "url": "http://h"}]}`,
+		`{"version": 1, "tools": [{"name": "a", "description": "x", "url": "http://h", "parameters": {"type": "object", "required": ["q"]}}]}`,
+	} {
+		if _, err := LoadWebhookTools(writeWebhookFile(t, body), &egress.Policy{}); err == nil {
+			t.Errorf("expected %s to be rejected", body)
+		}
+	}
+	policy := &egress.Policy{AllowedDomains: []string{"example.com"}, InternalHosts: []string{"hooks.internal"}}
+	body := `{"version": 1, "tools": [{"name": "a", "description": "x", "url": "https://evil.com/hook"}]}`
+	if _, err := LoadWebhookTools(writeWebhookFile(t, body), policy); !errors.Is(err, egress.ErrDomainNotAllowed) {
+		t.Errorf("expected a URL outside the allow-list to be rejected, got %v", err)
+	}
+	body = `{"version": 1, "tools": [{"name": "a", "description": "x", "url": "http://hooks.internal/a"}]}`
+	if _, err := LoadWebhookTools(writeWebhookFile(t, body), policy); err != nil {
+		t.Errorf("expected an internal host to be allowed, got %v", err)
+	}
+}
+
+func TestWebhookTools_Call(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.URL.Path {
+		case "/weather":
+			var args struct {
+				City string `json:"city"`
+			}
+			if json.Unmarshal(body, &args) != nil || args.City == "" {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			w.Write([]byte(`{ "city": "` + args.City + `", "chat": "` + r.Header.Get("X-Gryag-Chat-Id") + `", "tool": "` + r.Header.Get("X-Gryag-Tool") + `" }`))
+		case "/broken":
+			w.Write([]byte(`not json`))
+		case "/big":
+			w.Write([]byte(`"` + strings.Repeat("x", 2048) + `"`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("WEATHER_TOKEN", "s3cret")
+	file := writeWebhookFile(t, `{"version": 1, "tools": [
+		{"name": "weather", "description": "Weather for a city", "url": "`+srv.URL+`/weather",
+		 "headers": {"Authorization": "Bearer ${WEATHER_TOKEN}"},
+		 "parameters": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]}},
+		{"name": "broken", "description": "Returns text", "url": "`+srv.URL+`/broken", "headers": {"Authorization": "Bearer ${WEATHER_TOKEN}"}},
+		{"name": "down", "description": "Always fails", "url": "`+srv.URL+`/down", "headers": {"Authorization": "Bearer ${WEATHER_TOKEN}"}},
+		{"name": "big", "description": "Returns too much", "url": "`+srv.URL+`/big", "headers": {"Authorization": "Bearer ${WEATHER_TOKEN}"}}]}`)
+	hooks, err := LoadWebhookTools(file, &egress.Policy{InternalHosts: []string{"127.0.0.1"}, MaxResponseBytes: 1024})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if decls := hooks.Declarations(); len(decls) != 4 || decls[0].Name != "weather" || decls[0].Parameters.Required[0] != "city" {
+		t.Fatalf("unexpected declarations %v", decls)
+	}
+
+	ctx := context.WithValue(context.Background(), RequestChatIDKey, int64(-100))
+	out, err := hooks.Call(ctx, "weather", json.RawMessage(`{"city": "Kyiv"}`))
+	if err != nil || out != `{"city":"Kyiv","chat":"-100","tool":"weather"}` {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+	if _, err := hooks.Call(ctx, "weather", json.RawMessage(`{}`)); !errors.Is(err, ErrInvalidArgs) {
+		t.Errorf("expected invalid args for 422, got %v", err)
+	}
+	if _, err := hooks.Call(ctx, "broken", nil); err == nil || !strings.Contains(err.Error(), "invalid JSON") {
+		t.Errorf("expected an invalid JSON error, got %v", err)
+	}
+	if _, err := hooks.Call(ctx, "down", nil); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected unavailable for 502, got %v", err)
+	}
+	// EGRESS_MAX_RESPONSE_BYTES applies to webhooks too
+	if _, err := hooks.Call(ctx, "big", nil); !errors.Is(err, egress.ErrResponseTooLarge) {
+		t.Errorf("expected the response cap to apply, got %v", err)
+	}
+
+	// Loopback is refused unless the host is listed as internal
+	strict, err := LoadWebhookTools(file, &egress.Policy{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := strict.Call(ctx, "weather", json.RawMessage(`{"city": "Kyiv"}`)); !errors.Is(err, egress.ErrBlockedAddress) {
+		t.Errorf("expected a blocked address error, got %v", err)
+	}
+}
+
+func TestWebhookStatusError(t *testing.T) {
+	for code, want := range map[int]string{400: KindInvalidArgs, 401: KindForbidden, 404: KindNotFound, 429: KindRateLimited, 503: KindUnavailable, 302: KindInternal} {
+		if got := errorKind(webhookStatusError("x", code)); got != want {
+			t.Errorf("HTTP %d: got %s, want %s", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Package topicindex runs the hourly topic indexing job: new chat history is split into topics
+// by the LLM and stored in chat_topics_index for the recall_topic tool.
+package topicindex
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/cache"
+	"github.com/ThatHunky/gryag/backend/internal/config"
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+	"github.com/ThatHunky/gryag/backend/internal/logging"
+)
+
+const (
+	// batchSize caps the messages segmented in one LLM call.
+	batchSize = 300
+	// lookback bounds which messages are indexed at all (the first run doesn't read all history).
+	lookback = 30 * 24 * time.Hour
+	// settleTime is how long a conversation must have been quiet before the last topic of a pass
+	// is stored; until then it may still be going and is segmented again with the next messages.
+	settleTime = 2 * time.Hour
+	// maxChatsPerRun bounds the LLM calls of one hourly run; the rest wait for the next hour.
+	maxChatsPerRun = 20
+)
+
+// Runner performs one indexing pass over the chats with enough new messages.
+type Runner struct {
+	db     *db.DB
+	cache  *cache.Cache
+	llm    *llm.Client
+	config *config.Config
+}
+
+// NewRunner creates a topic index runner.
+func NewRunner(database *db.DB, c *cache.Cache, llmClient *llm.Client, cfg *config.Config) *Runner {
+	return &Runner{db: database, cache: c, llm: llmClient, config: cfg}
+}
+
+// RunOnce segments the new messages of up to maxChatsPerRun chat topics, busiest first.
+func (r *Runner) RunOnce(ctx context.Context, now time.Time) {
+	ctx = logging.With(ctx, "component", "topic_index")
+	threads, err := r.db.TopicIndexCandidates(ctx, r.config.TopicIndexMinMessages, now.Add(-lookback))
+	if err != nil {
+		slog.ErrorContext(ctx, "get topic index candidates failed", "error", err)
+		return
+	}
+	stored := 0
+	for i, t := range threads {
+		if i == maxChatsPerRun {
+			break
+		}
+		stored += r.indexThread(logging.With(ctx, "chat_id", t.ChatID, "thread_id", t.ThreadID), t, now)
+	}
+	slog.InfoContext(ctx, "topic indexing finished", "candidates", len(threads), "segments", stored)
+}
+
+// indexThread segments one chat topic's unindexed messages and stores the finished topics.
+// Returns how many were stored.
+func (r *Runner) indexThread(ctx context.Context, t db.ChatThread, now time.Time) int {
+	messages, err := r.db.UnindexedMessages(ctx, t, now.Add(-lookback), batchSize)
+	if err != nil {
+		slog.WarnContext(ctx, "get unindexed messages failed", "error", err)
+		return 0
+	}
+	if len(messages) < r.config.TopicIndexMinMessages {
+		return 0
+	}
+	labels, err := r.llm.SegmentTopics(ctx, messages)
+	if err != nil {
+		slog.WarnContext(ctx, "segment topics failed", "error", err)
+		return 0
+	}
+	segments := toSegments(t, messages, labels, len(messages) < batchSize, now)
+	if err := r.db.InsertTopicSegments(ctx, segments); err != nil {
+		slog.WarnContext(ctx, "store topic segments failed", "error", err)
+		return 0
+	}
+	return len(segments)
+}
+
+// toSegments turns labels over messages into rows. When more messages may follow (open) and the
+// last topic runs to the newest message written under settleTime before now, it is held back.
+func toSegments(t db.ChatThread, messages []db.Message, labels []llm.TopicLabel, open bool, now time.Time) []db.TopicSegment {
+	if n := len(labels); n > 0 && open && labels[n-1].Last == len(messages)-1 &&
+		now.Sub(messages[len(messages)-1].CreatedAt) < settleTime {
+		labels = labels[:n-1]
+	}
+	segments := make([]db.TopicSegment, len(labels))
+	for i, l := range labels {
+		first, last := messages[l.First], messages[l.Last]
+		segments[i] = db.TopicSegment{
+			ChatID:         t.ChatID,
+			ThreadID:       t.ThreadID,
+			Title:          l.Title,
+			Summary:        l.Summary,
+			Keywords:       l.Keywords,
+			FirstMessageID: first.ID,
+			LastMessageID:  last.ID,
+			MessageCount:   l.Last - l.First + 1,
+			StartedAt:      first.CreatedAt,
+			EndedAt:        last.CreatedAt,
+		}
+	}
+	return segments
+}
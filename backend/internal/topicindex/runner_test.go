@@ -0,0 +1,45 @@
+package topicindex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ThatHunky/gryag/backend/internal/db"
+	"github.com/ThatHunky/gryag/backend/internal/llm"
+)
+
+func TestToSegments(t *testing.T) {
+	now := time.Date(2026, 5, 1, 20, 0, 0, 0, time.UTC)
+	messages := make([]db.Message, 6)
+	for i := range messages {
+		messages[i] = db.Message{ID: int64(100 + i), CreatedAt: now.Add(time.Duration(i-6) * 10 * time.Minute)}
+	}
+	labels := []llm.TopicLabel{
+		{First: 0, Last: 2, Title: "Відпустка", Keywords: []string{"відпустка"}},
+		{First: 3, Last: 5, Title: "Футбол"},
+	}
+	thread := db.ChatThread{ChatID: -100, ThreadID: 7}
+
+	// The last topic is still going: held back
+	segs := toSegments(thread, messages, labels, true, now)
+	if len(segs) != 1 {
+		t.Fatalf("expected the open last topic to be held back, got %+v", segs)
+	}
+	s := segs[0]
+	if s.ChatID != -100 || s.ThreadID != 7 || s.FirstMessageID != 100 || s.LastMessageID != 102 || s.MessageCount != 3 ||
+		!s.StartedAt.Equal(messages[0].CreatedAt) || !s.EndedAt.Equal(messages[2].CreatedAt) || s.Title != "Відпустка" {
+		t.Errorf("unexpected segment: %+v", s)
+	}
+
+	// The chat went quiet, or the batch was full: everything is stored
+	if segs := toSegments(thread, messages, labels, true, now.Add(3*time.Hour)); len(segs) != 2 {
+		t.Errorf("expected both topics after the chat settled, got %d", len(segs))
+	}
+	if segs := toSegments(thread, messages, labels, false, now); len(segs) != 2 {
+		t.Errorf("expected both topics from a full batch, got %d", len(segs))
+	}
+	// A last topic that ends before the newest message is finished
+	if segs := toSegments(thread, messages, labels[:1], true, now); len(segs) != 1 {
+		t.Errorf("expected the finished topic to be stored, got %d", len(segs))
+	}
+}
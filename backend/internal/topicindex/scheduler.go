@@ -0,0 +1,36 @@
+package topicindex
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+const (
+	// runInterval is how often new messages are indexed.
+	runInterval = time.Hour
+	// slotTTL keeps an hour's job lock until well after the hour is over, so no replica (or a
+	// restarted one) repeats it.
+	slotTTL = 2 * time.Hour
+)
+
+// Scheduler runs the topic index once an hour. With several replicas each hour runs on the one
+// that takes the hour's job lock first.
+func Scheduler(ctx context.Context, r *Runner) {
+	logger := slog.With("component", "topic_index_scheduler")
+	for {
+		now := time.Now()
+		lock, err := r.cache.AcquireJobLock(ctx, "topic_index:"+now.UTC().Format("2006-01-02T15"), slotTTL)
+		if err != nil {
+			logger.Warn("acquire job lock failed", "error", err)
+		} else if lock != nil {
+			r.RunOnce(ctx, now)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(runInterval):
+		}
+	}
+}
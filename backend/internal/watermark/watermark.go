@@ -0,0 +1,102 @@
+// Package watermark stamps generated images with a small text badge (e.g. "AI") for chats that
+// require AI-content labeling. It uses only the standard library and a built-in bitmap font.
+package watermark
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxLabelLen is the longest label accepted, in characters.
+const MaxLabelLen = 16
+
+var (
+	badgeColor = color.NRGBA{R: 0, G: 0, B: 0, A: 160}
+	textColor  = color.NRGBA{R: 255, G: 255, B: 255, A: 255}
+)
+
+// normalizeLabel uppercases a label so it can be drawn with the built-in font.
+func normalizeLabel(label string) string {
+	return strings.ToUpper(strings.TrimSpace(label))
+}
+
+// ValidLabel reports whether label is non-empty, at most MaxLabelLen characters and uses only
+// characters the built-in font can draw.
+func ValidLabel(label string) error {
+	l := normalizeLabel(label)
+	if l == "" || utf8.RuneCountInString(l) > MaxLabelLen {
+		return fmt.Errorf("label must be 1-%d characters", MaxLabelLen)
+	}
+	for _, r := range l {
+		if _, ok := glyphs[unicode.ToUpper(r)]; !ok {
+			return fmt.Errorf("label may only use A-Z, 0-9, space, '-', '.', '!', 'Ш' and 'І'; got %q", r)
+		}
+	}
+	return nil
+}
+
+// Stamp draws label as a badge in the bottom-right corner of a PNG or JPEG image and returns the
+// image re-encoded in its original format. The badge scales with the image size.
+func Stamp(data []byte, label string) ([]byte, error) {
+	if err := ValidLabel(label); err != nil {
+		return nil, err
+	}
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	drawBadge(dst, normalizeLabel(label))
+
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: 92})
+	default:
+		err = png.Encode(&out, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// drawBadge renders the label on a translucent box near the bottom-right corner of img.
+func drawBadge(img *image.RGBA, label string) {
+	size := img.Bounds().Size()
+	// Glyph pixel size: about 2% of the shorter side, at least 1.
+	scale := max(min(size.X, size.Y)/350, 1)
+	runes := []rune(label)
+	textW := (len(runes)*(glyphWidth+1) - 1) * scale
+	textH := glyphHeight * scale
+	pad, margin := 2*scale, 3*scale
+
+	box := image.Rect(size.X-margin-textW-2*pad, size.Y-margin-textH-2*pad, size.X-margin, size.Y-margin)
+	draw.Draw(img, box, image.NewUniform(badgeColor), image.Point{}, draw.Over)
+
+	x0, y0 := box.Min.X+pad, box.Min.Y+pad
+	fill := image.NewUniform(textColor)
+	for i, r := range runes {
+		g := glyphs[unicode.ToUpper(r)]
+		gx := x0 + i*(glyphWidth+1)*scale
+		for row, line := range g {
+			for col, c := range line {
+				if c != '#' {
+					continue
+				}
+				px := image.Rect(gx+col*scale, y0+row*scale, gx+(col+1)*scale, y0+(row+1)*scale)
+				draw.Draw(img, px, fill, image.Point{}, draw.Over)
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package watermark
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func solidImage(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func TestValidLabel(t *testing.T) {
+	for _, ok := range []string{"AI", "ai", "ШІ", "AI-generated", "Made by AI!"} {
+		if err := ValidLabel(ok); err != nil {
+			t.Errorf("%q: unexpected error: %v", ok, err)
+		}
+	}
+	for _, bad := range []string{"", "   ", "ШТУЧНИЙ", "AI 🤖", "THIS LABEL IS TOO LONG"} {
+		if err := ValidLabel(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestStamp_PNG(t *testing.T) {
+	var in bytes.Buffer
+	png.Encode(&in, solidImage(400, 300, color.White))
+
+	out, err := Stamp(in.Bytes(), "AI")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	img, format, err := image.Decode(bytes.NewReader(out))
+	if err != nil || format != "png" {
+		t.Fatalf("expected png output, got %q (%v)", format, err)
+	}
+	if img.Bounds().Dx() != 400 || img.Bounds().Dy() != 300 {
+		t.Errorf("size changed: %v", img.Bounds())
+	}
+	// Top-left untouched, bottom-right corner area darkened by the badge.
+	if r, _, _, _ := img.At(5, 5).RGBA(); r>>8 != 255 {
+		t.Errorf("expected untouched pixel at top-left, got r=%d", r>>8)
+	}
+	if r, _, _, _ := img.At(400-4, 300-4).RGBA(); r>>8 >= 255 {
+		t.Error("expected the badge in the bottom-right corner")
+	}
+}
+
+func TestStamp_KeepsJPEG(t *testing.T) {
+	var in bytes.Buffer
+	jpeg.Encode(&in, solidImage(200, 200, color.White), nil)
+	out, err := Stamp(in.Bytes(), "ШІ")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(out)); err != nil || format != "jpeg" {
+		t.Errorf("expected jpeg output, got %q (%v)", format, err)
+	}
+}
+
+func TestStamp_NotAnImage(t *testing.T) {
+	if _, err := Stamp([]byte("nope"), "AI"); err == nil {
+		t.Error("expected decode error")
+	}
+}
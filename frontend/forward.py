@@ -0,0 +1,42 @@
+"""
+Forward metadata sent to the backend.
+
+A forwarded message was written by someone else; the backend tells the model who and when, so it
+doesn't treat a shared news post as the user's own words.
+"""
+
+
+def _chat_name(chat) -> str:
+    name = chat.title or ""
+    if getattr(chat, "username", None):
+        name = f"{name} (@{chat.username})" if name else f"@{chat.username}"
+    return name
+
+
+def _user_name(user) -> str:
+    name = " ".join(part for part in (user.first_name, getattr(user, "last_name", None)) if part)
+    if getattr(user, "username", None):
+        name = f"{name} (@{user.username})" if name else f"@{user.username}"
+    return name
+
+
+def forward_facts(message) -> dict:
+    """Payload fields for /api/v1/process: forwarded_from, forwarded_from_chat and forward_date."""
+    origin = getattr(message, "forward_origin", None)
+    if not origin:
+        return {}
+    facts = {}
+    if origin.type == "user":
+        facts["forwarded_from"] = _user_name(origin.sender_user)
+    elif origin.type == "hidden_user":
+        facts["forwarded_from"] = origin.sender_user_name
+    elif origin.type == "chat":
+        facts["forwarded_from_chat"] = _chat_name(origin.sender_chat)
+    elif origin.type == "channel":
+        facts["forwarded_from_chat"] = _chat_name(origin.chat)
+    signature = getattr(origin, "author_signature", None)
+    if signature and "forwarded_from" not in facts:
+        facts["forwarded_from"] = signature
+    if getattr(origin, "date", None):
+        facts["forward_date"] = origin.date.isoformat()
+    return {key: value for key, value in facts.items() if value}
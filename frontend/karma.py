@@ -0,0 +1,33 @@
+"""
+Karma votes from Telegram updates.
+
+A "+"/"-" reply or a reaction counts as a vote on the replied-to / reacted message.
+The backend resolves the author, ignores self-votes and keeps one vote per voter,
+message and source (a changed reaction replaces the earlier vote).
+"""
+
+REPLY_PLUS = {"+", "+1", "++", "👍", "дякую", "спасибі", "thanks"}
+REPLY_MINUS = {"-", "-1", "--", "👎"}
+
+REACTION_PLUS = {"👍", "❤", "❤️", "🔥", "👏", "💯", "🏆", "🥰", "😍"}
+REACTION_MINUS = {"👎", "💩", "🤮", "🤡"}
+
+
+def reply_vote(text: str | None) -> int:
+    """Delta of a reply: 1 for "+", -1 for "-", 0 for anything else."""
+    t = (text or "").strip().lower().rstrip("!.")
+    if t in REPLY_PLUS:
+        return 1
+    if t in REPLY_MINUS:
+        return -1
+    return 0
+
+
+def reaction_vote(emojis: list[str]) -> int:
+    """Delta of a user's current reactions on a message: a positive one wins, then a negative
+    one; none of either (or no reactions left) is 0, which retracts an earlier vote."""
+    if any(e in REACTION_PLUS for e in emojis):
+        return 1
+    if any(e in REACTION_MINUS for e in emojis):
+        return -1
+    return 0
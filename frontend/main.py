@@ -15,10 +15,15 @@ import aiohttp
 import structlog
 from aiogram import Bot, Dispatcher, types
 from aiogram.enums import ChatAction, ContentType, ParseMode
+from aiogram.exceptions import TelegramBadRequest, TelegramForbiddenError
+from aiogram.filters import Command, CommandObject
 from aiogram.types import BotCommand, BufferedInputFile
 from aiohttp import web
 
+from karma import reaction_vote, reply_vote
 from md_to_tg import md_to_telegram_html
+from forward import forward_facts
+from trigger import trigger_facts
 
 # ── Structured JSON Logging (Section 15.2) ──────────────────────────────
 structlog.configure(
@@ -36,9 +41,28 @@ log = structlog.get_logger()
 # ── Configuration ────────────────────────────────────────────────────────
 BOT_TOKEN = os.getenv("TELEGRAM_BOT_TOKEN", "")
 BACKEND_URL = f"http://{os.getenv('BACKEND_HOST', 'gryag-backend')}:{os.getenv('BACKEND_PORT', '27710')}"
+# Shared secret the backend requires on /api/v1/* (sent as X-Gryag-Secret); empty = no auth.
+BACKEND_API_SECRET = os.getenv("BACKEND_API_SECRET", "")
 HEALTH_PORT = int(os.getenv("FRONTEND_HEALTH_PORT", "27711"))
 ENABLE_PROACTIVE_MESSAGING = os.getenv("ENABLE_PROACTIVE_MESSAGING", "false").lower() in ("true", "1", "yes")
+# The weekly admin report is delivered through the same proactive queue.
+ENABLE_ACTIVITY_REPORT = os.getenv("ENABLE_ACTIVITY_REPORT", "false").lower() in ("true", "1", "yes")
+# So is the morning digest for opted-in chats.
+ENABLE_DAILY_DIGEST = os.getenv("ENABLE_DAILY_DIGEST", "false").lower() in ("true", "1", "yes")
+ENABLE_PERSONAL_DIGEST = os.getenv("ENABLE_PERSONAL_DIGEST", "false").lower() in ("true", "1", "yes")
+# deep_research posts its progress and answer through the queue too.
+ENABLE_DEEP_RESEARCH = os.getenv("ENABLE_DEEP_RESEARCH", "false").lower() in ("true", "1", "yes")
+# Report reactions and "+"/"-" replies as karma votes (the backend needs ENABLE_KARMA too).
+ENABLE_KARMA = os.getenv("ENABLE_KARMA", "false").lower() in ("true", "1", "yes")
 PROACTIVE_POLL_INTERVAL_SEC = int(os.getenv("PROACTIVE_POLL_INTERVAL_SEC", "90"))
+# Long-poll the queue (seconds to wait per request; 0 = the old interval polling without acks).
+PROACTIVE_LONG_POLL_SEC = int(os.getenv("PROACTIVE_LONG_POLL_SEC", "25"))
+
+
+def backend_session() -> aiohttp.ClientSession:
+    """HTTP session for backend calls, carrying the shared API secret when configured."""
+    headers = {"X-Gryag-Secret": BACKEND_API_SECRET} if BACKEND_API_SECRET else None
+    return aiohttp.ClientSession(headers=headers)
 
 
 # ── Bot & Dispatcher ────────────────────────────────────────────────────
@@ -77,22 +101,156 @@ def _mime_for_media_type(media_type: str, document_mime: str | None) -> str:
         "video": "video/mp4",
         "document": "image/png",
         "voice": "audio/ogg",
+        "audio": "audio/mpeg",
         "video_note": "video/mp4",
         "sticker": "image/webp",
         "animation": "video/mp4",
     }.get(media_type, "application/octet-stream")
 
 
-async def send_typing_loop(chat_id: int, stop_event: asyncio.Event) -> None:
+async def send_typing_loop(chat_id: int, stop_event: asyncio.Event, thread_id: int | None = None) -> None:
     """Continuously emit typing indicators until the backend responds (Section 10)."""
     while not stop_event.is_set():
         try:
-            await bot.send_chat_action(chat_id=chat_id, action=ChatAction.TYPING)
+            await bot.send_chat_action(chat_id=chat_id, action=ChatAction.TYPING, message_thread_id=thread_id)
         except Exception:
             pass
         await asyncio.sleep(4)
 
 
+async def ack_reply(request_id: str, chat_id: int, sent: types.Message | None) -> None:
+    """Report the Telegram message_id (and file_id for media) of a delivered bot reply to the backend."""
+    if sent is None:
+        return
+    payload = {"request_id": request_id, "chat_id": chat_id, "message_id": sent.message_id}
+    if sent.photo:
+        payload["file_id"] = sent.photo[-1].file_id
+    elif sent.document:
+        payload["file_id"] = sent.document.file_id
+    try:
+        async with backend_session() as session:
+            async with session.post(
+                f"{BACKEND_URL}/api/v1/ack_reply",
+                json=payload,
+                headers={"X-Request-ID": request_id},
+                timeout=aiohttp.ClientTimeout(total=10),
+            ) as resp:
+                if resp.status != 200:
+                    log.warning("ack_reply_bad_status", request_id=request_id, status=resp.status, **await backend_error(resp))
+    except Exception as e:
+        log.warning("ack_reply_failed", request_id=request_id, error=str(e))
+
+
+async def backend_error(resp: aiohttp.ClientResponse) -> dict:
+    """Read a backend error body ({"error", "code", "retryable", ...}) into log fields."""
+    try:
+        body = await resp.json(content_type=None)
+    except Exception:
+        return {"code": "unknown"}
+    if not isinstance(body, dict):
+        return {"code": "unknown"}
+    return {"code": body.get("code", "unknown"), "error": body.get("error"), "retryable": body.get("retryable", False)}
+
+
+async def send_event(event: dict) -> None:
+    """Forward a non-message update (polls, poll answers) to the backend for context storage."""
+    request_id = str(uuid.uuid4())
+    try:
+        async with backend_session() as session:
+            async with session.post(
+                f"{BACKEND_URL}/api/v1/event",
+                json=event,
+                headers={"X-Request-ID": request_id},
+                timeout=aiohttp.ClientTimeout(total=10),
+            ) as resp:
+                if resp.status != 200:
+                    log.warning("event_bad_status", request_id=request_id, type=event.get("type"), status=resp.status, **await backend_error(resp))
+    except Exception as e:
+        log.warning("event_failed", request_id=request_id, type=event.get("type"), error=str(e))
+
+
+def _poll_payload(poll: types.Poll) -> dict:
+    return {
+        "id": poll.id,
+        "question": poll.question,
+        "options": [o.text for o in poll.options],
+        "option_votes": [o.voter_count for o in poll.options],
+        "total_voter_count": poll.total_voter_count,
+        "is_anonymous": poll.is_anonymous,
+        "is_closed": poll.is_closed,
+    }
+
+
+@dp.poll()
+async def handle_poll(poll: types.Poll) -> None:
+    """Poll state updates (Telegram only sends these for polls the bot can observe)."""
+    await send_event({"type": "poll", "poll": _poll_payload(poll)})
+
+
+@dp.poll_answer()
+async def handle_poll_answer(answer: types.PollAnswer) -> None:
+    """Votes in non-anonymous polls."""
+    user = answer.user
+    await send_event({
+        "type": "poll_answer",
+        "user_id": user.id if user else None,
+        "username": user.username if user else None,
+        "first_name": user.first_name if user else None,
+        "poll_answer": {"poll_id": answer.poll_id, "option_ids": list(answer.option_ids)},
+    })
+
+
+@dp.message_reaction(lambda _: ENABLE_KARMA)
+async def handle_message_reaction(update: types.MessageReactionUpdate) -> None:
+    """Reactions count as karma votes on the reacted message (anonymous reactions don't)."""
+    if not update.user or update.user.is_bot:
+        return
+    emojis = [r.emoji for r in update.new_reaction if getattr(r, "emoji", None)]
+    await send_event({
+        "type": "karma",
+        "chat_id": update.chat.id,
+        "message_id": update.message_id,
+        "user_id": update.user.id,
+        "karma": {"source": "reaction", "delta": reaction_vote(emojis)},
+    })
+
+
+@dp.message(Command("proactive"), lambda _: ENABLE_PROACTIVE_MESSAGING)
+async def handle_proactive_command(message: types.Message, command: CommandObject) -> None:
+    """/proactive: show or change this chat's proactive message settings (the backend checks permissions)."""
+    request_id = str(uuid.uuid4())
+    user_id = message.from_user.id if message.from_user else None
+    is_chat_admin = False
+    if message.chat.type != "private" and user_id is not None:
+        try:
+            member = await bot.get_chat_member(message.chat.id, user_id)
+            is_chat_admin = member.status in ("creator", "administrator")
+        except Exception as e:
+            log.warning("chat_member_lookup_failed", request_id=request_id, error=str(e))
+    payload = {
+        "chat_id": message.chat.id,
+        "user_id": user_id,
+        "is_chat_admin": is_chat_admin,
+        "args": command.args or "",
+    }
+    try:
+        async with backend_session() as session:
+            async with session.post(
+                f"{BACKEND_URL}/api/v1/proactive/settings",
+                json=payload,
+                headers={"X-Request-ID": request_id},
+                timeout=aiohttp.ClientTimeout(total=10),
+            ) as resp:
+                if resp.status != 200:
+                    log.warning("proactive_settings_bad_status", request_id=request_id, status=resp.status, **await backend_error(resp))
+                    return
+                data = await resp.json()
+                if data.get("reply"):
+                    await message.answer(data["reply"], message_thread_id=message.message_thread_id if message.is_topic_message else None)
+    except Exception as e:
+        log.warning("proactive_settings_failed", request_id=request_id, error=str(e))
+
+
 @dp.message()
 async def handle_message(message: types.Message) -> None:
     """Forward every incoming message to the Go backend."""
@@ -107,9 +265,33 @@ async def handle_message(message: types.Message) -> None:
         content_type=message.content_type,
     )
 
+    # Polls are stored as events so tallies can be tracked; the message itself still goes to /process
+    if message.poll:
+        await send_event({
+            "type": "poll",
+            "chat_id": message.chat.id,
+            "message_id": message.message_id,
+            "user_id": message.from_user.id if message.from_user else None,
+            "poll": _poll_payload(message.poll),
+        })
+
+    # "+"/"-" replies are karma votes; the message itself still goes to /process
+    reply = getattr(message, "reply_to_message", None)
+    if ENABLE_KARMA and reply and reply.from_user and not reply.from_user.is_bot and message.from_user:
+        delta = reply_vote(message.text)
+        if delta:
+            await send_event({
+                "type": "karma",
+                "chat_id": message.chat.id,
+                "message_id": reply.message_id,
+                "user_id": message.from_user.id,
+                "karma": {"source": "reply", "delta": delta, "target_user_id": reply.from_user.id},
+            })
+
     # Start typing indicator
     stop_typing = asyncio.Event()
-    typing_task = asyncio.create_task(send_typing_loop(message.chat.id, stop_typing))
+    topic_thread_id = message.message_thread_id if message.is_topic_message else None
+    typing_task = asyncio.create_task(send_typing_loop(message.chat.id, stop_typing, topic_thread_id))
 
     try:
         # Extract file_id from media messages for storage in DB (media recall)
@@ -127,6 +309,9 @@ async def handle_message(message: types.Message) -> None:
         elif message.voice:
             file_id = message.voice.file_id
             media_type = "voice"
+        elif message.audio:
+            file_id = message.audio.file_id
+            media_type = "audio"
         elif message.video_note:
             file_id = message.video_note.file_id
             media_type = "video_note"
@@ -141,7 +326,7 @@ async def handle_message(message: types.Message) -> None:
         media_base64 = None
         mime_type = None
         if file_id:
-            doc_mime = getattr(message.document, "mime_type", None) if message.document else None
+            doc_mime = getattr(message.document or message.audio, "mime_type", None)
             mime_type = _mime_for_media_type(media_type or "", doc_mime)
             result = await download_media_as_base64(file_id, mime_type)
             if result:
@@ -155,12 +340,27 @@ async def handle_message(message: types.Message) -> None:
             "user_id": message.from_user.id if message.from_user else None,
             "username": message.from_user.username if message.from_user else None,
             "first_name": message.from_user.first_name if message.from_user else None,
-            "text": message.text or message.caption or "",
+            "language_code": message.from_user.language_code if message.from_user else None,
+            "text": message.text or message.caption or (f"📊 {message.poll.question}" if message.poll else ""),
             "message_id": message.message_id,
             "date": message.date.isoformat() if message.date else None,
             "file_id": file_id,
             "media_type": media_type,
         }
+        # Whether to answer is the backend's call (trigger policy); we only report the facts
+        me = await bot.me()
+        payload.update(trigger_facts(message, me.id, me.username))
+        # Forwarded posts: who wrote them and when, so the text isn't taken as the user's own
+        payload.update(forward_facts(message))
+        if topic_thread_id:
+            # Forum topic: the backend scopes context, summaries and search to this thread
+            payload["message_thread_id"] = topic_thread_id
+        if message.media_group_id:
+            # Album item: the backend collects the album and answers it once
+            payload["media_group_id"] = message.media_group_id
+        if message.sticker:
+            payload["sticker_emoji"] = message.sticker.emoji
+            payload["sticker_set"] = message.sticker.set_name
         if getattr(message, "reply_to_message", None):
             payload["reply_to_message_id"] = message.reply_to_message.message_id
             payload["reply_to_text"] = (
@@ -171,7 +371,7 @@ async def handle_message(message: types.Message) -> None:
             payload["mime_type"] = mime_type
             logger.info("sending_media_to_backend", media_type=media_type, mime_type=mime_type, size_bytes=len(media_base64) * 3 // 4)
 
-        async with aiohttp.ClientSession() as session:
+        async with backend_session() as session:
             async with session.post(
                 f"{BACKEND_URL}/api/v1/process",
                 json=payload,
@@ -184,9 +384,11 @@ async def handle_message(message: types.Message) -> None:
                     media_url = data.get("media_url", "")
                     media_type = data.get("media_type", "")
                     media_base64 = data.get("media_base64", "")
+                    thread_id = data.get("message_thread_id") or topic_thread_id
 
                     # Convert markdown to Telegram HTML
                     reply_html = md_to_telegram_html(reply_text) if reply_text else ""
+                    sent = None
 
                     # Handle media responses (image generation results)
                     if (media_url or media_base64) and media_type == "photo":
@@ -196,10 +398,11 @@ async def handle_message(message: types.Message) -> None:
                                 photo_bytes = base64.b64decode(media_base64)
                                 photo_data = BufferedInputFile(photo_bytes, filename="generated.png")
 
-                            await message.answer_photo(
+                            sent = await message.answer_photo(
                                 photo=photo_data,
                                 caption=reply_html[:1024] if reply_html else None,
                                 parse_mode=ParseMode.HTML,
+                                message_thread_id=thread_id,
                             )
                             logger.info("photo_sent", has_base64=bool(media_base64), media_url=media_url)
                         except Exception as e:
@@ -209,6 +412,7 @@ async def handle_message(message: types.Message) -> None:
                                 await message.answer(
                                     f"{reply_html}\n\n🖼 {media_url if media_url else '<Image generated but upload failed>'}",
                                     parse_mode=ParseMode.HTML,
+                                    message_thread_id=thread_id,
                                 )
                     elif (media_url or media_base64) and media_type == "document":
                         try:
@@ -216,10 +420,11 @@ async def handle_message(message: types.Message) -> None:
                             if media_base64:
                                 doc_bytes = base64.b64decode(media_base64)
                                 document_data = BufferedInputFile(doc_bytes, filename="generated.png")
-                            await message.answer_document(
+                            sent = await message.answer_document(
                                 document=document_data,
                                 caption=reply_html[:1024] if reply_html else None,
                                 parse_mode=ParseMode.HTML,
+                                message_thread_id=thread_id,
                             )
                             logger.info("document_sent", has_base64=bool(media_base64), media_url=media_url)
                         except Exception as e:
@@ -228,19 +433,39 @@ async def handle_message(message: types.Message) -> None:
                                 await message.answer(
                                     f"{reply_html}\n\n📎 {media_url if media_url else '<File generated but upload failed>'}",
                                     parse_mode=ParseMode.HTML,
+                                    message_thread_id=thread_id,
                                 )
                     elif reply_html:
                         # Split long messages (Telegram limit: 4096 chars)
                         for i in range(0, len(reply_html), 4096):
                             chunk = reply_html[i : i + 4096]
-                            await message.answer(chunk, parse_mode=ParseMode.HTML)
+                            chunk_msg = await message.answer(chunk, parse_mode=ParseMode.HTML, message_thread_id=thread_id)
+                            sent = sent or chunk_msg  # ack the first chunk; replies link to it
                         logger.info("reply_sent", reply_length=len(reply_text))
 
+                    await ack_reply(request_id, message.chat.id, sent)
+
+                    # Deletions requested via the request_delete tool (already checked and audited by the backend)
+                    for action in data.get("delete") or []:
+                        try:
+                            await bot.delete_message(chat_id=action["chat_id"], message_id=action["message_id"])
+                            logger.info("message_deleted", message_id=action["message_id"])
+                        except Exception as e:
+                            logger.warning("message_delete_failed", message_id=action.get("message_id"), error=str(e))
+
                 elif resp.status == 204:
                     # Rate limited — strict silence (Section 10)
-                    logger.info("throttled_silent", chat_id=message.chat.id)
+                    logger.info(
+                        "throttled_silent",
+                        chat_id=message.chat.id,
+                        scope=resp.headers.get("X-RateLimit-Scope"),
+                        retry_after=resp.headers.get("Retry-After"),
+                    )
+                elif resp.status == 429:
+                    # Backend at its concurrency limit — stay silent as well
+                    logger.warning("backend_overloaded", chat_id=message.chat.id)
                 else:
-                    logger.warn("backend_error", status=resp.status)
+                    logger.warn("backend_error", status=resp.status, **await backend_error(resp))
 
     except asyncio.TimeoutError:
         logger.error("backend_timeout")
@@ -256,34 +481,72 @@ async def handle_message(message: types.Message) -> None:
 
 
 # ── Proactive messaging poller ───────────────────────────────────────────
+async def ack_proactive(session: aiohttp.ClientSession, item_id: str) -> None:
+    """Confirm a proactive item so the backend does not redeliver it."""
+    async with session.post(
+        f"{BACKEND_URL}/api/v1/proactive/ack",
+        json={"id": item_id},
+        timeout=aiohttp.ClientTimeout(total=10),
+    ) as resp:
+        if resp.status != 200:
+            log.warning("proactive_ack_bad_status", id=item_id, status=resp.status)
+
+
 async def proactive_poller_loop() -> None:
-    """Poll backend for queued proactive messages and send them to Telegram."""
+    """Long-poll backend for queued proactive messages, send them to Telegram and acknowledge them.
+
+    An item that is popped but never acknowledged (e.g. the bot restarts mid-send) is redelivered
+    by the backend after PROACTIVE_ACK_TIMEOUT_SECONDS.
+    """
     logger = log.bind(component="proactive_poller")
+    long_poll = PROACTIVE_LONG_POLL_SEC > 0
     while True:
         try:
-            await asyncio.sleep(PROACTIVE_POLL_INTERVAL_SEC)
-            async with aiohttp.ClientSession() as session:
-                async with session.get(
-                    f"{BACKEND_URL}/api/v1/proactive",
-                    timeout=aiohttp.ClientTimeout(total=15),
-                ) as resp:
+            if long_poll:
+                url = f"{BACKEND_URL}/api/v1/proactive?wait={PROACTIVE_LONG_POLL_SEC}&ack=true"
+                timeout = aiohttp.ClientTimeout(total=PROACTIVE_LONG_POLL_SEC + 15)
+            else:
+                await asyncio.sleep(PROACTIVE_POLL_INTERVAL_SEC)
+                url = f"{BACKEND_URL}/api/v1/proactive"
+                timeout = aiohttp.ClientTimeout(total=15)
+            async with backend_session() as session:
+                async with session.get(url, timeout=timeout) as resp:
                     if resp.status == 204:
                         continue
                     if resp.status != 200:
                         logger.warning("proactive_poll_bad_status", status=resp.status)
+                        await asyncio.sleep(5)
                         continue
                     data = await resp.json()
-                    chat_id = data.get("chat_id")
-                    reply = data.get("reply", "")
-                    if not reply or chat_id is None:
-                        continue
-                    html = md_to_telegram_html(reply)
-                    await bot.send_message(chat_id=chat_id, text=html, parse_mode=ParseMode.HTML)
-                    logger.info("proactive_sent", chat_id=chat_id, reply_length=len(reply))
+                item_id = data.get("id")
+                chat_id = data.get("chat_id")
+                reply = data.get("reply", "")
+                media_base64 = data.get("media_base64", "")
+                media_type = data.get("media_type", "")
+                if (reply or media_base64) and chat_id is not None:
+                    html = md_to_telegram_html(reply) if reply else ""
+                    try:
+                        if media_base64:
+                            # A generated picture from a proactive turn; the reply is its caption
+                            media = BufferedInputFile(base64.b64decode(media_base64), filename="generated.png")
+                            caption = html[:1024] if html else None
+                            if media_type == "document":
+                                await bot.send_document(chat_id=chat_id, document=media, caption=caption, parse_mode=ParseMode.HTML)
+                            else:
+                                await bot.send_photo(chat_id=chat_id, photo=media, caption=caption, parse_mode=ParseMode.HTML)
+                        else:
+                            await bot.send_message(chat_id=chat_id, text=html, parse_mode=ParseMode.HTML)
+                        logger.info("proactive_sent", chat_id=chat_id, reply_length=len(reply), has_media=bool(media_base64))
+                    except (TelegramBadRequest, TelegramForbiddenError) as e:
+                        # Retrying won't help (bot removed, chat gone): drop the item.
+                        logger.warning("proactive_send_rejected", chat_id=chat_id, error=str(e))
+                if long_poll and item_id:
+                    await ack_proactive(session, item_id)
         except asyncio.CancelledError:
             break
         except Exception as e:
             logger.error("proactive_poller_error", error=str(e))
+            await asyncio.sleep(5)
 
 
 # ── Health Endpoint (Section 15.2) ──────────────────────────────────────
@@ -316,20 +579,23 @@ async def main() -> None:
         BotCommand(command="stats", description="Admin: backend stats"),
         BotCommand(command="reload_persona", description="Admin: reload persona config"),
     ]
+    if ENABLE_PROACTIVE_MESSAGING:
+        commands.append(BotCommand(command="proactive", description="Proactive messages in this chat"))
     await bot.set_my_commands(commands)
     log.info("commands_set")
 
     # Start health check server
     await start_health_server()
 
-    # Start proactive poller when enabled
-    if ENABLE_PROACTIVE_MESSAGING:
+    # Start proactive poller when enabled (also carries the weekly admin report, digests and research results)
+    if ENABLE_PROACTIVE_MESSAGING or ENABLE_ACTIVITY_REPORT or ENABLE_DAILY_DIGEST or ENABLE_PERSONAL_DIGEST or ENABLE_DEEP_RESEARCH:
         asyncio.create_task(proactive_poller_loop())
-        log.info("proactive_poller_started", interval_sec=PROACTIVE_POLL_INTERVAL_SEC)
+        log.info("proactive_poller_started", long_poll_sec=PROACTIVE_LONG_POLL_SEC, interval_sec=PROACTIVE_POLL_INTERVAL_SEC)
 
     # Start polling
     log.info("starting_polling")
-    await dp.start_polling(bot)
+    # Reactions are only delivered when requested explicitly
+    await dp.start_polling(bot, allowed_updates=dp.resolve_used_update_types())
 
 
 if __name__ == "__main__":
@@ -0,0 +1,39 @@
+"""Tests for the forward metadata sent to the backend."""
+
+from datetime import datetime, timezone
+from types import SimpleNamespace as NS
+
+from forward import forward_facts
+
+DATE = datetime(2026, 10, 5, 9, 30, tzinfo=timezone.utc)
+
+
+def test_not_forwarded():
+    assert forward_facts(NS(forward_origin=None)) == {}
+
+
+def test_from_user():
+    user = NS(first_name="Olena", last_name="K", username="olenak")
+    origin = NS(type="user", sender_user=user, date=DATE)
+    assert forward_facts(NS(forward_origin=origin)) == {
+        "forwarded_from": "Olena K (@olenak)", "forward_date": "2026-10-05T09:30:00+00:00",
+    }
+
+
+def test_from_hidden_user():
+    origin = NS(type="hidden_user", sender_user_name="Taras", date=DATE)
+    assert forward_facts(NS(forward_origin=origin))["forwarded_from"] == "Taras"
+
+
+def test_from_channel_with_signature():
+    origin = NS(type="channel", chat=NS(title="Новини", username="news_ua"), author_signature="Editor", date=DATE)
+    facts = forward_facts(NS(forward_origin=origin))
+    assert facts["forwarded_from_chat"] == "Новини (@news_ua)"
+    assert facts["forwarded_from"] == "Editor"
+
+
+def test_from_group_chat():
+    origin = NS(type="chat", sender_chat=NS(title="Group", username=None), author_signature=None, date=DATE)
+    assert forward_facts(NS(forward_origin=origin)) == {
+        "forwarded_from_chat": "Group", "forward_date": "2026-10-05T09:30:00+00:00",
+    }
@@ -0,0 +1,26 @@
+"""Tests for karma vote detection."""
+
+from karma import reaction_vote, reply_vote
+
+
+def test_reply_plus():
+    for text in ("+", " +1 ", "👍", "Дякую!", "thanks."):
+        assert reply_vote(text) == 1, text
+
+
+def test_reply_minus():
+    for text in ("-", "-1", "👎"):
+        assert reply_vote(text) == -1, text
+
+
+def test_reply_other():
+    for text in (None, "", "+ good point", "lol", "1"):
+        assert reply_vote(text) == 0, text
+
+
+def test_reaction():
+    assert reaction_vote(["👍"]) == 1
+    assert reaction_vote(["👎"]) == -1
+    assert reaction_vote(["👎", "🔥"]) == 1
+    assert reaction_vote(["🤔"]) == 0
+    assert reaction_vote([]) == 0
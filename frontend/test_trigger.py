@@ -0,0 +1,50 @@
+"""Tests for the trigger facts sent to the backend."""
+
+from types import SimpleNamespace as NS
+
+from trigger import trigger_facts
+
+BOT_ID = 42
+
+
+def message(text="", chat_type="supergroup", entities=None, reply_from=None):
+    reply = NS(from_user=NS(id=reply_from)) if reply_from else None
+    return NS(text=text, caption=None, chat=NS(type=chat_type), entities=entities,
+              caption_entities=None, reply_to_message=reply)
+
+
+def test_plain_group_message():
+    assert trigger_facts(message("привіт"), BOT_ID, "gryag_bot") == {
+        "chat_type": "supergroup", "is_command": False, "mentions_bot": False, "reply_to_bot": False,
+    }
+
+
+def command(text):
+    name = text.split()[0]
+    return message(text, entities=[NS(type="bot_command", offset=0, length=len(name.encode("utf-16-le")) // 2, user=None)])
+
+
+def test_private_and_command():
+    assert trigger_facts(message("hi", chat_type="private"), BOT_ID, "gryag_bot")["chat_type"] == "private"
+    assert trigger_facts(command("/stats"), BOT_ID, "gryag_bot")["is_command"]
+    assert trigger_facts(command("/stats@Gryag_Bot now"), BOT_ID, "gryag_bot")["is_command"]
+
+
+def test_command_for_another_bot():
+    assert not trigger_facts(command("/start@otherbot"), BOT_ID, "gryag_bot")["is_command"]
+    assert not trigger_facts(command("/start@gryag_bot"), BOT_ID, None)["is_command"]
+
+
+def test_mention_with_utf16_offsets():
+    text = "😀 @Gryag_Bot глянь"
+    entity = NS(type="mention", offset=3, length=10, user=None)  # the emoji is two UTF-16 units
+    assert trigger_facts(message(text, entities=[entity]), BOT_ID, "gryag_bot")["mentions_bot"]
+    other = NS(type="mention", offset=3, length=10, user=None)
+    assert not trigger_facts(message("😀 @other_bot1 x", entities=[other]), BOT_ID, "gryag_bot")["mentions_bot"]
+
+
+def test_text_mention_and_reply():
+    entity = NS(type="text_mention", offset=0, length=4, user=NS(id=BOT_ID))
+    assert trigger_facts(message("Гряг", entities=[entity]), BOT_ID, None)["mentions_bot"]
+    assert trigger_facts(message("так", reply_from=BOT_ID), BOT_ID, "gryag_bot")["reply_to_bot"]
+    assert not trigger_facts(message("так", reply_from=7), BOT_ID, "gryag_bot")["reply_to_bot"]
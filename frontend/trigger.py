@@ -0,0 +1,39 @@
+"""
+Facts the backend's trigger policy decides on.
+
+The frontend only reports what Telegram says about a message (chat type, command, @mention of the
+bot, reply to the bot); whether to answer is decided by the backend (TRIGGER_* settings).
+"""
+
+
+def _entity_text(text: str, entity) -> str:
+    """Text of an entity; Telegram offsets count UTF-16 code units."""
+    raw = text.encode("utf-16-le")
+    return raw[entity.offset * 2:(entity.offset + entity.length) * 2].decode("utf-16-le", errors="ignore")
+
+
+def _command_for_me(command: str, me_username: str | None) -> bool:
+    """Whether a /command (or /command@bot) is addressed to this bot rather than another one."""
+    _, at, target = command.partition("@")
+    return not at or bool(me_username) and target.lower() == me_username.lower()
+
+
+def trigger_facts(message, me_id: int, me_username: str | None) -> dict:
+    """Payload fields for /api/v1/process: chat_type, is_command, mentions_bot and reply_to_bot."""
+    text = message.text or message.caption or ""
+    mentions = command = False
+    for entity in (message.entities or message.caption_entities or []):
+        if entity.type == "bot_command" and entity.offset == 0:
+            command = _command_for_me(_entity_text(text, entity), me_username)
+        elif entity.type == "mention" and me_username:
+            if _entity_text(text, entity).lower() == f"@{me_username.lower()}":
+                mentions = True
+        elif entity.type == "text_mention" and entity.user and entity.user.id == me_id:
+            mentions = True
+    reply = getattr(message, "reply_to_message", None)
+    return {
+        "chat_type": message.chat.type,
+        "is_command": command,
+        "mentions_bot": mentions,
+        "reply_to_bot": bool(reply and reply.from_user and reply.from_user.id == me_id),
+    }
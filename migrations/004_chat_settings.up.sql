@@ -0,0 +1,11 @@
+-- Per-chat settings: overrides of the global env defaults. NULL columns inherit the default.
+CREATE TABLE IF NOT EXISTS chat_settings (
+    chat_id             BIGINT PRIMARY KEY,
+    language            TEXT,
+    persona             TEXT,
+    proactive_enabled   BOOLEAN,
+    disabled_tools      TEXT[] NOT NULL DEFAULT '{}',
+    temperature         DOUBLE PRECISION,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- Per-user preferences that follow a user across chats (reply language, etc.).
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id     BIGINT PRIMARY KEY,
+    language    TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS poll_answers;
+DROP TABLE IF EXISTS polls;
@@ -0,0 +1,27 @@
+-- Telegram polls seen in group chats and the answers users gave (non-anonymous polls only).
+CREATE TABLE IF NOT EXISTS polls (
+    poll_id        TEXT PRIMARY KEY,
+    chat_id        BIGINT NOT NULL,
+    message_id     BIGINT,
+    question       TEXT NOT NULL,
+    options        TEXT[] NOT NULL DEFAULT '{}',
+    option_votes   INTEGER[] NOT NULL DEFAULT '{}',
+    total_voters   INTEGER NOT NULL DEFAULT 0,
+    is_anonymous   BOOLEAN NOT NULL DEFAULT TRUE,
+    is_closed      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_by     BIGINT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_polls_chat_updated ON polls (chat_id, updated_at DESC);
+
+CREATE TABLE IF NOT EXISTS poll_answers (
+    poll_id      TEXT NOT NULL REFERENCES polls(poll_id) ON DELETE CASCADE,
+    user_id      BIGINT NOT NULL,
+    username     TEXT,
+    first_name   TEXT,
+    option_ids   INTEGER[] NOT NULL DEFAULT '{}',
+    answered_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (poll_id, user_id)
+);
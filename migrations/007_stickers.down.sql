@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sticker_usage;
+ALTER TABLE messages DROP COLUMN IF EXISTS sticker_set;
+ALTER TABLE messages DROP COLUMN IF EXISTS sticker_emoji;
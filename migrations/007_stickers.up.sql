@@ -0,0 +1,15 @@
+-- Sticker semantics: the emoji and set of each sticker message, plus a per-chat usage profile
+-- that survives message retention pruning.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sticker_emoji TEXT;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sticker_set TEXT;
+
+CREATE TABLE IF NOT EXISTS sticker_usage (
+    chat_id       BIGINT NOT NULL,
+    set_name      TEXT NOT NULL DEFAULT '',
+    emoji         TEXT NOT NULL DEFAULT '',
+    uses          INTEGER NOT NULL DEFAULT 0,
+    last_used_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, set_name, emoji)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sticker_usage_chat_uses ON sticker_usage (chat_id, uses DESC);
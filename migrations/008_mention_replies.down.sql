@@ -0,0 +1,2 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS mention_daily_cap;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS mention_reply_probability;
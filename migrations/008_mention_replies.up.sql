@@ -0,0 +1,3 @@
+-- Per-chat control over replies to indirect mentions of the bot's name (not @mentions or replies).
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS mention_reply_probability DOUBLE PRECISION;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS mention_daily_cap INTEGER;
@@ -0,0 +1,2 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS active_persona;
+DROP TABLE IF EXISTS personas;
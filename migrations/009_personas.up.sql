@@ -0,0 +1,10 @@
+-- Named personas a chat can switch between; "default" (the PERSONA_FILE) is built in and not stored.
+CREATE TABLE IF NOT EXISTS personas (
+    name         TEXT PRIMARY KEY,
+    description  TEXT NOT NULL DEFAULT '',
+    prompt       TEXT NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS active_persona TEXT;
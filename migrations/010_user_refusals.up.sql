@@ -0,0 +1,8 @@
+-- Offers a user declined ("no, I don't need a picture"), so the bot stops suggesting them.
+CREATE TABLE IF NOT EXISTS user_refusals (
+    user_id           BIGINT NOT NULL,
+    offer             TEXT NOT NULL,
+    times_declined    INTEGER NOT NULL DEFAULT 1,
+    last_declined_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, offer)
+);
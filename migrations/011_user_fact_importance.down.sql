@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_user_facts_importance;
+ALTER TABLE user_facts DROP COLUMN IF EXISTS importance;
+ALTER TABLE user_facts DROP COLUMN IF EXISTS category;
@@ -0,0 +1,7 @@
+-- Fact categories and importance (1 = trivia, 5 = core identity) so context can show the top facts only.
+ALTER TABLE user_facts ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'bio'
+    CHECK (category IN ('preference', 'bio', 'event', 'joke'));
+ALTER TABLE user_facts ADD COLUMN IF NOT EXISTS importance SMALLINT NOT NULL DEFAULT 3
+    CHECK (importance BETWEEN 1 AND 5);
+
+CREATE INDEX IF NOT EXISTS idx_user_facts_importance ON user_facts (chat_id, user_id, importance DESC, updated_at DESC);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_facts_last_referenced;
+ALTER TABLE user_facts DROP COLUMN IF EXISTS last_referenced_at;
@@ -0,0 +1,5 @@
+-- When a fact was last used (shown in a prompt, recalled or updated); the nightly job forgets facts unused for months.
+ALTER TABLE user_facts ADD COLUMN IF NOT EXISTS last_referenced_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+UPDATE user_facts SET last_referenced_at = updated_at;
+
+CREATE INDEX IF NOT EXISTS idx_user_facts_last_referenced ON user_facts (last_referenced_at);
@@ -0,0 +1,2 @@
+DROP FUNCTION IF EXISTS is_off_record(BIGINT, TIMESTAMPTZ);
+DROP TABLE IF EXISTS off_record_windows;
@@ -0,0 +1,25 @@
+-- Off-the-record windows: messages sent in [starts_at, ends_at) are excluded from summaries,
+-- search and exports, and no facts are stored while a window is open. ends_at NULL = still open.
+CREATE TABLE IF NOT EXISTS off_record_windows (
+    id          BIGSERIAL PRIMARY KEY,
+    chat_id     BIGINT NOT NULL,
+    starts_at   TIMESTAMPTZ NOT NULL,
+    ends_at     TIMESTAMPTZ,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_by  BIGINT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (ends_at IS NULL OR ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_off_record_windows_chat ON off_record_windows (chat_id, starts_at);
+
+-- The single place the window rule lives; every query that must honor windows calls this.
+CREATE OR REPLACE FUNCTION is_off_record(p_chat_id BIGINT, p_at TIMESTAMPTZ) RETURNS BOOLEAN
+LANGUAGE sql STABLE AS $$
+    SELECT EXISTS (
+        SELECT 1 FROM off_record_windows w
+        WHERE w.chat_id = p_chat_id
+          AND p_at >= w.starts_at
+          AND (w.ends_at IS NULL OR p_at < w.ends_at)
+    )
+$$;
@@ -0,0 +1,2 @@
+DELETE FROM user_facts WHERE chat_id = 0;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS global_memory;
@@ -0,0 +1,2 @@
+-- Opt-in for cross-chat memories: facts stored with chat_id = 0 follow the user into every chat.
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS global_memory BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_chat_summaries_lookup;
+ALTER TABLE chat_summaries DROP COLUMN IF EXISTS thread_id;
+CREATE INDEX IF NOT EXISTS idx_chat_summaries_lookup ON chat_summaries (chat_id, summary_type, period_end DESC);
+
+DROP INDEX IF EXISTS idx_messages_chat_thread_created;
+ALTER TABLE messages DROP COLUMN IF EXISTS thread_id;
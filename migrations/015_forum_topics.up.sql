@@ -0,0 +1,8 @@
+-- Forum supergroups: messages and summaries are scoped per topic thread.
+-- thread_id 0 = not a topic message (regular chats and the forum's General topic).
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_messages_chat_thread_created ON messages (chat_id, thread_id, created_at DESC);
+
+ALTER TABLE chat_summaries ADD COLUMN IF NOT EXISTS thread_id BIGINT NOT NULL DEFAULT 0;
+DROP INDEX IF EXISTS idx_chat_summaries_lookup;
+CREATE INDEX IF NOT EXISTS idx_chat_summaries_lookup ON chat_summaries (chat_id, thread_id, summary_type, period_end DESC);
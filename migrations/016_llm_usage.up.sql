@@ -0,0 +1,14 @@
+-- One row per Gemini call: token usage for spend estimates, and the error for failed calls.
+CREATE TABLE IF NOT EXISTS llm_usage (
+    id             BIGSERIAL PRIMARY KEY,
+    request_id     TEXT,
+    chat_id        BIGINT,
+    purpose        TEXT NOT NULL,
+    model          TEXT NOT NULL,
+    prompt_tokens  INT NOT NULL DEFAULT 0,
+    output_tokens  INT NOT NULL DEFAULT 0,
+    error          TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_usage_created ON llm_usage (created_at);
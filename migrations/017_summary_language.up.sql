@@ -0,0 +1,2 @@
+-- Per-chat override of the language summaries are written in (NULL = detect the chat's dominant language).
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_language TEXT;
@@ -0,0 +1,4 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS summary_anonymize;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS summary_interval_days;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS summary_run_hour;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS summary_enabled;
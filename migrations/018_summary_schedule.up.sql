@@ -0,0 +1,5 @@
+-- Per-chat summary schedule and privacy. NULL = env default (SUMMARY_RUN_HOUR, SUMMARY_7DAY_INTERVAL_DAYS, enabled, real names).
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_enabled BOOLEAN;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_run_hour INTEGER;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_interval_days INTEGER;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS summary_anonymize BOOLEAN;
@@ -0,0 +1,2 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS digest_hour;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS digest_enabled;
@@ -0,0 +1,3 @@
+-- Opt-in morning digest per chat. digest_hour NULL = DAILY_DIGEST_HOUR.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest_enabled BOOLEAN;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS digest_hour INTEGER;
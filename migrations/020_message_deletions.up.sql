@@ -0,0 +1,15 @@
+-- Audit log of request_delete tool calls (allowed and refused).
+CREATE TABLE IF NOT EXISTS message_deletions (
+    id              BIGSERIAL PRIMARY KEY,
+    chat_id         BIGINT NOT NULL,
+    message_id      BIGINT NOT NULL,
+    requested_by    BIGINT NOT NULL,
+    target_user_id  BIGINT,                -- author of the message; NULL for bot replies or unknown messages
+    target_is_bot   BOOLEAN NOT NULL DEFAULT FALSE,
+    allowed         BOOLEAN NOT NULL,
+    reason          TEXT,
+    request_id      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_message_deletions_chat ON message_deletions (chat_id, created_at DESC);
@@ -0,0 +1,2 @@
+-- Opt-in weekly personal digest DM (mentions of and replies to the user).
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS personal_digest BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS watermark_label;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS watermark_enabled;
@@ -0,0 +1,3 @@
+-- Per-chat AI-content label on generated images. NULL = WATERMARK_IMAGES / WATERMARK_LABEL.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS watermark_enabled BOOLEAN;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS watermark_label TEXT;
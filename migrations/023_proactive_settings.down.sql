@@ -0,0 +1,5 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS timezone;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_quiet_end;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_quiet_start;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_max_interval_minutes;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_min_interval_minutes;
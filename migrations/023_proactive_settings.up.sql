@@ -0,0 +1,6 @@
+-- Per-chat proactive pacing. NULL = no limit / no quiet hours / Europe/Kyiv.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_min_interval_minutes INT;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_max_interval_minutes INT;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_quiet_start SMALLINT;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_quiet_end SMALLINT;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS timezone TEXT;
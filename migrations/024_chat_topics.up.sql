@@ -0,0 +1,17 @@
+-- Topic hints for proactive messages: upcoming events, running jokes and follow-up items.
+-- Added by admins (/api/v1/admin/chat_topics) or the model (add_chat_topic tool).
+CREATE TABLE IF NOT EXISTS chat_topics (
+    id            BIGSERIAL PRIMARY KEY,
+    chat_id       BIGINT NOT NULL,
+    kind          TEXT NOT NULL CHECK (kind IN ('event', 'joke', 'follow_up')),
+    text          TEXT NOT NULL,
+    due_at        TIMESTAMPTZ,                 -- not brought up before this; NULL = any time
+    source        TEXT NOT NULL DEFAULT 'admin', -- 'admin' or 'tool'
+    created_by    BIGINT,
+    used_count    INT NOT NULL DEFAULT 0,
+    last_used_at  TIMESTAMPTZ,
+    done          BOOLEAN NOT NULL DEFAULT FALSE, -- events and follow-ups are done once used
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_topics_open ON chat_topics (chat_id, due_at) WHERE NOT done;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_chat_summaries_created;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS message_retention_days;
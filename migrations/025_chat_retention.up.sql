@@ -0,0 +1,5 @@
+-- Per-chat message retention in days. NULL = MESSAGE_RETENTION_DAYS, 0 = keep forever.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS message_retention_days INT;
+
+-- Summary retention deletes by age.
+CREATE INDEX IF NOT EXISTS idx_chat_summaries_created ON chat_summaries (created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS karma_votes;
+DROP TABLE IF EXISTS karma;
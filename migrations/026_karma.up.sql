@@ -0,0 +1,22 @@
+-- Per-chat user karma (ENABLE_KARMA), moved by reactions and "+"/"-" replies reported by the frontend.
+CREATE TABLE IF NOT EXISTS karma (
+    chat_id     BIGINT NOT NULL,
+    user_id     BIGINT NOT NULL,
+    score       INT NOT NULL DEFAULT 0,
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_karma_leaderboard ON karma (chat_id, score DESC);
+
+-- One vote per voter, message and source; a changed vote moves karma by the difference.
+CREATE TABLE IF NOT EXISTS karma_votes (
+    chat_id         BIGINT NOT NULL,
+    message_id      BIGINT NOT NULL,               -- Telegram message_id of the voted message
+    voter_id        BIGINT NOT NULL,
+    source          TEXT NOT NULL CHECK (source IN ('reaction', 'reply')),
+    target_user_id  BIGINT NOT NULL,
+    delta           SMALLINT NOT NULL CHECK (delta BETWEEN -1 AND 1), -- 0 = retracted
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, message_id, voter_id, source)
+);
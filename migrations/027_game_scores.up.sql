@@ -0,0 +1,10 @@
+-- Per-chat game scores (ENABLE_GAMES): trivia answers and Russian roulette survivals.
+CREATE TABLE IF NOT EXISTS game_scores (
+    chat_id     BIGINT NOT NULL,
+    user_id     BIGINT NOT NULL,
+    game        TEXT NOT NULL,          -- 'trivia' or 'roulette'
+    wins        INT NOT NULL DEFAULT 0, -- correct answers / survived pulls
+    losses      INT NOT NULL DEFAULT 0, -- roulette shots
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, game, user_id)
+);
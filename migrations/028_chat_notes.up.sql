@@ -0,0 +1,14 @@
+-- Notes a chat asked the bot to keep ("запиши, що оренда — 12-го числа"): save_note, list_notes, delete_note.
+CREATE TABLE IF NOT EXISTS chat_notes (
+    id           BIGSERIAL PRIMARY KEY,
+    chat_id      BIGINT NOT NULL,
+    title        TEXT NOT NULL,
+    body         TEXT NOT NULL,
+    author_id    BIGINT,
+    author_name  TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One note per title in a chat; saving under an existing title updates it.
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_notes_title ON chat_notes (chat_id, lower(title));
@@ -0,0 +1,2 @@
+-- Optional per-user IANA timezone (set_timezone). NULL means "use the chat's timezone".
+ALTER TABLE user_settings ADD COLUMN IF NOT EXISTS timezone TEXT;
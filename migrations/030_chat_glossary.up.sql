@@ -0,0 +1,13 @@
+-- Per-chat translation glossary (set_glossary_term): how translate renders names and slang.
+-- lang is the target language code the entry applies to; '' means every target language.
+CREATE TABLE IF NOT EXISTS chat_glossary (
+    id           BIGSERIAL PRIMARY KEY,
+    chat_id      BIGINT NOT NULL,
+    term         TEXT NOT NULL,
+    translation  TEXT NOT NULL,
+    lang         TEXT NOT NULL DEFAULT '',
+    created_by   BIGINT,
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_chat_glossary_term ON chat_glossary (chat_id, lower(term), lang);
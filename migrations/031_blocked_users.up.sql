@@ -0,0 +1,11 @@
+-- Users the bot ignores (admin /api/v1/admin/block). chat_id 0 blocks the user in every chat.
+-- Their messages are still stored as context, but never answered.
+CREATE TABLE IF NOT EXISTS blocked_users (
+    user_id     BIGINT NOT NULL,
+    chat_id     BIGINT NOT NULL DEFAULT 0,
+    reason      TEXT NOT NULL DEFAULT '',
+    blocked_by  BIGINT,
+    expires_at  TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, chat_id)
+);
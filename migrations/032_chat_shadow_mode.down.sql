@@ -0,0 +1 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS shadow_mode;
@@ -0,0 +1,2 @@
+-- Shadow mode: the chat's replies are generated and logged but never sent. NULL = off.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS shadow_mode BOOLEAN;
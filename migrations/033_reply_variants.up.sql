@@ -0,0 +1,16 @@
+-- Which variant (control or canary model/temperature) generated each reply, with the numbers
+-- POST /api/v1/admin/canary compares. Only written while a canary is configured.
+CREATE TABLE IF NOT EXISTS reply_variants (
+    request_id    TEXT PRIMARY KEY,               -- messages.request_id of the reply
+    chat_id       BIGINT NOT NULL,
+    variant       TEXT NOT NULL CHECK (variant IN ('control', 'canary')),
+    model         TEXT NOT NULL,
+    temperature   REAL NOT NULL,
+    latency_ms    INT NOT NULL,                   -- whole generate/tool loop
+    reply_length  INT NOT NULL,                   -- characters
+    tool_calls    INT NOT NULL DEFAULT 0,
+    failed        BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reply_variants_created ON reply_variants (created_at);
@@ -0,0 +1,18 @@
+-- Every tool invocation, for debugging, abuse detection and cost attribution. Pruned by the
+-- retention job after TOOL_CALL_RETENTION_DAYS.
+CREATE TABLE IF NOT EXISTS tool_calls (
+    id           BIGSERIAL PRIMARY KEY,
+    request_id   TEXT,                            -- X-Request-ID of the reply; NULL for background turns
+    chat_id      BIGINT,
+    user_id      BIGINT,
+    tool         TEXT NOT NULL,
+    args_hash    TEXT NOT NULL,                   -- SHA-256 of the JSON arguments
+    duration_ms  INT NOT NULL,
+    output       TEXT NOT NULL DEFAULT '',        -- first 500 characters
+    error        TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_calls_created ON tool_calls (created_at);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_chat ON tool_calls (chat_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_tool_calls_tool ON tool_calls (tool, created_at);
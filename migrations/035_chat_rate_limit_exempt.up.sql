@@ -0,0 +1,2 @@
+-- Users of a chat who skip its chat and per-user rate limits.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS rate_limit_exempt_users BIGINT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_messages_chat_request;
+DROP INDEX IF EXISTS idx_messages_chat_reply_to;
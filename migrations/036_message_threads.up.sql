@@ -0,0 +1,3 @@
+-- Reply chain lookups for get_thread: replies to a message, and bot replies by their trigger's request_id.
+CREATE INDEX IF NOT EXISTS idx_messages_chat_reply_to ON messages (chat_id, reply_to_message_id) WHERE reply_to_message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_chat_request ON messages (chat_id, request_id) WHERE request_id IS NOT NULL;
@@ -0,0 +1,11 @@
+-- Rebuild search_vector without transcripts, then drop them.
+DROP INDEX IF EXISTS idx_messages_search;
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE messages ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(text, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(first_name, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(username, '')), 'C')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector);
+ALTER TABLE messages DROP COLUMN IF EXISTS transcript;
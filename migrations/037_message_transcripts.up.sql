@@ -0,0 +1,14 @@
+-- Transcripts of voice notes and audio files (summarize_audio), searchable like message text.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS transcript TEXT;
+
+-- A generated column's expression can't be changed in place: rebuild search_vector with the transcript.
+DROP INDEX IF EXISTS idx_messages_search;
+ALTER TABLE messages DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE messages ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(text, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(transcript, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(first_name, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(username, '')), 'C')
+    ) STORED;
+CREATE INDEX IF NOT EXISTS idx_messages_search ON messages USING GIN (search_vector);
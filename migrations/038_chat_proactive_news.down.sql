@@ -0,0 +1,2 @@
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_news_queries;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS proactive_news_probability;
@@ -0,0 +1,3 @@
+-- Per-chat overrides of proactive news turns (PROACTIVE_NEWS_PROBABILITY, PROACTIVE_NEWS_QUERIES).
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_news_probability DOUBLE PRECISION;
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS proactive_news_queries TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,12 @@
+-- Proactive queue items the frontend took for delivery: proactive messages, digests, reports and
+-- research results. Recent ones are shown to the proactive prompt so it does not repeat itself.
+CREATE TABLE IF NOT EXISTS proactive_deliveries (
+    id            BIGSERIAL PRIMARY KEY,
+    chat_id       BIGINT NOT NULL,
+    text          TEXT NOT NULL,
+    source        TEXT NOT NULL,                  -- proactive, digest, personal_digest, activity_report, research
+    delivered_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_proactive_deliveries_chat ON proactive_deliveries (chat_id, delivered_at DESC);
+CREATE INDEX IF NOT EXISTS idx_proactive_deliveries_delivered ON proactive_deliveries (delivered_at);
@@ -0,0 +1,16 @@
+-- Promises the bot made in its replies ("нагадаю завтра", "I'll check later"), found by a
+-- post-processing pass and brought up by the proactive runner once due.
+CREATE TABLE IF NOT EXISTS follow_ups (
+    id          BIGSERIAL PRIMARY KEY,
+    chat_id     BIGINT NOT NULL,
+    user_id     BIGINT,                         -- who the promise was made to
+    request_id  TEXT,                           -- the reply that made it
+    text        TEXT NOT NULL,                  -- what to do, as an instruction to the bot
+    due_at      TIMESTAMPTZ NOT NULL,
+    done        BOOLEAN NOT NULL DEFAULT FALSE,
+    done_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_follow_ups_open ON follow_ups (chat_id, due_at) WHERE NOT done;
+CREATE INDEX IF NOT EXISTS idx_follow_ups_created ON follow_ups (created_at);
@@ -0,0 +1,2 @@
+ALTER TABLE messages DROP COLUMN IF EXISTS sentiment_heat;
+ALTER TABLE messages DROP COLUMN IF EXISTS sentiment_valence;
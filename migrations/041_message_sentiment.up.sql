@@ -0,0 +1,3 @@
+-- Tone of user messages (rule-based, see internal/sentiment), feeding the rolling chat mood.
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sentiment_valence REAL;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS sentiment_heat REAL;
@@ -0,0 +1,25 @@
+-- Chat history segmented into topics by a background job, so recall_topic can answer "when did
+-- we last talk about X?" from a few rows instead of scanning messages.
+CREATE TABLE IF NOT EXISTS chat_topics_index (
+    id               BIGSERIAL PRIMARY KEY,
+    chat_id          BIGINT NOT NULL,
+    thread_id        BIGINT NOT NULL DEFAULT 0,
+    title            TEXT NOT NULL,
+    summary          TEXT NOT NULL DEFAULT '',
+    keywords         TEXT NOT NULL DEFAULT '',      -- comma-separated, in dictionary form
+    first_message_id BIGINT NOT NULL,               -- messages.id range the segment covers
+    last_message_id  BIGINT NOT NULL,
+    message_count    INT NOT NULL,
+    started_at       TIMESTAMPTZ NOT NULL,
+    ended_at         TIMESTAMPTZ NOT NULL,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    search_vector    tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', title), 'A') ||
+        setweight(to_tsvector('simple', keywords), 'A') ||
+        setweight(to_tsvector('simple', summary), 'B')
+    ) STORED
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_topics_index_chat ON chat_topics_index (chat_id, thread_id, last_message_id DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_topics_index_search ON chat_topics_index USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_chat_topics_index_ended ON chat_topics_index (ended_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS chat_pseudonyms;
+ALTER TABLE chat_settings DROP COLUMN IF EXISTS privacy_mode;
@@ -0,0 +1,15 @@
+-- Privacy mode: names and user IDs in the chat's prompts become stable pseudonyms. NULL = off.
+ALTER TABLE chat_settings ADD COLUMN IF NOT EXISTS privacy_mode BOOLEAN;
+
+-- Each user's pseudonym in a privacy-mode chat ("Member <alias>", user ID <alias>) with the names
+-- last seen for them, so tool calls and replies can be mapped back. Never sent to the model.
+CREATE TABLE IF NOT EXISTS chat_pseudonyms (
+    chat_id    BIGINT NOT NULL,
+    user_id    BIGINT NOT NULL,
+    alias      INT NOT NULL,
+    first_name TEXT,
+    username   TEXT,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, user_id),
+    UNIQUE (chat_id, alias)
+);